@@ -148,6 +148,63 @@ func main() {
 			Usage:       "Audit log level: 0 - disable audit log, 1 - log event metadata, 2 - log event metadata and request body, 3 - log event metadata, request body and response body",
 			Destination: &config.AuditLevel,
 		},
+		cli.StringFlag{
+			Name:        "audit-policy-file",
+			EnvVar:      "AUDIT_POLICY_FILE",
+			Usage:       "Path to a YAML file of rules choosing the audit level by user, group, resource type, verb and path prefix, and of additional JSON paths to redact from logged bodies per resource type. The audit-level applies to requests no rule matches",
+			Destination: &config.AuditPolicyFile,
+		},
+		cli.StringFlag{
+			Name:        "audit-webhook-url",
+			EnvVar:      "AUDIT_WEBHOOK_URL",
+			Usage:       "URL audit records are posted to in batches, as a JSON array",
+			Destination: &config.AuditWebhookURL,
+		},
+		cli.IntFlag{
+			Name:        "audit-webhook-batch-size",
+			Value:       100,
+			EnvVar:      "AUDIT_WEBHOOK_BATCH_SIZE",
+			Usage:       "Maximum number of audit records posted to the webhook at once",
+			Destination: &config.AuditWebhookBatchSize,
+		},
+		cli.IntFlag{
+			Name:        "audit-webhook-batch-max-wait",
+			Value:       5,
+			EnvVar:      "AUDIT_WEBHOOK_BATCH_MAX_WAIT",
+			Usage:       "Maximum number of seconds an audit record waits for its batch to fill before it is posted to the webhook",
+			Destination: &config.AuditWebhookBatchMaxWait,
+		},
+		cli.IntFlag{
+			Name:        "audit-webhook-max-retries",
+			Value:       5,
+			EnvVar:      "AUDIT_WEBHOOK_MAX_RETRIES",
+			Usage:       "Number of times a batch of audit records failing to post to the webhook is retried before it is dropped",
+			Destination: &config.AuditWebhookMaxRetries,
+		},
+		cli.StringFlag{
+			Name:        "audit-syslog-address",
+			EnvVar:      "AUDIT_SYSLOG_ADDRESS",
+			Usage:       "Syslog server audit records are sent to as RFC 5424 messages, in udp://host:port, tcp://host:port or tls://host:port format",
+			Destination: &config.AuditSyslogAddress,
+		},
+		cli.StringFlag{
+			Name:        "audit-syslog-ca-file",
+			EnvVar:      "AUDIT_SYSLOG_CA_FILE",
+			Usage:       "Path to a PEM file of the CA certificates verifying a tls:// audit syslog server, instead of the system roots",
+			Destination: &config.AuditSyslogCAFile,
+		},
+		cli.StringFlag{
+			Name:        "audit-syslog-cert-file",
+			EnvVar:      "AUDIT_SYSLOG_CERT_FILE",
+			Usage:       "Path to a PEM client certificate authenticating to a tls:// audit syslog server",
+			Destination: &config.AuditSyslogCertFile,
+		},
+		cli.StringFlag{
+			Name:        "audit-syslog-key-file",
+			EnvVar:      "AUDIT_SYSLOG_KEY_FILE",
+			Usage:       "Path to the PEM key of the audit-syslog-cert-file",
+			Destination: &config.AuditSyslogKeyFile,
+		},
 		cli.StringFlag{
 			Name:        "profile-listen-address",
 			Value:       "127.0.0.1:6060",
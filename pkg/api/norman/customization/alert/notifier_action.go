@@ -75,19 +75,32 @@ func (h *Handler) testNotifier(ctx context.Context, actionName string, action *t
 		if err != nil {
 			return err
 		}
+		if input.Templates != nil {
+			// templates of the input are tried out before they are saved
+			notifier = notifier.DeepCopy()
+			notifier.Spec.Templates = input.Templates
+			if err := notifiers.Validate(&notifier.Spec); err != nil {
+				return httperror.NewAPIError(httperror.InvalidBodyContent, err.Error())
+			}
+		}
+	} else if err := notifiers.Validate(&notifier.Spec); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, err.Error())
 	}
 	notifierMessage := &notifiers.Message{
+		Title:   testSMTPTitle,
 		Content: msg,
-	}
-	if notifier.Spec.SMTPConfig != nil {
-		notifierMessage.Title = testSMTPTitle
+		Data:    notifiers.NewTestTemplateData(notifier.Spec.ClusterName, msg),
 	}
 
 	dialer, err := h.DialerFactory.ClusterDialer(clientNotifier.ClusterID)
 	if err != nil {
 		return errors.Wrap(err, "error getting dialer")
 	}
-	return notifiers.SendMessage(ctx, notifier, "", notifierMessage, dialer)
+	err = notifiers.SendMessage(ctx, h.Notifiers, notifier, "", notifierMessage, dialer)
+	if _, ok := notifiers.IsSuppressed(err); ok {
+		return httperror.NewAPIError(httperror.InvalidState, err.Error())
+	}
+	return err
 }
 
 func canCreateNotifier(apiContext *types.APIContext, resource *types.RawResource, clusterID string) bool {
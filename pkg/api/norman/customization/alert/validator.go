@@ -10,11 +10,42 @@
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
 	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/notifiers"
 	"github.com/rancher/rancher/pkg/ref"
 )
 
 const monitoringEnabled = "MonitoringEnabled"
 
+func NotifierValidator(resquest *types.APIContext, schema *types.Schema, data map[string]interface{}) error {
+	if resquest.ID != "" {
+		// updates may leave fields out, validate the notifier they result in.
+		// The store is read directly as the API hides password fields.
+		existing, err := schema.Store.ByID(resquest, schema, resquest.ID)
+		if err != nil {
+			return err
+		}
+		merged := map[string]interface{}{}
+		for k, v := range existing {
+			merged[k] = v
+		}
+		for k, v := range data {
+			merged[k] = v
+		}
+		data = merged
+	}
+
+	var spec v32.NotifierSpec
+	if err := convert.ToObj(data, &spec); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, fmt.Sprintf("%v", err))
+	}
+
+	if err := notifiers.Validate(&spec); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, err.Error())
+	}
+
+	return nil
+}
+
 func ClusterAlertRuleValidator(resquest *types.APIContext, schema *types.Schema, data map[string]interface{}) error {
 	var clusterID string
 	if resquest.ID != "" {
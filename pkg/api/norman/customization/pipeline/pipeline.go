@@ -14,6 +14,7 @@
 	"github.com/rancher/norman/types"
 	client "github.com/rancher/rancher/pkg/client/generated/project/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
 	"github.com/rancher/rancher/pkg/pipeline/providers"
 	"github.com/rancher/rancher/pkg/pipeline/remote"
 	"github.com/rancher/rancher/pkg/pipeline/remote/model"
@@ -37,6 +38,7 @@
 type Handler struct {
 	PipelineLister             v3.PipelineLister
 	PipelineExecutions         v3.PipelineExecutionInterface
+	PipelineSettingLister      v3.PipelineSettingLister
 	SourceCodeCredentialLister v3.SourceCodeCredentialLister
 	SourceCodeCredentials      v3.SourceCodeCredentialInterface
 }
@@ -116,6 +118,15 @@ func (h *Handler) run(apiContext *types.APIContext) error {
 		return fmt.Errorf("find no pipeline config to run in the branch")
 	}
 
+	_, projectID := ref.Parse(pipeline.Spec.ProjectName)
+	engine, err := common.GetEngineName(h.PipelineSettingLister, projectID)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidEngineConfig(*pipelineConfig, engine); err != nil {
+		return httperror.NewAPIError(httperror.InvalidState, err.Error())
+	}
+
 	info, err := h.getBuildInfoByBranch(pipeline, branch)
 	if err != nil {
 		return err
@@ -21,6 +21,7 @@
 	actionRerun         = "rerun"
 	actionStop          = "stop"
 	linkLog             = "log"
+	linkArtifact        = "artifact"
 )
 
 type ExecutionHandler struct {
@@ -41,11 +42,14 @@ func (h *ExecutionHandler) ExecutionFormatter(apiContext *types.APIContext, reso
 		}
 	}
 	resource.Links[linkLog] = apiContext.URLBuilder.Link(linkLog, resource)
+	resource.Links[linkArtifact] = apiContext.URLBuilder.Link(linkArtifact, resource)
 }
 
 func (h *ExecutionHandler) LinkHandler(apiContext *types.APIContext, next types.RequestHandler) error {
 	if apiContext.Link == linkLog {
 		return h.handleLog(apiContext)
+	} else if apiContext.Link == linkArtifact {
+		return h.handleArtifact(apiContext)
 	}
 
 	return httperror.NewAPIError(httperror.NotFound, "Link not found")
@@ -89,6 +93,7 @@ func (h *ExecutionHandler) rerun(apiContext *types.APIContext) error {
 	toCreate.Status.Started = time.Now().Format(time.RFC3339)
 	toCreate.Status.Ended = ""
 	toCreate.Status.Conditions = nil
+	toCreate.Status.Artifacts = nil
 	for i := 0; i < len(toCreate.Status.Stages); i++ {
 		stage := &toCreate.Status.Stages[i]
 		stage.State = utils.StateWaiting
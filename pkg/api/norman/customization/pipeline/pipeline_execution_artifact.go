@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/pipeline/engine"
+	"github.com/rancher/rancher/pkg/ref"
+)
+
+func (h *ExecutionHandler) handleArtifact(apiContext *types.APIContext) error {
+	query := apiContext.Request.URL.Query()
+	stage, err := strconv.Atoi(query.Get("stage"))
+	if err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, "invalid stage")
+	}
+	step, err := strconv.Atoi(query.Get("step"))
+	if err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, "invalid step")
+	}
+	name := query.Get("name")
+
+	ns, id := ref.Parse(apiContext.ID)
+	execution, err := h.PipelineExecutionLister.Get(ns, id)
+	if err != nil {
+		return err
+	}
+	found := false
+	var size int64
+	for _, artifact := range execution.Status.Artifacts {
+		if artifact.Stage == stage && artifact.Step == step && artifact.Name == name {
+			found = true
+			size = artifact.Size
+			break
+		}
+	}
+	if !found {
+		return httperror.NewAPIError(httperror.NotFound, fmt.Sprintf("artifact %s not found", name))
+	}
+
+	clusterName, _ := ref.Parse(execution.Spec.ProjectName)
+	userContext, err := h.ClusterManager.UserContext(clusterName)
+	if err != nil {
+		return err
+	}
+	reader, err := engine.New(userContext, false).GetArtifact(execution, stage, step, name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	apiContext.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	apiContext.Response.Header().Set("Content-Type", "application/octet-stream")
+	apiContext.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)))
+	apiContext.Response.WriteHeader(http.StatusOK)
+	_, err = io.Copy(apiContext.Response, reader)
+	return err
+}
@@ -59,6 +59,8 @@
 	"github.com/rancher/rancher/pkg/api/norman/store/userscope"
 	authapi "github.com/rancher/rancher/pkg/auth/api"
 	"github.com/rancher/rancher/pkg/auth/api/user"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/mfa"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
@@ -104,6 +106,7 @@ func Setup(ctx context.Context, apiContext *config.ScaledContext, clusterManager
 		client.PodSecurityPolicyTemplateProjectBindingType,
 		client.PodSecurityPolicyTemplateType,
 		client.PreferenceType,
+		client.PrincipalMappingRuleType,
 		client.ProjectNetworkPolicyType,
 		client.ProjectRoleTemplateBindingType,
 		client.ProjectType,
@@ -480,6 +483,8 @@ func User(ctx context.Context, schemas *types.Schemas, management *config.Scaled
 		UserClient:               management.Management.Users(""),
 		GlobalRoleBindingsClient: management.Management.GlobalRoleBindings(""),
 		UserAuthRefresher:        providerrefresh.NewUserAuthRefresher(ctx, management),
+		MFAManager:               mfa.NewManager(management),
+		LockoutManager:           lockout.NewManager(management),
 	}
 
 	schema.Formatter = handler.UserFormatter
@@ -595,6 +600,7 @@ func Alert(schemas *types.Schemas, management *config.ScaledContext) {
 	schema := schemas.Schema(&managementschema.Version, client.NotifierType)
 	schema.CollectionFormatter = alert.NotifierCollectionFormatter
 	schema.Formatter = alert.NotifierFormatter
+	schema.Validator = alert.NotifierValidator
 	schema.ActionHandler = handler.NotifierActionHandler
 
 	schema = schemas.Schema(&managementschema.Version, client.ClusterAlertRuleType)
@@ -635,6 +641,7 @@ func Pipeline(schemas *types.Schemas, management *config.ScaledContext, clusterM
 	pipelineHandler := &pipeline.Handler{
 		PipelineLister:             management.Project.Pipelines("").Controller().Lister(),
 		PipelineExecutions:         management.Project.PipelineExecutions(""),
+		PipelineSettingLister:      management.Project.PipelineSettings("").Controller().Lister(),
 		SourceCodeCredentials:      management.Project.SourceCodeCredentials(""),
 		SourceCodeCredentialLister: management.Project.SourceCodeCredentials("").Controller().Lister(),
 	}
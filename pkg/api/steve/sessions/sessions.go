@@ -0,0 +1,187 @@
+package sessions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const tokenSchema = "management.cattle.io.token"
+
+// Session is a login of a user, backed by their login token.
+type Session struct {
+	UserID       string `json:"userId"`
+	AuthProvider string `json:"authProvider"`
+	ClientIP     string `json:"clientIp,omitempty"`
+	UserAgent    string `json:"userAgent,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+	LastUsedAt   string `json:"lastUsedAt,omitempty"`
+	ExpiresAt    string `json:"expiresAt,omitempty"`
+	// Current is whether the session is the one making the request
+	Current bool `json:"current"`
+}
+
+// Register adds the sessions of the user making the request to the API. Users
+// who can delete tokens can manage the sessions of other users by listing
+// them with the userId query parameter.
+func Register(apiSchemas *types.APISchemas, tokenController mgmtcontrollers.TokenController) {
+	s := &store{
+		tokens:      tokenController,
+		tokenLister: tokenController.Cache(),
+	}
+	apiSchemas.InternalSchemas.TypeName("session", Session{})
+	apiSchemas.MustImportAndCustomize(Session{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{http.MethodGet, http.MethodDelete}
+		schema.CollectionActions = map[string]schemas.Action{
+			"revokeAll": {},
+		}
+		schema.ActionHandlers = map[string]http.Handler{
+			"revokeAll": http.HandlerFunc(s.revokeAll),
+		}
+		schema.Store = s
+	})
+}
+
+type store struct {
+	empty.Store
+	tokens      mgmtcontrollers.TokenClient
+	tokenLister mgmtcontrollers.TokenCache
+}
+
+func (s *store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	token, err := s.getSession(apiOp, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	return toAPIObject(apiOp, token), nil
+}
+
+func (s *store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	sessions, err := s.listSessions(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+	var result types.APIObjectList
+	for _, token := range sessions {
+		result.Objects = append(result.Objects, toAPIObject(apiOp, token))
+	}
+	return result, nil
+}
+
+func (s *store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	token, err := s.getSession(apiOp, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	if err := s.tokens.Delete(token.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return types.APIObject{}, err
+	}
+	return toAPIObject(apiOp, token), nil
+}
+
+// revokeAll deletes all the sessions of the user but the one making the
+// request.
+func (s *store) revokeAll(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	sessions, err := s.listSessions(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	current := currentTokenName(apiOp)
+	for _, token := range sessions {
+		if token.Name == current {
+			continue
+		}
+		if err := s.tokens.Delete(token.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			apiOp.WriteError(err)
+			return
+		}
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// listSessions returns the sessions of the user of the request, or of the
+// user of the userId query parameter.
+func (s *store) listSessions(apiOp *types.APIRequest) ([]*v3.Token, error) {
+	userID := apiOp.GetUser()
+	if target := apiOp.Request.URL.Query().Get("userId"); target != "" && target != userID {
+		if !canManageSessions(apiOp) {
+			return nil, apierror.NewAPIError(validation.PermissionDenied, "can not manage the sessions of other users")
+		}
+		userID = target
+	}
+	userTokens, err := s.tokenLister.List(labels.SelectorFromSet(labels.Set{tokens.UserIDLabel: userID}))
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*v3.Token
+	for _, token := range userTokens {
+		if isSession(token) {
+			sessions = append(sessions, token)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *store) getSession(apiOp *types.APIRequest, id string) (*v3.Token, error) {
+	token, err := s.tokenLister.Get(id)
+	if apierrors.IsNotFound(err) || (err == nil && !isSession(token)) {
+		return nil, apierror.NewAPIError(validation.NotFound, "session not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if token.UserID != apiOp.GetUser() && !canManageSessions(apiOp) {
+		return nil, apierror.NewAPIError(validation.NotFound, "session not found")
+	}
+	return token, nil
+}
+
+// isSession returns whether token was created by a login, and is still valid.
+func isSession(token *v3.Token) bool {
+	return !token.IsDerived && !tokens.IsExpired(*token)
+}
+
+// canManageSessions returns whether the user of apiOp can manage the
+// sessions of other users, as they can delete their tokens.
+func canManageSessions(apiOp *types.APIRequest) bool {
+	schema := apiOp.Schemas.LookupSchema(tokenSchema)
+	if schema == nil {
+		return false
+	}
+	return apiOp.AccessControl.CanDelete(apiOp, types.APIObject{}, schema) == nil
+}
+
+func currentTokenName(apiOp *types.APIRequest) string {
+	name, _ := tokens.SplitTokenParts(tokens.GetTokenAuthFromRequest(apiOp.Request))
+	return name
+}
+
+func toAPIObject(apiOp *types.APIRequest, token *v3.Token) types.APIObject {
+	return types.APIObject{
+		Type: "session",
+		ID:   token.Name,
+		Object: &Session{
+			UserID:       token.UserID,
+			AuthProvider: token.AuthProvider,
+			ClientIP:     token.ClientIP,
+			UserAgent:    token.UserAgent,
+			CreatedAt:    token.CreationTimestamp.UTC().Format(time.RFC3339),
+			LastUsedAt:   token.LastUsedAt,
+			ExpiresAt:    token.ExpiresAt,
+			Current:      token.Name == currentTokenName(apiOp),
+		},
+	}
+}
@@ -0,0 +1,202 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// fakeTokenCache serves the tokens of a map.
+type fakeTokenCache struct {
+	mgmtcontrollers.TokenCache
+	tokens map[string]*v3.Token
+}
+
+func (f *fakeTokenCache) Get(name string) (*v3.Token, error) {
+	token, ok := f.tokens[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "tokens"}, name)
+	}
+	return token, nil
+}
+
+func (f *fakeTokenCache) List(selector labels.Selector) ([]*v3.Token, error) {
+	var result []*v3.Token
+	for _, token := range f.tokens {
+		if selector.Matches(labels.Set(token.Labels)) {
+			result = append(result, token)
+		}
+	}
+	return result, nil
+}
+
+// fakeTokenClient deletes the tokens of a map.
+type fakeTokenClient struct {
+	mgmtcontrollers.TokenClient
+	tokens map[string]*v3.Token
+}
+
+func (f *fakeTokenClient) Delete(name string, options *metav1.DeleteOptions) error {
+	if _, ok := f.tokens[name]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "tokens"}, name)
+	}
+	delete(f.tokens, name)
+	return nil
+}
+
+// fakeAccessControl allows deleting tokens if canDelete is set.
+type fakeAccessControl struct {
+	types.AccessControl
+	canDelete bool
+}
+
+func (f *fakeAccessControl) CanDelete(apiOp *types.APIRequest, obj types.APIObject, schema *types.APISchema) error {
+	if f.canDelete {
+		return nil
+	}
+	return apierror.NewAPIError(validation.PermissionDenied, "can not delete tokens")
+}
+
+func newToken(name, userID string, mutate ...func(*v3.Token)) *v3.Token {
+	token := &v3.Token{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{tokens.UserIDLabel: userID},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		UserID: userID,
+	}
+	for _, f := range mutate {
+		f(token)
+	}
+	return token
+}
+
+func newTestStore() (*store, map[string]*v3.Token) {
+	userTokens := map[string]*v3.Token{}
+	for _, token := range []*v3.Token{
+		newToken("token-current", "u-alice"),
+		newToken("token-other", "u-alice"),
+		newToken("token-derived", "u-alice", func(t *v3.Token) { t.IsDerived = true }),
+		newToken("token-expired", "u-alice", func(t *v3.Token) { t.TTLMillis = time.Minute.Milliseconds() }),
+		newToken("token-bob", "u-bob"),
+	} {
+		userTokens[token.Name] = token
+	}
+	return &store{
+		tokens:      &fakeTokenClient{tokens: userTokens},
+		tokenLister: &fakeTokenCache{tokens: userTokens},
+	}, userTokens
+}
+
+// newRequest returns a request of u-alice, with the token-current session,
+// for the sessions of userID if it is set.
+func newRequest(userID string, canDelete bool) *types.APIRequest {
+	target := "/v1/sessions"
+	if userID != "" {
+		target += "?userId=" + userID
+	}
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set(tokens.AuthHeaderName, tokens.AuthValuePrefix+" token-current:secret")
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "u-alice"}))
+	apiSchemas := types.EmptyAPISchemas().MustAddSchema(types.APISchema{Schema: &schemas.Schema{ID: tokenSchema}})
+	return types.StoreAPIContext(&types.APIRequest{
+		Request:       req,
+		Schemas:       apiSchemas,
+		AccessControl: &fakeAccessControl{canDelete: canDelete},
+	})
+}
+
+func sessionNames(list types.APIObjectList) []string {
+	var names []string
+	for _, obj := range list.Objects {
+		names = append(names, obj.ID)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestList(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := newTestStore()
+
+	list, err := s.List(newRequest("", false), nil)
+	assert.Nil(err)
+	assert.Equal([]string{"token-current", "token-other"}, sessionNames(list), "derived and expired tokens are not sessions")
+	for _, obj := range list.Objects {
+		assert.Equal(obj.ID == "token-current", obj.Object.(*Session).Current)
+	}
+
+	list, err = s.List(newRequest("u-alice", false), nil)
+	assert.Nil(err)
+	assert.Equal([]string{"token-current", "token-other"}, sessionNames(list), "users list their own sessions by their ID")
+
+	_, err = s.List(newRequest("u-bob", false), nil)
+	assert.True(apierror.IsAPIError(err))
+	assert.Equal(validation.PermissionDenied, err.(*apierror.APIError).Code)
+
+	list, err = s.List(newRequest("u-bob", true), nil)
+	assert.Nil(err)
+	assert.Equal([]string{"token-bob"}, sessionNames(list), "users who can delete tokens list the sessions of others")
+}
+
+func TestByIDAndDelete(t *testing.T) {
+	assert := assert.New(t)
+	s, userTokens := newTestStore()
+
+	obj, err := s.ByID(newRequest("", false), nil, "token-other")
+	assert.Nil(err)
+	assert.Equal("u-alice", obj.Object.(*Session).UserID)
+
+	for _, name := range []string{"token-derived", "token-expired", "token-missing", "token-bob"} {
+		_, err := s.ByID(newRequest("", false), nil, name)
+		if assert.Error(err, name) {
+			assert.Equal(validation.NotFound, err.(*apierror.APIError).Code, name)
+		}
+	}
+
+	_, err = s.Delete(newRequest("", false), nil, "token-bob")
+	assert.Error(err)
+	assert.Contains(userTokens, "token-bob", "the sessions of others are not deleted")
+
+	_, err = s.Delete(newRequest("", true), nil, "token-bob")
+	assert.Nil(err)
+	assert.NotContains(userTokens, "token-bob")
+
+	_, err = s.Delete(newRequest("", false), nil, "token-other")
+	assert.Nil(err)
+	assert.NotContains(userTokens, "token-other")
+}
+
+func TestRevokeAll(t *testing.T) {
+	assert := assert.New(t)
+	s, userTokens := newTestStore()
+	userTokens["token-third"] = newToken("token-third", "u-alice")
+
+	rw := httptest.NewRecorder()
+	s.revokeAll(rw, newRequest("", false).Request)
+	assert.Equal(http.StatusOK, rw.Code)
+	var names []string
+	for name := range userTokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal([]string{"token-bob", "token-current", "token-derived", "token-expired"}, names, "the current session is kept")
+}
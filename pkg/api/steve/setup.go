@@ -5,6 +5,7 @@
 
 	"github.com/rancher/rancher/pkg/api/steve/catalog"
 	"github.com/rancher/rancher/pkg/api/steve/clusters"
+	"github.com/rancher/rancher/pkg/api/steve/sessions"
 	"github.com/rancher/rancher/pkg/api/steve/userpreferences"
 	"github.com/rancher/rancher/pkg/wrangler"
 	steve "github.com/rancher/steve/pkg/server"
@@ -12,6 +13,7 @@
 
 func Setup(ctx context.Context, server *steve.Server, config *wrangler.Context) error {
 	userpreferences.Register(server.BaseSchemas, server.ClientFactory)
+	sessions.Register(server.BaseSchemas, config.Mgmt.Token())
 	if err := clusters.Register(ctx, server); err != nil {
 		return err
 	}
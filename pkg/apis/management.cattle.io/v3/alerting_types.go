@@ -350,16 +350,18 @@ func (n *Notifier) ObjClusterName() string {
 type NotifierSpec struct {
 	ClusterName string `json:"clusterName" norman:"type=reference[cluster]"`
 
-	DisplayName     string           `json:"displayName,omitempty" norman:"required"`
-	Description     string           `json:"description,omitempty"`
-	SendResolved    bool             `json:"sendResolved,omitempty"`
-	SMTPConfig      *SMTPConfig      `json:"smtpConfig,omitempty"`
-	SlackConfig     *SlackConfig     `json:"slackConfig,omitempty"`
-	PagerdutyConfig *PagerdutyConfig `json:"pagerdutyConfig,omitempty"`
-	WebhookConfig   *WebhookConfig   `json:"webhookConfig,omitempty"`
-	WechatConfig    *WechatConfig    `json:"wechatConfig,omitempty"`
-	DingtalkConfig  *DingtalkConfig  `json:"dingtalkConfig,omitempty"`
-	MSTeamsConfig   *MSTeamsConfig   `json:"msteamsConfig,omitempty"`
+	DisplayName     string             `json:"displayName,omitempty" norman:"required"`
+	Description     string             `json:"description,omitempty"`
+	SendResolved    bool               `json:"sendResolved,omitempty"`
+	SMTPConfig      *SMTPConfig        `json:"smtpConfig,omitempty"`
+	SlackConfig     *SlackConfig       `json:"slackConfig,omitempty"`
+	PagerdutyConfig *PagerdutyConfig   `json:"pagerdutyConfig,omitempty"`
+	WebhookConfig   *WebhookConfig     `json:"webhookConfig,omitempty"`
+	WechatConfig    *WechatConfig      `json:"wechatConfig,omitempty"`
+	DingtalkConfig  *DingtalkConfig    `json:"dingtalkConfig,omitempty"`
+	MSTeamsConfig   *MSTeamsConfig     `json:"msteamsConfig,omitempty"`
+	Templates       *NotifierTemplates `json:"templates,omitempty"`
+	Policy          *NotifierPolicy    `json:"policy,omitempty"`
 }
 
 func (n *NotifierSpec) ObjClusterName() string {
@@ -367,14 +369,65 @@ func (n *NotifierSpec) ObjClusterName() string {
 }
 
 type Notification struct {
-	Message         string           `json:"message,omitempty"`
-	SMTPConfig      *SMTPConfig      `json:"smtpConfig,omitempty"`
-	SlackConfig     *SlackConfig     `json:"slackConfig,omitempty"`
-	PagerdutyConfig *PagerdutyConfig `json:"pagerdutyConfig,omitempty"`
-	WebhookConfig   *WebhookConfig   `json:"webhookConfig,omitempty"`
-	WechatConfig    *WechatConfig    `json:"wechatConfig,omitempty"`
-	DingtalkConfig  *DingtalkConfig  `json:"dingtalkConfig,omitempty"`
-	MSTeamsConfig   *MSTeamsConfig   `json:"msteamsConfig,omitempty"`
+	Message         string             `json:"message,omitempty"`
+	SMTPConfig      *SMTPConfig        `json:"smtpConfig,omitempty"`
+	SlackConfig     *SlackConfig       `json:"slackConfig,omitempty"`
+	PagerdutyConfig *PagerdutyConfig   `json:"pagerdutyConfig,omitempty"`
+	WebhookConfig   *WebhookConfig     `json:"webhookConfig,omitempty"`
+	WechatConfig    *WechatConfig      `json:"wechatConfig,omitempty"`
+	DingtalkConfig  *DingtalkConfig    `json:"dingtalkConfig,omitempty"`
+	MSTeamsConfig   *MSTeamsConfig     `json:"msteamsConfig,omitempty"`
+	Templates       *NotifierTemplates `json:"templates,omitempty"`
+}
+
+// NotifierTemplates are Go text/template templates that replace the default
+// title and content of the messages sent by a notifier. They are executed
+// against the alertmanager notification data (Status, Alerts, GroupLabels,
+// CommonLabels, CommonAnnotations), so alert labels such as alert_name,
+// severity, cluster_name, project_name and rule_id are available. Webhook,
+// DingTalk and Microsoft Teams notifiers receive alerts without a rendered
+// message and do not accept templates. The templates of a Notification
+// replace those of the notifier for the message being sent.
+type NotifierTemplates struct {
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// NotifierPolicy limits the messages a notifier delivers. Messages sent by
+// Rancher itself are suppressed when they break the rate limit or are
+// duplicates, and held until the end of quiet hours. Alerts delivered by
+// alertmanager are batched by raising the group and repeat intervals of the
+// routes using the notifier. During quiet hours the notifier is left out of
+// the alertmanager receivers, which keep their routes so that alerts still
+// firing are delivered once the quiet hours end; the alerts held back are
+// counted on the notifier status.
+type NotifierPolicy struct {
+	RateLimit     *NotifierRateLimit     `json:"rateLimit,omitempty"`
+	Deduplication *NotifierDeduplication `json:"deduplication,omitempty"`
+	QuietHours    []QuietHours           `json:"quietHours,omitempty"`
+}
+
+// NotifierRateLimit allows at most MaxMessages messages per window.
+type NotifierRateLimit struct {
+	MaxMessages   int `json:"maxMessages,omitempty" norman:"required,min=1"`
+	WindowSeconds int `json:"windowSeconds,omitempty" norman:"required,min=1"`
+}
+
+// NotifierDeduplication drops messages for an alert fingerprint that was
+// already notified within the window.
+type NotifierDeduplication struct {
+	WindowSeconds int `json:"windowSeconds,omitempty" norman:"required,min=1"`
+}
+
+// QuietHours is a recurring window during which the notifier is muted. Start
+// and End are HH:MM in Timezone; a window whose End is before its Start ends
+// on the next day. Days restricts the window to the given weekdays (as
+// Mon, Tue, ...), based on the day the window starts.
+type QuietHours struct {
+	Start    string   `json:"start,omitempty" norman:"required"`
+	End      string   `json:"end,omitempty" norman:"required"`
+	Timezone string   `json:"timezone,omitempty" norman:"default=UTC"`
+	Days     []string `json:"days,omitempty"`
 }
 
 type SMTPConfig struct {
@@ -425,6 +478,37 @@ type MSTeamsConfig struct {
 }
 
 type NotifierStatus struct {
+	SuppressedCount      int64  `json:"suppressedCount,omitempty"`
+	LastSuppressedTime   string `json:"lastSuppressedTime,omitempty"`
+	LastSuppressedReason string `json:"lastSuppressedReason,omitempty"`
+	// PolicyState is the delivery history the policy is enforced with. It is
+	// kept on the notifier so that all Rancher replicas enforce the same
+	// limits and restarts do not reset them.
+	PolicyState *NotifierPolicyState `json:"policyState,omitempty"`
+}
+
+// NotifierPolicyState records the messages a notifier sent and held back.
+// Times are RFC3339.
+type NotifierPolicyState struct {
+	// SentTimes are the times of the messages sent within the rate limit window.
+	SentTimes []string `json:"sentTimes,omitempty"`
+	// Fingerprints maps the messages sent within the deduplication window to
+	// the time they were sent.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+	// HeldMessages are sent when the quiet hours they were held in end.
+	HeldMessages []HeldNotification `json:"heldMessages,omitempty"`
+	// MutedAlerts are the alertmanager fingerprints of the alerts held back
+	// during the current quiet hours, so that each is counted once.
+	MutedAlerts []string `json:"mutedAlerts,omitempty"`
+}
+
+// HeldNotification is a rendered message held back during quiet hours.
+type HeldNotification struct {
+	Recipient   string `json:"recipient,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Content     string `json:"content,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	HeldTime    string `json:"heldTime,omitempty"`
 }
 
 // HTTPClientConfig configures an HTTP client.
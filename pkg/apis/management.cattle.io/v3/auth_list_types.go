@@ -74,6 +74,16 @@ type LocalConfigList struct {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// OIDCConfigList is a list of OIDCConfig resources
+type OIDCConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []OIDCConfig `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // SamlConfigList is a list of SamlConfig resources
 type SamlConfigList struct {
 	metav1.TypeMeta `json:",inline"`
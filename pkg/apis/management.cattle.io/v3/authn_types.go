@@ -7,7 +7,10 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const UserConditionInitialRolesPopulated condition.Cond = "InitialRolesPopulated"
+const (
+	UserConditionInitialRolesPopulated condition.Cond = "InitialRolesPopulated"
+	UserConditionLocked                condition.Cond = "Locked"
+)
 
 // +genclient
 // +genclient:nonNamespaced
@@ -32,12 +35,45 @@ type Token struct {
 	Current         bool              `json:"current"`
 	ClusterName     string            `json:"clusterName,omitempty" norman:"noupdate,type=reference[cluster]"`
 	Enabled         *bool             `json:"enabled,omitempty" norman:"default=true"`
+	ClientIP        string            `json:"clientIp,omitempty" norman:"nocreate,noupdate"`
+	UserAgent       string            `json:"userAgent,omitempty" norman:"nocreate,noupdate"`
+	LastUsedAt      string            `json:"lastUsedAt,omitempty" norman:"nocreate,noupdate"`
+	// Scopes restrict the requests the token can be used for, on top of the
+	// permissions of its user
+	Scopes *TokenScopes `json:"scopes,omitempty" norman:"noupdate"`
 }
 
 func (t *Token) ObjClusterName() string {
 	return t.ClusterName
 }
 
+// TokenScopes restrict a token to requests matching its rules, to the
+// namespaces of its projects and namespaces, and to reading only. Requests
+// that are not for API resources, such as discovery, are allowed for reading.
+type TokenScopes struct {
+	// Rules allow requests matching any of them. Tokens without rules are not
+	// restricted by resource.
+	Rules []TokenScopeRule `json:"rules,omitempty"`
+	// ProjectIDs and Namespaces, if any is set, restrict the token to
+	// requests within the namespaces of these projects or these namespaces.
+	// Namespaces of downstream clusters accessed through the cluster proxy
+	// only match Namespaces.
+	ProjectIDs []string `json:"projectIds,omitempty" norman:"type=array[reference[project]]"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ReadOnly restricts the token to the get, list and watch verbs
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// TokenScopeRule matches requests like the rules of a Kubernetes Role: by
+// API group, resource and verb, with * matching any value. Norman API
+// resources belong to the management.cattle.io, cluster.cattle.io and
+// project.cattle.io groups and their actions are the update verb.
+type TokenScopeRule struct {
+	APIGroups []string `json:"apiGroups,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+	Verbs     []string `json:"verbs,omitempty"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -50,10 +86,14 @@ type User struct {
 	Description        string     `json:"description"`
 	Username           string     `json:"username,omitempty"`
 	Password           string     `json:"password,omitempty" norman:"writeOnly,noupdate"`
+	PasswordHistory    []string   `json:"passwordHistory,omitempty" norman:"writeOnly,nocreate,noupdate"`
+	PasswordChangedAt  string     `json:"passwordChangedAt,omitempty" norman:"nocreate,noupdate"`
 	MustChangePassword bool       `json:"mustChangePassword,omitempty"`
 	PrincipalIDs       []string   `json:"principalIds,omitempty" norman:"type=array[reference[principal]]"`
 	Me                 bool       `json:"me,omitempty" norman:"nocreate,noupdate"`
 	Enabled            *bool      `json:"enabled,omitempty" norman:"default=true"`
+	MFAEnabled         bool       `json:"mfaEnabled,omitempty" norman:"nocreate,noupdate"`
+	MFARecoveryCodes   []string   `json:"mfaRecoveryCodes,omitempty" norman:"writeOnly,nocreate,noupdate"`
 	Spec               UserSpec   `json:"spec,omitempty"`
 	Status             UserStatus `json:"status"`
 }
@@ -90,6 +130,9 @@ type UserAttribute struct {
 
 	UserName        string
 	GroupPrincipals map[string]Principals // the value is a []Principal, but code generator cannot handle slice as a value
+	// ExtraByProvider are the attributes the auth providers told on the
+	// last login of the user, such as the attributes of SAML assertions
+	ExtraByProvider map[string]map[string][]string
 	LastRefresh     string
 	NeedsRefresh    bool
 }
@@ -154,6 +197,19 @@ type SetPasswordInput struct {
 	NewPassword string `json:"newPassword" norman:"type=string,required"`
 }
 
+type MFACodeInput struct {
+	// Code is a code of the authenticator or a recovery code
+	Code string `json:"code" norman:"type=string,required"`
+}
+
+// MFAEnrollment is what a user needs to add Rancher to their authenticator.
+// The recovery codes are only ever shown here.
+type MFAEnrollment struct {
+	Secret          string   `json:"secret,omitempty"`
+	ProvisioningURI string   `json:"provisioningUri,omitempty"`
+	RecoveryCodes   []string `json:"recoveryCodes,omitempty"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -239,6 +295,37 @@ type GoogleOauthConfigApplyInput struct {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+type OIDCConfig struct {
+	AuthConfig `json:",inline" mapstructure:",squash"`
+
+	Issuer       string `json:"issuer,omitempty" norman:"required,notnullable"`
+	ClientID     string `json:"clientId,omitempty" norman:"required,notnullable"`
+	ClientSecret string `json:"clientSecret,omitempty" norman:"type=password"`
+	RancherURL   string `json:"rancherUrl,omitempty" norman:"required,notnullable"`
+	Scope        string `json:"scope,omitempty" norman:"default=openid profile email groups offline_access,notnullable"`
+	// Certificate is a PEM encoded CA bundle to verify the issuer with
+	Certificate string `json:"certificate,omitempty"`
+	// UsernameClaim, DisplayNameClaim and GroupsClaim name the claims of the
+	// ID token or user info holding the login name, display name and groups
+	// of users
+	UsernameClaim    string `json:"usernameClaim,omitempty" norman:"default=preferred_username,notnullable"`
+	DisplayNameClaim string `json:"displayNameClaim,omitempty" norman:"default=name,notnullable"`
+	GroupsClaim      string `json:"groupsClaim,omitempty" norman:"default=groups,notnullable"`
+}
+
+type OIDCConfigTestOutput struct {
+	RedirectURL string `json:"redirectUrl"`
+}
+
+type OIDCConfigApplyInput struct {
+	OIDCConfig   OIDCConfig `json:"oidcConfig,omitempty"`
+	Code         string     `json:"code,omitempty"`
+	CodeVerifier string     `json:"codeVerifier,omitempty"`
+	Enabled      bool       `json:"enabled,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 type AzureADConfig struct {
 	AuthConfig `json:",inline" mapstructure:",squash"`
 
@@ -373,6 +460,15 @@ type SamlConfig struct {
 	UIDField           string `json:"uidField"           norman:"required"`
 	RancherAPIHost     string `json:"rancherApiHost"     norman:"required"`
 	EntityID           string `json:"entityID"`
+	// SingleLogoutEnabled ends the session at the identity provider when
+	// users log out of Rancher, and lets the identity provider log users out
+	// of Rancher.
+	SingleLogoutEnabled bool `json:"singleLogoutEnabled,omitempty"`
+	// IDPInitiatedLoginEnabled accepts logins started at the identity
+	// provider rather than by Rancher.
+	IDPInitiatedLoginEnabled bool `json:"idpInitiatedLoginEnabled,omitempty"`
+	// SignAuthnRequests signs authentication requests with the SP key.
+	SignAuthnRequests bool `json:"signAuthnRequests,omitempty"`
 }
 
 type SamlConfigTestInput struct {
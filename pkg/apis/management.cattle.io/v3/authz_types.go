@@ -190,6 +190,48 @@ func (c *ClusterRoleTemplateBinding) ObjClusterName() string {
 	return c.ClusterName
 }
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PrincipalMappingRule binds roles to the users whose principals match it. The
+// bindings are kept in sync with the group principals of the users.
+type PrincipalMappingRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Provider limits the rule to the principals of an auth provider.
+	Provider string `json:"provider,omitempty"`
+	// GroupPrincipalPatterns match the group principal IDs of users, such as
+	// "okta_group://eng-*". Patterns may use * and ? wildcards.
+	GroupPrincipalPatterns []string `json:"groupPrincipalPatterns,omitempty"`
+	// UserPrincipalPatterns match the user principal IDs of users, such as
+	// "openldap_user://*,ou=admins,dc=example,dc=com" for an LDAP DN suffix.
+	UserPrincipalPatterns []string `json:"userPrincipalPatterns,omitempty"`
+	// UserAttributePatterns match the attributes auth providers tell on
+	// login, such as the attributes of SAML assertions, keyed by attribute
+	// name. A rule matches users with any value of an attribute matching
+	// any of its patterns.
+	UserAttributePatterns map[string][]string  `json:"userAttributePatterns,omitempty"`
+	GlobalRoleNames       []string             `json:"globalRoleNames,omitempty" norman:"type=array[reference[globalRole]]"`
+	ClusterRoles          []ClusterRoleMapping `json:"clusterRoles,omitempty"`
+	ProjectRoles          []ProjectRoleMapping `json:"projectRoles,omitempty"`
+}
+
+type ClusterRoleMapping struct {
+	RoleTemplateName string `json:"roleTemplateName,omitempty" norman:"required,type=reference[roleTemplate]"`
+	// ClusterSelector selects the clusters by their labels. An empty selector
+	// selects all clusters.
+	ClusterSelector map[string]string `json:"clusterSelector,omitempty"`
+}
+
+type ProjectRoleMapping struct {
+	RoleTemplateName string `json:"roleTemplateName,omitempty" norman:"required,type=reference[roleTemplate]"`
+	ProjectName      string `json:"projectName,omitempty" norman:"required,type=reference[project]"`
+}
+
 type SetPodSecurityPolicyTemplateInput struct {
 	PodSecurityPolicyTemplateName string `json:"podSecurityPolicyTemplateId" norman:"required,type=reference[podSecurityPolicyTemplate]"`
 }
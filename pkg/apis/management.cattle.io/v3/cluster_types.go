@@ -69,6 +69,8 @@ func init() {
 	ClusterConditionPrometheusOperatorDeployed condition.Cond = "PrometheusOperatorDeployed"
 	ClusterConditionMonitoringEnabled          condition.Cond = "MonitoringEnabled"
 	ClusterConditionAlertingEnabled            condition.Cond = "AlertingEnabled"
+	// ClusterConditionEtcdBackupVerified is false when the verification of the latest recurring etcd backup failed
+	ClusterConditionEtcdBackupVerified condition.Cond = "EtcdBackupVerified"
 
 	ClusterDriverImported = "imported"
 	ClusterDriverLocal    = "local"
@@ -112,6 +114,7 @@ type ClusterSpecBase struct {
 	WindowsPreferedCluster               bool                                    `json:"windowsPreferedCluster" norman:"noupdate"`
 	LocalClusterAuthEndpoint             LocalClusterAuthEndpoint                `json:"localClusterAuthEndpoint,omitempty"`
 	ScheduledClusterScan                 *ScheduledClusterScan                   `json:"scheduledClusterScan,omitempty"`
+	EtcdBackupConfig                     *EtcdBackupConfig                       `json:"etcdBackupConfig,omitempty"`
 }
 
 type ClusterSpec struct {
@@ -172,6 +175,7 @@ type ClusterStatus struct {
 	ScheduledClusterScanStatus           *ScheduledClusterScanStatus `json:"scheduledClusterScanStatus,omitempty"`
 	CurrentCisRunName                    string                      `json:"currentCisRunName,omitempty"`
 	EKSStatus                            EKSStatus                   `json:"eksStatus,omitempty" norman:"nocreate,noupdate"`
+	EtcdBackupRetentionStatus            *EtcdBackupRetentionStatus  `json:"etcdBackupRetentionStatus,omitempty" norman:"nocreate,noupdate"`
 }
 
 type ClusterComponentStatus struct {
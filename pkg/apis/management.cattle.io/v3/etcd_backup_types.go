@@ -0,0 +1,130 @@
+package v3
+
+// EtcdBackupConfig holds the rancher side options of recurring etcd backups,
+// complementing the backup config of the rke cluster.
+type EtcdBackupConfig struct {
+	// Verification test-restores each recurring backup after it completes
+	Verification *EtcdBackupVerification `yaml:"verification,omitempty" json:"verification,omitempty"`
+	// Encryption encrypts snapshots stored on S3 with keys from a secret. rke
+	// then only keeps snapshots on the etcd nodes, rancher uploads them once
+	// they are encrypted.
+	Encryption *EtcdBackupEncryption `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+	// Targets snapshots are replicated to
+	Targets []EtcdBackupTarget `yaml:"targets,omitempty" json:"targets,omitempty"`
+	// PrimaryTarget names the target snapshots are stored on if the rke backup
+	// config has no S3 config. rke then only keeps snapshots on the etcd nodes,
+	// rancher uploads them to the target and replicates them to the others.
+	PrimaryTarget string `yaml:"primary_target,omitempty" json:"primaryTarget,omitempty"`
+	// Retention replaces the retention of the rke backup config
+	Retention *EtcdBackupRetention `yaml:"retention,omitempty" json:"retention,omitempty"`
+}
+
+type EtcdBackupVerification struct {
+	// Enable or disable verification of recurring backups
+	Enabled bool `yaml:"enabled" json:"enabled,omitempty" norman:"default=false"`
+	// Verification timeout in seconds
+	Timeout int `yaml:"timeout" json:"timeout,omitempty" norman:"default=300"`
+}
+
+type EtcdBackupEncryption struct {
+	// Secret holding the key encryption keys by ID, in namespace:name format
+	SecretName string `yaml:"secret_name" json:"secretName,omitempty" norman:"required"`
+	// ID of the key new snapshots are encrypted with, may be empty if the secret holds a single key
+	ActiveKey string `yaml:"active_key" json:"activeKey,omitempty"`
+}
+
+// EtcdBackupEncryptionStatus describes how the snapshot of a backup is
+// encrypted. The data key is only stored sealed with the key named by KeyID.
+type EtcdBackupEncryptionStatus struct {
+	Algorithm   string `json:"algorithm,omitempty"`
+	KeyID       string `json:"keyId,omitempty"`
+	SealedKey   string `json:"sealedKey,omitempty"`
+	NoncePrefix string `json:"noncePrefix,omitempty"`
+	ChunkSize   int    `json:"chunkSize,omitempty"`
+}
+
+// EtcdBackupTarget is a location snapshots are replicated to once they are
+// stored on S3 or on the primary target. Replicas are removed with their
+// backup and can be restored from when the primary copy is gone.
+type EtcdBackupTarget struct {
+	// Name of the target, unique within the cluster
+	Name string `yaml:"name" json:"name" norman:"required"`
+	// Azure Blob Storage target
+	AzureBlobConfig *AzureBlobBackupConfig `yaml:"azure_blob_config,omitempty" json:"azureBlobConfig,omitempty"`
+	// Google Cloud Storage target
+	GCSConfig *GCSBackupConfig `yaml:"gcs_config,omitempty" json:"gcsConfig,omitempty"`
+	// Filesystem target, such as an NFS volume mounted into the rancher server
+	FilesystemConfig *FilesystemBackupConfig `yaml:"filesystem_config,omitempty" json:"filesystemConfig,omitempty"`
+}
+
+type AzureBlobBackupConfig struct {
+	// Storage account name
+	AccountName string `yaml:"account_name" json:"accountName,omitempty" norman:"required"`
+	// Storage account key
+	AccountKey string `yaml:"account_key" json:"accountKey,omitempty" norman:"type=password"`
+	// Name of the container to use for backup
+	Container string `yaml:"container" json:"container,omitempty" norman:"required"`
+	// Folder to place the files
+	Folder string `yaml:"folder" json:"folder,omitempty"`
+	// Endpoint suffix of sovereign clouds, defaults to core.windows.net
+	EndpointSuffix string `yaml:"endpoint_suffix" json:"endpointSuffix,omitempty"`
+	// Address of the storage emulator, only used with the devstoreaccount1 account
+	EmulatorAddress string `yaml:"emulator_address" json:"emulatorAddress,omitempty"`
+}
+
+type GCSBackupConfig struct {
+	// Name of the bucket to use for backup
+	Bucket string `yaml:"bucket" json:"bucket,omitempty" norman:"required"`
+	// Folder to place the files
+	Folder string `yaml:"folder" json:"folder,omitempty"`
+	// Service account key in JSON format, application default credentials are used if empty
+	ServiceAccountKey string `yaml:"service_account_key" json:"serviceAccountKey,omitempty" norman:"type=password"`
+	// Endpoint is used if this is not the Google API
+	Endpoint string `yaml:"endpoint" json:"endpoint,omitempty"`
+}
+
+type FilesystemBackupConfig struct {
+	// Directory to place the files, must be available to all rancher servers
+	Path string `yaml:"path" json:"path,omitempty" norman:"required"`
+}
+
+// EtcdBackupRetention is a grandfather-father-son retention policy: the
+// newest recurring backup of each of the last Hourly hours, Daily days,
+// Weekly weeks and Monthly months is kept. If all of them are 0, recurring
+// backups are kept for retention times interval hours of the rke backup
+// config.
+type EtcdBackupRetention struct {
+	// Number of hours to keep the newest backup of
+	Hourly int `yaml:"hourly" json:"hourly,omitempty" norman:"min=0"`
+	// Number of days to keep the newest backup of
+	Daily int `yaml:"daily" json:"daily,omitempty" norman:"min=0"`
+	// Number of weeks to keep the newest backup of
+	Weekly int `yaml:"weekly" json:"weekly,omitempty" norman:"min=0"`
+	// Number of months to keep the newest backup of
+	Monthly int `yaml:"monthly" json:"monthly,omitempty" norman:"min=0"`
+	// Retention of manual backups, which are kept forever if not set
+	Manual *EtcdBackupManualRetention `yaml:"manual,omitempty" json:"manual,omitempty"`
+}
+
+type EtcdBackupManualRetention struct {
+	// Number of newest manual backups to keep, 0 keeps all
+	Count int `yaml:"count" json:"count,omitempty" norman:"min=0"`
+	// Age in hours after which manual backups are deleted, 0 keeps them regardless of age
+	MaxAgeHours int `yaml:"max_age_hours" json:"maxAgeHours,omitempty" norman:"min=0"`
+}
+
+// EtcdBackupRetentionStatus describes the retention applied to the backups of
+// a cluster.
+type EtcdBackupRetentionStatus struct {
+	// Policy is a summary of the retention in effect
+	Policy string `json:"policy,omitempty"`
+	// NextDeletions are the backups due to be deleted next, soonest first
+	NextDeletions []EtcdBackupDeletion `json:"nextDeletions,omitempty"`
+}
+
+type EtcdBackupDeletion struct {
+	BackupName string `json:"backupName,omitempty"`
+	Manual     bool   `json:"manual,omitempty"`
+	// Time the backup is deleted at the latest, in RFC3339 format
+	PlannedTime string `json:"plannedTime,omitempty"`
+}
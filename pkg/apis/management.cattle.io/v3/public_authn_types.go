@@ -39,6 +39,25 @@ type BasicLogin struct {
 	Password     string `json:"password" norman:"type=string,required"`
 }
 
+// MFAChallenge is returned instead of a token by the login of local users who
+// must pass a second factor, which they send along with the challenge ID in
+// an MFALogin.
+type MFAChallenge struct {
+	ChallengeID string `json:"challengeId"`
+	ExpiresAt   string `json:"expiresAt"`
+	// Enroll is set if the user must enroll the authenticator of the
+	// enrollment before logging in
+	Enroll        bool `json:"enroll,omitempty"`
+	MFAEnrollment `json:",inline"`
+}
+
+type MFALogin struct {
+	GenericLogin `json:",inline"`
+	ChallengeID  string `json:"challengeId" norman:"type=string,required"`
+	// Code is a code of the authenticator or a recovery code
+	Code string `json:"code" norman:"type=string,required"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -83,6 +102,22 @@ type GoogleOauthLogin struct {
 	Code         string `json:"code" norman:"type=string,required"`
 }
 
+type OIDCProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	AuthProvider      `json:",inline"`
+
+	// RedirectURL is the authorization URL of the issuer, missing the state
+	// and the code_challenge of PKCE
+	RedirectURL string `json:"redirectUrl"`
+}
+
+type OIDCLogin struct {
+	GenericLogin `json:",inline"`
+	Code         string `json:"code" norman:"type=string,required"`
+	CodeVerifier string `json:"codeVerifier" norman:"type=string,required"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -117,6 +152,10 @@ type SamlProvider struct {
 	AuthProvider      `json:",inline"`
 
 	RedirectURL string `json:"redirectUrl"`
+	// LogoutURL is set when single logout is enabled. Browsers post a form to
+	// it, with the CSRF cookie in the csrf field, to log out of both Rancher
+	// and the identity provider.
+	LogoutURL string `json:"logoutUrl,omitempty"`
 }
 
 type AzureADLogin struct {
@@ -18,7 +18,17 @@ type EtcdBackup struct {
 	// backup spec
 	Spec rketypes.EtcdBackupSpec `json:"spec"`
 	// backup status
-	Status rketypes.EtcdBackupStatus `yaml:"status" json:"status,omitempty"`
+	Status EtcdBackupStatus `yaml:"status" json:"status,omitempty"`
+}
+
+// EtcdBackupStatus extends the rke backup status with the state of the
+// rancher side backup options.
+type EtcdBackupStatus struct {
+	rketypes.EtcdBackupStatus `yaml:",inline" json:",inline"`
+	// Encryption describes how the snapshot is encrypted, it holds no secret material
+	Encryption *EtcdBackupEncryptionStatus `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+	// Target is the name of the backup target holding the snapshot in place of S3
+	Target string `yaml:"target,omitempty" json:"target,omitempty"`
 }
 
 // +genclient
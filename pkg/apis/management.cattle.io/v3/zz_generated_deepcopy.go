@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 /*
@@ -744,6 +745,22 @@ func (in *AzureADProviderList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureBlobBackupConfig) DeepCopyInto(out *AzureBlobBackupConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureBlobBackupConfig.
+func (in *AzureBlobBackupConfig) DeepCopy() *AzureBlobBackupConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureBlobBackupConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BasicLogin) DeepCopyInto(out *BasicLogin) {
 	*out = *in
@@ -2089,6 +2106,29 @@ func (in *ClusterRegistrationTokenStatus) DeepCopy() *ClusterRegistrationTokenSt
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRoleMapping) DeepCopyInto(out *ClusterRoleMapping) {
+	*out = *in
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRoleMapping.
+func (in *ClusterRoleMapping) DeepCopy() *ClusterRoleMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRoleMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterRoleTemplateBinding) DeepCopyInto(out *ClusterRoleTemplateBinding) {
 	*out = *in
@@ -2394,6 +2434,11 @@ func (in *ClusterSpecBase) DeepCopyInto(out *ClusterSpecBase) {
 		*out = new(ScheduledClusterScan)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.EtcdBackupConfig != nil {
+		in, out := &in.EtcdBackupConfig, &out.EtcdBackupConfig
+		*out = new(EtcdBackupConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -2494,6 +2539,11 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		**out = **in
 	}
 	in.EKSStatus.DeepCopyInto(&out.EKSStatus)
+	if in.EtcdBackupRetentionStatus != nil {
+		in, out := &in.EtcdBackupRetentionStatus, &out.EtcdBackupRetentionStatus
+		*out = new(EtcdBackupRetentionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -3300,6 +3350,92 @@ func (in *EtcdBackup) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupConfig) DeepCopyInto(out *EtcdBackupConfig) {
+	*out = *in
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(EtcdBackupVerification)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(EtcdBackupEncryption)
+		**out = **in
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]EtcdBackupTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(EtcdBackupRetention)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupConfig.
+func (in *EtcdBackupConfig) DeepCopy() *EtcdBackupConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupDeletion) DeepCopyInto(out *EtcdBackupDeletion) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupDeletion.
+func (in *EtcdBackupDeletion) DeepCopy() *EtcdBackupDeletion {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupDeletion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupEncryption) DeepCopyInto(out *EtcdBackupEncryption) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupEncryption.
+func (in *EtcdBackupEncryption) DeepCopy() *EtcdBackupEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupEncryptionStatus) DeepCopyInto(out *EtcdBackupEncryptionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupEncryptionStatus.
+func (in *EtcdBackupEncryptionStatus) DeepCopy() *EtcdBackupEncryptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupEncryptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EtcdBackupList) DeepCopyInto(out *EtcdBackupList) {
 	*out = *in
@@ -3333,6 +3469,133 @@ func (in *EtcdBackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupManualRetention) DeepCopyInto(out *EtcdBackupManualRetention) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupManualRetention.
+func (in *EtcdBackupManualRetention) DeepCopy() *EtcdBackupManualRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupManualRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupRetention) DeepCopyInto(out *EtcdBackupRetention) {
+	*out = *in
+	if in.Manual != nil {
+		in, out := &in.Manual, &out.Manual
+		*out = new(EtcdBackupManualRetention)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupRetention.
+func (in *EtcdBackupRetention) DeepCopy() *EtcdBackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupRetentionStatus) DeepCopyInto(out *EtcdBackupRetentionStatus) {
+	*out = *in
+	if in.NextDeletions != nil {
+		in, out := &in.NextDeletions, &out.NextDeletions
+		*out = make([]EtcdBackupDeletion, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupRetentionStatus.
+func (in *EtcdBackupRetentionStatus) DeepCopy() *EtcdBackupRetentionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupRetentionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupStatus) DeepCopyInto(out *EtcdBackupStatus) {
+	*out = *in
+	in.EtcdBackupStatus.DeepCopyInto(&out.EtcdBackupStatus)
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(EtcdBackupEncryptionStatus)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupStatus.
+func (in *EtcdBackupStatus) DeepCopy() *EtcdBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupTarget) DeepCopyInto(out *EtcdBackupTarget) {
+	*out = *in
+	if in.AzureBlobConfig != nil {
+		in, out := &in.AzureBlobConfig, &out.AzureBlobConfig
+		*out = new(AzureBlobBackupConfig)
+		**out = **in
+	}
+	if in.GCSConfig != nil {
+		in, out := &in.GCSConfig, &out.GCSConfig
+		*out = new(GCSBackupConfig)
+		**out = **in
+	}
+	if in.FilesystemConfig != nil {
+		in, out := &in.FilesystemConfig, &out.FilesystemConfig
+		*out = new(FilesystemBackupConfig)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupTarget.
+func (in *EtcdBackupTarget) DeepCopy() *EtcdBackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdBackupVerification) DeepCopyInto(out *EtcdBackupVerification) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdBackupVerification.
+func (in *EtcdBackupVerification) DeepCopy() *EtcdBackupVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdBackupVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventRule) DeepCopyInto(out *EventRule) {
 	*out = *in
@@ -3501,6 +3764,22 @@ func (in *File) DeepCopy() *File {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemBackupConfig) DeepCopyInto(out *FilesystemBackupConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemBackupConfig.
+func (in *FilesystemBackupConfig) DeepCopy() *FilesystemBackupConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemBackupConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Filter) DeepCopyInto(out *Filter) {
 	*out = *in
@@ -3734,6 +4013,22 @@ func (in *FreeIpaTestAndApplyInput) DeepCopy() *FreeIpaTestAndApplyInput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCSBackupConfig) DeepCopyInto(out *GCSBackupConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCSBackupConfig.
+func (in *GCSBackupConfig) DeepCopy() *GCSBackupConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GCSBackupConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GenerateKubeConfigOutput) DeepCopyInto(out *GenerateKubeConfigOutput) {
 	*out = *in
@@ -4596,6 +4891,22 @@ func (in *HTTPClientConfig) DeepCopy() *HTTPClientConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeldNotification) DeepCopyInto(out *HeldNotification) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeldNotification.
+func (in *HeldNotification) DeepCopy() *HeldNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(HeldNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImportClusterYamlInput) DeepCopyInto(out *ImportClusterYamlInput) {
 	*out = *in
@@ -5206,74 +5517,145 @@ func (in *LoggingTargets) DeepCopy() *LoggingTargets {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MSTeamsConfig) DeepCopyInto(out *MSTeamsConfig) {
+func (in *MFAChallenge) DeepCopyInto(out *MFAChallenge) {
 	*out = *in
-	if in.HTTPClientConfig != nil {
-		in, out := &in.HTTPClientConfig, &out.HTTPClientConfig
-		*out = new(HTTPClientConfig)
-		**out = **in
-	}
+	in.MFAEnrollment.DeepCopyInto(&out.MFAEnrollment)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MSTeamsConfig.
-func (in *MSTeamsConfig) DeepCopy() *MSTeamsConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MFAChallenge.
+func (in *MFAChallenge) DeepCopy() *MFAChallenge {
 	if in == nil {
 		return nil
 	}
-	out := new(MSTeamsConfig)
+	out := new(MFAChallenge)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MapDelta) DeepCopyInto(out *MapDelta) {
+func (in *MFACodeInput) DeepCopyInto(out *MFACodeInput) {
 	*out = *in
-	if in.Add != nil {
-		in, out := &in.Add, &out.Add
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Delete != nil {
-		in, out := &in.Delete, &out.Delete
-		*out = make(map[string]bool, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapDelta.
-func (in *MapDelta) DeepCopy() *MapDelta {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MFACodeInput.
+func (in *MFACodeInput) DeepCopy() *MFACodeInput {
 	if in == nil {
 		return nil
 	}
-	out := new(MapDelta)
+	out := new(MFACodeInput)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in MapStringInterface) DeepCopyInto(out *MapStringInterface) {
-	{
-		in := &in
-		clone := in.DeepCopy()
-		*out = *clone
-		return
-	}
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Member) DeepCopyInto(out *Member) {
+func (in *MFAEnrollment) DeepCopyInto(out *MFAEnrollment) {
 	*out = *in
+	if in.RecoveryCodes != nil {
+		in, out := &in.RecoveryCodes, &out.RecoveryCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Member.
-func (in *Member) DeepCopy() *Member {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MFAEnrollment.
+func (in *MFAEnrollment) DeepCopy() *MFAEnrollment {
+	if in == nil {
+		return nil
+	}
+	out := new(MFAEnrollment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MFALogin) DeepCopyInto(out *MFALogin) {
+	*out = *in
+	out.GenericLogin = in.GenericLogin
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MFALogin.
+func (in *MFALogin) DeepCopy() *MFALogin {
+	if in == nil {
+		return nil
+	}
+	out := new(MFALogin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MSTeamsConfig) DeepCopyInto(out *MSTeamsConfig) {
+	*out = *in
+	if in.HTTPClientConfig != nil {
+		in, out := &in.HTTPClientConfig, &out.HTTPClientConfig
+		*out = new(HTTPClientConfig)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MSTeamsConfig.
+func (in *MSTeamsConfig) DeepCopy() *MSTeamsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MSTeamsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapDelta) DeepCopyInto(out *MapDelta) {
+	*out = *in
+	if in.Add != nil {
+		in, out := &in.Add, &out.Add
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Delete != nil {
+		in, out := &in.Delete, &out.Delete
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapDelta.
+func (in *MapDelta) DeepCopy() *MapDelta {
+	if in == nil {
+		return nil
+	}
+	out := new(MapDelta)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in MapStringInterface) DeepCopyInto(out *MapStringInterface) {
+	{
+		in := &in
+		clone := in.DeepCopy()
+		*out = *clone
+		return
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Member) DeepCopyInto(out *Member) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Member.
+func (in *Member) DeepCopy() *Member {
 	if in == nil {
 		return nil
 	}
@@ -6394,6 +6776,11 @@ func (in *Notification) DeepCopyInto(out *Notification) {
 		*out = new(MSTeamsConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(NotifierTemplates)
+		**out = **in
+	}
 	return
 }
 
@@ -6414,7 +6801,7 @@ func (in *Notifier) DeepCopyInto(out *Notifier) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -6436,6 +6823,22 @@ func (in *Notifier) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierDeduplication) DeepCopyInto(out *NotifierDeduplication) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierDeduplication.
+func (in *NotifierDeduplication) DeepCopy() *NotifierDeduplication {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierDeduplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NotifierList) DeepCopyInto(out *NotifierList) {
 	*out = *in
@@ -6469,6 +6872,93 @@ func (in *NotifierList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierPolicy) DeepCopyInto(out *NotifierPolicy) {
+	*out = *in
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(NotifierRateLimit)
+		**out = **in
+	}
+	if in.Deduplication != nil {
+		in, out := &in.Deduplication, &out.Deduplication
+		*out = new(NotifierDeduplication)
+		**out = **in
+	}
+	if in.QuietHours != nil {
+		in, out := &in.QuietHours, &out.QuietHours
+		*out = make([]QuietHours, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierPolicy.
+func (in *NotifierPolicy) DeepCopy() *NotifierPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierPolicyState) DeepCopyInto(out *NotifierPolicyState) {
+	*out = *in
+	if in.SentTimes != nil {
+		in, out := &in.SentTimes, &out.SentTimes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Fingerprints != nil {
+		in, out := &in.Fingerprints, &out.Fingerprints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HeldMessages != nil {
+		in, out := &in.HeldMessages, &out.HeldMessages
+		*out = make([]HeldNotification, len(*in))
+		copy(*out, *in)
+	}
+	if in.MutedAlerts != nil {
+		in, out := &in.MutedAlerts, &out.MutedAlerts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierPolicyState.
+func (in *NotifierPolicyState) DeepCopy() *NotifierPolicyState {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierPolicyState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierRateLimit) DeepCopyInto(out *NotifierRateLimit) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierRateLimit.
+func (in *NotifierRateLimit) DeepCopy() *NotifierRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NotifierSpec) DeepCopyInto(out *NotifierSpec) {
 	*out = *in
@@ -6507,6 +6997,16 @@ func (in *NotifierSpec) DeepCopyInto(out *NotifierSpec) {
 		*out = new(MSTeamsConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(NotifierTemplates)
+		**out = **in
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(NotifierPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -6523,6 +7023,11 @@ func (in *NotifierSpec) DeepCopy() *NotifierSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NotifierStatus) DeepCopyInto(out *NotifierStatus) {
 	*out = *in
+	if in.PolicyState != nil {
+		in, out := &in.PolicyState, &out.PolicyState
+		*out = new(NotifierPolicyState)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -6536,6 +7041,149 @@ func (in *NotifierStatus) DeepCopy() *NotifierStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierTemplates) DeepCopyInto(out *NotifierTemplates) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierTemplates.
+func (in *NotifierTemplates) DeepCopy() *NotifierTemplates {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierTemplates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfig) DeepCopyInto(out *OIDCConfig) {
+	*out = *in
+	in.AuthConfig.DeepCopyInto(&out.AuthConfig)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfig.
+func (in *OIDCConfig) DeepCopy() *OIDCConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OIDCConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfigApplyInput) DeepCopyInto(out *OIDCConfigApplyInput) {
+	*out = *in
+	in.OIDCConfig.DeepCopyInto(&out.OIDCConfig)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfigApplyInput.
+func (in *OIDCConfigApplyInput) DeepCopy() *OIDCConfigApplyInput {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfigApplyInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfigList) DeepCopyInto(out *OIDCConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OIDCConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfigList.
+func (in *OIDCConfigList) DeepCopy() *OIDCConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OIDCConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfigTestOutput) DeepCopyInto(out *OIDCConfigTestOutput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfigTestOutput.
+func (in *OIDCConfigTestOutput) DeepCopy() *OIDCConfigTestOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfigTestOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCLogin) DeepCopyInto(out *OIDCLogin) {
+	*out = *in
+	out.GenericLogin = in.GenericLogin
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCLogin.
+func (in *OIDCLogin) DeepCopy() *OIDCLogin {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCLogin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCProvider) DeepCopyInto(out *OIDCProvider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.AuthProvider.DeepCopyInto(&out.AuthProvider)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCProvider.
+func (in *OIDCProvider) DeepCopy() *OIDCProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OKTAConfig) DeepCopyInto(out *OKTAConfig) {
 	*out = *in
@@ -6981,6 +7629,107 @@ func (in *PrincipalList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrincipalMappingRule) DeepCopyInto(out *PrincipalMappingRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.GroupPrincipalPatterns != nil {
+		in, out := &in.GroupPrincipalPatterns, &out.GroupPrincipalPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserPrincipalPatterns != nil {
+		in, out := &in.UserPrincipalPatterns, &out.UserPrincipalPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserAttributePatterns != nil {
+		in, out := &in.UserAttributePatterns, &out.UserAttributePatterns
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.GlobalRoleNames != nil {
+		in, out := &in.GlobalRoleNames, &out.GlobalRoleNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterRoles != nil {
+		in, out := &in.ClusterRoles, &out.ClusterRoles
+		*out = make([]ClusterRoleMapping, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProjectRoles != nil {
+		in, out := &in.ProjectRoles, &out.ProjectRoles
+		*out = make([]ProjectRoleMapping, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrincipalMappingRule.
+func (in *PrincipalMappingRule) DeepCopy() *PrincipalMappingRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PrincipalMappingRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrincipalMappingRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrincipalMappingRuleList) DeepCopyInto(out *PrincipalMappingRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PrincipalMappingRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrincipalMappingRuleList.
+func (in *PrincipalMappingRuleList) DeepCopy() *PrincipalMappingRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(PrincipalMappingRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrincipalMappingRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Principals) DeepCopyInto(out *Principals) {
 	*out = *in
@@ -7720,6 +8469,22 @@ func (in *ProjectResourceQuota) DeepCopy() *ProjectResourceQuota {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectRoleMapping) DeepCopyInto(out *ProjectRoleMapping) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectRoleMapping.
+func (in *ProjectRoleMapping) DeepCopy() *ProjectRoleMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectRoleMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectRoleTemplateBinding) DeepCopyInto(out *ProjectRoleTemplateBinding) {
 	*out = *in
@@ -8101,6 +8866,27 @@ func (in *Question) DeepCopy() *Question {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuietHours) DeepCopyInto(out *QuietHours) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuietHours.
+func (in *QuietHours) DeepCopy() *QuietHours {
+	if in == nil {
+		return nil
+	}
+	out := new(QuietHours)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Recipient) DeepCopyInto(out *Recipient) {
 	*out = *in
@@ -9557,6 +10343,11 @@ func (in *Token) DeepCopyInto(out *Token) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = new(TokenScopes)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -9611,6 +10402,70 @@ func (in *TokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenScopeRule) DeepCopyInto(out *TokenScopeRule) {
+	*out = *in
+	if in.APIGroups != nil {
+		in, out := &in.APIGroups, &out.APIGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenScopeRule.
+func (in *TokenScopeRule) DeepCopy() *TokenScopeRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenScopeRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenScopes) DeepCopyInto(out *TokenScopes) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]TokenScopeRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProjectIDs != nil {
+		in, out := &in.ProjectIDs, &out.ProjectIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenScopes.
+func (in *TokenScopes) DeepCopy() *TokenScopes {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenScopes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateGlobalDNSTargetsInput) DeepCopyInto(out *UpdateGlobalDNSTargetsInput) {
 	*out = *in
@@ -9686,6 +10541,11 @@ func (in *User) DeepCopyInto(out *User) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.PasswordHistory != nil {
+		in, out := &in.PasswordHistory, &out.PasswordHistory
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.PrincipalIDs != nil {
 		in, out := &in.PrincipalIDs, &out.PrincipalIDs
 		*out = make([]string, len(*in))
@@ -9696,6 +10556,11 @@ func (in *User) DeepCopyInto(out *User) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.MFARecoveryCodes != nil {
+		in, out := &in.MFARecoveryCodes, &out.MFARecoveryCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 	return
@@ -9731,6 +10596,31 @@ func (in *UserAttribute) DeepCopyInto(out *UserAttribute) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.ExtraByProvider != nil {
+		in, out := &in.ExtraByProvider, &out.ExtraByProvider
+		*out = make(map[string]map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal map[string][]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string][]string, len(*in))
+				for key, val := range *in {
+					var outVal []string
+					if val == nil {
+						(*out)[key] = nil
+					} else {
+						in, out := &val, &outVal
+						*out = make([]string, len(*in))
+						copy(*out, *in)
+					}
+					(*out)[key] = outVal
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 	return
 }
 
@@ -910,6 +910,23 @@ func NewPrincipal(namespace, name string, obj Principal) *Principal {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// PrincipalMappingRuleList is a list of PrincipalMappingRule resources
+type PrincipalMappingRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []PrincipalMappingRule `json:"items"`
+}
+
+func NewPrincipalMappingRule(namespace, name string, obj PrincipalMappingRule) *PrincipalMappingRule {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("PrincipalMappingRule").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ProjectList is a list of Project resources
 type ProjectList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -81,6 +81,7 @@
 	PodSecurityPolicyTemplateProjectBindingResourceName = "podsecuritypolicytemplateprojectbindings"
 	PreferenceResourceName                              = "preferences"
 	PrincipalResourceName                               = "principals"
+	PrincipalMappingRuleResourceName                    = "principalmappingrules"
 	ProjectResourceName                                 = "projects"
 	ProjectAlertResourceName                            = "projectalerts"
 	ProjectAlertGroupResourceName                       = "projectalertgroups"
@@ -232,6 +233,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&PreferenceList{},
 		&Principal{},
 		&PrincipalList{},
+		&PrincipalMappingRule{},
+		&PrincipalMappingRuleList{},
 		&Project{},
 		&ProjectList{},
 		&ProjectAlert{},
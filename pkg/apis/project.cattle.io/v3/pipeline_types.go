@@ -18,6 +18,7 @@
 	PipelineExecutionConditionInitialized condition.Cond = "Initialized"
 	PipelineExecutionConditionBuilt       condition.Cond = "Built"
 	PipelineExecutionConditionNotified    condition.Cond = "Notified"
+	PipelineExecutionConditionReported    condition.Cond = "Reported"
 )
 
 // +genclient
@@ -29,7 +30,7 @@ type SourceCodeProvider struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	ProjectName string `json:"projectName" norman:"type=reference[project]"`
-	Type        string `json:"type" norman:"options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	Type        string `json:"type" norman:"options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 }
 
 func (s *SourceCodeProvider) ObjClusterName() string {
@@ -65,6 +66,10 @@ type BitbucketServerProvider struct {
 	OauthProvider `json:",inline"`
 }
 
+type GiteaProvider struct {
+	OauthProvider `json:",inline"`
+}
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -75,7 +80,7 @@ type SourceCodeProviderConfig struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	ProjectName string `json:"projectName" norman:"required,type=reference[project]"`
-	Type        string `json:"type" norman:"noupdate,options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	Type        string `json:"type" norman:"noupdate,options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 	Enabled     bool   `json:"enabled,omitempty"`
 }
 
@@ -140,6 +145,19 @@ type BitbucketServerPipelineConfig struct {
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+type GiteaPipelineConfig struct {
+	SourceCodeProviderConfig `json:",inline" mapstructure:",squash"`
+
+	Hostname     string `json:"hostname,omitempty" norman:"noupdate"`
+	TLS          bool   `json:"tls,omitempty" norman:"notnullable,default=true" norman:"noupdate"`
+	ClientID     string `json:"clientId,omitempty" norman:"noupdate"`
+	ClientSecret string `json:"clientSecret,omitempty" norman:"noupdate,type=password"`
+	RedirectURL  string `json:"redirectUrl,omitempty" norman:"noupdate"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 type Pipeline struct {
 	types.Namespaced
 
@@ -315,6 +333,31 @@ type Step struct {
 	MemoryRequest string            `json:"memoryRequest,omitempty" yaml:"memoryRequest,omitempty"`
 	MemoryLimit   string            `json:"memoryLimit,omitempty" yaml:"memoryLimit,omitempty"`
 	When          *Constraints      `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// Cache and Artifacts are supported on run script steps of the kubernetes engine.
+	Cache     *StepCache `json:"cache,omitempty" yaml:"cache,omitempty"`
+	Artifacts []string   `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+
+	Matrix *StepMatrix `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+}
+
+// StepMatrix runs a step once for every combination of Values, with the values
+// of the combination set as environment variables of the step. A failed
+// combination lets the other steps of the stage finish unless FailFast is set.
+type StepMatrix struct {
+	Values   map[string][]string `json:"values,omitempty" yaml:"values,omitempty" norman:"required"`
+	FailFast bool                `json:"failFast,omitempty" yaml:"failFast,omitempty"`
+}
+
+// StepCache restores Paths of the workspace saved by a previous execution of
+// the pipeline before a step runs, and saves them after the step succeeds if
+// there was nothing to restore. The cache is looked up by Key and the
+// checksums of KeyFiles, so it is rebuilt whenever one of the files changes.
+// Paths and KeyFiles are relative to the workspace.
+type StepCache struct {
+	Key      string   `json:"key,omitempty" yaml:"key,omitempty" norman:"required"`
+	KeyFiles []string `json:"keyFiles,omitempty" yaml:"keyFiles,omitempty"`
+	Paths    []string `json:"paths,omitempty" yaml:"paths,omitempty" norman:"required"`
 }
 
 type Constraints struct {
@@ -402,6 +445,15 @@ type PipelineExecutionStatus struct {
 	Started        string        `json:"started,omitempty"`
 	Ended          string        `json:"ended,omitempty"`
 	Stages         []StageStatus `json:"stages,omitempty"`
+	Artifacts      []Artifact    `json:"artifacts,omitempty"`
+}
+
+// Artifact is a file uploaded by a step of an execution.
+type Artifact struct {
+	Name  string `json:"name,omitempty"`
+	Stage int    `json:"stage,omitempty"`
+	Step  int    `json:"step,omitempty"`
+	Size  int64  `json:"size,omitempty"`
 }
 
 type StageStatus struct {
@@ -419,7 +471,7 @@ type StepStatus struct {
 
 type SourceCodeCredentialSpec struct {
 	ProjectName    string `json:"projectName" norman:"type=reference[project]"`
-	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"required,options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"required,options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 	UserName       string `json:"userName" norman:"required,type=reference[user]"`
 	DisplayName    string `json:"displayName,omitempty" norman:"required"`
 	AvatarURL      string `json:"avatarUrl,omitempty"`
@@ -445,7 +497,7 @@ type SourceCodeCredentialStatus struct {
 
 type SourceCodeRepositorySpec struct {
 	ProjectName              string   `json:"projectName" norman:"type=reference[project]"`
-	SourceCodeType           string   `json:"sourceCodeType,omitempty" norman:"required,options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	SourceCodeType           string   `json:"sourceCodeType,omitempty" norman:"required,options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 	UserName                 string   `json:"userName" norman:"required,type=reference[user]"`
 	SourceCodeCredentialName string   `json:"sourceCodeCredentialName,omitempty" norman:"required,type=reference[sourceCodeCredential]"`
 	URL                      string   `json:"url,omitempty"`
@@ -476,7 +528,7 @@ type RunPipelineInput struct {
 
 type AuthAppInput struct {
 	InheritGlobal  bool   `json:"inheritGlobal,omitempty"`
-	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"type=string,required,options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"type=string,required,options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 	RedirectURL    string `json:"redirectUrl,omitempty" norman:"type=string"`
 	TLS            bool   `json:"tls,omitempty"`
 	Host           string `json:"host,omitempty"`
@@ -486,7 +538,7 @@ type AuthAppInput struct {
 }
 
 type AuthUserInput struct {
-	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"type=string,required,options=github|gitlab|bitbucketcloud|bitbucketserver"`
+	SourceCodeType string `json:"sourceCodeType,omitempty" norman:"type=string,required,options=github|gitlab|bitbucketcloud|bitbucketserver|gitea"`
 	RedirectURL    string `json:"redirectUrl,omitempty" norman:"type=string"`
 	Code           string `json:"code,omitempty" norman:"type=string,required"`
 }
@@ -528,6 +580,10 @@ type BitbucketCloudApplyInput struct {
 	OauthApplyInput
 }
 
+type GiteaApplyInput struct {
+	OauthApplyInput
+}
+
 type BitbucketServerApplyInput struct {
 	OAuthToken    string `json:"oauthToken,omitempty"`
 	OAuthVerifier string `json:"oauthVerifier,omitempty"`
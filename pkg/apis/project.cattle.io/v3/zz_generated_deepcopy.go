@@ -337,6 +337,22 @@ func (in *ApplyYamlConfig) DeepCopy() *ApplyYamlConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Artifact) DeepCopyInto(out *Artifact) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Artifact.
+func (in *Artifact) DeepCopy() *Artifact {
+	if in == nil {
+		return nil
+	}
+	out := new(Artifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthAppInput) DeepCopyInto(out *AuthAppInput) {
 	*out = *in
@@ -794,6 +810,65 @@ func (in *EnvFrom) DeepCopy() *EnvFrom {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaApplyInput) DeepCopyInto(out *GiteaApplyInput) {
+	*out = *in
+	out.OauthApplyInput = in.OauthApplyInput
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaApplyInput.
+func (in *GiteaApplyInput) DeepCopy() *GiteaApplyInput {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaApplyInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaPipelineConfig) DeepCopyInto(out *GiteaPipelineConfig) {
+	*out = *in
+	in.SourceCodeProviderConfig.DeepCopyInto(&out.SourceCodeProviderConfig)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaPipelineConfig.
+func (in *GiteaPipelineConfig) DeepCopy() *GiteaPipelineConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaPipelineConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GiteaPipelineConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaProvider) DeepCopyInto(out *GiteaProvider) {
+	*out = *in
+	in.OauthProvider.DeepCopyInto(&out.OauthProvider)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaProvider.
+func (in *GiteaProvider) DeepCopy() *GiteaProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubApplyInput) DeepCopyInto(out *GithubApplyInput) {
 	*out = *in
@@ -1437,6 +1512,11 @@ func (in *PipelineExecutionStatus) DeepCopyInto(out *PipelineExecutionStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]Artifact, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2337,6 +2417,21 @@ func (in *Step) DeepCopyInto(out *Step) {
 		*out = new(Constraints)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(StepCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = new(StepMatrix)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -2350,6 +2445,63 @@ func (in *Step) DeepCopy() *Step {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepCache) DeepCopyInto(out *StepCache) {
+	*out = *in
+	if in.KeyFiles != nil {
+		in, out := &in.KeyFiles, &out.KeyFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepCache.
+func (in *StepCache) DeepCopy() *StepCache {
+	if in == nil {
+		return nil
+	}
+	out := new(StepCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepMatrix) DeepCopyInto(out *StepMatrix) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepMatrix.
+func (in *StepMatrix) DeepCopy() *StepMatrix {
+	if in == nil {
+		return nil
+	}
+	out := new(StepMatrix)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StepStatus) DeepCopyInto(out *StepStatus) {
 	*out = *in
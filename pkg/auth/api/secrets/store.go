@@ -26,6 +26,7 @@
 		client.OKTAConfigType:            {client.OKTAConfigFieldSpKey},
 		client.ShibbolethConfigType:      {client.ShibbolethConfigFieldSpKey},
 		client.GoogleOauthConfigType:     {client.GoogleOauthConfigFieldOauthCredential, client.GoogleOauthConfigFieldServiceAccountCredential},
+		client.OIDCConfigType:            {client.OIDCConfigFieldClientSecret},
 	}
 
 	SubTypeToFields = map[string]map[string][]string{
@@ -8,6 +8,9 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/parse"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/mfa"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/settings"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
@@ -21,6 +24,12 @@ func (h *Handler) UserFormatter(apiContext *types.APIContext, resource *types.Ra
 	if canRefresh := h.userCanRefresh(apiContext); canRefresh {
 		resource.AddAction(apiContext, "refreshauthprovideraccess")
 	}
+	if convert.ToBool(resource.Values[client.UserFieldMFAEnabled]) && h.userCanUpdate(apiContext) {
+		resource.AddAction(apiContext, "resetmfa")
+	}
+	if convert.ToString(resource.Values[client.UserFieldUsername]) != "" && h.userCanUpdate(apiContext) {
+		resource.AddAction(apiContext, "unlock")
+	}
 }
 
 func (h *Handler) CollectionFormatter(apiContext *types.APIContext, collection *types.GenericCollection) {
@@ -28,12 +37,17 @@ func (h *Handler) CollectionFormatter(apiContext *types.APIContext, collection *
 	if canRefresh := h.userCanRefresh(apiContext); canRefresh {
 		collection.AddAction(apiContext, "refreshauthprovideraccess")
 	}
+	collection.AddAction(apiContext, "enrollmfa")
+	collection.AddAction(apiContext, "confirmmfa")
+	collection.AddAction(apiContext, "disablemfa")
 }
 
 type Handler struct {
 	UserClient               v3.UserInterface
 	GlobalRoleBindingsClient v3.GlobalRoleBindingInterface
 	UserAuthRefresher        providerrefresh.UserAuthRefresher
+	MFAManager               *mfa.Manager
+	LockoutManager           *lockout.Manager
 }
 
 func (h *Handler) Actions(actionName string, action *types.Action, apiContext *types.APIContext) error {
@@ -50,6 +64,26 @@ func (h *Handler) Actions(actionName string, action *types.Action, apiContext *t
 		if err := h.refreshAttributes(actionName, action, apiContext); err != nil {
 			return err
 		}
+	case "enrollmfa":
+		if err := h.enrollMFA(actionName, action, apiContext); err != nil {
+			return err
+		}
+	case "confirmmfa":
+		if err := h.confirmMFA(actionName, action, apiContext); err != nil {
+			return err
+		}
+	case "disablemfa":
+		if err := h.disableMFA(actionName, action, apiContext); err != nil {
+			return err
+		}
+	case "resetmfa":
+		if err := h.resetMFA(actionName, action, apiContext); err != nil {
+			return err
+		}
+	case "unlock":
+		if err := h.unlock(actionName, action, apiContext); err != nil {
+			return err
+		}
 	default:
 		return errors.Errorf("bad action %v", actionName)
 	}
@@ -97,12 +131,9 @@ func (h *Handler) changePassword(actionName string, action *types.Action, reques
 		return httperror.NewAPIError(httperror.InvalidBodyContent, "invalid current password")
 	}
 
-	newPassHash, err := HashPasswordString(newPass)
-	if err != nil {
+	if err := setUserPassword(user, newPass); err != nil {
 		return err
 	}
-
-	user.Password = newPassHash
 	user.MustChangePassword = false
 	user, err = h.UserClient.Update(user)
 	if err != nil {
@@ -127,16 +158,28 @@ func (h *Handler) setPassword(actionName string, action *types.Action, request *
 	if err != nil {
 		return err
 	}
+	if err := request.AccessControl.CanDo(v3.UserGroupVersionKind.Group, v3.UserResource.Name, "update", request, userData, request.Schema); err != nil {
+		return err
+	}
 
 	newPass, ok := actionInput["newPassword"].(string)
 	if !ok || len(newPass) == 0 {
 		return errors.New("Invalid password")
 	}
 
-	userData[client.UserFieldPassword] = newPass
-	if err := hashPassword(userData); err != nil {
+	// the password and its history are write only, so the policy is checked
+	// against the stored user
+	user, err := h.UserClient.Get(request.ID, v1.GetOptions{})
+	if err != nil {
 		return err
 	}
+	if err := setUserPassword(user, newPass); err != nil {
+		return err
+	}
+
+	userData[client.UserFieldPassword] = user.Password
+	userData[client.UserFieldPasswordHistory] = user.PasswordHistory
+	userData[client.UserFieldPasswordChangedAt] = user.PasswordChangedAt
 	userData[client.UserFieldMustChangePassword] = false
 	delete(userData, "me")
 
@@ -169,3 +212,116 @@ func (h *Handler) refreshAttributes(actionName string, action *types.Action, req
 func (h *Handler) userCanRefresh(request *types.APIContext) bool {
 	return request.AccessControl.CanDo(v3.UserGroupVersionKind.Group, v3.UserResource.Name, "create", request, nil, request.Schema) == nil
 }
+
+func (h *Handler) enrollMFA(actionName string, action *types.Action, request *types.APIContext) error {
+	user, err := h.getLocalUser(request)
+	if err != nil {
+		return err
+	}
+	enrollment, err := h.MFAManager.Enroll(user)
+	if err != nil {
+		return err
+	}
+	data, err := convert.EncodeToMap(enrollment)
+	if err != nil {
+		return err
+	}
+	data["type"] = client.MFAEnrollmentType
+	request.WriteResponse(http.StatusOK, data)
+	return nil
+}
+
+func (h *Handler) confirmMFA(actionName string, action *types.Action, request *types.APIContext) error {
+	code, err := readMFACode(request)
+	if err != nil {
+		return err
+	}
+	user, err := h.getLocalUser(request)
+	if err != nil {
+		return err
+	}
+	if err := h.MFAManager.ConfirmEnrollment(user, code); err != nil {
+		return err
+	}
+	request.WriteResponse(http.StatusOK, nil)
+	return nil
+}
+
+func (h *Handler) disableMFA(actionName string, action *types.Action, request *types.APIContext) error {
+	code, err := readMFACode(request)
+	if err != nil {
+		return err
+	}
+	user, err := h.getLocalUser(request)
+	if err != nil {
+		return err
+	}
+	if err := h.MFAManager.Disable(user, code); err != nil {
+		return err
+	}
+	request.WriteResponse(http.StatusOK, nil)
+	return nil
+}
+
+func (h *Handler) resetMFA(actionName string, action *types.Action, request *types.APIContext) error {
+	if !h.userCanUpdate(request) {
+		return httperror.NewAPIError(httperror.PermissionDenied, "can not reset multi-factor authentication")
+	}
+	user, err := h.UserClient.Get(request.ID, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := h.MFAManager.Reset(user); err != nil {
+		return err
+	}
+	request.WriteResponse(http.StatusOK, nil)
+	return nil
+}
+
+func (h *Handler) unlock(actionName string, action *types.Action, request *types.APIContext) error {
+	if !h.userCanUpdate(request) {
+		return httperror.NewAPIError(httperror.PermissionDenied, "can not unlock user")
+	}
+	user, err := h.UserClient.Get(request.ID, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := h.LockoutManager.Unlock(request.Request.Context(), user); err != nil {
+		return httperror.WrapAPIError(err, httperror.InvalidAction, err.Error())
+	}
+	request.WriteResponse(http.StatusOK, nil)
+	return nil
+}
+
+// getLocalUser returns the user making the request, who must be a local user
+// to use multi-factor authentication.
+func (h *Handler) getLocalUser(request *types.APIContext) (*v3.User, error) {
+	userID := request.Request.Header.Get("Impersonate-User")
+	if userID == "" {
+		return nil, errors.New("can't find user")
+	}
+	user, err := h.UserClient.Get(userID, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if user.Username == "" {
+		return nil, httperror.NewAPIError(httperror.InvalidAction, "multi-factor authentication is only available to local users")
+	}
+	return user, nil
+}
+
+func readMFACode(request *types.APIContext) (string, error) {
+	actionInput, err := parse.ReadBody(request.Request)
+	if err != nil {
+		return "", err
+	}
+	code, ok := actionInput["code"].(string)
+	if !ok || len(code) == 0 {
+		return "", httperror.NewAPIError(httperror.InvalidBodyContent, "must specify code")
+	}
+	return code, nil
+}
+
+func (h *Handler) userCanUpdate(request *types.APIContext) bool {
+	return request.AccessControl.CanDo(v3.UserGroupVersionKind.Group, v3.UserResource.Name, "update", request, nil, request.Schema) == nil
+}
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/store/empty"
+	"github.com/rancher/norman/types"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeAccessControl allows the verbs in verbs on users.
+type fakeAccessControl struct {
+	types.AccessControl
+	verbs map[string]bool
+}
+
+func (f *fakeAccessControl) CanDo(apiGroup, resource, verb string, apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) error {
+	if f.verbs[verb] {
+		return nil
+	}
+	return httperror.NewAPIError(httperror.PermissionDenied, "can not "+verb+" "+resource)
+}
+
+// fakeUserStore serves the user data of a single user.
+type fakeUserStore struct {
+	empty.Store
+	data    map[string]interface{}
+	updated map[string]interface{}
+}
+
+func (f *fakeUserStore) ByID(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
+	return map[string]interface{}{types.ResourceFieldID: id, client.UserFieldUsername: f.data[client.UserFieldUsername]}, nil
+}
+
+func (f *fakeUserStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
+	f.updated = data
+	return data, nil
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(apiContext *types.APIContext, code int, obj interface{}) {}
+
+func newSetPasswordRequest(store types.Store, verbs ...string) *types.APIContext {
+	allowed := map[string]bool{}
+	for _, verb := range verbs {
+		allowed[verb] = true
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/v3/users/u-admin?action=setpassword", strings.NewReader(`{"newPassword":"a-new-password"}`))
+	return &types.APIContext{
+		ID:             "u-admin",
+		Request:        req,
+		Schema:         &types.Schema{Store: store},
+		AccessControl:  &fakeAccessControl{verbs: allowed},
+		ResponseWriter: discardWriter{},
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.PasswordHistorySize.Set(settings.PasswordHistorySize.Get())
+	settings.PasswordHistorySize.Set("2")
+
+	oldHash, _ := HashPasswordString("a-new-password")
+	var written *v3.User
+	h := &Handler{
+		UserClient: &fakes.UserInterfaceMock{
+			GetFunc: func(name string, opts metav1.GetOptions) (*v3.User, error) {
+				return &v3.User{ObjectMeta: metav1.ObjectMeta{Name: name}, Username: "admin", Password: oldHash}, nil
+			},
+			UpdateFunc: func(user *v3.User) (*v3.User, error) {
+				written = user
+				return user, nil
+			},
+		},
+	}
+
+	store := &fakeUserStore{data: map[string]interface{}{client.UserFieldUsername: "admin"}}
+	err := h.setPassword("setpassword", nil, newSetPasswordRequest(store, "get"))
+	if assert.Error(err) {
+		assert.Equal(httperror.PermissionDenied, err.(*httperror.APIError).Code, "refused to callers who can only get the user")
+	}
+	assert.Nil(store.updated)
+	assert.Nil(written)
+
+	err = h.setPassword("setpassword", nil, newSetPasswordRequest(store, "get", "update"))
+	assert.Error(err, "the password policy applies")
+	assert.Nil(store.updated)
+
+	oldHash, _ = HashPasswordString("an-old-password")
+	err = h.setPassword("setpassword", nil, newSetPasswordRequest(store, "get", "update"))
+	assert.Nil(err)
+	assert.Nil(written, "written through the store of the caller")
+	if assert.NotNil(store.updated) {
+		hash, _ := store.updated[client.UserFieldPassword].(string)
+		assert.Nil(bcrypt.CompareHashAndPassword([]byte(hash), []byte("a-new-password")))
+		assert.Equal([]string{oldHash}, store.updated[client.UserFieldPasswordHistory])
+		assert.NotEmpty(store.updated[client.UserFieldPasswordChangedAt])
+		assert.Equal(false, store.updated[client.UserFieldMustChangePassword])
+	}
+}
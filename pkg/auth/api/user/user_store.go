@@ -9,6 +9,7 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/store/transform"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -98,6 +99,23 @@ func hashPassword(data map[string]interface{}) error {
 	return nil
 }
 
+// setUserPassword sets the new password of user once it meets the password
+// policy.
+func setUserPassword(user *v3.User, password string) error {
+	if err := passwordpolicy.Validate(user.Username, password); err != nil {
+		return err
+	}
+	if err := passwordpolicy.CheckReuse(user, password); err != nil {
+		return err
+	}
+	hash, err := HashPasswordString(password)
+	if err != nil {
+		return err
+	}
+	passwordpolicy.SetPassword(user, hash, time.Now())
+	return nil
+}
+
 func HashPasswordString(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -107,9 +125,15 @@ func HashPasswordString(password string) (string, error) {
 }
 
 func (s *userStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	password, _ := data[client.UserFieldPassword].(string)
+	username, _ := data[client.UserFieldUsername].(string)
+	if err := passwordpolicy.Validate(username, password); err != nil {
+		return nil, err
+	}
 	if err := hashPassword(data); err != nil {
 		return nil, err
 	}
+	data[client.UserFieldPasswordChangedAt] = time.Now().UTC().Format(time.RFC3339)
 
 	created, err := s.create(apiContext, schema, data)
 	if err != nil {
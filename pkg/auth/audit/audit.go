@@ -6,6 +6,7 @@
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/pborman/uuid"
@@ -35,24 +36,27 @@
 )
 
 type auditLog struct {
-	log     *log
-	writer  *LogWriter
-	reqBody []byte
+	log          *log
+	writer       *LogWriter
+	level        int
+	resourceType string
+	reqBody      []byte
 }
 
 type log struct {
-	AuditID           k8stypes.UID `json:"auditID,omitempty"`
-	RequestURI        string       `json:"requestURI,omitempty"`
-	User              *User        `json:"user,omitempty"`
-	Method            string       `json:"method,omitempty"`
-	RemoteAddr        string       `json:"remoteAddr,omitempty"`
-	RequestTimestamp  string       `json:"requestTimestamp,omitempty"`
-	ResponseTimestamp string       `json:"responseTimestamp,omitempty"`
-	ResponseCode      int          `json:"responseCode,omitempty"`
-	RequestHeader     http.Header  `json:"requestHeader,omitempty"`
-	ResponseHeader    http.Header  `json:"responseHeader,omitempty"`
-	RequestBody       []byte       `json:"requestBody,omitempty"`
-	ResponseBody      []byte       `json:"responseBody,omitempty"`
+	AuditID           k8stypes.UID      `json:"auditID,omitempty"`
+	RequestURI        string            `json:"requestURI,omitempty"`
+	User              *User             `json:"user,omitempty"`
+	Method            string            `json:"method,omitempty"`
+	RemoteAddr        string            `json:"remoteAddr,omitempty"`
+	RequestTimestamp  string            `json:"requestTimestamp,omitempty"`
+	ResponseTimestamp string            `json:"responseTimestamp,omitempty"`
+	ResponseCode      int               `json:"responseCode,omitempty"`
+	RequestHeader     http.Header       `json:"requestHeader,omitempty"`
+	ResponseHeader    http.Header       `json:"responseHeader,omitempty"`
+	RequestBody       []byte            `json:"requestBody,omitempty"`
+	ResponseBody      []byte            `json:"responseBody,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
 }
 
 var userKey struct{}
@@ -79,9 +83,44 @@ func FromContext(ctx context.Context) (*User, bool) {
 	return u, ok
 }
 
-func newAuditLog(writer *LogWriter, req *http.Request) (*auditLog, error) {
+type annotationsKey struct{}
+
+type annotations struct {
+	sync.Mutex
+	values map[string]string
+}
+
+func contextWithAnnotations(ctx context.Context, a *annotations) context.Context {
+	return context.WithValue(ctx, annotationsKey{}, a)
+}
+
+// AddAnnotation annotates the audit log of the request of ctx with an event,
+// such as an account being locked. Annotated requests are logged at the
+// metadata level at least, whatever the policy.
+func AddAnnotation(ctx context.Context, key, value string) {
+	a, ok := ctx.Value(annotationsKey{}).(*annotations)
+	if !ok {
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	if a.values == nil {
+		a.values = map[string]string{}
+	}
+	a.values[key] = value
+}
+
+func (a *annotations) get() map[string]string {
+	a.Lock()
+	defer a.Unlock()
+	return a.values
+}
+
+func newAuditLog(writer *LogWriter, level int, req *http.Request) (*auditLog, error) {
 	auditLog := &auditLog{
-		writer: writer,
+		writer:       writer,
+		level:        level,
+		resourceType: getResourceType(req.URL.Path),
 		log: &log{
 			AuditID:          k8stypes.UID(uuid.NewRandom().String()),
 			RequestURI:       req.RequestURI,
@@ -92,7 +131,7 @@ func newAuditLog(writer *LogWriter, req *http.Request) (*auditLog, error) {
 	}
 
 	contentType := req.Header.Get("Content-Type")
-	if writer.Level >= levelRequest && bodyMethods[req.Method] && contentType == contentTypeJSON {
+	if level >= levelRequest && bodyMethods[req.Method] && contentType == contentTypeJSON {
 		reqBody, err := readBodyWithoutLosingContent(req)
 		if err != nil {
 			return nil, err
@@ -102,7 +141,11 @@ func newAuditLog(writer *LogWriter, req *http.Request) (*auditLog, error) {
 	return auditLog, nil
 }
 
-func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, resCode int, resBody []byte) error {
+func (a *auditLog) write(userInfo *User, level int, reqHeaders, resHeaders http.Header, resCode int, resBody []byte) error {
+	if level == levelNull {
+		return nil
+	}
+	a.level = level
 	a.log.User = userInfo
 	a.log.ResponseTimestamp = time.Now().Format(time.RFC3339)
 	a.log.RequestHeader = filterOutHeaders(reqHeaders, sensitiveRequestHeader)
@@ -116,13 +159,18 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 	}
 
 	buffer.Write(bytes.TrimSuffix(alByte, []byte("}")))
-	if a.writer.Level >= levelRequest && len(a.reqBody) > 0 {
-		buffer.WriteString(`,"requestBody":`)
-		buffer.Write(bytes.TrimSuffix(a.reqBody, []byte("\n")))
+	redactionPaths := a.writer.Policy.redactionPaths(a.resourceType)
+	if a.level >= levelRequest && len(a.reqBody) > 0 {
+		if body, ok := redactBody(a.reqBody, a.resourceType, redactionPaths); ok {
+			buffer.WriteString(`,"requestBody":`)
+			buffer.Write(body)
+		}
 	}
-	if a.writer.Level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON && len(resBody) > 0 {
-		buffer.WriteString(`,"responseBody":`)
-		buffer.Write(bytes.TrimSuffix(resBody, []byte("\n")))
+	if a.level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON && len(resBody) > 0 {
+		if body, ok := redactBody(resBody, a.resourceType, redactionPaths); ok {
+			buffer.WriteString(`,"responseBody":`)
+			buffer.Write(body)
+		}
 	}
 	buffer.WriteString("}")
 
@@ -132,9 +180,7 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 		return errors.Wrap(err, "compact audit log json failed")
 	}
 
-	compactBuffer.WriteString("\n")
-	_, err = a.writer.Output.Write(compactBuffer.Bytes())
-	return err
+	return a.writer.write(compactBuffer.Bytes())
 }
 
 func readBodyWithoutLosingContent(req *http.Request) ([]byte, error) {
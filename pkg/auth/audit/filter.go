@@ -33,12 +33,29 @@ func (h auditHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the user is only known once the request is authenticated further down
+	// the chain, so the request is prepared for the highest level it may get
+	maxLevel := h.auditWriter.maxLevelFor(req)
+	annotations := &annotations{}
+	if maxLevel == levelNull {
+		// requests no rule audits are still logged if a handler annotates them
+		req = req.WithContext(contextWithAnnotations(req.Context(), annotations))
+		auditLog, _ := newAuditLog(h.auditWriter, levelMetadata, req)
+		h.next.ServeHTTP(rw, req)
+		if values := annotations.get(); len(values) > 0 {
+			auditLog.log.Annotations = values
+			auditLog.write(getUserInfo(req), levelMetadata, req.Header, nil, 0, nil)
+		}
+		return
+	}
+
 	user := getUserInfo(req)
 
 	context := context.WithValue(req.Context(), userKey, user)
+	context = contextWithAnnotations(context, annotations)
 	req = req.WithContext(context)
 
-	auditLog, err := newAuditLog(h.auditWriter, req)
+	auditLog, err := newAuditLog(h.auditWriter, maxLevel, req)
 	if err != nil {
 		util.ReturnHTTPError(rw, req, 500, err.Error())
 		return
@@ -47,7 +64,14 @@ func (h auditHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	wr := &wrapWriter{ResponseWriter: rw, auditWriter: h.auditWriter, statusCode: http.StatusOK}
 	h.next.ServeHTTP(wr, req)
 
-	auditLog.write(user, req.Header, wr.Header(), wr.statusCode, wr.buf.Bytes())
+	level := h.auditWriter.levelFor(req, user)
+	if values := annotations.get(); len(values) > 0 {
+		auditLog.log.Annotations = values
+		if level == levelNull {
+			level = levelMetadata
+		}
+	}
+	auditLog.write(user, level, req.Header, wr.Header(), wr.statusCode, wr.buf.Bytes())
 }
 
 type wrapWriter struct {
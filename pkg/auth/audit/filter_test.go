@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8suser "k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type testSink struct {
+	records []map[string]interface{}
+}
+
+func (s *testSink) Write(record []byte) error {
+	var r map[string]interface{}
+	if err := json.Unmarshal(record, &r); err != nil {
+		return err
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *testSink) Start(ctx context.Context) {}
+
+func TestAuditLogMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	sink := &testSink{}
+	writer := &LogWriter{
+		Level: levelMetadata,
+		Policy: &Policy{Rules: []PolicyRule{
+			{Level: levelNull, PathPrefixes: []string{"/healthz"}},
+			{Level: levelRequest, Users: []string{"user-admin"}},
+		}},
+		Sinks: []Sink{sink},
+	}
+	handler := NewAuditLogMiddleware(writer)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// the user is only known once authenticated further down the chain
+		if user, ok := FromContext(req.Context()); ok {
+			user.Name = req.Header.Get("X-Test-User")
+		}
+		rw.Header().Set("Content-Type", contentTypeJSON)
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+
+	serve := func(method, path, user string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"c1"}`))
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.Header.Set("X-Test-User", user)
+		req = req.WithContext(request.WithUser(req.Context(), &k8suser.DefaultInfo{}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("GET", "/healthz", "user-admin")
+	serve("PUT", "/v3/clusters/c1", "user-admin")
+	serve("PUT", "/v3/clusters/c1", "user-other")
+
+	if assert.Len(sink.records, 2) {
+		assert.Equal(map[string]interface{}{"name": "c1"}, sink.records[0]["requestBody"])
+		assert.Nil(sink.records[0]["responseBody"])
+		assert.Nil(sink.records[1]["requestBody"], "metadata level does not log the body")
+		assert.Equal("user-other", sink.records[1]["user"].(map[string]interface{})["name"])
+	}
+}
+
+func TestAuditAnnotations(t *testing.T) {
+	assert := assert.New(t)
+	sink := &testSink{}
+	writer := &LogWriter{
+		Policy: &Policy{Rules: []PolicyRule{
+			{Level: levelRequest, PathPrefixes: []string{"/v3/"}},
+		}},
+		Sinks: []Sink{sink},
+	}
+	handler := NewAuditLogMiddleware(writer)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if event := req.Header.Get("X-Test-Event"); event != "" {
+			AddAnnotation(req.Context(), "test.cattle.io/event", event)
+		}
+	}))
+
+	serve := func(path, event string) {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("X-Test-Event", event)
+		req = req.WithContext(request.WithUser(req.Context(), &k8suser.DefaultInfo{}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("/v3-public/localProviders/local", "")
+	serve("/v3-public/localProviders/local", "locked")
+	serve("/v3/users", "unlocked")
+
+	if assert.Len(sink.records, 2, "annotated requests are logged whatever the policy") {
+		assert.Equal("/v3-public/localProviders/local", sink.records[0]["requestURI"])
+		assert.Equal(map[string]interface{}{"test.cattle.io/event": "locked"}, sink.records[0]["annotations"])
+		assert.Equal(map[string]interface{}{"test.cattle.io/event": "unlocked"}, sink.records[1]["annotations"])
+	}
+
+	AddAnnotation(context.Background(), "test.cattle.io/event", "ignored")
+}
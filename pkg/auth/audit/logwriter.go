@@ -2,37 +2,105 @@
 
 import (
 	"context"
+	"net/http"
+	"time"
 
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+	"github.com/sirupsen/logrus"
 )
 
+// LogWriterOptions configure the audit log of the API.
+type LogWriterOptions struct {
+	// Level applies to requests no policy rule matches
+	Level      int
+	PolicyFile string
+
+	Path      string
+	MaxAge    int
+	MaxBackup int
+	MaxSize   int
+
+	WebhookURL          string
+	WebhookBatchSize    int
+	WebhookBatchMaxWait time.Duration
+	WebhookMaxRetries   int
+
+	SyslogAddress string
+	SyslogAppName string
+	SyslogTLS     SyslogTLSOptions
+}
+
 type LogWriter struct {
 	Level  int
-	Output *lumberjack.Logger
+	Policy *Policy
+	Sinks  []Sink
 }
 
 func (l *LogWriter) Start(ctx context.Context) {
 	if l == nil {
 		return
 	}
-	go func() {
-		<-ctx.Done()
-		l.Output.Close()
-	}()
+	for _, sink := range l.Sinks {
+		sink.Start(ctx)
+	}
 }
 
-func NewLogWriter(path string, level, maxAge, maxBackup, maxSize int) *LogWriter {
-	if path == "" || level == levelNull {
-		return nil
+// NewLogWriter returns the writer configured by opts, or nil if auditing is
+// disabled: no sink is configured, or the level is 0 and there is no policy
+// to raise it.
+func NewLogWriter(opts LogWriterOptions) (*LogWriter, error) {
+	writer := &LogWriter{
+		Level: opts.Level,
+	}
+	if opts.PolicyFile != "" {
+		policy, err := LoadPolicy(opts.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		writer.Policy = policy
+	}
+	if writer.Level == levelNull && writer.Policy == nil {
+		return nil, nil
+	}
+
+	if opts.Path != "" {
+		writer.Sinks = append(writer.Sinks, NewFileSink(opts.Path, opts.MaxAge, opts.MaxBackup, opts.MaxSize))
+	}
+	if opts.WebhookURL != "" {
+		writer.Sinks = append(writer.Sinks, NewWebhookSink(opts.WebhookURL, opts.WebhookBatchSize, opts.WebhookBatchMaxWait, opts.WebhookMaxRetries))
+	}
+	if opts.SyslogAddress != "" {
+		sink, err := NewSyslogSink(opts.SyslogAddress, opts.SyslogAppName, opts.SyslogTLS)
+		if err != nil {
+			return nil, err
+		}
+		writer.Sinks = append(writer.Sinks, sink)
 	}
+	if len(writer.Sinks) == 0 {
+		return nil, nil
+	}
+	return writer, nil
+}
+
+// levelFor returns the audit level of req made by user.
+func (l *LogWriter) levelFor(req *http.Request, user *User) int {
+	return l.Policy.levelFor(req, user, l.Level)
+}
+
+// maxLevelFor returns the highest audit level req may have once its user is
+// known.
+func (l *LogWriter) maxLevelFor(req *http.Request) int {
+	return l.Policy.maxLevelFor(req, l.Level)
+}
 
-	return &LogWriter{
-		Level: level,
-		Output: &lumberjack.Logger{
-			Filename:   path,
-			MaxAge:     maxAge,
-			MaxBackups: maxBackup,
-			MaxSize:    maxSize,
-		},
+// write passes record to every sink. A failing sink does not keep the record
+// from the others.
+func (l *LogWriter) write(record []byte) error {
+	var lastErr error
+	for _, sink := range l.Sinks {
+		if err := sink.Write(record); err != nil {
+			logrus.Debugf("Failed to write audit record: %v", err)
+			lastErr = err
+		}
 	}
+	return lastErr
 }
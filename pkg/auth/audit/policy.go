@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+var levelNames = map[string]int{
+	"none":            levelNull,
+	"metadata":        levelMetadata,
+	"request":         levelRequest,
+	"requestresponse": levelRequestResponse,
+}
+
+// Level is an audit level. In a policy file it is either the number used by
+// the audit-level flag or one of None, Metadata, Request and RequestResponse.
+type Level int
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if level, ok := levelNames[strings.ToLower(value)]; ok {
+		*l = Level(level)
+		return nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < levelNull || level > levelRequestResponse {
+		return fmt.Errorf("invalid audit level %s", data)
+	}
+	*l = Level(level)
+	return nil
+}
+
+// Policy chooses the audit level of each request. Rules are evaluated in
+// order and the first matching rule applies; requests no rule matches are
+// logged at the level of the audit-level flag.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+	// Redactions mask fields of request and response bodies besides the
+	// passwords, secrets, keys and tokens that are always masked
+	Redactions []RedactionRule `json:"redactions,omitempty"`
+}
+
+// PolicyRule matches requests by all of its non-empty fields. A field
+// matches if any of its values does.
+type PolicyRule struct {
+	Level Level `json:"level"`
+	// Users are user names, such as user-abcde
+	Users []string `json:"users,omitempty"`
+	// Groups are group principals of the user, such as system:authenticated
+	Groups []string `json:"groups,omitempty"`
+	// ResourceTypes are API resource types, such as clusters or secrets
+	ResourceTypes []string `json:"resourceTypes,omitempty"`
+	// Verbs are HTTP methods, such as get or put
+	Verbs []string `json:"verbs,omitempty"`
+	// PathPrefixes are prefixes of the request path, such as /v3/tokens
+	PathPrefixes []string `json:"pathPrefixes,omitempty"`
+}
+
+// LoadPolicy reads a policy from a YAML or JSON file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("invalid audit policy %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// levelFor returns the level of the first rule matching req made by user, or
+// defaultLevel if no rule matches.
+func (p *Policy) levelFor(req *http.Request, user *User, defaultLevel int) int {
+	if p == nil {
+		return defaultLevel
+	}
+	resourceType := getResourceType(req.URL.Path)
+	for _, rule := range p.Rules {
+		if rule.matches(req, user, resourceType) {
+			return int(rule.Level)
+		}
+	}
+	return defaultLevel
+}
+
+// maxLevelFor returns the highest level req may be logged at, before the
+// user making it is known: the level of the first rule matching req
+// regardless of user, or any higher level of a user or group rule before it.
+func (p *Policy) maxLevelFor(req *http.Request, defaultLevel int) int {
+	if p == nil {
+		return defaultLevel
+	}
+	resourceType := getResourceType(req.URL.Path)
+	max := levelNull
+	for _, rule := range p.Rules {
+		if !rule.matchesRequest(req, resourceType) {
+			continue
+		}
+		if int(rule.Level) > max {
+			max = int(rule.Level)
+		}
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return max
+		}
+	}
+	if defaultLevel > max {
+		return defaultLevel
+	}
+	return max
+}
+
+func (r *PolicyRule) matches(req *http.Request, user *User, resourceType string) bool {
+	if len(r.Users) > 0 && !isExist(r.Users, user.Name) {
+		return false
+	}
+	if len(r.Groups) > 0 && !anyExist(r.Groups, user.Group) {
+		return false
+	}
+	return r.matchesRequest(req, resourceType)
+}
+
+func (r *PolicyRule) matchesRequest(req *http.Request, resourceType string) bool {
+	if len(r.Verbs) > 0 && !isExistFold(r.Verbs, req.Method) {
+		return false
+	}
+	if len(r.ResourceTypes) > 0 && !matchResourceType(r.ResourceTypes, resourceType) {
+		return false
+	}
+	if len(r.PathPrefixes) > 0 && !hasAnyPrefix(req.URL.Path, r.PathPrefixes) {
+		return false
+	}
+	return true
+}
+
+// getResourceType returns the resource type a request path refers to, for
+// the norman (/v3), steve (/v1) and kubernetes proxy (/k8s/clusters) APIs.
+func getResourceType(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && (parts[0] == "v3" || parts[0] == "v3-public"):
+		// /v3/project/<id>/<type> and /v3/cluster/<id>/<type> are scoped collections
+		if len(parts) >= 4 && (parts[1] == "project" || parts[1] == "cluster") {
+			return parts[3]
+		}
+		return parts[1]
+	case len(parts) >= 2 && parts[0] == "v1":
+		return parts[1]
+	case len(parts) >= 3 && parts[0] == "k8s" && parts[1] == "clusters":
+		return getKubernetesResourceType(parts[3:])
+	}
+	return ""
+}
+
+func getKubernetesResourceType(parts []string) string {
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return ""
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		return parts[2]
+	}
+	if len(parts) >= 1 {
+		return parts[0]
+	}
+	return ""
+}
+
+// matchResourceType matches steve types, such as
+// management.cattle.io.clusters, by their plain name too.
+func matchResourceType(types []string, resourceType string) bool {
+	for _, t := range types {
+		if t == resourceType || strings.HasSuffix(resourceType, "."+t) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyExist(array, keys []string) bool {
+	for _, key := range keys {
+		if isExist(array, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExistFold(array []string, key string) bool {
+	for _, v := range array {
+		if strings.EqualFold(v, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+rules:
+- level: None
+  pathPrefixes: ["/v3/settings"]
+- level: RequestResponse
+  users: ["user-admin"]
+  resourceTypes: ["secrets"]
+- level: Metadata
+  verbs: ["put"]
+- level: 2
+  groups: ["github_org://1"]
+`
+
+func loadTestPolicy(t *testing.T) *Policy {
+	f, err := ioutil.TempFile("", "audit-policy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testPolicy)
+	f.Close()
+	policy, err := LoadPolicy(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestPolicyLevelFor(t *testing.T) {
+	assert := assert.New(t)
+	policy := loadTestPolicy(t)
+	admin := &User{Name: "user-admin"}
+	member := &User{Name: "user-abcde", Group: []string{"github_org://1"}}
+
+	tests := []struct {
+		method, path string
+		user         *User
+		level        int
+	}{
+		{"GET", "/v3/settings/server-url", admin, levelNull},
+		{"GET", "/v3/project/c-abcde:p-abcde/secrets/ns:s", admin, levelRequestResponse},
+		{"GET", "/v1/secrets/ns/s", admin, levelRequestResponse},
+		{"GET", "/k8s/clusters/c-abcde/api/v1/namespaces/ns/secrets/s", admin, levelRequestResponse},
+		{"PUT", "/v3/project/c-abcde:p-abcde/secrets/ns:s", member, levelMetadata},
+		{"POST", "/v3/clusters", member, levelRequest},
+		{"POST", "/v3/clusters", &User{Name: "user-other"}, levelMetadata},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		assert.Equal(test.level, policy.levelFor(req, test.user, levelMetadata), "%s %s", test.method, test.path)
+	}
+
+	var none *Policy
+	assert.Equal(levelRequest, none.levelFor(httptest.NewRequest("GET", "/v3", nil), admin, levelRequest))
+}
+
+func TestPolicyMaxLevelFor(t *testing.T) {
+	assert := assert.New(t)
+	policy := loadTestPolicy(t)
+
+	assert.Equal(levelNull, policy.maxLevelFor(httptest.NewRequest("GET", "/v3/settings", nil), levelMetadata))
+	assert.Equal(levelRequestResponse, policy.maxLevelFor(httptest.NewRequest("PUT", "/v1/secrets/ns/s", nil), levelMetadata),
+		"a user rule before the first matching rule may apply")
+	assert.Equal(levelMetadata, policy.maxLevelFor(httptest.NewRequest("PUT", "/v3/clusters/c-abcde", nil), levelNull))
+	assert.Equal(levelRequest, policy.maxLevelFor(httptest.NewRequest("POST", "/v3/clusters", nil), levelMetadata))
+}
+
+func TestGetResourceType(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("clusters", getResourceType("/v3/clusters/c-abcde"))
+	assert.Equal("apps", getResourceType("/v3/project/c-abcde:p-abcde/apps"))
+	assert.Equal("management.cattle.io.clusters", getResourceType("/v1/management.cattle.io.clusters"))
+	assert.Equal("nodes", getResourceType("/k8s/clusters/c-abcde/api/v1/nodes"))
+	assert.Equal("deployments", getResourceType("/k8s/clusters/c-abcde/apis/apps/v1/namespaces/default/deployments/web"))
+	assert.Equal("", getResourceType("/healthz"))
+	assert.True(matchResourceType([]string{"clusters"}, "management.cattle.io.clusters"))
+}
+
+func TestLoadPolicyInvalidLevel(t *testing.T) {
+	var level Level
+	assert.Error(t, level.UnmarshalJSON([]byte(`"Verbose"`)))
+	assert.Error(t, level.UnmarshalJSON([]byte(`4`)))
+	assert.Nil(t, level.UnmarshalJSON([]byte(`"requestResponse"`)))
+	assert.Equal(t, Level(levelRequestResponse), level)
+}
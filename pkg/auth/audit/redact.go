@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+var (
+	// values of fields with these suffixes are masked wherever they are
+	// strings, such as serviceAccountPassword, clientSecret or bearerToken
+	sensitiveFieldSuffixes = []string{
+		"password",
+		"secret",
+		"token",
+		"privatekey",
+		"secretkey",
+		"accountkey",
+		"credential",
+	}
+	// values of fields with these names are masked wherever they are strings
+	sensitiveFields = map[string]bool{
+		// saml service provider key
+		"spkey": true,
+		// mfa login challenges stand in for the password until they expire
+		"challengeid": true,
+	}
+	// objects whose kind, type or baseType is one of these have the listed
+	// fields masked, whatever their value
+	sensitiveObjectFields = map[string][]string{
+		"Secret":                   {"data", "stringData"},
+		"secret":                   {"data", "stringData"},
+		"namespacedSecret":         {"data", "stringData"},
+		"certificate":              {"key"},
+		"namespacedCertificate":    {"key"},
+		"generateKubeConfigOutput": {"config"},
+		"mfaChallenge":             {"recoveryCodes"},
+		"mfaEnrollment":            {"recoveryCodes"},
+		// registration commands embed the token
+		"clusterRegistrationToken": {"command", "insecureCommand", "nodeCommand", "windowsNodeCommand", "manifestUrl"},
+	}
+	// the data of objects of these resource types is masked, as items of
+	// kubernetes lists do not tell their kind
+	secretResourceTypes = map[string]bool{
+		"secrets":           true,
+		"namespacedsecrets": true,
+	}
+)
+
+// RedactionRule masks fields at JSON paths in the bodies of requests to the
+// resource types it lists, on top of the fields masked for every request.
+type RedactionRule struct {
+	ResourceTypes []string `json:"resourceTypes,omitempty"`
+	// Paths are dot separated field names, such as spec.config.apiKey. A *
+	// matches every field of an object or element of an array. Paths apply to
+	// the body and to every item of a list.
+	Paths []string `json:"paths"`
+}
+
+// redactionPaths returns the paths of the redaction rules matching
+// resourceType, split into their fields.
+func (p *Policy) redactionPaths(resourceType string) [][]string {
+	if p == nil {
+		return nil
+	}
+	var paths [][]string
+	for _, rule := range p.Redactions {
+		if len(rule.ResourceTypes) > 0 && !matchResourceType(rule.ResourceTypes, resourceType) {
+			continue
+		}
+		for _, path := range rule.Paths {
+			path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+			if path != "" {
+				paths = append(paths, strings.Split(path, "."))
+			}
+		}
+	}
+	return paths
+}
+
+// redactBody returns the JSON body with sensitive fields masked. Bodies that
+// are not valid JSON are dropped, as there is no telling what they hold.
+func redactBody(body []byte, resourceType string, paths [][]string) ([]byte, bool) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, false
+	}
+	value = redactValue(value, secretResourceTypes[getPlainResourceType(resourceType)])
+	for _, path := range paths {
+		redactPath(value, path)
+		for _, item := range getListItems(value) {
+			redactPath(item, path)
+		}
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+func redactValue(value interface{}, secretData bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		objectFields := getSensitiveObjectFields(v)
+		for key, field := range v {
+			switch {
+			case field == nil:
+			case isExist(objectFields, key):
+				v[key] = redacted
+			case secretData && (key == "data" || key == "stringData") && isObject(field):
+				v[key] = redacted
+			case isString(field) && isSensitiveField(key):
+				v[key] = redacted
+			default:
+				v[key] = redactValue(field, secretData)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i], secretData)
+		}
+	}
+	return value
+}
+
+func getSensitiveObjectFields(object map[string]interface{}) []string {
+	var fields []string
+	for _, key := range []string{"kind", "type", "baseType"} {
+		if t, ok := object[key].(string); ok {
+			fields = append(fields, sensitiveObjectFields[t]...)
+		}
+	}
+	return fields
+}
+
+func isSensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveFields[key] {
+		return true
+	}
+	for _, suffix := range sensitiveFieldSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactPath masks the fields at path below value.
+func redactPath(value interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	last := len(path) == 1
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if last {
+				v[key] = redacted
+			} else {
+				redactPath(field, path[1:])
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			if path[0] != "*" && path[0] != strconv.Itoa(i) {
+				continue
+			}
+			if last {
+				v[i] = redacted
+			} else {
+				redactPath(v[i], path[1:])
+			}
+		}
+	}
+}
+
+// getListItems returns the items of a norman collection or kubernetes list.
+func getListItems(value interface{}) []interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, key := range []string{"data", "items"} {
+		if items, ok := object[key].([]interface{}); ok {
+			return items
+		}
+	}
+	return nil
+}
+
+// getPlainResourceType returns steve types, such as
+// management.cattle.io.clusters, without their group.
+func getPlainResourceType(resourceType string) string {
+	return strings.ToLower(resourceType[strings.LastIndex(resourceType, ".")+1:])
+}
+
+func isObject(value interface{}) bool {
+	_, ok := value.(map[string]interface{})
+	return ok
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func redactTestBody(t *testing.T, body, resourceType string, paths [][]string) map[string]interface{} {
+	result, ok := redactBody([]byte(body), resourceType, paths)
+	if !ok {
+		t.Fatalf("failed to redact %s", body)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(result, &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestRedactBody(t *testing.T) {
+	assert := assert.New(t)
+
+	ldap := redactTestBody(t, `{"type":"openLdapConfig","serviceAccountUsername":"admin","serviceAccountPassword":"hunter2","servers":["ldap"]}`, "openldapconfigs", nil)
+	assert.Equal("admin", ldap["serviceAccountUsername"])
+	assert.Equal(redacted, ldap["serviceAccountPassword"])
+	assert.Equal([]interface{}{"ldap"}, ldap["servers"])
+
+	saml := redactTestBody(t, `{"spCert":"cert","spKey":"key","idpMetadataContent":"md"}`, "pingconfigs", nil)
+	assert.Equal(redacted, saml["spKey"])
+	assert.Equal("cert", saml["spCert"])
+
+	credential := redactTestBody(t, `{"amazonec2credentialConfig":{"accessKey":"AKIA","secretKey":"s3cr3t"}}`, "cloudcredentials", nil)
+	assert.Equal(map[string]interface{}{"accessKey": "AKIA", "secretKey": redacted}, credential["amazonec2credentialConfig"])
+
+	secret := redactTestBody(t, `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s"},"data":{"key":"dmFsdWU="}}`, "", nil)
+	assert.Equal(redacted, secret["data"])
+	assert.Equal(map[string]interface{}{"name": "s"}, secret["metadata"])
+
+	list := redactTestBody(t, `{"kind":"SecretList","items":[{"metadata":{"name":"s"},"data":{"key":"dmFsdWU="}}]}`, "secrets", nil)
+	assert.Equal(redacted, list["items"].([]interface{})[0].(map[string]interface{})["data"], "items of secret lists do not tell their kind")
+
+	collection := redactTestBody(t, `{"type":"collection","data":[{"type":"namespacedSecret","data":{"key":"dmFsdWU="}}]}`, "namespacedsecrets", nil)
+	assert.Equal(redacted, collection["data"].([]interface{})[0].(map[string]interface{})["data"])
+
+	kubeconfig := redactTestBody(t, `{"baseType":"generateKubeConfigOutput","type":"generateKubeConfigOutput","config":"apiVersion: v1"}`, "clusters", nil)
+	assert.Equal(redacted, kubeconfig["config"])
+
+	challenge := redactTestBody(t, `{"type":"mfaChallenge","challengeId":"u-abcde:key","secret":"ABCD","recoveryCodes":["aaaa-bbbb-cccc-dddd"]}`, "localproviders", nil)
+	assert.Equal(redacted, challenge["challengeId"])
+	assert.Equal(redacted, challenge["secret"])
+	assert.Equal(redacted, challenge["recoveryCodes"])
+
+	pod := redactTestBody(t, `{"spec":{"automountServiceAccountToken":true,"volumes":[{"secret":{"secretName":"s"}}]}}`, "pods", nil)
+	assert.Equal(map[string]interface{}{
+		"automountServiceAccountToken": true,
+		"volumes":                      []interface{}{map[string]interface{}{"secret": map[string]interface{}{"secretName": "s"}}},
+	}, pod["spec"], "only string values are masked by name")
+
+	_, ok := redactBody([]byte(`not json`), "", nil)
+	assert.False(ok)
+}
+
+func TestRedactPaths(t *testing.T) {
+	assert := assert.New(t)
+	policy := &Policy{Redactions: []RedactionRule{
+		{ResourceTypes: []string{"configmaps"}, Paths: []string{"$.data.*"}},
+		{ResourceTypes: []string{"apps"}, Paths: []string{"answers.apiKey", "values.0"}},
+		{Paths: []string{"metadata.annotations.internal"}},
+	}}
+
+	configMap := redactTestBody(t, `{"metadata":{"annotations":{"internal":"x","public":"y"}},"data":{"a":"1","b":"2"}}`,
+		"configmaps", policy.redactionPaths("configmaps"))
+	assert.Equal(map[string]interface{}{"a": redacted, "b": redacted}, configMap["data"])
+	assert.Equal(map[string]interface{}{"internal": redacted, "public": "y"}, configMap["metadata"].(map[string]interface{})["annotations"])
+
+	apps := redactTestBody(t, `{"type":"collection","data":[{"answers":{"apiKey":"k","replicas":"1"},"values":["a","b"]}]}`,
+		"apps", policy.redactionPaths("apps"))
+	assert.Equal(map[string]interface{}{
+		"answers": map[string]interface{}{"apiKey": redacted, "replicas": "1"},
+		"values":  []interface{}{redacted, "b"},
+	}, apps["data"].([]interface{})[0], "paths apply to the items of lists")
+
+	assert.Len(policy.redactionPaths("secrets"), 1)
+	var none *Policy
+	assert.Nil(none.redactionPaths("apps"))
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"context"
+
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink receives audit records, each a single line of compact JSON without
+// the trailing newline.
+type Sink interface {
+	Write(record []byte) error
+	// Start runs the sink until ctx is done.
+	Start(ctx context.Context)
+}
+
+type fileSink struct {
+	output *lumberjack.Logger
+}
+
+// NewFileSink returns a sink writing one record per line to a file that is
+// rotated by size.
+func NewFileSink(path string, maxAge, maxBackup, maxSize int) Sink {
+	return &fileSink{
+		output: &lumberjack.Logger{
+			Filename:   path,
+			MaxAge:     maxAge,
+			MaxBackups: maxBackup,
+			MaxSize:    maxSize,
+		},
+	}
+}
+
+func (f *fileSink) Write(record []byte) error {
+	_, err := f.output.Write(append(record, '\n'))
+	return err
+}
+
+func (f *fileSink) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		f.output.Close()
+	}()
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSink(t *testing.T) {
+	assert := assert.New(t)
+
+	var lock sync.Mutex
+	var batches [][]map[string]string
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []map[string]string
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Nil(json.Unmarshal(body, &batch))
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := NewWebhookSink(server.URL, 2, 50*time.Millisecond, 3)
+	sink.Start(ctx)
+	for _, id := range []string{"1", "2", "3"} {
+		assert.Nil(sink.Write([]byte(`{"auditID":"` + id + `"}`)))
+	}
+
+	assert.Eventually(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(batches) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal([][]map[string]string{
+		{{"auditID": "1"}, {"auditID": "2"}},
+		{{"auditID": "3"}},
+	}, batches, "a full batch is sent at once, the rest after waiting, the failed batch is retried")
+}
+
+func TestFormatSyslogMessage(t *testing.T) {
+	ts := time.Date(2021, 3, 31, 12, 30, 0, 123456789, time.UTC)
+	assert.Equal(t, `<110>1 2021-03-31T12:30:00.123456Z rancher-0 rancher 7 audit - {"auditID":"1"}`,
+		string(formatSyslogMessage(ts, "rancher-0", "rancher", 7, []byte(`{"auditID":"1"}`))))
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	assert := assert.New(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		length, _ := r.ReadString(' ')
+		msg := make([]byte, len(`<110>1 `))
+		r.Read(msg)
+		received <- length + string(msg)
+	}()
+
+	sink, err := NewSyslogSink("tcp://"+l.Addr().String(), "", SyslogTLSOptions{})
+	assert.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink.Start(ctx)
+	assert.Nil(sink.Write([]byte(`{"auditID":"1"}`)))
+	select {
+	case msg := <-received:
+		assert.True(strings.HasSuffix(msg, " <110>1 "), "messages are framed by octet counting: %q", msg)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	_, err = NewSyslogSink("http://localhost:514", "", SyslogTLSOptions{})
+	assert.EqualError(err, "invalid audit syslog address http://localhost:514: scheme must be udp, tcp or tls")
+}
+
+func TestSyslogSinkQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	// nothing is sent before the sink is started, records only queue up
+	sink, err := NewSyslogSink("tcp://192.0.2.1:514", "", SyslogTLSOptions{})
+	assert.Nil(err)
+	for i := 0; i < syslogQueueSize; i++ {
+		assert.Nil(sink.Write([]byte(`{"auditID":"1"}`)))
+	}
+	assert.EqualError(sink.Write([]byte(`{"auditID":"2"}`)), "audit syslog queue is full, dropping record")
+
+	_, err = NewSyslogSink("tls://localhost:6514", "", SyslogTLSOptions{CAFile: "/nonexistent/ca.pem"})
+	assert.Error(err)
+	_, err = NewSyslogSink("tcp://localhost:514", "", SyslogTLSOptions{CAFile: "/nonexistent/ca.pem"})
+	assert.Nil(err, "tls options only apply to tls servers")
+}
@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// facility 13 is log audit, severity 6 informational
+	syslogPriority = 13*8 + 6
+	syslogMsgID    = "audit"
+	syslogNilValue = "-"
+	// RFC 5424 allows at most microseconds
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+	syslogTimeout = 10 * time.Second
+	// messages waiting to be sent, further records are dropped
+	syslogQueueSize = 10000
+)
+
+// SyslogTLSOptions configure the connections of the syslog sink to a
+// tls://host:port server.
+type SyslogTLSOptions struct {
+	// CAFile verifies the server instead of the system roots
+	CAFile string
+	// CertFile and KeyFile authenticate to the server
+	CertFile string
+	KeyFile  string
+}
+
+type syslogSink struct {
+	network   string
+	address   string
+	appName   string
+	hostname  string
+	tlsConfig *tls.Config
+	queue     chan []byte
+
+	// conn is only used by the sender
+	conn net.Conn
+}
+
+// NewSyslogSink returns a sink sending records as RFC 5424 messages to
+// address, which is udp://host:port, tcp://host:port or tls://host:port.
+// Messages sent over tcp and tls are framed by octet counting, RFC 6587.
+// Records are sent in the background; they are dropped while the queue of
+// unsent records is full or when the server fails to take them, so a slow
+// server never blocks requests.
+func NewSyslogSink(address, appName string, tlsOpts SyslogTLSOptions) (Sink, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit syslog address %s: %v", address, err)
+	}
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("invalid audit syslog address %s: scheme must be udp, tcp or tls", address)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid audit syslog address %s: host is required", address)
+	}
+	hostname, _ := os.Hostname()
+	if appName == "" {
+		appName = "rancher"
+	}
+	sink := &syslogSink{
+		network:  u.Scheme,
+		address:  u.Host,
+		appName:  appName,
+		hostname: hostname,
+		queue:    make(chan []byte, syslogQueueSize),
+	}
+	if u.Scheme == "tls" {
+		if sink.tlsConfig, err = newSyslogTLSConfig(tlsOpts); err != nil {
+			return nil, err
+		}
+	}
+	return sink, nil
+}
+
+func newSyslogTLSConfig(opts SyslogTLSOptions) (*tls.Config, error) {
+	config := &tls.Config{}
+	if opts.CAFile != "" {
+		ca, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit syslog CA: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in audit syslog CA %s", opts.CAFile)
+		}
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audit syslog client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (s *syslogSink) Write(record []byte) error {
+	msg := formatSyslogMessage(time.Now(), s.hostname, s.appName, os.Getpid(), record)
+	if s.network != "udp" {
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		return fmt.Errorf("audit syslog queue is full, dropping record")
+	}
+}
+
+func (s *syslogSink) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *syslogSink) run(ctx context.Context) {
+	defer func() {
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+	}()
+	for {
+		select {
+		case msg := <-s.queue:
+			if err := s.send(msg); err != nil {
+				logrus.Warnf("Failed to send audit record to syslog, dropping it: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *syslogSink) send(msg []byte) error {
+	// reconnect once, the server may have closed an idle connection
+	for i := 0; ; i++ {
+		if s.conn == nil {
+			conn, err := s.dial()
+			if err != nil {
+				return err
+			}
+			s.conn = conn
+		}
+		err := s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if err == nil {
+			_, err = s.conn.Write(msg)
+		}
+		if err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+		if i > 0 {
+			return err
+		}
+	}
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	if s.network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig.Clone())
+	}
+	return dialer.Dial(s.network, s.address)
+}
+
+// formatSyslogMessage returns record as an RFC 5424 message without
+// structured data.
+func formatSyslogMessage(t time.Time, hostname, appName string, pid int, record []byte) []byte {
+	if hostname == "" {
+		hostname = syslogNilValue
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s %s ",
+		syslogPriority, t.UTC().Format(syslogTimeFormat), hostname, appName, pid, syslogMsgID, syslogNilValue)
+	return append([]byte(header), record...)
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultWebhookBatchSize    = 100
+	defaultWebhookBatchMaxWait = 5 * time.Second
+	defaultWebhookMaxRetries   = 5
+	// records waiting to be sent, further records are dropped
+	webhookQueueSize = 10000
+)
+
+type webhookSink struct {
+	url          string
+	client       *http.Client
+	batchSize    int
+	batchMaxWait time.Duration
+	maxRetries   int
+	queue        chan []byte
+}
+
+// NewWebhookSink returns a sink posting records to url in batches, as a JSON
+// array. A batch is sent once it holds batchSize records or its first record
+// waited batchMaxWait. Failed batches are retried with exponential backoff up
+// to maxRetries times and then dropped; records are dropped as well while the
+// queue of unsent records is full, so a slow endpoint never blocks requests.
+func NewWebhookSink(url string, batchSize int, batchMaxWait time.Duration, maxRetries int) Sink {
+	if batchSize <= 0 {
+		batchSize = defaultWebhookBatchSize
+	}
+	if batchMaxWait <= 0 {
+		batchMaxWait = defaultWebhookBatchMaxWait
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultWebhookMaxRetries
+	}
+	return &webhookSink{
+		url:          url,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		batchSize:    batchSize,
+		batchMaxWait: batchMaxWait,
+		maxRetries:   maxRetries,
+		queue:        make(chan []byte, webhookQueueSize),
+	}
+}
+
+func (w *webhookSink) Write(record []byte) error {
+	select {
+	case w.queue <- record:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue is full, dropping record")
+	}
+}
+
+func (w *webhookSink) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *webhookSink) run(ctx context.Context) {
+	var batch [][]byte
+	timer := time.NewTimer(w.batchMaxWait)
+	timer.Stop()
+	for {
+		select {
+		case record := <-w.queue:
+			if len(batch) == 0 {
+				timer.Reset(w.batchMaxWait)
+			}
+			batch = append(batch, record)
+			if len(batch) < w.batchSize {
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		case <-ctx.Done():
+			// flush what is queued already, without retrying
+			for len(w.queue) > 0 {
+				batch = append(batch, <-w.queue)
+			}
+			if len(batch) > 0 {
+				if err := w.send(context.Background(), batch); err != nil {
+					logrus.Warnf("Failed to send %d audit records to webhook: %v", len(batch), err)
+				}
+			}
+			return
+		}
+		w.sendWithRetry(ctx, batch)
+		batch = nil
+	}
+}
+
+func (w *webhookSink) sendWithRetry(ctx context.Context, batch [][]byte) {
+	backoff := wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    w.maxRetries + 1,
+	}
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		if lastErr = w.send(ctx, batch); lastErr != nil {
+			return false, ctx.Err()
+		}
+		return true, nil
+	})
+	if err != nil {
+		logrus.Warnf("Failed to send %d audit records to webhook, dropping them: %v", len(batch), lastErr)
+	}
+}
+
+func (w *webhookSink) send(ctx context.Context, batch [][]byte) error {
+	body := append([]byte{'['}, bytes.Join(batch, []byte{','})...)
+	body = append(body, ']')
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package device
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	grantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	csrfCookie = "CSRF"
+	csrfHeader = "X-API-CSRF"
+)
+
+// Handler serves the OAuth 2.0 device authorization grant of RFC 8628. A
+// device gets a user code from DeviceCode, which a user logged in with any
+// auth provider approves with Verify, and then polls Token for a token of
+// the user.
+type Handler struct {
+	manager *Manager
+}
+
+func NewHandler(ctx context.Context, scaledContext *config.ScaledContext) *Handler {
+	manager := newManager(
+		scaledContext.Core.Secrets(""),
+		scaledContext.Core.Secrets("").Controller().Lister(),
+		scaledContext.Management.Tokens("").Controller().Lister(),
+		scaledContext.Management.Users("").Controller().Lister(),
+		tokens.NewManager(ctx, scaledContext),
+	)
+	manager.RunCleanup(ctx.Done())
+	return &Handler{manager: manager}
+}
+
+type deviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// DeviceCode starts the login of a device.
+func (h *Handler) DeviceCode(rw http.ResponseWriter, req *http.Request) {
+	authorization, err := h.manager.Authorize(req)
+	if deviceErr, ok := err.(*Error); ok {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(rateWindow.Seconds())))
+		writeJSON(rw, http.StatusTooManyRequests, deviceErr)
+		return
+	} else if err != nil {
+		logrus.Errorf("Failed to create device authorization: %v", err)
+		writeJSON(rw, http.StatusInternalServerError, &Error{Code: "server_error"})
+		return
+	}
+	verificationURI := serverURL(req) + "/v1-device"
+	userCode := FormatUserCode(authorization.UserCode)
+	writeJSON(rw, http.StatusOK, deviceCodeResponse{
+		DeviceCode:              authorization.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(authorization.ExpiresAt.Sub(authorization.CreatedAt).Seconds()),
+		Interval:                int(authorization.Interval.Seconds()),
+	})
+}
+
+// Token returns the token of a device once its login is approved.
+func (h *Handler) Token(rw http.ResponseWriter, req *http.Request) {
+	if req.PostFormValue("grant_type") != grantType {
+		writeJSON(rw, http.StatusBadRequest, &Error{Code: "unsupported_grant_type"})
+		return
+	}
+	deviceCode := req.PostFormValue("device_code")
+	if deviceCode == "" {
+		writeJSON(rw, http.StatusBadRequest, &Error{Code: "invalid_request", Description: "device_code is required"})
+		return
+	}
+	token, expiresIn, err := h.manager.Token(deviceCode, req)
+	if deviceErr, ok := err.(*Error); ok {
+		writeJSON(rw, http.StatusBadRequest, deviceErr)
+		return
+	} else if err != nil {
+		logrus.Errorf("Failed to create device token: %v", err)
+		writeJSON(rw, http.StatusInternalServerError, &Error{Code: "server_error"})
+		return
+	}
+	writeJSON(rw, http.StatusOK, tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn,
+	})
+}
+
+type verifyRequest struct {
+	UserCode string `json:"userCode"`
+	Approve  bool   `json:"approve"`
+}
+
+type verifyResponse struct {
+	UserCode  string `json:"userCode"`
+	ClientIP  string `json:"clientIp"`
+	UserAgent string `json:"userAgent"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// Verify shows the pending login with the user_code query parameter to the
+// logged in user on GET, and approves or denies it on POST.
+func (h *Handler) Verify(rw http.ResponseWriter, req *http.Request) {
+	if !checkCSRF(rw, req) {
+		writeJSON(rw, http.StatusForbidden, &Error{Code: "access_denied", Description: "invalid CSRF token"})
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		authorization, err := h.manager.Lookup(req.URL.Query().Get("user_code"))
+		if err != nil {
+			writeVerifyError(rw, err)
+			return
+		}
+		writeJSON(rw, http.StatusOK, verifyResponse{
+			UserCode:  FormatUserCode(authorization.UserCode),
+			ClientIP:  authorization.ClientIP,
+			UserAgent: authorization.UserAgent,
+			CreatedAt: authorization.CreatedAt.UTC().Format(time.RFC3339),
+			ExpiresAt: authorization.ExpiresAt.UTC().Format(time.RFC3339),
+		})
+	case http.MethodPost:
+		var input verifyRequest
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			writeJSON(rw, http.StatusBadRequest, &Error{Code: "invalid_request", Description: err.Error()})
+			return
+		}
+		var err error
+		if input.Approve {
+			tokenName, _ := tokens.SplitTokenParts(tokens.GetTokenAuthFromRequest(req))
+			err = h.manager.Approve(input.UserCode, tokenName)
+		} else {
+			err = h.manager.Deny(input.UserCode)
+		}
+		if err != nil {
+			writeVerifyError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeVerifyError(rw http.ResponseWriter, err error) {
+	if err == errInvalid {
+		writeJSON(rw, http.StatusNotFound, &Error{Code: "invalid_grant", Description: "unknown or expired code"})
+		return
+	}
+	writeJSON(rw, http.StatusForbidden, &Error{Code: "access_denied", Description: err.Error()})
+}
+
+// checkCSRF checks that requests authenticated by the session cookie of a
+// browser send the CSRF cookie in the CSRF header, as the API does. It sets
+// the cookie for browsers that have none.
+func checkCSRF(rw http.ResponseWriter, req *http.Request) bool {
+	if _, err := req.Cookie(tokens.CookieName); err != nil || req.Header.Get("Authorization") != "" {
+		return true
+	}
+	cookie, err := req.Cookie(csrfCookie)
+	if err != nil {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return false
+		}
+		cookie = &http.Cookie{
+			Name:   csrfCookie,
+			Value:  hex.EncodeToString(bytes),
+			Path:   "/",
+			Secure: true,
+		}
+		http.SetCookie(rw, cookie)
+	}
+	return req.Method == http.MethodGet || cookie.Value == req.Header.Get(csrfHeader)
+}
+
+func serverURL(req *http.Request) string {
+	if serverURL := settings.ServerURL.Get(); serverURL != "" {
+		return strings.TrimSuffix(serverURL, "/")
+	}
+	return "https://" + req.Host
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(body); err != nil {
+		logrus.Errorf("Failed to write device login response: %v", err)
+	}
+}
+
+// Page is the page users approve the logins of devices on.
+func (h *Handler) Page(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pageTemplate.Execute(rw, req.URL.Query().Get("user_code")); err != nil {
+		logrus.Errorf("Failed to write device login page: %v", err)
+	}
+}
+
+var pageTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Device login</title>
+</head>
+<body>
+<h1>Device login</h1>
+<form id="lookup">
+<label>Code <input id="code" value="{{.}}" autocomplete="off" autofocus></label>
+<button type="submit">Continue</button>
+</form>
+<div id="details" hidden>
+<p>A device at <b id="ip"></b> (<span id="agent"></span>) asks to log in as you. Approve it only if you started this login.</p>
+<button id="approve">Approve</button>
+<button id="deny">Deny</button>
+</div>
+<p id="message"></p>
+<script>
+var code = document.getElementById("code");
+var details = document.getElementById("details");
+var message = document.getElementById("message");
+
+function show(text) {
+  message.textContent = text;
+}
+
+function failed(response) {
+  if (response.status === 401) {
+    message.innerHTML = 'Log in to <a href="/" target="_blank">Rancher</a> first, then try again.';
+  } else if (response.status === 404) {
+    show("The code is unknown or expired.");
+  } else {
+    show("The request failed with status " + response.status + ".");
+  }
+}
+
+function csrf() {
+  var match = document.cookie.match(/(?:^|;\s*)CSRF=([^;]*)/);
+  return match ? match[1] : "";
+}
+
+document.getElementById("lookup").addEventListener("submit", function(event) {
+  event.preventDefault();
+  details.hidden = true;
+  show("");
+  fetch("/v1-device/verify?user_code=" + encodeURIComponent(code.value), {credentials: "same-origin"}).then(function(response) {
+    if (!response.ok) {
+      return failed(response);
+    }
+    return response.json().then(function(authorization) {
+      document.getElementById("ip").textContent = authorization.clientIp;
+      document.getElementById("agent").textContent = authorization.userAgent;
+      details.hidden = false;
+    });
+  });
+});
+
+function verify(approve) {
+  fetch("/v1-device/verify", {
+    method: "POST",
+    credentials: "same-origin",
+    headers: {"Content-Type": "application/json", "X-API-CSRF": csrf()},
+    body: JSON.stringify({userCode: code.value, approve: approve})
+  }).then(function(response) {
+    if (!response.ok) {
+      return failed(response);
+    }
+    details.hidden = true;
+    show(approve ? "The device is logged in. You can close this page." : "The login was denied.");
+  });
+}
+
+document.getElementById("approve").addEventListener("click", function() { verify(true); });
+document.getElementById("deny").addEventListener("click", function() { verify(false); });
+
+if (code.value) {
+  document.getElementById("lookup").dispatchEvent(new Event("submit"));
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,498 @@
+package device
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/auth/util"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/randomtoken"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// pending authorizations are kept in secrets, which all replicas share
+	authorizationPrefix = "device-auth-"
+	authorizationLabel  = "authn.management.cattle.io/device-auth"
+	userCodeLabel       = "authn.management.cattle.io/device-user-code"
+
+	userCodeField  = "userCode"
+	clientIPField  = "clientIP"
+	userAgentField = "userAgent"
+	createdAtField = "createdAt"
+	expiresAtField = "expiresAt"
+	intervalField  = "interval"
+	lastPollField  = "lastPoll"
+	statusField    = "status"
+	userIDField    = "userId"
+	tokenNameField = "tokenName"
+	statusPending  = "pending"
+	statusApproved = "approved"
+	statusDenied   = "denied"
+	// claimed authorizations are approved ones a poll is creating the token
+	// of
+	statusClaimed   = "claimed"
+	defaultInterval = 5 * time.Second
+	cleanupInterval = time.Minute
+	// clients may start auth-device-code-rate-per-client logins per window
+	rateWindow = time.Minute
+
+	// user codes leave out vowels and look-alike characters, so that they are
+	// easy to type and do not spell words
+	userCodeCharacters = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength     = 8
+)
+
+// Error is an error of the token endpoint, as RFC 8628 defines them.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+var (
+	errPending  = &Error{Code: "authorization_pending"}
+	errSlowDown = &Error{Code: "slow_down"}
+	errDenied   = &Error{Code: "access_denied"}
+	errExpired  = &Error{Code: "expired_token"}
+	errInvalid  = &Error{Code: "invalid_grant"}
+
+	errRateLimited    = &Error{Code: "slow_down", Description: "too many device logins from this client"}
+	errTooManyPending = &Error{Code: "slow_down", Description: "too many pending device logins"}
+)
+
+// Authorization is a pending login of a device.
+type Authorization struct {
+	DeviceCode string
+	UserCode   string
+	ClientIP   string
+	UserAgent  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	Interval   time.Duration
+}
+
+// Manager issues device codes, and tokens for them once a logged in user
+// approves them.
+type Manager struct {
+	secrets      v1.SecretInterface
+	secretLister v1.SecretLister
+	tokenLister  v3.TokenLister
+	userLister   v3.UserLister
+	tokenMGR     tokenCreator
+	now          func() time.Time
+
+	// recent are the times clients recently started logins at, which are
+	// limited per client IP
+	recent     map[string][]time.Time
+	recentLock sync.Mutex
+}
+
+type tokenCreator interface {
+	NewLoginToken(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string, req *http.Request) (v3.Token, string, error)
+	GetGroupsForTokenAuthProvider(token *v3.Token) []v3.Principal
+}
+
+func newManager(secrets v1.SecretInterface, secretLister v1.SecretLister, tokenLister v3.TokenLister, userLister v3.UserLister, tokenMGR tokenCreator) *Manager {
+	return &Manager{
+		secrets:      secrets,
+		secretLister: secretLister,
+		tokenLister:  tokenLister,
+		userLister:   userLister,
+		tokenMGR:     tokenMGR,
+		now:          time.Now,
+		recent:       map[string][]time.Time{},
+	}
+}
+
+func secretName(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return authorizationPrefix + hex.EncodeToString(sum[:16])
+}
+
+func newUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeCharacters)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeCharacters[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode drops the separators and case users may type user codes
+// with.
+func normalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+}
+
+// FormatUserCode formats user codes for users to read.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+// Authorize starts the login of the device making req. Clients starting too
+// many logins, or too many pending logins overall, are told to slow down.
+func (m *Manager) Authorize(req *http.Request) (*Authorization, error) {
+	clientIP := util.GetClientIP(req)
+	if !m.allow(clientIP) {
+		return nil, errRateLimited
+	}
+	if err := m.checkPending(); err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := randomtoken.Generate()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	authorization := &Authorization{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ClientIP:   clientIP,
+		UserAgent:  req.UserAgent(),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(time.Duration(settings.AuthDeviceCodeTTLMinutes.GetInt()) * time.Minute),
+		Interval:   defaultInterval,
+	}
+	_, err = m.secrets.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(deviceCode),
+			Namespace: namespace.System,
+			Labels: map[string]string{
+				authorizationLabel: "true",
+				userCodeLabel:      userCode,
+			},
+		},
+		Data: map[string][]byte{
+			userCodeField:  []byte(userCode),
+			clientIPField:  []byte(authorization.ClientIP),
+			userAgentField: []byte(authorization.UserAgent),
+			createdAtField: []byte(now.UTC().Format(time.RFC3339)),
+			expiresAtField: []byte(authorization.ExpiresAt.UTC().Format(time.RFC3339)),
+			intervalField:  []byte(strconv.Itoa(int(authorization.Interval.Seconds()))),
+			statusField:    []byte(statusPending),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+// allow records a login started by clientIP, unless it started as many as
+// the auth-device-code-rate-per-client setting allows within the rate window.
+// Clients are tracked by each replica.
+func (m *Manager) allow(clientIP string) bool {
+	limit := settings.AuthDeviceCodeRatePerClient.GetInt()
+	if limit <= 0 {
+		return true
+	}
+	m.recentLock.Lock()
+	defer m.recentLock.Unlock()
+
+	now := m.now()
+	recent := pruneRecent(m.recent[clientIP], now)
+	if len(recent) >= limit {
+		m.recent[clientIP] = recent
+		return false
+	}
+	m.recent[clientIP] = append(recent, now)
+	return true
+}
+
+func pruneRecent(times []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= rateWindow {
+		i++
+	}
+	return times[i:]
+}
+
+// checkPending returns an error if there are as many pending authorizations
+// as the auth-device-max-pending setting allows.
+func (m *Manager) checkPending() error {
+	limit := settings.AuthDeviceMaxPending.GetInt()
+	if limit <= 0 {
+		return nil
+	}
+	secrets, err := m.secretLister.List(namespace.System, labels.SelectorFromSet(labels.Set{authorizationLabel: "true"}))
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	pending := 0
+	for _, secret := range secrets {
+		if now.Before(m.toAuthorization(secret).ExpiresAt) {
+			pending++
+		}
+	}
+	if pending >= limit {
+		logrus.Warnf("Refusing device login, %d device logins are pending", pending)
+		return errTooManyPending
+	}
+	return nil
+}
+
+// Lookup returns the pending authorization with userCode.
+func (m *Manager) Lookup(userCode string) (*Authorization, error) {
+	secret, err := m.getByUserCode(userCode)
+	if err != nil {
+		return nil, err
+	}
+	return m.toAuthorization(secret), nil
+}
+
+// Approve approves the pending authorization with userCode, for the user of
+// the login token with tokenName. The device gets a token of the user on its
+// next poll.
+func (m *Manager) Approve(userCode, tokenName string) error {
+	token, err := m.tokenLister.Get("", tokenName)
+	if err != nil {
+		return err
+	}
+	if token.IsDerived {
+		return fmt.Errorf("devices can only be approved from a login session")
+	}
+	return m.setStatus(userCode, statusApproved, token)
+}
+
+// Deny denies the pending authorization with userCode.
+func (m *Manager) Deny(userCode string) error {
+	return m.setStatus(userCode, statusDenied, nil)
+}
+
+func (m *Manager) setStatus(userCode, status string, token *v3.Token) error {
+	secret, err := m.getByUserCode(userCode)
+	if err != nil {
+		return err
+	}
+	secret = secret.DeepCopy()
+	if string(secret.Data[statusField]) != statusPending {
+		return errInvalid
+	}
+	secret.Data[statusField] = []byte(status)
+	if token != nil {
+		secret.Data[userIDField] = []byte(token.UserID)
+		secret.Data[tokenNameField] = []byte(token.Name)
+		logrus.Infof("User %s approved the login of device %s (%s)", token.UserID, secret.Data[clientIPField], secret.Data[userAgentField])
+	}
+	_, err = m.secrets.Update(secret)
+	return err
+}
+
+// Token returns the token of the device with deviceCode once its login is
+// approved, or the error telling the device how to go on. Tokens are given
+// once, to the first poll after the approval.
+func (m *Manager) Token(deviceCode string, req *http.Request) (string, int64, error) {
+	secret, err := m.secrets.GetNamespaced(namespace.System, secretName(deviceCode), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", 0, errInvalid
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	now := m.now()
+	authorization := m.toAuthorization(secret)
+	if !now.Before(authorization.ExpiresAt) {
+		m.delete(secret)
+		return "", 0, errExpired
+	}
+
+	switch string(secret.Data[statusField]) {
+	case statusDenied:
+		m.delete(secret)
+		return "", 0, errDenied
+	case statusApproved:
+		return m.claim(secret, req)
+	case statusClaimed:
+		return "", 0, errPending
+	}
+
+	lastPoll, _ := time.Parse(time.RFC3339Nano, string(secret.Data[lastPollField]))
+	secret = secret.DeepCopy()
+	secret.Data[lastPollField] = []byte(now.UTC().Format(time.RFC3339Nano))
+	pollErr := errPending
+	if now.Sub(lastPoll) < authorization.Interval {
+		// devices polling too often must wait longer
+		pollErr = errSlowDown
+		secret.Data[intervalField] = []byte(strconv.Itoa(int((authorization.Interval + defaultInterval).Seconds())))
+	}
+	if _, err := m.secrets.Update(secret); err != nil && !apierrors.IsConflict(err) {
+		return "", 0, err
+	}
+	return "", 0, pollErr
+}
+
+// claim creates the token of an approved authorization. Claiming the
+// authorization first ensures only one poll gets the token, and it is only
+// deleted once the token exists so that a failure to create it can be
+// retried by the next poll.
+func (m *Manager) claim(secret *corev1.Secret, req *http.Request) (string, int64, error) {
+	claimed := secret.DeepCopy()
+	claimed.Data[statusField] = []byte(statusClaimed)
+	claimed, err := m.secrets.Update(claimed)
+	if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+		// another poll claimed it
+		return "", 0, errPending
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	token, expiresIn, err := m.newToken(claimed, req)
+	if err == errDenied {
+		m.delete(claimed)
+		return "", 0, err
+	} else if err != nil {
+		claimed = claimed.DeepCopy()
+		claimed.Data[statusField] = []byte(statusApproved)
+		if _, updateErr := m.secrets.Update(claimed); updateErr != nil {
+			logrus.Errorf("Failed to release device authorization %s: %v", claimed.Name, updateErr)
+		}
+		return "", 0, err
+	}
+
+	if err := m.delete(claimed); err != nil && !apierrors.IsNotFound(err) {
+		// it is claimed, so no other poll gets a token before it expires
+		logrus.Errorf("Failed to delete device authorization %s: %v", claimed.Name, err)
+	}
+	return token, expiresIn, nil
+}
+
+// newToken creates a login token for the device, for the user who approved
+// its login and with the groups of the session they approved it with.
+func (m *Manager) newToken(secret *corev1.Secret, req *http.Request) (string, int64, error) {
+	userID := string(secret.Data[userIDField])
+	approval, err := m.tokenLister.Get("", string(secret.Data[tokenNameField]))
+	if err != nil || approval.UserID != userID || tokens.IsExpired(*approval) {
+		// the session the login was approved with is gone
+		return "", 0, errDenied
+	}
+	user, err := m.userLister.Get("", userID)
+	if err != nil {
+		return "", 0, errDenied
+	}
+	if user.Enabled != nil && !*user.Enabled {
+		return "", 0, errDenied
+	}
+
+	ttl := int64(settings.AuthDeviceTokenTTLMinutes.GetInt()) * 60 * 1000
+	groups := m.tokenMGR.GetGroupsForTokenAuthProvider(approval)
+	token, key, err := m.tokenMGR.NewLoginToken(userID, approval.UserPrincipal, groups, "", ttl, "Device login", req)
+	if err != nil {
+		return "", 0, err
+	}
+	logrus.Infof("Created token %s for the device login of user %s", token.Name, userID)
+	return token.Name + ":" + key, ttl / 1000, nil
+}
+
+func (m *Manager) getByUserCode(userCode string) (*corev1.Secret, error) {
+	secrets, err := m.secretLister.List(namespace.System, labels.SelectorFromSet(labels.Set{
+		authorizationLabel: "true",
+		userCodeLabel:      normalizeUserCode(userCode),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		if string(secret.Data[statusField]) == statusPending && m.now().Before(m.toAuthorization(secret).ExpiresAt) {
+			return secret, nil
+		}
+	}
+	return nil, errInvalid
+}
+
+func (m *Manager) toAuthorization(secret *corev1.Secret) *Authorization {
+	authorization := &Authorization{
+		UserCode:  string(secret.Data[userCodeField]),
+		ClientIP:  string(secret.Data[clientIPField]),
+		UserAgent: string(secret.Data[userAgentField]),
+		Interval:  defaultInterval,
+	}
+	authorization.CreatedAt, _ = time.Parse(time.RFC3339, string(secret.Data[createdAtField]))
+	authorization.ExpiresAt, _ = time.Parse(time.RFC3339, string(secret.Data[expiresAtField]))
+	if seconds, err := strconv.Atoi(string(secret.Data[intervalField])); err == nil {
+		authorization.Interval = time.Duration(seconds) * time.Second
+	}
+	return authorization
+}
+
+// delete deletes the authorization of secret, unless it changed since it was
+// read.
+func (m *Manager) delete(secret *corev1.Secret) error {
+	return m.secrets.DeleteNamespaced(secret.Namespace, secret.Name, &metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			UID:             &secret.UID,
+			ResourceVersion: &secret.ResourceVersion,
+		},
+	})
+}
+
+// RunCleanup periodically deletes the authorizations that expired without
+// devices polling them.
+func (m *Manager) RunCleanup(stop <-chan struct{}) {
+	go wait.JitterUntil(m.cleanup, cleanupInterval, .1, true, stop)
+}
+
+func (m *Manager) cleanup() {
+	m.recentLock.Lock()
+	for clientIP, times := range m.recent {
+		if times = pruneRecent(times, m.now()); len(times) == 0 {
+			delete(m.recent, clientIP)
+		} else {
+			m.recent[clientIP] = times
+		}
+	}
+	m.recentLock.Unlock()
+
+	secrets, err := m.secretLister.List(namespace.System, labels.SelectorFromSet(labels.Set{authorizationLabel: "true"}))
+	if err != nil {
+		logrus.Errorf("Failed to list device authorizations: %v", err)
+		return
+	}
+	now := m.now()
+	for _, secret := range secrets {
+		if now.Before(m.toAuthorization(secret).ExpiresAt) {
+			continue
+		}
+		if err := m.delete(secret); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			logrus.Errorf("Failed to delete expired device authorization %s: %v", secret.Name, err)
+		}
+	}
+}
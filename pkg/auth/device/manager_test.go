@@ -0,0 +1,286 @@
+package device
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeTokenCreator struct {
+	created []v3.Token
+	err     error
+}
+
+func (f *fakeTokenCreator) NewLoginToken(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string, req *http.Request) (v3.Token, string, error) {
+	if f.err != nil {
+		return v3.Token{}, "", f.err
+	}
+	token := v3.Token{
+		ObjectMeta:    metav1.ObjectMeta{Name: "token-device"},
+		UserID:        userID,
+		UserPrincipal: userPrincipal,
+		TTLMillis:     ttl,
+		ClientIP:      req.RemoteAddr,
+	}
+	f.created = append(f.created, token)
+	return token, "key", nil
+}
+
+func (f *fakeTokenCreator) GetGroupsForTokenAuthProvider(token *v3.Token) []v3.Principal {
+	return nil
+}
+
+func newTestManager() (*Manager, map[string]*corev1.Secret, map[string]*v3.User, *fakeTokenCreator, *time.Time) {
+	secrets := map[string]*corev1.Secret{}
+	users := map[string]*v3.User{
+		"u-abcde": {ObjectMeta: metav1.ObjectMeta{Name: "u-abcde"}},
+	}
+	tokens := map[string]*v3.Token{
+		"token-session": {
+			ObjectMeta:    metav1.ObjectMeta{Name: "token-session", CreationTimestamp: metav1.Now()},
+			UserID:        "u-abcde",
+			UserPrincipal: v3.Principal{ObjectMeta: metav1.ObjectMeta{Name: "github_user://1"}, Provider: "github"},
+		},
+		"token-apikey": {
+			ObjectMeta: metav1.ObjectMeta{Name: "token-apikey"},
+			UserID:     "u-abcde",
+			IsDerived:  true,
+		},
+	}
+	notFound := func(resource, name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+	get := func(namespace, name string) (*corev1.Secret, error) {
+		if secret, ok := secrets[name]; ok {
+			return secret, nil
+		}
+		return nil, notFound("secrets", name)
+	}
+	version := 0
+	save := func(secret *corev1.Secret) (*corev1.Secret, error) {
+		if current, ok := secrets[secret.Name]; ok && current.ResourceVersion != secret.ResourceVersion {
+			return nil, apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, secret.Name, nil)
+		}
+		version++
+		secret = secret.DeepCopy()
+		secret.ResourceVersion = strconv.Itoa(version)
+		secrets[secret.Name] = secret
+		return secret, nil
+	}
+	creator := &fakeTokenCreator{}
+	now := time.Unix(1600000000, 0)
+	m := newManager(
+		&corefakes.SecretInterfaceMock{
+			GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+				return get(namespace, name)
+			},
+			CreateFunc: save,
+			UpdateFunc: func(secret *corev1.Secret) (*corev1.Secret, error) {
+				if _, ok := secrets[secret.Name]; !ok {
+					return nil, notFound("secrets", secret.Name)
+				}
+				return save(secret)
+			},
+			DeleteNamespacedFunc: func(namespace, name string, options *metav1.DeleteOptions) error {
+				if _, ok := secrets[name]; !ok {
+					return notFound("secrets", name)
+				}
+				delete(secrets, name)
+				return nil
+			},
+		},
+		&corefakes.SecretListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+				var result []*corev1.Secret
+				for _, secret := range secrets {
+					if selector.Matches(labels.Set(secret.Labels)) {
+						result = append(result, secret)
+					}
+				}
+				return result, nil
+			},
+		},
+		&fakes.TokenListerMock{
+			GetFunc: func(namespace, name string) (*v3.Token, error) {
+				if token, ok := tokens[name]; ok {
+					return token, nil
+				}
+				return nil, notFound("tokens", name)
+			},
+		},
+		&fakes.UserListerMock{
+			GetFunc: func(namespace, name string) (*v3.User, error) {
+				if user, ok := users[name]; ok {
+					return user, nil
+				}
+				return nil, notFound("users", name)
+			},
+		},
+		creator,
+	)
+	m.now = func() time.Time {
+		return now
+	}
+	return m, secrets, users, creator, &now
+}
+
+func newDeviceRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1-device/code", nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	req.Header.Set("User-Agent", "rancher-cli")
+	return req
+}
+
+func TestApprove(t *testing.T) {
+	assert := assert.New(t)
+	m, secrets, _, creator, now := newTestManager()
+
+	authorization, err := m.Authorize(newDeviceRequest())
+	assert.Nil(err)
+	assert.Len(authorization.UserCode, 8)
+	_, _, err = m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Equal(errPending, err)
+	_, _, err = m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Equal(errSlowDown, err, "devices must wait between polls")
+	*now = now.Add(10 * time.Second)
+	_, _, err = m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Equal(errPending, err, "slow downs make the interval longer")
+
+	lookup, err := m.Lookup(FormatUserCode(authorization.UserCode))
+	assert.Nil(err)
+	assert.Equal("10.0.0.1", lookup.ClientIP)
+	assert.Equal("rancher-cli", lookup.UserAgent)
+	_, err = m.Lookup("BCDF-GHJK")
+	assert.Equal(errInvalid, err)
+
+	assert.Error(m.Approve(authorization.UserCode, "token-apikey"), "API keys can not approve devices")
+	assert.Nil(m.Approve(authorization.UserCode, "token-session"))
+	assert.Equal(errInvalid, m.Deny(authorization.UserCode), "approved logins can not be denied")
+
+	token, expiresIn, err := m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Nil(err)
+	assert.Equal("token-device:key", token)
+	assert.Equal(int64(960*60), expiresIn)
+	assert.Len(creator.created, 1)
+	assert.Equal("u-abcde", creator.created[0].UserID)
+	assert.Equal("github_user://1", creator.created[0].UserPrincipal.Name)
+	assert.Equal("10.0.0.1:40000", creator.created[0].ClientIP, "tokens record the device")
+	assert.Empty(secrets)
+
+	_, _, err = m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Equal(errInvalid, err, "tokens are given once")
+}
+
+func TestDenyAndExpire(t *testing.T) {
+	assert := assert.New(t)
+	m, secrets, users, creator, now := newTestManager()
+
+	denied, _ := m.Authorize(newDeviceRequest())
+	assert.Nil(m.Deny(denied.UserCode))
+	_, _, err := m.Token(denied.DeviceCode, newDeviceRequest())
+	assert.Equal(errDenied, err)
+
+	expired, _ := m.Authorize(newDeviceRequest())
+	*now = now.Add(10 * time.Minute)
+	assert.Equal(errInvalid, m.Approve(expired.UserCode, "token-session"))
+	_, _, err = m.Token(expired.DeviceCode, newDeviceRequest())
+	assert.Equal(errExpired, err)
+
+	disabled, _ := m.Authorize(newDeviceRequest())
+	assert.Nil(m.Approve(disabled.UserCode, "token-session"))
+	enabled := false
+	users["u-abcde"].Enabled = &enabled
+	_, _, err = m.Token(disabled.DeviceCode, newDeviceRequest())
+	assert.Equal(errDenied, err, "disabled users do not get tokens")
+	assert.Empty(creator.created)
+
+	m.Authorize(newDeviceRequest())
+	*now = now.Add(10 * time.Minute)
+	m.cleanup()
+	assert.Empty(secrets)
+}
+
+func TestTokenCreationFailure(t *testing.T) {
+	assert := assert.New(t)
+	m, secrets, _, creator, _ := newTestManager()
+
+	authorization, _ := m.Authorize(newDeviceRequest())
+	assert.Nil(m.Approve(authorization.UserCode, "token-session"))
+	creator.err = errors.New("tokens are unavailable")
+	_, _, err := m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.EqualError(err, "tokens are unavailable")
+	assert.Len(secrets, 1, "the authorization is kept until the token exists")
+
+	creator.err = nil
+	token, _, err := m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Nil(err)
+	assert.Equal("token-device:key", token)
+	assert.Empty(secrets)
+}
+
+func TestTokenClaimed(t *testing.T) {
+	assert := assert.New(t)
+	m, secrets, _, creator, _ := newTestManager()
+
+	authorization, _ := m.Authorize(newDeviceRequest())
+	assert.Nil(m.Approve(authorization.UserCode, "token-session"))
+	// a concurrent poll read the authorization before this one claimed it
+	var stale *corev1.Secret
+	for _, secret := range secrets {
+		stale = secret.DeepCopy()
+	}
+	_, _, err := m.Token(authorization.DeviceCode, newDeviceRequest())
+	assert.Nil(err)
+	_, _, err = m.claim(stale, newDeviceRequest())
+	assert.Equal(errPending, err, "only one poll gets the token")
+	assert.Len(creator.created, 1)
+}
+
+func TestAuthorizeLimits(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.AuthDeviceCodeRatePerClient.Set(settings.AuthDeviceCodeRatePerClient.Get())
+	defer settings.AuthDeviceMaxPending.Set(settings.AuthDeviceMaxPending.Get())
+	m, secrets, _, _, now := newTestManager()
+
+	settings.AuthDeviceCodeRatePerClient.Set("2")
+	settings.AuthDeviceMaxPending.Set("3")
+	for i := 0; i < 2; i++ {
+		_, err := m.Authorize(newDeviceRequest())
+		assert.Nil(err)
+	}
+	_, err := m.Authorize(newDeviceRequest())
+	assert.Equal(errRateLimited, err)
+
+	other := newDeviceRequest()
+	other.RemoteAddr = "10.0.0.2:40000"
+	_, err = m.Authorize(other)
+	assert.Nil(err, "clients are limited separately")
+	other.RemoteAddr = "10.0.0.3:40000"
+	_, err = m.Authorize(other)
+	assert.Equal(errTooManyPending, err)
+	assert.Len(secrets, 3)
+
+	*now = now.Add(rateWindow)
+	_, err = m.Authorize(newDeviceRequest())
+	assert.Equal(errTooManyPending, err, "the rate window passed but the logins are still pending")
+	*now = now.Add(10 * time.Minute)
+	_, err = m.Authorize(newDeviceRequest())
+	assert.Nil(err, "expired logins are not pending")
+
+	m.cleanup()
+	assert.Len(m.recent, 1)
+}
@@ -0,0 +1,425 @@
+package lockout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/audit"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+const (
+	// failed logins are counted in secrets, which all replicas share
+	attemptsNamespace = "cattle-system"
+	attemptsPrefix    = "login-attempts-"
+	attemptsLabel     = "authn.management.cattle.io/login-attempts"
+	// the hashed source IP that created the secret of a username
+	sourceLabel = "authn.management.cattle.io/login-attempts-source"
+	// secrets of failed logins, beyond which the failures of usernames other
+	// than local users and of source IPs are kept in memory
+	maxPersistedAttempts = 5000
+
+	keyField         = "key"
+	failuresField    = "failures"
+	lastFailureField = "lastFailure"
+	lockedUntilField = "lockedUntil"
+
+	localProvider   = "local"
+	cleanupInterval = time.Minute
+
+	lockedAnnotation   = "authn.management.cattle.io/locked"
+	blockedAnnotation  = "authn.management.cattle.io/login-blocked"
+	unlockedAnnotation = "authn.management.cattle.io/unlocked"
+
+	usernameIndex = "authn.management.cattle.io/lockout-username-index"
+)
+
+// Manager tracks the failed logins of usernames and source IPs, locking them
+// out of the login API once they fail too often. The failed logins are shared
+// by all replicas, except those of unknown local usernames and those beyond
+// the caps on secrets, which each replica keeps.
+type Manager struct {
+	secrets      v1.SecretInterface
+	secretLister v1.SecretLister
+	users        v3.UserInterface
+	userIndexer  cache.Indexer
+	memory       *memoryAttempts
+	now          func() time.Time
+}
+
+func NewManager(mgmt *config.ScaledContext) *Manager {
+	informer := mgmt.Management.Users("").Controller().Informer()
+	informer.AddIndexers(map[string]cache.IndexFunc{usernameIndex: usernameIndexer})
+
+	return &Manager{
+		secrets:      mgmt.Core.Secrets(""),
+		secretLister: mgmt.Core.Secrets("").Controller().Lister(),
+		users:        mgmt.Management.Users(""),
+		userIndexer:  informer.GetIndexer(),
+		memory:       newMemoryAttempts(),
+		now:          time.Now,
+	}
+}
+
+func usernameIndexer(obj interface{}) ([]string, error) {
+	user, ok := obj.(*v3.User)
+	if !ok || user.Username == "" {
+		return []string{}, nil
+	}
+	return []string{strings.ToLower(user.Username)}, nil
+}
+
+// attempts are the recent failed logins of a username or source IP.
+type attempts struct {
+	key         string
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func userKey(provider, username string) string {
+	return "user:" + provider + "/" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func secretName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return attemptsPrefix + hex.EncodeToString(sum[:16])
+}
+
+// Check returns how long a login of username with provider, or from ip, must
+// wait because they are locked out or backing off from failed logins. Either
+// may be empty when the login does not tell them.
+func (m *Manager) Check(ctx context.Context, provider, username, ip string) time.Duration {
+	now := m.now()
+	var wait time.Duration
+	if username != "" {
+		a := m.get(userKey(provider, username))
+		if a.lockedUntil.After(now) {
+			audit.AddAnnotation(ctx, blockedAnnotation, fmt.Sprintf("user %s of provider %s is locked until %s", username, provider, a.lockedUntil.Format(time.RFC3339)))
+			return a.lockedUntil.Sub(now)
+		}
+		wait = backoff(a, now)
+	}
+	if ip != "" {
+		a := m.get(ipKey(ip))
+		if a.lockedUntil.After(now) {
+			audit.AddAnnotation(ctx, blockedAnnotation, fmt.Sprintf("source %s is locked until %s", ip, a.lockedUntil.Format(time.RFC3339)))
+			return a.lockedUntil.Sub(now)
+		}
+	}
+	return wait
+}
+
+// Failed records a failed login, locking out username or ip once they reach
+// their maximum of failures.
+func (m *Manager) Failed(ctx context.Context, provider, username, ip string) {
+	if username != "" && (settings.AuthLockoutUserMaxFailures.GetInt() > 0 || settings.AuthLoginBackoffBaseSeconds.GetInt() > 0) {
+		key := userKey(provider, username)
+		var persist bool
+		if provider == localProvider {
+			// anyone can fail logins with made up local usernames
+			persist = m.isLocalUser(username)
+		} else {
+			persist = m.canPersist(key, ip)
+		}
+		a, locked, err := m.recordFailure(key, ip, persist, settings.AuthLockoutUserMaxFailures.GetInt())
+		if err != nil {
+			logrus.Errorf("Failed to record failed login of user %s: %v", username, err)
+		} else if locked {
+			logrus.Infof("Locking out user %s of provider %s until %s after %d failed logins", username, provider, a.lockedUntil.Format(time.RFC3339), settings.AuthLockoutUserMaxFailures.GetInt())
+			audit.AddAnnotation(ctx, lockedAnnotation, fmt.Sprintf("user %s of provider %s is locked until %s", username, provider, a.lockedUntil.Format(time.RFC3339)))
+			if provider == localProvider {
+				m.setLocalUserLocked(username, a.lockedUntil)
+			}
+		}
+	}
+	if ip != "" && settings.AuthLockoutIPMaxFailures.GetInt() > 0 {
+		key := ipKey(ip)
+		a, locked, err := m.recordFailure(key, "", m.canPersist(key, ""), settings.AuthLockoutIPMaxFailures.GetInt())
+		if err != nil {
+			logrus.Errorf("Failed to record failed login from %s: %v", ip, err)
+		} else if locked {
+			logrus.Infof("Locking out logins from %s until %s after %d failed logins", ip, a.lockedUntil.Format(time.RFC3339), settings.AuthLockoutIPMaxFailures.GetInt())
+			audit.AddAnnotation(ctx, lockedAnnotation, fmt.Sprintf("source %s is locked until %s", ip, a.lockedUntil.Format(time.RFC3339)))
+		}
+	}
+}
+
+// Succeeded forgets the failed logins of username. Those of the source IP are
+// kept, a successful login of one account must not clear the attempts on
+// others.
+func (m *Manager) Succeeded(provider, username string) {
+	if username == "" {
+		return
+	}
+	m.memory.forget(userKey(provider, username))
+	if _, err := m.secretLister.Get(attemptsNamespace, secretName(userKey(provider, username))); err != nil {
+		return
+	}
+	if err := m.clear(provider, username); err != nil {
+		logrus.Errorf("Failed to clear failed logins of user %s: %v", username, err)
+	}
+}
+
+// Unlock forgets the failed logins of a local user, unlocking them.
+func (m *Manager) Unlock(ctx context.Context, user *v3.User) error {
+	if user.Username == "" {
+		return fmt.Errorf("only local users can be unlocked, the lockout of user %s expires after %s minutes", user.Name, settings.AuthLockoutDurationMinutes.Get())
+	}
+	if err := m.clear(localProvider, user.Username); err != nil {
+		return err
+	}
+	audit.AddAnnotation(ctx, unlockedAnnotation, fmt.Sprintf("user %s was unlocked", user.Username))
+	return nil
+}
+
+func (m *Manager) clear(provider, username string) error {
+	m.memory.forget(userKey(provider, username))
+	err := m.secrets.DeleteNamespaced(attemptsNamespace, secretName(userKey(provider, username)), &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if provider == localProvider {
+		m.setLocalUserLocked(username, time.Time{})
+	}
+	return nil
+}
+
+// RunCleanup periodically deletes the failed logins which expired, and marks
+// local users whose lockout expired as unlocked.
+func (m *Manager) RunCleanup(ctx context.Context) {
+	go wait.JitterUntil(m.cleanup, cleanupInterval, .1, true, ctx.Done())
+}
+
+func (m *Manager) cleanup() {
+	now := m.now()
+	window := time.Duration(settings.AuthLockoutFailureWindowMinutes.GetInt()) * time.Minute
+	m.memory.prune(now, window)
+
+	secrets, err := m.secretLister.List(attemptsNamespace, labels.SelectorFromSet(labels.Set{attemptsLabel: "true"}))
+	if err != nil {
+		logrus.Errorf("Error listing failed logins during cleanup: %v", err)
+		return
+	}
+	for _, secret := range secrets {
+		a := fromSecret(secret)
+		if a.lockedUntil.After(now) || now.Sub(a.lastFailure) <= window {
+			continue
+		}
+		err := m.secrets.DeleteNamespaced(attemptsNamespace, secret.Name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logrus.Errorf("Error deleting failed logins %s: %v", secret.Name, err)
+			continue
+		}
+		if strings.HasPrefix(a.key, userKey(localProvider, "")) && !a.lockedUntil.IsZero() {
+			m.setLocalUserLocked(strings.TrimPrefix(a.key, userKey(localProvider, "")), time.Time{})
+		}
+	}
+}
+
+// backoff returns how long the next login must wait after the failures of a.
+func backoff(a *attempts, now time.Time) time.Duration {
+	base := time.Duration(settings.AuthLoginBackoffBaseSeconds.GetInt()) * time.Second
+	max := time.Duration(settings.AuthLoginBackoffMaxSeconds.GetInt()) * time.Second
+	if a.failures == 0 || base <= 0 {
+		return 0
+	}
+	delay := max
+	if a.failures <= 30 && base<<uint(a.failures-1) < max {
+		delay = base << uint(a.failures-1)
+	}
+	if wait := a.lastFailure.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (m *Manager) get(key string) *attempts {
+	secret, err := m.secretLister.Get(attemptsNamespace, secretName(key))
+	if err == nil {
+		return fromSecret(secret)
+	}
+	if !apierrors.IsNotFound(err) {
+		logrus.Errorf("Failed to get failed logins: %v", err)
+	}
+	if a, ok := m.memory.get(key); ok {
+		return a
+	}
+	return &attempts{key: key}
+}
+
+// canPersist returns whether the failures of key, failing from source, may be
+// written to a secret. Keys with a secret may, keys kept in memory may not,
+// and new keys may as long as there are fewer than maxPersistedAttempts
+// secrets, and source, if any, created fewer than maxSourceAttempts of them
+// within the failure window.
+func (m *Manager) canPersist(key, source string) bool {
+	if _, err := m.secretLister.Get(attemptsNamespace, secretName(key)); err == nil {
+		return true
+	}
+	if _, ok := m.memory.get(key); ok {
+		return false
+	}
+	secrets, err := m.secretLister.List(attemptsNamespace, labels.SelectorFromSet(labels.Set{attemptsLabel: "true"}))
+	if err != nil || len(secrets) >= maxPersistedAttempts {
+		return false
+	}
+	if source == "" {
+		return true
+	}
+	secrets, err = m.secretLister.List(attemptsNamespace, labels.SelectorFromSet(labels.Set{sourceLabel: sourceHash(source)}))
+	return err == nil && len(secrets) < maxSourceAttempts
+}
+
+func sourceHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:16])
+}
+
+// recordFailure counts a failure of key from source, which may be empty,
+// returning whether it locked key. The failure is written to the secret of
+// key if persist is set, and kept in memory otherwise, as few keys per source
+// as maxSourceAttempts allows.
+func (m *Manager) recordFailure(key, source string, persist bool, maxFailures int) (*attempts, bool, error) {
+	if !persist {
+		a, locked := m.memory.fail(key, source, func(a *attempts) bool {
+			return m.fail(a, maxFailures)
+		})
+		return a, locked, nil
+	}
+
+	var a *attempts
+	var locked bool
+	name := secretName(key)
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		secret, err := m.secrets.GetNamespaced(attemptsNamespace, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: attemptsNamespace,
+					Labels:    map[string]string{attemptsLabel: "true"},
+				},
+			}
+			if source != "" {
+				secret.Labels[sourceLabel] = sourceHash(source)
+			}
+		} else if err != nil {
+			return err
+		} else {
+			secret = secret.DeepCopy()
+		}
+
+		a = fromSecret(secret)
+		a.key = key
+		locked = m.fail(a, maxFailures)
+		a.toSecret(secret)
+
+		if secret.ResourceVersion == "" {
+			_, err = m.secrets.Create(secret)
+		} else {
+			_, err = m.secrets.Update(secret)
+		}
+		return err
+	})
+	return a, locked, err
+}
+
+// isLocalUser returns whether username is an existing local user.
+func (m *Manager) isLocalUser(username string) bool {
+	users, err := m.userIndexer.ByIndex(usernameIndex, strings.ToLower(username))
+	return err == nil && len(users) > 0
+}
+
+// fail counts a failure in a, locking it once it reaches maxFailures, and
+// returns whether it did.
+func (m *Manager) fail(a *attempts, maxFailures int) bool {
+	now := m.now()
+	window := time.Duration(settings.AuthLockoutFailureWindowMinutes.GetInt()) * time.Minute
+	if now.Sub(a.lastFailure) > window {
+		a.failures = 0
+	}
+	a.failures++
+	a.lastFailure = now
+	if maxFailures <= 0 || a.failures < maxFailures || a.lockedUntil.After(now) {
+		return false
+	}
+	a.lockedUntil = now.Add(time.Duration(settings.AuthLockoutDurationMinutes.GetInt()) * time.Minute)
+	// the failures start over once the lockout expires
+	a.failures = 0
+	return true
+}
+
+func fromSecret(secret *corev1.Secret) *attempts {
+	a := &attempts{key: string(secret.Data[keyField])}
+	a.failures, _ = strconv.Atoi(string(secret.Data[failuresField]))
+	a.lastFailure, _ = time.Parse(time.RFC3339, string(secret.Data[lastFailureField]))
+	a.lockedUntil, _ = time.Parse(time.RFC3339, string(secret.Data[lockedUntilField]))
+	return a
+}
+
+func (a *attempts) toSecret(secret *corev1.Secret) {
+	secret.Data = map[string][]byte{
+		keyField:         []byte(a.key),
+		failuresField:    []byte(strconv.Itoa(a.failures)),
+		lastFailureField: []byte(a.lastFailure.Format(time.RFC3339)),
+	}
+	if !a.lockedUntil.IsZero() {
+		secret.Data[lockedUntilField] = []byte(a.lockedUntil.Format(time.RFC3339))
+	}
+}
+
+// setLocalUserLocked sets the Locked condition of the local user with
+// username, until the given time, or clears it if that is zero.
+func (m *Manager) setLocalUserLocked(username string, until time.Time) {
+	users, err := m.userIndexer.ByIndex(usernameIndex, strings.ToLower(username))
+	if err != nil {
+		logrus.Errorf("Failed to get user %s: %v", username, err)
+		return
+	}
+	for _, obj := range users {
+		user, ok := obj.(*v3.User)
+		if !ok {
+			continue
+		}
+		if until.IsZero() && !v32.UserConditionLocked.IsTrue(user) {
+			return
+		}
+		user = user.DeepCopy()
+		if until.IsZero() {
+			v32.UserConditionLocked.False(user)
+			v32.UserConditionLocked.Message(user, "")
+		} else {
+			v32.UserConditionLocked.True(user)
+			v32.UserConditionLocked.Message(user, "locked until "+until.Format(time.RFC3339))
+		}
+		if _, err := m.users.Update(user); err != nil {
+			logrus.Errorf("Failed to update the Locked condition of user %s: %v", user.Name, err)
+		}
+		return
+	}
+}
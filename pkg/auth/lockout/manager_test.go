@@ -0,0 +1,258 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+func newTestManager() (*Manager, map[string]*corev1.Secret, map[string]*v3.User, *time.Time) {
+	secrets := map[string]*corev1.Secret{}
+	users := map[string]*v3.User{
+		"u-abcde": {ObjectMeta: metav1.ObjectMeta{Name: "u-abcde"}, Username: "jdoe"},
+	}
+	notFound := func(name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	get := func(namespace, name string) (*corev1.Secret, error) {
+		if secret, ok := secrets[name]; ok {
+			return secret, nil
+		}
+		return nil, notFound(name)
+	}
+	save := func(secret *corev1.Secret) (*corev1.Secret, error) {
+		secret.ResourceVersion = "1"
+		secrets[secret.Name] = secret
+		return secret, nil
+	}
+	userIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{usernameIndex: usernameIndexer})
+	for _, user := range users {
+		userIndexer.Add(user)
+	}
+	now := time.Unix(1600000000, 0)
+	m := &Manager{
+		secrets: &corefakes.SecretInterfaceMock{
+			GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+				return get(namespace, name)
+			},
+			CreateFunc: save,
+			UpdateFunc: save,
+			DeleteNamespacedFunc: func(namespace, name string, options *metav1.DeleteOptions) error {
+				if _, ok := secrets[name]; !ok {
+					return notFound(name)
+				}
+				delete(secrets, name)
+				return nil
+			},
+		},
+		secretLister: &corefakes.SecretListerMock{
+			GetFunc: get,
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+				var result []*corev1.Secret
+				for _, secret := range secrets {
+					if selector.Matches(labels.Set(secret.Labels)) {
+						result = append(result, secret)
+					}
+				}
+				return result, nil
+			},
+		},
+		users: &fakes.UserInterfaceMock{
+			UpdateFunc: func(user *v3.User) (*v3.User, error) {
+				users[user.Name] = user
+				return user, userIndexer.Update(user)
+			},
+		},
+		userIndexer: userIndexer,
+		memory:      newMemoryAttempts(),
+		now: func() time.Time {
+			return now
+		},
+	}
+	return m, secrets, users, &now
+}
+
+func setTestSettings(t *testing.T) {
+	for setting, value := range map[*settings.Setting]string{
+		&settings.AuthLockoutUserMaxFailures:      "3",
+		&settings.AuthLockoutIPMaxFailures:        "5",
+		&settings.AuthLockoutDurationMinutes:      "15",
+		&settings.AuthLockoutFailureWindowMinutes: "10",
+		&settings.AuthLoginBackoffBaseSeconds:     "1",
+		&settings.AuthLoginBackoffMaxSeconds:      "30",
+	} {
+		previous := setting.Get()
+		setting.Set(value)
+		s := setting
+		t.Cleanup(func() { s.Set(previous) })
+	}
+}
+
+func TestUserLockout(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	m, _, users, now := newTestManager()
+	ctx := context.Background()
+
+	assert.Zero(m.Check(ctx, "local", "jdoe", ""))
+	m.Failed(ctx, "local", "jdoe", "")
+	assert.Equal(time.Second, m.Check(ctx, "local", "JDoe", ""), "usernames are not case sensitive")
+	*now = now.Add(time.Second)
+	assert.Zero(m.Check(ctx, "local", "jdoe", ""))
+	m.Failed(ctx, "local", "jdoe", "")
+	assert.Equal(2*time.Second, m.Check(ctx, "local", "jdoe", ""), "back-off doubles on each failure")
+	assert.Zero(m.Check(ctx, "github", "jdoe", ""), "usernames are counted per provider")
+	assert.False(v32.UserConditionLocked.IsTrue(users["u-abcde"]))
+
+	*now = now.Add(2 * time.Second)
+	m.Failed(ctx, "local", "jdoe", "")
+	assert.Equal(15*time.Minute, m.Check(ctx, "local", "jdoe", ""))
+	assert.True(v32.UserConditionLocked.IsTrue(users["u-abcde"]))
+
+	*now = now.Add(15 * time.Minute)
+	assert.Zero(m.Check(ctx, "local", "jdoe", ""), "lockouts expire")
+	m.cleanup()
+	assert.False(v32.UserConditionLocked.IsTrue(users["u-abcde"]))
+}
+
+func TestFailureWindow(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	m, secrets, _, now := newTestManager()
+	ctx := context.Background()
+
+	m.Failed(ctx, "local", "jdoe", "")
+	m.Failed(ctx, "local", "jdoe", "")
+	*now = now.Add(11 * time.Minute)
+	m.Failed(ctx, "local", "jdoe", "")
+	assert.Equal(time.Second, m.Check(ctx, "local", "jdoe", ""), "failures older than the window are forgotten")
+
+	*now = now.Add(11 * time.Minute)
+	m.cleanup()
+	assert.Empty(secrets)
+}
+
+func TestIPLockout(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	m, _, _, _ := newTestManager()
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		m.Failed(ctx, "local", "", "10.0.0.1")
+		assert.Zero(m.Check(ctx, "local", "", "10.0.0.1"), "sources do not back off")
+	}
+	m.Failed(ctx, "local", "", "10.0.0.1")
+	assert.Equal(15*time.Minute, m.Check(ctx, "local", "other", "10.0.0.1"))
+	assert.Zero(m.Check(ctx, "local", "other", "10.0.0.2"))
+
+	m.Succeeded("local", "other")
+	assert.Equal(15*time.Minute, m.Check(ctx, "local", "", "10.0.0.1"), "logins do not unlock their source")
+}
+
+func TestSucceededAndUnlock(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	m, secrets, users, _ := newTestManager()
+	ctx := context.Background()
+
+	m.Failed(ctx, "local", "jdoe", "10.0.0.1")
+	m.Succeeded("local", "jdoe")
+	assert.Zero(m.Check(ctx, "local", "jdoe", ""))
+	assert.Len(secrets, 1, "failures of the source are kept")
+
+	for i := 0; i < 3; i++ {
+		m.Failed(ctx, "local", "jdoe", "")
+	}
+	assert.True(v32.UserConditionLocked.IsTrue(users["u-abcde"]))
+	assert.Nil(m.Unlock(ctx, users["u-abcde"]))
+	assert.Zero(m.Check(ctx, "local", "jdoe", ""))
+	assert.False(v32.UserConditionLocked.IsTrue(users["u-abcde"]))
+
+	assert.Error(m.Unlock(ctx, &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-fghij"}}), "only local users can be unlocked")
+}
+
+func TestDisabled(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	settings.AuthLockoutUserMaxFailures.Set("0")
+	settings.AuthLockoutIPMaxFailures.Set("0")
+	settings.AuthLoginBackoffBaseSeconds.Set("0")
+	m, secrets, _, _ := newTestManager()
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		m.Failed(ctx, "local", "jdoe", "10.0.0.1")
+	}
+	assert.Zero(m.Check(ctx, "local", "jdoe", "10.0.0.1"))
+	assert.Empty(secrets)
+}
+
+func TestUnknownUsernames(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	m, secrets, _, now := newTestManager()
+	ctx := context.Background()
+
+	m.Failed(ctx, "local", "nobody", "10.0.0.1")
+	assert.Len(secrets, 1, "the failures of unknown local usernames are not written, only those of the source")
+	assert.Equal(time.Second, m.Check(ctx, "local", "nobody", ""), "unknown usernames back off alike")
+
+	for i := 0; i < maxSourceAttempts; i++ {
+		m.Failed(ctx, "local", fmt.Sprintf("nobody-%d", i), "10.0.0.1")
+	}
+	assert.Zero(m.Check(ctx, "local", "nobody", ""), "the oldest unknown usernames are forgotten")
+
+	*now = now.Add(11 * time.Minute)
+	m.cleanup()
+	assert.Empty(m.memory.attempts)
+	assert.Empty(m.memory.sources)
+}
+
+func TestPersistedCaps(t *testing.T) {
+	assert := assert.New(t)
+	setTestSettings(t)
+	settings.AuthLockoutIPMaxFailures.Set("1000")
+	m, secrets, _, now := newTestManager()
+	ctx := context.Background()
+
+	m.Failed(ctx, "github", "jdoe", "10.0.0.1")
+	assert.Len(secrets, 2, "the failures of other providers and of sources are shared by all replicas")
+	assert.Equal(time.Second, m.Check(ctx, "github", "jdoe", ""))
+
+	for i := 1; i < maxSourceAttempts; i++ {
+		m.Failed(ctx, "github", fmt.Sprintf("user-%d", i), "10.0.0.1")
+	}
+	assert.Len(secrets, maxSourceAttempts+1)
+	m.Failed(ctx, "github", "one-too-many", "10.0.0.1")
+	assert.Len(secrets, maxSourceAttempts+1, "a source writes as many usernames as maxSourceAttempts")
+	assert.Equal(time.Second, m.Check(ctx, "github", "one-too-many", ""), "the others are kept in memory")
+	m.Failed(ctx, "github", "jdoe", "10.0.0.1")
+	assert.Equal(2*time.Second, m.Check(ctx, "github", "jdoe", ""), "usernames with a secret keep counting in it")
+
+	m.Failed(ctx, "github", "one-too-many", "10.0.0.2")
+	assert.Len(secrets, maxSourceAttempts+2, "usernames kept in memory stay in memory")
+	assert.Equal(2*time.Second, m.Check(ctx, "github", "one-too-many", ""))
+	m.Failed(ctx, "github", "other", "10.0.0.2")
+	assert.Len(secrets, maxSourceAttempts+3, "other sources write their own usernames")
+
+	*now = now.Add(16 * time.Minute)
+	m.cleanup()
+	assert.Empty(secrets)
+	assert.Empty(m.memory.attempts)
+}
@@ -0,0 +1,103 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// failed logins kept in memory by each replica, beyond which new ones
+	// are not counted
+	maxMemoryAttempts = 10000
+	// usernames whose failed logins are kept in memory for each source IP,
+	// beyond which the oldest are forgotten
+	maxSourceAttempts = 20
+)
+
+// memoryAttempts keeps the failed logins of unknown local usernames, and of
+// the usernames and source IPs beyond the caps on secrets. Anyone can fail
+// logins with made up usernames, so they are bounded in number too.
+type memoryAttempts struct {
+	sync.Mutex
+	attempts map[string]*attempts
+	// the keys of the usernames that failed from each source, oldest first
+	sources map[string][]string
+}
+
+func newMemoryAttempts() *memoryAttempts {
+	return &memoryAttempts{
+		attempts: map[string]*attempts{},
+		sources:  map[string][]string{},
+	}
+}
+
+// get returns a copy of the failed logins of key, if there are any.
+func (ma *memoryAttempts) get(key string) (*attempts, bool) {
+	ma.Lock()
+	defer ma.Unlock()
+	a, ok := ma.attempts[key]
+	if !ok {
+		return nil, false
+	}
+	result := *a
+	return &result, true
+}
+
+// fail counts a failure of key from source, which may be empty, with fail.
+// It returns a copy of the failed logins of key and whether fail locked them.
+func (ma *memoryAttempts) fail(key, source string, fail func(*attempts) bool) (*attempts, bool) {
+	ma.Lock()
+	defer ma.Unlock()
+	a, ok := ma.attempts[key]
+	if !ok {
+		a = &attempts{key: key}
+		if len(ma.attempts) >= maxMemoryAttempts {
+			// not kept, only this failure counts
+			locked := fail(a)
+			return a, locked
+		}
+		ma.attempts[key] = a
+		if source != "" {
+			keys := append(ma.sources[source], key)
+			if len(keys) > maxSourceAttempts {
+				delete(ma.attempts, keys[0])
+				keys = keys[1:]
+			}
+			ma.sources[source] = keys
+		}
+	}
+	locked := fail(a)
+	result := *a
+	return &result, locked
+}
+
+// forget deletes the failed logins of key.
+func (ma *memoryAttempts) forget(key string) {
+	ma.Lock()
+	defer ma.Unlock()
+	delete(ma.attempts, key)
+}
+
+// prune deletes the failed logins which are not locked and older than window.
+func (ma *memoryAttempts) prune(now time.Time, window time.Duration) {
+	ma.Lock()
+	defer ma.Unlock()
+	for key, a := range ma.attempts {
+		if !a.lockedUntil.After(now) && now.Sub(a.lastFailure) > window {
+			delete(ma.attempts, key)
+		}
+	}
+	for source, keys := range ma.sources {
+		var kept []string
+		for _, key := range keys {
+			if _, ok := ma.attempts[key]; ok {
+				kept = append(kept, key)
+			}
+		}
+		if len(kept) == 0 {
+			delete(ma.sources, source)
+		} else {
+			ma.sources[source] = kept
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// keys of the secret of the user holding their MFA state
+	totpKey      = "mfa-totp"
+	pendingKey   = "mfa-totp-pending"
+	challengeKey = "mfa-challenge"
+
+	// the secret of a user, the one tokens.Manager keeps the tokens of
+	// auth providers in
+	secretNamespace  = "cattle-system"
+	secretNameEnding = "-secret"
+
+	issuer               = "Rancher"
+	challengeTTL         = 5 * time.Minute
+	maxChallengeFailures = 5
+)
+
+// ChallengeRequired is returned by the login of users who must pass a second
+// factor, in place of their principals.
+type ChallengeRequired struct {
+	Challenge *v32.MFAChallenge
+}
+
+func (c *ChallengeRequired) Error() string {
+	return "multi-factor authentication required"
+}
+
+type totpState struct {
+	Secret   string `json:"secret"`
+	LastStep int64  `json:"lastStep,omitempty"`
+}
+
+// enrollment is an authenticator a user has not confirmed with a code yet.
+type enrollment struct {
+	Secret             string   `json:"secret"`
+	RecoveryCodeHashes []string `json:"recoveryCodeHashes"`
+}
+
+type challenge struct {
+	Hash       string      `json:"hash"`
+	ExpiresAt  time.Time   `json:"expiresAt"`
+	Failures   int         `json:"failures,omitempty"`
+	Enrollment *enrollment `json:"enrollment,omitempty"`
+}
+
+// Manager enrolls local users in TOTP multi-factor authentication and checks
+// their second factor on login. TOTP secrets and login challenges are kept in
+// the secret of the user, the hashes of recovery codes on the user.
+//
+// The secret is read live rather than from the cache and written with the
+// resourceVersion it was read at, so that concurrent logins can not both
+// pass with the same challenge or code, nor lose failed attempts. Logins
+// losing such a conflict fail.
+type Manager struct {
+	users               v3.UserInterface
+	userLister          v3.UserLister
+	userAttributeLister v3.UserAttributeLister
+	grbLister           v3.GlobalRoleBindingLister
+	secrets             v1.SecretInterface
+	now                 func() time.Time
+}
+
+func NewManager(mgmt *config.ScaledContext) *Manager {
+	return &Manager{
+		users:               mgmt.Management.Users(""),
+		userLister:          mgmt.Management.Users("").Controller().Lister(),
+		userAttributeLister: mgmt.Management.UserAttributes("").Controller().Lister(),
+		grbLister:           mgmt.Management.GlobalRoleBindings("").Controller().Lister(),
+		secrets:             mgmt.Core.Secrets(""),
+		now:                 time.Now,
+	}
+}
+
+// Required returns whether user must log in with a second factor, which they
+// must once enrolled or if the settings require it of them.
+func (m *Manager) Required(user *v3.User, groupPrincipals []v3.Principal) (bool, error) {
+	if user.MFAEnabled {
+		return true, nil
+	}
+	return m.requiredBySettings(user, groupPrincipals)
+}
+
+func (m *Manager) requiredBySettings(user *v3.User, groupPrincipals []v3.Principal) (bool, error) {
+	if settings.LocalMFARequired.Get() == "true" {
+		return true, nil
+	}
+	roles := sets.NewString()
+	for _, role := range strings.Split(settings.LocalMFARequiredGlobalRoles.Get(), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles.Insert(role)
+		}
+	}
+	if roles.Len() == 0 {
+		return false, nil
+	}
+
+	groups := sets.NewString()
+	for _, group := range groupPrincipals {
+		groups.Insert(group.Name)
+	}
+	grbs, err := m.grbLister.List("", labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, grb := range grbs {
+		if !roles.Has(grb.GlobalRoleName) {
+			continue
+		}
+		if grb.UserName == user.Name || (grb.GroupPrincipalName != "" && groups.Has(grb.GroupPrincipalName)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// groupPrincipals returns the group principals of user as of their last login,
+// those of every auth provider, as requests are authenticated with.
+func (m *Manager) groupPrincipals(user *v3.User) ([]v3.Principal, error) {
+	attribs, err := m.userAttributeLister.Get("", user.Name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var groupPrincipals []v3.Principal
+	for _, principals := range attribs.GroupPrincipals {
+		groupPrincipals = append(groupPrincipals, principals.Items...)
+	}
+	return groupPrincipals, nil
+}
+
+// NewChallenge starts the second step of the login of user. Users who have
+// not enrolled yet get an authenticator to enroll with the challenge.
+func (m *Manager) NewChallenge(user *v3.User) (*v32.MFAChallenge, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(key)
+	c := &challenge{
+		Hash:      hashChallenge(token),
+		ExpiresAt: m.now().Add(challengeTTL).UTC(),
+	}
+	result := &v32.MFAChallenge{
+		ChallengeID: user.Name + ":" + token,
+		ExpiresAt:   c.ExpiresAt.Format(time.RFC3339),
+	}
+
+	if !user.MFAEnabled {
+		e, codes, err := newEnrollment()
+		if err != nil {
+			return nil, err
+		}
+		c.Enrollment = e
+		result.Enroll = true
+		result.MFAEnrollment = v32.MFAEnrollment{
+			Secret:          e.Secret,
+			ProvisioningURI: provisioningURI(issuer, user.Username, e.Secret),
+			RecoveryCodes:   codes,
+		}
+	}
+
+	secret, err := m.getSecret(user.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := setValue(secret, challengeKey, c); err != nil {
+		return nil, err
+	}
+	if err := m.saveSecret(secret); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyChallenge returns the user of the challenge if code is a valid code
+// of their authenticator or one of their recovery codes. Users enrolling
+// with the challenge are enrolled.
+func (m *Manager) VerifyChallenge(challengeID, code string) (*v3.User, error) {
+	userName, token := splitChallengeID(challengeID)
+	if userName == "" {
+		return nil, httperror.NewAPIError(httperror.Unauthorized, "invalid challenge")
+	}
+	secret, err := m.getSecret(userName)
+	if err != nil {
+		return nil, err
+	}
+	c := &challenge{}
+	found, err := getValue(secret, challengeKey, c)
+	if err != nil {
+		return nil, err
+	}
+	if !found || subtle.ConstantTimeCompare([]byte(c.Hash), []byte(hashChallenge(token))) != 1 {
+		return nil, httperror.NewAPIError(httperror.Unauthorized, "invalid challenge")
+	}
+	if m.now().After(c.ExpiresAt) {
+		clearValue(secret, challengeKey)
+		m.saveSecret(secret)
+		return nil, httperror.NewAPIError(httperror.Unauthorized, "challenge expired")
+	}
+
+	user, err := m.userLister.Get("", userName)
+	if err != nil {
+		return nil, err
+	}
+	var updated *v3.User
+	if c.Enrollment != nil {
+		updated, err = m.confirm(user, secret, c.Enrollment, code)
+	} else {
+		updated, err = m.checkCode(user, secret, code)
+	}
+	if err != nil {
+		c.Failures++
+		if c.Failures >= maxChallengeFailures {
+			clearValue(secret, challengeKey)
+		} else if setErr := setValue(secret, challengeKey, c); setErr != nil {
+			return nil, setErr
+		}
+		if saveErr := m.saveSecret(secret); saveErr != nil {
+			return nil, checkConflict(saveErr)
+		}
+		return nil, err
+	}
+
+	clearValue(secret, challengeKey)
+	if err := m.saveSecret(secret); err != nil {
+		return nil, checkConflict(err)
+	}
+	if updated != user {
+		return m.users.Update(updated)
+	}
+	return user, nil
+}
+
+// Enroll starts the enrollment of user in multi-factor authentication, which
+// ConfirmEnrollment finishes with a code of the authenticator.
+func (m *Manager) Enroll(user *v3.User) (*v32.MFAEnrollment, error) {
+	if user.MFAEnabled {
+		return nil, httperror.NewAPIError(httperror.Conflict, "multi-factor authentication is already enabled")
+	}
+	e, codes, err := newEnrollment()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := m.getSecret(user.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := setValue(secret, pendingKey, e); err != nil {
+		return nil, err
+	}
+	if err := m.saveSecret(secret); err != nil {
+		return nil, err
+	}
+	return &v32.MFAEnrollment{
+		Secret:          e.Secret,
+		ProvisioningURI: provisioningURI(issuer, user.Username, e.Secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (m *Manager) ConfirmEnrollment(user *v3.User, code string) error {
+	secret, err := m.getSecret(user.Name)
+	if err != nil {
+		return err
+	}
+	e := &enrollment{}
+	found, err := getValue(secret, pendingKey, e)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return httperror.NewAPIError(httperror.InvalidAction, "no multi-factor authentication enrollment to confirm")
+	}
+	updated, err := m.confirm(user, secret, e, code)
+	if err != nil {
+		return err
+	}
+	clearValue(secret, pendingKey)
+	if err := m.saveSecret(secret); err != nil {
+		return checkConflict(err)
+	}
+	_, err = m.users.Update(updated)
+	return err
+}
+
+// Disable removes the authenticator of user, if the settings do not require
+// one of them.
+func (m *Manager) Disable(user *v3.User, code string) error {
+	if !user.MFAEnabled {
+		return httperror.NewAPIError(httperror.InvalidAction, "multi-factor authentication is not enabled")
+	}
+	groupPrincipals, err := m.groupPrincipals(user)
+	if err != nil {
+		return err
+	}
+	required, err := m.requiredBySettings(user, groupPrincipals)
+	if err != nil {
+		return err
+	}
+	if required {
+		return httperror.NewAPIError(httperror.PermissionDenied, "multi-factor authentication is required")
+	}
+	secret, err := m.getSecret(user.Name)
+	if err != nil {
+		return err
+	}
+	user, err = m.checkCode(user, secret, code)
+	if err != nil {
+		return err
+	}
+	// Reset drops the recovery codes, a used one included
+	if err := m.saveSecret(secret); err != nil {
+		return checkConflict(err)
+	}
+	return m.Reset(user)
+}
+
+// Reset removes the authenticator and recovery codes of user, who has to
+// enroll again on their next login if the settings require it.
+func (m *Manager) Reset(user *v3.User) error {
+	secret, err := m.getSecret(user.Name)
+	if err != nil {
+		return err
+	}
+	if secret.ResourceVersion != "" {
+		for _, key := range []string{totpKey, pendingKey, challengeKey} {
+			clearValue(secret, key)
+		}
+		if err := m.saveSecret(secret); err != nil {
+			return err
+		}
+	}
+	if !user.MFAEnabled && len(user.MFARecoveryCodes) == 0 {
+		return nil
+	}
+	user = user.DeepCopy()
+	user.MFAEnabled = false
+	user.MFARecoveryCodes = nil
+	_, err = m.users.Update(user)
+	return err
+}
+
+// confirm records the authenticator of e in secret if code is valid for it
+// and returns a copy of user enrolled with it, which the caller saves after
+// secret.
+func (m *Manager) confirm(user *v3.User, secret *corev1.Secret, e *enrollment, code string) (*v3.User, error) {
+	step, ok := validateTOTP(e.Secret, strings.TrimSpace(code), m.now(), 0)
+	if !ok {
+		return nil, httperror.NewAPIError(httperror.Unauthorized, "invalid code")
+	}
+	if err := setValue(secret, totpKey, &totpState{Secret: e.Secret, LastStep: step}); err != nil {
+		return nil, err
+	}
+	user = user.DeepCopy()
+	user.MFAEnabled = true
+	user.MFARecoveryCodes = e.RecoveryCodeHashes
+	return user, nil
+}
+
+// checkCode checks code against the authenticator and the recovery codes of
+// user. The step of a valid authenticator code is recorded in secret so it
+// can not be used again, a matching recovery code is removed from the
+// returned copy of user, which the caller saves after secret.
+func (m *Manager) checkCode(user *v3.User, secret *corev1.Secret, code string) (*v3.User, error) {
+	code = strings.TrimSpace(code)
+	state := &totpState{}
+	found, err := getValue(secret, totpKey, state)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if step, ok := validateTOTP(state.Secret, code, m.now(), state.LastStep); ok {
+			state.LastStep = step
+			return user, setValue(secret, totpKey, state)
+		}
+	}
+
+	i := matchRecoveryCode(user.MFARecoveryCodes, code)
+	if i < 0 {
+		return nil, httperror.NewAPIError(httperror.Unauthorized, "invalid code")
+	}
+	user = user.DeepCopy()
+	user.MFARecoveryCodes = append(user.MFARecoveryCodes[:i:i], user.MFARecoveryCodes[i+1:]...)
+	return user, nil
+}
+
+// getSecret reads the secret of userName live, returning a new one if it
+// does not exist yet.
+func (m *Manager) getSecret(userName string) (*corev1.Secret, error) {
+	secret, err := m.secrets.GetNamespaced(secretNamespace, userName+secretNameEnding, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      userName + secretNameEnding,
+				Namespace: secretNamespace,
+			},
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secret.DeepCopy(), nil
+}
+
+// saveSecret creates secret or updates it at the resourceVersion it was read
+// at, failing with a conflict if it changed since.
+func (m *Manager) saveSecret(secret *corev1.Secret) error {
+	var err error
+	if secret.ResourceVersion == "" {
+		_, err = m.secrets.Create(secret)
+	} else {
+		_, err = m.secrets.Update(secret)
+	}
+	return err
+}
+
+// checkConflict turns the conflict of a concurrent login into a failed one.
+func checkConflict(err error) error {
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return httperror.NewAPIError(httperror.Unauthorized, "concurrent multi-factor authentication attempt")
+	}
+	return err
+}
+
+func getValue(secret *corev1.Secret, key string, v interface{}) (bool, error) {
+	value := secret.Data[key]
+	if len(value) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(value, v)
+}
+
+func setValue(secret *corev1.Secret, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = value
+	return nil
+}
+
+func clearValue(secret *corev1.Secret, key string) {
+	delete(secret.Data, key)
+}
+
+func newEnrollment() (*enrollment, []string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &enrollment{Secret: secret, RecoveryCodeHashes: hashes}, codes, nil
+}
+
+func hashChallenge(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChallengeUser returns the name of the user challengeID was issued to, or
+// an empty string if it is malformed.
+func ChallengeUser(challengeID string) string {
+	userName, _ := splitChallengeID(challengeID)
+	return userName
+}
+
+// splitChallengeID splits an ID in the format <user>:<secret>.
+func splitChallengeID(challengeID string) (string, string) {
+	parts := strings.SplitN(challengeID, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,319 @@
+package mfa
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+// fakeSecrets stores secrets like the API server, rejecting updates of
+// secrets that changed since they were read.
+type fakeSecrets map[string]*corev1.Secret
+
+func (f fakeSecrets) client() *corefakes.SecretInterfaceMock {
+	return &corefakes.SecretInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+			secret, ok := f[name]
+			if !ok {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+			}
+			return secret.DeepCopy(), nil
+		},
+		CreateFunc: func(secret *corev1.Secret) (*corev1.Secret, error) {
+			if _, ok := f[secret.Name]; ok {
+				return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, secret.Name)
+			}
+			secret = secret.DeepCopy()
+			secret.ResourceVersion = "1"
+			f[secret.Name] = secret
+			return secret, nil
+		},
+		UpdateFunc: func(secret *corev1.Secret) (*corev1.Secret, error) {
+			current, ok := f[secret.Name]
+			if !ok {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, secret.Name)
+			}
+			if current.ResourceVersion != secret.ResourceVersion {
+				return nil, apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, secret.Name, nil)
+			}
+			version, _ := strconv.Atoi(current.ResourceVersion)
+			secret = secret.DeepCopy()
+			secret.ResourceVersion = strconv.Itoa(version + 1)
+			f[secret.Name] = secret
+			return secret, nil
+		},
+	}
+}
+
+func newTestManager(grbs ...*v3.GlobalRoleBinding) (*Manager, map[string]*v3.User, fakeSecrets, *time.Time) {
+	users := map[string]*v3.User{
+		"u-abcde": {ObjectMeta: metav1.ObjectMeta{Name: "u-abcde"}, Username: "jdoe"},
+	}
+	now := time.Unix(1600000000, 0)
+	secrets := fakeSecrets{}
+	m := &Manager{
+		users: &fakes.UserInterfaceMock{
+			UpdateFunc: func(user *v3.User) (*v3.User, error) {
+				users[user.Name] = user
+				return user, nil
+			},
+		},
+		userLister: &fakes.UserListerMock{
+			GetFunc: func(namespace string, name string) (*v3.User, error) {
+				if user, ok := users[name]; ok {
+					return user, nil
+				}
+				return nil, apierrors.NewNotFound(v3.UserGroupVersionResource.GroupResource(), name)
+			},
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+				return nil, apierrors.NewNotFound(v3.UserAttributeGroupVersionResource.GroupResource(), name)
+			},
+		},
+		grbLister: &fakes.GlobalRoleBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.GlobalRoleBinding, error) {
+				return grbs, nil
+			},
+		},
+		secrets: secrets.client(),
+		now: func() time.Time {
+			return now
+		},
+	}
+	return m, users, secrets, &now
+}
+
+func codeOf(secret string, t time.Time) string {
+	key, _ := secretEncoding.DecodeString(secret)
+	return totpCode(key, t.Unix()/totpPeriod)
+}
+
+func TestRequired(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.LocalMFARequired.Set(settings.LocalMFARequired.Get())
+	defer settings.LocalMFARequiredGlobalRoles.Set(settings.LocalMFARequiredGlobalRoles.Get())
+
+	m, users, _, _ := newTestManager(
+		&v3.GlobalRoleBinding{UserName: "u-abcde", GlobalRoleName: "user"},
+		&v3.GlobalRoleBinding{GroupPrincipalName: "local://g-admins", GlobalRoleName: "admin"},
+	)
+	user := users["u-abcde"]
+	admins := []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "local://g-admins"}}}
+
+	settings.LocalMFARequired.Set("false")
+	settings.LocalMFARequiredGlobalRoles.Set("")
+	required, err := m.Required(user, admins)
+	assert.Nil(err)
+	assert.False(required)
+
+	settings.LocalMFARequiredGlobalRoles.Set("admin, restricted-admin")
+	required, _ = m.Required(user, nil)
+	assert.False(required)
+	required, _ = m.Required(user, admins)
+	assert.True(required, "required of members of groups bound to the role")
+
+	settings.LocalMFARequiredGlobalRoles.Set("user")
+	required, _ = m.Required(user, nil)
+	assert.True(required, "required of users bound to the role")
+
+	settings.LocalMFARequiredGlobalRoles.Set("")
+	settings.LocalMFARequired.Set("true")
+	required, _ = m.Required(user, nil)
+	assert.True(required)
+
+	settings.LocalMFARequired.Set("false")
+	user.MFAEnabled = true
+	required, _ = m.Required(user, nil)
+	assert.True(required, "required of enrolled users")
+}
+
+func TestChallengeEnrollment(t *testing.T) {
+	assert := assert.New(t)
+	m, users, _, now := newTestManager()
+
+	challenge, err := m.NewChallenge(users["u-abcde"])
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(challenge.Enroll)
+	assert.Contains(challenge.ProvisioningURI, "otpauth://totp/Rancher:jdoe?")
+	assert.Len(challenge.RecoveryCodes, recoveryCodeCount)
+
+	_, err = m.VerifyChallenge(challenge.ChallengeID, challenge.RecoveryCodes[0])
+	assert.True(httperror.IsAPIError(err), "recovery codes can not confirm an enrollment")
+
+	user, err := m.VerifyChallenge(challenge.ChallengeID, codeOf(challenge.Secret, *now))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(user.MFAEnabled)
+	assert.Len(user.MFARecoveryCodes, recoveryCodeCount)
+	assert.NotContains(user.MFARecoveryCodes, challenge.RecoveryCodes[0], "only hashes are stored")
+
+	_, err = m.VerifyChallenge(challenge.ChallengeID, codeOf(challenge.Secret, *now))
+	assert.Error(err, "challenges can only be passed once")
+
+	// enrolled users log in with their authenticator or a recovery code
+	next, err := m.NewChallenge(user)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.False(next.Enroll)
+	assert.Empty(next.Secret)
+	_, err = m.VerifyChallenge(next.ChallengeID, codeOf(challenge.Secret, *now))
+	assert.Error(err, "codes can not be used twice")
+	*now = now.Add(totpPeriod * time.Second)
+	_, err = m.VerifyChallenge(next.ChallengeID, codeOf(challenge.Secret, *now))
+	assert.Nil(err)
+
+	next, _ = m.NewChallenge(users["u-abcde"])
+	user, err = m.VerifyChallenge(next.ChallengeID, challenge.RecoveryCodes[4])
+	if assert.Nil(err) {
+		assert.Len(user.MFARecoveryCodes, recoveryCodeCount-1)
+	}
+	next, _ = m.NewChallenge(users["u-abcde"])
+	_, err = m.VerifyChallenge(next.ChallengeID, challenge.RecoveryCodes[4])
+	assert.Error(err, "recovery codes can not be used twice")
+}
+
+func TestChallengeLimits(t *testing.T) {
+	assert := assert.New(t)
+	m, users, _, now := newTestManager()
+
+	challenge, err := m.NewChallenge(users["u-abcde"])
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = m.VerifyChallenge("u-abcde:other", codeOf(challenge.Secret, *now))
+	assert.Error(err)
+	_, err = m.VerifyChallenge("u-other:"+challenge.ChallengeID[len("u-abcde:"):], codeOf(challenge.Secret, *now))
+	assert.Error(err)
+
+	for i := 0; i < maxChallengeFailures; i++ {
+		_, err = m.VerifyChallenge(challenge.ChallengeID, "000000")
+		assert.Error(err)
+	}
+	_, err = m.VerifyChallenge(challenge.ChallengeID, codeOf(challenge.Secret, *now))
+	assert.EqualError(err, "Unauthorized 401: invalid challenge", "challenges are dropped after too many failures")
+
+	challenge, _ = m.NewChallenge(users["u-abcde"])
+	*now = now.Add(challengeTTL + time.Second)
+	_, err = m.VerifyChallenge(challenge.ChallengeID, codeOf(challenge.Secret, *now))
+	assert.EqualError(err, "Unauthorized 401: challenge expired")
+	assert.False(users["u-abcde"].MFAEnabled)
+}
+
+func TestEnrollAndDisable(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.LocalMFARequired.Set(settings.LocalMFARequired.Get())
+	m, users, secrets, now := newTestManager()
+
+	assert.Error(m.ConfirmEnrollment(users["u-abcde"], "000000"), "nothing to confirm")
+	enrollment, err := m.Enroll(users["u-abcde"])
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Error(m.ConfirmEnrollment(users["u-abcde"], "000000"))
+	assert.Nil(m.ConfirmEnrollment(users["u-abcde"], codeOf(enrollment.Secret, *now)))
+	assert.True(users["u-abcde"].MFAEnabled)
+	_, err = m.Enroll(users["u-abcde"])
+	assert.Error(err, "already enrolled")
+
+	settings.LocalMFARequired.Set("true")
+	assert.Error(m.Disable(users["u-abcde"], enrollment.RecoveryCodes[0]), "required by the settings")
+	settings.LocalMFARequired.Set("false")
+	assert.Error(m.Disable(users["u-abcde"], "000000"))
+	assert.Nil(m.Disable(users["u-abcde"], enrollment.RecoveryCodes[0]))
+	assert.False(users["u-abcde"].MFAEnabled)
+	assert.Empty(users["u-abcde"].MFARecoveryCodes)
+	_, found := secrets["u-abcde-secret"].Data[totpKey]
+	assert.False(found, "the secret is removed")
+}
+
+func TestDisableRequiredByGroup(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.LocalMFARequiredGlobalRoles.Set(settings.LocalMFARequiredGlobalRoles.Get())
+	m, users, _, now := newTestManager(
+		&v3.GlobalRoleBinding{GroupPrincipalName: "github_team://1234", GlobalRoleName: "admin"},
+	)
+	m.userAttributeLister = &fakes.UserAttributeListerMock{
+		GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+			return &v3.UserAttribute{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				GroupPrincipals: map[string]v32.Principals{
+					"github": {Items: []v32.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "github_team://1234"}}}},
+				},
+			}, nil
+		},
+	}
+
+	enrollment, err := m.Enroll(users["u-abcde"])
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Nil(m.ConfirmEnrollment(users["u-abcde"], codeOf(enrollment.Secret, *now)))
+
+	settings.LocalMFARequiredGlobalRoles.Set("admin")
+	assert.Error(m.Disable(users["u-abcde"], enrollment.RecoveryCodes[0]), "required of members of groups bound to the role")
+	assert.True(users["u-abcde"].MFAEnabled)
+
+	settings.LocalMFARequiredGlobalRoles.Set("")
+	assert.Nil(m.Disable(users["u-abcde"], enrollment.RecoveryCodes[0]))
+	assert.False(users["u-abcde"].MFAEnabled)
+}
+
+func TestChallengeConflict(t *testing.T) {
+	assert := assert.New(t)
+	m, users, secrets, now := newTestManager()
+
+	enrollment, err := m.Enroll(users["u-abcde"])
+	if !assert.Nil(err) {
+		return
+	}
+	*now = now.Add(-totpPeriod * time.Second)
+	assert.Nil(m.ConfirmEnrollment(users["u-abcde"], codeOf(enrollment.Secret, *now)))
+	*now = now.Add(totpPeriod * time.Second)
+
+	// a login changing the secret between the read and the write of another
+	// fails the other, which would otherwise reuse the same code
+	next, _ := m.NewChallenge(users["u-abcde"])
+	client := m.secrets.(*corefakes.SecretInterfaceMock)
+	get := client.GetNamespacedFunc
+	client.GetNamespacedFunc = func(namespace string, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+		secret, err := get(namespace, name, opts)
+		client.GetNamespacedFunc = get
+		secrets[name] = secrets[name].DeepCopy()
+		secrets[name].ResourceVersion += "0"
+		return secret, err
+	}
+	_, err = m.VerifyChallenge(next.ChallengeID, codeOf(enrollment.Secret, *now))
+	assert.EqualError(err, "Unauthorized 401: concurrent multi-factor authentication attempt")
+	_, err = m.VerifyChallenge(next.ChallengeID, codeOf(enrollment.Secret, *now))
+	assert.Nil(err, "the code was not used up")
+
+	// failures are counted on the live secret, not a cached copy
+	next, _ = m.NewChallenge(users["u-abcde"])
+	for i := 0; i < maxChallengeFailures-1; i++ {
+		_, err = m.VerifyChallenge(next.ChallengeID, "000000")
+		assert.Error(err)
+	}
+	c := &challenge{}
+	found, _ := getValue(secrets["u-abcde-secret"], challengeKey, c)
+	assert.True(found)
+	assert.Equal(maxChallengeFailures-1, c.Failures)
+}
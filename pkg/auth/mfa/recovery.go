@@ -0,0 +1,50 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+// generateRecoveryCodes returns new recovery codes and their hashes. The codes
+// are random, unlike passwords, so a fast hash does not make them guessable.
+func generateRecoveryCodes() ([]string, []string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(secretEncoding.EncodeToString(b))
+		// group the 16 characters in fours for legibility
+		code = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchRecoveryCode returns the index of the hash of code in hashes, or -1.
+func matchRecoveryCode(hashes []string, code string) int {
+	hash := []byte(hashRecoveryCode(code))
+	match := -1
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), hash) == 1 {
+			match = i
+		}
+	}
+	return match
+}
@@ -0,0 +1,92 @@
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	assert := assert.New(t)
+
+	// test vectors of RFC 6238 for SHA1, truncated to 6 digits
+	key := []byte("12345678901234567890")
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for seconds, code := range tests {
+		assert.Equal(code, totpCode(key, seconds/totpPeriod), "at %d", seconds)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	assert := assert.New(t)
+
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	got, ok := validateTOTP(secret, "050471", now, 0)
+	assert.True(ok)
+	assert.Equal(step, got)
+
+	_, ok = validateTOTP(secret, "081804", now, 0)
+	assert.True(ok, "codes of the previous period are accepted")
+	_, ok = validateTOTP(secret, "050471", now.Add(3*totpPeriod*time.Second), 0)
+	assert.False(ok, "codes of older periods are not")
+	_, ok = validateTOTP(secret, "050471", now, step)
+	assert.False(ok, "codes can not be used twice")
+	_, ok = validateTOTP(secret, "000000", now, 0)
+	assert.False(ok)
+	_, ok = validateTOTP(secret, "0504710", now, 0)
+	assert.False(ok)
+	_, ok = validateTOTP("not base32!", "050471", now, 0)
+	assert.False(ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	assert := assert.New(t)
+
+	secret, err := generateSecret()
+	assert.Nil(err)
+	assert.Len(secret, 32)
+
+	u, err := url.Parse(provisioningURI("Rancher", "jane doe", secret))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("otpauth", u.Scheme)
+	assert.Equal("totp", u.Host)
+	assert.Equal("/Rancher:jane doe", u.Path)
+	assert.Equal(secret, u.Query().Get("secret"))
+	assert.Equal("Rancher", u.Query().Get("issuer"))
+	assert.Equal("6", u.Query().Get("digits"))
+	assert.Equal("30", u.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	codes, hashes, err := generateRecoveryCodes()
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Len(codes, recoveryCodeCount)
+	assert.Len(hashes, recoveryCodeCount)
+	assert.Regexp("^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$", codes[0])
+	for _, hash := range hashes {
+		assert.NotContains(codes, hash)
+	}
+
+	assert.Equal(3, matchRecoveryCode(hashes, codes[3]))
+	assert.Equal(3, matchRecoveryCode(hashes, " "+codes[3][:9]+codes[3][10:]), "dashes and spaces are ignored")
+	assert.Equal(-1, matchRecoveryCode(hashes, "aaaa-bbbb-cccc-dddd"))
+	assert.Equal(-1, matchRecoveryCode(nil, codes[0]))
+}
@@ -0,0 +1,126 @@
+package passwordpolicy
+
+import (
+	_ "embed" // for the denylist of common passwords
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rancher/norman/httperror"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:embed common-passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseDenylist(commonPasswordsFile)
+
+func parseDenylist(content string) map[string]bool {
+	denylist := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = true
+	}
+	return denylist
+}
+
+var characterClasses = map[string]func(rune) bool{
+	"lower":  unicode.IsLower,
+	"upper":  unicode.IsUpper,
+	"digit":  unicode.IsDigit,
+	"symbol": func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) },
+}
+
+// Validate checks that password meets the policy set by the settings, for the
+// user with username.
+func Validate(username, password string) error {
+	if minLength := settings.PasswordMinLength.GetInt(); utf8.RuneCountInString(password) < minLength {
+		return invalid(fmt.Sprintf("password must be at least %d characters", minLength))
+	}
+	for _, class := range strings.Split(settings.PasswordRequiredCharacterClasses.Get(), ",") {
+		class = strings.TrimSpace(class)
+		if class == "" {
+			continue
+		}
+		is, ok := characterClasses[class]
+		if !ok {
+			return fmt.Errorf("unknown character class %s in setting %s", class, settings.PasswordRequiredCharacterClasses.Name)
+		}
+		if strings.IndexFunc(password, is) < 0 {
+			return invalid(fmt.Sprintf("password must contain at least one %s character", class))
+		}
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return invalid("password must not be the username")
+	}
+	if settings.PasswordDenylistEnabled.Get() == "true" && commonPasswords[strings.ToLower(password)] {
+		return invalid("password is too common")
+	}
+	return nil
+}
+
+// CheckReuse checks that password is not one of the last passwords of user,
+// as many as the password-history-size setting tells.
+func CheckReuse(user *v3.User, password string) error {
+	size := settings.PasswordHistorySize.GetInt()
+	if size <= 0 {
+		return nil
+	}
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	if len(hashes) > size {
+		hashes = hashes[:size]
+	}
+	for _, hash := range hashes {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return invalid(fmt.Sprintf("password must not be one of the last %d passwords", size))
+		}
+	}
+	return nil
+}
+
+// SetPassword sets the hash of the new password of user, keeping the hash of
+// the current one in their history.
+func SetPassword(user *v3.User, hash string, now time.Time) {
+	if user.Password != "" {
+		user.PasswordHistory = append([]string{user.Password}, user.PasswordHistory...)
+	}
+	// the current password is the first of the last ones
+	size := settings.PasswordHistorySize.GetInt() - 1
+	if size < 0 {
+		size = 0
+	}
+	if len(user.PasswordHistory) > size {
+		user.PasswordHistory = user.PasswordHistory[:size]
+	}
+	if len(user.PasswordHistory) == 0 {
+		user.PasswordHistory = nil
+	}
+	user.Password = hash
+	user.PasswordChangedAt = now.UTC().Format(time.RFC3339)
+}
+
+// Expired returns whether the password of user is older than the
+// password-max-age-days setting allows. The passwords of users created before
+// their change time was recorded are as old as the users.
+func Expired(user *v3.User, now time.Time) bool {
+	days := settings.PasswordMaxAgeDays.GetInt()
+	if days <= 0 || user.Password == "" {
+		return false
+	}
+	changed := user.CreationTimestamp.Time
+	if t, err := time.Parse(time.RFC3339, user.PasswordChangedAt); err == nil {
+		changed = t
+	}
+	return now.Sub(changed) > time.Duration(days)*24*time.Hour
+}
+
+func invalid(message string) error {
+	return httperror.NewAPIError(httperror.InvalidFormat, message)
+}
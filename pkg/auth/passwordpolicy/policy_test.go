@@ -0,0 +1,106 @@
+package passwordpolicy
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setSetting(t *testing.T, setting settings.Setting, value string) {
+	previous := setting.Get()
+	setting.Set(value)
+	t.Cleanup(func() { setting.Set(previous) })
+}
+
+func hash(t *testing.T, password string) string {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	setSetting(t, settings.PasswordMinLength, "12")
+	setSetting(t, settings.PasswordRequiredCharacterClasses, "")
+	setSetting(t, settings.PasswordDenylistEnabled, "true")
+
+	assert.Nil(Validate("jdoe", "tiny-crab-on-a-bike"))
+	assert.EqualError(Validate("jdoe", "short"), "InvalidFormat 422: password must be at least 12 characters")
+	assert.Error(Validate("jdoe", "ñandúñandúñ"), "length counts characters, not bytes")
+	assert.Nil(Validate("jdoe", "ñandúñandúña"))
+	assert.EqualError(Validate("jdoe", "Password1234"), "InvalidFormat 422: password is too common")
+	assert.EqualError(Validate("administrator", "Administrator"), "InvalidFormat 422: password must not be the username")
+
+	setSetting(t, settings.PasswordDenylistEnabled, "false")
+	assert.Nil(Validate("jdoe", "Password1234"))
+
+	setSetting(t, settings.PasswordRequiredCharacterClasses, "lower, upper,digit,symbol")
+	assert.EqualError(Validate("jdoe", "tiny-crab-on-a-bike"), "InvalidFormat 422: password must contain at least one upper character")
+	assert.EqualError(Validate("jdoe", "Tiny-crab-on-a-bike"), "InvalidFormat 422: password must contain at least one digit character")
+	assert.EqualError(Validate("jdoe", "Tiny2crabsonabike"), "InvalidFormat 422: password must contain at least one symbol character")
+	assert.Nil(Validate("jdoe", "Tiny 2 crabs on a bike"))
+
+	setSetting(t, settings.PasswordRequiredCharacterClasses, "emoji")
+	assert.Error(Validate("jdoe", "Tiny 2 crabs on a bike"))
+}
+
+func TestDenylist(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(commonPasswords["password123"])
+	assert.False(commonPasswords["# common passwords rejected by the password policy, one per line and in lower"])
+	for password := range commonPasswords {
+		assert.NotEqual("", password)
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	assert := assert.New(t)
+	setSetting(t, settings.PasswordHistorySize, "3")
+	now := time.Unix(1600000000, 0)
+
+	user := &v3.User{Username: "jdoe"}
+	for _, password := range []string{"first-password", "second-password", "third-password", "fourth-password"} {
+		assert.Nil(CheckReuse(user, password))
+		SetPassword(user, hash(t, password), now)
+	}
+	assert.Len(user.PasswordHistory, 2, "the current password is one of the last ones")
+	assert.Equal("2020-09-13T12:26:40Z", user.PasswordChangedAt)
+
+	assert.Error(CheckReuse(user, "fourth-password"))
+	assert.Error(CheckReuse(user, "second-password"))
+	assert.Nil(CheckReuse(user, "first-password"), "older passwords can be used again")
+
+	setSetting(t, settings.PasswordHistorySize, "0")
+	assert.Nil(CheckReuse(user, "fourth-password"))
+	SetPassword(user, hash(t, "fifth-password"), now)
+	assert.Nil(user.PasswordHistory)
+}
+
+func TestExpired(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1600000000, 0)
+	user := &v3.User{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-100 * 24 * time.Hour))},
+		Password:   "hash",
+	}
+
+	setSetting(t, settings.PasswordMaxAgeDays, "0")
+	assert.False(Expired(user, now))
+
+	setSetting(t, settings.PasswordMaxAgeDays, "90")
+	assert.True(Expired(user, now), "passwords without a change time are as old as their user")
+	user.PasswordChangedAt = now.Add(-89 * 24 * time.Hour).Format(time.RFC3339)
+	assert.False(Expired(user, now))
+	user.PasswordChangedAt = now.Add(-91 * 24 * time.Hour).Format(time.RFC3339)
+	assert.True(Expired(user, now))
+
+	user.Password = ""
+	assert.False(Expired(user, now), "users without password do not log in with one")
+}
@@ -74,6 +74,18 @@ func RefreshAllForCron() {
 	ref.refreshAll(false)
 }
 
+// RefreshProviderUsers refreshes every user with a principal of provider,
+// regardless of when they were last refreshed, so that those who lost access
+// to it are logged out.
+func RefreshProviderUsers(providerName string) {
+	if ref == nil {
+		return
+	}
+
+	logrus.Debugf("Triggering auth refresh of the users of provider %v", providerName)
+	ref.refreshProviderUsers(providerName)
+}
+
 func RefreshAttributes(attribs *v3.UserAttribute) (*v3.UserAttribute, error) {
 	if ref == nil {
 		return nil, errors.Errorf("refresh daemon not yet initialized")
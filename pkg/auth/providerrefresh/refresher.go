@@ -99,6 +99,30 @@ func (r *refresher) refreshAll(force bool) {
 	}
 }
 
+func (r *refresher) refreshProviderUsers(providerName string) {
+	users, err := r.userLister.List("", labels.Everything())
+	if err != nil {
+		logrus.Errorf("Error listing Users during auth provider refresh: %v", err)
+	}
+	prefix := principalPrefix(providerName)
+	for _, user := range users {
+		for _, principalID := range user.PrincipalIDs {
+			if strings.HasPrefix(principalID, prefix) {
+				r.triggerUserRefresh(user.Name, true)
+				break
+			}
+		}
+	}
+}
+
+// principalPrefix returns the prefix of the user principal IDs of provider.
+func principalPrefix(providerName string) string {
+	if providerName == "local" {
+		return "local://"
+	}
+	return providerName + "_user://"
+}
+
 func (r *refresher) triggerUserRefresh(userName string, force bool) {
 	attribs, needCreate, err := r.tokenMGR.EnsureAndGetUserAttribute(userName)
 	if err != nil {
@@ -188,10 +212,7 @@ func (r *refresher) refreshAttributes(attribs *v3.UserAttribute) (*v3.UserAttrib
 
 	for providerName := range providers.ProviderNames {
 		// We have to find out if the user has a userprincipal for the provider.
-		prefix := providerName + "_user://"
-		if providerName == "local" {
-			prefix = "local://"
-		}
+		prefix := principalPrefix(providerName)
 		principalID := ""
 		for _, id := range user.PrincipalIDs {
 			if strings.HasPrefix(id, prefix) {
@@ -263,6 +284,7 @@ func (r *refresher) refreshAttributes(attribs *v3.UserAttribute) (*v3.UserAttrib
 		}
 
 		// If the user doesn't have access through this provider, we want to remove their login tokens for this provider
+		// and disable the tokens they derived from them
 		if !canAccessProvider {
 			for _, token := range loginTokens[providerName] {
 				err := r.tokens.Delete(token.Name, &metav1.DeleteOptions{})
@@ -273,6 +295,14 @@ func (r *refresher) refreshAttributes(attribs *v3.UserAttribute) (*v3.UserAttrib
 					return nil, err
 				}
 			}
+			for _, token := range derivedTokens {
+				if token.AuthProvider != providerName {
+					continue
+				}
+				if err := r.disableToken(token); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 
@@ -282,12 +312,20 @@ func (r *refresher) refreshAttributes(attribs *v3.UserAttribute) (*v3.UserAttrib
 	}
 
 	for _, token := range derivedTokens {
-		token.Enabled = falsePointer
-		_, err := r.tokenMGR.UpdateToken(token)
-		if err != nil {
+		if err := r.disableToken(token); err != nil {
 			return nil, err
 		}
 	}
 
 	return attribs, nil
 }
+
+func (r *refresher) disableToken(token *v3.Token) error {
+	if token.Enabled != nil && !*token.Enabled {
+		return nil
+	}
+	token = token.DeepCopy()
+	token.Enabled = falsePointer
+	_, err := r.tokenMGR.UpdateToken(token)
+	return err
+}
@@ -5,6 +5,7 @@
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
@@ -13,6 +14,7 @@
 	"github.com/rancher/norman/types/slice"
 	"github.com/rancher/rancher/pkg/auth/providers/common/ldap"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
 	"github.com/sirupsen/logrus"
 	ldapv2 "gopkg.in/ldap.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,21 +29,13 @@ func (p *adProvider) loginUser(adCredential *v32.BasicLogin, config *v32.ActiveD
 		return v3.Principal{}, nil, httperror.NewAPIError(httperror.MissingRequired, "password not provided")
 	}
 	externalID := ldap.GetUserExternalID(username, config.DefaultLoginDomain)
+	defer metrics.ObserveLDAPOperation(Name, "login", time.Now())
 
-	lConn, err := p.ldapConnection(config, caPool)
+	lConn, put, err := p.loginConnection(config, caPool, testServiceAccountBind)
 	if err != nil {
 		return v3.Principal{}, nil, err
 	}
-	defer lConn.Close()
-
-	serviceAccountPassword := config.ServiceAccountPassword
-	serviceAccountUserName := config.ServiceAccountUsername
-	if testServiceAccountBind {
-		err = ldap.AuthenticateServiceAccountUser(serviceAccountPassword, serviceAccountUserName, config.DefaultLoginDomain, lConn)
-		if err != nil {
-			return v3.Principal{}, nil, err
-		}
-	}
+	defer put()
 
 	logrus.Debug("Binding username password")
 	err = lConn.Bind(externalID, password)
@@ -74,7 +68,7 @@ func (p *adProvider) loginUser(adCredential *v32.BasicLogin, config *v32.ActiveD
 		return v3.Principal{}, nil, fmt.Errorf("ldap user search found more than one result")
 	}
 
-	userPrincipal, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, config, lConn)
+	userPrincipal, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, config, lConn, false)
 	if err != nil {
 		return v3.Principal{}, nil, err
 	}
@@ -90,25 +84,47 @@ func (p *adProvider) loginUser(adCredential *v32.BasicLogin, config *v32.ActiveD
 	return userPrincipal, groupPrincipals, err
 }
 
-func (p *adProvider) RefetchGroupPrincipals(principalID string, secret string) ([]v3.Principal, error) {
-	config, caPool, err := p.getActiveDirectoryConfig()
-	if err != nil {
-		return nil, err
+// loginConnection returns a connection for logging a user in and a func
+// releasing it. Logins use pooled connections, except when testing a config
+// that is not saved yet, or when the service account can not bind, as users
+// can still log in then.
+func (p *adProvider) loginConnection(config *v32.ActiveDirectoryConfig, caPool *x509.CertPool, testServiceAccountBind bool) (*ldapv2.Conn, func(), error) {
+	if !testServiceAccountBind {
+		lConn, err := p.pool.Get(ldap.ActiveDirectoryConnConfig(config), caPool)
+		if err == nil {
+			return lConn, func() { p.pool.Put(lConn) }, nil
+		}
+		if !ldap.IsInvalidCredentials(err) {
+			return nil, nil, err
+		}
 	}
 
 	lConn, err := p.ldapConnection(config, caPool)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer lConn.Close()
+	if testServiceAccountBind {
+		err = ldap.AuthenticateServiceAccountUser(config.ServiceAccountPassword, config.ServiceAccountUsername, config.DefaultLoginDomain, lConn)
+		if err != nil {
+			lConn.Close()
+			return nil, nil, err
+		}
+	}
+	return lConn, lConn.Close, nil
+}
 
-	serviceAccountPassword := config.ServiceAccountPassword
-	serviceAccountUserName := config.ServiceAccountUsername
+func (p *adProvider) RefetchGroupPrincipals(principalID string, secret string) ([]v3.Principal, error) {
+	config, caPool, err := p.getActiveDirectoryConfig()
+	if err != nil {
+		return nil, err
+	}
+	defer metrics.ObserveLDAPOperation(Name, "refetchGroups", time.Now())
 
-	err = ldap.AuthenticateServiceAccountUser(serviceAccountPassword, serviceAccountUserName, config.DefaultLoginDomain, lConn)
+	lConn, err := p.pool.Get(ldap.ActiveDirectoryConnConfig(config), caPool)
 	if err != nil {
 		return nil, err
 	}
+	defer p.pool.Put(lConn)
 
 	externalID, _, err := p.getDNAndScopeFromPrincipalID(principalID)
 	if err != nil {
@@ -142,7 +158,9 @@ func (p *adProvider) RefetchGroupPrincipals(principalID string, secret string) (
 		return nil, fmt.Errorf("ldap user search found more than one result")
 	}
 
-	_, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, config, lConn)
+	// refreshing looks the groups up in the directory rather than the cache,
+	// so that users removed from groups lose the access of those groups
+	_, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, config, lConn, true)
 	if err != nil {
 		return nil, err
 	}
@@ -150,14 +168,14 @@ func (p *adProvider) RefetchGroupPrincipals(principalID string, secret string) (
 	return groupPrincipals, err
 }
 
-func (p *adProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult, config *v32.ActiveDirectoryConfig, lConn *ldapv2.Conn) (v3.Principal, []v3.Principal, error) {
+// getPrincipalsFromSearchResult returns the principal and groups of a user.
+// With refresh, the groups that groups are nested in are looked up in the
+// directory instead of the cache.
+func (p *adProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult, config *v32.ActiveDirectoryConfig, lConn *ldapv2.Conn, refresh bool) (v3.Principal, []v3.Principal, error) {
 	var groupPrincipals []v3.Principal
 	var userPrincipal v3.Principal
 
 	var nonDupGroupPrincipals []v3.Principal
-	var nestedGroupPrincipals []v3.Principal
-
-	groupMap := make(map[string]bool)
 
 	entry := result.Entries[0]
 
@@ -228,24 +246,9 @@ func (p *adProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult,
 
 			// Handling nestedgroups: tracing from down to top in order to find the parent groups, parent parent groups, and so on...
 			// When traversing up, we note down all the parent groups and add them to groupPrincipals
-			commonConfig := ldap.ConfigAttributes{
-				GroupMemberMappingAttribute: config.GroupMemberMappingAttribute,
-				GroupNameAttribute:          config.GroupNameAttribute,
-				GroupObjectClass:            config.GroupObjectClass,
-				GroupSearchAttribute:        config.GroupSearchAttribute,
-				ObjectClass:                 ObjectClass,
-				ProviderName:                Name,
-				UserLoginAttribute:          config.UserLoginAttribute,
-				UserNameAttribute:           config.UserNameAttribute,
-				UserObjectClass:             config.UserObjectClass,
-			}
-			searchAttributes := []string{MemberOfAttribute, ObjectClass, config.GroupObjectClass, config.UserLoginAttribute, config.GroupNameAttribute,
-				config.GroupSearchAttribute}
-			for _, groupPrincipal := range groupPrincipals {
-				err = ldap.GatherParentGroups(groupPrincipal, searchDomain, GroupScope, &commonConfig, lConn, groupMap, &nestedGroupPrincipals, searchAttributes)
-				if err != nil {
-					return userPrincipal, groupPrincipals, nil
-				}
+			nestedGroupPrincipals, err := p.gatherParentGroups(groupPrincipals, searchDomain, config, lConn, refresh)
+			if err != nil {
+				return userPrincipal, groupPrincipals, nil
 			}
 			nonDupGroupPrincipals = ldap.FindNonDuplicateBetweenGroupPrincipals(nestedGroupPrincipals, groupPrincipals, []v3.Principal{})
 			groupPrincipals = append(groupPrincipals, nonDupGroupPrincipals...)
@@ -256,6 +259,68 @@ func (p *adProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult,
 	return userPrincipal, groupPrincipals, nil
 }
 
+// gatherParentGroups returns the groups that groups are nested in, tracing
+// them up level by level. The parents of each group are cached, as users
+// mostly share groups, and refresh searches them again.
+func (p *adProvider) gatherParentGroups(groupPrincipals []v3.Principal, searchDomain string, config *v32.ActiveDirectoryConfig, lConn *ldapv2.Conn, refresh bool) ([]v3.Principal, error) {
+	connConfig := ldap.ActiveDirectoryConnConfig(config)
+	commonConfig := ldap.ConfigAttributes{
+		GroupMemberMappingAttribute: config.GroupMemberMappingAttribute,
+		GroupNameAttribute:          config.GroupNameAttribute,
+		GroupObjectClass:            config.GroupObjectClass,
+		GroupSearchAttribute:        config.GroupSearchAttribute,
+		ObjectClass:                 ObjectClass,
+		ProviderName:                Name,
+		UserLoginAttribute:          config.UserLoginAttribute,
+		UserNameAttribute:           config.UserNameAttribute,
+		UserObjectClass:             config.UserObjectClass,
+	}
+	searchAttributes := []string{MemberOfAttribute, ObjectClass, config.GroupObjectClass, config.UserLoginAttribute, config.GroupNameAttribute,
+		config.GroupSearchAttribute}
+
+	seen := make(map[string]bool)
+	for _, groupPrincipal := range groupPrincipals {
+		seen[groupPrincipal.Name] = true
+	}
+
+	var nestedGroupPrincipals []v3.Principal
+	level := groupPrincipals
+	for len(level) > 0 {
+		var next []v3.Principal
+		for _, groupPrincipal := range level {
+			parts := strings.SplitN(groupPrincipal.Name, "://", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid id %v", groupPrincipal.Name)
+			}
+			groupDN := parts[1]
+
+			var parents []v3.Principal
+			cached := false
+			if !refresh {
+				parents, cached = p.cache.GetPrincipals(ldap.ParentGroupCache, connConfig, groupDN)
+			}
+			if !cached {
+				var err error
+				parents, err = ldap.SearchParentGroups(groupDN, searchDomain, GroupScope, &commonConfig, lConn, searchAttributes)
+				if err != nil {
+					return nil, err
+				}
+				p.cache.AddPrincipals(ldap.ParentGroupCache, connConfig, groupDN, parents)
+			}
+
+			for _, parent := range parents {
+				if !seen[parent.Name] {
+					seen[parent.Name] = true
+					nestedGroupPrincipals = append(nestedGroupPrincipals, parent)
+					next = append(next, parent)
+				}
+			}
+		}
+		level = next
+	}
+	return nestedGroupPrincipals, nil
+}
+
 func (p *adProvider) getGroupPrincipalsFromSearch(searchBase string, filter string, config *v32.ActiveDirectoryConfig, lConn *ldapv2.Conn,
 	groupDN []string) ([]v3.Principal, error) {
 	var groupPrincipals []v3.Principal
@@ -341,18 +406,18 @@ func (p *adProvider) getPrincipal(distinguishedName string, scope string, config
 	}
 
 	logrus.Debugf("Query for getPrincipal(%s): %s", distinguishedName, filter)
-	lConn, err := p.ldapConnection(config, caPool)
-	if err != nil {
-		return nil, err
+	connConfig := ldap.ActiveDirectoryConnConfig(config)
+	cacheKey := scope + "://" + distinguishedName
+	if principal, ok := p.cache.GetPrincipal(connConfig, cacheKey); ok {
+		return principal, nil
 	}
-	defer lConn.Close()
-	// Bind before query
-	// If service acc bind fails, and auth is on, return principal formed using DN
-	serviceAccountUsername := ldap.GetUserExternalID(config.ServiceAccountUsername, config.DefaultLoginDomain)
-	err = lConn.Bind(serviceAccountUsername, config.ServiceAccountPassword)
+	defer metrics.ObserveLDAPOperation(Name, "getPrincipal", time.Now())
 
+	// The pool binds as the service account before the query
+	// If service acc bind fails, and auth is on, return principal formed using DN
+	lConn, err := p.pool.Get(connConfig, caPool)
 	if err != nil {
-		if ldapv2.IsErrorWithCode(err, ldapv2.LDAPResultInvalidCredentials) && config.Enabled {
+		if ldap.IsInvalidCredentials(err) && config.Enabled {
 			var kind string
 			if strings.EqualFold(UserScope, scope) {
 				kind = "user"
@@ -370,6 +435,7 @@ func (p *adProvider) getPrincipal(distinguishedName string, scope string, config
 		}
 		return nil, fmt.Errorf("Error in ldap bind: %v", err)
 	}
+	defer p.pool.Put(lConn)
 
 	if strings.EqualFold(UserScope, scope) {
 		search = ldapv2.NewSearchRequest(distinguishedName,
@@ -407,10 +473,12 @@ func (p *adProvider) getPrincipal(distinguishedName string, scope string, config
 	if err != nil {
 		return nil, err
 	}
+	p.cache.AddPrincipal(connConfig, cacheKey, principal)
 	return principal, nil
 }
 
 func (p *adProvider) searchPrincipals(name, principalType string, config *v32.ActiveDirectoryConfig, lConn *ldapv2.Conn) ([]v3.Principal, error) {
+	defer metrics.ObserveLDAPOperation(Name, "search", time.Now())
 	name = ldapv2.EscapeFilter(name)
 
 	var principals []v3.Principal
@@ -12,6 +12,7 @@
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/rancher/pkg/auth/providers/common"
+	"github.com/rancher/rancher/pkg/auth/providers/common/ldap"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3public"
@@ -42,6 +43,8 @@ type adProvider struct {
 	certs       string
 	caPool      *x509.CertPool
 	tokenMGR    *tokens.Manager
+	pool        *ldap.ConnPool
+	cache       *ldap.Cache
 }
 
 func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.Manager, tokenMGR *tokens.Manager) common.AuthProvider {
@@ -51,6 +54,8 @@ func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.
 		secrets:     mgmtCtx.Core.Secrets(""),
 		userMGR:     userMGR,
 		tokenMGR:    tokenMGR,
+		pool:        ldap.NewConnPool(Name),
+		cache:       ldap.NewCache(Name),
 	}
 }
 
@@ -101,11 +106,11 @@ func (p *adProvider) SearchPrincipals(searchKey, principalType string, myToken v
 		return principals, nil
 	}
 
-	lConn, err := p.ldapConnection(config, caPool)
+	lConn, err := p.pool.Get(ldap.ActiveDirectoryConnConfig(config), caPool)
 	if err != nil {
 		return principals, nil
 	}
-	defer lConn.Close()
+	defer p.pool.Put(lConn)
 
 	principals, err = p.searchPrincipals(searchKey, principalType, config, lConn)
 	if err == nil {
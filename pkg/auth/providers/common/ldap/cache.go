@@ -0,0 +1,93 @@
+package ldap
+
+import (
+	"strings"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/settings"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// PrincipalCache caches principals by DN.
+	PrincipalCache = "principal"
+	// ParentGroupCache caches the groups that groups are members of by DN.
+	ParentGroupCache = "parents"
+
+	cacheSize = 10000
+)
+
+// Cache caches the principals looked up in the directory of an ldap auth
+// provider for ldap-cache-ttl-seconds. Entries are keyed by the config, so
+// changing the config does not return entries of the old one.
+type Cache struct {
+	provider string
+	entries  *cache.LRUExpireCache
+}
+
+func NewCache(provider string) *Cache {
+	return &Cache{
+		provider: provider,
+		entries:  cache.NewLRUExpireCache(cacheSize),
+	}
+}
+
+// GetPrincipal returns a copy of the cached principal with a DN.
+func (c *Cache) GetPrincipal(config *ConnConfig, dn string) (*v3.Principal, bool) {
+	value, ok := c.get(PrincipalCache, config, dn)
+	if !ok {
+		return nil, false
+	}
+	return value.(*v3.Principal).DeepCopy(), true
+}
+
+func (c *Cache) AddPrincipal(config *ConnConfig, dn string, principal *v3.Principal) {
+	c.add(PrincipalCache, config, dn, principal.DeepCopy())
+}
+
+// GetPrincipals returns a copy of the principals cached in a cache with a DN.
+func (c *Cache) GetPrincipals(name string, config *ConnConfig, dn string) ([]v3.Principal, bool) {
+	value, ok := c.get(name, config, dn)
+	if !ok {
+		return nil, false
+	}
+	return copyPrincipals(value.([]v3.Principal)), true
+}
+
+func (c *Cache) AddPrincipals(name string, config *ConnConfig, dn string, principals []v3.Principal) {
+	c.add(name, config, dn, copyPrincipals(principals))
+}
+
+func (c *Cache) get(name string, config *ConnConfig, dn string) (interface{}, bool) {
+	if ttl() <= 0 {
+		return nil, false
+	}
+	value, ok := c.entries.Get(cacheKey(name, config, dn))
+	metrics.RecordLDAPCacheLookup(c.provider, name, ok)
+	return value, ok
+}
+
+func (c *Cache) add(name string, config *ConnConfig, dn string, value interface{}) {
+	if ttl := ttl(); ttl > 0 {
+		c.entries.Add(cacheKey(name, config, dn), value, ttl)
+	}
+}
+
+func ttl() time.Duration {
+	return time.Duration(settings.LdapCacheTTLSeconds.GetInt()) * time.Second
+}
+
+// cacheKey keys entries by DN case insensitively, as directories compare DNs.
+func cacheKey(name string, config *ConnConfig, dn string) string {
+	return name + "/" + config.Key + "/" + strings.ToLower(dn)
+}
+
+func copyPrincipals(principals []v3.Principal) []v3.Principal {
+	result := make([]v3.Principal, len(principals))
+	for i := range principals {
+		principals[i].DeepCopyInto(&result[i])
+	}
+	return result
+}
@@ -247,21 +247,41 @@ func AttributesToPrincipal(attribs []*ldapv2.EntryAttribute, dnStr, scope, provi
 func GatherParentGroups(groupPrincipal v3.Principal, searchDomain string, groupScope string, config *ConfigAttributes, lConn *ldapv2.Conn,
 	groupMap map[string]bool, nestedGroupPrincipals *[]v3.Principal, searchAttributes []string) error {
 	groupMap[groupPrincipal.ObjectMeta.Name] = true
-	principals := []v3.Principal{}
-	//var searchAttributes []string
 	parts := strings.SplitN(groupPrincipal.ObjectMeta.Name, ":", 2)
 	if len(parts) != 2 {
 		return errors.Errorf("invalid id %v", groupPrincipal.ObjectMeta.Name)
 	}
 	groupDN := strings.TrimPrefix(parts[1], "//")
 
+	principals, err := SearchParentGroups(groupDN, searchDomain, groupScope, config, lConn, searchAttributes)
+	if err != nil {
+		return err
+	}
+
+	for _, gp := range principals {
+		if _, ok := groupMap[gp.ObjectMeta.Name]; ok {
+			continue
+		} else {
+			*nestedGroupPrincipals = append(*nestedGroupPrincipals, gp)
+			err = GatherParentGroups(gp, searchDomain, groupScope, config, lConn, groupMap, nestedGroupPrincipals, searchAttributes)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SearchParentGroups returns the groups that a group is a direct member of.
+func SearchParentGroups(groupDN string, searchDomain string, groupScope string, config *ConfigAttributes, lConn *ldapv2.Conn, searchAttributes []string) ([]v3.Principal, error) {
+	principals := []v3.Principal{}
 	searchGroup := ldapv2.NewSearchRequest(searchDomain,
 		ldapv2.ScopeWholeSubtree, ldapv2.NeverDerefAliases, 0, 0, false,
 		fmt.Sprintf("(&(%v=%v)(%v=%v))", config.GroupMemberMappingAttribute, ldapv2.EscapeFilter(groupDN), config.ObjectClass, config.GroupObjectClass),
 		searchAttributes, nil)
 	resultGroups, err := lConn.SearchWithPaging(searchGroup, 1000)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for i := 0; i < len(resultGroups.Entries); i++ {
@@ -273,19 +293,16 @@ func GatherParentGroups(groupPrincipal v3.Principal, searchDomain string, groupS
 		}
 		principals = append(principals, *principal)
 	}
+	return principals, nil
+}
 
-	for _, gp := range principals {
-		if _, ok := groupMap[gp.ObjectMeta.Name]; ok {
-			continue
-		} else {
-			*nestedGroupPrincipals = append(*nestedGroupPrincipals, gp)
-			err = GatherParentGroups(gp, searchDomain, groupScope, config, lConn, groupMap, nestedGroupPrincipals, searchAttributes)
-			if err != nil {
-				return err
-			}
-		}
+// IsInvalidCredentials returns whether binding failed because of the
+// credentials, such as when the password of a service account changed.
+func IsInvalidCredentials(err error) bool {
+	if apiErr, ok := err.(*httperror.APIError); ok {
+		err = apiErr.Cause
 	}
-	return nil
+	return ldapv2.IsErrorWithCode(err, ldapv2.LDAPResultInvalidCredentials)
 }
 
 func FindNonDuplicateBetweenGroupPrincipals(newGroupPrincipals []v3.Principal, groupPrincipals []v3.Principal, nonDupGroupPrincipals []v3.Principal) []v3.Principal {
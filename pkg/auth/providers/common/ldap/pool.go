@@ -0,0 +1,246 @@
+package ldap
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	ldapv2 "gopkg.in/ldap.v2"
+)
+
+// maxIdleTime is how long a connection may stay idle in the pool. Directory
+// servers such as Active Directory drop connections idle for longer.
+const maxIdleTime = 5 * time.Minute
+
+// ConnConfig is the part of the config of an ldap auth provider that the
+// connections of its pool and its cached lookups depend on.
+type ConnConfig struct {
+	Servers                []string
+	Port                   int64
+	TLS                    bool
+	StartTLS               bool
+	ConnectionTimeout      int64
+	ServiceAccountUsername string
+	ServiceAccountPassword string
+	DefaultLoginDomain     string
+	// Key identifies the fields of the config, so that the connections and
+	// cached lookups of an older config are not used.
+	Key string
+}
+
+// LdapConnConfig returns the ConnConfig of an openldap or freeipa config.
+func LdapConnConfig(config *v3.LdapConfig) *ConnConfig {
+	return &ConnConfig{
+		Servers:                config.Servers,
+		Port:                   config.Port,
+		TLS:                    config.TLS,
+		StartTLS:               config.StartTLS,
+		ConnectionTimeout:      config.ConnectionTimeout,
+		ServiceAccountUsername: config.ServiceAccountDistinguishedName,
+		ServiceAccountPassword: config.ServiceAccountPassword,
+		Key:                    configKey(config.LdapFields),
+	}
+}
+
+// ActiveDirectoryConnConfig returns the ConnConfig of an active directory
+// config.
+func ActiveDirectoryConnConfig(config *v32.ActiveDirectoryConfig) *ConnConfig {
+	fields := *config
+	fields.AuthConfig = v32.AuthConfig{}
+	return &ConnConfig{
+		Servers:                config.Servers,
+		Port:                   config.Port,
+		TLS:                    config.TLS,
+		StartTLS:               config.StartTLS,
+		ConnectionTimeout:      config.ConnectionTimeout,
+		ServiceAccountUsername: config.ServiceAccountUsername,
+		ServiceAccountPassword: config.ServiceAccountPassword,
+		DefaultLoginDomain:     config.DefaultLoginDomain,
+		Key:                    configKey(fields),
+	}
+}
+
+// ConnPool is a bounded pool of connections of an ldap auth provider, bound as
+// its service account. Connections are checked when taken from the pool by
+// binding them as the service account again, which also undoes the bind of a
+// user logging in on them.
+type ConnPool struct {
+	provider string
+
+	lock sync.Mutex
+	// slots bounds the connections in use to ldap-connection-pool-size. It is
+	// replaced when the setting changes, and connections are put back to the
+	// slots they were taken from.
+	slots chan struct{}
+	key   string
+	idle  []idleConn
+	inUse map[*ldapv2.Conn]checkedOut
+
+	dial func(config *ConnConfig, caPool *x509.CertPool) (*ldapv2.Conn, error)
+	bind func(config *ConnConfig, lConn *ldapv2.Conn) error
+	now  func() time.Time
+}
+
+type idleConn struct {
+	lConn *ldapv2.Conn
+	since time.Time
+}
+
+type checkedOut struct {
+	key   string
+	slots chan struct{}
+}
+
+func NewConnPool(provider string) *ConnPool {
+	return &ConnPool{
+		provider: provider,
+		inUse:    map[*ldapv2.Conn]checkedOut{},
+		dial: func(config *ConnConfig, caPool *x509.CertPool) (*ldapv2.Conn, error) {
+			return NewLDAPConn(config.Servers, config.TLS, config.StartTLS, config.Port, config.ConnectionTimeout, caPool)
+		},
+		bind: func(config *ConnConfig, lConn *ldapv2.Conn) error {
+			return AuthenticateServiceAccountUser(config.ServiceAccountPassword, config.ServiceAccountUsername, config.DefaultLoginDomain, lConn)
+		},
+		now: time.Now,
+	}
+}
+
+// currentSlots returns the slots of the pool sized after the current value of
+// ldap-connection-pool-size.
+func (p *ConnPool) currentSlots() chan struct{} {
+	size := poolSize()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.slots == nil || cap(p.slots) != size {
+		p.slots = make(chan struct{}, size)
+	}
+	return p.slots
+}
+
+func poolSize() int {
+	size := settings.LdapConnectionPoolSize.GetInt()
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// Get returns a connection bound as the service account of the config, waiting
+// for one to be put back if all of them are in use. Connections must be put
+// back with Put.
+func (p *ConnPool) Get(config *ConnConfig, caPool *x509.CertPool) (*ldapv2.Conn, error) {
+	start := p.now()
+	defer metrics.ObserveLDAPOperation(p.provider, "connect", start)
+
+	timeout := time.Duration(config.ConnectionTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	slots := p.currentSlots()
+	select {
+	case slots <- struct{}{}:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out waiting for a connection to %v", strings.Join(config.Servers, ","))
+	}
+
+	key := config.Key
+	for {
+		lConn := p.takeIdle(key)
+		if lConn == nil {
+			break
+		}
+		if err := p.bind(config, lConn); err != nil {
+			logrus.Debugf("Dropping pooled ldap connection of %v: %v", p.provider, err)
+			lConn.Close()
+			continue
+		}
+		return p.checkOut(lConn, key, slots), nil
+	}
+
+	lConn, err := p.dial(config, caPool)
+	if err != nil {
+		<-slots
+		return nil, err
+	}
+	if err := p.bind(config, lConn); err != nil {
+		lConn.Close()
+		<-slots
+		return nil, err
+	}
+	return p.checkOut(lConn, key, slots), nil
+}
+
+// Put returns a connection taken with Get to the pool. Connections of an older
+// config, or beyond a pool size that was lowered, are closed instead.
+func (p *ConnPool) Put(lConn *ldapv2.Conn) {
+	p.lock.Lock()
+	out, ok := p.inUse[lConn]
+	delete(p.inUse, lConn)
+	keep := ok && out.key == p.key && len(p.idle) < cap(p.slots)
+	if keep {
+		p.idle = append(p.idle, idleConn{lConn: lConn, since: p.now()})
+	}
+	p.lock.Unlock()
+
+	if !keep {
+		lConn.Close()
+	}
+	if ok {
+		<-out.slots
+	}
+}
+
+func (p *ConnPool) checkOut(lConn *ldapv2.Conn, key string, slots chan struct{}) *ldapv2.Conn {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inUse[lConn] = checkedOut{key: key, slots: slots}
+	return lConn
+}
+
+// takeIdle returns the most recently used idle connection of a config, after
+// closing the idle connections of other configs and those idle for too long.
+func (p *ConnPool) takeIdle(key string) *ldapv2.Conn {
+	var stale []*ldapv2.Conn
+	defer func() {
+		for _, lConn := range stale {
+			lConn.Close()
+		}
+	}()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.key != key {
+		for _, idle := range p.idle {
+			stale = append(stale, idle.lConn)
+		}
+		p.idle = nil
+		p.key = key
+	}
+	for len(p.idle) > 0 {
+		idle := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.now().Sub(idle.since) > maxIdleTime {
+			stale = append(stale, idle.lConn)
+			continue
+		}
+		return idle.lConn
+	}
+	return nil
+}
+
+func configKey(fields interface{}) string {
+	data, _ := json.Marshal(fields)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
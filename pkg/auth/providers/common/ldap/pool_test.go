@@ -0,0 +1,160 @@
+package ldap
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	ldapv2 "gopkg.in/ldap.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPool(t *testing.T) (*ConnPool, *int, map[*ldapv2.Conn]bool, *time.Time) {
+	dials := 0
+	broken := map[*ldapv2.Conn]bool{}
+	now := time.Unix(1600000000, 0)
+	pool := NewConnPool("openldap")
+	pool.dial = func(config *ConnConfig, caPool *x509.CertPool) (*ldapv2.Conn, error) {
+		dials++
+		client, server := net.Pipe()
+		t.Cleanup(func() {
+			server.Close()
+		})
+		lConn := ldapv2.NewConn(client, false)
+		lConn.Start()
+		return lConn, nil
+	}
+	pool.bind = func(config *ConnConfig, lConn *ldapv2.Conn) error {
+		if broken[lConn] {
+			return errors.New("connection closed")
+		}
+		return nil
+	}
+	pool.now = func() time.Time {
+		return now
+	}
+	return pool, &dials, broken, &now
+}
+
+func newTestConfig(server string) *ConnConfig {
+	return LdapConnConfig(&v3.LdapConfig{
+		LdapFields: v32.LdapFields{
+			Servers:           []string{server},
+			ConnectionTimeout: 100,
+		},
+	})
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	assert := assert.New(t)
+	pool, dials, broken, now := newTestPool(t)
+	config := newTestConfig("ldap.example.com")
+
+	first, err := pool.Get(config, nil)
+	assert.Nil(err)
+	pool.Put(first)
+	second, err := pool.Get(config, nil)
+	assert.Nil(err)
+	assert.Equal(first, second, "idle connections are reused")
+	assert.Equal(1, *dials)
+
+	broken[second] = true
+	pool.Put(second)
+	third, err := pool.Get(config, nil)
+	assert.Nil(err)
+	assert.NotEqual(second, third, "connections failing the health check are dropped")
+	assert.Equal(2, *dials)
+
+	pool.Put(third)
+	*now = now.Add(maxIdleTime + time.Second)
+	fourth, err := pool.Get(config, nil)
+	assert.Nil(err)
+	assert.NotEqual(third, fourth, "connections idle for too long are dropped")
+	pool.Put(fourth)
+
+	fifth, err := pool.Get(newTestConfig("ldap2.example.com"), nil)
+	assert.Nil(err)
+	assert.NotEqual(fourth, fifth, "connections of another config are not reused")
+	assert.Equal(4, *dials)
+	pool.Put(fifth)
+}
+
+func TestConnPoolSize(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(settings.LdapConnectionPoolSize.Set("2"))
+	t.Cleanup(func() {
+		settings.LdapConnectionPoolSize.Set(settings.LdapConnectionPoolSize.Default)
+	})
+	pool, _, _, _ := newTestPool(t)
+	config := newTestConfig("ldap.example.com")
+
+	first, err := pool.Get(config, nil)
+	assert.Nil(err)
+	_, err = pool.Get(config, nil)
+	assert.Nil(err)
+	_, err = pool.Get(config, nil)
+	assert.Error(err, "no more connections than the pool size are opened")
+
+	pool.Put(first)
+	second, err := pool.Get(config, nil)
+	assert.Nil(err)
+
+	assert.Nil(settings.LdapConnectionPoolSize.Set("3"))
+	_, err = pool.Get(config, nil)
+	assert.Nil(err, "changes of the pool size apply to the next connection")
+	pool.Put(second)
+	assert.Len(pool.idle, 1, "connections are put back to the slots they were taken from")
+}
+
+func TestActiveDirectoryConnConfig(t *testing.T) {
+	assert := assert.New(t)
+	config := &v32.ActiveDirectoryConfig{
+		Servers:                []string{"ad.example.com"},
+		ServiceAccountUsername: "svc",
+		DefaultLoginDomain:     "EXAMPLE",
+	}
+	connConfig := ActiveDirectoryConnConfig(config)
+	assert.Equal("svc", connConfig.ServiceAccountUsername)
+	assert.Equal("EXAMPLE", connConfig.DefaultLoginDomain)
+
+	config.AllowedPrincipalIDs = []string{"activedirectory_group://cn=admins"}
+	assert.Equal(connConfig.Key, ActiveDirectoryConnConfig(config).Key, "changes of the access mode keep the connections")
+	config.UserSearchBase = "ou=users,dc=example,dc=com"
+	assert.NotEqual(connConfig.Key, ActiveDirectoryConnConfig(config).Key)
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := NewCache("openldap")
+	config := newTestConfig("ldap.example.com")
+	principal := &v3.Principal{ObjectMeta: metav1.ObjectMeta{Name: "openldap_user://uid=a,dc=example,dc=com"}}
+
+	cache.AddPrincipal(config, "uid=a,dc=example,dc=com", principal)
+	cached, ok := cache.GetPrincipal(config, "UID=a,DC=example,DC=com")
+	assert.True(ok)
+	assert.Equal(principal, cached)
+	cached.Me = true
+	cached, _ = cache.GetPrincipal(config, "uid=a,dc=example,dc=com")
+	assert.False(cached.Me, "cached principals are copied")
+
+	_, ok = cache.GetPrincipal(newTestConfig("ldap2.example.com"), "uid=a,dc=example,dc=com")
+	assert.False(ok, "entries of other configs are not returned")
+
+	cache.AddPrincipals(ParentGroupCache, config, "cn=a,dc=example,dc=com", nil)
+	groups, ok := cache.GetPrincipals(ParentGroupCache, config, "cn=a,dc=example,dc=com")
+	assert.True(ok, "empty results are cached")
+	assert.Empty(groups)
+
+	assert.Nil(settings.LdapCacheTTLSeconds.Set("0"))
+	t.Cleanup(func() {
+		settings.LdapCacheTTLSeconds.Set(settings.LdapCacheTTLSeconds.Default)
+	})
+	_, ok = cache.GetPrincipal(config, "uid=a,dc=example,dc=com")
+	assert.False(ok, "a ttl of 0 disables the cache")
+}
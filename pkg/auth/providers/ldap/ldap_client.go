@@ -5,6 +5,7 @@
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
@@ -12,6 +13,7 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/rancher/pkg/auth/providers/common/ldap"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
 	"github.com/sirupsen/logrus"
 	ldapv2 "gopkg.in/ldap.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,19 +30,13 @@ func (p *ldapProvider) loginUser(credential *v32.BasicLogin, config *v3.LdapConf
 	if password == "" {
 		return v3.Principal{}, nil, httperror.NewAPIError(httperror.MissingRequired, "password not provided")
 	}
+	defer metrics.ObserveLDAPOperation(p.providerName, "login", time.Now())
 
-	lConn, err := ldap.Connect(config, caPool)
-	if err != nil {
-		return v3.Principal{}, nil, err
-	}
-	defer lConn.Close()
-
-	serviceAccountPassword := config.ServiceAccountPassword
-	serviceAccountUserName := config.ServiceAccountDistinguishedName
-	err = ldap.AuthenticateServiceAccountUser(serviceAccountPassword, serviceAccountUserName, "", lConn)
+	lConn, err := p.pool.Get(ldap.LdapConnConfig(config), caPool)
 	if err != nil {
 		return v3.Principal{}, nil, err
 	}
+	defer p.pool.Put(lConn)
 
 	logrus.Debug("Binding username password")
 
@@ -81,7 +77,7 @@ func (p *ldapProvider) loginUser(credential *v32.BasicLogin, config *v3.LdapConf
 		return v3.Principal{}, nil, httperror.WrapAPIError(err, httperror.Unauthorized, "Cannot locate user information for "+searchOpRequest.Filter)
 	}
 
-	userPrincipal, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, opResult, config, lConn)
+	userPrincipal, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, opResult, config, lConn, false)
 	if err != nil {
 		return v3.Principal{}, nil, err
 	}
@@ -97,7 +93,10 @@ func (p *ldapProvider) loginUser(credential *v32.BasicLogin, config *v3.LdapConf
 	return userPrincipal, groupPrincipals, err
 }
 
-func (p *ldapProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult, opResult *ldapv2.SearchResult, config *v3.LdapConfig, lConn *ldapv2.Conn) (v3.Principal, []v3.Principal, error) {
+// getPrincipalsFromSearchResult returns the principal and groups of a user.
+// With refresh, the groups that groups are nested in are looked up in the
+// directory instead of the cache.
+func (p *ldapProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult, opResult *ldapv2.SearchResult, config *v3.LdapConfig, lConn *ldapv2.Conn, refresh bool) (v3.Principal, []v3.Principal, error) {
 	var groupPrincipals []v3.Principal
 	var userPrincipal v3.Principal
 	var nonDupGroupPrincipals []v3.Principal
@@ -105,7 +104,6 @@ func (p *ldapProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult
 	var nestedGroupPrincipals []v3.Principal
 	var freeipaNonEntrydnApproach bool
 
-	groupMap := make(map[string]bool)
 	entry := result.Entries[0]
 	userAttributes := entry.Attributes
 
@@ -205,24 +203,9 @@ func (p *ldapProvider) getPrincipalsFromSearchResult(result *ldapv2.SearchResult
 
 		// Handling nestedgroups: tracing from down to top in order to find the parent groups, parent parent groups, and so on...
 		// When traversing up, we note down all the parent groups and add them to groupPrincipals
-		commonConfig := ldap.ConfigAttributes{
-			GroupMemberMappingAttribute: config.GroupMemberMappingAttribute,
-			GroupNameAttribute:          config.GroupNameAttribute,
-			GroupObjectClass:            config.GroupObjectClass,
-			GroupSearchAttribute:        config.GroupSearchAttribute,
-			ObjectClass:                 ObjectClass,
-			ProviderName:                OpenLdapName,
-			UserLoginAttribute:          config.UserLoginAttribute,
-			UserNameAttribute:           config.UserNameAttribute,
-			UserObjectClass:             config.UserObjectClass,
-		}
-		searchAttributes := []string{config.GroupMemberUserAttribute, config.GroupMemberMappingAttribute, ObjectClass, config.GroupObjectClass, config.UserLoginAttribute,
-			config.GroupNameAttribute, config.GroupSearchAttribute}
-		for _, groupPrincipal := range groupPrincipals {
-			err = ldap.GatherParentGroups(groupPrincipal, searchDomain, groupScope, &commonConfig, lConn, groupMap, &nestedGroupPrincipals, searchAttributes)
-			if err != nil {
-				return userPrincipal, groupPrincipals, nil
-			}
+		nestedGroupPrincipals, err = p.gatherParentGroups(groupPrincipals, searchDomain, config, lConn, refresh)
+		if err != nil {
+			return userPrincipal, groupPrincipals, nil
 		}
 		nonDupGroupPrincipals = ldap.FindNonDuplicateBetweenGroupPrincipals(nestedGroupPrincipals, groupPrincipals, []v3.Principal{})
 		groupPrincipals = append(groupPrincipals, nonDupGroupPrincipals...)
@@ -267,18 +250,18 @@ func (p *ldapProvider) getPrincipal(distinguishedName string, scope string, conf
 
 	logrus.Debugf("Query for getPrincipal(%v): %v", distinguishedName, filter)
 
-	lConn, err := ldap.Connect(config, caPool)
-	if err != nil {
-		return nil, err
+	connConfig := ldap.LdapConnConfig(config)
+	cacheKey := scope + "://" + distinguishedName
+	if principal, ok := p.cache.GetPrincipal(connConfig, cacheKey); ok {
+		return principal, nil
 	}
-	defer lConn.Close()
-	// Bind before query
-	// If service acc bind fails, and auth is on, return principal formed using DN
-	serviceAccountUsername := ldap.GetUserExternalID(config.ServiceAccountDistinguishedName, "")
-	err = lConn.Bind(serviceAccountUsername, config.ServiceAccountPassword)
+	defer metrics.ObserveLDAPOperation(p.providerName, "getPrincipal", time.Now())
 
+	// The pool binds as the service account before the query
+	// If service acc bind fails, and auth is on, return principal formed using DN
+	lConn, err := p.pool.Get(connConfig, caPool)
 	if err != nil {
-		if ldapv2.IsErrorWithCode(err, ldapv2.LDAPResultInvalidCredentials) && config.Enabled {
+		if ldap.IsInvalidCredentials(err) && config.Enabled {
 			var kind string
 			if strings.EqualFold("user", entityType) {
 				kind = "user"
@@ -296,6 +279,7 @@ func (p *ldapProvider) getPrincipal(distinguishedName string, scope string, conf
 		}
 		return nil, fmt.Errorf("Error in ldap bind: %v", err)
 	}
+	defer p.pool.Put(lConn)
 
 	if strings.EqualFold("user", entityType) {
 		search = ldapv2.NewSearchRequest(distinguishedName,
@@ -334,10 +318,12 @@ func (p *ldapProvider) getPrincipal(distinguishedName string, scope string, conf
 	if err != nil {
 		return nil, err
 	}
+	p.cache.AddPrincipal(connConfig, cacheKey, principal)
 	return principal, nil
 }
 
 func (p *ldapProvider) searchPrincipals(name, principalType string, config *v3.LdapConfig, lConn *ldapv2.Conn) ([]v3.Principal, error) {
+	defer metrics.ObserveLDAPOperation(p.providerName, "search", time.Now())
 	name = ldapv2.EscapeFilter(name)
 	var principals []v3.Principal
 
@@ -476,23 +462,18 @@ func (p *ldapProvider) RefetchGroupPrincipals(principalID string, secret string)
 	if err != nil {
 		return nil, err
 	}
-	lConn, err := ldap.Connect(config, caPool)
-	if err != nil {
-		return nil, err
-	}
-	defer lConn.Close()
 
-	serviceAccountPassword := config.ServiceAccountPassword
-	serviceAccountUserName := config.ServiceAccountDistinguishedName
-	err = ldap.AuthenticateServiceAccountUser(serviceAccountPassword, serviceAccountUserName, "", lConn)
+	distinguishedName, _, err := p.getDNAndScopeFromPrincipalID(principalID)
 	if err != nil {
 		return nil, err
 	}
+	defer metrics.ObserveLDAPOperation(p.providerName, "refetchGroups", time.Now())
 
-	distinguishedName, _, err := p.getDNAndScopeFromPrincipalID(principalID)
+	lConn, err := p.pool.Get(ldap.LdapConnConfig(config), caPool)
 	if err != nil {
 		return nil, err
 	}
+	defer p.pool.Put(lConn)
 
 	searchRequest := ldapv2.NewSearchRequest(
 		distinguishedName,
@@ -539,9 +520,152 @@ func (p *ldapProvider) RefetchGroupPrincipals(principalID string, secret string)
 		return nil, httperror.WrapAPIError(err, httperror.Unauthorized, "Cannot locate user information for "+searchOpRequest.Filter)
 	}
 
-	_, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, opResult, config, lConn)
+	// refreshing looks the groups up in the directory rather than the cache,
+	// so that users removed from groups lose the access of those groups
+	_, groupPrincipals, err := p.getPrincipalsFromSearchResult(result, opResult, config, lConn, true)
 	if err != nil {
 		return nil, err
 	}
 	return groupPrincipals, nil
 }
+
+// gatherParentGroups returns the groups that groups are nested in, tracing
+// them up level by level. The parents of each level are searched in batches.
+func (p *ldapProvider) gatherParentGroups(groupPrincipals []v3.Principal, searchDomain string, config *v3.LdapConfig, lConn *ldapv2.Conn, refresh bool) ([]v3.Principal, error) {
+	seen := make(map[string]bool)
+	for _, groupPrincipal := range groupPrincipals {
+		seen[groupPrincipal.Name] = true
+	}
+
+	var nestedGroupPrincipals []v3.Principal
+	level := groupPrincipals
+	for len(level) > 0 {
+		parents, err := p.parentGroups(level, searchDomain, config, lConn, refresh)
+		if err != nil {
+			return nil, err
+		}
+		var next []v3.Principal
+		for _, parent := range parents {
+			if !seen[parent.Name] {
+				seen[parent.Name] = true
+				nestedGroupPrincipals = append(nestedGroupPrincipals, parent)
+				next = append(next, parent)
+			}
+		}
+		level = next
+	}
+	return nestedGroupPrincipals, nil
+}
+
+// parentGroups returns the groups that any of the groups are direct members
+// of. The parents of each group are cached, as users mostly share groups, and
+// refresh searches them again.
+func (p *ldapProvider) parentGroups(groupPrincipals []v3.Principal, searchDomain string, config *v3.LdapConfig, lConn *ldapv2.Conn, refresh bool) ([]v3.Principal, error) {
+	connConfig := ldap.LdapConnConfig(config)
+	var parents []v3.Principal
+	var uncached []string
+	for _, groupPrincipal := range groupPrincipals {
+		parts := strings.SplitN(groupPrincipal.Name, "://", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid id %v", groupPrincipal.Name)
+		}
+		if refresh {
+			uncached = append(uncached, parts[1])
+		} else if cached, ok := p.cache.GetPrincipals(ldap.ParentGroupCache, connConfig, parts[1]); ok {
+			parents = append(parents, cached...)
+		} else {
+			uncached = append(uncached, parts[1])
+		}
+	}
+
+	for i := 0; i < len(uncached); i += 50 {
+		batch := uncached[i:ldap.Min(i+50, len(uncached))]
+		batchParents, err := p.searchParentGroups(batch, searchDomain, config, lConn)
+		if err != nil {
+			return nil, err
+		}
+		for _, groupDN := range batch {
+			p.cache.AddPrincipals(ldap.ParentGroupCache, connConfig, groupDN, batchParents[groupDN])
+			parents = append(parents, batchParents[groupDN]...)
+		}
+	}
+	return parents, nil
+}
+
+// searchParentGroups searches the parents of a batch of groups with one query,
+// and tells them apart by their member attribute. When a parent has none of the
+// groups in its member attribute, such as when a directory lists only part of
+// the members of large groups, the groups are searched one by one instead.
+func (p *ldapProvider) searchParentGroups(groupDNs []string, searchDomain string, config *v3.LdapConfig, lConn *ldapv2.Conn) (map[string][]v3.Principal, error) {
+	query := "(|"
+	batch := make(map[string]string)
+	for _, groupDN := range groupDNs {
+		query += fmt.Sprintf("(%v=%v)", config.GroupMemberMappingAttribute, ldapv2.EscapeFilter(groupDN))
+		batch[normalizeDN(groupDN)] = groupDN
+	}
+	query += ")"
+	query = fmt.Sprintf("(&(%v=%v)%v)", ObjectClass, config.GroupObjectClass, query)
+	logrus.Debugf("Ldap: Query for pulling parent groups: %v", query)
+
+	searchAttributes := []string{config.GroupMemberUserAttribute, config.GroupMemberMappingAttribute, ObjectClass, config.GroupObjectClass, config.UserLoginAttribute,
+		config.GroupNameAttribute, config.GroupSearchAttribute}
+	search := ldapv2.NewSearchRequest(searchDomain,
+		ldapv2.ScopeWholeSubtree, ldapv2.NeverDerefAliases, 0, 0, false,
+		query, searchAttributes, nil)
+	results, err := lConn.SearchWithPaging(search, 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string][]v3.Principal)
+	for _, entry := range results.Entries {
+		principal, err := ldap.AttributesToPrincipal(entry.Attributes, entry.DN, p.groupScope, OpenLdapName, config.UserObjectClass, config.UserNameAttribute, config.UserLoginAttribute, config.GroupObjectClass, config.GroupNameAttribute)
+		if err != nil {
+			logrus.Errorf("Error translating group result: %v", err)
+			continue
+		}
+		if len(groupDNs) == 1 {
+			parents[groupDNs[0]] = append(parents[groupDNs[0]], *principal)
+			continue
+		}
+
+		matched := false
+		for _, member := range entry.GetAttributeValues(config.GroupMemberMappingAttribute) {
+			if groupDN, ok := batch[normalizeDN(member)]; ok {
+				parents[groupDN] = append(parents[groupDN], *principal)
+				matched = true
+			}
+		}
+		if !matched {
+			logrus.Debugf("Ldap: can not match the members of %v to the groups, searching their parents one by one", entry.DN)
+			parents = make(map[string][]v3.Principal)
+			for _, groupDN := range groupDNs {
+				groupParents, err := p.searchParentGroups([]string{groupDN}, searchDomain, config, lConn)
+				if err != nil {
+					return nil, err
+				}
+				parents[groupDN] = groupParents[groupDN]
+			}
+			return parents, nil
+		}
+	}
+	return parents, nil
+}
+
+// normalizeDN lowercases the attribute types and values of a DN and drops the
+// spaces between them, so that DNs written differently compare equal.
+func normalizeDN(dn string) string {
+	parsed, err := ldapv2.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	var rdns []string
+	for _, rdn := range parsed.RDNs {
+		var attributes []string
+		for _, attribute := range rdn.Attributes {
+			attributes = append(attributes, strings.ToLower(attribute.Type)+"="+strings.ToLower(attribute.Value))
+		}
+		rdns = append(rdns, strings.Join(attributes, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
@@ -5,6 +5,7 @@
 	"crypto/x509"
 	"fmt"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
@@ -17,6 +18,7 @@
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/user"
 	"github.com/sirupsen/logrus"
@@ -59,6 +61,8 @@ type ldapProvider struct {
 	testAndApplyInputType string
 	userScope             string
 	groupScope            string
+	pool                  *ldap.ConnPool
+	cache                 *ldap.Cache
 }
 
 func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.Manager, tokenMGR *tokens.Manager, providerName string) common.AuthProvider {
@@ -72,6 +76,8 @@ func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.
 		testAndApplyInputType: testAndApplyInputTypes[providerName],
 		userScope:             providerName + "_user",
 		groupScope:            providerName + "_group",
+		pool:                  ldap.NewConnPool(providerName),
+		cache:                 ldap.NewCache(providerName),
 	}
 }
 
@@ -135,12 +141,12 @@ func (p *ldapProvider) SearchPrincipals(searchKey, principalType string, myToken
 		return principals, nil
 	}
 
-	lConn, err := ldap.Connect(config, caPool)
+	lConn, err := p.pool.Get(ldap.LdapConnConfig(config), caPool)
 	if err != nil {
 		logrus.Warnf("ldap search principals failed to connect to ldap: %s\n", err)
 		return principals, nil
 	}
-	defer lConn.Close()
+	defer p.pool.Put(lConn)
 
 	principals, err = p.searchPrincipals(searchKey, principalType, config, lConn)
 	if err == nil {
@@ -304,17 +310,18 @@ func (p *ldapProvider) samlSearchGetPrincipal(
 		return nil, fmt.Errorf("Invalid scope")
 	}
 
-	lConn, err := ldap.Connect(config, caPool)
-	if err != nil {
-		return nil, err
+	connConfig := ldap.LdapConnConfig(config)
+	cacheKey := scope + "://" + externalID
+	if principal, ok := p.cache.GetPrincipal(connConfig, cacheKey); ok {
+		return principal, nil
 	}
-	defer lConn.Close()
+	defer metrics.ObserveLDAPOperation(p.providerName, "getPrincipal", time.Now())
 
-	err = ldap.AuthenticateServiceAccountUser(
-		config.ServiceAccountPassword, config.ServiceAccountDistinguishedName, "", lConn)
+	lConn, err := p.pool.Get(connConfig, caPool)
 	if err != nil {
 		return nil, err
 	}
+	defer p.pool.Put(lConn)
 
 	var searchRequest *ldapv2.SearchRequest
 	var filter string
@@ -358,7 +365,7 @@ func (p *ldapProvider) samlSearchGetPrincipal(
 		}
 	}
 
-	return ldap.AttributesToPrincipal(
+	principal, err := ldap.AttributesToPrincipal(
 		entryAttributes,
 		externalID,
 		scope,
@@ -368,4 +375,9 @@ func (p *ldapProvider) samlSearchGetPrincipal(
 		config.UserLoginAttribute,
 		config.GroupObjectClass,
 		config.GroupNameAttribute)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.AddPrincipal(connConfig, cacheKey, principal)
+	return principal, nil
 }
@@ -4,12 +4,15 @@
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/auth/mfa"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	"github.com/rancher/rancher/pkg/auth/providers/common"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
@@ -32,11 +35,13 @@
 
 type Provider struct {
 	userLister   v3.UserLister
+	userClient   v3.UserInterface
 	groupLister  v3.GroupLister
 	userIndexer  cache.Indexer
 	gmIndexer    cache.Indexer
 	groupIndexer cache.Indexer
 	tokenMGR     *tokens.Manager
+	mfaMGR       *mfa.Manager
 	invalidHash  []byte
 }
 
@@ -61,7 +66,9 @@ func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, tokenMGR *tok
 		groupLister:  mgmtCtx.Management.Groups("").Controller().Lister(),
 		groupIndexer: gInformer.GetIndexer(),
 		userLister:   mgmtCtx.Management.Users("").Controller().Lister(),
+		userClient:   mgmtCtx.Management.Users(""),
 		tokenMGR:     tokenMGR,
+		mfaMGR:       mfa.NewManager(mgmtCtx),
 		invalidHash:  invalidHash,
 	}
 	return l
@@ -101,6 +108,9 @@ func (l *Provider) getUser(username string) (*v3.User, error) {
 }
 
 func (l *Provider) AuthenticateUser(ctx context.Context, input interface{}) (v3.Principal, []v3.Principal, string, error) {
+	if mfaInput, ok := input.(*v32.MFALogin); ok {
+		return l.authenticateMFA(mfaInput)
+	}
 	localInput, ok := input.(*v32.BasicLogin)
 	if !ok {
 		return v3.Principal{}, nil, "", httperror.NewAPIError(httperror.ServerError, "Unexpected input type")
@@ -122,16 +132,59 @@ func (l *Provider) AuthenticateUser(ctx context.Context, input interface{}) (v3.
 		return v3.Principal{}, nil, "", httperror.WrapAPIError(err, httperror.Unauthorized, "authentication failed")
 	}
 
+	if !user.MustChangePassword && passwordpolicy.Expired(user, time.Now()) {
+		// the user changes their password after logging in, as on their first login
+		user = user.DeepCopy()
+		user.MustChangePassword = true
+		if user, err = l.userClient.Update(user); err != nil {
+			return v3.Principal{}, nil, "", err
+		}
+	}
+
+	userPrincipal, groupPrincipals, err := l.getPrincipals(user)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+
+	required, err := l.mfaMGR.Required(user, groupPrincipals)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	if required {
+		challenge, err := l.mfaMGR.NewChallenge(user)
+		if err != nil {
+			return v3.Principal{}, nil, "", err
+		}
+		return v3.Principal{}, nil, "", &mfa.ChallengeRequired{Challenge: challenge}
+	}
+
+	return userPrincipal, groupPrincipals, "", nil
+}
+
+// authenticateMFA finishes the login of users who passed their password and
+// were challenged for a second factor.
+func (l *Provider) authenticateMFA(input *v32.MFALogin) (v3.Principal, []v3.Principal, string, error) {
+	user, err := l.mfaMGR.VerifyChallenge(input.ChallengeID, input.Code)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	userPrincipal, groupPrincipals, err := l.getPrincipals(user)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	return userPrincipal, groupPrincipals, "", nil
+}
+
+func (l *Provider) getPrincipals(user *v3.User) (v3.Principal, []v3.Principal, error) {
 	principalID := getLocalPrincipalID(user)
 	userPrincipal := l.toPrincipal("user", user.DisplayName, user.Username, principalID, nil)
 	userPrincipal.Me = true
 
 	groupPrincipals, err := l.getGroupPrincipals(user)
 	if err != nil {
-		return v3.Principal{}, nil, "", errors.Wrapf(err, "failed to get groups for %v", user.Name)
+		return v3.Principal{}, nil, errors.Wrapf(err, "failed to get groups for %v", user.Name)
 	}
-
-	return userPrincipal, groupPrincipals, "", nil
+	return userPrincipal, groupPrincipals, nil
 }
 
 func getLocalPrincipalID(user *v3.User) string {
@@ -0,0 +1,448 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"golang.org/x/oauth2"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// issuerTTL is how long discovery documents and signing keys are cached
+	issuerTTL = time.Hour
+	// maxResponseSize caps the responses read from the issuer
+	maxResponseSize = 1 << 20
+)
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// discovery is the part of the OpenID provider metadata of an issuer used here.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// issuer holds the discovery document and signing keys of an issuer.
+type issuer struct {
+	discovery
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+// issuerCache caches issuers by their URL, so logins do not fetch the
+// discovery document and signing keys every time.
+type issuerCache struct {
+	sync.Mutex
+	issuers map[string]*issuer
+}
+
+func newIssuerCache() *issuerCache {
+	return &issuerCache{
+		issuers: map[string]*issuer{},
+	}
+}
+
+// get returns the issuer at issuerURL, fetching it if it is not cached or
+// its keys are older than issuerTTL.
+func (c *issuerCache) get(ctx context.Context, httpClient *http.Client, issuerURL string) (*issuer, error) {
+	c.Lock()
+	defer c.Unlock()
+	if cached, ok := c.issuers[issuerURL]; ok && time.Since(cached.fetched) < issuerTTL {
+		return cached, nil
+	}
+	fetched, err := fetchIssuer(ctx, httpClient, issuerURL)
+	if err != nil {
+		return nil, err
+	}
+	c.issuers[issuerURL] = fetched
+	return fetched, nil
+}
+
+// refreshKeys fetches the signing keys of cached again, when a token is
+// signed with a key they do not hold, as issuers rotate their keys.
+func (c *issuerCache) refreshKeys(ctx context.Context, httpClient *http.Client, cached *issuer) (*issuer, error) {
+	keys, err := fetchKeys(ctx, httpClient, cached.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	refreshed := &issuer{
+		discovery: cached.discovery,
+		keys:      keys,
+		fetched:   time.Now(),
+	}
+	c.Lock()
+	c.issuers[strings.TrimSuffix(cached.Issuer, "/")] = refreshed
+	c.Unlock()
+	return refreshed, nil
+}
+
+func fetchIssuer(ctx context.Context, httpClient *http.Client, issuerURL string) (*issuer, error) {
+	doc := discovery{}
+	if err := getJSON(ctx, httpClient, issuerURL+discoveryPath, "", &doc); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC issuer %s: %v", issuerURL, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != issuerURL {
+		return nil, fmt.Errorf("OIDC issuer %s does not match the configured issuer %s", doc.Issuer, issuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC issuer %s does not publish its authorization, token and keys endpoints", issuerURL)
+	}
+	keys, err := fetchKeys(ctx, httpClient, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	return &issuer{
+		discovery: doc,
+		keys:      keys,
+		fetched:   time.Now(),
+	}, nil
+}
+
+func fetchKeys(ctx context.Context, httpClient *http.Client, jwksURI string) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, httpClient, jwksURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("failed to get OIDC signing keys: %v", err)
+	}
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			// keys of unsupported types do not keep the others from being used
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("OIDC issuer publishes no supported signing keys")
+	}
+	return keys, nil
+}
+
+func parseJSONWebKey(jwk jsonWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, url, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d", url, resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// oidcClient talks to the issuer of an OIDCConfig.
+type oidcClient struct {
+	config     *v32.OIDCConfig
+	httpClient *http.Client
+	cache      *issuerCache
+	issuer     *issuer
+}
+
+func newOIDCClient(ctx context.Context, config *v32.OIDCConfig, cache *issuerCache) (*oidcClient, error) {
+	httpClient, err := newHTTPClient(config.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := cache.get(ctx, httpClient, strings.TrimSuffix(config.Issuer, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &oidcClient{
+		config:     config,
+		httpClient: httpClient,
+		cache:      cache,
+		issuer:     issuer,
+	}, nil
+}
+
+func newHTTPClient(certificate string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if certificate != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(certificate)) {
+			return nil, fmt.Errorf("invalid OIDC issuer certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}, nil
+}
+
+func (c *oidcClient) oauth2Config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.config.ClientID,
+		ClientSecret: c.config.ClientSecret,
+		RedirectURL:  c.config.RancherURL,
+		Scopes:       strings.Fields(c.config.Scope),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  c.issuer.AuthorizationEndpoint,
+			TokenURL: c.issuer.TokenEndpoint,
+		},
+	}
+}
+
+func (c *oidcClient) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+}
+
+// authCodeURL returns the URL users are redirected to for logging in. The UI
+// adds the state and the code_challenge of PKCE.
+func (c *oidcClient) authCodeURL() string {
+	return c.oauth2Config().AuthCodeURL("", oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+}
+
+// exchange swaps the authorization code for tokens, proving the code was
+// requested by the holder of codeVerifier.
+func (c *oidcClient) exchange(ctx context.Context, code, codeVerifier string) (*oauth2.Token, error) {
+	return c.oauth2Config().Exchange(c.context(ctx), code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+}
+
+// refresh returns new tokens for the refresh token of token.
+func (c *oidcClient) refresh(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("no OIDC refresh token, the offline_access scope may be missing")
+	}
+	expired := &oauth2.Token{RefreshToken: token.RefreshToken}
+	return c.oauth2Config().TokenSource(c.context(ctx), expired).Token()
+}
+
+// getClaims returns the claims of the ID token of token, with the claims of
+// the user info endpoint added if the ID token does not hold those mapped
+// to the username, display name or groups.
+func (c *oidcClient) getClaims(ctx context.Context, token *oauth2.Token) (map[string]interface{}, error) {
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("OIDC token response has no ID token")
+	}
+	claims, err := c.verifyIDToken(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.issuer.UserInfoEndpoint == "" || !c.missingClaims(claims) {
+		return claims, nil
+	}
+	userInfo := map[string]interface{}{}
+	if err := getJSON(ctx, c.httpClient, c.issuer.UserInfoEndpoint, token.AccessToken, &userInfo); err != nil {
+		return nil, fmt.Errorf("failed to get OIDC user info: %v", err)
+	}
+	if userInfo["sub"] != claims["sub"] {
+		return nil, fmt.Errorf("OIDC user info is for a different subject than the ID token")
+	}
+	for key, value := range userInfo {
+		if _, ok := claims[key]; !ok {
+			claims[key] = value
+		}
+	}
+	return claims, nil
+}
+
+func (c *oidcClient) missingClaims(claims map[string]interface{}) bool {
+	for _, name := range []string{c.config.UsernameClaim, c.config.DisplayNameClaim, c.config.GroupsClaim} {
+		if name != "" && getClaim(claims, name) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyIDToken checks the signature, issuer, audience and expiry of an ID
+// token and returns its claims.
+func (c *oidcClient) verifyIDToken(ctx context.Context, rawIDToken string) (map[string]interface{}, error) {
+	parser := &jwt.Parser{ValidMethods: signingMethods}
+	token, err := parser.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := c.getKey(kid); ok {
+			return key, nil
+		}
+		refreshed, err := c.cache.refreshKeys(ctx, c.httpClient, c.issuer)
+		if err != nil {
+			return nil, err
+		}
+		c.issuer = refreshed
+		if key, ok := c.getKey(kid); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC ID token: %v", err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+
+	if iss, _ := claims["iss"].(string); iss != c.issuer.Issuer {
+		return nil, fmt.Errorf("invalid OIDC ID token: issuer %q does not match %q", iss, c.issuer.Issuer)
+	}
+	audience := claimStrings(claims["aud"])
+	if !contains(audience, c.config.ClientID) {
+		return nil, fmt.Errorf("invalid OIDC ID token: audience does not include client %s", c.config.ClientID)
+	}
+	if azp, ok := claims["azp"].(string); ok && len(audience) > 1 && azp != c.config.ClientID {
+		return nil, fmt.Errorf("invalid OIDC ID token: authorized party %s is not client %s", azp, c.config.ClientID)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("invalid OIDC ID token: no expiry")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("invalid OIDC ID token: no subject")
+	}
+	return claims, nil
+}
+
+// getKey returns the key with ID kid, or the only key of the issuer if the
+// token does not name its key.
+func (c *oidcClient) getKey(kid string) (interface{}, bool) {
+	if kid == "" && len(c.issuer.keys) == 1 {
+		for _, key := range c.issuer.keys {
+			return key, true
+		}
+	}
+	key, ok := c.issuer.keys[kid]
+	return key, ok
+}
+
+// getClaim returns the claim called name, or the claim at name as a dot
+// separated path, such as realm_access.roles, if there is none.
+func getClaim(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	var value interface{} = claims
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := getClaim(claims, name).(string)
+	return value
+}
+
+// claimStrings returns a claim that is a string or an array of strings.
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,400 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientID     = "rancher"
+	testClientSecret = "secret"
+	testKeyID        = "key-1"
+	testVerifier     = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+// mockIssuer is an OpenID provider issuing ID tokens for a single user.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// codes maps authorization codes to their PKCE code challenge
+	codes map[string]string
+	// claims are added to the ID tokens issued
+	claims jwt.MapClaims
+	// userInfo is served by the user info endpoint
+	userInfo map[string]interface{}
+	// audience, issuer and expiry of the ID tokens issued
+	audience interface{}
+	issuer   string
+	expiry   time.Duration
+	// refreshToken is the only refresh token accepted
+	refreshToken string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{
+		key:          key,
+		codes:        map[string]string{},
+		claims:       jwt.MapClaims{},
+		userInfo:     map[string]interface{}{"sub": "user-1"},
+		audience:     testClientID,
+		expiry:       time.Hour,
+		refreshToken: "refresh-1",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"userinfo_endpoint":      m.server.URL + "/userinfo",
+			"jwks_uri":               m.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.serveToken)
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, m.userInfo)
+	})
+	m.server = httptest.NewServer(mux)
+	m.issuer = m.server.URL
+	return m
+}
+
+func (m *mockIssuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if clientID, secret, _ := r.BasicAuth(); clientID != testClientID || secret != testClientSecret {
+		writeError(w, "invalid_client")
+		return
+	}
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		challenge, ok := m.codes[r.PostFormValue("code")]
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			writeError(w, "invalid_grant")
+			return
+		}
+		delete(m.codes, r.PostFormValue("code"))
+	case "refresh_token":
+		if r.PostFormValue("refresh_token") != m.refreshToken {
+			writeError(w, "invalid_grant")
+			return
+		}
+		m.refreshToken = "refresh-2"
+	default:
+		writeError(w, "unsupported_grant_type")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": m.issuer,
+		"sub": "user-1",
+		"aud": m.audience,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(m.expiry).Unix(),
+	}
+	for key, value := range m.claims {
+		claims[key] = value
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = testKeyID
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token":  "access",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"refresh_token": m.refreshToken,
+		"id_token":      signed,
+	})
+}
+
+// authorize returns a code as the authorization endpoint would after the user
+// logged in.
+func (m *mockIssuer) authorize(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	m.codes["code"] = base64.RawURLEncoding.EncodeToString(sum[:])
+	return "code"
+}
+
+func (m *mockIssuer) config() *v32.OIDCConfig {
+	return &v32.OIDCConfig{
+		Issuer:           m.server.URL,
+		ClientID:         testClientID,
+		ClientSecret:     testClientSecret,
+		RancherURL:       "https://rancher.example.com/verify-auth",
+		Scope:            "openid profile groups offline_access",
+		UsernameClaim:    "preferred_username",
+		DisplayNameClaim: "name",
+		GroupsClaim:      "groups",
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func TestLogin(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+	issuer.claims = jwt.MapClaims{
+		"preferred_username": "jdoe",
+		"name":               "Jane Doe",
+		"groups":             []string{"admins", "devs"},
+	}
+
+	config := issuer.config()
+	client, err := newOIDCClient(ctx, config, newIssuerCache())
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Contains(client.authCodeURL(), issuer.server.URL+"/authorize?")
+	assert.Contains(client.authCodeURL(), "code_challenge_method=S256")
+
+	_, err = client.exchange(ctx, issuer.authorize(testVerifier), "wrong")
+	assert.Error(err, "the code verifier must match the challenge")
+
+	token, err := client.exchange(ctx, issuer.authorize(testVerifier), testVerifier)
+	if !assert.Nil(err) {
+		return
+	}
+	claims, err := client.getClaims(ctx, token)
+	if !assert.Nil(err) {
+		return
+	}
+
+	user := toUserPrincipal(config, claims)
+	assert.Equal("oidc_user://user-1", user.Name)
+	assert.Equal("jdoe", user.LoginName)
+	assert.Equal("Jane Doe", user.DisplayName)
+	var groups []string
+	for _, group := range toGroupPrincipals(config, claims) {
+		groups = append(groups, group.Name)
+	}
+	assert.Equal([]string{"oidc_group://admins", "oidc_group://devs"}, groups)
+}
+
+func TestClaimMappings(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+	issuer.claims = jwt.MapClaims{
+		"email":        "jdoe@example.com",
+		"realm_access": map[string]interface{}{"roles": []string{"ops"}},
+	}
+	issuer.userInfo["name"] = "Jane Doe"
+
+	config := issuer.config()
+	config.UsernameClaim = "upn"
+	config.GroupsClaim = "realm_access.roles"
+	client, err := newOIDCClient(ctx, config, newIssuerCache())
+	if !assert.Nil(err) {
+		return
+	}
+	token, err := client.exchange(ctx, issuer.authorize(testVerifier), testVerifier)
+	if !assert.Nil(err) {
+		return
+	}
+	claims, err := client.getClaims(ctx, token)
+	if !assert.Nil(err) {
+		return
+	}
+
+	user := toUserPrincipal(config, claims)
+	assert.Equal("jdoe@example.com", user.LoginName, "falls back to the email")
+	assert.Equal("Jane Doe", user.DisplayName, "taken from the user info")
+	groups := toGroupPrincipals(config, claims)
+	if assert.Len(groups, 1) {
+		assert.Equal("oidc_group://ops", groups[0].Name)
+	}
+
+	issuer.userInfo["sub"] = "user-2"
+	token, err = client.exchange(ctx, issuer.authorize(testVerifier), testVerifier)
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = client.getClaims(ctx, token)
+	assert.Error(err, "user info of another subject must not be merged")
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		modify func(*mockIssuer)
+		valid  bool
+	}{
+		{name: "valid", modify: func(m *mockIssuer) {}, valid: true},
+		{name: "audience list", modify: func(m *mockIssuer) {
+			m.audience = []string{"other", testClientID}
+			m.claims["azp"] = testClientID
+		}, valid: true},
+		{name: "other audience", modify: func(m *mockIssuer) { m.audience = "other" }},
+		{name: "other authorized party", modify: func(m *mockIssuer) {
+			m.audience = []string{"other", testClientID}
+			m.claims["azp"] = "other"
+		}},
+		{name: "other issuer", modify: func(m *mockIssuer) { m.issuer = "https://issuer.example.com" }},
+		{name: "expired", modify: func(m *mockIssuer) { m.expiry = -time.Minute }},
+		{name: "no subject", modify: func(m *mockIssuer) { m.claims["sub"] = "" }},
+	}
+	for _, test := range tests {
+		issuer := newMockIssuer(t)
+		test.modify(issuer)
+		client, err := newOIDCClient(ctx, issuer.config(), newIssuerCache())
+		if !assert.Nil(err, test.name) {
+			issuer.server.Close()
+			continue
+		}
+		token, err := client.exchange(ctx, issuer.authorize(testVerifier), testVerifier)
+		if assert.Nil(err, test.name) {
+			_, err = client.getClaims(ctx, token)
+			if test.valid {
+				assert.Nil(err, test.name)
+			} else {
+				assert.Error(err, test.name)
+			}
+		}
+		issuer.server.Close()
+	}
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	client, err := newOIDCClient(ctx, issuer.config(), newIssuerCache())
+	if !assert.Nil(err) {
+		return
+	}
+	claims := jwt.MapClaims{
+		"iss": issuer.server.URL,
+		"sub": "user-1",
+		"aud": testClientID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(err) {
+		return
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	forged.Header["kid"] = testKeyID
+	signed, _ := forged.SignedString(other)
+	_, err = client.verifyIDToken(ctx, signed)
+	assert.Error(err, "signed by another key")
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	unknown.Header["kid"] = "key-2"
+	signed, _ = unknown.SignedString(issuer.key)
+	_, err = client.verifyIDToken(ctx, signed)
+	assert.Error(err, "signed by an unknown key")
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, _ = unsigned.SignedString([]byte(testClientSecret))
+	_, err = client.verifyIDToken(ctx, signed)
+	assert.Error(err, "symmetric signatures are not accepted")
+}
+
+func TestRefresh(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+	issuer.claims = jwt.MapClaims{"groups": []string{"admins"}}
+
+	config := issuer.config()
+	client, err := newOIDCClient(ctx, config, newIssuerCache())
+	if !assert.Nil(err) {
+		return
+	}
+	token, err := client.exchange(ctx, issuer.authorize(testVerifier), testVerifier)
+	if !assert.Nil(err) {
+		return
+	}
+
+	issuer.claims = jwt.MapClaims{"groups": []string{"devs"}}
+	refreshed, err := client.refresh(ctx, token)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("refresh-2", refreshed.RefreshToken)
+	claims, err := client.getClaims(ctx, refreshed)
+	if !assert.Nil(err) {
+		return
+	}
+	groups := toGroupPrincipals(config, claims)
+	if assert.Len(groups, 1) {
+		assert.Equal("oidc_group://devs", groups[0].Name)
+	}
+
+	_, err = client.refresh(ctx, token)
+	assert.Error(err, "used refresh tokens are revoked")
+}
+
+func TestParsePrincipalID(t *testing.T) {
+	assert := assert.New(t)
+
+	id, principalType, err := parsePrincipalID("oidc_user://user-1")
+	assert.Nil(err)
+	assert.Equal("user-1", id)
+	assert.Equal("user", principalType)
+
+	id, principalType, err = parsePrincipalID("oidc_group://team/a")
+	assert.Nil(err)
+	assert.Equal("team/a", id)
+	assert.Equal("group", principalType)
+
+	_, _, err = parsePrincipalID("github_user://1")
+	assert.Error(err)
+	_, _, err = parsePrincipalID("oidc_org://1")
+	assert.Error(err)
+}
@@ -0,0 +1,369 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	publicclient "github.com/rancher/rancher/pkg/client/generated/management/v3public"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/user"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	Name      = "oidc"
+	userType  = "user"
+	groupType = "group"
+)
+
+type oidcProvider struct {
+	ctx         context.Context
+	authConfigs v3.AuthConfigInterface
+	secrets     corev1.SecretInterface
+	userMGR     user.Manager
+	tokenMGR    *tokens.Manager
+	issuers     *issuerCache
+}
+
+func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.Manager, tokenMGR *tokens.Manager) common.AuthProvider {
+	return &oidcProvider{
+		ctx:         ctx,
+		authConfigs: mgmtCtx.Management.AuthConfigs(""),
+		secrets:     mgmtCtx.Core.Secrets(""),
+		userMGR:     userMGR,
+		tokenMGR:    tokenMGR,
+		issuers:     newIssuerCache(),
+	}
+}
+
+func (o *oidcProvider) GetName() string {
+	return Name
+}
+
+func (o *oidcProvider) AuthenticateUser(ctx context.Context, input interface{}) (v3.Principal, []v3.Principal, string, error) {
+	login, ok := input.(*v32.OIDCLogin)
+	if !ok {
+		return v3.Principal{}, nil, "", errors.New("unexpected input type")
+	}
+	return o.loginUser(ctx, login, nil, false)
+}
+
+// loginUser exchanges the code of login for tokens, maps the claims of the ID
+// token to the user and group principals and returns them with the tokens,
+// which hold the refresh token used to refetch the groups.
+func (o *oidcProvider) loginUser(ctx context.Context, login *v32.OIDCLogin, config *v32.OIDCConfig, test bool) (v3.Principal, []v3.Principal, string, error) {
+	var err error
+	if config == nil {
+		config, err = o.getOIDCConfig()
+		if err != nil {
+			return v3.Principal{}, nil, "", err
+		}
+	}
+
+	oidcClient, err := newOIDCClient(ctx, config, o.issuers)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	logrus.Debugf("[OIDC] loginUser: Exchanging code for tokens")
+	oauthToken, err := oidcClient.exchange(ctx, login.Code, login.CodeVerifier)
+	if err != nil {
+		return v3.Principal{}, nil, "", httperror.WrapAPIError(err, httperror.Unauthorized, "failed to exchange OIDC authorization code")
+	}
+	claims, err := oidcClient.getClaims(ctx, oauthToken)
+	if err != nil {
+		return v3.Principal{}, nil, "", httperror.WrapAPIError(err, httperror.Unauthorized, "failed to verify OIDC ID token")
+	}
+
+	userPrincipal := toUserPrincipal(config, claims)
+	userPrincipal.Me = true
+	groupPrincipals := toGroupPrincipals(config, claims)
+
+	testAllowedPrincipals := config.AllowedPrincipalIDs
+	if test && config.AccessMode == "restricted" {
+		testAllowedPrincipals = append(testAllowedPrincipals, userPrincipal.Name)
+	}
+	allowed, err := o.userMGR.CheckAccess(config.AccessMode, testAllowedPrincipals, userPrincipal.Name, groupPrincipals)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	if !allowed {
+		return v3.Principal{}, nil, "", httperror.NewAPIError(httperror.Unauthorized, "unauthorized")
+	}
+
+	providerToken, err := json.Marshal(oauthToken)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	return userPrincipal, groupPrincipals, string(providerToken), nil
+}
+
+// SearchPrincipals returns a principal named by searchKey, as OIDC has no
+// directory to search: users are found by subject and groups by name.
+func (o *oidcProvider) SearchPrincipals(searchKey, principalType string, token v3.Token) ([]v3.Principal, error) {
+	if principalType == "" {
+		principalType = userType
+	}
+	p := v3.Principal{
+		ObjectMeta:    metav1.ObjectMeta{Name: Name + "_" + principalType + "://" + searchKey},
+		DisplayName:   searchKey,
+		LoginName:     searchKey,
+		PrincipalType: principalType,
+		Provider:      Name,
+	}
+	o.setMe(&p, &token)
+	return []v3.Principal{p}, nil
+}
+
+func (o *oidcProvider) GetPrincipal(principalID string, token v3.Token) (v3.Principal, error) {
+	if principalID == token.UserPrincipal.Name {
+		p := token.UserPrincipal
+		p.Me = true
+		return p, nil
+	}
+	externalID, principalType, err := parsePrincipalID(principalID)
+	if err != nil {
+		return v3.Principal{}, err
+	}
+	p := v3.Principal{
+		ObjectMeta:    metav1.ObjectMeta{Name: principalID},
+		DisplayName:   externalID,
+		LoginName:     externalID,
+		PrincipalType: principalType,
+		Provider:      Name,
+	}
+	o.setMe(&p, &token)
+	return p, nil
+}
+
+func (o *oidcProvider) setMe(p *v3.Principal, token *v3.Token) {
+	if p.PrincipalType == userType {
+		p.Me = p.Name == token.UserPrincipal.Name
+	} else {
+		p.MemberOf = o.tokenMGR.IsMemberOf(*token, *p)
+	}
+}
+
+func (o *oidcProvider) CustomizeSchema(schema *types.Schema) {
+	schema.ActionHandler = o.actionHandler
+	schema.Formatter = o.formatter
+}
+
+func (o *oidcProvider) TransformToAuthProvider(authConfig map[string]interface{}) (map[string]interface{}, error) {
+	p := common.TransformToAuthProvider(authConfig)
+	config := &v32.OIDCConfig{}
+	if err := mapstructure.Decode(authConfig, config); err != nil {
+		return nil, err
+	}
+	redirectURL, err := o.getRedirectURL(config)
+	if err != nil {
+		// an unreachable issuer must not break listing the other providers
+		logrus.Warnf("[OIDC] failed to get the redirect URL: %v", err)
+	}
+	p[publicclient.OIDCProviderFieldRedirectURL] = redirectURL
+	return p, nil
+}
+
+func (o *oidcProvider) getRedirectURL(config *v32.OIDCConfig) (string, error) {
+	oidcClient, err := newOIDCClient(o.ctx, config, o.issuers)
+	if err != nil {
+		return "", err
+	}
+	return oidcClient.authCodeURL(), nil
+}
+
+// RefetchGroupPrincipals refreshes the tokens stored in secret and returns
+// the groups claimed by the new ID token. The refreshed tokens are stored, as
+// issuers may only accept a refresh token once.
+func (o *oidcProvider) RefetchGroupPrincipals(principalID string, secret string) ([]v3.Principal, error) {
+	config, err := o.getOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+	stored := &oauth2.Token{}
+	if err := json.Unmarshal([]byte(secret), stored); err != nil {
+		return nil, fmt.Errorf("failed to read stored OIDC tokens: %v", err)
+	}
+	oidcClient, err := newOIDCClient(o.ctx, config, o.issuers)
+	if err != nil {
+		return nil, err
+	}
+	refreshed, err := oidcClient.refresh(o.ctx, stored)
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && strings.Contains(string(retrieveErr.Body), "invalid_grant") {
+			// the user was removed or their session revoked
+			return nil, errors.New("no access")
+		}
+		return nil, err
+	}
+	claims, err := oidcClient.getClaims(o.ctx, refreshed)
+	if err != nil {
+		return nil, err
+	}
+	if toUserPrincipal(config, claims).Name != principalID {
+		return nil, fmt.Errorf("stored OIDC tokens are not for %s", principalID)
+	}
+
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = stored.RefreshToken
+	}
+	if err := o.storeTokens(principalID, refreshed); err != nil {
+		logrus.Warnf("[OIDC] failed to store refreshed tokens of %s: %v", principalID, err)
+	}
+	return toGroupPrincipals(config, claims), nil
+}
+
+func (o *oidcProvider) storeTokens(principalID string, oauthToken *oauth2.Token) error {
+	u, err := o.userMGR.GetUserByPrincipalID(principalID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return fmt.Errorf("no user with principal %s", principalID)
+	}
+	providerToken, err := json.Marshal(oauthToken)
+	if err != nil {
+		return err
+	}
+	return o.tokenMGR.UpdateSecret(u.Name, Name, string(providerToken))
+}
+
+func (o *oidcProvider) CanAccessWithGroupProviders(userPrincipalID string, groupPrincipals []v3.Principal) (bool, error) {
+	config, err := o.getOIDCConfig()
+	if err != nil {
+		logrus.Errorf("Error fetching OIDC config: %v", err)
+		return false, err
+	}
+	allowed, err := o.userMGR.CheckAccess(config.AccessMode, config.AllowedPrincipalIDs, userPrincipalID, groupPrincipals)
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+func (o *oidcProvider) getOIDCConfig() (*v32.OIDCConfig, error) {
+	authConfigObj, err := o.authConfigs.ObjectClient().UnstructuredClient().Get(Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve OIDCConfig, error: %v", err)
+	}
+	u, ok := authConfigObj.(runtime.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("failed to retrieve OIDCConfig, cannot read k8s Unstructured data")
+	}
+	storedOIDCConfigMap := u.UnstructuredContent()
+
+	storedOIDCConfig := &v32.OIDCConfig{}
+	mapstructure.Decode(storedOIDCConfigMap, storedOIDCConfig)
+
+	metadataMap, ok := storedOIDCConfigMap["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to retrieve OIDCConfig metadata, cannot read k8s Unstructured data")
+	}
+
+	objectMeta := &metav1.ObjectMeta{}
+	mapstructure.Decode(metadataMap, objectMeta)
+	storedOIDCConfig.ObjectMeta = *objectMeta
+
+	if storedOIDCConfig.ClientSecret != "" {
+		value, err := common.ReadFromSecret(o.secrets, storedOIDCConfig.ClientSecret,
+			strings.ToLower(client.OIDCConfigFieldClientSecret))
+		if err != nil {
+			return nil, err
+		}
+		storedOIDCConfig.ClientSecret = value
+	}
+	return storedOIDCConfig, nil
+}
+
+func (o *oidcProvider) saveOIDCConfig(config *v32.OIDCConfig) error {
+	storedOIDCConfig, err := o.getOIDCConfig()
+	if err != nil {
+		return err
+	}
+	config.APIVersion = "management.cattle.io/v3"
+	config.Kind = v3.AuthConfigGroupVersionKind.Kind
+	config.Type = client.OIDCConfigType
+	config.ObjectMeta = storedOIDCConfig.ObjectMeta
+
+	if config.ClientSecret != "" {
+		field := strings.ToLower(client.OIDCConfigFieldClientSecret)
+		if err := common.CreateOrUpdateSecrets(o.secrets, config.ClientSecret, field, strings.ToLower(config.Type)); err != nil {
+			return err
+		}
+		config.ClientSecret = common.GetName(config.Type, field)
+	}
+
+	logrus.Debugf("updating OIDCConfig")
+	_, err = o.authConfigs.ObjectClient().Update(config.ObjectMeta.Name, config)
+	return err
+}
+
+// toUserPrincipal maps the claims of a user to their principal, identified by
+// the subject, which unlike the username never changes.
+func toUserPrincipal(config *v32.OIDCConfig, claims map[string]interface{}) v3.Principal {
+	sub := claimString(claims, "sub")
+	loginName := claimString(claims, config.UsernameClaim)
+	if loginName == "" {
+		loginName = claimString(claims, "email")
+	}
+	if loginName == "" {
+		loginName = sub
+	}
+	displayName := claimString(claims, config.DisplayNameClaim)
+	if displayName == "" {
+		displayName = loginName
+	}
+	return v3.Principal{
+		ObjectMeta:    metav1.ObjectMeta{Name: Name + "_" + userType + "://" + sub},
+		DisplayName:   displayName,
+		LoginName:     loginName,
+		PrincipalType: userType,
+		Provider:      Name,
+	}
+}
+
+func toGroupPrincipals(config *v32.OIDCConfig, claims map[string]interface{}) []v3.Principal {
+	if config.GroupsClaim == "" {
+		return nil
+	}
+	var principals []v3.Principal
+	for _, group := range claimStrings(getClaim(claims, config.GroupsClaim)) {
+		principals = append(principals, v3.Principal{
+			ObjectMeta:    metav1.ObjectMeta{Name: Name + "_" + groupType + "://" + group},
+			DisplayName:   group,
+			LoginName:     group,
+			PrincipalType: groupType,
+			Provider:      Name,
+			MemberOf:      true,
+		})
+	}
+	return principals
+}
+
+// parsePrincipalID splits an ID in the format oidc_<type>://<ID>.
+func parsePrincipalID(principalID string) (string, string, error) {
+	parts := strings.SplitN(principalID, "://", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], Name+"_") {
+		return "", "", httperror.NewAPIError(httperror.NotFound, fmt.Sprintf("invalid principal %s", principalID))
+	}
+	principalType := strings.TrimPrefix(parts[0], Name+"_")
+	if principalType != userType && principalType != groupType {
+		return "", "", httperror.NewAPIError(httperror.NotFound, fmt.Sprintf("invalid principal %s", principalID))
+	}
+	return parts[1], principalType, nil
+}
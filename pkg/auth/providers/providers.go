@@ -12,6 +12,7 @@
 	"github.com/rancher/rancher/pkg/auth/providers/googleoauth"
 	"github.com/rancher/rancher/pkg/auth/providers/ldap"
 	"github.com/rancher/rancher/pkg/auth/providers/local"
+	"github.com/rancher/rancher/pkg/auth/providers/oidc"
 	"github.com/rancher/rancher/pkg/auth/providers/saml"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
@@ -129,6 +130,13 @@ func Configure(ctx context.Context, mgmt *config.ScaledContext) {
 	providers[googleoauth.Name] = p
 	providersByType[client.GoogleOauthConfigType] = p
 	providersByType[publicclient.GoogleOAuthProviderType] = p
+
+	p = oidc.Configure(ctx, mgmt, userMGR, tokenMGR)
+	ProviderNames[oidc.Name] = true
+	ProvidersWithSecrets[oidc.Name] = true
+	providers[oidc.Name] = p
+	providersByType[client.OIDCConfigType] = p
+	providersByType[publicclient.OIDCProviderType] = p
 }
 
 func AuthenticateUser(ctx context.Context, input interface{}, providerName string) (v3.Principal, []v3.Principal, string, error) {
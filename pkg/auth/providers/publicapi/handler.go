@@ -45,6 +45,7 @@ func NewHandler(ctx context.Context, mgmtCtx *config.ScaledContext, opts ...Serv
 	v3public.OKTAProviderType,
 	v3public.ShibbolethProviderType,
 	v3public.GoogleOAuthProviderType,
+	v3public.OIDCProviderType,
 }
 
 func authProviderSchemas(ctx context.Context, management *config.ScaledContext, schemas *types.Schemas) error {
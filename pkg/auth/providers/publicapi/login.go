@@ -12,6 +12,9 @@
 
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/mfa"
 	"github.com/rancher/rancher/pkg/auth/providers"
 	"github.com/rancher/rancher/pkg/auth/providers/activedirectory"
 	"github.com/rancher/rancher/pkg/auth/providers/azure"
@@ -19,6 +22,7 @@
 	"github.com/rancher/rancher/pkg/auth/providers/googleoauth"
 	"github.com/rancher/rancher/pkg/auth/providers/ldap"
 	"github.com/rancher/rancher/pkg/auth/providers/local"
+	"github.com/rancher/rancher/pkg/auth/providers/oidc"
 	"github.com/rancher/rancher/pkg/auth/providers/saml"
 	"github.com/rancher/rancher/pkg/auth/settings"
 	"github.com/rancher/rancher/pkg/auth/tokens"
@@ -36,19 +40,25 @@
 )
 
 func newLoginHandler(ctx context.Context, mgmt *config.ScaledContext) *loginHandler {
+	lockoutMGR := lockout.NewManager(mgmt)
+	lockoutMGR.RunCleanup(ctx)
 	return &loginHandler{
-		userMGR:  mgmt.UserManager,
-		tokenMGR: tokens.NewManager(ctx, mgmt),
+		userMGR:    mgmt.UserManager,
+		tokenMGR:   tokens.NewManager(ctx, mgmt),
+		lockoutMGR: lockoutMGR,
+		userLister: mgmt.Management.Users("").Controller().Lister(),
 	}
 }
 
 type loginHandler struct {
-	userMGR  user.Manager
-	tokenMGR *tokens.Manager
+	userMGR    user.Manager
+	tokenMGR   *tokens.Manager
+	lockoutMGR *lockout.Manager
+	userLister v3.UserLister
 }
 
 func (h *loginHandler) login(actionName string, action *types.Action, request *types.APIContext) error {
-	if actionName != "login" {
+	if actionName != "login" && actionName != "mfaLogin" {
 		return httperror.NewAPIError(httperror.ActionNotAvailable, "")
 	}
 
@@ -73,7 +83,7 @@ func (h *loginHandler) login(actionName string, action *types.Action, request *t
 			HttpOnly: true,
 		}
 		http.SetCookie(w, tokenCookie)
-	} else if responseType == "saml" {
+	} else if responseType == "saml" || responseType == "mfa" {
 		return nil
 	} else {
 		tokenData, err := tokens.ConvertTokenResource(request.Schemas.Schema(&schema.PublicVersion, client.TokenType), token)
@@ -120,6 +130,9 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 	switch request.Type {
 	case client.LocalProviderType:
 		input = &v32.BasicLogin{}
+		if request.Action == "mfaLogin" {
+			input = &v32.MFALogin{}
+		}
 		providerName = local.Name
 	case client.GithubProviderType:
 		input = &v32.GithubLogin{}
@@ -154,6 +167,9 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 	case client.GoogleOAuthProviderType:
 		input = &v32.GoogleOauthLogin{}
 		providerName = googleoauth.Name
+	case client.OIDCProviderType:
+		input = &v32.OIDCLogin{}
+		providerName = oidc.Name
 	default:
 		return v3.Token{}, "", "", httperror.NewAPIError(httperror.ServerError, "unknown authentication provider")
 	}
@@ -174,11 +190,43 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 		return v3.Token{}, "", "saml", err
 	}
 
+	// usernames are only known to the logins with a password and to the
+	// second step of their logins, which count toward the same lockout
+	var username string
+	switch login := input.(type) {
+	case *v32.BasicLogin:
+		username = login.Username
+	case *v32.MFALogin:
+		username = h.challengeUsername(login.ChallengeID)
+	}
+	ip := util.GetClientIP(request.Request)
+	if wait := h.lockoutMGR.Check(request.Request.Context(), providerName, username, ip); wait > 0 {
+		request.Response.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+		return v3.Token{}, "", "", httperror.NewAPIErrorLong(http.StatusTooManyRequests, "TooManyRequests", "too many failed logins, try again later")
+	}
+
 	ctx := context.WithValue(request.Request.Context(), util.RequestKey, request.Request)
 	userPrincipal, groupPrincipals, providerToken, err = providers.AuthenticateUser(ctx, input, providerName)
+	if isLoginFailure(err) {
+		h.lockoutMGR.Failed(request.Request.Context(), providerName, username, ip)
+	}
+	if challenge, ok := err.(*mfa.ChallengeRequired); ok {
+		// the user logs in with the second factor in an mfaLogin
+		data, err := convert.EncodeToMap(challenge.Challenge)
+		if err != nil {
+			return v3.Token{}, "", "", err
+		}
+		data["type"] = client.MFAChallengeType
+		request.WriteResponse(http.StatusOK, data)
+		return v3.Token{}, "", "mfa", nil
+	}
 	if err != nil {
 		return v3.Token{}, "", "", err
 	}
+	if username == "" {
+		username = userPrincipal.LoginName
+	}
+	h.lockoutMGR.Succeeded(providerName, username)
 
 	displayName := userPrincipal.DisplayName
 	if displayName == "" {
@@ -201,6 +249,27 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 		return *token, token.Token, responseType, nil
 	}
 
-	rToken, unhashedTokenKey, err := h.tokenMGR.NewLoginToken(currUser.Name, userPrincipal, groupPrincipals, providerToken, ttl, description)
+	rToken, unhashedTokenKey, err := h.tokenMGR.NewLoginToken(currUser.Name, userPrincipal, groupPrincipals, providerToken, ttl, description, request.Request)
 	return rToken, unhashedTokenKey, responseType, err
 }
+
+// challengeUsername returns the username of the local user an MFA challenge
+// was issued to, or an empty string if it is not known.
+func (h *loginHandler) challengeUsername(challengeID string) string {
+	userName := mfa.ChallengeUser(challengeID)
+	if userName == "" {
+		return ""
+	}
+	u, err := h.userLister.Get("", userName)
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
+
+// isLoginFailure returns whether err rejects the credentials of a login, as
+// opposed to failing to check them.
+func isLoginFailure(err error) bool {
+	apiError, ok := err.(*httperror.APIError)
+	return ok && (apiError.Code.Status == http.StatusUnauthorized || apiError.Code.Status == http.StatusForbidden)
+}
@@ -0,0 +1,305 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// maxMessageSize limits the size of inflated messages of the HTTP-Redirect binding.
+const maxMessageSize = 1 << 20
+
+var postBindingForm = template.Must(template.New("saml-post-binding").Parse(`` +
+	`<!DOCTYPE html><html><body>` +
+	`<form method="post" action="{{.URL}}" id="SAMLForm">` +
+	`<input type="hidden" name="{{.Param}}" value="{{.Message}}" />` +
+	`{{if .RelayState}}<input type="hidden" name="RelayState" value="{{.RelayState}}" />{{end}}` +
+	`<noscript><input type="submit" value="Continue" /></noscript>` +
+	`</form>` +
+	`<script>document.getElementById('SAMLForm').submit();</script>` +
+	`</body></html>`))
+
+// sendMessage sends a signed SAML message to an endpoint of the identity
+// provider through the browser, with the HTTP-Redirect or HTTP-POST binding.
+func (s *Provider) sendMessage(w http.ResponseWriter, r *http.Request, binding, location, param string, el *etree.Element) error {
+	if binding == saml.HTTPRedirectBinding {
+		redirectURL, err := s.redirectBindingURL(location, param, el, "", true)
+		if err != nil {
+			return err
+		}
+		http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+		return nil
+	}
+
+	signed, err := s.signEnveloped(el)
+	if err != nil {
+		return err
+	}
+	doc := etree.NewDocument()
+	doc.SetRoot(signed)
+	message, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	err = postBindingForm.Execute(buf, map[string]string{
+		"URL":     location,
+		"Param":   param,
+		"Message": base64.StdEncoding.EncodeToString(message),
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// redirectBindingURL encodes a SAML message in the query of an endpoint for the
+// HTTP-Redirect binding. The binding signs the query rather than the message.
+func (s *Provider) redirectBindingURL(location, param string, el *etree.Element, relayState string, sign bool) (*url.URL, error) {
+	buf := &bytes.Buffer{}
+	writer, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	doc := etree.NewDocument()
+	doc.SetRoot(el)
+	if _, err := doc.WriteTo(writer); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	query := param + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	if sign {
+		query += "&SigAlg=" + url.QueryEscape(dsig.RSASHA256SignatureMethod)
+		digest := sha256.Sum256([]byte(query))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, s.serviceProvider.Key, crypto.SHA256, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&" + query
+	} else {
+		u.RawQuery = query
+	}
+	return u, nil
+}
+
+// signEnveloped signs a SAML message with the SP key for the HTTP-POST binding.
+// The signature is placed right after the issuer, as the SAML schema requires.
+func (s *Provider) signEnveloped(el *etree.Element) (*etree.Element, error) {
+	sp := s.serviceProvider
+	keyStore := dsig.TLSCertKeyStore(tls.Certificate{
+		Certificate: [][]byte{sp.Certificate.Raw},
+		PrivateKey:  sp.Key,
+		Leaf:        sp.Certificate,
+	})
+	signingContext := dsig.NewDefaultSigningContext(keyStore)
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	if err := signingContext.SetSignatureMethod(dsig.RSASHA256SignatureMethod); err != nil {
+		return nil, err
+	}
+	signed, err := signingContext.SignEnveloped(el)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := signed.RemoveChildAt(len(signed.Child) - 1)
+	index := 0
+	for i, child := range signed.Child {
+		if childEl, ok := child.(*etree.Element); ok && childEl.Tag == "Issuer" {
+			index = i + 1
+			break
+		}
+	}
+	signed.InsertChildAt(index, signature)
+	return signed, nil
+}
+
+// readMessage returns a SAML message of the identity provider sent with the
+// HTTP-Redirect or HTTP-POST binding, after checking its signature. Messages
+// of the HTTP-POST binding are returned as they were signed.
+func (s *Provider) readMessage(r *http.Request, param string) (string, []byte, error) {
+	certs, err := s.idpSigningCerts()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if encoded := r.URL.Query().Get(param); encoded != "" {
+		if err := verifyRedirectSignature(r.URL.RawQuery, param, certs); err != nil {
+			return "", nil, err
+		}
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot decode message: %v", err)
+		}
+		message, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxMessageSize))
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot inflate message: %v", err)
+		}
+		return saml.HTTPRedirectBinding, message, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(r.PostForm.Get(param))
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot decode message: %v", err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return "", nil, fmt.Errorf("cannot parse message: %v", err)
+	}
+	if doc.Root() == nil {
+		return "", nil, errors.New("message is empty")
+	}
+	validationContext := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+	validationContext.IdAttribute = "ID"
+	if saml.Clock != nil {
+		validationContext.Clock = saml.Clock
+	}
+	validated, err := validationContext.Validate(doc.Root())
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot validate signature: %v", err)
+	}
+	doc = etree.NewDocument()
+	doc.SetRoot(validated)
+	message, err := doc.WriteToBytes()
+	if err != nil {
+		return "", nil, err
+	}
+	return saml.HTTPPostBinding, message, nil
+}
+
+// verifyRedirectSignature checks the signature of the query of a SAML message
+// sent with the HTTP-Redirect binding. The signature is over the parameters as
+// they were encoded by the sender.
+func verifyRedirectSignature(rawQuery, param string, certs []*x509.Certificate) error {
+	values := map[string]string{}
+	for _, part := range strings.Split(rawQuery, "&") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if _, ok := values[kv[0]]; !ok {
+			values[kv[0]] = kv[1]
+		}
+	}
+	if values["Signature"] == "" || values["SigAlg"] == "" {
+		return errors.New("message is not signed")
+	}
+
+	signed := param + "=" + values[param]
+	if relayState, ok := values["RelayState"]; ok {
+		signed += "&RelayState=" + relayState
+	}
+	signed += "&SigAlg=" + values["SigAlg"]
+
+	sigAlg, err := url.QueryUnescape(values["SigAlg"])
+	if err != nil {
+		return err
+	}
+	encodedSignature, err := url.QueryUnescape(values["Signature"])
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("cannot decode signature: %v", err)
+	}
+
+	var hash crypto.Hash
+	var digest []byte
+	switch sigAlg {
+	case dsig.RSASHA1SignatureMethod:
+		sum := sha1.Sum([]byte(signed))
+		hash, digest = crypto.SHA1, sum[:]
+	case dsig.RSASHA256SignatureMethod:
+		sum := sha256.Sum256([]byte(signed))
+		hash, digest = crypto.SHA256, sum[:]
+	case dsig.RSASHA512SignatureMethod:
+		sum := sha512.Sum512([]byte(signed))
+		hash, digest = crypto.SHA512, sum[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %v", sigAlg)
+	}
+
+	for _, cert := range certs {
+		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if ok && rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match the signing certificates of the identity provider")
+}
+
+// idpSigningCerts returns the signing certificates of the identity provider,
+// or its first certificate if none is marked for signing.
+func (s *Provider) idpSigningCerts() ([]*x509.Certificate, error) {
+	var signing, other []string
+	for _, descriptor := range s.serviceProvider.IDPMetadata.IDPSSODescriptors {
+		for _, keyDescriptor := range descriptor.KeyDescriptors {
+			if keyDescriptor.KeyInfo.Certificate == "" {
+				continue
+			}
+			switch keyDescriptor.Use {
+			case "signing":
+				signing = append(signing, keyDescriptor.KeyInfo.Certificate)
+			case "":
+				other = append(other, keyDescriptor.KeyInfo.Certificate)
+			}
+		}
+	}
+	if len(signing) == 0 && len(other) > 0 {
+		signing = other[:1]
+	}
+	if len(signing) == 0 {
+		return nil, errors.New("cannot find any signing certificate in the IDP metadata")
+	}
+
+	var certs []*x509.Certificate
+	for _, encoded := range signing {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode IDP certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse IDP certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -133,13 +133,17 @@ func InitializeSamlServiceProvider(configToSet *v32.SamlConfig, name string) err
 	metadataURL.Path = metadataURL.Path + "/saml/metadata"
 	acsURL := *actURL
 	acsURL.Path = acsURL.Path + "/saml/acs"
+	sloURL := *actURL
+	sloURL.Path = sloURL.Path + "/saml/slo"
 
 	sp := saml.ServiceProvider{
-		Key:         privKey,
-		Certificate: cert,
-		MetadataURL: metadataURL,
-		AcsURL:      acsURL,
-		EntityID:    configToSet.EntityID,
+		Key:               privKey,
+		Certificate:       cert,
+		MetadataURL:       metadataURL,
+		AcsURL:            acsURL,
+		SloURL:            sloURL,
+		EntityID:          configToSet.EntityID,
+		AllowIDPInitiated: configToSet.IDPInitiatedLoginEnabled,
 	}
 
 	// XML unmarshal throws an error for IdP Metadata cacheDuration field, as it's of type xml Duration. Using a separate struct for unmarshaling for now
@@ -169,6 +173,15 @@ func InitializeSamlServiceProvider(configToSet *v32.SamlConfig, name string) err
 	}
 
 	provider.clientState = &cookieStore
+	provider.logoutState = &ClientCookies{
+		ServiceProvider: &sp,
+		Name:            "token",
+		Domain:          actURL.Host,
+		Path:            sloURL.Path,
+	}
+	provider.logoutPath = actURL.Path + "/saml/logout"
+	provider.signAuthnRequests = configToSet.SignAuthnRequests
+	provider.singleLogout = configToSet.SingleLogoutEnabled
 
 	root.Use(responsewriter.ContentTypeOptions)
 
@@ -178,18 +191,28 @@ func InitializeSamlServiceProvider(configToSet *v32.SamlConfig, name string) err
 	case PingName:
 		root.Get("PingACS").HandlerFunc(provider.ServeHTTP)
 		root.Get("PingMetadata").HandlerFunc(provider.ServeHTTP)
+		root.Get("PingSLO").HandlerFunc(provider.ServeHTTP)
+		root.Get("PingLogout").HandlerFunc(provider.ServeHTTP)
 	case ADFSName:
 		root.Get("AdfsACS").HandlerFunc(provider.ServeHTTP)
 		root.Get("AdfsMetadata").HandlerFunc(provider.ServeHTTP)
+		root.Get("AdfsSLO").HandlerFunc(provider.ServeHTTP)
+		root.Get("AdfsLogout").HandlerFunc(provider.ServeHTTP)
 	case KeyCloakName:
 		root.Get("KeyCloakACS").HandlerFunc(provider.ServeHTTP)
 		root.Get("KeyCloakMetadata").HandlerFunc(provider.ServeHTTP)
+		root.Get("KeyCloakSLO").HandlerFunc(provider.ServeHTTP)
+		root.Get("KeyCloakLogout").HandlerFunc(provider.ServeHTTP)
 	case OKTAName:
 		root.Get("OktaACS").HandlerFunc(provider.ServeHTTP)
 		root.Get("OktaMetadata").HandlerFunc(provider.ServeHTTP)
+		root.Get("OktaSLO").HandlerFunc(provider.ServeHTTP)
+		root.Get("OktaLogout").HandlerFunc(provider.ServeHTTP)
 	case ShibbolethName:
 		root.Get("ShibbolethACS").HandlerFunc(provider.ServeHTTP)
 		root.Get("ShibbolethMetadata").HandlerFunc(provider.ServeHTTP)
+		root.Get("ShibbolethSLO").HandlerFunc(provider.ServeHTTP)
+		root.Get("ShibbolethLogout").HandlerFunc(provider.ServeHTTP)
 	}
 
 	appliedVersion = configToSet.ResourceVersion
@@ -202,18 +225,28 @@ func AuthHandler() http.Handler {
 
 	root.Methods("POST").Path("/v1-saml/ping/saml/acs").Name("PingACS")
 	root.Methods("GET").Path("/v1-saml/ping/saml/metadata").Name("PingMetadata")
+	root.Methods("GET", "POST").Path("/v1-saml/ping/saml/slo").Name("PingSLO")
+	root.Methods("POST").Path("/v1-saml/ping/saml/logout").Name("PingLogout")
 
 	root.Methods("POST").Path("/v1-saml/adfs/saml/acs").Name("AdfsACS")
 	root.Methods("GET").Path("/v1-saml/adfs/saml/metadata").Name("AdfsMetadata")
+	root.Methods("GET", "POST").Path("/v1-saml/adfs/saml/slo").Name("AdfsSLO")
+	root.Methods("POST").Path("/v1-saml/adfs/saml/logout").Name("AdfsLogout")
 
 	root.Methods("POST").Path("/v1-saml/keycloak/saml/acs").Name("KeyCloakACS")
 	root.Methods("GET").Path("/v1-saml/keycloak/saml/metadata").Name("KeyCloakMetadata")
+	root.Methods("GET", "POST").Path("/v1-saml/keycloak/saml/slo").Name("KeyCloakSLO")
+	root.Methods("POST").Path("/v1-saml/keycloak/saml/logout").Name("KeyCloakLogout")
 
 	root.Methods("POST").Path("/v1-saml/okta/saml/acs").Name("OktaACS")
 	root.Methods("GET").Path("/v1-saml/okta/saml/metadata").Name("OktaMetadata")
+	root.Methods("GET", "POST").Path("/v1-saml/okta/saml/slo").Name("OktaSLO")
+	root.Methods("POST").Path("/v1-saml/okta/saml/logout").Name("OktaLogout")
 
 	root.Methods("POST").Path("/v1-saml/shibboleth/saml/acs").Name("ShibbolethACS")
 	root.Methods("GET").Path("/v1-saml/shibboleth/saml/metadata").Name("ShibbolethMetadata")
+	root.Methods("GET", "POST").Path("/v1-saml/shibboleth/saml/slo").Name("ShibbolethSLO")
+	root.Methods("POST").Path("/v1-saml/shibboleth/saml/logout").Name("ShibbolethLogout")
 
 	return root
 }
@@ -272,7 +305,10 @@ func (s *Provider) HandleSamlAssertion(w http.ResponseWriter, r *http.Request, a
 
 	redirectURL := s.clientState.GetState(r, "Rancher_FinalRedirectURL")
 	rancherAction := s.clientState.GetState(r, "Rancher_Action")
-	if rancherAction == loginAction {
+	// logins started at the identity provider have no state, the assertion
+	// was only accepted if the config allows them
+	idpInitiated := rancherAction == ""
+	if rancherAction == loginAction || idpInitiated {
 		redirectURL += "/login?"
 	} else if rancherAction == testAndEnableAction {
 		// the first query param is config=saml_provider_name set by UI
@@ -342,7 +378,7 @@ func (s *Provider) HandleSamlAssertion(w http.ResponseWriter, r *http.Request, a
 		if r.URL.Scheme == "https" {
 			isSecure = true
 		}
-		err = setRancherToken(w, r, s.tokenMGR, user.Name, userPrincipal, groupPrincipals, isSecure)
+		err = setRancherToken(w, r, s.tokenMGR, user.Name, userPrincipal, groupPrincipals, samlData, assertion, isSecure)
 		if err != nil {
 			log.Errorf("SAML: Failed creating token with error: %v", err)
 			http.Redirect(w, r, redirectURL+"errorCode=500", http.StatusFound)
@@ -377,10 +413,15 @@ func (s *Provider) HandleSamlAssertion(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
-	err = setRancherToken(w, r, s.tokenMGR, user.Name, userPrincipal, groupPrincipals, true)
+	err = setRancherToken(w, r, s.tokenMGR, user.Name, userPrincipal, groupPrincipals, samlData, assertion, true)
 	if err != nil {
 		log.Errorf("SAML: Failed creating token with error: %v", err)
 		http.Redirect(w, r, redirectURL+"errorCode=500", http.StatusFound)
+		return
+	}
+	if idpInitiated {
+		http.Redirect(w, r, s.localRedirectURL(r.Form.Get("RelayState")), http.StatusFound)
+		return
 	}
 	redirectURL = s.clientState.GetState(r, "Rancher_FinalRedirectURL")
 
@@ -450,9 +491,12 @@ func (s *Provider) HandleSamlAssertion(w http.ResponseWriter, r *http.Request, a
 	}
 }
 
+// setRancherToken logs the user of an assertion in, recording the attributes
+// of the assertion for principal mapping rules to match.
 func setRancherToken(w http.ResponseWriter, r *http.Request, tokenMGR *tokens.Manager, userID string, userPrincipal v3.Principal,
-	groupPrincipals []v3.Principal, isSecure bool) error {
-	rToken, unhashedTokenKey, err := tokenMGR.NewLoginToken(userID, userPrincipal, groupPrincipals, "", 0, "")
+	groupPrincipals []v3.Principal, samlData map[string][]string, assertion *saml.Assertion, isSecure bool) error {
+	providerInfo, labels := sessionInfo(userPrincipal.Provider, assertion)
+	rToken, unhashedTokenKey, err := tokenMGR.NewLoginTokenWithProviderInfo(userID, userPrincipal, groupPrincipals, "", 0, "", r, providerInfo, labels, samlData)
 	if err != nil {
 		return err
 	}
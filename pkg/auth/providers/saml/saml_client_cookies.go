@@ -29,6 +29,16 @@ type ClientCookies struct {
 	Name            string
 	Domain          string
 	Secure          bool
+	// Path limits the state cookies to a path, which defaults to the path of
+	// the ACS URL.
+	Path string
+}
+
+func (c ClientCookies) statePath() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return c.ServiceProvider.AcsURL.Path
 }
 
 // SetState stores the named state value by setting a cookie.
@@ -39,7 +49,7 @@ func (c ClientCookies) SetState(w http.ResponseWriter, r *http.Request, id strin
 		MaxAge:   int(saml.MaxIssueDelay.Seconds()),
 		HttpOnly: true,
 		Secure:   c.Secure || r.URL.Scheme == "https",
-		Path:     c.ServiceProvider.AcsURL.Path,
+		Path:     c.statePath(),
 	})
 }
 
@@ -72,6 +82,7 @@ func (c ClientCookies) DeleteState(w http.ResponseWriter, r *http.Request, id st
 		return err
 	}
 	cookie.Value = ""
+	cookie.Path = c.statePath()
 	cookie.Expires = time.Unix(1, 0) // past time as close to epoch as possible, but not zero time.Time{}
 	http.SetCookie(w, cookie)
 	return nil
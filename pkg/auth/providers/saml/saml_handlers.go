@@ -1,23 +1,42 @@
 package saml
 
 import (
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/crewjam/saml"
 	"github.com/dgrijalva/jwt-go"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/namespace"
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-// ServeHTTP is the handler for /saml/metadata and /saml/acs endpoints
+const (
+	// the IDs of used assertions are kept in secrets labeled with the
+	// provider they logged in to
+	assertionPrefix = "saml-assertion-"
+	assertionLabel  = "authn.management.cattle.io/saml-assertion"
+	expiresAtField  = "expiresAt"
+	cleanupInterval = time.Minute
+)
+
+// ServeHTTP is the handler for /saml/metadata, /saml/acs, /saml/slo and /saml/logout endpoints
 func (s *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	serviceProvider := s.serviceProvider
 	if r.URL.Path == serviceProvider.MetadataURL.Path {
-		buf, _ := xml.MarshalIndent(serviceProvider.Metadata(), "", "  ")
+		buf, _ := xml.MarshalIndent(s.metadata(), "", "  ")
 		w.Header().Set("Content-Type", "application/samlmetadata+xml")
 		w.Write(buf)
 		return
@@ -35,10 +54,33 @@ func (s *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, redirectURL, http.StatusFound)
 			return
 		}
+		added, err := s.assertions.add(assertion.ID, assertion.IssueInstant.Add(saml.MaxIssueDelay))
+		if err != nil {
+			log.Errorf("SAML: Failed to record assertion %v of provider %v: %v", assertion.ID, s.name, err)
+			redirectURL := r.URL.Host + "/login?errorCode=500"
+			http.Redirect(w, r, redirectURL, http.StatusFound)
+			return
+		}
+		if !added {
+			log.Errorf("SAML: Rejecting assertion %v of provider %v, it was already used", assertion.ID, s.name)
+			redirectURL := r.URL.Host + "/login?errorCode=403"
+			http.Redirect(w, r, redirectURL, http.StatusFound)
+			return
+		}
 		s.HandleSamlAssertion(w, r, assertion)
 		return
 	}
 
+	if r.URL.Path == serviceProvider.SloURL.Path && s.singleLogout {
+		s.HandleSamlSLO(w, r)
+		return
+	}
+
+	if r.URL.Path == s.logoutPath {
+		s.HandleSamlLogout(w, r)
+		return
+	}
+
 	http.NotFoundHandler().ServeHTTP(w, r)
 }
 
@@ -101,6 +143,13 @@ func (s *Provider) HandleSamlLogin(w http.ResponseWriter, r *http.Request) (stri
 	s.clientState.SetState(w, r, relayState, signedState)
 
 	if binding == saml.HTTPRedirectBinding {
+		if s.signAuthnRequests {
+			redirectURL, err := s.redirectBindingURL(req.Destination, "SAMLRequest", req.Element(), relayState, true)
+			if err != nil {
+				return "", err
+			}
+			return redirectURL.String(), nil
+		}
 		redirectURL := req.Redirect(relayState)
 		return redirectURL.String(), nil
 	}
@@ -114,3 +163,69 @@ func randomBytes(n int) []byte {
 	}
 	return rv
 }
+
+// assertionStore remembers the IDs of the assertions used to log in for as
+// long as they are accepted, so that they can't be replayed. The IDs are kept
+// in secrets, which all Rancher servers share.
+type assertionStore struct {
+	provider     string
+	secrets      corev1.SecretInterface
+	secretLister corev1.SecretLister
+	now          func() time.Time
+}
+
+func newAssertionStore(provider string, secrets corev1.SecretInterface, secretLister corev1.SecretLister) *assertionStore {
+	return &assertionStore{
+		provider:     provider,
+		secrets:      secrets,
+		secretLister: secretLister,
+		now:          time.Now,
+	}
+}
+
+func assertionSecretName(provider, id string) string {
+	hash := sha256.Sum256([]byte(provider + ":" + id))
+	return assertionPrefix + hex.EncodeToString(hash[:16])
+}
+
+// add records the ID of an assertion until it expires. It returns false if the
+// ID was already recorded.
+func (c *assertionStore) add(id string, expiresAt time.Time) (bool, error) {
+	_, err := c.secrets.Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      assertionSecretName(c.provider, id),
+			Namespace: namespace.System,
+			Labels:    map[string]string{assertionLabel: c.provider},
+		},
+		Data: map[string][]byte{
+			expiresAtField: []byte(expiresAt.UTC().Format(time.RFC3339)),
+		},
+	})
+	if apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// runCleanup periodically deletes the IDs of the assertions that expired.
+func (c *assertionStore) runCleanup(stop <-chan struct{}) {
+	go wait.JitterUntil(c.cleanup, cleanupInterval, .1, true, stop)
+}
+
+func (c *assertionStore) cleanup() {
+	secrets, err := c.secretLister.List(namespace.System, labels.SelectorFromSet(labels.Set{assertionLabel: c.provider}))
+	if err != nil {
+		log.Errorf("SAML: Failed to list the used assertions of %v: %v", c.provider, err)
+		return
+	}
+	now := c.now()
+	for _, secret := range secrets {
+		expiresAt, err := time.Parse(time.RFC3339, string(secret.Data[expiresAtField]))
+		if err == nil && now.Before(expiresAt.Add(saml.MaxClockSkew)) {
+			continue
+		}
+		if err := c.secrets.DeleteNamespaced(secret.Namespace, secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("SAML: Failed to delete used assertion %v: %v", secret.Name, err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package saml
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// samlNameIDLabel holds a hash of the name ID a login token was created
+	// for, to find the tokens to delete on logout requests of the IdP
+	samlNameIDLabel = "authn.management.cattle.io/saml-name-id"
+
+	nameIDInfo          = "saml_name_id"
+	nameIDFormatInfo    = "saml_name_id_format"
+	nameQualifierInfo   = "saml_name_qualifier"
+	spNameQualifierInfo = "saml_sp_name_qualifier"
+	sessionIndexInfo    = "saml_session_index"
+
+	csrfHeader = "X-API-CSRF"
+	csrfField  = "csrf"
+)
+
+// sessionInfo returns the provider info and labels of the login token created
+// for an assertion, recording the subject and session of the assertion at the
+// identity provider.
+func sessionInfo(provider string, assertion *saml.Assertion) (map[string]string, map[string]string) {
+	if assertion == nil || assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, nil
+	}
+	nameID := assertion.Subject.NameID
+	info := map[string]string{
+		nameIDInfo: nameID.Value,
+	}
+	setInfo := func(key, value string) {
+		if value != "" {
+			info[key] = value
+		}
+	}
+	setInfo(nameIDFormatInfo, nameID.Format)
+	setInfo(nameQualifierInfo, nameID.NameQualifier)
+	setInfo(spNameQualifierInfo, nameID.SPNameQualifier)
+	for _, statement := range assertion.AuthnStatements {
+		if statement.SessionIndex != "" {
+			setInfo(sessionIndexInfo, statement.SessionIndex)
+			break
+		}
+	}
+	return info, map[string]string{samlNameIDLabel: nameIDHash(provider, nameID.Value)}
+}
+
+func nameIDHash(provider, nameID string) string {
+	hash := sha256.Sum256([]byte(provider + ":" + nameID))
+	return hex.EncodeToString(hash[:16])
+}
+
+// HandleSamlLogout is the handler for /saml/logout. It ends the Rancher session
+// of the request and, with single logout enabled, the session of the user at
+// the identity provider, before sending the browser to finalRedirectUrl.
+// Browsers post a form to it with the CSRF cookie in the csrf field, so that
+// other sites can't log users out.
+func (s *Provider) HandleSamlLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	if !checkCSRF(r) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	finalRedirectURL := s.localRedirectURL(r.Form.Get("finalRedirectUrl"))
+
+	token, status, err := s.tokenMGR.EndSession(w, r)
+	if err != nil {
+		if status != http.StatusUnauthorized {
+			log.Errorf("SAML: Failed to end session: %v", err)
+			http.Error(w, "failed to end session", http.StatusInternalServerError)
+			return
+		}
+		// there is no session to end
+		log.Debugf("SAML [HandleSamlLogout]: %v", err)
+	}
+
+	if !s.singleLogout || token == nil || token.AuthProvider != s.name || token.ProviderInfo[nameIDInfo] == "" {
+		http.Redirect(w, r, finalRedirectURL, http.StatusFound)
+		return
+	}
+
+	log.Debugf("SAML [HandleSamlLogout]: Creating logout request for user %v of %v", token.UserID, s.name)
+	if err := s.sendLogoutRequest(w, r, token, finalRedirectURL); err != nil {
+		log.Errorf("SAML: Failed to log user %v out of the identity provider: %v", token.UserID, err)
+		http.Redirect(w, r, finalRedirectURL, http.StatusFound)
+	}
+}
+
+// checkCSRF checks that requests authenticated by the session cookie of a
+// browser send the CSRF cookie in the CSRF header or the csrf form field.
+func checkCSRF(r *http.Request) bool {
+	if _, err := r.Cookie(tokens.CookieName); err != nil || r.Header.Get("Authorization") != "" {
+		return true
+	}
+	cookie, err := r.Cookie(tokens.CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	value := r.Header.Get(csrfHeader)
+	if value == "" {
+		value = r.PostForm.Get(csrfField)
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(value)) == 1
+}
+
+func (s *Provider) sendLogoutRequest(w http.ResponseWriter, r *http.Request, token *v3.Token, finalRedirectURL string) error {
+	binding, location := s.idpSLOEndpoint(saml.HTTPRedirectBinding, false)
+	if location == "" {
+		return fmt.Errorf("the identity provider has no single logout endpoint")
+	}
+
+	req := saml.LogoutRequest{
+		ID:           fmt.Sprintf("id-%x", randomBytes(20)),
+		Version:      "2.0",
+		IssueInstant: saml.TimeNow(),
+		Destination:  location,
+		Issuer:       s.issuer(),
+		NameID: &saml.NameID{
+			Value:           token.ProviderInfo[nameIDInfo],
+			Format:          token.ProviderInfo[nameIDFormatInfo],
+			NameQualifier:   token.ProviderInfo[nameQualifierInfo],
+			SPNameQualifier: token.ProviderInfo[spNameQualifierInfo],
+		},
+	}
+	if sessionIndex := token.ProviderInfo[sessionIndexInfo]; sessionIndex != "" {
+		req.SessionIndex = &saml.SessionIndex{Value: sessionIndex}
+	}
+
+	s.logoutState.SetState(w, r, "Rancher_LogoutRequestID", req.ID)
+	s.logoutState.SetState(w, r, "Rancher_LogoutRedirectURL", finalRedirectURL)
+	return s.sendMessage(w, r, binding, location, "SAMLRequest", req.Element())
+}
+
+// HandleSamlSLO is the handler for /saml/slo. It handles the logout requests
+// of the identity provider, which end the Rancher sessions of the user, and
+// the responses to the logout requests sent by HandleSamlLogout.
+func (s *Provider) HandleSamlSLO(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.Form.Get("SAMLRequest") != "":
+		s.handleLogoutRequest(w, r)
+	case r.Form.Get("SAMLResponse") != "":
+		s.handleLogoutResponse(w, r)
+	default:
+		http.Error(w, "missing SAMLRequest or SAMLResponse", http.StatusBadRequest)
+	}
+}
+
+func (s *Provider) handleLogoutRequest(w http.ResponseWriter, r *http.Request) {
+	binding, message, err := s.readMessage(r, "SAMLRequest")
+	if err != nil {
+		log.Errorf("SAML: Rejecting logout request of %v: %v", s.name, err)
+		http.Error(w, "invalid logout request", http.StatusBadRequest)
+		return
+	}
+	req := saml.LogoutRequest{}
+	if err := xml.Unmarshal(message, &req); err != nil {
+		log.Errorf("SAML: Rejecting logout request of %v: %v", s.name, err)
+		http.Error(w, "invalid logout request", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateMessage(req.Issuer, req.Destination, req.IssueInstant); err != nil {
+		log.Errorf("SAML: Rejecting logout request of %v: %v", s.name, err)
+		http.Error(w, "invalid logout request", http.StatusBadRequest)
+		return
+	}
+
+	status := saml.StatusSuccess
+	if req.NameID == nil || req.NameID.Value == "" {
+		status = saml.StatusRequester
+	} else {
+		var sessionIndex string
+		if req.SessionIndex != nil {
+			sessionIndex = req.SessionIndex.Value
+		}
+		if err := s.endSessions(req.NameID.Value, sessionIndex); err != nil {
+			log.Errorf("SAML: Failed to end the sessions of %v: %v", req.NameID.Value, err)
+			status = saml.StatusResponder
+		}
+	}
+
+	binding, location := s.idpSLOEndpoint(binding, true)
+	if location == "" {
+		log.Errorf("SAML: Cannot respond to logout request of %v, the identity provider has no single logout endpoint", s.name)
+		http.Error(w, "no single logout endpoint", http.StatusInternalServerError)
+		return
+	}
+	resp := saml.LogoutResponse{
+		ID:           fmt.Sprintf("id-%x", randomBytes(20)),
+		InResponseTo: req.ID,
+		Version:      "2.0",
+		IssueInstant: saml.TimeNow(),
+		Destination:  location,
+		Issuer:       s.issuer(),
+		Status: saml.Status{
+			StatusCode: saml.StatusCode{
+				Value: status,
+			},
+		},
+	}
+	if err := s.sendMessage(w, r, binding, location, "SAMLResponse", resp.Element()); err != nil {
+		log.Errorf("SAML: Failed to respond to logout request of %v: %v", s.name, err)
+		http.Error(w, "failed to create logout response", http.StatusInternalServerError)
+	}
+}
+
+func (s *Provider) handleLogoutResponse(w http.ResponseWriter, r *http.Request) {
+	finalRedirectURL := s.localRedirectURL(s.logoutState.GetState(r, "Rancher_LogoutRedirectURL"))
+	requestID := s.logoutState.GetState(r, "Rancher_LogoutRequestID")
+	s.logoutState.DeleteState(w, r, "Rancher_LogoutRedirectURL")
+	s.logoutState.DeleteState(w, r, "Rancher_LogoutRequestID")
+
+	// the Rancher session has already ended, the browser is sent on even if
+	// the identity provider failed to end its session
+	if err := s.validateLogoutResponse(r, requestID); err != nil {
+		log.Errorf("SAML: Logout at the identity provider %v failed: %v", s.name, err)
+	}
+	http.Redirect(w, r, finalRedirectURL, http.StatusFound)
+}
+
+func (s *Provider) validateLogoutResponse(r *http.Request, requestID string) error {
+	_, message, err := s.readMessage(r, "SAMLResponse")
+	if err != nil {
+		return err
+	}
+	resp := saml.LogoutResponse{}
+	if err := xml.Unmarshal(message, &resp); err != nil {
+		return err
+	}
+	if err := s.validateMessage(resp.Issuer, resp.Destination, resp.IssueInstant); err != nil {
+		return err
+	}
+	if requestID == "" || resp.InResponseTo != requestID {
+		return fmt.Errorf("response is not for the logout request %q", requestID)
+	}
+	if resp.Status.StatusCode.Value != saml.StatusSuccess {
+		return fmt.Errorf("status is %v", resp.Status.StatusCode.Value)
+	}
+	return nil
+}
+
+// validateMessage checks that a logout message was issued by the identity
+// provider for this service provider recently.
+func (s *Provider) validateMessage(issuer *saml.Issuer, destination string, issueInstant time.Time) error {
+	sp := s.serviceProvider
+	if issuer == nil || issuer.Value != sp.IDPMetadata.EntityID {
+		return fmt.Errorf("issuer does not match the IDP metadata (expected %q)", sp.IDPMetadata.EntityID)
+	}
+	if destination != sp.SloURL.String() {
+		return fmt.Errorf("destination does not match the SLO URL (expected %q)", sp.SloURL.String())
+	}
+	now := saml.TimeNow()
+	if issueInstant.Add(saml.MaxIssueDelay).Before(now) {
+		return fmt.Errorf("expired at %s", issueInstant.Add(saml.MaxIssueDelay))
+	}
+	if issueInstant.Add(-saml.MaxClockSkew).After(now) {
+		return fmt.Errorf("issued in the future at %s", issueInstant)
+	}
+	return nil
+}
+
+// endSessions deletes the login tokens of a name ID. If sessionIndex is set,
+// only the tokens of that session at the identity provider are deleted.
+func (s *Provider) endSessions(nameID, sessionIndex string) error {
+	tokens, err := s.tokenLister.List("", labels.SelectorFromSet(labels.Set{samlNameIDLabel: nameIDHash(s.name, nameID)}))
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.AuthProvider != s.name || token.ProviderInfo[nameIDInfo] != nameID {
+			continue
+		}
+		if sessionIndex != "" && token.ProviderInfo[sessionIndexInfo] != sessionIndex {
+			continue
+		}
+		if err := s.tokens.Delete(token.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Debugf("SAML [endSessions]: Deleted token %v of user %v", token.Name, token.UserID)
+	}
+	return nil
+}
+
+// idpSLOEndpoint returns the single logout endpoint of the identity provider
+// for a binding, falling back to its other binding. Responses are sent to the
+// response location of the endpoint if it has one.
+func (s *Provider) idpSLOEndpoint(binding string, response bool) (string, string) {
+	bindings := []string{binding, saml.HTTPRedirectBinding, saml.HTTPPostBinding}
+	for _, binding := range bindings {
+		for _, descriptor := range s.serviceProvider.IDPMetadata.IDPSSODescriptors {
+			for _, endpoint := range descriptor.SingleLogoutServices {
+				if endpoint.Binding != binding {
+					continue
+				}
+				if response && endpoint.ResponseLocation != "" {
+					return binding, endpoint.ResponseLocation
+				}
+				return binding, endpoint.Location
+			}
+		}
+	}
+	return "", ""
+}
+
+func (s *Provider) issuer() *saml.Issuer {
+	return &saml.Issuer{
+		Format: "urn:oasis:names:tc:SAML:2.0:nameid-format:entity",
+		Value:  s.entityID(),
+	}
+}
+
+func (s *Provider) entityID() string {
+	if s.serviceProvider.EntityID != "" {
+		return s.serviceProvider.EntityID
+	}
+	return s.serviceProvider.MetadataURL.String()
+}
+
+// localRedirectURL returns target if it is on the Rancher server, so that
+// browsers can't be sent elsewhere, and "/" otherwise.
+func (s *Provider) localRedirectURL(target string) string {
+	if target == "" || strings.HasPrefix(target, "//") || strings.Contains(target, "\\") {
+		return "/"
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "/"
+	}
+	if u.IsAbs() || u.Host != "" {
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host != s.serviceProvider.AcsURL.Host {
+			return "/"
+		}
+		return target
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return "/"
+	}
+	return target
+}
+
+// metadata returns the metadata of the service provider, which only lists the
+// single logout endpoints if single logout is enabled.
+func (s *Provider) metadata() *saml.EntityDescriptor {
+	metadata := s.serviceProvider.Metadata()
+	sloURL := s.serviceProvider.SloURL.String()
+	for i := range metadata.SPSSODescriptors {
+		descriptor := &metadata.SPSSODescriptors[i]
+		descriptor.SingleLogoutServices = nil
+		if s.singleLogout {
+			descriptor.SingleLogoutServices = []saml.Endpoint{
+				{Binding: saml.HTTPRedirectBinding, Location: sloURL},
+				{Binding: saml.HTTPPostBinding, Location: sloURL},
+			}
+		}
+		signsRequests := s.signAuthnRequests
+		descriptor.AuthnRequestsSigned = &signsRequests
+		if !s.signAuthnRequests && !s.singleLogout {
+			continue
+		}
+		for _, keyDescriptor := range descriptor.KeyDescriptors {
+			if keyDescriptor.Use == "encryption" {
+				descriptor.KeyDescriptors = append(descriptor.KeyDescriptors, saml.KeyDescriptor{
+					Use:     "signing",
+					KeyInfo: keyDescriptor.KeyInfo,
+				})
+				break
+			}
+		}
+	}
+	return metadata
+}
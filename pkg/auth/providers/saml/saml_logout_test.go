@@ -0,0 +1,362 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testIDPEntityID = "https://idp.example.com/metadata"
+
+// newTestProvider returns a provider whose identity provider signs with the
+// key of the service provider, so that tests can sign messages of the IdP.
+func newTestProvider(t *testing.T, tokens []*v3.Token) (*Provider, *[]string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rancher.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustParse := func(rawURL string) url.URL {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *u
+	}
+	sp := &saml.ServiceProvider{
+		Key:         key,
+		Certificate: cert,
+		MetadataURL: mustParse("https://rancher.example.com/v1-saml/okta/saml/metadata"),
+		AcsURL:      mustParse("https://rancher.example.com/v1-saml/okta/saml/acs"),
+		SloURL:      mustParse("https://rancher.example.com/v1-saml/okta/saml/slo"),
+		IDPMetadata: &saml.EntityDescriptor{
+			EntityID: testIDPEntityID,
+			IDPSSODescriptors: []saml.IDPSSODescriptor{
+				{
+					SSODescriptor: saml.SSODescriptor{
+						RoleDescriptor: saml.RoleDescriptor{
+							KeyDescriptors: []saml.KeyDescriptor{
+								{Use: "signing", KeyInfo: saml.KeyInfo{Certificate: base64.StdEncoding.EncodeToString(der)}},
+							},
+						},
+						SingleLogoutServices: []saml.Endpoint{
+							{Binding: saml.HTTPRedirectBinding, Location: "https://idp.example.com/slo"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var deleted []string
+	p := &Provider{
+		name:            OKTAName,
+		serviceProvider: sp,
+		singleLogout:    true,
+		logoutState:     &ClientCookies{ServiceProvider: sp, Path: sp.SloURL.Path},
+		tokenLister: &fakes.TokenListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Token, error) {
+				var result []*v3.Token
+				for _, token := range tokens {
+					if selector.Matches(labels.Set(token.Labels)) {
+						result = append(result, token)
+					}
+				}
+				return result, nil
+			},
+		},
+		tokens: &fakes.TokenInterfaceMock{
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				deleted = append(deleted, name)
+				return nil
+			},
+		},
+	}
+	return p, &deleted
+}
+
+func newTestToken(name, provider, nameID, sessionIndex string) *v3.Token {
+	assertion := &saml.Assertion{
+		Subject:         &saml.Subject{NameID: &saml.NameID{Value: nameID}},
+		AuthnStatements: []saml.AuthnStatement{{SessionIndex: sessionIndex}},
+	}
+	providerInfo, labels := sessionInfo(provider, assertion)
+	return &v3.Token{
+		ObjectMeta:   metav1.ObjectMeta{Name: name, Labels: labels},
+		AuthProvider: provider,
+		ProviderInfo: providerInfo,
+	}
+}
+
+func newTestLogoutRequest(p *Provider, sessionIndex string) *saml.LogoutRequest {
+	req := &saml.LogoutRequest{
+		ID:           "id-logout",
+		Version:      "2.0",
+		IssueInstant: saml.TimeNow(),
+		Destination:  p.serviceProvider.SloURL.String(),
+		Issuer:       &saml.Issuer{Value: testIDPEntityID},
+		NameID:       &saml.NameID{Value: "jdoe@example.com"},
+	}
+	if sessionIndex != "" {
+		req.SessionIndex = &saml.SessionIndex{Value: sessionIndex}
+	}
+	return req
+}
+
+// readLogoutResponse returns the logout response the provider redirected to
+// the identity provider, checking its signature.
+func readLogoutResponse(t *testing.T, p *Provider, recorder *httptest.ResponseRecorder) *saml.LogoutResponse {
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "idp.example.com", location.Host)
+	certs, err := p.idpSigningCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, verifyRedirectSignature(location.RawQuery, "SAMLResponse", certs))
+
+	compressed, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLResponse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &saml.LogoutResponse{}
+	if err := xml.Unmarshal(message, resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestLogoutRequestRedirectBinding(t *testing.T) {
+	assert := assert.New(t)
+	p, deleted := newTestProvider(t, []*v3.Token{
+		newTestToken("token-1", OKTAName, "jdoe@example.com", "session-1"),
+		newTestToken("token-2", OKTAName, "jdoe@example.com", "session-2"),
+		newTestToken("token-3", PingName, "jdoe@example.com", "session-1"),
+		newTestToken("token-4", OKTAName, "alice@example.com", "session-1"),
+	})
+
+	req := newTestLogoutRequest(p, "session-1")
+	requestURL, err := p.redirectBindingURL(p.serviceProvider.SloURL.String(), "SAMLRequest", req.Element(), "relay", true)
+	assert.Nil(err)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, requestURL.String(), nil))
+	assert.Equal(http.StatusFound, recorder.Code)
+	assert.Equal([]string{"token-1"}, *deleted, "only the tokens of the session are deleted")
+
+	resp := readLogoutResponse(t, p, recorder)
+	assert.Equal("id-logout", resp.InResponseTo)
+	assert.Equal(saml.StatusSuccess, resp.Status.StatusCode.Value)
+	assert.Equal("https://rancher.example.com/v1-saml/okta/saml/metadata", resp.Issuer.Value)
+
+	*deleted = nil
+	unsignedURL, err := p.redirectBindingURL(p.serviceProvider.SloURL.String(), "SAMLRequest", req.Element(), "", false)
+	assert.Nil(err)
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, unsignedURL.String(), nil))
+	assert.Equal(http.StatusBadRequest, recorder.Code, "unsigned requests are rejected")
+	assert.Empty(*deleted)
+
+	p.singleLogout = false
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, requestURL.String(), nil))
+	assert.Equal(http.StatusNotFound, recorder.Code, "logout requests need single logout to be enabled")
+}
+
+func TestLogoutRequestPostBinding(t *testing.T) {
+	assert := assert.New(t)
+	p, deleted := newTestProvider(t, []*v3.Token{
+		newTestToken("token-1", OKTAName, "jdoe@example.com", "session-1"),
+		newTestToken("token-2", OKTAName, "jdoe@example.com", "session-2"),
+	})
+
+	signed, err := p.signEnveloped(newTestLogoutRequest(p, "").Element())
+	assert.Nil(err)
+	doc := etree.NewDocument()
+	doc.SetRoot(signed)
+	message, err := doc.WriteToString()
+	assert.Nil(err)
+	assert.Equal("Signature", signed.ChildElements()[1].Tag, "the signature follows the issuer")
+
+	post := func(message string) *httptest.ResponseRecorder {
+		form := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString([]byte(message))}}
+		req := httptest.NewRequest(http.MethodPost, p.serviceProvider.SloURL.String(), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	tampered := strings.Replace(message, "jdoe@example.com", "alice@example.com", 1)
+	recorder := post(tampered)
+	assert.Equal(http.StatusBadRequest, recorder.Code, "requests not matching their signature are rejected")
+	assert.Empty(*deleted)
+
+	recorder = post(message)
+	assert.Equal(http.StatusFound, recorder.Code)
+	assert.Equal([]string{"token-1", "token-2"}, *deleted, "requests without session index end all sessions")
+	resp := readLogoutResponse(t, p, recorder)
+	assert.Equal(saml.StatusSuccess, resp.Status.StatusCode.Value)
+}
+
+func TestLogoutResponse(t *testing.T) {
+	assert := assert.New(t)
+	p, _ := newTestProvider(t, nil)
+
+	resp := saml.LogoutResponse{
+		ID:           "id-response",
+		InResponseTo: "id-request",
+		Version:      "2.0",
+		IssueInstant: saml.TimeNow(),
+		Destination:  p.serviceProvider.SloURL.String(),
+		Issuer:       &saml.Issuer{Value: testIDPEntityID},
+		Status:       saml.Status{StatusCode: saml.StatusCode{Value: saml.StatusSuccess}},
+	}
+	responseURL, err := p.redirectBindingURL(p.serviceProvider.SloURL.String(), "SAMLResponse", resp.Element(), "", true)
+	assert.Nil(err)
+
+	req := httptest.NewRequest(http.MethodGet, responseURL.String(), nil)
+	assert.Nil(p.validateLogoutResponse(req, "id-request"))
+	assert.Error(p.validateLogoutResponse(req, "id-other"), "responses must match the logout request")
+
+	req = httptest.NewRequest(http.MethodGet, responseURL.String(), nil)
+	req.AddCookie(&http.Cookie{Name: stateCookiePrefix + "Rancher_LogoutRequestID", Value: "id-request"})
+	req.AddCookie(&http.Cookie{Name: stateCookiePrefix + "Rancher_LogoutRedirectURL", Value: "/dashboard/auth/login?logged-out"})
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, req)
+	assert.Equal(http.StatusFound, recorder.Code)
+	assert.Equal("/dashboard/auth/login?logged-out", recorder.Header().Get("Location"))
+}
+
+func TestLocalRedirectURL(t *testing.T) {
+	assert := assert.New(t)
+	p, _ := newTestProvider(t, nil)
+
+	assert.Equal("/dashboard/", p.localRedirectURL("/dashboard/"))
+	assert.Equal("https://rancher.example.com/login", p.localRedirectURL("https://rancher.example.com/login"))
+	assert.Equal("/", p.localRedirectURL(""))
+	assert.Equal("/", p.localRedirectURL("https://evil.example.com/"))
+	assert.Equal("/", p.localRedirectURL("//evil.example.com/"))
+	assert.Equal("/", p.localRedirectURL("/\\evil.example.com/"))
+	assert.Equal("/", p.localRedirectURL("javascript:alert(1)"))
+	assert.Equal("/", p.localRedirectURL("opaque-relay-state"))
+}
+
+func TestAssertionStore(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1600000000, 0)
+	secrets := map[string]*corev1.Secret{}
+	store := newAssertionStore(OKTAName, &corefakes.SecretInterfaceMock{
+		CreateFunc: func(secret *corev1.Secret) (*corev1.Secret, error) {
+			if _, ok := secrets[secret.Name]; ok {
+				return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, secret.Name)
+			}
+			secrets[secret.Name] = secret
+			return secret, nil
+		},
+		DeleteNamespacedFunc: func(namespace, name string, options *metav1.DeleteOptions) error {
+			delete(secrets, name)
+			return nil
+		},
+	}, &corefakes.SecretListerMock{
+		ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+			var result []*corev1.Secret
+			for _, secret := range secrets {
+				if selector.Matches(labels.Set(secret.Labels)) {
+					result = append(result, secret)
+				}
+			}
+			return result, nil
+		},
+	})
+	store.now = func() time.Time {
+		return now
+	}
+
+	added, err := store.add("id-1", now.Add(saml.MaxIssueDelay))
+	assert.Nil(err)
+	assert.True(added)
+	added, err = store.add("id-1", now.Add(saml.MaxIssueDelay))
+	assert.Nil(err)
+	assert.False(added, "assertions can't be used twice")
+	added, _ = store.add("id-2", now.Add(saml.MaxIssueDelay))
+	assert.True(added)
+
+	store.cleanup()
+	assert.Len(secrets, 2, "assertions are remembered until they expire")
+	now = now.Add(saml.MaxIssueDelay + saml.MaxClockSkew + time.Second)
+	store.add("id-3", now.Add(saml.MaxIssueDelay))
+	store.cleanup()
+	assert.Len(secrets, 1, "expired assertions are forgotten")
+}
+
+func TestLogoutCSRF(t *testing.T) {
+	assert := assert.New(t)
+	p, _ := newTestProvider(t, nil)
+
+	recorder := httptest.NewRecorder()
+	p.HandleSamlLogout(recorder, httptest.NewRequest(http.MethodGet, "/v1-saml/okta/saml/logout", nil))
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code, "logging out needs a POST")
+
+	newRequest := func(csrf string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1-saml/okta/saml/logout", strings.NewReader(url.Values{"csrf": {csrf}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: tokens.CookieName, Value: "token-abcde:secret"})
+		req.AddCookie(&http.Cookie{Name: tokens.CSRFCookie, Value: "0123456789"})
+		req.ParseForm()
+		return req
+	}
+	recorder = httptest.NewRecorder()
+	p.HandleSamlLogout(recorder, newRequest("other"))
+	assert.Equal(http.StatusForbidden, recorder.Code, "sessions are only ended with the CSRF token")
+
+	assert.True(checkCSRF(newRequest("0123456789")))
+	assert.False(checkCSRF(newRequest("")))
+	assert.True(checkCSRF(httptest.NewRequest(http.MethodPost, "/v1-saml/okta/saml/logout", nil)), "requests without a session need no CSRF token")
+}
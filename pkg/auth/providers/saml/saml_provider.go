@@ -38,18 +38,25 @@
 )
 
 type Provider struct {
-	ctx             context.Context
-	authConfigs     v3.AuthConfigInterface
-	secrets         corev1.SecretInterface
-	samlTokens      v3.SamlTokenInterface
-	userMGR         user.Manager
-	tokenMGR        *tokens.Manager
-	serviceProvider *saml.ServiceProvider
-	name            string
-	userType        string
-	groupType       string
-	clientState     ClientState
-	ldapProvider    common.AuthProvider
+	ctx               context.Context
+	authConfigs       v3.AuthConfigInterface
+	secrets           corev1.SecretInterface
+	samlTokens        v3.SamlTokenInterface
+	tokens            v3.TokenInterface
+	tokenLister       v3.TokenLister
+	userMGR           user.Manager
+	tokenMGR          *tokens.Manager
+	serviceProvider   *saml.ServiceProvider
+	name              string
+	userType          string
+	groupType         string
+	clientState       ClientState
+	logoutState       ClientState
+	logoutPath        string
+	signAuthnRequests bool
+	singleLogout      bool
+	assertions        *assertionStore
+	ldapProvider      common.AuthProvider
 }
 
 var SamlProviders = make(map[string]*Provider)
@@ -60,12 +67,16 @@ func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.
 		authConfigs: mgmtCtx.Management.AuthConfigs(""),
 		secrets:     mgmtCtx.Core.Secrets(""),
 		samlTokens:  mgmtCtx.Management.SamlTokens(""),
+		tokens:      mgmtCtx.Management.Tokens(""),
+		tokenLister: mgmtCtx.Management.Tokens("").Controller().Lister(),
 		userMGR:     userMGR,
 		tokenMGR:    tokenMGR,
 		name:        name,
 		userType:    name + "_user",
 		groupType:   name + "_group",
 	}
+	samlp.assertions = newAssertionStore(name, samlp.secrets, mgmtCtx.Core.Secrets("").Controller().Lister())
+	samlp.assertions.runCleanup(ctx.Done())
 
 	if samlp.hasLdapGroupSearch() {
 		samlp.ldapProvider = ldap.Configure(ctx, mgmtCtx, userMGR, tokenMGR, name)
@@ -86,17 +97,27 @@ func (s *Provider) CustomizeSchema(schema *types.Schema) {
 
 func (s *Provider) TransformToAuthProvider(authConfig map[string]interface{}) (map[string]interface{}, error) {
 	p := common.TransformToAuthProvider(authConfig)
+	var logoutURL string
+	// the single logout field has the same name in the configs of all saml providers
+	if singleLogout, _ := authConfig[client.PingConfigFieldSingleLogoutEnabled].(bool); singleLogout {
+		logoutURL = formSamlLogoutURLFromMap(authConfig, s.name)
+	}
 	switch s.name {
 	case PingName:
 		p[publicclient.PingProviderFieldRedirectURL] = formSamlRedirectURLFromMap(authConfig, s.name)
+		p[publicclient.PingProviderFieldLogoutURL] = logoutURL
 	case ADFSName:
 		p[publicclient.ADFSProviderFieldRedirectURL] = formSamlRedirectURLFromMap(authConfig, s.name)
+		p[publicclient.ADFSProviderFieldLogoutURL] = logoutURL
 	case KeyCloakName:
 		p[publicclient.KeyCloakProviderFieldRedirectURL] = formSamlRedirectURLFromMap(authConfig, s.name)
+		p[publicclient.KeyCloakProviderFieldLogoutURL] = logoutURL
 	case OKTAName:
 		p[publicclient.OKTAProviderFieldRedirectURL] = formSamlRedirectURLFromMap(authConfig, s.name)
+		p[publicclient.OKTAProviderFieldLogoutURL] = logoutURL
 	case ShibbolethName:
 		p[publicclient.ShibbolethProviderFieldRedirectURL] = formSamlRedirectURLFromMap(authConfig, s.name)
+		p[publicclient.ShibbolethProviderFieldLogoutURL] = logoutURL
 	}
 	return p, nil
 }
@@ -345,6 +366,10 @@ func formSamlRedirectURLFromMap(config map[string]interface{}, name string) stri
 	return path
 }
 
+func formSamlLogoutURLFromMap(config map[string]interface{}, name string) string {
+	return strings.TrimSuffix(formSamlRedirectURLFromMap(config, name), "/login") + "/saml/logout"
+}
+
 func splitPrincipalID(principalID string) (string, string) {
 	parts := strings.SplitN(principalID, ":", 2)
 	if len(parts) != 2 {
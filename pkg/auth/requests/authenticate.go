@@ -4,14 +4,19 @@
 	"context"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/tokens"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/steve/pkg/auth"
+	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -22,6 +27,16 @@
 	ErrMustAuthenticate = httperror.NewAPIError(httperror.Unauthorized, "must authenticate")
 )
 
+// scopeDeniedUser is the user of requests made with a valid token whose
+// scopes do not allow them, which are rejected with 403 instead of 401.
+const scopeDeniedUser = "system:cattle:scope-denied"
+
+// scopeError is returned by Authenticate when the token scopes do not allow
+// the request.
+type scopeError struct {
+	error
+}
+
 type Authenticator interface {
 	Authenticate(req *http.Request) (authed bool, user string, groups []string, err error)
 	TokenFromRequest(req *http.Request) (*v3.Token, error)
@@ -30,6 +45,13 @@ type Authenticator interface {
 func ToAuthMiddleware(a Authenticator) auth.Middleware {
 	f := func(req *http.Request) (user.Info, bool, error) {
 		authed, u, groups, err := a.Authenticate(req)
+		if scopeErr, ok := err.(*scopeError); ok {
+			return &user.DefaultInfo{
+				Name:  scopeDeniedUser,
+				UID:   scopeDeniedUser,
+				Extra: map[string][]string{"reason": {scopeErr.Error()}},
+			}, true, nil
+		}
 		return &user.DefaultInfo{
 			Name:   u,
 			UID:    u,
@@ -52,8 +74,10 @@ func NewAuthenticator(ctx context.Context, clusterRouter ClusterRouter, mgmtCtx
 		userAttributeLister: mgmtCtx.Management.UserAttributes("").Controller().Lister(),
 		userAttributes:      mgmtCtx.Management.UserAttributes(""),
 		userLister:          mgmtCtx.Management.Users("").Controller().Lister(),
+		namespaceLister:     mgmtCtx.Core.Namespaces("").Controller().Lister(),
 		clusterRouter:       clusterRouter,
 		userAuthRefresher:   providerrefresh.NewUserAuthRefresher(ctx, mgmtCtx),
+		lastUsedUpdates:     map[string]bool{},
 	}
 }
 
@@ -64,12 +88,20 @@ type tokenAuthenticator struct {
 	userAttributes      v3.UserAttributeInterface
 	userAttributeLister v3.UserAttributeLister
 	userLister          v3.UserLister
+	namespaceLister     v1.NamespaceLister
 	clusterRouter       ClusterRouter
 	userAuthRefresher   providerrefresh.UserAuthRefresher
+	// lastUsedUpdates are the tokens whose last used time is being updated
+	lastUsedUpdates map[string]bool
+	lastUsedLock    sync.Mutex
 }
 
 const (
 	tokenKeyIndex = "authn.management.cattle.io/token-key-index"
+	// the last used time of tokens is updated at most once per interval,
+	// every update of a token wakes its watchers, such as the sync of
+	// cluster auth tokens to downstream clusters
+	lastUsedUpdateInterval = time.Hour
 )
 
 func tokenKeyIndexer(obj interface{}) ([]string, error) {
@@ -93,6 +125,9 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (bool, string, []st
 	if token.ClusterName != "" && token.ClusterName != a.clusterRouter(req) {
 		return false, "", []string{}, errors.Wrapf(ErrMustAuthenticate, "clusterID does not match")
 	}
+	if err := tokens.CheckScopes(token.Scopes, tokens.GetScopeRequest(req), a.projectResolver(req)); err != nil {
+		return false, "", []string{}, &scopeError{err}
+	}
 
 	attribs, err := a.userAttributeLister.Get("", token.UserID)
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -136,10 +171,55 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (bool, string, []st
 	if !strings.HasPrefix(token.UserID, "system:") {
 		go a.userAuthRefresher.TriggerUserRefresh(token.UserID, false)
 	}
+	a.updateLastUsed(token)
 
 	return true, token.UserID, groups, nil
 }
 
+// updateLastUsed records that token is being used, unless it recently was.
+func (a *tokenAuthenticator) updateLastUsed(token *v3.Token) {
+	now := time.Now()
+	if lastUsed, err := time.Parse(time.RFC3339, token.LastUsedAt); err == nil && now.Sub(lastUsed) < lastUsedUpdateInterval {
+		return
+	}
+
+	a.lastUsedLock.Lock()
+	defer a.lastUsedLock.Unlock()
+	if a.lastUsedUpdates[token.Name] {
+		return
+	}
+	a.lastUsedUpdates[token.Name] = true
+
+	go func() {
+		defer func() {
+			a.lastUsedLock.Lock()
+			delete(a.lastUsedUpdates, token.Name)
+			a.lastUsedLock.Unlock()
+		}()
+		token := token.DeepCopy()
+		token.LastUsedAt = now.UTC().Format(time.RFC3339)
+		if _, err := a.tokenClient.Update(token); err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+			logrus.Debugf("Failed to update the last used time of token %s: %v", token.Name, err)
+		}
+	}()
+}
+
+// projectResolver returns the project of namespaces of the local cluster, for
+// the steve API and the cluster proxy to the local cluster. Namespaces of
+// downstream clusters are not cached here and have no known project.
+func (a *tokenAuthenticator) projectResolver(req *http.Request) tokens.ProjectResolver {
+	if clusterID := a.clusterRouter(req); clusterID != "" && clusterID != "local" {
+		return nil
+	}
+	return func(namespace string) string {
+		ns, err := a.namespaceLister.Get("", namespace)
+		if err != nil {
+			return ""
+		}
+		return ns.Annotations[project.ProjectIDAnn]
+	}
+}
+
 func (a *tokenAuthenticator) TokenFromRequest(req *http.Request) (*v3.Token, error) {
 	tokenAuthValue := tokens.GetTokenAuthFromRequest(req)
 	if tokenAuthValue == "" {
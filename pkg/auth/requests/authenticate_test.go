@@ -0,0 +1,72 @@
+package requests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+func TestUpdateLastUsed(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	updated := make(chan *v3.Token)
+	a := &tokenAuthenticator{
+		tokenClient: &fakes.TokenInterfaceMock{
+			UpdateFunc: func(token *v3.Token) (*v3.Token, error) {
+				<-release
+				updated <- token
+				return token, nil
+			},
+		},
+		lastUsedUpdates: map[string]bool{},
+	}
+	waitUpdated := func() *v3.Token {
+		release <- struct{}{}
+		select {
+		case token := <-updated:
+			// the in-flight update is forgotten once Update returns
+			for i := 0; i < 100 && a.isUpdatingLastUsed(token.Name); i++ {
+				time.Sleep(time.Millisecond)
+			}
+			return token
+		case <-time.After(5 * time.Second):
+			assert.Fail("the last used time was not updated")
+			return nil
+		}
+	}
+	token := &v3.Token{ObjectMeta: metav1.ObjectMeta{Name: "token-abcde"}}
+
+	a.updateLastUsed(token)
+	a.updateLastUsed(token)
+	assert.True(a.isUpdatingLastUsed("token-abcde"))
+	if result := waitUpdated(); assert.NotNil(result) {
+		assert.NotEmpty(result.LastUsedAt)
+	}
+	assert.Empty(token.LastUsedAt, "the cached token is not modified")
+	select {
+	case release <- struct{}{}:
+		assert.Fail("tokens being updated are updated once")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	token.LastUsedAt = time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+	a.updateLastUsed(token)
+	assert.False(a.isUpdatingLastUsed("token-abcde"), "tokens used within the interval are not updated")
+
+	token.LastUsedAt = time.Now().Add(-lastUsedUpdateInterval).UTC().Format(time.RFC3339)
+	a.updateLastUsed(token)
+	assert.True(a.isUpdatingLastUsed("token-abcde"))
+	assert.NotNil(waitUpdated())
+}
+
+func (a *tokenAuthenticator) isUpdatingLastUsed(name string) bool {
+	a.lastUsedLock.Lock()
+	defer a.lastUsedLock.Unlock()
+	return a.lastUsedUpdates[name]
+}
@@ -6,6 +6,7 @@
 
 	"github.com/rancher/rancher/pkg/auth/audit"
 	"github.com/rancher/rancher/pkg/auth/util"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
 )
 
@@ -26,6 +27,10 @@ func (h authHeaderHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 		util.ReturnHTTPError(rw, req, 401, ErrMustAuthenticate.Error())
 		return
 	}
+	if userInfo.GetName() == scopeDeniedUser {
+		returnScopeDenied(rw, req, userInfo)
+		return
+	}
 
 	// clean extra
 	for header := range req.Header {
@@ -71,7 +76,21 @@ func (h authedFilter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			util.ReturnHTTPError(rw, req, 401, ErrMustAuthenticate.Error())
 			return
 		}
+		if userInfo.GetName() == scopeDeniedUser {
+			returnScopeDenied(rw, req, userInfo)
+			return
+		}
 	}
 
 	h.next.ServeHTTP(rw, req)
 }
+
+// returnScopeDenied rejects a request authenticated with a token whose scopes
+// do not allow it.
+func returnScopeDenied(rw http.ResponseWriter, req *http.Request, userInfo user.Info) {
+	msg := "token scopes do not allow the request"
+	if reason := userInfo.GetExtra()["reason"]; len(reason) > 0 {
+		msg = reason[0]
+	}
+	util.ReturnHTTPError(rw, req, http.StatusForbidden, msg)
+}
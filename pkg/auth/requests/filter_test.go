@@ -0,0 +1,49 @@
+package requests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAuthenticator struct {
+	err error
+}
+
+func (a *fakeAuthenticator) Authenticate(req *http.Request) (bool, string, []string, error) {
+	if a.err != nil {
+		return false, "", []string{}, a.err
+	}
+	return true, "u-abcde", []string{"system:authenticated"}, nil
+}
+
+func (a *fakeAuthenticator) TokenFromRequest(req *http.Request) (*v3.Token, error) {
+	return nil, ErrMustAuthenticate
+}
+
+func TestAuthenticatedFilterStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"authenticated", nil, http.StatusOK},
+		{"invalid token", ErrMustAuthenticate, http.StatusUnauthorized},
+		{"scope mismatch", &scopeError{errors.New("token scopes do not allow get secrets")}, http.StatusForbidden},
+	}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	for _, test := range tests {
+		auth := ToAuthMiddleware(&fakeAuthenticator{err: test.err})
+		for _, filter := range []func(http.Handler) http.Handler{NewAuthenticatedFilter, NewRequireAuthenticatedFilter("/v1/")} {
+			rw := httptest.NewRecorder()
+			auth(filter(next)).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/secrets", nil))
+			assert.Equal(test.expected, rw.Code, test.name)
+		}
+	}
+}
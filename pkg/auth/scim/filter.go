@@ -0,0 +1,47 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// filter is an equality filter on an attribute, the only kind of filter
+// identity providers use to look up resources.
+type filter struct {
+	attribute string
+	value     string
+}
+
+// parseFilter parses filters of the form `attribute eq "value"`, with
+// attribute one of attributes. The empty filter matches everything and
+// parses as nil.
+func parseFilter(expression string, attributes ...string) (*filter, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(expression, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return nil, invalidFilter("only filters of the form 'attribute eq \"value\"' are supported")
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return nil, invalidFilter("filter values must be quoted strings")
+	}
+	for _, attribute := range attributes {
+		if strings.EqualFold(parts[0], attribute) {
+			return &filter{attribute: attribute, value: value}, nil
+		}
+	}
+	return nil, invalidFilter("filtering on " + parts[0] + " is not supported")
+}
+
+// matches returns whether the attributes of a resource match f.
+func (f *filter) matches(attributes map[string]string) bool {
+	return f == nil || attributes[f.attribute] == f.value
+}
+
+func invalidFilter(detail string) *Error {
+	return newError(http.StatusBadRequest, "invalidFilter", detail)
+}
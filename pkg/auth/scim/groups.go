@@ -0,0 +1,602 @@
+package scim
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+const (
+	// groups are kept in config maps, and their members in the group
+	// principals of the user attributes of the members, under a key of their
+	// own so that logins and refreshes of the provider keep them
+	groupPrefix   = "scim-group-"
+	groupLabel    = "authn.management.cattle.io/scim-group"
+	providerLabel = "authn.management.cattle.io/scim-provider"
+
+	displayNameField = "displayName"
+	externalIDField  = "externalId"
+	principalIDField = "principalId"
+
+	groupPrincipalIndex = "authn.management.cattle.io/scim-group-principal-index"
+)
+
+// Group is a group of users of an auth provider.
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// group is a provisioned group.
+type group struct {
+	id          string
+	provider    string
+	principalID string
+	displayName string
+	externalID  string
+	created     metav1.Time
+}
+
+func groupPrincipal(provider, displayName, externalID string) string {
+	if externalID != "" {
+		return provider + "_group://" + externalID
+	}
+	return provider + "_group://" + displayName
+}
+
+func groupID(principalID string) string {
+	sum := sha256.Sum256([]byte(principalID))
+	return "g-" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:])[:10])
+}
+
+func fromConfigMap(configMap *corev1.ConfigMap) *group {
+	return &group{
+		id:          strings.TrimPrefix(configMap.Name, groupPrefix),
+		provider:    configMap.Labels[providerLabel],
+		principalID: configMap.Data[principalIDField],
+		displayName: configMap.Data[displayNameField],
+		externalID:  configMap.Data[externalIDField],
+		created:     configMap.CreationTimestamp,
+	}
+}
+
+func (g *group) principal() v32.Principal {
+	return v32.Principal{
+		ObjectMeta:    metav1.ObjectMeta{Name: g.principalID},
+		DisplayName:   g.displayName,
+		Provider:      g.provider,
+		PrincipalType: "group",
+		MemberOf:      true,
+	}
+}
+
+func (h *Handler) listGroupsOf(provider string) ([]*group, error) {
+	configMaps, err := h.configMapLister.List(namespace.System, labels.SelectorFromSet(labels.Set{
+		groupLabel:    "true",
+		providerLabel: provider,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var groups []*group
+	for _, configMap := range configMaps {
+		groups = append(groups, fromConfigMap(configMap))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].id < groups[j].id
+	})
+	return groups, nil
+}
+
+func (h *Handler) getGroupOf(provider, id string) (*group, error) {
+	// identity providers change groups right after creating them, before the
+	// cache may have them
+	configMap, err := h.configMaps.GetNamespaced(namespace.System, groupPrefix+id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && configMap.Labels[providerLabel] != provider) {
+		return nil, newError(http.StatusNotFound, "", "group "+id+" not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return fromConfigMap(configMap), nil
+}
+
+func (h *Handler) listGroups(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	f, err := parseFilter(req.URL.Query().Get("filter"), "id", "displayName", "externalId")
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	groups, err := h.listGroupsOf(provider)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	var matches []*group
+	for _, g := range groups {
+		if f.matches(map[string]string{
+			"id":          g.id,
+			"displayName": g.displayName,
+			"externalId":  g.externalID,
+		}) {
+			matches = append(matches, g)
+		}
+	}
+
+	start, end := page(req, len(matches))
+	resources := []Group{}
+	for _, g := range matches[start:end] {
+		resource, err := h.toSCIMGroup(req, g)
+		if err != nil {
+			writeError(rw, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	writeJSON(rw, http.StatusOK, ListResponse{
+		Schemas:      []string{listSchema},
+		TotalResults: len(matches),
+		StartIndex:   start + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (h *Handler) getGroup(rw http.ResponseWriter, req *http.Request) {
+	g, err := h.getGroupOf(mux.Vars(req)["provider"], mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeGroup(rw, req, http.StatusOK, g)
+}
+
+func (h *Handler) createGroup(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	var input Group
+	if err := readJSON(req, &input); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if input.DisplayName == "" {
+		writeError(rw, invalidValue("displayName is required"))
+		return
+	}
+	if err := h.checkMembers(provider, input.Members); err != nil {
+		writeError(rw, err)
+		return
+	}
+	principalID := groupPrincipal(provider, input.DisplayName, input.ExternalID)
+	configMap, err := h.configMaps.Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      groupPrefix + groupID(principalID),
+			Namespace: namespace.System,
+			Labels: map[string]string{
+				groupLabel:    "true",
+				providerLabel: provider,
+			},
+		},
+		Data: map[string]string{
+			displayNameField: input.DisplayName,
+			externalIDField:  input.ExternalID,
+			principalIDField: principalID,
+		},
+	})
+	if apierrors.IsAlreadyExists(err) {
+		writeError(rw, newError(http.StatusConflict, "uniqueness", "group "+input.DisplayName+" already exists"))
+		return
+	} else if err != nil {
+		writeError(rw, err)
+		return
+	}
+	g := fromConfigMap(configMap)
+	logrus.Infof("SCIM: provisioned group %s for principal %s", g.id, principalID)
+	if err := h.setMembers(g, input.Members); err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeGroup(rw, req, http.StatusCreated, g)
+}
+
+func (h *Handler) replaceGroup(rw http.ResponseWriter, req *http.Request) {
+	var input Group
+	if err := readJSON(req, &input); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if input.DisplayName == "" {
+		writeError(rw, invalidValue("displayName is required"))
+		return
+	}
+	g, err := h.getGroupOf(mux.Vars(req)["provider"], mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if err := h.checkMembers(g.provider, input.Members); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if g, err = h.updateGroup(g, input.DisplayName, input.ExternalID); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if err := h.setMembers(g, input.Members); err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeGroup(rw, req, http.StatusOK, g)
+}
+
+func (h *Handler) patchGroup(rw http.ResponseWriter, req *http.Request) {
+	var patch PatchRequest
+	if err := readJSON(req, &patch); err != nil {
+		writeError(rw, err)
+		return
+	}
+	g, err := h.getGroupOf(mux.Vars(req)["provider"], mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	for _, op := range patch.Operations {
+		if g, err = h.patchGroupAttributes(g, op); err != nil {
+			writeError(rw, err)
+			return
+		}
+	}
+	h.writeGroup(rw, req, http.StatusOK, g)
+}
+
+// deleteGroup deletes the group, removing it from the groups of its members.
+func (h *Handler) deleteGroup(rw http.ResponseWriter, req *http.Request) {
+	g, err := h.getGroupOf(mux.Vars(req)["provider"], mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if err := h.setMembers(g, nil); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if err := h.configMaps.DeleteNamespaced(namespace.System, groupPrefix+g.id, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		writeError(rw, err)
+		return
+	}
+	logrus.Infof("SCIM: deleted group %s", g.id)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// patchGroupAttributes applies op to g, returning the updated group.
+func (h *Handler) patchGroupAttributes(g *group, op PatchOperation) (*group, error) {
+	operation := strings.ToLower(op.Op)
+	if operation != "add" && operation != "replace" && operation != "remove" {
+		return nil, invalidValue("unknown operation " + op.Op)
+	}
+
+	path := strings.ToLower(op.Path)
+	// members[value eq "id"] selects a single member
+	if strings.HasPrefix(path, "members[") && strings.HasSuffix(path, "]") {
+		f, err := parseFilter(op.Path[len("members["):len(op.Path)-1], "value")
+		if err != nil {
+			return nil, err
+		}
+		if operation != "remove" {
+			return nil, invalidValue("members can only be removed by filter")
+		}
+		return g, h.removeMember(g, f.value)
+	}
+
+	values := map[string]json.RawMessage{}
+	if path == "" {
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return nil, invalidValue("operations without path must have an object value")
+		}
+	} else {
+		values[op.Path] = op.Value
+	}
+
+	displayName, externalID := g.displayName, g.externalID
+	for attribute, value := range values {
+		switch strings.ToLower(attribute) {
+		case "members":
+			var members []Reference
+			if len(value) > 0 && string(value) != "null" {
+				if err := json.Unmarshal(value, &members); err != nil {
+					return nil, invalidValue("members must be a list of references")
+				}
+			}
+			if err := h.patchMembers(g, operation, members); err != nil {
+				return nil, err
+			}
+		case "displayname":
+			if operation == "remove" {
+				return nil, invalidValue("displayName is required")
+			}
+			if err := json.Unmarshal(value, &displayName); err != nil {
+				return nil, invalidValue("displayName must be a string")
+			}
+		case "externalid":
+			externalID = ""
+			if operation != "remove" {
+				if err := json.Unmarshal(value, &externalID); err != nil {
+					return nil, invalidValue("externalId must be a string")
+				}
+			}
+		}
+	}
+	return h.updateGroup(g, displayName, externalID)
+}
+
+func (h *Handler) patchMembers(g *group, operation string, members []Reference) error {
+	switch {
+	case operation == "replace":
+		return h.setMembers(g, members)
+	case operation == "remove" && len(members) == 0:
+		return h.setMembers(g, nil)
+	}
+	for _, member := range members {
+		var err error
+		if operation == "add" {
+			err = h.addMember(g, member.Value)
+		} else {
+			err = h.removeMember(g, member.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateGroup sets the display name and external ID of g. When they change
+// the principal of the group, the members get the new principal.
+func (h *Handler) updateGroup(g *group, displayName, externalID string) (*group, error) {
+	if displayName == g.displayName && externalID == g.externalID {
+		return g, nil
+	}
+	updated := *g
+	updated.displayName = displayName
+	updated.externalID = externalID
+	updated.principalID = groupPrincipal(g.provider, displayName, externalID)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := h.configMaps.GetNamespaced(namespace.System, groupPrefix+g.id, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		configMap = configMap.DeepCopy()
+		configMap.Data[displayNameField] = displayName
+		configMap.Data[externalIDField] = externalID
+		configMap.Data[principalIDField] = updated.principalID
+		_, err = h.configMaps.Update(configMap)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := h.members(g)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		err := h.updateGroupPrincipals(member, g.provider, func(principals []v32.Principal) []v32.Principal {
+			return append(withoutPrincipal(principals, g.principalID), updated.principal())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &updated, nil
+}
+
+// members returns the IDs of the users who have the principal of g.
+func (h *Handler) members(g *group) ([]string, error) {
+	objs, err := h.userAttributeIndexer.ByIndex(groupPrincipalIndex, g.principalID)
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	for _, obj := range objs {
+		attrib, ok := obj.(*v3.UserAttribute)
+		if !ok {
+			continue
+		}
+		for _, principal := range attrib.GroupPrincipals[tokens.SCIMGroupPrincipalsKey(g.provider)].Items {
+			if principal.Name == g.principalID {
+				members = append(members, attrib.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// groupPrincipalIndexer indexes user attributes by the names of the group
+// principals SCIM provisioned.
+func groupPrincipalIndexer(obj interface{}) ([]string, error) {
+	attrib, ok := obj.(*v3.UserAttribute)
+	if !ok {
+		return []string{}, nil
+	}
+	var result []string
+	for key, principals := range attrib.GroupPrincipals {
+		if tokens.GroupPrincipalsProvider(key) == key {
+			continue
+		}
+		for _, principal := range principals.Items {
+			result = append(result, principal.Name)
+		}
+	}
+	return result, nil
+}
+
+// setMembers makes members the only members of g.
+func (h *Handler) setMembers(g *group, members []Reference) error {
+	keep := map[string]bool{}
+	for _, member := range members {
+		keep[member.Value] = true
+		if err := h.addMember(g, member.Value); err != nil {
+			return err
+		}
+	}
+	current, err := h.members(g)
+	if err != nil {
+		return err
+	}
+	for _, userID := range current {
+		if keep[userID] {
+			continue
+		}
+		if err := h.removeMember(g, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMembers checks that members are users of provider, so that groups are
+// not changed by requests that fail part way.
+func (h *Handler) checkMembers(provider string, members []Reference) error {
+	for _, member := range members {
+		if _, err := h.getProviderUser(provider, member.Value); err != nil {
+			if scimErr, ok := err.(*Error); ok && scimErr.Status == strconv.Itoa(http.StatusNotFound) {
+				return invalidValue("member " + member.Value + " is not a user of provider " + provider)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) addMember(g *group, userID string) error {
+	if err := h.checkMembers(g.provider, []Reference{{Value: userID}}); err != nil {
+		return err
+	}
+	return h.updateGroupPrincipals(userID, g.provider, func(principals []v32.Principal) []v32.Principal {
+		return append(withoutPrincipal(principals, g.principalID), g.principal())
+	})
+}
+
+func (h *Handler) removeMember(g *group, userID string) error {
+	err := h.updateGroupPrincipals(userID, g.provider, func(principals []v32.Principal) []v32.Principal {
+		return withoutPrincipal(principals, g.principalID)
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func withoutPrincipal(principals []v32.Principal, principalID string) []v32.Principal {
+	var result []v32.Principal
+	for _, principal := range principals {
+		if principal.Name != principalID {
+			result = append(result, principal)
+		}
+	}
+	return result
+}
+
+// updateGroupPrincipals updates the group principals SCIM provisioned in
+// provider for the user with userID, creating their user attribute if they have none yet.
+func (h *Handler) updateGroupPrincipals(userID, provider string, update func([]v32.Principal) []v32.Principal) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		attribs, err := h.userAttributes.Get(userID, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			user, err := h.users.Get(userID, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			attribs = &v3.UserAttribute{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: user.Name,
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "management.cattle.io/v3",
+						Kind:       "User",
+						UID:        user.UID,
+						Name:       user.Name,
+					}},
+				},
+				GroupPrincipals: map[string]v32.Principals{
+					tokens.SCIMGroupPrincipalsKey(provider): {Items: update(nil)},
+				},
+			}
+			_, err = h.userAttributes.Create(attribs)
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		attribs = attribs.DeepCopy()
+		if attribs.GroupPrincipals == nil {
+			attribs.GroupPrincipals = map[string]v32.Principals{}
+		}
+		key := tokens.SCIMGroupPrincipalsKey(provider)
+		attribs.GroupPrincipals[key] = v32.Principals{Items: update(attribs.GroupPrincipals[key].Items)}
+		_, err = h.userAttributes.Update(attribs)
+		return err
+	})
+}
+
+func (h *Handler) writeGroup(rw http.ResponseWriter, req *http.Request, status int, g *group) {
+	resource, err := h.toSCIMGroup(req, g)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	writeJSON(rw, status, resource)
+}
+
+func (h *Handler) toSCIMGroup(req *http.Request, g *group) (Group, error) {
+	result := Group{
+		Schemas:     []string{groupSchema},
+		ID:          g.id,
+		ExternalID:  g.externalID,
+		DisplayName: g.displayName,
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      g.created.UTC().Format(time.RFC3339),
+			Location:     location(req, "Groups", g.id),
+		},
+	}
+	if strings.Contains(req.URL.Query().Get("excludedAttributes"), "members") {
+		return result, nil
+	}
+	members, err := h.members(g)
+	if err != nil {
+		return result, err
+	}
+	for _, userID := range members {
+		member := Reference{Value: userID}
+		if user, err := h.userLister.Get("", userID); err == nil {
+			member.Display = user.DisplayName
+		}
+		result.Members = append(result.Members, member)
+	}
+	return result, nil
+}
@@ -0,0 +1,305 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	authv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	userSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	listSchema                  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	patchSchema                 = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	errorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	serviceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	contentType = "application/scim+json"
+
+	// maxResults is the most resources a list returns
+	maxResults = 200
+)
+
+type userManager interface {
+	EnsureUser(principalName, displayName string) (*v3.User, error)
+	GetUserByPrincipalID(principalName string) (*v3.User, error)
+}
+
+// Handler is a SCIM 2.0 server provisioning the users and groups of the
+// external auth providers, so that changes in the identity provider apply
+// before the users log in or get refreshed.
+//
+// Users are identified by the principal <provider>_user://<userName>, so the
+// identity provider must send as userName the ID the auth provider gives
+// users, e.g. the UID attribute for SAML or the object ID for Azure AD.
+// Groups are identified by the principal <provider>_group://<externalId>, or
+// by their display name when they have no external ID.
+type Handler struct {
+	users                v3.UserInterface
+	userLister           v3.UserLister
+	userAttributes       v3.UserAttributeInterface
+	userAttributeLister  v3.UserAttributeLister
+	userAttributeIndexer cache.Indexer
+	authConfigLister     v3.AuthConfigLister
+	configMaps           v1.ConfigMapInterface
+	configMapLister      v1.ConfigMapLister
+	userManager          userManager
+	authorize            func(req *http.Request) (bool, error)
+	now                  func() time.Time
+}
+
+func NewHandler(scaledContext *config.ScaledContext) http.Handler {
+	informer := scaledContext.Management.UserAttributes("").Controller().Informer()
+	informer.AddIndexers(map[string]cache.IndexFunc{groupPrincipalIndex: groupPrincipalIndexer})
+	h := &Handler{
+		users:                scaledContext.Management.Users(""),
+		userLister:           scaledContext.Management.Users("").Controller().Lister(),
+		userAttributes:       scaledContext.Management.UserAttributes(""),
+		userAttributeLister:  scaledContext.Management.UserAttributes("").Controller().Lister(),
+		userAttributeIndexer: informer.GetIndexer(),
+		authConfigLister:     scaledContext.Management.AuthConfigs("").Controller().Lister(),
+		configMaps:           scaledContext.Core.ConfigMaps(""),
+		configMapLister:      scaledContext.Core.ConfigMaps("").Controller().Lister(),
+		userManager:          scaledContext.UserManager,
+		authorize:            subjectAccessReview(scaledContext.K8sClient),
+		now:                  time.Now,
+	}
+	return h.router()
+}
+
+func (h *Handler) router() http.Handler {
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.Use(h.filter)
+
+	api := router.PathPrefix("/v1-scim/{provider}/v2").Subrouter()
+	api.Methods(http.MethodGet).Path("/ServiceProviderConfig").HandlerFunc(h.serviceProviderConfig)
+	api.Methods(http.MethodGet).Path("/Users").HandlerFunc(h.listUsers)
+	api.Methods(http.MethodPost).Path("/Users").HandlerFunc(h.createUser)
+	api.Methods(http.MethodGet).Path("/Users/{id}").HandlerFunc(h.getUser)
+	api.Methods(http.MethodPut).Path("/Users/{id}").HandlerFunc(h.replaceUser)
+	api.Methods(http.MethodPatch).Path("/Users/{id}").HandlerFunc(h.patchUser)
+	api.Methods(http.MethodDelete).Path("/Users/{id}").HandlerFunc(h.deleteUser)
+	api.Methods(http.MethodGet).Path("/Groups").HandlerFunc(h.listGroups)
+	api.Methods(http.MethodPost).Path("/Groups").HandlerFunc(h.createGroup)
+	api.Methods(http.MethodGet).Path("/Groups/{id}").HandlerFunc(h.getGroup)
+	api.Methods(http.MethodPut).Path("/Groups/{id}").HandlerFunc(h.replaceGroup)
+	api.Methods(http.MethodPatch).Path("/Groups/{id}").HandlerFunc(h.patchGroup)
+	api.Methods(http.MethodDelete).Path("/Groups/{id}").HandlerFunc(h.deleteGroup)
+
+	router.NotFoundHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeError(rw, newError(http.StatusNotFound, "", "not found"))
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeError(rw, newError(http.StatusMethodNotAllowed, "", "method not allowed"))
+	})
+	return router
+}
+
+// filter lets through the requests of users who can manage users, for
+// providers that are enabled.
+func (h *Handler) filter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		allowed, err := h.authorize(req)
+		if err != nil {
+			writeError(rw, err)
+			return
+		}
+		if !allowed {
+			writeError(rw, newError(http.StatusForbidden, "", "not allowed to provision users"))
+			return
+		}
+		if provider := mux.Vars(req)["provider"]; provider != "" {
+			if err := h.checkProvider(provider); err != nil {
+				writeError(rw, err)
+				return
+			}
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func (h *Handler) checkProvider(provider string) error {
+	if provider == "local" {
+		return newError(http.StatusNotFound, "", "local users can not be provisioned")
+	}
+	authConfig, err := h.authConfigLister.Get("", provider)
+	if apierrors.IsNotFound(err) {
+		return newError(http.StatusNotFound, "", "unknown auth provider "+provider)
+	} else if err != nil {
+		return err
+	}
+	if !authConfig.Enabled {
+		return newError(http.StatusNotFound, "", "auth provider "+provider+" is not enabled")
+	}
+	return nil
+}
+
+// subjectAccessReview allows the users who can create users and update their
+// attributes.
+func subjectAccessReview(k8sClient kubernetes.Interface) func(req *http.Request) (bool, error) {
+	return func(req *http.Request) (bool, error) {
+		for _, resource := range []string{"users", "userattributes"} {
+			review := authv1.SubjectAccessReview{
+				Spec: authv1.SubjectAccessReviewSpec{
+					User:   req.Header.Get("Impersonate-User"),
+					Groups: req.Header["Impersonate-Group"],
+					ResourceAttributes: &authv1.ResourceAttributes{
+						Verb:     "*",
+						Resource: resource,
+						Group:    "management.cattle.io",
+					},
+				},
+			}
+			result, err := k8sClient.AuthorizationV1().SubjectAccessReviews().Create(req.Context(), &review, metav1.CreateOptions{})
+			if err != nil {
+				return false, err
+			}
+			if !result.Status.Allowed {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func (h *Handler) serviceProviderConfig(rw http.ResponseWriter, req *http.Request) {
+	supported := func(supported bool) map[string]interface{} {
+		return map[string]interface{}{"supported": supported}
+	}
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{serviceProviderConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxResults},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Rancher API token of a user who can manage users",
+		}},
+	})
+}
+
+// Meta is the metadata of a resource.
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// ListResponse is a page of resources.
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchRequest is a list of changes to a resource.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation adds, replaces or removes the value at path, or the
+// attributes of value when there is no path.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Error is the body of the responses of failed requests.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func newError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func invalidValue(detail string) *Error {
+	return newError(http.StatusBadRequest, "invalidValue", detail)
+}
+
+func writeError(rw http.ResponseWriter, err error) {
+	scimErr, ok := err.(*Error)
+	if !ok {
+		logrus.Errorf("SCIM: %v", err)
+		scimErr = newError(http.StatusInternalServerError, "", err.Error())
+	}
+	status, _ := strconv.Atoi(scimErr.Status)
+	writeJSON(rw, status, scimErr)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body interface{}) {
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(body); err != nil {
+		logrus.Errorf("SCIM: failed to write response: %v", err)
+	}
+}
+
+func readJSON(req *http.Request, into interface{}) error {
+	if err := json.NewDecoder(req.Body).Decode(into); err != nil {
+		return newError(http.StatusBadRequest, "invalidSyntax", "invalid request body: "+err.Error())
+	}
+	return nil
+}
+
+// page returns the bounds of the page of a list of total resources the
+// startIndex and count query parameters select.
+func page(req *http.Request, total int) (start, end int) {
+	startIndex, err := strconv.Atoi(req.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(req.URL.Query().Get("count"))
+	if err != nil || count > maxResults {
+		count = maxResults
+	}
+	if count < 0 {
+		count = 0
+	}
+	start = startIndex - 1
+	if start > total {
+		start = total
+	}
+	end = start + count
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func location(req *http.Request, resourceType, id string) string {
+	return "/v1-scim/" + mux.Vars(req)["provider"] + "/v2/" + resourceType + "/" + id
+}
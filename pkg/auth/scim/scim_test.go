@@ -0,0 +1,299 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+type fakeUserManager struct {
+	users map[string]*v3.User
+}
+
+func (m *fakeUserManager) EnsureUser(principalName, displayName string) (*v3.User, error) {
+	user := &v3.User{
+		ObjectMeta:   metav1.ObjectMeta{Name: "u-" + strings.TrimPrefix(principalName, "okta_user://")},
+		DisplayName:  displayName,
+		PrincipalIDs: []string{principalName},
+	}
+	m.users[user.Name] = user
+	return user, nil
+}
+
+func (m *fakeUserManager) GetUserByPrincipalID(principalName string) (*v3.User, error) {
+	for _, user := range m.users {
+		for _, principalID := range user.PrincipalIDs {
+			if principalID == principalName {
+				return user, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+type testEnv struct {
+	handler    http.Handler
+	users      map[string]*v3.User
+	attributes map[string]*v3.UserAttribute
+	configMaps map[string]*corev1.ConfigMap
+	allowed    bool
+}
+
+func newTestEnv() *testEnv {
+	env := &testEnv{
+		users:      map[string]*v3.User{},
+		attributes: map[string]*v3.UserAttribute{},
+		configMaps: map[string]*corev1.ConfigMap{},
+		allowed:    true,
+	}
+	notFound := func(resource, name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+	getUser := func(name string) (*v3.User, error) {
+		if user, ok := env.users[name]; ok {
+			return user, nil
+		}
+		return nil, notFound("users", name)
+	}
+	getAttribute := func(name string) (*v3.UserAttribute, error) {
+		if attribs, ok := env.attributes[name]; ok {
+			return attribs, nil
+		}
+		return nil, notFound("userattributes", name)
+	}
+	attributeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{groupPrincipalIndex: groupPrincipalIndexer})
+	saveAttribute := func(attribs *v3.UserAttribute) (*v3.UserAttribute, error) {
+		env.attributes[attribs.Name] = attribs
+		return attribs, attributeIndexer.Update(attribs)
+	}
+	getConfigMap := func(namespace, name string) (*corev1.ConfigMap, error) {
+		if configMap, ok := env.configMaps[name]; ok {
+			return configMap, nil
+		}
+		return nil, notFound("configmaps", name)
+	}
+
+	h := &Handler{
+		users: &fakes.UserInterfaceMock{
+			GetFunc: func(name string, opts metav1.GetOptions) (*v3.User, error) {
+				return getUser(name)
+			},
+			UpdateFunc: func(user *v3.User) (*v3.User, error) {
+				env.users[user.Name] = user
+				return user, nil
+			},
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				delete(env.users, name)
+				return nil
+			},
+		},
+		userLister: &fakes.UserListerMock{
+			GetFunc: func(namespace, name string) (*v3.User, error) {
+				return getUser(name)
+			},
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.User, error) {
+				var result []*v3.User
+				for _, user := range env.users {
+					result = append(result, user)
+				}
+				return result, nil
+			},
+		},
+		userAttributes: &fakes.UserAttributeInterfaceMock{
+			GetFunc: func(name string, opts metav1.GetOptions) (*v3.UserAttribute, error) {
+				return getAttribute(name)
+			},
+			CreateFunc: saveAttribute,
+			UpdateFunc: saveAttribute,
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace, name string) (*v3.UserAttribute, error) {
+				return getAttribute(name)
+			},
+		},
+		userAttributeIndexer: attributeIndexer,
+		authConfigLister: &fakes.AuthConfigListerMock{
+			GetFunc: func(namespace, name string) (*v3.AuthConfig, error) {
+				switch name {
+				case "okta":
+					return &v3.AuthConfig{Enabled: true}, nil
+				case "github":
+					return &v3.AuthConfig{}, nil
+				}
+				return nil, notFound("authconfigs", name)
+			},
+		},
+		configMaps: &corefakes.ConfigMapInterfaceMock{
+			GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*corev1.ConfigMap, error) {
+				return getConfigMap(namespace, name)
+			},
+			CreateFunc: func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+				if _, ok := env.configMaps[configMap.Name]; ok {
+					return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, configMap.Name)
+				}
+				env.configMaps[configMap.Name] = configMap
+				return configMap, nil
+			},
+			UpdateFunc: func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+				env.configMaps[configMap.Name] = configMap
+				return configMap, nil
+			},
+			DeleteNamespacedFunc: func(namespace, name string, options *metav1.DeleteOptions) error {
+				delete(env.configMaps, name)
+				return nil
+			},
+		},
+		configMapLister: &corefakes.ConfigMapListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.ConfigMap, error) {
+				var result []*corev1.ConfigMap
+				for _, configMap := range env.configMaps {
+					if selector.Matches(labels.Set(configMap.Labels)) {
+						result = append(result, configMap)
+					}
+				}
+				return result, nil
+			},
+		},
+		userManager: &fakeUserManager{users: env.users},
+		authorize: func(req *http.Request) (bool, error) {
+			return env.allowed, nil
+		},
+	}
+	env.handler = h.router()
+	return env
+}
+
+func (env *testEnv) do(method, path, body string, into interface{}) int {
+	req := httptest.NewRequest(method, "/v1-scim/okta/v2"+path, strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	env.handler.ServeHTTP(rw, req)
+	if into != nil {
+		json.Unmarshal(rw.Body.Bytes(), into)
+	}
+	return rw.Code
+}
+
+func (env *testEnv) groupPrincipals(userID string) []string {
+	var result []string
+	if attribs, ok := env.attributes[userID]; ok {
+		for _, principal := range attribs.GroupPrincipals[tokens.SCIMGroupPrincipalsKey("okta")].Items {
+			result = append(result, principal.Name)
+		}
+	}
+	return result
+}
+
+func TestUsers(t *testing.T) {
+	assert := assert.New(t)
+	env := newTestEnv()
+
+	var user User
+	assert.Equal(http.StatusCreated, env.do(http.MethodPost, "/Users", `{"userName":"jdoe","externalId":"00u1","name":{"givenName":"John","familyName":"Doe"}}`, &user))
+	assert.Equal("u-jdoe", user.ID)
+	assert.Equal("John Doe", user.DisplayName)
+	assert.True(*user.Active)
+	assert.Equal([]string{"okta_user://jdoe"}, env.users["u-jdoe"].PrincipalIDs)
+	assert.Equal(http.StatusConflict, env.do(http.MethodPost, "/Users", `{"userName":"jdoe"}`, nil))
+	assert.Equal(http.StatusBadRequest, env.do(http.MethodPost, "/Users", `{"displayName":"nobody"}`, nil))
+
+	env.users["u-local"] = &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-local"}, Username: "admin", PrincipalIDs: []string{"local://u-local"}}
+	var list ListResponse
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, "/Users", "", &list))
+	assert.Equal(1, list.TotalResults, "only users of the provider are listed")
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, `/Users?filter=userName+eq+"jdoe"`, "", &list))
+	assert.Equal(1, list.TotalResults)
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, `/Users?filter=externalId+eq+"00u2"`, "", &list))
+	assert.Equal(0, list.TotalResults)
+	assert.Equal(http.StatusBadRequest, env.do(http.MethodGet, `/Users?filter=emails+co+"example"`, "", nil))
+	assert.Equal(http.StatusNotFound, env.do(http.MethodGet, "/Users/u-local", "", nil))
+
+	assert.Equal(http.StatusOK, env.do(http.MethodPatch, "/Users/u-jdoe", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`, &user))
+	assert.False(*user.Active, "booleans may come as strings")
+	assert.False(*env.users["u-jdoe"].Enabled)
+	assert.Equal(http.StatusOK, env.do(http.MethodPatch, "/Users/u-jdoe", `{"Operations":[{"op":"replace","value":{"active":true,"displayName":"Johnny"}}]}`, &user))
+	assert.True(*env.users["u-jdoe"].Enabled)
+	assert.Equal("Johnny", user.DisplayName)
+
+	var replaced User
+	assert.Equal(http.StatusOK, env.do(http.MethodPut, "/Users/u-jdoe", `{"userName":"jdoe","displayName":"John","active":false}`, &replaced))
+	assert.Equal("", replaced.ExternalID)
+	assert.False(*env.users["u-jdoe"].Enabled)
+	assert.Equal(http.StatusBadRequest, env.do(http.MethodPut, "/Users/u-jdoe", `{"userName":"jane"}`, nil))
+
+	assert.Equal(http.StatusNoContent, env.do(http.MethodDelete, "/Users/u-jdoe", "", nil))
+	assert.Equal(http.StatusNotFound, env.do(http.MethodGet, "/Users/u-jdoe", "", nil))
+}
+
+func TestGroups(t *testing.T) {
+	assert := assert.New(t)
+	env := newTestEnv()
+	env.do(http.MethodPost, "/Users", `{"userName":"jdoe"}`, nil)
+	env.do(http.MethodPost, "/Users", `{"userName":"jane"}`, nil)
+
+	var group Group
+	assert.Equal(http.StatusCreated, env.do(http.MethodPost, "/Groups", `{"displayName":"Engineering","members":[{"value":"u-jdoe"}]}`, &group))
+	assert.Equal([]Reference{{Value: "u-jdoe", Display: "jdoe"}}, group.Members)
+	assert.Equal([]string{"okta_group://Engineering"}, env.groupPrincipals("u-jdoe"))
+	assert.Equal(http.StatusConflict, env.do(http.MethodPost, "/Groups", `{"displayName":"Engineering"}`, nil))
+	assert.Equal(http.StatusBadRequest, env.do(http.MethodPost, "/Groups", `{"displayName":"Sales","members":[{"value":"u-nobody"}]}`, nil))
+
+	var user User
+	env.do(http.MethodGet, "/Users/u-jdoe", "", &user)
+	assert.Equal([]Reference{{Value: group.ID, Display: "Engineering"}}, user.Groups)
+
+	path := "/Groups/" + group.ID
+	assert.Equal(http.StatusOK, env.do(http.MethodPatch, path, `{"Operations":[{"op":"add","path":"members","value":[{"value":"u-jane"}]}]}`, &group))
+	assert.Len(group.Members, 2)
+	assert.Equal(http.StatusOK, env.do(http.MethodPatch, path, `{"Operations":[{"op":"remove","path":"members[value eq \"u-jdoe\"]"}]}`, &group))
+	assert.Equal([]Reference{{Value: "u-jane", Display: "jane"}}, group.Members)
+	assert.Empty(env.groupPrincipals("u-jdoe"))
+
+	// a login replaces the group principals of the provider
+	env.attributes["u-jane"].GroupPrincipals["okta"] = v32.Principals{}
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, path, "", &group))
+	assert.Equal([]Reference{{Value: "u-jane", Display: "jane"}}, group.Members, "logins keep the members")
+
+	assert.Equal(http.StatusOK, env.do(http.MethodPatch, path, `{"Operations":[{"op":"replace","value":{"displayName":"R&D","externalId":"00g1"}}]}`, &group))
+	assert.Equal("R&D", group.DisplayName)
+	assert.Equal([]string{"okta_group://00g1"}, env.groupPrincipals("u-jane"), "members get the new principal")
+
+	var list ListResponse
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, `/Groups?filter=displayName+eq+"R%26D"`, "", &list))
+	assert.Equal(1, list.TotalResults)
+
+	assert.Equal(http.StatusNoContent, env.do(http.MethodDelete, path, "", nil))
+	assert.Empty(env.groupPrincipals("u-jane"))
+	assert.Empty(env.configMaps)
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+	env := newTestEnv()
+
+	assert.Equal(http.StatusOK, env.do(http.MethodGet, "/ServiceProviderConfig", "", nil))
+	env.allowed = false
+	assert.Equal(http.StatusForbidden, env.do(http.MethodGet, "/Users", "", nil))
+	env.allowed = true
+
+	for _, provider := range []string{"local", "github", "unknown"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1-scim/"+provider+"/v2/Users", nil)
+		rw := httptest.NewRecorder()
+		env.handler.ServeHTTP(rw, req)
+		assert.Equal(http.StatusNotFound, rw.Code, provider)
+	}
+}
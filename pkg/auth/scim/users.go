@@ -0,0 +1,413 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+const externalIDAnnotation = "authn.management.cattle.io/scim-external-id"
+
+// User is a user of an auth provider.
+type User struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Name        *Name       `json:"name,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Groups      []Reference `json:"groups,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// Name is the name of a user, which becomes their display name when they have
+// none.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Reference is a member of a group, or a group of a user.
+type Reference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+func userPrincipal(provider, userName string) string {
+	return provider + "_user://" + userName
+}
+
+// userName returns the name of the principal of user in provider, if they
+// have one.
+func userName(provider string, user *v3.User) (string, bool) {
+	prefix := userPrincipal(provider, "")
+	for _, principalID := range user.PrincipalIDs {
+		if strings.HasPrefix(principalID, prefix) {
+			return strings.TrimPrefix(principalID, prefix), true
+		}
+	}
+	return "", false
+}
+
+// displayName returns the name to display for u.
+func (u *User) displayName() string {
+	switch {
+	case u.DisplayName != "":
+		return u.DisplayName
+	case u.Name != nil && u.Name.Formatted != "":
+		return u.Name.Formatted
+	case u.Name != nil && (u.Name.GivenName != "" || u.Name.FamilyName != ""):
+		return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	}
+	return u.UserName
+}
+
+func (h *Handler) listUsers(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	f, err := parseFilter(req.URL.Query().Get("filter"), "id", "userName", "externalId", "displayName")
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	users, err := h.userLister.List("", labels.Everything())
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	var matches []*v3.User
+	for _, user := range users {
+		name, ok := userName(provider, user)
+		if !ok {
+			continue
+		}
+		if f.matches(map[string]string{
+			"id":          user.Name,
+			"userName":    name,
+			"externalId":  user.Annotations[externalIDAnnotation],
+			"displayName": user.DisplayName,
+		}) {
+			matches = append(matches, user)
+		}
+	}
+
+	groups, err := h.listGroupsOf(provider)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	start, end := page(req, len(matches))
+	resources := []User{}
+	for _, user := range matches[start:end] {
+		resources = append(resources, h.toSCIMUser(req, provider, user, groups))
+	}
+	writeJSON(rw, http.StatusOK, ListResponse{
+		Schemas:      []string{listSchema},
+		TotalResults: len(matches),
+		StartIndex:   start + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (h *Handler) getUser(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	user, err := h.getProviderUser(provider, mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeUser(rw, req, http.StatusOK, provider, user)
+}
+
+func (h *Handler) createUser(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	var input User
+	if err := readJSON(req, &input); err != nil {
+		writeError(rw, err)
+		return
+	}
+	if input.UserName == "" {
+		writeError(rw, invalidValue("userName is required"))
+		return
+	}
+	principalID := userPrincipal(provider, input.UserName)
+	existing, err := h.userManager.GetUserByPrincipalID(principalID)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if existing != nil {
+		writeError(rw, newError(http.StatusConflict, "uniqueness", "user "+input.UserName+" already exists with id "+existing.Name))
+		return
+	}
+
+	user, err := h.userManager.EnsureUser(principalID, input.displayName())
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	logrus.Infof("SCIM: provisioned user %s for principal %s", user.Name, principalID)
+	user, err = h.updateUser(user.Name, func(user *v3.User) error {
+		setExternalID(user, input.ExternalID)
+		setActive(user, input.Active == nil || *input.Active)
+		return nil
+	})
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeUser(rw, req, http.StatusCreated, provider, user)
+}
+
+func (h *Handler) replaceUser(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	var input User
+	if err := readJSON(req, &input); err != nil {
+		writeError(rw, err)
+		return
+	}
+	user, err := h.getProviderUser(provider, mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if name, _ := userName(provider, user); input.UserName != "" && input.UserName != name {
+		writeError(rw, newError(http.StatusBadRequest, "mutability", "userName can not be changed"))
+		return
+	}
+	user, err = h.updateUser(user.Name, func(user *v3.User) error {
+		user.DisplayName = input.displayName()
+		if user.DisplayName == "" {
+			user.DisplayName, _ = userName(provider, user)
+		}
+		setExternalID(user, input.ExternalID)
+		setActive(user, input.Active == nil || *input.Active)
+		return nil
+	})
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeUser(rw, req, http.StatusOK, provider, user)
+}
+
+func (h *Handler) patchUser(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	var patch PatchRequest
+	if err := readJSON(req, &patch); err != nil {
+		writeError(rw, err)
+		return
+	}
+	user, err := h.getProviderUser(provider, mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	user, err = h.updateUser(user.Name, func(user *v3.User) error {
+		for _, op := range patch.Operations {
+			if err := patchUserAttributes(user, op); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h.writeUser(rw, req, http.StatusOK, provider, user)
+}
+
+// deleteUser deletes the user, who loses their bindings as well as their
+// access. Identity providers usually deactivate users instead.
+func (h *Handler) deleteUser(rw http.ResponseWriter, req *http.Request) {
+	provider := mux.Vars(req)["provider"]
+	user, err := h.getProviderUser(provider, mux.Vars(req)["id"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if err := h.users.Delete(user.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		writeError(rw, err)
+		return
+	}
+	logrus.Infof("SCIM: deleted user %s", user.Name)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// patchUserAttributes applies op to the attributes of user. Changes to
+// attributes Rancher does not keep are ignored.
+func patchUserAttributes(user *v3.User, op PatchOperation) error {
+	operation := strings.ToLower(op.Op)
+	if operation != "add" && operation != "replace" && operation != "remove" {
+		return invalidValue("unknown operation " + op.Op)
+	}
+	values := map[string]json.RawMessage{}
+	if op.Path == "" {
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return invalidValue("operations without path must have an object value")
+		}
+	} else {
+		values[op.Path] = op.Value
+	}
+	for path, value := range values {
+		switch strings.ToLower(path) {
+		case "active":
+			if operation == "remove" {
+				continue
+			}
+			active, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			setActive(user, active)
+		case "displayname":
+			if operation == "remove" {
+				user.DisplayName = ""
+				continue
+			}
+			if err := json.Unmarshal(value, &user.DisplayName); err != nil {
+				return invalidValue("displayName must be a string")
+			}
+		case "externalid":
+			var externalID string
+			if operation != "remove" {
+				if err := json.Unmarshal(value, &externalID); err != nil {
+					return invalidValue("externalId must be a string")
+				}
+			}
+			setExternalID(user, externalID)
+		}
+	}
+	return nil
+}
+
+// parseBool parses booleans, which some identity providers send as strings.
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, invalidValue("active must be a boolean")
+}
+
+func setExternalID(user *v3.User, externalID string) {
+	if externalID == "" {
+		delete(user.Annotations, externalIDAnnotation)
+		return
+	}
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[externalIDAnnotation] = externalID
+}
+
+// setActive enables or disables user. Disabled users can not log in, and lose
+// their tokens.
+func setActive(user *v3.User, active bool) {
+	if user.Enabled == nil || *user.Enabled != active {
+		if active {
+			logrus.Infof("SCIM: activating user %s", user.Name)
+		} else {
+			logrus.Infof("SCIM: deactivating user %s", user.Name)
+		}
+	}
+	user.Enabled = &active
+}
+
+// getProviderUser returns the user with id, if they are a user of provider.
+// Users are read from the API, as identity providers change them right after
+// creating them.
+func (h *Handler) getProviderUser(provider, id string) (*v3.User, error) {
+	user, err := h.users.Get(id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, newError(http.StatusNotFound, "", "user "+id+" not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if _, ok := userName(provider, user); !ok {
+		return nil, newError(http.StatusNotFound, "", "user "+id+" not found")
+	}
+	return user, nil
+}
+
+// updateUser applies update to the latest version of the user with name,
+// retrying on conflicts.
+func (h *Handler) updateUser(name string, update func(user *v3.User) error) (*v3.User, error) {
+	var result *v3.User
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		user, err := h.users.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		user = user.DeepCopy()
+		if err := update(user); err != nil {
+			return err
+		}
+		result, err = h.users.Update(user)
+		return err
+	})
+	return result, err
+}
+
+func (h *Handler) writeUser(rw http.ResponseWriter, req *http.Request, status int, provider string, user *v3.User) {
+	groups, err := h.listGroupsOf(provider)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	writeJSON(rw, status, h.toSCIMUser(req, provider, user, groups))
+}
+
+func (h *Handler) toSCIMUser(req *http.Request, provider string, user *v3.User, groups []*group) User {
+	name, _ := userName(provider, user)
+	active := user.Enabled == nil || *user.Enabled
+	result := User{
+		Schemas:     []string{userSchema},
+		ID:          user.Name,
+		ExternalID:  user.Annotations[externalIDAnnotation],
+		UserName:    name,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      user.CreationTimestamp.UTC().Format(time.RFC3339),
+			Location:     location(req, "Users", user.Name),
+		},
+	}
+	attribs, err := h.userAttributeLister.Get("", user.Name)
+	if err != nil {
+		return result
+	}
+	for _, principal := range attribs.GroupPrincipals[tokens.SCIMGroupPrincipalsKey(provider)].Items {
+		for _, g := range groups {
+			if g.principalID == principal.Name {
+				result.Groups = append(result.Groups, Reference{Value: g.id, Display: g.displayName})
+			}
+		}
+	}
+	return result
+}
@@ -7,7 +7,9 @@
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/rancher/norman/httperror"
@@ -102,6 +104,10 @@ func (m *Manager) createDerivedToken(jsonInput clientv3.Token, tokenAuthValue st
 	if err != nil {
 		return v3.Token{}, "", 401, err
 	}
+	// a token derived from a scoped token would not be restricted by its scopes
+	if token.Scopes != nil {
+		return v3.Token{}, "", 403, fmt.Errorf("scoped tokens cannot create tokens")
+	}
 
 	tokenTTL, err := ValidateMaxTTL(time.Duration(int64(jsonInput.TTLMillis)) * time.Millisecond)
 	if err != nil {
@@ -118,6 +124,7 @@ func (m *Manager) createDerivedToken(jsonInput clientv3.Token, tokenAuthValue st
 		ProviderInfo:  token.ProviderInfo,
 		Description:   jsonInput.Description,
 		ClusterName:   jsonInput.ClusterID,
+		Scopes:        toTokenScopes(jsonInput.Scopes),
 	}
 	derivedToken, unhashedTokenKey, err = m.createToken(&derivedToken)
 
@@ -125,6 +132,25 @@ func (m *Manager) createDerivedToken(jsonInput clientv3.Token, tokenAuthValue st
 
 }
 
+func toTokenScopes(scopes *clientv3.TokenScopes) *v32.TokenScopes {
+	if scopes == nil {
+		return nil
+	}
+	result := &v32.TokenScopes{
+		ProjectIDs: scopes.ProjectIDs,
+		Namespaces: scopes.Namespaces,
+		ReadOnly:   scopes.ReadOnly,
+	}
+	for _, rule := range scopes.Rules {
+		result.Rules = append(result.Rules, v32.TokenScopeRule{
+			APIGroups: rule.APIGroups,
+			Resources: rule.Resources,
+			Verbs:     rule.Verbs,
+		})
+	}
+	return result
+}
+
 // createToken returns the token object and it's unhashed token key, which is stored hashed
 func (m *Manager) createToken(k8sToken *v3.Token) (v3.Token, string, error) {
 	key, err := randomtoken.Generate()
@@ -217,19 +243,23 @@ func (m *Manager) getTokens(tokenAuthValue string) ([]v3.Token, int, error) {
 	return tokens, 0, nil
 }
 
-func (m *Manager) deleteToken(tokenAuthValue string) (int, error) {
+func (m *Manager) deleteToken(tokenAuthValue string) (*v3.Token, int, error) {
 	logrus.Debug("DELETE Token Invoked")
 
 	storedToken, status, err := m.getToken(tokenAuthValue)
 	if err != nil {
 		if status == 404 {
-			return 0, nil
+			return nil, 0, nil
 		} else if status != 410 {
-			return 401, err
+			return nil, 401, err
 		}
 	}
 
-	return m.deleteTokenByName(storedToken.Name)
+	status, err = m.deleteTokenByName(storedToken.Name)
+	if err != nil {
+		return nil, status, err
+	}
+	return storedToken, 0, nil
 }
 
 func (m *Manager) deleteTokenByName(tokenName string) (int, error) {
@@ -352,13 +382,21 @@ func (m *Manager) listTokens(request *types.APIContext) error {
 }
 
 func (m *Manager) logout(actionName string, action *types.Action, request *types.APIContext) error {
-	r := request.Request
-	w := request.Response
+	request.Response.Header().Add("Content-type", "application/json")
+	if _, status, err := m.EndSession(request.Response, request.Request); err != nil {
+		return httperror.NewAPIErrorLong(status, util.GetHTTPErrorCode(status), fmt.Sprintf("%v", err))
+	}
+	return nil
+}
 
+// EndSession deletes the token of the request and clears the session cookies.
+// It returns the deleted token, which is nil if it no longer existed, along
+// with the http status of any error.
+func (m *Manager) EndSession(w http.ResponseWriter, r *http.Request) (*v3.Token, int, error) {
 	tokenAuthValue := GetTokenAuthFromRequest(r)
 	if tokenAuthValue == "" {
 		// no cookie or auth header, cannot authenticate
-		return httperror.NewAPIErrorLong(http.StatusUnauthorized, util.GetHTTPErrorCode(http.StatusUnauthorized), "No valid token cookie or auth header")
+		return nil, http.StatusUnauthorized, errors.New("No valid token cookie or auth header")
 	}
 
 	isSecure := false
@@ -378,18 +416,16 @@ func (m *Manager) logout(actionName string, action *types.Action, request *types
 		}
 		http.SetCookie(w, tokenCookie)
 	}
-	w.Header().Add("Content-type", "application/json")
 
-	//getToken
-	status, err := m.deleteToken(tokenAuthValue)
+	storedToken, status, err := m.deleteToken(tokenAuthValue)
 	if err != nil {
 		logrus.Errorf("DeleteToken failed with error: %v", err)
 		if status == 0 {
 			status = http.StatusInternalServerError
 		}
-		return httperror.NewAPIErrorLong(status, util.GetHTTPErrorCode(status), fmt.Sprintf("%v", err))
+		return nil, status, err
 	}
-	return nil
+	return storedToken, 0, nil
 }
 
 func (m *Manager) getTokenFromRequest(request *types.APIContext) error {
@@ -573,7 +609,27 @@ func (m *Manager) EnsureAndGetUserAttribute(userID string) (*v3.UserAttribute, b
 	return attribs, true, nil
 }
 
-func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrincipals []v3.Principal) error {
+// UserAttributeCreateOrUpdate records the group principals and the
+// attributes the provider told on the login of a user. Attributes are left
+// unchanged when extras is nil, as most providers do not tell any.
+// scimGroupPrincipalsPrefix prefixes the provider in the keys of the group
+// principals SCIM provisions in user attributes.
+const scimGroupPrincipalsPrefix = "scim:"
+
+// SCIMGroupPrincipalsKey returns the key of the group principals SCIM
+// provisions for provider in user attributes. They are kept apart from the
+// group principals of provider itself, which logins and refreshes replace.
+func SCIMGroupPrincipalsKey(provider string) string {
+	return scimGroupPrincipalsPrefix + provider
+}
+
+// GroupPrincipalsProvider returns the provider of the group principals kept
+// under key in user attributes.
+func GroupPrincipalsProvider(key string) string {
+	return strings.TrimPrefix(key, scimGroupPrincipalsPrefix)
+}
+
+func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrincipals []v3.Principal, extras map[string][]string) error {
 	attribs, needCreate, err := m.EnsureAndGetUserAttribute(userID)
 	if err != nil {
 		return err
@@ -581,6 +637,7 @@ func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrin
 
 	if needCreate {
 		attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
+		setExtras(attribs, provider, extras)
 		_, err := m.userAttributes.Create(attribs)
 		if err != nil {
 			return err
@@ -589,8 +646,10 @@ func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrin
 	}
 
 	// Exists, just update if necessary
-	if m.UserAttributeChanged(attribs, provider, groupPrincipals) {
+	extrasChanged := extras != nil && !reflect.DeepEqual(attribs.ExtraByProvider[provider], extras)
+	if m.UserAttributeChanged(attribs, provider, groupPrincipals) || extrasChanged {
 		attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
+		setExtras(attribs, provider, extras)
 		_, err := m.userAttributes.Update(attribs)
 		if err != nil {
 			return err
@@ -600,6 +659,16 @@ func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrin
 	return nil
 }
 
+func setExtras(attribs *v32.UserAttribute, provider string, extras map[string][]string) {
+	if extras == nil {
+		return
+	}
+	if attribs.ExtraByProvider == nil {
+		attribs.ExtraByProvider = map[string]map[string][]string{}
+	}
+	attribs.ExtraByProvider[provider] = extras
+}
+
 func (m *Manager) UserAttributeChanged(attribs *v32.UserAttribute, provider string, groupPrincipals []v32.Principal) bool {
 	oldSet := []string{}
 	newSet := []string{}
@@ -632,9 +701,21 @@ func (m *Manager) UserAttributeChanged(attribs *v32.UserAttribute, provider stri
 	Steps:    5,
 }
 
-func (m *Manager) NewLoginToken(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string) (v3.Token, string, error) {
+// NewLoginToken creates the token of a login session of the user, recording
+// the client of the login request req.
+func (m *Manager) NewLoginToken(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string, req *http.Request) (v3.Token, string, error) {
+	return m.NewLoginTokenWithProviderInfo(userID, userPrincipal, groupPrincipals, providerToken, ttl, description, req, nil, nil, nil)
+}
+
+// NewLoginTokenWithProviderInfo is NewLoginToken for providers that need to
+// find the sessions of a login again, such as to end them when the identity
+// provider logs the user out, or that tell attributes of the user. providerInfo
+// is stored on the token and labels are added to it, extras are recorded on
+// the attributes of the user for principal mapping rules to match.
+func (m *Manager) NewLoginTokenWithProviderInfo(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string, req *http.Request,
+	providerInfo map[string]string, labels map[string]string, extras map[string][]string) (v3.Token, string, error) {
 	provider := userPrincipal.Provider
-	if (provider == "github" || provider == "azuread" || provider == "googleoauth") && providerToken != "" {
+	if (provider == "github" || provider == "azuread" || provider == "googleoauth" || provider == "oidc") && providerToken != "" {
 		err := m.CreateSecret(userID, provider, providerToken)
 		if err != nil {
 			return v3.Token{}, "", fmt.Errorf("unable to create secret: %s", err)
@@ -642,7 +723,7 @@ func (m *Manager) NewLoginToken(userID string, userPrincipal v3.Principal, group
 	}
 
 	err := wait.ExponentialBackoff(uaBackoff, func() (bool, error) {
-		err := m.UserAttributeCreateOrUpdate(userID, provider, groupPrincipals)
+		err := m.UserAttributeCreateOrUpdate(userID, provider, groupPrincipals, extras)
 		if err != nil {
 			logrus.Warnf("Problem creating or updating userAttribute for %v: %v", userID, err)
 		}
@@ -660,12 +741,20 @@ func (m *Manager) NewLoginToken(userID string, userPrincipal v3.Principal, group
 		UserID:        userID,
 		AuthProvider:  provider,
 		Description:   description,
+		ProviderInfo:  providerInfo,
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: map[string]string{
 				TokenKindLabel: "session",
 			},
 		},
 	}
+	for k, v := range labels {
+		token.Labels[k] = v
+	}
+	if req != nil {
+		token.ClientIP = util.GetClientIP(req)
+		token.UserAgent = req.UserAgent()
+	}
 	return m.createToken(token)
 }
 
@@ -734,7 +823,7 @@ func (m *Manager) IsMemberOf(token v3.Token, group v3.Principal) bool {
 }
 
 func (m *Manager) CreateTokenAndSetCookie(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int, description string, request *types.APIContext) error {
-	token, unhashedTokenKey, err := m.NewLoginToken(userID, userPrincipal, groupPrincipals, providerToken, 0, description)
+	token, unhashedTokenKey, err := m.NewLoginToken(userID, userPrincipal, groupPrincipals, providerToken, 0, description, request.Request)
 	if err != nil {
 		logrus.Errorf("Failed creating token with error: %v", err)
 		return httperror.NewAPIErrorLong(500, "", fmt.Sprintf("Failed creating token with error: %v", err))
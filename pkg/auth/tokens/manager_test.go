@@ -7,7 +7,9 @@
 	"time"
 
 	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
 	"github.com/rancher/wrangler/pkg/randomtoken"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -120,6 +122,38 @@ func TestTokenStreamTransformer(t *testing.T) {
 	}
 }
 
+// TestUserAttributeCreateOrUpdateKeepsSCIMGroups validates that logins keep the
+// group principals SCIM provisioned for the provider
+func TestUserAttributeCreateOrUpdateKeepsSCIMGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	scimKey := SCIMGroupPrincipalsKey("okta")
+	attribs := &v3.UserAttribute{
+		ObjectMeta: v1.ObjectMeta{Name: "u-jdoe"},
+		GroupPrincipals: map[string]v32.Principals{
+			scimKey: {Items: []v32.Principal{{ObjectMeta: v1.ObjectMeta{Name: "okta_group://Engineering"}}}},
+		},
+	}
+	tokenManager := Manager{
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace, name string) (*v3.UserAttribute, error) {
+				return attribs, nil
+			},
+		},
+		userAttributes: &fakes.UserAttributeInterfaceMock{
+			UpdateFunc: func(updated *v3.UserAttribute) (*v3.UserAttribute, error) {
+				attribs = updated
+				return updated, nil
+			},
+		},
+	}
+
+	groups := []v3.Principal{{ObjectMeta: v1.ObjectMeta{Name: "okta_group://Everyone"}}}
+	assert.Nil(tokenManager.UserAttributeCreateOrUpdate("u-jdoe", "okta", groups, nil))
+	assert.Equal(groups, attribs.GroupPrincipals["okta"].Items)
+	assert.Equal("okta_group://Engineering", attribs.GroupPrincipals[scimKey].Items[0].Name)
+}
+
 func receivedData(c <-chan map[string]interface{}, t <-chan time.Time, result chan<- bool) {
 	select {
 	case <-c:
@@ -0,0 +1,311 @@
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	managementGroup = "management.cattle.io"
+	clusterGroup    = "cluster.cattle.io"
+	projectGroup    = "project.cattle.io"
+)
+
+var (
+	readOnlyVerbs = sets.NewString("get", "list", "watch")
+	safeMethods   = sets.NewString(http.MethodGet, http.MethodHead, http.MethodOptions)
+
+	kubernetesRequestInfo = &apirequest.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+
+	// discoveryPaths are the paths outside of API resources scoped tokens
+	// can read, anything else such as /metrics or /meta/proxy is denied.
+	discoveryPaths        = sets.NewString("/", "/v3", "/v1", "/version", "/api", "/api/v1", "/apis")
+	discoveryPathPrefixes = []string{"/apis/", "/openapi/"}
+)
+
+// ScopeRequest describes a request in the terms of token scopes.
+type ScopeRequest struct {
+	// IsResourceRequest is false for requests that are not for API
+	// resources, such as discovery
+	IsResourceRequest bool
+	Method            string
+	APIGroup          string
+	Resource          string
+	Verb              string
+	// Namespace is empty for cluster scoped and cluster wide requests
+	Namespace string
+	// ProjectID is set for requests to the norman project API
+	ProjectID string
+	// Path is set for requests that are not for API resources, relative to
+	// the API of the cluster for proxied requests
+	Path string
+}
+
+func (r ScopeRequest) String() string {
+	if !r.IsResourceRequest {
+		return r.Method + " " + r.Path
+	}
+	s := r.Verb + " " + r.Resource
+	if r.APIGroup != "" {
+		s += "." + r.APIGroup
+	}
+	switch {
+	case r.ProjectID != "" && r.Namespace != "":
+		s += fmt.Sprintf(" in project %s namespace %s", r.ProjectID, r.Namespace)
+	case r.ProjectID != "":
+		s += " in project " + r.ProjectID
+	case r.Namespace != "":
+		s += " in namespace " + r.Namespace
+	}
+	return s
+}
+
+// GetScopeRequest returns the scope request of req to the norman (/v3),
+// steve (/v1) or kubernetes (/api, /apis) API, or to the steve or kubernetes
+// API of a cluster proxied below /k8s/clusters/<id>.
+func GetScopeRequest(req *http.Request) ScopeRequest {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "v3":
+		return getNormanScopeRequest(req, parts[1:])
+	case len(parts) >= 2 && parts[0] == "v1":
+		return getSteveScopeRequest(req, parts[1:])
+	case len(parts) >= 5 && parts[0] == "k8s" && parts[1] == "clusters" && parts[3] == "v1":
+		return getSteveScopeRequest(req, parts[4:])
+	case len(parts) >= 4 && parts[0] == "k8s" && parts[1] == "clusters":
+		return getKubernetesScopeRequest(req, parts[3:])
+	case parts[0] == "api" || parts[0] == "apis":
+		return getKubernetesScopeRequest(req, parts)
+	}
+	return ScopeRequest{Method: req.Method, Path: "/" + strings.Join(parts, "/")}
+}
+
+// getNormanScopeRequest handles /v3/<type>/<id>, /v3/cluster/<id>/<type>/<id>
+// and /v3/project/<id>/<type>/<id>.
+func getNormanScopeRequest(req *http.Request, parts []string) ScopeRequest {
+	r := ScopeRequest{
+		IsResourceRequest: true,
+		Method:            req.Method,
+		APIGroup:          managementGroup,
+	}
+	switch {
+	case len(parts) >= 3 && parts[0] == "cluster":
+		r.APIGroup = clusterGroup
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "project":
+		r.APIGroup = projectGroup
+		r.ProjectID = parts[1]
+		parts = parts[2:]
+	}
+
+	r.Resource = strings.ToLower(parts[0])
+	var id string
+	if len(parts) >= 2 {
+		id = parts[1]
+	}
+	if r.Resource == "subscribe" {
+		r.Verb = "watch"
+		return r
+	}
+	if r.Resource == "projects" && r.APIGroup == managementGroup && id != "" {
+		r.ProjectID = id
+	}
+	// project resources are <namespace>:<name>, workloads <kind>:<namespace>:<name>
+	if idParts := strings.Split(id, ":"); r.APIGroup == projectGroup && len(idParts) >= 2 {
+		r.Namespace = idParts[len(idParts)-2]
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.Verb = "list"
+		if id != "" {
+			r.Verb = "get"
+		}
+	case http.MethodPost:
+		r.Verb = "create"
+		if id != "" || req.URL.Query().Get("action") != "" {
+			r.Verb = "update"
+		}
+	case http.MethodPut:
+		r.Verb = "update"
+	case http.MethodDelete:
+		r.Verb = "delete"
+	default:
+		r.Verb = strings.ToLower(req.Method)
+	}
+	return r
+}
+
+// getSteveScopeRequest handles /v1/<type>/<namespace>/<name>. Types are the
+// group and singular kind, such as apps.deployment, and are matched by their
+// plural resource name. Paths of a single segment after the type are taken
+// to name a namespace, so tokens restricted to namespaces can not get cluster
+// scoped objects.
+func getSteveScopeRequest(req *http.Request, parts []string) ScopeRequest {
+	r := ScopeRequest{
+		IsResourceRequest: true,
+		Method:            req.Method,
+	}
+	if parts[0] == "subscribe" {
+		r.Resource = "subscribe"
+		r.Verb = "watch"
+		return r
+	}
+
+	kind := parts[0]
+	if i := strings.LastIndex(kind, "."); i >= 0 {
+		r.APIGroup, kind = kind[:i], kind[i+1:]
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(schema.GroupVersionKind{Group: r.APIGroup, Kind: kind})
+	r.Resource = strings.ToLower(gvr.Resource)
+
+	var name string
+	switch {
+	case len(parts) >= 3:
+		r.Namespace, name = parts[1], parts[2]
+	case len(parts) == 2 && r.Resource == "namespaces":
+		r.Namespace, name = parts[1], parts[1]
+	case len(parts) == 2:
+		r.Namespace = parts[1]
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.Verb = "list"
+		if name != "" {
+			r.Verb = "get"
+		}
+	case http.MethodPost:
+		r.Verb = "create"
+		if req.URL.Query().Get("action") != "" {
+			r.Verb = "update"
+		}
+	case http.MethodPut:
+		r.Verb = "update"
+	case http.MethodPatch:
+		r.Verb = "patch"
+	case http.MethodDelete:
+		r.Verb = "delete"
+	default:
+		r.Verb = strings.ToLower(req.Method)
+	}
+	return r
+}
+
+// getKubernetesScopeRequest handles requests to the kubernetes API, with
+// parts being the path below /k8s/clusters/<id> for proxied requests.
+func getKubernetesScopeRequest(req *http.Request, parts []string) ScopeRequest {
+	proxied := req.Clone(req.Context())
+	proxied.URL.Path = "/" + strings.Join(parts, "/")
+	info, err := kubernetesRequestInfo.NewRequestInfo(proxied)
+	if err != nil || !info.IsResourceRequest {
+		return ScopeRequest{Method: req.Method, Path: proxied.URL.Path}
+	}
+	resource := info.Resource
+	if info.Subresource != "" {
+		resource += "/" + info.Subresource
+	}
+	r := ScopeRequest{
+		IsResourceRequest: true,
+		Method:            req.Method,
+		APIGroup:          info.APIGroup,
+		Resource:          resource,
+		Verb:              info.Verb,
+		Namespace:         info.Namespace,
+	}
+	if info.Resource == "namespaces" && info.Namespace == "" {
+		r.Namespace = info.Name
+	}
+	return r
+}
+
+// ProjectResolver returns the project of a namespace, or an empty string if
+// it is not known.
+type ProjectResolver func(namespace string) string
+
+// CheckScopes returns an error if scopes do not allow r. Tokens without
+// scopes allow every request.
+func CheckScopes(scopes *v32.TokenScopes, r ScopeRequest, projectOf ProjectResolver) error {
+	if scopes == nil {
+		return nil
+	}
+	if !r.IsResourceRequest {
+		if !safeMethods.Has(r.Method) {
+			return fmt.Errorf("token scopes only allow reading outside of API resources")
+		}
+		if !isDiscoveryPath(r.Path) {
+			return fmt.Errorf("token scopes do not allow %s", r)
+		}
+		return nil
+	}
+	if scopes.ReadOnly && !readOnlyVerbs.Has(r.Verb) {
+		return fmt.Errorf("token is read-only")
+	}
+	if !scopesAllowNamespace(scopes, r, projectOf) {
+		return fmt.Errorf("token scopes do not allow %s", r)
+	}
+	if len(scopes.Rules) == 0 {
+		return nil
+	}
+	for _, rule := range scopes.Rules {
+		if matchScopeValue(rule.APIGroups, r.APIGroup) &&
+			matchScopeValue(rule.Resources, r.Resource) &&
+			matchScopeValue(rule.Verbs, r.Verb) {
+			return nil
+		}
+	}
+	return fmt.Errorf("token scopes do not allow %s", r)
+}
+
+func isDiscoveryPath(path string) bool {
+	if discoveryPaths.Has(path) {
+		return true
+	}
+	for _, prefix := range discoveryPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func scopesAllowNamespace(scopes *v32.TokenScopes, r ScopeRequest, projectOf ProjectResolver) bool {
+	if len(scopes.ProjectIDs) == 0 && len(scopes.Namespaces) == 0 {
+		return true
+	}
+	projectIDs := sets.NewString(scopes.ProjectIDs...)
+	// norman project API requests name their project
+	if r.ProjectID != "" && projectIDs.Has(r.ProjectID) {
+		return true
+	}
+	if r.Namespace == "" {
+		return false
+	}
+	if sets.NewString(scopes.Namespaces...).Has(r.Namespace) {
+		return true
+	}
+	if r.ProjectID != "" || projectIDs.Len() == 0 || projectOf == nil {
+		return false
+	}
+	projectID := projectOf(r.Namespace)
+	return projectID != "" && projectIDs.Has(projectID)
+}
+
+func matchScopeValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
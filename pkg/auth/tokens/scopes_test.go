@@ -0,0 +1,107 @@
+package tokens
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetScopeRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		method, path string
+		expected     ScopeRequest
+	}{
+		{"GET", "/v3/clusters", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "management.cattle.io", Resource: "clusters", Verb: "list"}},
+		{"POST", "/v3/clusters/c-abcde?action=generateKubeconfig", ScopeRequest{IsResourceRequest: true, Method: "POST", APIGroup: "management.cattle.io", Resource: "clusters", Verb: "update"}},
+		{"PUT", "/v3/projects/c-abcde:p-fghij", ScopeRequest{IsResourceRequest: true, Method: "PUT", APIGroup: "management.cattle.io", Resource: "projects", Verb: "update", ProjectID: "c-abcde:p-fghij"}},
+		{"DELETE", "/v3/project/c-abcde:p-fghij/workloads/deployment:ns1:web", ScopeRequest{IsResourceRequest: true, Method: "DELETE", APIGroup: "project.cattle.io", Resource: "workloads", Verb: "delete", Namespace: "ns1", ProjectID: "c-abcde:p-fghij"}},
+		{"GET", "/v3/project/c-abcde:p-fghij/secrets/ns1:creds", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "project.cattle.io", Resource: "secrets", Verb: "get", Namespace: "ns1", ProjectID: "c-abcde:p-fghij"}},
+		{"GET", "/v3/cluster/c-abcde/namespaces", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "cluster.cattle.io", Resource: "namespaces", Verb: "list"}},
+		{"GET", "/v3/subscribe", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "management.cattle.io", Resource: "subscribe", Verb: "watch"}},
+		{"GET", "/v1/apps.deployment/ns1/web", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "apps", Resource: "deployments", Verb: "get", Namespace: "ns1"}},
+		{"GET", "/v1/management.cattle.io.cluster", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "management.cattle.io", Resource: "clusters", Verb: "list"}},
+		{"GET", "/v1/policy", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "policies", Verb: "list"}},
+		{"GET", "/v1/namespace/ns1", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "namespaces", Verb: "get", Namespace: "ns1"}},
+		{"PATCH", "/v1/secret/ns1/creds", ScopeRequest{IsResourceRequest: true, Method: "PATCH", Resource: "secrets", Verb: "patch", Namespace: "ns1"}},
+		{"GET", "/k8s/clusters/c-abcde/api/v1/namespaces/ns1/pods?watch=true", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "pods", Verb: "watch", Namespace: "ns1"}},
+		{"POST", "/k8s/clusters/c-abcde/api/v1/namespaces/ns1/pods/web/exec", ScopeRequest{IsResourceRequest: true, Method: "POST", Resource: "pods/exec", Verb: "create", Namespace: "ns1"}},
+		{"GET", "/k8s/clusters/local/apis/apps/v1/deployments", ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "apps", Resource: "deployments", Verb: "list"}},
+		{"GET", "/k8s/clusters/local/api/v1/namespaces/ns1", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "namespaces", Verb: "get", Namespace: "ns1"}},
+		{"GET", "/k8s/clusters/c-abcde/v1/secret/ns1/creds", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "secrets", Verb: "get", Namespace: "ns1"}},
+		{"GET", "/k8s/clusters/c-abcde/v1/secret", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "secrets", Verb: "list"}},
+		{"GET", "/k8s/clusters/c-abcde/v1/subscribe", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "subscribe", Verb: "watch"}},
+		{"GET", "/k8s/clusters/c-abcde/v1", ScopeRequest{Method: "GET", Path: "/v1"}},
+		{"GET", "/api/v1/namespaces/ns1/secrets", ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "secrets", Verb: "list", Namespace: "ns1"}},
+		{"GET", "/k8s/clusters/local/apis", ScopeRequest{Method: "GET", Path: "/apis"}},
+		{"GET", "/k8s/clusters/local/healthz", ScopeRequest{Method: "GET", Path: "/healthz"}},
+		{"GET", "/v3", ScopeRequest{Method: "GET", Path: "/v3"}},
+		{"GET", "/metrics", ScopeRequest{Method: "GET", Path: "/metrics"}},
+		{"POST", "/meta/proxy/example.com", ScopeRequest{Method: "POST", Path: "/meta/proxy/example.com"}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		assert.Equal(test.expected, GetScopeRequest(req), "%s %s", test.method, test.path)
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	projectOf := func(namespace string) string {
+		if namespace == "ns2" {
+			return "c-abcde:p-fghij"
+		}
+		return ""
+	}
+	rules := []v32.TokenScopeRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"*"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: []string{"get", "list"}},
+	}
+	restricted := &v32.TokenScopes{
+		Rules:      rules,
+		ProjectIDs: []string{"c-abcde:p-fghij"},
+		Namespaces: []string{"ns1"},
+	}
+	pods := ScopeRequest{IsResourceRequest: true, Method: "DELETE", Resource: "pods", Verb: "delete", Namespace: "ns1"}
+	deployments := ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "apps", Resource: "deployments", Verb: "get", Namespace: "ns1"}
+	updateDeployment := ScopeRequest{IsResourceRequest: true, Method: "PUT", APIGroup: "apps", Resource: "deployments", Verb: "update", Namespace: "ns1"}
+	secrets := ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "secrets", Verb: "get", Namespace: "ns1"}
+	inProject := ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "pods", Verb: "list", Namespace: "ns2"}
+	inOtherNamespace := ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "pods", Verb: "list", Namespace: "ns3"}
+	clusterWide := ScopeRequest{IsResourceRequest: true, Method: "GET", Resource: "pods", Verb: "list"}
+	normanProject := ScopeRequest{IsResourceRequest: true, Method: "GET", APIGroup: "project.cattle.io", Resource: "pods", Verb: "list", ProjectID: "c-abcde:p-fghij"}
+	discovery := ScopeRequest{Method: "GET", Path: "/apis/apps/v1"}
+	metrics := ScopeRequest{Method: "GET", Path: "/metrics"}
+	proxy := ScopeRequest{Method: "POST", Path: "/meta/proxy/example.com"}
+
+	assert.Nil(CheckScopes(nil, updateDeployment, nil))
+	assert.Nil(CheckScopes(nil, proxy, nil))
+
+	assert.Nil(CheckScopes(restricted, pods, projectOf))
+	assert.Nil(CheckScopes(restricted, deployments, projectOf))
+	assert.EqualError(CheckScopes(restricted, updateDeployment, projectOf), "token scopes do not allow update deployments.apps in namespace ns1")
+	assert.EqualError(CheckScopes(restricted, secrets, projectOf), "token scopes do not allow get secrets in namespace ns1")
+	assert.Nil(CheckScopes(restricted, inProject, projectOf))
+	assert.Error(CheckScopes(restricted, inProject, nil), "projects of downstream namespaces are not known")
+	assert.EqualError(CheckScopes(restricted, inOtherNamespace, projectOf), "token scopes do not allow list pods in namespace ns3")
+	assert.Error(CheckScopes(restricted, clusterWide, projectOf))
+	assert.Error(CheckScopes(restricted, normanProject, projectOf), "rules apply to norman resources too")
+	assert.Nil(CheckScopes(restricted, discovery, projectOf))
+	assert.EqualError(CheckScopes(restricted, metrics, projectOf), "token scopes do not allow GET /metrics")
+	assert.EqualError(CheckScopes(restricted, proxy, projectOf), "token scopes only allow reading outside of API resources")
+
+	readOnly := &v32.TokenScopes{ReadOnly: true}
+	assert.Nil(CheckScopes(readOnly, secrets, nil))
+	assert.Nil(CheckScopes(readOnly, clusterWide, nil))
+	assert.Error(CheckScopes(readOnly, metrics, nil), "unknown paths are denied to every scoped token")
+	assert.EqualError(CheckScopes(readOnly, pods, nil), "token is read-only")
+
+	projectOnly := &v32.TokenScopes{ProjectIDs: []string{"c-abcde:p-fghij"}}
+	assert.Nil(CheckScopes(projectOnly, normanProject, nil))
+	normanProject.ProjectID = "c-abcde:p-other"
+	assert.Error(CheckScopes(projectOnly, normanProject, nil))
+}
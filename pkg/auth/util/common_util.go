@@ -2,8 +2,13 @@
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -53,6 +58,73 @@ func GetHost(req *http.Request) string {
 	return host
 }
 
+// GetClientIP returns the IP address of the client making req. Requests
+// from the proxies of the auth-trusted-proxies setting, such as the ingress
+// controller, are from the right-most address of their X-Forwarded-For that
+// is not one of those proxies. The header is ignored on requests from other
+// addresses, as any client can set it.
+func GetClientIP(req *http.Request) string {
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ip = host
+	}
+	proxies := parseTrustedProxies(settings.AuthTrustedProxies.Get())
+	if !isTrustedProxy(proxies, ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			// the proxy that added a malformed address is the closest
+			// client known
+			return ip
+		}
+		ip = hop
+		if !isTrustedProxy(proxies, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func parseTrustedProxies(value string) []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logrus.Warnf("Ignoring invalid trusted proxy %s: %v", proxy, err)
+			continue
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies
+}
+
+func isTrustedProxy(proxies []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
 //AuthError structure contains the error resource definition
 type AuthError struct {
 	Type    string `json:"type"`
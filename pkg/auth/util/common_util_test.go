@@ -0,0 +1,39 @@
+package util
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClientIP(t *testing.T) {
+	assert := assert.New(t)
+	defer settings.AuthTrustedProxies.Set(settings.AuthTrustedProxies.Get())
+
+	tests := []struct {
+		name, proxies, remoteAddr string
+		forwarded                 []string
+		expected                  string
+	}{
+		{"direct", "", "203.0.113.7:51234", nil, "203.0.113.7"},
+		{"untrusted proxy", "", "10.42.0.5:51234", []string{"203.0.113.7"}, "10.42.0.5"},
+		{"trusted proxy", "10.42.0.0/16", "10.42.0.5:51234", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed hops", "10.42.0.0/16", "10.42.0.5:51234", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"proxy chain", "10.42.0.0/16, 192.0.2.10", "10.42.0.5:51234", []string{"203.0.113.7, 192.0.2.10"}, "203.0.113.7"},
+		{"several headers", "10.42.0.0/16", "10.42.0.5:51234", []string{"198.51.100.1", "203.0.113.7"}, "203.0.113.7"},
+		{"malformed hop", "10.42.0.0/16", "10.42.0.5:51234", []string{"203.0.113.7, unknown"}, "10.42.0.5"},
+		{"only proxies", "10.42.0.0/16", "10.42.0.5:51234", []string{"10.42.0.9"}, "10.42.0.9"},
+		{"ipv6 proxy", "fd00::1", "[fd00::1]:51234", []string{"2001:db8::7"}, "2001:db8::7"},
+	}
+	for _, test := range tests {
+		settings.AuthTrustedProxies.Set(test.proxies)
+		req := httptest.NewRequest("POST", "/v3-public/localProviders/local?action=login", nil)
+		req.RemoteAddr = test.remoteAddr
+		for _, value := range test.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		assert.Equal(test.expected, GetClientIP(req), test.name)
+	}
+}
@@ -0,0 +1,20 @@
+package client
+
+const (
+	AzureBlobBackupConfigType                 = "azureBlobBackupConfig"
+	AzureBlobBackupConfigFieldAccountKey      = "accountKey"
+	AzureBlobBackupConfigFieldAccountName     = "accountName"
+	AzureBlobBackupConfigFieldContainer       = "container"
+	AzureBlobBackupConfigFieldEmulatorAddress = "emulatorAddress"
+	AzureBlobBackupConfigFieldEndpointSuffix  = "endpointSuffix"
+	AzureBlobBackupConfigFieldFolder          = "folder"
+)
+
+type AzureBlobBackupConfig struct {
+	AccountKey      string `json:"accountKey,omitempty" yaml:"accountKey,omitempty"`
+	AccountName     string `json:"accountName,omitempty" yaml:"accountName,omitempty"`
+	Container       string `json:"container,omitempty" yaml:"container,omitempty"`
+	EmulatorAddress string `json:"emulatorAddress,omitempty" yaml:"emulatorAddress,omitempty"`
+	EndpointSuffix  string `json:"endpointSuffix,omitempty" yaml:"endpointSuffix,omitempty"`
+	Folder          string `json:"folder,omitempty" yaml:"folder,omitempty"`
+}
@@ -19,6 +19,7 @@ type Client struct {
 	PodSecurityPolicyTemplateProjectBinding PodSecurityPolicyTemplateProjectBindingOperations
 	ClusterRoleTemplateBinding              ClusterRoleTemplateBindingOperations
 	ProjectRoleTemplateBinding              ProjectRoleTemplateBindingOperations
+	PrincipalMappingRule                    PrincipalMappingRuleOperations
 	Cluster                                 ClusterOperations
 	ClusterRegistrationToken                ClusterRegistrationTokenOperations
 	Catalog                                 CatalogOperations
@@ -96,6 +97,7 @@ func NewClient(opts *clientbase.ClientOpts) (*Client, error) {
 	client.PodSecurityPolicyTemplateProjectBinding = newPodSecurityPolicyTemplateProjectBindingClient(client)
 	client.ClusterRoleTemplateBinding = newClusterRoleTemplateBindingClient(client)
 	client.ProjectRoleTemplateBinding = newProjectRoleTemplateBindingClient(client)
+	client.PrincipalMappingRule = newPrincipalMappingRuleClient(client)
 	client.Cluster = newClusterClient(client)
 	client.ClusterRegistrationToken = newClusterRegistrationTokenClient(client)
 	client.Catalog = newCatalogClient(client)
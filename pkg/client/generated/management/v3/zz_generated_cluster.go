@@ -43,6 +43,8 @@
 	ClusterFieldEnableClusterAlerting                = "enableClusterAlerting"
 	ClusterFieldEnableClusterMonitoring              = "enableClusterMonitoring"
 	ClusterFieldEnableNetworkPolicy                  = "enableNetworkPolicy"
+	ClusterFieldEtcdBackupConfig                     = "etcdBackupConfig"
+	ClusterFieldEtcdBackupRetentionStatus            = "etcdBackupRetentionStatus"
 	ClusterFieldFailedSpec                           = "failedSpec"
 	ClusterFieldFleetWorkspaceName                   = "fleetWorkspaceName"
 	ClusterFieldImportedConfig                       = "importedConfig"
@@ -111,6 +113,8 @@ type Cluster struct {
 	EnableClusterAlerting                bool                           `json:"enableClusterAlerting,omitempty" yaml:"enableClusterAlerting,omitempty"`
 	EnableClusterMonitoring              bool                           `json:"enableClusterMonitoring,omitempty" yaml:"enableClusterMonitoring,omitempty"`
 	EnableNetworkPolicy                  *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
+	EtcdBackupConfig                     *EtcdBackupConfig              `json:"etcdBackupConfig,omitempty" yaml:"etcdBackupConfig,omitempty"`
+	EtcdBackupRetentionStatus            *EtcdBackupRetentionStatus     `json:"etcdBackupRetentionStatus,omitempty" yaml:"etcdBackupRetentionStatus,omitempty"`
 	FailedSpec                           *ClusterSpec                   `json:"failedSpec,omitempty" yaml:"failedSpec,omitempty"`
 	FleetWorkspaceName                   string                         `json:"fleetWorkspaceName,omitempty" yaml:"fleetWorkspaceName,omitempty"`
 	ImportedConfig                       *ImportedConfig                `json:"importedConfig,omitempty" yaml:"importedConfig,omitempty"`
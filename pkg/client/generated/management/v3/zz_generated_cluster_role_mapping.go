@@ -0,0 +1,12 @@
+package client
+
+const (
+	ClusterRoleMappingType                 = "clusterRoleMapping"
+	ClusterRoleMappingFieldClusterSelector = "clusterSelector"
+	ClusterRoleMappingFieldRoleTemplateID  = "roleTemplateId"
+)
+
+type ClusterRoleMapping struct {
+	ClusterSelector map[string]string `json:"clusterSelector,omitempty" yaml:"clusterSelector,omitempty"`
+	RoleTemplateID  string            `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
+}
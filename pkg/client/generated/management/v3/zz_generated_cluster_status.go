@@ -20,6 +20,7 @@
 	ClusterStatusFieldCurrentCisRunName                    = "currentCisRunName"
 	ClusterStatusFieldDriver                               = "driver"
 	ClusterStatusFieldEKSStatus                            = "eksStatus"
+	ClusterStatusFieldEtcdBackupRetentionStatus            = "etcdBackupRetentionStatus"
 	ClusterStatusFieldFailedSpec                           = "failedSpec"
 	ClusterStatusFieldIstioEnabled                         = "istioEnabled"
 	ClusterStatusFieldLimits                               = "limits"
@@ -51,6 +52,7 @@ type ClusterStatus struct {
 	CurrentCisRunName                    string                      `json:"currentCisRunName,omitempty" yaml:"currentCisRunName,omitempty"`
 	Driver                               string                      `json:"driver,omitempty" yaml:"driver,omitempty"`
 	EKSStatus                            *EKSStatus                  `json:"eksStatus,omitempty" yaml:"eksStatus,omitempty"`
+	EtcdBackupRetentionStatus            *EtcdBackupRetentionStatus  `json:"etcdBackupRetentionStatus,omitempty" yaml:"etcdBackupRetentionStatus,omitempty"`
 	FailedSpec                           *ClusterSpec                `json:"failedSpec,omitempty" yaml:"failedSpec,omitempty"`
 	IstioEnabled                         bool                        `json:"istioEnabled,omitempty" yaml:"istioEnabled,omitempty"`
 	Limits                               map[string]string           `json:"limits,omitempty" yaml:"limits,omitempty"`
@@ -0,0 +1,18 @@
+package client
+
+const (
+	EtcdBackupConfigType               = "etcdBackupConfig"
+	EtcdBackupConfigFieldEncryption    = "encryption"
+	EtcdBackupConfigFieldPrimaryTarget = "primaryTarget"
+	EtcdBackupConfigFieldRetention     = "retention"
+	EtcdBackupConfigFieldTargets       = "targets"
+	EtcdBackupConfigFieldVerification  = "verification"
+)
+
+type EtcdBackupConfig struct {
+	Encryption    *EtcdBackupEncryption   `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	PrimaryTarget string                  `json:"primaryTarget,omitempty" yaml:"primaryTarget,omitempty"`
+	Retention     *EtcdBackupRetention    `json:"retention,omitempty" yaml:"retention,omitempty"`
+	Targets       []EtcdBackupTarget      `json:"targets,omitempty" yaml:"targets,omitempty"`
+	Verification  *EtcdBackupVerification `json:"verification,omitempty" yaml:"verification,omitempty"`
+}
@@ -0,0 +1,14 @@
+package client
+
+const (
+	EtcdBackupDeletionType             = "etcdBackupDeletion"
+	EtcdBackupDeletionFieldBackupName  = "backupName"
+	EtcdBackupDeletionFieldManual      = "manual"
+	EtcdBackupDeletionFieldPlannedTime = "plannedTime"
+)
+
+type EtcdBackupDeletion struct {
+	BackupName  string `json:"backupName,omitempty" yaml:"backupName,omitempty"`
+	Manual      bool   `json:"manual,omitempty" yaml:"manual,omitempty"`
+	PlannedTime string `json:"plannedTime,omitempty" yaml:"plannedTime,omitempty"`
+}
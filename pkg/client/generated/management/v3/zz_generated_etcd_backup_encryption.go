@@ -0,0 +1,12 @@
+package client
+
+const (
+	EtcdBackupEncryptionType            = "etcdBackupEncryption"
+	EtcdBackupEncryptionFieldActiveKey  = "activeKey"
+	EtcdBackupEncryptionFieldSecretName = "secretName"
+)
+
+type EtcdBackupEncryption struct {
+	ActiveKey  string `json:"activeKey,omitempty" yaml:"activeKey,omitempty"`
+	SecretName string `json:"secretName,omitempty" yaml:"secretName,omitempty"`
+}
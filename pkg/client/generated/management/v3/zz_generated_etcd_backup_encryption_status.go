@@ -0,0 +1,18 @@
+package client
+
+const (
+	EtcdBackupEncryptionStatusType             = "etcdBackupEncryptionStatus"
+	EtcdBackupEncryptionStatusFieldAlgorithm   = "algorithm"
+	EtcdBackupEncryptionStatusFieldChunkSize   = "chunkSize"
+	EtcdBackupEncryptionStatusFieldKeyID       = "keyId"
+	EtcdBackupEncryptionStatusFieldNoncePrefix = "noncePrefix"
+	EtcdBackupEncryptionStatusFieldSealedKey   = "sealedKey"
+)
+
+type EtcdBackupEncryptionStatus struct {
+	Algorithm   string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	ChunkSize   int64  `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`
+	KeyID       string `json:"keyId,omitempty" yaml:"keyId,omitempty"`
+	NoncePrefix string `json:"noncePrefix,omitempty" yaml:"noncePrefix,omitempty"`
+	SealedKey   string `json:"sealedKey,omitempty" yaml:"sealedKey,omitempty"`
+}
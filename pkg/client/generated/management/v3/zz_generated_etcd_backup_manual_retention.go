@@ -0,0 +1,12 @@
+package client
+
+const (
+	EtcdBackupManualRetentionType             = "etcdBackupManualRetention"
+	EtcdBackupManualRetentionFieldCount       = "count"
+	EtcdBackupManualRetentionFieldMaxAgeHours = "maxAgeHours"
+)
+
+type EtcdBackupManualRetention struct {
+	Count       int64 `json:"count,omitempty" yaml:"count,omitempty"`
+	MaxAgeHours int64 `json:"maxAgeHours,omitempty" yaml:"maxAgeHours,omitempty"`
+}
@@ -0,0 +1,18 @@
+package client
+
+const (
+	EtcdBackupRetentionType         = "etcdBackupRetention"
+	EtcdBackupRetentionFieldDaily   = "daily"
+	EtcdBackupRetentionFieldHourly  = "hourly"
+	EtcdBackupRetentionFieldManual  = "manual"
+	EtcdBackupRetentionFieldMonthly = "monthly"
+	EtcdBackupRetentionFieldWeekly  = "weekly"
+)
+
+type EtcdBackupRetention struct {
+	Daily   int64                      `json:"daily,omitempty" yaml:"daily,omitempty"`
+	Hourly  int64                      `json:"hourly,omitempty" yaml:"hourly,omitempty"`
+	Manual  *EtcdBackupManualRetention `json:"manual,omitempty" yaml:"manual,omitempty"`
+	Monthly int64                      `json:"monthly,omitempty" yaml:"monthly,omitempty"`
+	Weekly  int64                      `json:"weekly,omitempty" yaml:"weekly,omitempty"`
+}
@@ -0,0 +1,12 @@
+package client
+
+const (
+	EtcdBackupRetentionStatusType               = "etcdBackupRetentionStatus"
+	EtcdBackupRetentionStatusFieldNextDeletions = "nextDeletions"
+	EtcdBackupRetentionStatusFieldPolicy        = "policy"
+)
+
+type EtcdBackupRetentionStatus struct {
+	NextDeletions []EtcdBackupDeletion `json:"nextDeletions,omitempty" yaml:"nextDeletions,omitempty"`
+	Policy        string               `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
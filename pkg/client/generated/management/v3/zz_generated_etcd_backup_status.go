@@ -4,11 +4,15 @@
 	EtcdBackupStatusType                   = "etcdBackupStatus"
 	EtcdBackupStatusFieldClusterObject     = "clusterObject"
 	EtcdBackupStatusFieldConditions        = "conditions"
+	EtcdBackupStatusFieldEncryption        = "encryption"
 	EtcdBackupStatusFieldKubernetesVersion = "kubernetesVersion"
+	EtcdBackupStatusFieldTarget            = "target"
 )
 
 type EtcdBackupStatus struct {
-	ClusterObject     string                `json:"clusterObject,omitempty" yaml:"clusterObject,omitempty"`
-	Conditions        []EtcdBackupCondition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
-	KubernetesVersion string                `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
+	ClusterObject     string                      `json:"clusterObject,omitempty" yaml:"clusterObject,omitempty"`
+	Conditions        []EtcdBackupCondition       `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Encryption        *EtcdBackupEncryptionStatus `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	KubernetesVersion string                      `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
+	Target            string                      `json:"target,omitempty" yaml:"target,omitempty"`
 }
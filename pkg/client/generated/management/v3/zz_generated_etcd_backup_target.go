@@ -0,0 +1,16 @@
+package client
+
+const (
+	EtcdBackupTargetType                  = "etcdBackupTarget"
+	EtcdBackupTargetFieldAzureBlobConfig  = "azureBlobConfig"
+	EtcdBackupTargetFieldFilesystemConfig = "filesystemConfig"
+	EtcdBackupTargetFieldGCSConfig        = "gcsConfig"
+	EtcdBackupTargetFieldName             = "name"
+)
+
+type EtcdBackupTarget struct {
+	AzureBlobConfig  *AzureBlobBackupConfig  `json:"azureBlobConfig,omitempty" yaml:"azureBlobConfig,omitempty"`
+	FilesystemConfig *FilesystemBackupConfig `json:"filesystemConfig,omitempty" yaml:"filesystemConfig,omitempty"`
+	GCSConfig        *GCSBackupConfig        `json:"gcsConfig,omitempty" yaml:"gcsConfig,omitempty"`
+	Name             string                  `json:"name,omitempty" yaml:"name,omitempty"`
+}
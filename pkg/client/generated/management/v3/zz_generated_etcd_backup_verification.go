@@ -0,0 +1,12 @@
+package client
+
+const (
+	EtcdBackupVerificationType         = "etcdBackupVerification"
+	EtcdBackupVerificationFieldEnabled = "enabled"
+	EtcdBackupVerificationFieldTimeout = "timeout"
+)
+
+type EtcdBackupVerification struct {
+	Enabled bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
@@ -0,0 +1,10 @@
+package client
+
+const (
+	FilesystemBackupConfigType      = "filesystemBackupConfig"
+	FilesystemBackupConfigFieldPath = "path"
+)
+
+type FilesystemBackupConfig struct {
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
@@ -0,0 +1,16 @@
+package client
+
+const (
+	GCSBackupConfigType                   = "gcsBackupConfig"
+	GCSBackupConfigFieldBucket            = "bucket"
+	GCSBackupConfigFieldEndpoint          = "endpoint"
+	GCSBackupConfigFieldFolder            = "folder"
+	GCSBackupConfigFieldServiceAccountKey = "serviceAccountKey"
+)
+
+type GCSBackupConfig struct {
+	Bucket            string `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Endpoint          string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Folder            string `json:"folder,omitempty" yaml:"folder,omitempty"`
+	ServiceAccountKey string `json:"serviceAccountKey,omitempty" yaml:"serviceAccountKey,omitempty"`
+}
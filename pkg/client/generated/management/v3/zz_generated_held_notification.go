@@ -0,0 +1,18 @@
+package client
+
+const (
+	HeldNotificationType             = "heldNotification"
+	HeldNotificationFieldContent     = "content"
+	HeldNotificationFieldFingerprint = "fingerprint"
+	HeldNotificationFieldHeldTime    = "heldTime"
+	HeldNotificationFieldRecipient   = "recipient"
+	HeldNotificationFieldTitle       = "title"
+)
+
+type HeldNotification struct {
+	Content     string `json:"content,omitempty" yaml:"content,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	HeldTime    string `json:"heldTime,omitempty" yaml:"heldTime,omitempty"`
+	Recipient   string `json:"recipient,omitempty" yaml:"recipient,omitempty"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+}
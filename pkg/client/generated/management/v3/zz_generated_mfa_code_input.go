@@ -0,0 +1,10 @@
+package client
+
+const (
+	MFACodeInputType      = "mfaCodeInput"
+	MFACodeInputFieldCode = "code"
+)
+
+type MFACodeInput struct {
+	Code string `json:"code,omitempty" yaml:"code,omitempty"`
+}
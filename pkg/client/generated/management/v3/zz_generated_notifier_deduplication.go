@@ -0,0 +1,10 @@
+package client
+
+const (
+	NotifierDeduplicationType               = "notifierDeduplication"
+	NotifierDeduplicationFieldWindowSeconds = "windowSeconds"
+)
+
+type NotifierDeduplication struct {
+	WindowSeconds int64 `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+}
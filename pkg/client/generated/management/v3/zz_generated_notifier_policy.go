@@ -0,0 +1,14 @@
+package client
+
+const (
+	NotifierPolicyType               = "notifierPolicy"
+	NotifierPolicyFieldDeduplication = "deduplication"
+	NotifierPolicyFieldQuietHours    = "quietHours"
+	NotifierPolicyFieldRateLimit     = "rateLimit"
+)
+
+type NotifierPolicy struct {
+	Deduplication *NotifierDeduplication `json:"deduplication,omitempty" yaml:"deduplication,omitempty"`
+	QuietHours    []QuietHours           `json:"quietHours,omitempty" yaml:"quietHours,omitempty"`
+	RateLimit     *NotifierRateLimit     `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+}
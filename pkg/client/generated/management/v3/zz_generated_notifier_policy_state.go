@@ -0,0 +1,16 @@
+package client
+
+const (
+	NotifierPolicyStateType              = "notifierPolicyState"
+	NotifierPolicyStateFieldFingerprints = "fingerprints"
+	NotifierPolicyStateFieldHeldMessages = "heldMessages"
+	NotifierPolicyStateFieldMutedAlerts  = "mutedAlerts"
+	NotifierPolicyStateFieldSentTimes    = "sentTimes"
+)
+
+type NotifierPolicyState struct {
+	Fingerprints map[string]string  `json:"fingerprints,omitempty" yaml:"fingerprints,omitempty"`
+	HeldMessages []HeldNotification `json:"heldMessages,omitempty" yaml:"heldMessages,omitempty"`
+	MutedAlerts  []string           `json:"mutedAlerts,omitempty" yaml:"mutedAlerts,omitempty"`
+	SentTimes    []string           `json:"sentTimes,omitempty" yaml:"sentTimes,omitempty"`
+}
@@ -0,0 +1,12 @@
+package client
+
+const (
+	NotifierRateLimitType               = "notifierRateLimit"
+	NotifierRateLimitFieldMaxMessages   = "maxMessages"
+	NotifierRateLimitFieldWindowSeconds = "windowSeconds"
+)
+
+type NotifierRateLimit struct {
+	MaxMessages   int64 `json:"maxMessages,omitempty" yaml:"maxMessages,omitempty"`
+	WindowSeconds int64 `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+}
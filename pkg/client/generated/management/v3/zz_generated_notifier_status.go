@@ -1,8 +1,16 @@
 package client
 
 const (
-	NotifierStatusType = "notifierStatus"
+	NotifierStatusType                      = "notifierStatus"
+	NotifierStatusFieldLastSuppressedReason = "lastSuppressedReason"
+	NotifierStatusFieldLastSuppressedTime   = "lastSuppressedTime"
+	NotifierStatusFieldPolicyState          = "policyState"
+	NotifierStatusFieldSuppressedCount      = "suppressedCount"
 )
 
 type NotifierStatus struct {
+	LastSuppressedReason string               `json:"lastSuppressedReason,omitempty" yaml:"lastSuppressedReason,omitempty"`
+	LastSuppressedTime   string               `json:"lastSuppressedTime,omitempty" yaml:"lastSuppressedTime,omitempty"`
+	PolicyState          *NotifierPolicyState `json:"policyState,omitempty" yaml:"policyState,omitempty"`
+	SuppressedCount      int64                `json:"suppressedCount,omitempty" yaml:"suppressedCount,omitempty"`
 }
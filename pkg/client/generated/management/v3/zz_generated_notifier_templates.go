@@ -0,0 +1,12 @@
+package client
+
+const (
+	NotifierTemplatesType         = "notifierTemplates"
+	NotifierTemplatesFieldContent = "content"
+	NotifierTemplatesFieldTitle   = "title"
+)
+
+type NotifierTemplates struct {
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+}
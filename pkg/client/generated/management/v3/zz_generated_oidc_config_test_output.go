@@ -0,0 +1,10 @@
+package client
+
+const (
+	OIDCConfigTestOutputType             = "oidcConfigTestOutput"
+	OIDCConfigTestOutputFieldRedirectURL = "redirectUrl"
+)
+
+type OIDCConfigTestOutput struct {
+	RedirectURL string `json:"redirectUrl,omitempty" yaml:"redirectUrl,omitempty"`
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"github.com/rancher/norman/types"
+)
+
+const (
+	PrincipalMappingRuleType                        = "principalMappingRule"
+	PrincipalMappingRuleFieldAnnotations            = "annotations"
+	PrincipalMappingRuleFieldClusterRoles           = "clusterRoles"
+	PrincipalMappingRuleFieldCreated                = "created"
+	PrincipalMappingRuleFieldCreatorID              = "creatorId"
+	PrincipalMappingRuleFieldDescription            = "description"
+	PrincipalMappingRuleFieldDisplayName            = "displayName"
+	PrincipalMappingRuleFieldGlobalRoleIDs          = "globalRoleIds"
+	PrincipalMappingRuleFieldGroupPrincipalPatterns = "groupPrincipalPatterns"
+	PrincipalMappingRuleFieldLabels                 = "labels"
+	PrincipalMappingRuleFieldName                   = "name"
+	PrincipalMappingRuleFieldOwnerReferences        = "ownerReferences"
+	PrincipalMappingRuleFieldProjectRoles           = "projectRoles"
+	PrincipalMappingRuleFieldProvider               = "provider"
+	PrincipalMappingRuleFieldRemoved                = "removed"
+	PrincipalMappingRuleFieldUUID                   = "uuid"
+	PrincipalMappingRuleFieldUserAttributePatterns  = "userAttributePatterns"
+	PrincipalMappingRuleFieldUserPrincipalPatterns  = "userPrincipalPatterns"
+)
+
+type PrincipalMappingRule struct {
+	types.Resource
+	Annotations            map[string]string    `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	ClusterRoles           []ClusterRoleMapping `json:"clusterRoles,omitempty" yaml:"clusterRoles,omitempty"`
+	Created                string               `json:"created,omitempty" yaml:"created,omitempty"`
+	CreatorID              string               `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
+	Description            string               `json:"description,omitempty" yaml:"description,omitempty"`
+	DisplayName            string               `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	GlobalRoleIDs          []string             `json:"globalRoleIds,omitempty" yaml:"globalRoleIds,omitempty"`
+	GroupPrincipalPatterns []string             `json:"groupPrincipalPatterns,omitempty" yaml:"groupPrincipalPatterns,omitempty"`
+	Labels                 map[string]string    `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Name                   string               `json:"name,omitempty" yaml:"name,omitempty"`
+	OwnerReferences        []OwnerReference     `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	ProjectRoles           []ProjectRoleMapping `json:"projectRoles,omitempty" yaml:"projectRoles,omitempty"`
+	Provider               string               `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Removed                string               `json:"removed,omitempty" yaml:"removed,omitempty"`
+	UUID                   string               `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	UserAttributePatterns  map[string][]string  `json:"userAttributePatterns,omitempty" yaml:"userAttributePatterns,omitempty"`
+	UserPrincipalPatterns  []string             `json:"userPrincipalPatterns,omitempty" yaml:"userPrincipalPatterns,omitempty"`
+}
+
+type PrincipalMappingRuleCollection struct {
+	types.Collection
+	Data   []PrincipalMappingRule `json:"data,omitempty"`
+	client *PrincipalMappingRuleClient
+}
+
+type PrincipalMappingRuleClient struct {
+	apiClient *Client
+}
+
+type PrincipalMappingRuleOperations interface {
+	List(opts *types.ListOpts) (*PrincipalMappingRuleCollection, error)
+	ListAll(opts *types.ListOpts) (*PrincipalMappingRuleCollection, error)
+	Create(opts *PrincipalMappingRule) (*PrincipalMappingRule, error)
+	Update(existing *PrincipalMappingRule, updates interface{}) (*PrincipalMappingRule, error)
+	Replace(existing *PrincipalMappingRule) (*PrincipalMappingRule, error)
+	ByID(id string) (*PrincipalMappingRule, error)
+	Delete(container *PrincipalMappingRule) error
+}
+
+func newPrincipalMappingRuleClient(apiClient *Client) *PrincipalMappingRuleClient {
+	return &PrincipalMappingRuleClient{
+		apiClient: apiClient,
+	}
+}
+
+func (c *PrincipalMappingRuleClient) Create(container *PrincipalMappingRule) (*PrincipalMappingRule, error) {
+	resp := &PrincipalMappingRule{}
+	err := c.apiClient.Ops.DoCreate(PrincipalMappingRuleType, container, resp)
+	return resp, err
+}
+
+func (c *PrincipalMappingRuleClient) Update(existing *PrincipalMappingRule, updates interface{}) (*PrincipalMappingRule, error) {
+	resp := &PrincipalMappingRule{}
+	err := c.apiClient.Ops.DoUpdate(PrincipalMappingRuleType, &existing.Resource, updates, resp)
+	return resp, err
+}
+
+func (c *PrincipalMappingRuleClient) Replace(obj *PrincipalMappingRule) (*PrincipalMappingRule, error) {
+	resp := &PrincipalMappingRule{}
+	err := c.apiClient.Ops.DoReplace(PrincipalMappingRuleType, &obj.Resource, obj, resp)
+	return resp, err
+}
+
+func (c *PrincipalMappingRuleClient) List(opts *types.ListOpts) (*PrincipalMappingRuleCollection, error) {
+	resp := &PrincipalMappingRuleCollection{}
+	err := c.apiClient.Ops.DoList(PrincipalMappingRuleType, opts, resp)
+	resp.client = c
+	return resp, err
+}
+
+func (c *PrincipalMappingRuleClient) ListAll(opts *types.ListOpts) (*PrincipalMappingRuleCollection, error) {
+	resp := &PrincipalMappingRuleCollection{}
+	resp, err := c.List(opts)
+	if err != nil {
+		return resp, err
+	}
+	data := resp.Data
+	for next, err := resp.Next(); next != nil && err == nil; next, err = next.Next() {
+		data = append(data, next.Data...)
+		resp = next
+		resp.Data = data
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, err
+}
+
+func (cc *PrincipalMappingRuleCollection) Next() (*PrincipalMappingRuleCollection, error) {
+	if cc != nil && cc.Pagination != nil && cc.Pagination.Next != "" {
+		resp := &PrincipalMappingRuleCollection{}
+		err := cc.client.apiClient.Ops.DoNext(cc.Pagination.Next, resp)
+		resp.client = cc.client
+		return resp, err
+	}
+	return nil, nil
+}
+
+func (c *PrincipalMappingRuleClient) ByID(id string) (*PrincipalMappingRule, error) {
+	resp := &PrincipalMappingRule{}
+	err := c.apiClient.Ops.DoByID(PrincipalMappingRuleType, id, resp)
+	return resp, err
+}
+
+func (c *PrincipalMappingRuleClient) Delete(container *PrincipalMappingRule) error {
+	return c.apiClient.Ops.DoResourceDelete(PrincipalMappingRuleType, &container.Resource)
+}
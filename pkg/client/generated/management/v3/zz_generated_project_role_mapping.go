@@ -0,0 +1,12 @@
+package client
+
+const (
+	ProjectRoleMappingType                = "projectRoleMapping"
+	ProjectRoleMappingFieldProjectID      = "projectId"
+	ProjectRoleMappingFieldRoleTemplateID = "roleTemplateId"
+)
+
+type ProjectRoleMapping struct {
+	ProjectID      string `json:"projectId,omitempty" yaml:"projectId,omitempty"`
+	RoleTemplateID string `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
+}
@@ -0,0 +1,16 @@
+package client
+
+const (
+	QuietHoursType          = "quietHours"
+	QuietHoursFieldDays     = "days"
+	QuietHoursFieldEnd      = "end"
+	QuietHoursFieldStart    = "start"
+	QuietHoursFieldTimezone = "timezone"
+)
+
+type QuietHours struct {
+	Days     []string `json:"days,omitempty" yaml:"days,omitempty"`
+	End      string   `json:"end,omitempty" yaml:"end,omitempty"`
+	Start    string   `json:"start,omitempty" yaml:"start,omitempty"`
+	Timezone string   `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
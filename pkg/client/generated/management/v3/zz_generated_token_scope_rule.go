@@ -0,0 +1,14 @@
+package client
+
+const (
+	TokenScopeRuleType           = "tokenScopeRule"
+	TokenScopeRuleFieldAPIGroups = "apiGroups"
+	TokenScopeRuleFieldResources = "resources"
+	TokenScopeRuleFieldVerbs     = "verbs"
+)
+
+type TokenScopeRule struct {
+	APIGroups []string `json:"apiGroups,omitempty" yaml:"apiGroups,omitempty"`
+	Resources []string `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Verbs     []string `json:"verbs,omitempty" yaml:"verbs,omitempty"`
+}
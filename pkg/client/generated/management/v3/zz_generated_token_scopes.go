@@ -0,0 +1,16 @@
+package client
+
+const (
+	TokenScopesType            = "tokenScopes"
+	TokenScopesFieldNamespaces = "namespaces"
+	TokenScopesFieldProjectIDs = "projectIds"
+	TokenScopesFieldReadOnly   = "readOnly"
+	TokenScopesFieldRules      = "rules"
+)
+
+type TokenScopes struct {
+	Namespaces []string         `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	ProjectIDs []string         `json:"projectIds,omitempty" yaml:"projectIds,omitempty"`
+	ReadOnly   bool             `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	Rules      []TokenScopeRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
@@ -6,6 +6,7 @@
 	ADFSProviderFieldCreated         = "created"
 	ADFSProviderFieldCreatorID       = "creatorId"
 	ADFSProviderFieldLabels          = "labels"
+	ADFSProviderFieldLogoutURL       = "logoutUrl"
 	ADFSProviderFieldName            = "name"
 	ADFSProviderFieldOwnerReferences = "ownerReferences"
 	ADFSProviderFieldRedirectURL     = "redirectUrl"
@@ -19,6 +20,7 @@ type ADFSProvider struct {
 	Created         string            `json:"created,omitempty" yaml:"created,omitempty"`
 	CreatorID       string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
 	Labels          map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LogoutURL       string            `json:"logoutUrl,omitempty" yaml:"logoutUrl,omitempty"`
 	Name            string            `json:"name,omitempty" yaml:"name,omitempty"`
 	OwnerReferences []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
 	RedirectURL     string            `json:"redirectUrl,omitempty" yaml:"redirectUrl,omitempty"`
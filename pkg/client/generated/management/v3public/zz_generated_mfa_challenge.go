@@ -0,0 +1,20 @@
+package client
+
+const (
+	MFAChallengeType                 = "mfaChallenge"
+	MFAChallengeFieldChallengeID     = "challengeId"
+	MFAChallengeFieldEnroll          = "enroll"
+	MFAChallengeFieldExpiresAt       = "expiresAt"
+	MFAChallengeFieldProvisioningURI = "provisioningUri"
+	MFAChallengeFieldRecoveryCodes   = "recoveryCodes"
+	MFAChallengeFieldSecret          = "secret"
+)
+
+type MFAChallenge struct {
+	ChallengeID     string   `json:"challengeId,omitempty" yaml:"challengeId,omitempty"`
+	Enroll          bool     `json:"enroll,omitempty" yaml:"enroll,omitempty"`
+	ExpiresAt       string   `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
+	ProvisioningURI string   `json:"provisioningUri,omitempty" yaml:"provisioningUri,omitempty"`
+	RecoveryCodes   []string `json:"recoveryCodes,omitempty" yaml:"recoveryCodes,omitempty"`
+	Secret          string   `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
@@ -0,0 +1,18 @@
+package client
+
+const (
+	MFALoginType              = "mfaLogin"
+	MFALoginFieldChallengeID  = "challengeId"
+	MFALoginFieldCode         = "code"
+	MFALoginFieldDescription  = "description"
+	MFALoginFieldResponseType = "responseType"
+	MFALoginFieldTTLMillis    = "ttl"
+)
+
+type MFALogin struct {
+	ChallengeID  string `json:"challengeId,omitempty" yaml:"challengeId,omitempty"`
+	Code         string `json:"code,omitempty" yaml:"code,omitempty"`
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+	ResponseType string `json:"responseType,omitempty" yaml:"responseType,omitempty"`
+	TTLMillis    int64  `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
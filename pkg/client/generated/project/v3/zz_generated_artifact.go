@@ -0,0 +1,16 @@
+package client
+
+const (
+	ArtifactType       = "artifact"
+	ArtifactFieldName  = "name"
+	ArtifactFieldSize  = "size"
+	ArtifactFieldStage = "stage"
+	ArtifactFieldStep  = "step"
+)
+
+type Artifact struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Size  int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Stage int64  `json:"stage,omitempty" yaml:"stage,omitempty"`
+	Step  int64  `json:"step,omitempty" yaml:"step,omitempty"`
+}
@@ -2,6 +2,7 @@
 
 const (
 	PipelineExecutionStatusType                = "pipelineExecutionStatus"
+	PipelineExecutionStatusFieldArtifacts      = "artifacts"
 	PipelineExecutionStatusFieldConditions     = "conditions"
 	PipelineExecutionStatusFieldEnded          = "ended"
 	PipelineExecutionStatusFieldExecutionState = "executionState"
@@ -10,6 +11,7 @@
 )
 
 type PipelineExecutionStatus struct {
+	Artifacts      []Artifact          `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
 	Conditions     []PipelineCondition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 	Ended          string              `json:"ended,omitempty" yaml:"ended,omitempty"`
 	ExecutionState string              `json:"executionState,omitempty" yaml:"executionState,omitempty"`
@@ -4,10 +4,13 @@
 	StepType                      = "step"
 	StepFieldApplyAppConfig       = "applyAppConfig"
 	StepFieldApplyYamlConfig      = "applyYamlConfig"
+	StepFieldArtifacts            = "artifacts"
 	StepFieldCPULimit             = "cpuLimit"
 	StepFieldCPURequest           = "cpuRequest"
+	StepFieldCache                = "cache"
 	StepFieldEnv                  = "env"
 	StepFieldEnvFrom              = "envFrom"
+	StepFieldMatrix               = "matrix"
 	StepFieldMemoryLimit          = "memoryLimit"
 	StepFieldMemoryRequest        = "memoryRequest"
 	StepFieldPrivileged           = "privileged"
@@ -21,10 +24,13 @@
 type Step struct {
 	ApplyAppConfig       *ApplyAppConfig       `json:"applyAppConfig,omitempty" yaml:"applyAppConfig,omitempty"`
 	ApplyYamlConfig      *ApplyYamlConfig      `json:"applyYamlConfig,omitempty" yaml:"applyYamlConfig,omitempty"`
+	Artifacts            []string              `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
 	CPULimit             string                `json:"cpuLimit,omitempty" yaml:"cpuLimit,omitempty"`
 	CPURequest           string                `json:"cpuRequest,omitempty" yaml:"cpuRequest,omitempty"`
+	Cache                *StepCache            `json:"cache,omitempty" yaml:"cache,omitempty"`
 	Env                  map[string]string     `json:"env,omitempty" yaml:"env,omitempty"`
 	EnvFrom              []EnvFrom             `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
+	Matrix               *StepMatrix           `json:"matrix,omitempty" yaml:"matrix,omitempty"`
 	MemoryLimit          string                `json:"memoryLimit,omitempty" yaml:"memoryLimit,omitempty"`
 	MemoryRequest        string                `json:"memoryRequest,omitempty" yaml:"memoryRequest,omitempty"`
 	Privileged           bool                  `json:"privileged,omitempty" yaml:"privileged,omitempty"`
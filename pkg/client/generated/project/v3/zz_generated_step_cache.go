@@ -0,0 +1,14 @@
+package client
+
+const (
+	StepCacheType          = "stepCache"
+	StepCacheFieldKey      = "key"
+	StepCacheFieldKeyFiles = "keyFiles"
+	StepCacheFieldPaths    = "paths"
+)
+
+type StepCache struct {
+	Key      string   `json:"key,omitempty" yaml:"key,omitempty"`
+	KeyFiles []string `json:"keyFiles,omitempty" yaml:"keyFiles,omitempty"`
+	Paths    []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+}
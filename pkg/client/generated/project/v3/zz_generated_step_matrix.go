@@ -0,0 +1,12 @@
+package client
+
+const (
+	StepMatrixType          = "stepMatrix"
+	StepMatrixFieldFailFast = "failFast"
+	StepMatrixFieldValues   = "values"
+)
+
+type StepMatrix struct {
+	FailFast bool                `json:"failFast,omitempty" yaml:"failFast,omitempty"`
+	Values   map[string][]string `json:"values,omitempty" yaml:"values,omitempty"`
+}
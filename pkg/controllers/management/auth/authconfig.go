@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/auth/providerrefresh"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	authConfigController = "mgmt-auth-config-controller"
+	// accessHashAnnotation is the hash of the access restrictions of an auth
+	// config its users were last refreshed for
+	accessHashAnnotation = "auth.management.cattle.io/access-hash"
+)
+
+// AuthConfigController refreshes the users of an auth provider whenever its
+// access restrictions change, so that users who lose access are logged out
+// right away rather than on their next refresh.
+type AuthConfigController struct {
+	authConfigs  v3.AuthConfigInterface
+	refreshUsers func(providerName string)
+}
+
+func newAuthConfigController(mgmt *config.ManagementContext) *AuthConfigController {
+	return &AuthConfigController{
+		authConfigs:  mgmt.Management.AuthConfigs(""),
+		refreshUsers: providerrefresh.RefreshProviderUsers,
+	}
+}
+
+func (c *AuthConfigController) sync(key string, obj *v3.AuthConfig) (runtime.Object, error) {
+	if obj == nil || obj.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	hash := accessHash(obj)
+	if obj.Annotations[accessHashAnnotation] == hash {
+		return obj, nil
+	}
+	c.refreshUsers(obj.Name)
+
+	obj = obj.DeepCopy()
+	if obj.Annotations == nil {
+		obj.Annotations = map[string]string{}
+	}
+	obj.Annotations[accessHashAnnotation] = hash
+	return c.authConfigs.Update(obj)
+}
+
+func accessHash(config *v3.AuthConfig) string {
+	allowed := append([]string{}, config.AllowedPrincipalIDs...)
+	sort.Strings(allowed)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%t/%s/%s", config.Enabled, config.AccessMode, strings.Join(allowed, ","))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAuthConfigSyncRefreshesUsers(t *testing.T) {
+	assert := assert.New(t)
+
+	var refreshed []string
+	c := &AuthConfigController{
+		authConfigs: &fakes.AuthConfigInterfaceMock{
+			UpdateFunc: func(config *v3.AuthConfig) (*v3.AuthConfig, error) {
+				return config, nil
+			},
+		},
+		refreshUsers: func(providerName string) {
+			refreshed = append(refreshed, providerName)
+		},
+	}
+
+	config := &v3.AuthConfig{
+		ObjectMeta:          v1.ObjectMeta{Name: "github"},
+		Enabled:             true,
+		AccessMode:          "restricted",
+		AllowedPrincipalIDs: []string{"github_user://1", "github_org://2"},
+	}
+	obj, err := c.sync("github", config)
+	assert.Nil(err)
+	assert.Equal([]string{"github"}, refreshed)
+	config = obj.(*v3.AuthConfig)
+
+	// other updates and reordered principals do not change the access
+	config.Annotations["other"] = "value"
+	config.AllowedPrincipalIDs = []string{"github_org://2", "github_user://1"}
+	_, err = c.sync("github", config)
+	assert.Nil(err)
+	assert.Len(refreshed, 1)
+
+	config.AllowedPrincipalIDs = []string{"github_org://2"}
+	_, err = c.sync("github", config)
+	assert.Nil(err)
+	assert.Len(refreshed, 2, "users are refreshed when an allowed principal is removed")
+}
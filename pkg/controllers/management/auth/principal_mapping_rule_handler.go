@@ -0,0 +1,468 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	principalMappingRuleController          = "mgmt-auth-principal-mapping-rule-controller"
+	principalMappingUserAttributeController = "mgmt-auth-principal-mapping-userattribute-controller"
+	principalMappingClusterController       = "mgmt-auth-principal-mapping-cluster-controller"
+	principalMappingProjectController       = "mgmt-auth-principal-mapping-project-controller"
+
+	principalMappingRuleLabel = "authz.management.cattle.io/principal-mapping-rule"
+	principalMappingUserLabel = "authz.management.cattle.io/principal-mapping-user"
+)
+
+// principalMappingRuleHandler maintains the bindings that principal mapping
+// rules grant to users. Bindings are labeled with the rule and the user they
+// were created for, and their names are derived from what they bind, so they
+// are only ever created or deleted.
+type principalMappingRuleHandler struct {
+	// scopes holds the last seen state of the clusters and projects that
+	// rules bind to, so that updates of their status are ignored.
+	scopes     map[string]string
+	scopesLock sync.Mutex
+
+	rules               v3.PrincipalMappingRuleLister
+	users               v3.UserLister
+	userAttributes      v3.UserAttributeController
+	userAttributeLister v3.UserAttributeLister
+	clusters            v3.ClusterLister
+	projects            v3.ProjectLister
+	grbs                v3.GlobalRoleBindingInterface
+	grbLister           v3.GlobalRoleBindingLister
+	crtbs               v3.ClusterRoleTemplateBindingInterface
+	crtbLister          v3.ClusterRoleTemplateBindingLister
+	prtbs               v3.ProjectRoleTemplateBindingInterface
+	prtbLister          v3.ProjectRoleTemplateBindingLister
+}
+
+func newPrincipalMappingRuleHandler(mgmt *config.ManagementContext) *principalMappingRuleHandler {
+	return &principalMappingRuleHandler{
+		scopes:              map[string]string{},
+		rules:               mgmt.Management.PrincipalMappingRules("").Controller().Lister(),
+		users:               mgmt.Management.Users("").Controller().Lister(),
+		userAttributes:      mgmt.Management.UserAttributes("").Controller(),
+		userAttributeLister: mgmt.Management.UserAttributes("").Controller().Lister(),
+		clusters:            mgmt.Management.Clusters("").Controller().Lister(),
+		projects:            mgmt.Management.Projects("").Controller().Lister(),
+		grbs:                mgmt.Management.GlobalRoleBindings(""),
+		grbLister:           mgmt.Management.GlobalRoleBindings("").Controller().Lister(),
+		crtbs:               mgmt.Management.ClusterRoleTemplateBindings(""),
+		crtbLister:          mgmt.Management.ClusterRoleTemplateBindings("").Controller().Lister(),
+		prtbs:               mgmt.Management.ProjectRoleTemplateBindings(""),
+		prtbLister:          mgmt.Management.ProjectRoleTemplateBindings("").Controller().Lister(),
+	}
+}
+
+// mappedBindings are the bindings of a user keyed by namespace and name.
+type mappedBindings struct {
+	grbs  map[string]*v3.GlobalRoleBinding
+	crtbs map[string]*v3.ClusterRoleTemplateBinding
+	prtbs map[string]*v3.ProjectRoleTemplateBinding
+}
+
+func (h *principalMappingRuleHandler) syncUserAttribute(key string, obj *v3.UserAttribute) (runtime.Object, error) {
+	if obj == nil || obj.DeletionTimestamp != nil {
+		// the user lifecycle removes the bindings of deleted users
+		return obj, nil
+	}
+	return obj, h.reconcile(obj)
+}
+
+// syncRule resyncs all users, as a changed or deleted rule may match any of
+// them.
+func (h *principalMappingRuleHandler) syncRule(key string, obj *v3.PrincipalMappingRule) (runtime.Object, error) {
+	attributes, err := h.userAttributeLister.List("", labels.Everything())
+	if err != nil {
+		return obj, err
+	}
+	for _, attribute := range attributes {
+		h.userAttributes.Enqueue("", attribute.Name)
+	}
+	return obj, nil
+}
+
+// syncCluster resyncs the users whose bindings in a cluster changed, such as
+// when the labels of the cluster start or stop matching a cluster selector.
+// Only the labels and deletion of a cluster can change which rules select it.
+func (h *principalMappingRuleHandler) syncCluster(key string, obj *v3.Cluster) (runtime.Object, error) {
+	if obj == nil {
+		h.forgetScope("cluster:" + key)
+		return obj, nil
+	}
+	state := fmt.Sprintf("%t/%s", obj.DeletionTimestamp != nil, labels.Set(obj.Labels).String())
+	if !h.scopeChanged("cluster:"+key, state) {
+		return obj, nil
+	}
+	rules, err := h.rules.List("", labels.Everything())
+	if err != nil {
+		return obj, err
+	}
+	var selected []*v3.PrincipalMappingRule
+	for _, rule := range rules {
+		if len(rule.ClusterRoles) > 0 {
+			selected = append(selected, rule)
+		}
+	}
+	if err := h.enqueueOutdated(obj.Name, selected); err != nil {
+		return obj, err
+	}
+	h.setScope("cluster:"+key, state)
+	return obj, nil
+}
+
+// syncProject resyncs the users with bindings in a project that was created
+// after the rules binding them to it, or that is being deleted.
+func (h *principalMappingRuleHandler) syncProject(key string, obj *v3.Project) (runtime.Object, error) {
+	if obj == nil {
+		h.forgetScope("project:" + key)
+		return obj, nil
+	}
+	state := fmt.Sprintf("%t", obj.DeletionTimestamp != nil)
+	if !h.scopeChanged("project:"+key, state) {
+		return obj, nil
+	}
+	rules, err := h.rules.List("", labels.Everything())
+	if err != nil {
+		return obj, err
+	}
+	projectName := obj.Namespace + ":" + obj.Name
+	var selected []*v3.PrincipalMappingRule
+	for _, rule := range rules {
+		for _, mapping := range rule.ProjectRoles {
+			if mapping.ProjectName == projectName {
+				selected = append(selected, rule)
+				break
+			}
+		}
+	}
+	if err := h.enqueueOutdated(obj.Name, selected); err != nil {
+		return obj, err
+	}
+	h.setScope("project:"+key, state)
+	return obj, nil
+}
+
+func (h *principalMappingRuleHandler) scopeChanged(key, state string) bool {
+	h.scopesLock.Lock()
+	defer h.scopesLock.Unlock()
+	last, ok := h.scopes[key]
+	return !ok || last != state
+}
+
+func (h *principalMappingRuleHandler) setScope(key, state string) {
+	h.scopesLock.Lock()
+	defer h.scopesLock.Unlock()
+	h.scopes[key] = state
+}
+
+func (h *principalMappingRuleHandler) forgetScope(key string) {
+	h.scopesLock.Lock()
+	defer h.scopesLock.Unlock()
+	delete(h.scopes, key)
+}
+
+// enqueueOutdated resyncs the users whose bindings in a namespace differ from
+// those that the given rules grant. The other rules do not bind to the
+// namespace.
+func (h *principalMappingRuleHandler) enqueueOutdated(namespace string, rules []*v3.PrincipalMappingRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	attributes, err := h.userAttributeLister.List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, attribute := range attributes {
+		desired, err := h.desiredBindings(attribute, rules)
+		if err != nil {
+			return err
+		}
+		existing, err := h.existingBindings(attribute.Name)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(desired.namespaceKeys(namespace), existing.namespaceKeys(namespace)) {
+			h.userAttributes.Enqueue("", attribute.Name)
+		}
+	}
+	return nil
+}
+
+func (h *principalMappingRuleHandler) reconcile(attribute *v3.UserAttribute) error {
+	rules, err := h.rules.List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+	desired, err := h.desiredBindings(attribute, rules)
+	if err != nil {
+		return err
+	}
+	existing, err := h.existingBindings(attribute.Name)
+	if err != nil {
+		return err
+	}
+
+	for key, grb := range existing.grbs {
+		if _, ok := desired.grbs[key]; !ok {
+			logrus.Infof("[%v] Deleting globalRoleBinding %v of user %v", principalMappingRuleController, grb.Name, attribute.Name)
+			if err := h.grbs.Delete(grb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	for key, crtb := range existing.crtbs {
+		if _, ok := desired.crtbs[key]; !ok {
+			logrus.Infof("[%v] Deleting clusterRoleTemplateBinding %v of user %v", principalMappingRuleController, key, attribute.Name)
+			if err := h.crtbs.DeleteNamespaced(crtb.Namespace, crtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	for key, prtb := range existing.prtbs {
+		if _, ok := desired.prtbs[key]; !ok {
+			logrus.Infof("[%v] Deleting projectRoleTemplateBinding %v of user %v", principalMappingRuleController, key, attribute.Name)
+			if err := h.prtbs.DeleteNamespaced(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	for key, grb := range desired.grbs {
+		if _, ok := existing.grbs[key]; !ok {
+			logrus.Infof("[%v] Creating globalRoleBinding %v of user %v", principalMappingRuleController, grb.Name, attribute.Name)
+			if _, err := h.grbs.Create(grb); err != nil && !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+		}
+	}
+	for key, crtb := range desired.crtbs {
+		if _, ok := existing.crtbs[key]; !ok {
+			logrus.Infof("[%v] Creating clusterRoleTemplateBinding %v of user %v", principalMappingRuleController, key, attribute.Name)
+			if _, err := h.crtbs.Create(crtb); err != nil && !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+		}
+	}
+	for key, prtb := range desired.prtbs {
+		if _, ok := existing.prtbs[key]; !ok {
+			logrus.Infof("[%v] Creating projectRoleTemplateBinding %v of user %v", principalMappingRuleController, key, attribute.Name)
+			if _, err := h.prtbs.Create(prtb); err != nil && !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (h *principalMappingRuleHandler) existingBindings(userName string) (mappedBindings, error) {
+	bindings := newMappedBindings()
+	selector := labels.SelectorFromSet(labels.Set{principalMappingUserLabel: userName})
+
+	grbs, err := h.grbLister.List("", selector)
+	if err != nil {
+		return bindings, err
+	}
+	for _, grb := range grbs {
+		bindings.grbs[grb.Name] = grb
+	}
+	crtbs, err := h.crtbLister.List("", selector)
+	if err != nil {
+		return bindings, err
+	}
+	for _, crtb := range crtbs {
+		bindings.crtbs[crtb.Namespace+"/"+crtb.Name] = crtb
+	}
+	prtbs, err := h.prtbLister.List("", selector)
+	if err != nil {
+		return bindings, err
+	}
+	for _, prtb := range prtbs {
+		bindings.prtbs[prtb.Namespace+"/"+prtb.Name] = prtb
+	}
+	return bindings, nil
+}
+
+// desiredBindings returns the bindings that the rules matching a user grant
+// to the user.
+func (h *principalMappingRuleHandler) desiredBindings(attribute *v3.UserAttribute, rules []*v3.PrincipalMappingRule) (mappedBindings, error) {
+	bindings := newMappedBindings()
+	user, err := h.users.Get("", attribute.Name)
+	if apierrors.IsNotFound(err) {
+		return bindings, nil
+	} else if err != nil {
+		return bindings, err
+	}
+	if user.DeletionTimestamp != nil {
+		return bindings, nil
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	for _, rule := range rules {
+		if rule.DeletionTimestamp != nil || !ruleMatches(rule, user, attribute) {
+			continue
+		}
+		ruleLabels := map[string]string{
+			principalMappingRuleLabel: rule.Name,
+			principalMappingUserLabel: user.Name,
+		}
+
+		for _, globalRole := range rule.GlobalRoleNames {
+			name := mappedBindingName(rule.Name, user.Name, globalRole)
+			bindings.grbs[name] = &v3.GlobalRoleBinding{
+				ObjectMeta:     metav1.ObjectMeta{Name: name, Labels: ruleLabels},
+				UserName:       user.Name,
+				GlobalRoleName: globalRole,
+			}
+		}
+
+		for _, mapping := range rule.ClusterRoles {
+			clusters, err := h.clusters.List("", labels.SelectorFromSet(mapping.ClusterSelector))
+			if err != nil {
+				return bindings, err
+			}
+			for _, cluster := range clusters {
+				if cluster.DeletionTimestamp != nil {
+					continue
+				}
+				name := mappedBindingName(rule.Name, user.Name, cluster.Name, mapping.RoleTemplateName)
+				bindings.crtbs[cluster.Name+"/"+name] = &v3.ClusterRoleTemplateBinding{
+					ObjectMeta:       metav1.ObjectMeta{Name: name, Namespace: cluster.Name, Labels: ruleLabels},
+					UserName:         user.Name,
+					ClusterName:      cluster.Name,
+					RoleTemplateName: mapping.RoleTemplateName,
+				}
+			}
+		}
+
+		for _, mapping := range rule.ProjectRoles {
+			parts := strings.SplitN(mapping.ProjectName, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			project, err := h.projects.Get(parts[0], parts[1])
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return bindings, err
+			}
+			if project.DeletionTimestamp != nil {
+				continue
+			}
+			name := mappedBindingName(rule.Name, user.Name, mapping.ProjectName, mapping.RoleTemplateName)
+			bindings.prtbs[project.Name+"/"+name] = &v3.ProjectRoleTemplateBinding{
+				ObjectMeta:       metav1.ObjectMeta{Name: name, Namespace: project.Name, Labels: ruleLabels},
+				UserName:         user.Name,
+				ProjectName:      mapping.ProjectName,
+				RoleTemplateName: mapping.RoleTemplateName,
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// ruleMatches returns whether a user principal, group principal or provider
+// attribute of the user matches the rule. Rules without patterns match no one.
+func ruleMatches(rule *v3.PrincipalMappingRule, user *v3.User, attribute *v3.UserAttribute) bool {
+	for _, principalID := range user.PrincipalIDs {
+		if rule.Provider != "" && principalProvider(principalID) != rule.Provider {
+			continue
+		}
+		if matchesAny(rule.UserPrincipalPatterns, principalID) {
+			return true
+		}
+	}
+	for key, principals := range attribute.GroupPrincipals {
+		if rule.Provider != "" && tokens.GroupPrincipalsProvider(key) != rule.Provider {
+			continue
+		}
+		for _, principal := range principals.Items {
+			if matchesAny(rule.GroupPrincipalPatterns, principal.Name) {
+				return true
+			}
+		}
+	}
+	for provider, extras := range attribute.ExtraByProvider {
+		if rule.Provider != "" && provider != rule.Provider {
+			continue
+		}
+		for name, patterns := range rule.UserAttributePatterns {
+			for _, value := range extras[name] {
+				if matchesAny(patterns, value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// principalProvider returns the provider of a principal ID such as
+// "openldap_user://cn=..." or "local://u-abcde".
+func principalProvider(principalID string) string {
+	scheme := strings.SplitN(principalID, "://", 2)[0]
+	return strings.SplitN(scheme, "_", 2)[0]
+}
+
+// matchesAny returns whether a value matches any of the patterns, in which *
+// matches any characters and ? any single character.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+		if matched, _ := regexp.MatchString("^"+expr+"$", value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func mappedBindingName(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return "pmr-" + hex.EncodeToString(sum[:])[:16]
+}
+
+func newMappedBindings() mappedBindings {
+	return mappedBindings{
+		grbs:  map[string]*v3.GlobalRoleBinding{},
+		crtbs: map[string]*v3.ClusterRoleTemplateBinding{},
+		prtbs: map[string]*v3.ProjectRoleTemplateBinding{},
+	}
+}
+
+// namespaceKeys returns the keys of the cluster and project role template
+// bindings in a namespace.
+func (b mappedBindings) namespaceKeys(namespace string) map[string]bool {
+	keys := map[string]bool{}
+	prefix := namespace + "/"
+	for key := range b.crtbs {
+		if strings.HasPrefix(key, prefix) {
+			keys[key] = true
+		}
+	}
+	for key := range b.prtbs {
+		if strings.HasPrefix(key, prefix) {
+			keys[key] = true
+		}
+	}
+	return keys
+}
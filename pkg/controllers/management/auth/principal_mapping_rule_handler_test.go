@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type principalMappingTestStore struct {
+	grbs       map[string]*v3.GlobalRoleBinding
+	crtbs      map[string]*v3.ClusterRoleTemplateBinding
+	prtbs      map[string]*v3.ProjectRoleTemplateBinding
+	clusters   map[string]*v3.Cluster
+	attributes map[string]*v3.UserAttribute
+	enqueued   []string
+}
+
+func newPrincipalMappingTestHandler() (*principalMappingRuleHandler, *principalMappingTestStore) {
+	store := &principalMappingTestStore{
+		grbs:  map[string]*v3.GlobalRoleBinding{},
+		crtbs: map[string]*v3.ClusterRoleTemplateBinding{},
+		prtbs: map[string]*v3.ProjectRoleTemplateBinding{},
+		clusters: map[string]*v3.Cluster{
+			"c-1": {ObjectMeta: v1.ObjectMeta{Name: "c-1", Labels: map[string]string{"env": "prod"}}},
+			"c-2": {ObjectMeta: v1.ObjectMeta{Name: "c-2", Labels: map[string]string{"env": "dev"}}},
+		},
+		attributes: map[string]*v3.UserAttribute{
+			"u-1": {
+				ObjectMeta: v1.ObjectMeta{Name: "u-1"},
+				GroupPrincipals: map[string]v32.Principals{
+					"okta": {Items: []v32.Principal{{ObjectMeta: v1.ObjectMeta{Name: "okta_group://eng-web"}}}},
+				},
+			},
+		},
+	}
+	rules := []*v3.PrincipalMappingRule{
+		{
+			ObjectMeta:             v1.ObjectMeta{Name: "engineering"},
+			Provider:               "okta",
+			GroupPrincipalPatterns: []string{"okta_group://eng-*"},
+			GlobalRoleNames:        []string{"user"},
+			ClusterRoles: []v32.ClusterRoleMapping{
+				{RoleTemplateName: "cluster-member", ClusterSelector: map[string]string{"env": "prod"}},
+			},
+		},
+		{
+			ObjectMeta:            v1.ObjectMeta{Name: "admins"},
+			UserPrincipalPatterns: []string{"openldap_user://*,ou=admins,dc=example,dc=com"},
+			ProjectRoles: []v32.ProjectRoleMapping{
+				{RoleTemplateName: "project-owner", ProjectName: "c-1:p-1"},
+			},
+		},
+		{
+			ObjectMeta:             v1.ObjectMeta{Name: "github"},
+			Provider:               "github",
+			GroupPrincipalPatterns: []string{"*"},
+			GlobalRoleNames:        []string{"admin"},
+		},
+	}
+	notFound := func(resource, name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+
+	h := &principalMappingRuleHandler{
+		scopes: map[string]string{},
+		rules: &fakes.PrincipalMappingRuleListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.PrincipalMappingRule, error) {
+				return rules, nil
+			},
+		},
+		users: &fakes.UserListerMock{
+			GetFunc: func(namespace, name string) (*v3.User, error) {
+				if name != "u-1" {
+					return nil, notFound("users", name)
+				}
+				return &v3.User{
+					ObjectMeta:   v1.ObjectMeta{Name: "u-1"},
+					PrincipalIDs: []string{"openldap_user://uid=jdoe,ou=admins,dc=example,dc=com", "local://u-1"},
+				}, nil
+			},
+		},
+		userAttributes: &fakes.UserAttributeControllerMock{
+			EnqueueFunc: func(namespace, name string) {
+				store.enqueued = append(store.enqueued, name)
+			},
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.UserAttribute, error) {
+				var result []*v3.UserAttribute
+				for _, attribute := range store.attributes {
+					result = append(result, attribute)
+				}
+				return result, nil
+			},
+		},
+		clusters: &fakes.ClusterListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Cluster, error) {
+				var result []*v3.Cluster
+				for _, cluster := range store.clusters {
+					if selector.Matches(labels.Set(cluster.Labels)) {
+						result = append(result, cluster)
+					}
+				}
+				return result, nil
+			},
+		},
+		projects: &fakes.ProjectListerMock{
+			GetFunc: func(namespace, name string) (*v3.Project, error) {
+				if namespace != "c-1" || name != "p-1" {
+					return nil, notFound("projects", name)
+				}
+				return &v3.Project{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace}}, nil
+			},
+		},
+		grbs: &fakes.GlobalRoleBindingInterfaceMock{
+			CreateFunc: func(grb *v3.GlobalRoleBinding) (*v3.GlobalRoleBinding, error) {
+				store.grbs[grb.Name] = grb
+				return grb, nil
+			},
+			DeleteFunc: func(name string, options *v1.DeleteOptions) error {
+				delete(store.grbs, name)
+				return nil
+			},
+		},
+		grbLister: &fakes.GlobalRoleBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.GlobalRoleBinding, error) {
+				var result []*v3.GlobalRoleBinding
+				for _, grb := range store.grbs {
+					if selector.Matches(labels.Set(grb.Labels)) {
+						result = append(result, grb)
+					}
+				}
+				return result, nil
+			},
+		},
+		crtbs: &fakes.ClusterRoleTemplateBindingInterfaceMock{
+			CreateFunc: func(crtb *v3.ClusterRoleTemplateBinding) (*v3.ClusterRoleTemplateBinding, error) {
+				store.crtbs[crtb.Namespace+"/"+crtb.Name] = crtb
+				return crtb, nil
+			},
+			DeleteNamespacedFunc: func(namespace, name string, options *v1.DeleteOptions) error {
+				delete(store.crtbs, namespace+"/"+name)
+				return nil
+			},
+		},
+		crtbLister: &fakes.ClusterRoleTemplateBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ClusterRoleTemplateBinding, error) {
+				var result []*v3.ClusterRoleTemplateBinding
+				for _, crtb := range store.crtbs {
+					if selector.Matches(labels.Set(crtb.Labels)) {
+						result = append(result, crtb)
+					}
+				}
+				return result, nil
+			},
+		},
+		prtbs: &fakes.ProjectRoleTemplateBindingInterfaceMock{
+			CreateFunc: func(prtb *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+				store.prtbs[prtb.Namespace+"/"+prtb.Name] = prtb
+				return prtb, nil
+			},
+			DeleteNamespacedFunc: func(namespace, name string, options *v1.DeleteOptions) error {
+				delete(store.prtbs, namespace+"/"+name)
+				return nil
+			},
+		},
+		prtbLister: &fakes.ProjectRoleTemplateBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ProjectRoleTemplateBinding, error) {
+				var result []*v3.ProjectRoleTemplateBinding
+				for _, prtb := range store.prtbs {
+					if selector.Matches(labels.Set(prtb.Labels)) {
+						result = append(result, prtb)
+					}
+				}
+				return result, nil
+			},
+		},
+	}
+	return h, store
+}
+
+func TestPrincipalMappingRuleReconcile(t *testing.T) {
+	assert := assert.New(t)
+	h, store := newPrincipalMappingTestHandler()
+	attribute := store.attributes["u-1"]
+
+	_, err := h.syncUserAttribute("u-1", attribute)
+	assert.Nil(err)
+	if assert.Len(store.grbs, 1) {
+		for _, grb := range store.grbs {
+			assert.Equal("user", grb.GlobalRoleName)
+			assert.Equal("u-1", grb.UserName)
+			assert.Equal("engineering", grb.Labels[principalMappingRuleLabel])
+		}
+	}
+	if assert.Len(store.crtbs, 1, "only clusters matching the selector get bindings") {
+		for _, crtb := range store.crtbs {
+			assert.Equal("c-1", crtb.Namespace)
+			assert.Equal("c-1", crtb.ClusterName)
+			assert.Equal("cluster-member", crtb.RoleTemplateName)
+		}
+	}
+	if assert.Len(store.prtbs, 1, "rules match user principals") {
+		for _, prtb := range store.prtbs {
+			assert.Equal("p-1", prtb.Namespace)
+			assert.Equal("c-1:p-1", prtb.ProjectName)
+			assert.Equal("project-owner", prtb.RoleTemplateName)
+		}
+	}
+
+	_, err = h.syncUserAttribute("u-1", attribute)
+	assert.Nil(err)
+	assert.Len(store.grbs, 1, "reconciling again changes nothing")
+	assert.Len(store.crtbs, 1)
+
+	attribute.GroupPrincipals["okta"] = v32.Principals{Items: []v32.Principal{{ObjectMeta: v1.ObjectMeta{Name: "okta_group://sales"}}}}
+	_, err = h.syncUserAttribute("u-1", attribute)
+	assert.Nil(err)
+	assert.Empty(store.grbs, "bindings are removed when the groups stop matching")
+	assert.Empty(store.crtbs)
+	assert.Len(store.prtbs, 1)
+}
+
+func TestPrincipalMappingRuleSyncCluster(t *testing.T) {
+	assert := assert.New(t)
+	h, store := newPrincipalMappingTestHandler()
+	_, err := h.syncUserAttribute("u-1", store.attributes["u-1"])
+	assert.Nil(err)
+
+	_, err = h.syncCluster("c-1", store.clusters["c-1"])
+	assert.Nil(err)
+	assert.Empty(store.enqueued, "users with up to date bindings are not resynced")
+
+	store.clusters["c-2"].Labels["env"] = "prod"
+	_, err = h.syncCluster("c-2", store.clusters["c-2"])
+	assert.Nil(err)
+	assert.Equal([]string{"u-1"}, store.enqueued)
+
+	_, err = h.syncUserAttribute("u-1", store.attributes["u-1"])
+	assert.Nil(err)
+	assert.Len(store.crtbs, 2)
+
+	store.enqueued = nil
+	store.clusters["c-1"].Labels["env"] = "dev"
+	store.clusters["c-1"].Status.Driver = "imported"
+	_, err = h.syncCluster("c-1", store.clusters["c-1"])
+	assert.Nil(err)
+	assert.Equal([]string{"u-1"}, store.enqueued, "label changes resync users")
+
+	store.enqueued = nil
+	store.clusters["c-1"].Status.Driver = "rancherKubernetesEngine"
+	_, err = h.syncCluster("c-1", store.clusters["c-1"])
+	assert.Nil(err)
+	assert.Empty(store.enqueued, "status updates are ignored")
+}
+
+func TestPrincipalMappingRuleSyncProject(t *testing.T) {
+	assert := assert.New(t)
+	h, store := newPrincipalMappingTestHandler()
+
+	project := &v3.Project{ObjectMeta: v1.ObjectMeta{Name: "p-1", Namespace: "c-1"}}
+	_, err := h.syncProject("c-1/p-1", project)
+	assert.Nil(err)
+	assert.Equal([]string{"u-1"}, store.enqueued, "users are resynced for projects created after their rules")
+
+	store.enqueued = nil
+	project.Status.PodSecurityPolicyTemplateName = "restricted"
+	_, err = h.syncProject("c-1/p-1", project)
+	assert.Nil(err)
+	assert.Empty(store.enqueued, "status updates are ignored")
+
+	_, err = h.syncProject("c-1/p-2", &v3.Project{ObjectMeta: v1.ObjectMeta{Name: "p-2", Namespace: "c-1"}})
+	assert.Nil(err)
+	assert.Empty(store.enqueued, "projects no rule binds to are ignored")
+}
+
+func TestRuleMatchesUserAttributes(t *testing.T) {
+	assert := assert.New(t)
+	rule := &v3.PrincipalMappingRule{
+		Provider:              "okta",
+		UserAttributePatterns: map[string][]string{"department": {"eng*"}},
+	}
+	user := &v3.User{PrincipalIDs: []string{"okta_user://jdoe"}}
+	attribute := &v3.UserAttribute{
+		ExtraByProvider: map[string]map[string][]string{
+			"okta": {"department": {"sales", "engineering"}},
+		},
+	}
+	assert.True(ruleMatches(rule, user, attribute))
+
+	attribute.ExtraByProvider["okta"]["department"] = []string{"sales"}
+	assert.False(ruleMatches(rule, user, attribute))
+
+	attribute.ExtraByProvider = map[string]map[string][]string{"ping": {"department": {"engineering"}}}
+	assert.False(ruleMatches(rule, user, attribute), "attributes of other providers do not match")
+}
+
+func TestMatchesAny(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(matchesAny([]string{"okta_group://eng-*"}, "okta_group://eng-web"))
+	assert.True(matchesAny([]string{"openldap_user://*,dc=example,dc=com"}, "openldap_user://uid=a,ou=b,dc=example,dc=com"))
+	assert.True(matchesAny([]string{"github_team://1?"}, "github_team://12"))
+	assert.False(matchesAny([]string{"okta_group://eng-*"}, "okta_group://sales"))
+	assert.False(matchesAny([]string{"okta_group://eng.web"}, "okta_group://eng-web"), "patterns are not regular expressions")
+	assert.False(matchesAny(nil, "okta_group://eng-web"))
+	assert.Equal("openldap", principalProvider("openldap_user://uid=a"))
+	assert.Equal("local", principalProvider("local://u-1"))
+}
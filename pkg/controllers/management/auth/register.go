@@ -64,7 +64,9 @@ func RegisterEarly(ctx context.Context, management *config.ManagementContext, cl
 	n := newTokenController(management)
 	ua := newUserAttributeController(management)
 	s := newAuthSettingController(management)
+	ac := newAuthConfigController(management)
 	rt := newRoleTemplateLifecycle(management, clusterManager)
+	pmr := newPrincipalMappingRuleHandler(management)
 	grbLegacy := newLegacyGRBCleaner(management)
 	rtLegacy := newLegacyRTCleaner(management)
 
@@ -80,6 +82,11 @@ func RegisterEarly(ctx context.Context, management *config.ManagementContext, cl
 	management.Management.Tokens("").AddHandler(ctx, tokenController, n.sync)
 	management.Management.UserAttributes("").AddHandler(ctx, userAttributeController, ua.sync)
 	management.Management.Settings("").AddHandler(ctx, authSettingController, s.sync)
+	management.Management.AuthConfigs("").AddHandler(ctx, authConfigController, ac.sync)
+	management.Management.PrincipalMappingRules("").AddHandler(ctx, principalMappingRuleController, pmr.syncRule)
+	management.Management.UserAttributes("").AddHandler(ctx, principalMappingUserAttributeController, pmr.syncUserAttribute)
+	management.Management.Clusters("").AddHandler(ctx, principalMappingClusterController, pmr.syncCluster)
+	management.Management.Projects("").AddHandler(ctx, principalMappingProjectController, pmr.syncProject)
 	management.Management.GlobalRoleBindings("").AddHandler(ctx, "legacy-grb-cleaner", grbLegacy.sync)
 	management.Management.RoleTemplates("").AddHandler(ctx, "legacy-rt-cleaner", rtLegacy.sync)
 }
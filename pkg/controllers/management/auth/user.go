@@ -153,6 +153,23 @@ func (l *userLifecycle) Updated(user *v3.User) (runtime.Object, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if user.Enabled != nil && !*user.Enabled {
+		// disabled users are logged out of their sessions
+		tokens, err := l.getTokensByUserName(user.Name)
+		if err != nil {
+			return nil, err
+		}
+		var sessions []*v3.Token
+		for _, token := range tokens {
+			if !token.IsDerived {
+				sessions = append(sessions, token)
+			}
+		}
+		if err := l.deleteAllTokens(sessions); err != nil {
+			return nil, err
+		}
+	}
 	return user, nil
 }
 
@@ -9,6 +9,7 @@
 	rketypes "github.com/rancher/rke/types"
 
 	"github.com/rancher/rancher/pkg/clusterprovisioninglogger"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainer-engine/service"
 	"github.com/rancher/rke/services"
@@ -163,6 +164,9 @@ func cleanRKE(spec v32.ClusterSpec) v32.ClusterSpec {
 		return spec
 	}
 
+	// rancher uploads the snapshots of clusters with encryption enabled itself
+	spec = snapshot.WithoutS3Upload(spec)
+
 	result := spec.DeepCopy()
 
 	var filteredNodes []rketypes.RKEConfigNode
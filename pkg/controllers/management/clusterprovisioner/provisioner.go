@@ -20,6 +20,7 @@
 	util "github.com/rancher/rancher/pkg/cluster"
 	kd "github.com/rancher/rancher/pkg/controllers/management/kontainerdrivermetadata"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/apps/v1"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/rke"
 	"github.com/rancher/rancher/pkg/kontainer-engine/service"
@@ -28,6 +29,7 @@
 	"github.com/rancher/rancher/pkg/rkedialerfactory"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rke/hosts"
 	"github.com/rancher/rke/services"
 	rketypes "github.com/rancher/rke/types"
 	"github.com/sirupsen/logrus"
@@ -43,6 +45,7 @@
 	RKEDriverKey          = "rancherKubernetesEngineConfig"
 	KontainerEngineUpdate = "provisioner.cattle.io/ke-driver-update"
 	RkeRestoreAnnotation  = "rke.cattle.io/restore"
+	compressedExtension   = "zip"
 )
 
 type Provisioner struct {
@@ -58,6 +61,8 @@ type Provisioner struct {
 	Backups               v3.EtcdBackupLister
 	RKESystemImages       v3.RkeK8sSystemImageInterface
 	RKESystemImagesLister v3.RkeK8sSystemImageLister
+	SecretLister          corev1.SecretLister
+	DockerDialer          hosts.DialerFactory
 }
 
 func Register(ctx context.Context, management *config.ManagementContext) {
@@ -74,6 +79,7 @@ func Register(ctx context.Context, management *config.ManagementContext) {
 		RKESystemImagesLister: management.Management.RkeK8sSystemImages("").Controller().Lister(),
 		RKESystemImages:       management.Management.RkeK8sSystemImages(""),
 		DaemonsetLister:       management.Apps.DaemonSets("").Controller().Lister(),
+		SecretLister:          management.Core.Secrets("").Controller().Lister(),
 	}
 	// Add handlers
 	p.Clusters.AddLifecycle(ctx, "cluster-provisioner-controller", p)
@@ -92,6 +98,7 @@ func Register(ctx context.Context, management *config.ManagementContext) {
 	driver := service.Drivers[service.RancherKubernetesEngineDriverName]
 	rkeDriver := driver.(*rke.Driver)
 	rkeDriver.DockerDialer = docker.Build
+	p.DockerDialer = docker.Build
 	rkeDriver.LocalDialer = local.Build
 	rkeDriver.WrapTransportFactory = docker.WrapTransport
 	mgmt := management.Management
@@ -945,7 +952,13 @@ func (p *Provisioner) restoreClusterBackup(cluster *v3.Cluster, spec apimgmtv3.C
 		return "", "", "", fmt.Errorf("snapshot [%s] is not a backup of cluster [%s]", backup.Name, cluster.Name)
 	}
 
-	api, token, cert, err = p.driverRestore(cluster, spec, GetBackupFilename(backup))
+	snapshotName, restoreSpec, cleanup, err := p.prepareRestoreSnapshot(cluster, spec, backup)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer cleanup()
+
+	api, token, cert, err = p.driverRestore(cluster, restoreSpec, snapshotName)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -0,0 +1,163 @@
+package clusterprovisioner
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+)
+
+const restoreSnapshotSuffix = "-restore"
+
+// prepareRestoreSnapshot returns the name of the snapshot rke should restore
+// backup from and the cluster spec to restore with, which reads the snapshot
+// from the S3 config the backup was taken with. rke can only read plain
+// snapshots from S3, so encrypted backups, backups stored on a primary target
+// or only left on one of the additional backup targets and backups of
+// clusters with encryption enabled are decrypted onto the etcd nodes and
+// restored from there; the returned cleanup function removes the staged
+// copies. Plain snapshots are never written to S3 or the backup targets.
+func (p *Provisioner) prepareRestoreSnapshot(cluster *v3.Cluster, spec apimgmtv3.ClusterSpec, backup *v3.EtcdBackup) (string, apimgmtv3.ClusterSpec, func(), error) {
+	snapshotName := GetBackupFilename(backup)
+	noop := func() {}
+	sbc := backup.Spec.BackupConfig.S3BackupConfig
+	restoreSpec := withRestoreS3Config(spec, sbc)
+	encrypted := backup.Status.Encryption != nil
+	replicas := snapshot.GetReplicas(backup.Annotations[snapshot.ReplicasAnnotation])
+	encryptionEnabled := snapshot.IsEncryptionEnabled(&spec)
+	if !encrypted && len(replicas) == 0 && backup.Status.Target == "" && !encryptionEnabled {
+		return snapshotName, restoreSpec, noop, nil
+	}
+
+	ctx := context.Background()
+	primary, err := getPrimaryRestoreTarget(ctx, spec, backup)
+	if err != nil {
+		return "", spec, noop, err
+	}
+	if primary == nil {
+		if encrypted {
+			return "", spec, noop, fmt.Errorf("snapshot [%s] is encrypted and can only be restored from S3 or a backup target", backup.Name)
+		}
+		return snapshotName, restoreSpec, noop, nil
+	}
+
+	filename := snapshotName + "." + compressedExtension
+	src, err := p.getRestoreSource(ctx, spec, primary, filename, replicas)
+	if err != nil {
+		return "", spec, noop, err
+	}
+	if src == primary && sbc != nil && !encrypted && !encryptionEnabled {
+		return snapshotName, restoreSpec, noop, nil
+	}
+
+	var encryption *apimgmtv3.EtcdBackupEncryption
+	if spec.EtcdBackupConfig != nil {
+		encryption = spec.EtcdBackupConfig.Encryption
+	}
+	keyring, err := snapshot.LoadKeyring(p.SecretLister, encryption)
+	if err != nil {
+		return "", spec, noop, err
+	}
+	nodes, err := snapshot.NewEtcdNodeTargets(spec.RancherKubernetesEngineConfig, p.DockerDialer)
+	if err != nil {
+		return "", spec, noop, err
+	}
+
+	stagedName := snapshotName + restoreSnapshotSuffix
+	stagedFilename := stagedName + "." + compressedExtension
+	logrus.Infof("[etcd-backup] Staging snapshot [%s] of cluster [%s] on the etcd nodes for restore", backup.Name, cluster.Name)
+	if err := snapshot.StageDecrypted(ctx, src, filename, keyring, nodes, stagedFilename); err != nil {
+		removeStaged(ctx, nodes, stagedFilename)
+		return "", spec, noop, err
+	}
+	return stagedName, withRestoreS3Config(spec, nil), func() {
+		removeStaged(ctx, nodes, stagedFilename)
+	}, nil
+}
+
+// getPrimaryRestoreTarget returns the target the snapshot of backup is stored
+// on, or nil if it is only kept on the etcd nodes.
+func getPrimaryRestoreTarget(ctx context.Context, spec apimgmtv3.ClusterSpec, backup *v3.EtcdBackup) (snapshot.Target, error) {
+	if sbc := backup.Spec.BackupConfig.S3BackupConfig; sbc != nil {
+		// the cluster may be down, so S3 is reached from rancher directly
+		return snapshot.NewS3Target(sbc, (&net.Dialer{}).DialContext)
+	}
+	if backup.Status.Target == "" {
+		return nil, nil
+	}
+	var targets []apimgmtv3.EtcdBackupTarget
+	if spec.EtcdBackupConfig != nil {
+		targets = spec.EtcdBackupConfig.Targets
+	}
+	config := snapshot.FindTarget(targets, backup.Status.Target)
+	if config == nil {
+		return nil, fmt.Errorf("backup target [%s] of snapshot [%s] is not configured", backup.Status.Target, backup.Name)
+	}
+	return snapshot.NewTarget(ctx, config)
+}
+
+// withRestoreS3Config returns a copy of spec that makes rke read the snapshot
+// from sbc, or from the etcd nodes if sbc is nil.
+func withRestoreS3Config(spec apimgmtv3.ClusterSpec, sbc *rketypes.S3BackupConfig) apimgmtv3.ClusterSpec {
+	result := spec.DeepCopy()
+	if result.RancherKubernetesEngineConfig == nil {
+		return *result
+	}
+	if result.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig == nil {
+		if sbc == nil {
+			return *result
+		}
+		result.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig = &rketypes.BackupConfig{}
+	}
+	result.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.S3BackupConfig = sbc.DeepCopy()
+	return *result
+}
+
+func removeStaged(ctx context.Context, nodes []snapshot.Target, name string) {
+	for _, node := range nodes {
+		if err := node.Delete(ctx, name); err != nil && !snapshot.IsNotFound(err) {
+			logrus.Warnf("[etcd-backup] Failed to remove staged snapshot [%s] from [%s]: %v", name, node, err)
+		}
+	}
+}
+
+// getRestoreSource returns primary if it holds the snapshot file name,
+// otherwise the first of the replicas that does.
+func (p *Provisioner) getRestoreSource(ctx context.Context, spec apimgmtv3.ClusterSpec, primary snapshot.Target, name string, replicas []string) (snapshot.Target, error) {
+	_, err := primary.Stat(ctx, name)
+	if err == nil {
+		return primary, nil
+	} else if !snapshot.IsNotFound(err) {
+		return nil, err
+	}
+
+	configs := map[string]apimgmtv3.EtcdBackupTarget{}
+	if spec.EtcdBackupConfig != nil {
+		for _, config := range spec.EtcdBackupConfig.Targets {
+			configs[config.Name] = config
+		}
+	}
+	for _, replica := range replicas {
+		config, ok := configs[replica]
+		if !ok {
+			continue
+		}
+		target, err := snapshot.NewTarget(ctx, &config)
+		if err != nil {
+			logrus.Warnf("[etcd-backup] Skipping backup target [%s]: %v", replica, err)
+			continue
+		}
+		if _, err := target.Stat(ctx, name); err != nil {
+			logrus.Warnf("[etcd-backup] Snapshot [%s] is not available on backup target [%s]: %v", name, replica, err)
+			continue
+		}
+		logrus.Infof("[etcd-backup] Snapshot [%s] is missing from its primary target, restoring from backup target [%s]", name, replica)
+		return target, nil
+	}
+	return nil, fmt.Errorf("snapshot [%s] is missing from its primary target and from every backup target", name)
+}
@@ -0,0 +1,83 @@
+package etcdbackup
+
+import (
+	"fmt"
+
+	"github.com/rancher/norman/condition"
+	"github.com/rancher/rancher/pkg/controllers/management/clusterprovisioner"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+)
+
+// BackupConditionEncrypted is true once the encrypted snapshot of a backup has
+// been stored on S3 or on the primary target.
+const BackupConditionEncrypted condition.Cond = "Encrypted"
+
+// shouldEncrypt reports whether b is a completed backup stored on S3 or on a
+// primary target of a cluster with encryption enabled that has not been
+// encrypted yet. rke is not given the S3 config of such clusters, it only
+// keeps the snapshot on the etcd nodes, where it is not encrypted, and
+// rancher uploads the encrypted copy.
+func shouldEncrypt(cluster *v3.Cluster, b *v3.EtcdBackup) bool {
+	return isStoredRemotely(b) &&
+		rketypes.BackupConditionCompleted.IsTrue(b) &&
+		!BackupConditionEncrypted.IsTrue(b) &&
+		snapshot.IsEncryptionEnabled(&cluster.Spec)
+}
+
+func (c *Controller) getKeyring(cluster *v3.Cluster) (*snapshot.Keyring, error) {
+	if cluster.Spec.EtcdBackupConfig == nil {
+		return nil, nil
+	}
+	return snapshot.LoadKeyring(c.secretLister, cluster.Spec.EtcdBackupConfig.Encryption)
+}
+
+// encryptBackup uploads the encrypted snapshot of b to its primary target and
+// records the encryption metadata on the status of b.
+func (c *Controller) encryptBackup(cluster *v3.Cluster, b *v3.EtcdBackup) (*v3.EtcdBackup, error) {
+	meta, err := c.doEncryptBackup(cluster, b)
+	if err != nil {
+		BackupConditionEncrypted.False(b)
+		BackupConditionEncrypted.ReasonAndMessageFromError(b, err)
+		return b, fmt.Errorf("[etcd-backup] failed to encrypt backup %s: %v", b.Name, err)
+	}
+	b.Status.Encryption = meta.Status()
+	BackupConditionEncrypted.True(b)
+	BackupConditionEncrypted.Reason(b, "")
+	BackupConditionEncrypted.Message(b, fmt.Sprintf("encrypted with %s, key [%s]", meta.Algorithm, meta.KeyID))
+	return b, nil
+}
+
+func (c *Controller) doEncryptBackup(cluster *v3.Cluster, b *v3.EtcdBackup) (*snapshot.EncryptionMetadata, error) {
+	keyring, err := c.getKeyring(cluster)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := c.getPrimaryTarget(cluster, b)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("[etcd-backup] Encrypting backup %s of cluster [%s]", b.Name, cluster.Name)
+	name := getSnapshotFilename(b)
+	// snapshots taken before encryption was enabled were uploaded unencrypted
+	if _, err := dst.Stat(c.ctx, name); err == nil {
+		return snapshot.Encrypt(c.ctx, dst, name, keyring)
+	} else if !snapshot.IsNotFound(err) {
+		return nil, err
+	}
+
+	nodes, err := c.getEtcdNodeTargets(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.EncryptFrom(c.ctx, nodes, name, keyring, dst, snapshotMetadata(cluster))
+}
+
+// getSnapshotFilename returns the name of the snapshot file of b, relative to
+// the folder of its target.
+func getSnapshotFilename(b *v3.EtcdBackup) string {
+	return fmt.Sprintf("%s.%s", clusterprovisioner.GetBackupFilename(b), compressedExtension)
+}
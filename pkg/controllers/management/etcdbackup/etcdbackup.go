@@ -4,14 +4,12 @@
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -19,14 +17,16 @@
 	rketypes "github.com/rancher/rke/types"
 
 	minio "github.com/minio/minio-go"
-	"github.com/minio/minio-go/pkg/credentials"
 	"github.com/rancher/rancher/pkg/controllers/management/clusterprovisioner"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/rke"
 	"github.com/rancher/rancher/pkg/kontainer-engine/service"
 	"github.com/rancher/rancher/pkg/rkedialerfactory"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
+	"github.com/rancher/rke/hosts"
 	"github.com/rancher/wrangler/pkg/ticker"
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -39,7 +39,6 @@
 const (
 	clusterBackupCheckInterval = 5 * time.Minute
 	compressedExtension        = "zip"
-	s3Endpoint                 = "s3.amazonaws.com"
 )
 
 type Controller struct {
@@ -50,6 +49,13 @@ type Controller struct {
 	backupLister          v3.EtcdBackupLister
 	backupDriver          *service.EngineService
 	KontainerDriverLister v3.KontainerDriverLister
+	dialerFactory         dialer.Factory
+	dockerDialer          hosts.DialerFactory
+	secretLister          v1.SecretLister
+	// verifying holds the backups being verified and verifyAttempts the
+	// number of failed attempts to download their snapshot
+	verifying      sync.Map
+	verifyAttempts sync.Map
 }
 
 func Register(ctx context.Context, management *config.ManagementContext) {
@@ -61,6 +67,8 @@ func Register(ctx context.Context, management *config.ManagementContext) {
 		backupLister:          management.Management.EtcdBackups("").Controller().Lister(),
 		backupDriver:          service.NewEngineService(clusterprovisioner.NewPersistentStore(management.Core.Namespaces(""), management.Core)),
 		KontainerDriverLister: management.Management.KontainerDrivers("").Controller().Lister(),
+		dialerFactory:         management.Dialer,
+		secretLister:          management.Core.Secrets("").Controller().Lister(),
 	}
 
 	local := &rkedialerfactory.RKEDialerFactory{
@@ -77,6 +85,7 @@ func Register(ctx context.Context, management *config.ManagementContext) {
 	rkeDriver.DockerDialer = docker.Build
 	rkeDriver.LocalDialer = local.Build
 	rkeDriver.WrapTransportFactory = docker.WrapTransport
+	c.dockerDialer = docker.Build
 
 	c.backupClient.AddLifecycle(ctx, "etcdbackup-controller", c)
 	go c.clusterBackupSync(ctx, clusterBackupCheckInterval)
@@ -99,6 +108,13 @@ func (c *Controller) Create(b *v3.EtcdBackup) (runtime.Object, error) {
 	if !rketypes.BackupConditionCreated.IsTrue(b) {
 		b.Spec.Filename = generateBackupFilename(b.Name, cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig)
 		b.Spec.BackupConfig = *cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig
+		target, err := getPrimaryTargetConfig(cluster)
+		if err != nil {
+			return b, fmt.Errorf("[etcd-backup] %v", err)
+		}
+		if target != nil {
+			b.Status.Target = target.Name
+		}
 		rketypes.BackupConditionCreated.True(b)
 		// we set ConditionCompleted to Unknown to avoid incorrect "active" state
 		rketypes.BackupConditionCompleted.Unknown(b)
@@ -124,11 +140,37 @@ func (c *Controller) Remove(b *v3.EtcdBackup) (runtime.Object, error) {
 	if err := c.etcdRemoveSnapshotWithBackoff(b); err != nil {
 		logrus.Warnf("giving up on deleting backup [%s]: %v", b.Name, err)
 	}
+	c.removeReplicas(b)
 	return b, nil
 }
 
 func (c *Controller) Updated(b *v3.EtcdBackup) (runtime.Object, error) {
-	return b, nil
+	cluster, err := c.clusterLister.Get("", b.Spec.ClusterID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return b, nil
+		}
+		return b, err
+	}
+	if shouldEncrypt(cluster, b) {
+		if b, err = c.encryptBackup(cluster, b); err != nil {
+			return b, err
+		}
+	}
+	if shouldUpload(cluster, b) {
+		if b, err = c.uploadBackup(cluster, b); err != nil {
+			return b, err
+		}
+	}
+	if shouldReplicate(cluster, b) {
+		if b, err = c.replicateBackup(cluster, b); err != nil {
+			return b, err
+		}
+	}
+	if !shouldVerify(cluster, b) {
+		return b, nil
+	}
+	return c.verifyBackup(cluster, b)
 }
 
 func (c *Controller) clusterBackupSync(ctx context.Context, interval time.Duration) error {
@@ -152,10 +194,13 @@ func (c *Controller) doClusterBackupSync(cluster *v3.Cluster) error {
 	if cluster == nil || cluster.DeletionTimestamp != nil {
 		return nil
 	}
-	// check if the cluster is eligible for backup.
-	if !shouldBackup(cluster) {
+	if !canBackup(cluster) {
 		return nil
 	}
+	// manual backups are rotated even if recurring backups are disabled
+	if !shouldBackup(cluster) {
+		return c.rotateExpiredBackups(cluster, nil)
+	}
 
 	clusterBackups, err := c.getRecuringBackupsList(cluster)
 	if err != nil {
@@ -222,7 +267,7 @@ func (c *Controller) etcdSaveWithBackoff(b *v3.EtcdBackup) (runtime.Object, erro
 		}
 		var inErr error
 		err = wait.ExponentialBackoff(backoff, func() (bool, error) {
-			if inErr = c.backupDriver.ETCDSave(c.ctx, cluster.Name, kontainerDriver, cluster.Spec, snapshotName); inErr != nil {
+			if inErr = c.backupDriver.ETCDSave(c.ctx, cluster.Name, kontainerDriver, snapshot.WithoutS3Upload(cluster.Spec), snapshotName); inErr != nil {
 				logrus.Warnf("%v", inErr)
 				return false, nil
 			}
@@ -251,26 +296,49 @@ func (c *Controller) etcdRemoveSnapshotWithBackoff(b *v3.EtcdBackup) error {
 		return err
 	}
 	snapshotName := clusterprovisioner.GetBackupFilename(b)
-	return wait.ExponentialBackoff(backoff, func() (bool, error) {
-		if inErr := c.backupDriver.ETCDRemoveSnapshot(c.ctx, cluster.Name, kontainerDriver, cluster.Spec, snapshotName); inErr != nil {
+	err = wait.ExponentialBackoff(backoff, func() (bool, error) {
+		if inErr := c.backupDriver.ETCDRemoveSnapshot(c.ctx, cluster.Name, kontainerDriver, snapshot.WithoutS3Upload(cluster.Spec), snapshotName); inErr != nil {
 			logrus.Warnf("%v", inErr)
 			return false, nil
 		}
 		return true, nil
 	})
+	if err != nil {
+		return err
+	}
+	// rke does not know about the snapshots rancher uploaded
+	if b.Status.Target != "" || (b.Spec.BackupConfig.S3BackupConfig != nil && snapshot.IsEncryptionEnabled(&cluster.Spec)) {
+		return c.removePrimarySnapshot(cluster, b)
+	}
+	return nil
 }
 
 func (c *Controller) rotateExpiredBackups(cluster *v3.Cluster, clusterBackups []*v3.EtcdBackup) error {
-	retention := cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.Retention
-	intervalHours := cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.IntervalHours
-	expiredBackups := getExpiredBackups(retention, intervalHours, clusterBackups)
+	manualBackups, err := c.getManualBackupsList(cluster)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	expiredBackups := getExpiredRecurringBackups(cluster, clusterBackups, now)
+	expiredBackups = append(expiredBackups, getExpiredManualBackups(getRetention(cluster), manualBackups, now)...)
+	expired := map[string]bool{}
 	for _, backup := range expiredBackups {
-		if backup.Spec.Manual {
-			continue
-		}
+		logrus.Infof("[etcd-backup] Deleting expired backup %s of cluster [%s]", backup.Name, cluster.Name)
 		if err := c.backupClient.DeleteNamespaced(backup.Namespace, backup.Name, &metav1.DeleteOptions{}); err != nil {
 			return err
 		}
+		expired[backup.Name] = true
+	}
+
+	status := &v32.EtcdBackupRetentionStatus{
+		Policy:        describeRetention(cluster),
+		NextDeletions: getNextDeletions(cluster, withoutBackups(clusterBackups, expired), withoutBackups(manualBackups, expired), now),
+	}
+	if err := c.setRetentionStatus(cluster.Name, status); err != nil {
+		return err
+	}
+	if err := c.removeOrphanedReplicas(cluster); err != nil {
+		logrus.Warnf("[etcd-backup] Failed to delete orphaned snapshots of cluster [%s]: %v", cluster.Name, err)
 	}
 	return nil
 }
@@ -311,9 +379,11 @@ func NewBackupObject(cluster *v3.Cluster, manual bool) (*v3.EtcdBackup, error) {
 			ClusterID: cluster.Name,
 			Manual:    manual,
 		},
-		Status: rketypes.EtcdBackupStatus{
-			KubernetesVersion: cluster.Spec.RancherKubernetesEngineConfig.Version,
-			ClusterObject:     compressedCluster,
+		Status: v32.EtcdBackupStatus{
+			EtcdBackupStatus: rketypes.EtcdBackupStatus{
+				KubernetesVersion: cluster.Spec.RancherKubernetesEngineConfig.Version,
+				ClusterObject:     compressedCluster,
+			},
 		},
 	}, nil
 }
@@ -392,48 +462,7 @@ func generateBackupFilename(snapshotName string, backupConfig *rketypes.BackupCo
 }
 
 func GetS3Client(sbc *rketypes.S3BackupConfig, timeout int, dialer dialer.Dialer) (*minio.Client, error) {
-	if sbc == nil {
-		return nil, fmt.Errorf("Can't find S3 backup target configuration")
-	}
-	var s3Client = &minio.Client{}
-	var creds *credentials.Credentials
-	var tr http.RoundTripper = &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		DialContext:           dialer,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	endpoint := sbc.Endpoint
-	// no access credentials, we assume IAM roles
-	if sbc.AccessKey == "" ||
-		sbc.SecretKey == "" {
-		creds = credentials.NewIAM("")
-		if sbc.Endpoint == "" {
-			endpoint = s3Endpoint
-		}
-	} else {
-		accessKey := sbc.AccessKey
-		secretKey := sbc.SecretKey
-		creds = credentials.NewStatic(accessKey, secretKey, "", credentials.SignatureDefault)
-	}
-
-	bucketLookup := getBucketLookupType(endpoint)
-	s3Client, err := minio.NewWithOptions(endpoint, &minio.Options{
-		Creds:        creds,
-		Region:       sbc.Region,
-		Secure:       true,
-		BucketLookup: bucketLookup,
-	})
-	if err != nil {
-		return nil, err
-	}
-	if sbc.CustomCA != "" {
-		tr = getCustomCATransport(tr, sbc.CustomCA)
-	}
-	s3Client.SetCustomTransport(tr)
-	return s3Client, nil
+	return snapshot.NewS3Client(sbc, dialer)
 }
 
 func (c *Controller) getRecuringBackupsList(cluster *v3.Cluster) ([]*v3.EtcdBackup, error) {
@@ -450,33 +479,35 @@ func (c *Controller) getRecuringBackupsList(cluster *v3.Cluster) ([]*v3.EtcdBack
 	return retList, nil
 }
 
-func getBucketLookupType(endpoint string) minio.BucketLookupType {
-	if endpoint == "" {
-		return minio.BucketLookupAuto
-	}
-	if strings.Contains(endpoint, "aliyun") {
-		return minio.BucketLookupDNS
-	}
-	return minio.BucketLookupAuto
-}
-
 func getBackupCompletedTime(o runtime.Object) time.Time {
 	t, _ := time.Parse(time.RFC3339, rketypes.BackupConditionCompleted.GetLastUpdated(o))
 	return t
 }
 
-func getExpiredBackups(retention, intervalHours int, backups []*v3.EtcdBackup) []*v3.EtcdBackup {
+func getExpiredBackups(retention, intervalHours int, backups []*v3.EtcdBackup, now time.Time) []*v3.EtcdBackup {
 	expiredList := []*v3.EtcdBackup{}
 	toKeepDuration := time.Duration(retention*intervalHours) * time.Hour
 	for _, backup := range backups {
-		if time.Since(getBackupCompletedTime(backup)) > toKeepDuration {
+		if now.Sub(getBackupCompletedTime(backup)) > toKeepDuration {
 			expiredList = append(expiredList, backup)
 		}
 	}
 	return expiredList
 }
 
-func shouldBackup(cluster *v3.Cluster) bool {
+func withoutBackups(backups []*v3.EtcdBackup, names map[string]bool) []*v3.EtcdBackup {
+	var result []*v3.EtcdBackup
+	for _, backup := range backups {
+		if !names[backup.Name] {
+			result = append(result, backup)
+		}
+	}
+	return result
+}
+
+// canBackup reports whether cluster is a ready rke cluster with a backup
+// config, whose backups can be taken and removed.
+func canBackup(cluster *v3.Cluster) bool {
 	// not an rke cluster, we do nothing
 	if cluster.Spec.RancherKubernetesEngineConfig == nil {
 		logrus.Debugf("[etcd-backup] [%s] is not an rke cluster, skipping..", cluster.Name)
@@ -488,10 +519,14 @@ func shouldBackup(cluster *v3.Cluster) bool {
 		return false
 	}
 	// we only work with ready clusters
-	if !v32.ClusterConditionReady.IsTrue(cluster) {
+	return v32.ClusterConditionReady.IsTrue(cluster)
+}
+
+// shouldBackup reports whether recurring backups of cluster are taken.
+func shouldBackup(cluster *v3.Cluster) bool {
+	if !canBackup(cluster) {
 		return false
 	}
-
 	if !isRecurringBackupEnabled(cluster.Spec.RancherKubernetesEngineConfig) {
 		logrus.Debugf("[etcd-backup] Recurring backup is disabled cluster [%s]", cluster.Name)
 		return false
@@ -516,12 +551,3 @@ func isBackupSet(rkeConfig *rketypes.RancherKubernetesEngineConfig) bool {
 func isRecurringBackupEnabled(rkeConfig *rketypes.RancherKubernetesEngineConfig) bool {
 	return isBackupSet(rkeConfig) && rkeConfig.Services.Etcd.BackupConfig.Enabled != nil && *rkeConfig.Services.Etcd.BackupConfig.Enabled
 }
-
-func getCustomCATransport(tr http.RoundTripper, ca string) http.RoundTripper {
-	certPool := x509.NewCertPool()
-	certPool.AppendCertsFromPEM([]byte(ca))
-	tr.(*http.Transport).TLSClientConfig = &tls.Config{
-		RootCAs: certPool,
-	}
-	return tr
-}
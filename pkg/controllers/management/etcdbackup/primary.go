@@ -0,0 +1,124 @@
+package etcdbackup
+
+import (
+	"fmt"
+
+	"github.com/rancher/norman/condition"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+)
+
+// BackupConditionUploaded is true once the snapshot of a backup rke kept on
+// the etcd nodes has been uploaded to the primary target of its cluster.
+const BackupConditionUploaded condition.Cond = "Uploaded"
+
+func getPrimaryTargetName(cluster *v3.Cluster) string {
+	if cluster.Spec.EtcdBackupConfig == nil {
+		return ""
+	}
+	return cluster.Spec.EtcdBackupConfig.PrimaryTarget
+}
+
+// getPrimaryTargetConfig returns the target snapshots of cluster are stored
+// on in place of S3, or nil if they are stored on S3 or only on the etcd
+// nodes.
+func getPrimaryTargetConfig(cluster *v3.Cluster) (*v32.EtcdBackupTarget, error) {
+	name := getPrimaryTargetName(cluster)
+	rke := cluster.Spec.RancherKubernetesEngineConfig
+	if name == "" || (rke != nil && rke.Services.Etcd.BackupConfig != nil && rke.Services.Etcd.BackupConfig.S3BackupConfig != nil) {
+		return nil, nil
+	}
+	if err := snapshot.ValidateTargets(getBackupTargets(cluster), name); err != nil {
+		return nil, err
+	}
+	return snapshot.FindTarget(getBackupTargets(cluster), name), nil
+}
+
+// isStoredRemotely reports whether the snapshot of b is stored on S3 or on a
+// primary target rather than only on the etcd nodes.
+func isStoredRemotely(b *v3.EtcdBackup) bool {
+	return b.Spec.BackupConfig.S3BackupConfig != nil || b.Status.Target != ""
+}
+
+// getPrimaryTarget returns the target the snapshot of b is stored on.
+func (c *Controller) getPrimaryTarget(cluster *v3.Cluster, b *v3.EtcdBackup) (snapshot.Target, error) {
+	if sbc := b.Spec.BackupConfig.S3BackupConfig; sbc != nil {
+		dialer, err := c.dialerFactory.ClusterDialer(cluster.Name)
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.NewS3Target(sbc, dialer)
+	}
+	config := snapshot.FindTarget(getBackupTargets(cluster), b.Status.Target)
+	if config == nil {
+		return nil, fmt.Errorf("backup target [%s] is not configured", b.Status.Target)
+	}
+	return snapshot.NewTarget(c.ctx, config)
+}
+
+// getEtcdNodeTargets returns the etcd nodes of cluster, on which rke keeps
+// the snapshots.
+func (c *Controller) getEtcdNodeTargets(cluster *v3.Cluster) ([]snapshot.Target, error) {
+	rkeConfig := cluster.Status.AppliedSpec.RancherKubernetesEngineConfig
+	if rkeConfig == nil {
+		return nil, fmt.Errorf("cluster [%s] has no applied rke config", cluster.Name)
+	}
+	return snapshot.NewEtcdNodeTargets(rkeConfig, c.dockerDialer)
+}
+
+// snapshotMetadata returns the metadata of the snapshot files rancher stores
+// for cluster, see snapshot.MetadataCluster.
+func snapshotMetadata(cluster *v3.Cluster) map[string]string {
+	return map[string]string{snapshot.MetadataCluster: cluster.Name}
+}
+
+// shouldUpload reports whether b is a completed backup whose snapshot rke
+// kept on the etcd nodes and that has not been uploaded to its primary target
+// yet. Snapshots to be encrypted are uploaded once they are.
+func shouldUpload(cluster *v3.Cluster, b *v3.EtcdBackup) bool {
+	return b.Status.Target != "" &&
+		rketypes.BackupConditionCompleted.IsTrue(b) &&
+		!BackupConditionUploaded.IsTrue(b) &&
+		!snapshot.IsEncryptionEnabled(&cluster.Spec)
+}
+
+// uploadBackup copies the snapshot of b from the etcd nodes to its primary
+// target.
+func (c *Controller) uploadBackup(cluster *v3.Cluster, b *v3.EtcdBackup) (*v3.EtcdBackup, error) {
+	err := c.doUploadBackup(cluster, b)
+	if err != nil {
+		BackupConditionUploaded.False(b)
+		BackupConditionUploaded.ReasonAndMessageFromError(b, err)
+		return b, fmt.Errorf("[etcd-backup] failed to upload backup %s: %v", b.Name, err)
+	}
+	BackupConditionUploaded.True(b)
+	BackupConditionUploaded.Reason(b, "")
+	BackupConditionUploaded.Message(b, fmt.Sprintf("uploaded to %s", b.Status.Target))
+	return b, nil
+}
+
+func (c *Controller) doUploadBackup(cluster *v3.Cluster, b *v3.EtcdBackup) error {
+	dst, err := c.getPrimaryTarget(cluster, b)
+	if err != nil {
+		return err
+	}
+	nodes, err := c.getEtcdNodeTargets(cluster)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("[etcd-backup] Uploading backup %s of cluster [%s] to target [%s]", b.Name, cluster.Name, b.Status.Target)
+	return snapshot.CopyFrom(c.ctx, nodes, dst, getSnapshotFilename(b), snapshotMetadata(cluster))
+}
+
+// removePrimarySnapshot deletes the snapshot of b from the target rancher
+// uploaded it to, rke only removes the snapshots it uploaded itself.
+func (c *Controller) removePrimarySnapshot(cluster *v3.Cluster, b *v3.EtcdBackup) error {
+	target, err := c.getPrimaryTarget(cluster, b)
+	if err != nil {
+		return err
+	}
+	return target.Delete(c.ctx, getSnapshotFilename(b))
+}
@@ -0,0 +1,183 @@
+package etcdbackup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/norman/condition"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// BackupConditionReplicated is true once the snapshot of a backup stored on
+// S3 or on the primary target has been copied to every other backup target
+// of its cluster.
+const BackupConditionReplicated condition.Cond = "Replicated"
+
+func getBackupTargets(cluster *v3.Cluster) []v32.EtcdBackupTarget {
+	if cluster.Spec.EtcdBackupConfig == nil {
+		return nil
+	}
+	return cluster.Spec.EtcdBackupConfig.Targets
+}
+
+// getPendingTargets returns the targets of cluster the snapshot of b has not
+// been copied to yet.
+func getPendingTargets(cluster *v3.Cluster, b *v3.EtcdBackup) []v32.EtcdBackupTarget {
+	done := map[string]bool{b.Status.Target: true}
+	for _, name := range snapshot.GetReplicas(b.Annotations[snapshot.ReplicasAnnotation]) {
+		done[name] = true
+	}
+	var pending []v32.EtcdBackupTarget
+	for _, target := range getBackupTargets(cluster) {
+		if !done[target.Name] {
+			pending = append(pending, target)
+		}
+	}
+	return pending
+}
+
+// shouldReplicate reports whether b is a completed backup stored on S3 or on
+// a primary target whose snapshot is missing from some of the other targets
+// of its cluster. They receive copies once the snapshot is stored, encrypted
+// if it is to be.
+func shouldReplicate(cluster *v3.Cluster, b *v3.EtcdBackup) bool {
+	return isStoredRemotely(b) &&
+		rketypes.BackupConditionCompleted.IsTrue(b) &&
+		!shouldEncrypt(cluster, b) &&
+		!shouldUpload(cluster, b) &&
+		len(getPendingTargets(cluster, b)) > 0
+}
+
+// replicateBackup copies the snapshot of b from its primary target to the
+// pending targets of cluster, recording the targets holding a copy on b.
+func (c *Controller) replicateBackup(cluster *v3.Cluster, b *v3.EtcdBackup) (*v3.EtcdBackup, error) {
+	if err := snapshot.ValidateTargets(getBackupTargets(cluster), getPrimaryTargetName(cluster)); err != nil {
+		BackupConditionReplicated.False(b)
+		BackupConditionReplicated.ReasonAndMessageFromError(b, err)
+		return b, nil
+	}
+	src, err := c.getPrimaryTarget(cluster, b)
+	if err != nil {
+		return b, err
+	}
+
+	replicas := snapshot.GetReplicas(b.Annotations[snapshot.ReplicasAnnotation])
+	var errs []string
+	for _, config := range getPendingTargets(cluster, b) {
+		logrus.Infof("[etcd-backup] Copying backup %s of cluster [%s] to target [%s]", b.Name, cluster.Name, config.Name)
+		if err := copySnapshot(c.ctx, src, &config, getSnapshotFilename(b), snapshotMetadata(cluster)); err != nil {
+			errs = append(errs, fmt.Sprintf("target [%s]: %v", config.Name, err))
+			continue
+		}
+		replicas = append(replicas, config.Name)
+	}
+
+	sort.Strings(replicas)
+	if b.Annotations == nil {
+		b.Annotations = map[string]string{}
+	}
+	b.Annotations[snapshot.ReplicasAnnotation] = strings.Join(replicas, ",")
+	if len(errs) > 0 {
+		err := fmt.Errorf("failed to copy snapshot: %s", strings.Join(errs, "; "))
+		BackupConditionReplicated.False(b)
+		BackupConditionReplicated.ReasonAndMessageFromError(b, err)
+		return b, fmt.Errorf("[etcd-backup] backup %s: %v", b.Name, err)
+	}
+	BackupConditionReplicated.True(b)
+	BackupConditionReplicated.Reason(b, "")
+	BackupConditionReplicated.Message(b, fmt.Sprintf("copied to %s", strings.Join(replicas, ", ")))
+	return b, nil
+}
+
+func copySnapshot(ctx context.Context, src snapshot.Target, config *v32.EtcdBackupTarget, name string, metadata map[string]string) error {
+	dst, err := snapshot.NewTarget(ctx, config)
+	if err != nil {
+		return err
+	}
+	return snapshot.Copy(ctx, src, dst, name, metadata)
+}
+
+// removeReplicas deletes the copies of the snapshot of b from the targets of
+// its cluster. Targets that were removed from the cluster are skipped.
+func (c *Controller) removeReplicas(b *v3.EtcdBackup) {
+	replicas := snapshot.GetReplicas(b.Annotations[snapshot.ReplicasAnnotation])
+	if len(replicas) == 0 {
+		return
+	}
+	cluster, err := c.clusterLister.Get("", b.Spec.ClusterID)
+	if err != nil {
+		logrus.Warnf("[etcd-backup] Failed to get cluster of backup %s, keeping its copies: %v", b.Name, err)
+		return
+	}
+	configs := map[string]v32.EtcdBackupTarget{}
+	for _, config := range getBackupTargets(cluster) {
+		configs[config.Name] = config
+	}
+	for _, name := range replicas {
+		config, ok := configs[name]
+		if !ok {
+			continue
+		}
+		target, err := snapshot.NewTarget(c.ctx, &config)
+		if err == nil {
+			err = target.Delete(c.ctx, getSnapshotFilename(b))
+		}
+		if err != nil {
+			logrus.Warnf("[etcd-backup] Failed to delete copy of backup %s from target [%s]: %v", b.Name, name, err)
+		}
+	}
+}
+
+// removeOrphanedReplicas deletes snapshots of cluster from its targets that
+// no backup refers to anymore, such as copies of backups that were deleted
+// while a target was unreachable. Only files rancher stored for cluster are
+// considered, anything else sharing the target is left alone.
+func (c *Controller) removeOrphanedReplicas(cluster *v3.Cluster) error {
+	targets := getBackupTargets(cluster)
+	if len(targets) == 0 {
+		return nil
+	}
+	backups, err := c.backupLister.List(cluster.Name, labels.NewSelector())
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, backup := range backups {
+		known[getSnapshotFilename(backup)] = true
+	}
+	for i := range targets {
+		target, err := snapshot.NewTarget(c.ctx, &targets[i])
+		if err != nil {
+			return err
+		}
+		files, err := target.List(c.ctx, cluster.Name+"-")
+		if err != nil {
+			return fmt.Errorf("failed to list backups on target [%s]: %v", targets[i].Name, err)
+		}
+		for _, file := range getOrphanedFiles(files, known, cluster.Name) {
+			logrus.Infof("[etcd-backup] Deleting orphaned snapshot [%s] of cluster [%s] from target [%s]", file, cluster.Name, targets[i].Name)
+			if err := target.Delete(c.ctx, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getOrphanedFiles(files []snapshot.FileInfo, known map[string]bool, clusterName string) []string {
+	var orphaned []string
+	for _, file := range files {
+		if !known[file.Name] && strings.HasSuffix(file.Name, "."+compressedExtension) &&
+			file.Metadata[snapshot.MetadataCluster] == clusterName {
+			orphaned = append(orphaned, file.Name)
+		}
+	}
+	return orphaned
+}
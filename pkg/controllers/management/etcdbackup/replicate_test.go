@@ -0,0 +1,131 @@
+package etcdbackup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestShouldReplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &v3.Cluster{}
+	cluster.Spec.EtcdBackupConfig = &v32.EtcdBackupConfig{
+		Targets: []v32.EtcdBackupTarget{
+			{Name: "nfs", FilesystemConfig: &v32.FilesystemBackupConfig{Path: "/backups"}},
+			{Name: "gcs", GCSConfig: &v32.GCSBackupConfig{Bucket: "backups"}},
+		},
+	}
+	backup := &v3.EtcdBackup{}
+	backup.Spec.BackupConfig.S3BackupConfig = &rketypes.S3BackupConfig{BucketName: "backups"}
+	assert.False(shouldReplicate(cluster, backup), "backup is not completed")
+
+	rketypes.BackupConditionCompleted.True(backup)
+	assert.True(shouldReplicate(cluster, backup))
+
+	backup.Annotations = map[string]string{snapshot.ReplicasAnnotation: "nfs"}
+	assert.Equal([]v32.EtcdBackupTarget{cluster.Spec.EtcdBackupConfig.Targets[1]}, getPendingTargets(cluster, backup))
+
+	backup.Annotations[snapshot.ReplicasAnnotation] = "gcs,nfs"
+	assert.False(shouldReplicate(cluster, backup), "snapshot is on every target")
+
+	cluster.Spec.EtcdBackupConfig.Encryption = &v32.EtcdBackupEncryption{SecretName: "etcd-keys"}
+	backup.Annotations = nil
+	assert.False(shouldReplicate(cluster, backup), "snapshot is copied once encrypted")
+
+	backup.Spec.BackupConfig.S3BackupConfig = nil
+	assert.False(shouldReplicate(&v3.Cluster{}, backup))
+}
+
+func TestGetOrphanedFiles(t *testing.T) {
+	owned := map[string]string{snapshot.MetadataCluster: "c-abcde"}
+	files := []snapshot.FileInfo{
+		{Name: "c-abcde-rs-1_2021-01-01T00:00:00Z.zip", Metadata: owned},
+		{Name: "c-abcde-rs-2_2021-01-02T00:00:00Z.zip", Metadata: owned},
+		{Name: "c-abcde-notes.txt", Metadata: owned},
+		{Name: "c-abcde-rs-3_2021-01-03T00:00:00Z.zip", Metadata: map[string]string{}},
+		{Name: "c-abcde-rs-4_2021-01-04T00:00:00Z.zip", Metadata: map[string]string{snapshot.MetadataCluster: "c-abcde-fghij"}},
+	}
+	known := map[string]bool{"c-abcde-rs-2_2021-01-02T00:00:00Z.zip": true}
+	assert.Equal(t, []string{"c-abcde-rs-1_2021-01-01T00:00:00Z.zip"}, getOrphanedFiles(files, known, "c-abcde"))
+}
+
+func newTestTargetConfig(t *testing.T, name string) (v32.EtcdBackupTarget, func()) {
+	dir, err := ioutil.TempDir("", "etcd-snapshot-replicate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v32.EtcdBackupTarget{Name: name, FilesystemConfig: &v32.FilesystemBackupConfig{Path: dir}}, func() { os.RemoveAll(dir) }
+}
+
+func TestReplicatePrimaryTarget(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	nfs, cleanupNFS := newTestTargetConfig(t, "nfs")
+	defer cleanupNFS()
+	azure, cleanupAzure := newTestTargetConfig(t, "azure")
+	defer cleanupAzure()
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	cluster.Spec.RancherKubernetesEngineConfig = &rketypes.RancherKubernetesEngineConfig{}
+	cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig = &rketypes.BackupConfig{}
+	cluster.Spec.EtcdBackupConfig = &v32.EtcdBackupConfig{Targets: []v32.EtcdBackupTarget{nfs, azure}, PrimaryTarget: "missing"}
+	_, err := getPrimaryTargetConfig(cluster)
+	assert.EqualError(err, "primary backup target [missing] is not one of the backup targets")
+	cluster.Spec.EtcdBackupConfig.PrimaryTarget = "nfs"
+	config, err := getPrimaryTargetConfig(cluster)
+	assert.Nil(err)
+	assert.Equal(&nfs, config)
+
+	backup := &v3.EtcdBackup{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde-rl-1", Namespace: "c-abcde"}}
+	backup.Spec.Filename = "c-abcde-rl-1"
+	backup.Status.Target = "nfs"
+	rketypes.BackupConditionCompleted.True(backup)
+	assert.True(shouldUpload(cluster, backup))
+	assert.False(shouldReplicate(cluster, backup), "snapshot is replicated once uploaded")
+	BackupConditionUploaded.True(backup)
+	assert.True(shouldReplicate(cluster, backup))
+	assert.Equal([]v32.EtcdBackupTarget{azure}, getPendingTargets(cluster, backup), "the primary target is not a replica")
+
+	c := &Controller{ctx: ctx}
+	primary, err := c.getPrimaryTarget(cluster, backup)
+	assert.Nil(err)
+	assert.Nil(primary.Upload(ctx, getSnapshotFilename(backup), strings.NewReader("snapshot"), 8, snapshotMetadata(cluster)))
+	backup, err = c.replicateBackup(cluster, backup)
+	assert.Nil(err)
+	assert.True(BackupConditionReplicated.IsTrue(backup))
+	assert.Equal("azure", backup.Annotations[snapshot.ReplicasAnnotation])
+
+	replica, err := snapshot.NewTarget(ctx, &azure)
+	assert.Nil(err)
+	info, err := replica.Stat(ctx, getSnapshotFilename(backup))
+	assert.Nil(err)
+	assert.Equal("c-abcde", info.Metadata[snapshot.MetadataCluster])
+
+	// only files rancher stored for the cluster are removed as orphans
+	foreign := "c-abcde-rl-foreign.zip"
+	assert.Nil(replica.Upload(ctx, foreign, strings.NewReader("foreign"), 7, nil))
+	c.backupLister = &fakes.EtcdBackupListerMock{
+		ListFunc: func(namespace string, selector labels.Selector) ([]*v3.EtcdBackup, error) {
+			return nil, nil
+		},
+	}
+	assert.Nil(c.removeOrphanedReplicas(cluster))
+	for _, target := range []snapshot.Target{primary, replica} {
+		_, err = target.Stat(ctx, getSnapshotFilename(backup))
+		assert.True(snapshot.IsNotFound(err))
+	}
+	_, err = replica.Stat(ctx, foreign)
+	assert.Nil(err)
+}
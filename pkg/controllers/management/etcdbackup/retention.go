@@ -0,0 +1,272 @@
+package etcdbackup
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// maxPlannedDeletions caps the deletions listed in the cluster status
+const maxPlannedDeletions = 10
+
+// retentionPeriod buckets backups by the hour, day, week or month they
+// completed in; the newest backup of each of the last count buckets is kept.
+type retentionPeriod struct {
+	count int
+	key   func(time.Time) string
+}
+
+func getRetention(cluster *v3.Cluster) *v32.EtcdBackupRetention {
+	if cluster.Spec.EtcdBackupConfig == nil {
+		return nil
+	}
+	return cluster.Spec.EtcdBackupConfig.Retention
+}
+
+func isGFSRetention(retention *v32.EtcdBackupRetention) bool {
+	return retention != nil && retention.Hourly+retention.Daily+retention.Weekly+retention.Monthly > 0
+}
+
+func getRetentionPeriods(retention *v32.EtcdBackupRetention) []retentionPeriod {
+	return []retentionPeriod{
+		{retention.Hourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{retention.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{retention.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{retention.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+}
+
+// describeRetention returns a summary of the retention of the backups of
+// cluster, for the cluster status.
+func describeRetention(cluster *v3.Cluster) string {
+	backupConfig := cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig
+	retention := getRetention(cluster)
+
+	var policy string
+	if isGFSRetention(retention) {
+		policy = fmt.Sprintf("recurring: %d hourly, %d daily, %d weekly, %d monthly",
+			retention.Hourly, retention.Daily, retention.Weekly, retention.Monthly)
+	} else {
+		policy = fmt.Sprintf("recurring: %d backups every %dh", backupConfig.Retention, backupConfig.IntervalHours)
+	}
+
+	if retention == nil || retention.Manual == nil || retention.Manual.Count+retention.Manual.MaxAgeHours == 0 {
+		return policy + "; manual: keep all"
+	}
+	var manual []string
+	if retention.Manual.Count > 0 {
+		manual = append(manual, fmt.Sprintf("newest %d", retention.Manual.Count))
+	}
+	if retention.Manual.MaxAgeHours > 0 {
+		manual = append(manual, fmt.Sprintf("at most %dh old", retention.Manual.MaxAgeHours))
+	}
+	return fmt.Sprintf("%s; manual: %s", policy, strings.Join(manual, ", "))
+}
+
+// getExpiredRecurringBackups returns the recurring backups of cluster that
+// are no longer kept at now.
+func getExpiredRecurringBackups(cluster *v3.Cluster, backups []*v3.EtcdBackup, now time.Time) []*v3.EtcdBackup {
+	retention := getRetention(cluster)
+	if !isGFSRetention(retention) {
+		backupConfig := cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig
+		return getExpiredBackups(backupConfig.Retention, backupConfig.IntervalHours, backups, now)
+	}
+	return getGFSExpiredBackups(retention, backups)
+}
+
+// getGFSExpiredBackups returns the backups not kept by any period of
+// retention. The newest successful backup is always kept; failed backups
+// expire once a newer backup succeeded and backups in progress never do.
+func getGFSExpiredBackups(retention *v32.EtcdBackupRetention, backups []*v3.EtcdBackup) []*v3.EtcdBackup {
+	var completed []*v3.EtcdBackup
+	for _, backup := range backups {
+		if rketypes.BackupConditionCompleted.IsTrue(backup) {
+			completed = append(completed, backup)
+		}
+	}
+	if len(completed) == 0 {
+		return nil
+	}
+	sortNewestFirst(completed)
+
+	keep := map[string]bool{completed[0].Name: true}
+	for _, period := range getRetentionPeriods(retention) {
+		seen := map[string]bool{}
+		for _, backup := range completed {
+			if len(seen) >= period.count {
+				break
+			}
+			key := period.key(getBackupCompletedTime(backup).UTC())
+			if !seen[key] {
+				seen[key] = true
+				keep[backup.Name] = true
+			}
+		}
+	}
+
+	newest := getBackupCompletedTime(completed[0])
+	var expired []*v3.EtcdBackup
+	for _, backup := range backups {
+		switch {
+		case rketypes.BackupConditionCompleted.IsTrue(backup):
+			if !keep[backup.Name] {
+				expired = append(expired, backup)
+			}
+		case rketypes.BackupConditionCompleted.IsFalse(backup):
+			if getBackupCompletedTime(backup).Before(newest) {
+				expired = append(expired, backup)
+			}
+		}
+	}
+	return expired
+}
+
+// getExpiredManualBackups returns the manual backups that are no longer kept
+// at now. Manual backups are kept forever if retention has no manual policy.
+// Only successful backups count against the retention; failed backups expire
+// once a newer backup succeeded or when they reach the maximum age.
+func getExpiredManualBackups(retention *v32.EtcdBackupRetention, backups []*v3.EtcdBackup, now time.Time) []*v3.EtcdBackup {
+	if retention == nil || retention.Manual == nil {
+		return nil
+	}
+	sorted := append([]*v3.EtcdBackup(nil), backups...)
+	sortNewestFirst(sorted)
+
+	var expired []*v3.EtcdBackup
+	succeeded := 0
+	for _, backup := range sorted {
+		tooOld := retention.Manual.MaxAgeHours > 0 &&
+			now.Sub(getBackupCompletedTime(backup)) > time.Duration(retention.Manual.MaxAgeHours)*time.Hour
+		switch {
+		case rketypes.BackupConditionCompleted.IsTrue(backup):
+			succeeded++
+			if tooOld || (retention.Manual.Count > 0 && succeeded > retention.Manual.Count) {
+				expired = append(expired, backup)
+			}
+		case rketypes.BackupConditionCompleted.IsFalse(backup):
+			if tooOld || succeeded > 0 {
+				expired = append(expired, backup)
+			}
+		}
+	}
+	return expired
+}
+
+// getNextDeletions returns the backups of cluster that are due to be deleted
+// next: recurring backups expiring once the next recurring backup completes,
+// and manual backups reaching their maximum age.
+func getNextDeletions(cluster *v3.Cluster, recurring, manual []*v3.EtcdBackup, now time.Time) []v32.EtcdBackupDeletion {
+	var deletions []v32.EtcdBackupDeletion
+
+	if next := getNextBackupTime(cluster, recurring); !next.IsZero() {
+		expired := map[string]bool{}
+		for _, backup := range getExpiredRecurringBackups(cluster, recurring, now) {
+			expired[backup.Name] = true
+		}
+		// a backup completing at next is what makes older ones expire with GFS
+		upcoming := &v3.EtcdBackup{}
+		upcoming.Name = "upcoming"
+		rketypes.BackupConditionCompleted.True(upcoming)
+		rketypes.BackupConditionCompleted.LastUpdated(upcoming, next.UTC().Format(time.RFC3339))
+		for _, backup := range getExpiredRecurringBackups(cluster, append(append([]*v3.EtcdBackup(nil), recurring...), upcoming), next) {
+			if backup != upcoming && !expired[backup.Name] {
+				deletions = append(deletions, v32.EtcdBackupDeletion{
+					BackupName:  backup.Name,
+					PlannedTime: next.UTC().Format(time.RFC3339),
+				})
+			}
+		}
+	}
+
+	if retention := getRetention(cluster); retention != nil && retention.Manual != nil && retention.Manual.MaxAgeHours > 0 {
+		maxAge := time.Duration(retention.Manual.MaxAgeHours) * time.Hour
+		for _, backup := range manual {
+			if !rketypes.BackupConditionCompleted.IsTrue(backup) {
+				continue
+			}
+			if deleteAt := getBackupCompletedTime(backup).Add(maxAge); deleteAt.After(now) {
+				deletions = append(deletions, v32.EtcdBackupDeletion{
+					BackupName:  backup.Name,
+					Manual:      true,
+					PlannedTime: deleteAt.UTC().Format(time.RFC3339),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(deletions, func(i, j int) bool {
+		return deletions[i].PlannedTime < deletions[j].PlannedTime
+	})
+	if len(deletions) > maxPlannedDeletions {
+		deletions = deletions[:maxPlannedDeletions]
+	}
+	return deletions
+}
+
+// getNextBackupTime returns when the next recurring backup of cluster is due,
+// which is in the past while it is overdue, or the zero time if none of the
+// recurring backups completed. It only depends on the backups so that the
+// deletions planned from it do not change on every sync.
+func getNextBackupTime(cluster *v3.Cluster, recurring []*v3.EtcdBackup) time.Time {
+	interval := time.Duration(cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.IntervalHours) * time.Hour
+	var newest time.Time
+	for _, backup := range recurring {
+		if t := getBackupCompletedTime(backup); t.After(newest) {
+			newest = t
+		}
+	}
+	if newest.IsZero() {
+		return newest
+	}
+	return newest.Add(interval)
+}
+
+func sortNewestFirst(backups []*v3.EtcdBackup) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		return getBackupCompletedTime(backups[i]).After(getBackupCompletedTime(backups[j]))
+	})
+}
+
+func (c *Controller) getManualBackupsList(cluster *v3.Cluster) ([]*v3.EtcdBackup, error) {
+	var manual []*v3.EtcdBackup
+	backups, err := c.backupLister.List(cluster.Name, labels.NewSelector())
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.Spec.Manual {
+			manual = append(manual, backup)
+		}
+	}
+	return manual, nil
+}
+
+// setRetentionStatus records the retention policy of cluster and the next
+// planned deletions on its status, if they changed.
+func (c *Controller) setRetentionStatus(clusterName string, status *v32.EtcdBackupRetentionStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := c.clusterClient.Get(clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(cluster.Status.EtcdBackupRetentionStatus, status) {
+			return nil
+		}
+		updated := cluster.DeepCopy()
+		updated.Status.EtcdBackupRetentionStatus = status
+		_, err = c.clusterClient.Update(updated)
+		return err
+	})
+}
@@ -0,0 +1,191 @@
+package etcdbackup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newTestBackup(name string, completed time.Time, manual bool) *v3.EtcdBackup {
+	backup := &v3.EtcdBackup{}
+	backup.Name = name
+	backup.Spec.Manual = manual
+	rketypes.BackupConditionCompleted.True(backup)
+	rketypes.BackupConditionCompleted.LastUpdated(backup, completed.UTC().Format(time.RFC3339))
+	return backup
+}
+
+func newTestRetentionCluster(retention *v32.EtcdBackupRetention) *v3.Cluster {
+	cluster := &v3.Cluster{}
+	cluster.Spec.RancherKubernetesEngineConfig = &rketypes.RancherKubernetesEngineConfig{}
+	cluster.Spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig = &rketypes.BackupConfig{
+		IntervalHours: 6,
+		Retention:     4,
+	}
+	cluster.Spec.EtcdBackupConfig = &v32.EtcdBackupConfig{Retention: retention}
+	return cluster
+}
+
+func backupNames(backups []*v3.EtcdBackup) []string {
+	var names []string
+	for _, backup := range backups {
+		names = append(names, backup.Name)
+	}
+	return names
+}
+
+func TestGetExpiredRecurringBackupsGFS(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2021, 3, 31, 12, 30, 0, 0, time.UTC)
+
+	// one backup every 6 hours for the last 60 days
+	var backups []*v3.EtcdBackup
+	for i := 0; i < 60*4; i++ {
+		backups = append(backups, newTestBackup(fmt.Sprintf("b%03d", i), now.Add(-time.Duration(i)*6*time.Hour), false))
+	}
+
+	cluster := newTestRetentionCluster(&v32.EtcdBackupRetention{Hourly: 2, Daily: 3, Weekly: 2, Monthly: 2})
+	expired := getExpiredRecurringBackups(cluster, backups, now)
+	names := map[string]bool{}
+	for _, backup := range expired {
+		names[backup.Name] = true
+	}
+	kept := withoutBackups(backups, names)
+	// hourly: b000 (12:30), b001 (06:30); daily: b000, b003 (03-30 18:30), b007 (03-29 18:30);
+	// weekly: b000, b011 (sunday 03-28 18:30); monthly: b000, b123 (02-28 18:30)
+	assert.Equal([]string{"b000", "b001", "b003", "b007", "b011", "b123"}, backupNames(kept))
+
+	failed := newTestBackup("failed", now.Add(-time.Hour), false)
+	rketypes.BackupConditionCompleted.False(failed)
+	rketypes.BackupConditionCompleted.LastUpdated(failed, now.Add(-time.Hour).Format(time.RFC3339))
+	running := &v3.EtcdBackup{}
+	running.Name = "running"
+	expired = getExpiredRecurringBackups(cluster, []*v3.EtcdBackup{backups[0], failed, running}, now)
+	assert.Equal([]string{"failed"}, backupNames(expired), "failed backups older than the newest backup expire")
+}
+
+func TestGetExpiredRecurringBackupsCount(t *testing.T) {
+	now := time.Date(2021, 3, 31, 12, 30, 0, 0, time.UTC)
+	var backups []*v3.EtcdBackup
+	for i := 0; i < 6; i++ {
+		backups = append(backups, newTestBackup(fmt.Sprintf("b%d", i), now.Add(-time.Duration(i)*6*time.Hour-time.Minute), false))
+	}
+	// without GFS periods, backups are kept for retention times interval hours
+	cluster := newTestRetentionCluster(&v32.EtcdBackupRetention{Manual: &v32.EtcdBackupManualRetention{Count: 1}})
+	assert.Equal(t, []string{"b4", "b5"}, backupNames(getExpiredRecurringBackups(cluster, backups, now)))
+}
+
+func TestGetExpiredManualBackups(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2021, 3, 31, 12, 30, 0, 0, time.UTC)
+	backups := []*v3.EtcdBackup{
+		newTestBackup("m2", now.Add(-2*time.Hour), true),
+		newTestBackup("m1", now.Add(-1*time.Hour), true),
+		newTestBackup("m3", now.Add(-50*time.Hour), true),
+	}
+
+	assert.Nil(getExpiredManualBackups(nil, backups, now), "manual backups are kept without a manual retention")
+	assert.Equal([]string{"m2", "m3"}, backupNames(getExpiredManualBackups(&v32.EtcdBackupRetention{
+		Manual: &v32.EtcdBackupManualRetention{Count: 1},
+	}, backups, now)))
+	assert.Equal([]string{"m3"}, backupNames(getExpiredManualBackups(&v32.EtcdBackupRetention{
+		Manual: &v32.EtcdBackupManualRetention{MaxAgeHours: 48},
+	}, backups, now)))
+
+	// failed backups do not count against the retention
+	newFailed := func(name string, completed time.Time) *v3.EtcdBackup {
+		backup := newTestBackup(name, completed, true)
+		rketypes.BackupConditionCompleted.False(backup)
+		rketypes.BackupConditionCompleted.LastUpdated(backup, completed.UTC().Format(time.RFC3339))
+		return backup
+	}
+	backups = []*v3.EtcdBackup{
+		newFailed("f0", now.Add(-30*time.Minute)),
+		newTestBackup("m1", now.Add(-1*time.Hour), true),
+		newFailed("f1", now.Add(-90*time.Minute)),
+		newTestBackup("m2", now.Add(-2*time.Hour), true),
+	}
+	assert.Equal([]string{"f1"}, backupNames(getExpiredManualBackups(&v32.EtcdBackupRetention{
+		Manual: &v32.EtcdBackupManualRetention{Count: 2},
+	}, backups, now)), "failed backups expire once a newer backup succeeded")
+}
+
+func TestGetNextDeletions(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2021, 3, 31, 12, 30, 0, 0, time.UTC)
+	recurring := []*v3.EtcdBackup{
+		newTestBackup("r0", now.Add(-time.Hour), false),
+		newTestBackup("r1", now.Add(-7*time.Hour), false),
+	}
+	manual := []*v3.EtcdBackup{
+		newTestBackup("m0", now.Add(-time.Hour), true),
+	}
+	cluster := newTestRetentionCluster(&v32.EtcdBackupRetention{
+		Hourly: 2,
+		Manual: &v32.EtcdBackupManualRetention{MaxAgeHours: 24},
+	})
+
+	assert.Equal([]v32.EtcdBackupDeletion{
+		{BackupName: "r1", PlannedTime: "2021-03-31T17:30:00Z"},
+		{BackupName: "m0", Manual: true, PlannedTime: "2021-04-01T11:30:00Z"},
+	}, getNextDeletions(cluster, recurring, manual, now))
+	assert.Equal("recurring: 2 hourly, 0 daily, 0 weekly, 0 monthly; manual: at most 24h old", describeRetention(cluster))
+
+	assert.Equal("recurring: 4 backups every 6h; manual: keep all", describeRetention(newTestRetentionCluster(nil)))
+
+	// an overdue backup does not move the planned deletions
+	overdue := now.Add(7 * time.Hour)
+	deletions := getNextDeletions(cluster, recurring, nil, overdue)
+	assert.Equal([]v32.EtcdBackupDeletion{{BackupName: "r1", PlannedTime: "2021-03-31T17:30:00Z"}}, deletions)
+	assert.Equal(deletions, getNextDeletions(cluster, recurring, nil, overdue.Add(clusterBackupCheckInterval)))
+	assert.Empty(getNextDeletions(cluster, nil, nil, now), "nothing is planned before a recurring backup completed")
+}
+
+func TestRotateManualBackupsWithoutRecurring(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	cluster := newTestRetentionCluster(&v32.EtcdBackupRetention{Manual: &v32.EtcdBackupManualRetention{Count: 1}})
+	cluster.Name = "c-abcde"
+	v32.ClusterConditionReady.True(cluster)
+	backups := []*v3.EtcdBackup{
+		newTestBackup("m0", now.Add(-time.Hour), true),
+		newTestBackup("m1", now.Add(-2*time.Hour), true),
+		newTestBackup("r0", now.Add(-3*time.Hour), false),
+	}
+
+	var deleted []string
+	c := &Controller{
+		backupLister: &fakes.EtcdBackupListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.EtcdBackup, error) {
+				return backups, nil
+			},
+		},
+		backupClient: &fakes.EtcdBackupInterfaceMock{
+			DeleteNamespacedFunc: func(namespace string, name string, options *metav1.DeleteOptions) error {
+				deleted = append(deleted, name)
+				return nil
+			},
+		},
+		clusterClient: &fakes.ClusterInterfaceMock{
+			GetFunc: func(name string, opts metav1.GetOptions) (*v3.Cluster, error) {
+				return cluster, nil
+			},
+			UpdateFunc: func(in *v3.Cluster) (*v3.Cluster, error) {
+				cluster = in
+				return in, nil
+			},
+		},
+	}
+	assert.False(shouldBackup(cluster), "recurring backups are disabled")
+	assert.Nil(c.doClusterBackupSync(cluster))
+	assert.Equal([]string{"m1"}, deleted, "manual backups are rotated and recurring ones kept")
+	assert.NotNil(cluster.Status.EtcdBackupRetentionStatus)
+}
@@ -0,0 +1,183 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+const (
+	azureBlockSize = 4 * 1024 * 1024
+	// address the storage client sends requests for the emulator account to
+	azureEmulatorAddress = "127.0.0.1:10000"
+)
+
+type azureBlobTarget struct {
+	container *storage.Container
+	folder    string
+}
+
+// NewAzureBlobTarget returns an Azure Blob Storage backup target. The
+// devstoreaccount1 account targets the storage emulator, Azurite.
+func NewAzureBlobTarget(config *v32.AzureBlobBackupConfig) (Target, error) {
+	var (
+		client storage.Client
+		err    error
+	)
+	if config.AccountName == storage.StorageEmulatorAccountName {
+		client, err = storage.NewEmulatorClient()
+		if err == nil && config.EmulatorAddress != "" {
+			client.HTTPClient = &http.Client{Transport: emulatorTransport(config.EmulatorAddress)}
+		}
+	} else {
+		suffix := config.EndpointSuffix
+		if suffix == "" {
+			suffix = storage.DefaultBaseURL
+		}
+		client, err = storage.NewClient(config.AccountName, config.AccountKey, suffix, storage.DefaultAPIVersion, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	blobService := client.GetBlobService()
+	return &azureBlobTarget{
+		container: blobService.GetContainerReference(config.Container),
+		folder:    config.Folder,
+	}, nil
+}
+
+// emulatorTransport sends the requests of the storage client, which always
+// go to the default emulator address, to address instead.
+func emulatorTransport(address string) http.RoundTripper {
+	dialer := &net.Dialer{}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == azureEmulatorAddress {
+			addr = address
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return tr
+}
+
+func (t *azureBlobTarget) Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error) {
+	info, err := t.Stat(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := t.blob(name).Get(nil)
+	if err != nil {
+		return nil, nil, t.wrapError(name, err)
+	}
+	return rc, info, nil
+}
+
+func (t *azureBlobTarget) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	blob := t.blob(name)
+	if err := blob.GetProperties(nil); err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	if err := blob.GetMetadata(nil); err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	return azureFileInfo(name, blob), nil
+}
+
+func (t *azureBlobTarget) Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error {
+	blob := t.blob(name)
+	var blocks []storage.Block
+	buf := make([]byte, azureBlockSize)
+	for read := int64(0); read < size; {
+		n, err := io.ReadFull(src, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", len(blocks))))
+		if err := blob.PutBlock(id, buf[:n], nil); err != nil {
+			return err
+		}
+		blocks = append(blocks, storage.Block{ID: id, Status: storage.BlockStatusUncommitted})
+		read += int64(n)
+	}
+	if err := blob.PutBlockList(blocks, nil); err != nil {
+		return err
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	// metadata names must be valid C# identifiers
+	blob.Metadata = storage.BlobMetadata{}
+	for k, v := range metadata {
+		blob.Metadata[strings.ReplaceAll(k, "-", "_")] = v
+	}
+	return blob.SetMetadata(nil)
+}
+
+func (t *azureBlobTarget) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var result []FileInfo
+	params := storage.ListBlobsParameters{
+		Prefix:  joinFolder(t.folder, prefix),
+		Include: &storage.IncludeBlobDataset{Metadata: true},
+	}
+	for {
+		resp, err := t.container.ListBlobs(params)
+		if err != nil {
+			return nil, err
+		}
+		for i := range resp.Blobs {
+			blob := &resp.Blobs[i]
+			if strings.Contains(strings.TrimPrefix(blob.Name, params.Prefix), "/") {
+				continue
+			}
+			result = append(result, *azureFileInfo(strings.TrimPrefix(blob.Name, joinFolder(t.folder, "")), blob))
+		}
+		if resp.NextMarker == "" {
+			return result, nil
+		}
+		params.Marker = resp.NextMarker
+	}
+}
+
+func (t *azureBlobTarget) Delete(ctx context.Context, name string) error {
+	_, err := t.blob(name).DeleteIfExists(nil)
+	return err
+}
+
+func (t *azureBlobTarget) blob(name string) *storage.Blob {
+	return t.container.GetBlobReference(joinFolder(t.folder, name))
+}
+
+func (t *azureBlobTarget) wrapError(name string, err error) error {
+	status := 0
+	switch e := err.(type) {
+	case storage.AzureStorageServiceError:
+		status = e.StatusCode
+	case *storage.AzureStorageServiceError:
+		status = e.StatusCode
+	case storage.UnexpectedStatusCodeError:
+		status = e.Got()
+	}
+	if status == http.StatusNotFound {
+		return &NotFoundError{Name: name}
+	}
+	return err
+}
+
+func azureFileInfo(name string, blob *storage.Blob) *FileInfo {
+	metadata := map[string]string{}
+	for k, v := range blob.Metadata {
+		metadata[strings.ReplaceAll(strings.ToLower(k), "_", "-")] = v
+	}
+	return &FileInfo{
+		Name:     name,
+		Size:     blob.Properties.ContentLength,
+		Metadata: metadata,
+	}
+}
@@ -0,0 +1,81 @@
+package snapshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+)
+
+// The tests in this file run against local emulators and are skipped unless
+// their address is set:
+//
+//   AZURITE_ADDRESS=127.0.0.1:10000 for Azurite
+//     (docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0)
+//   MINIO_ENDPOINT=127.0.0.1:9000, MINIO_ACCESS_KEY, MINIO_SECRET_KEY and
+//     MINIO_CA_FILE for a MinIO server with TLS enabled
+
+func testBucketName() string {
+	return fmt.Sprintf("etcd-snapshot-test-%d", time.Now().UnixNano())
+}
+
+func TestAzureBlobTargetEmulator(t *testing.T) {
+	address := os.Getenv("AZURITE_ADDRESS")
+	if address == "" {
+		t.Skip("AZURITE_ADDRESS is not set")
+	}
+	target, err := NewAzureBlobTarget(&v32.AzureBlobBackupConfig{
+		AccountName:     storage.StorageEmulatorAccountName,
+		Container:       testBucketName(),
+		EmulatorAddress: address,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := target.(*azureBlobTarget).container
+	if _, err := container.CreateIfNotExists(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer container.DeleteIfExists(nil)
+	testTarget(t, target)
+}
+
+func TestS3TargetEmulator(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT is not set")
+	}
+	sbc := &rketypes.S3BackupConfig{
+		Endpoint:   endpoint,
+		AccessKey:  os.Getenv("MINIO_ACCESS_KEY"),
+		SecretKey:  os.Getenv("MINIO_SECRET_KEY"),
+		BucketName: testBucketName(),
+		Folder:     "etcd",
+	}
+	if file := os.Getenv("MINIO_CA_FILE"); file != "" {
+		ca, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sbc.CustomCA = string(ca)
+	}
+	dialer := (&net.Dialer{}).DialContext
+	client, err := NewS3Client(sbc, dialer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MakeBucket(sbc.BucketName, ""); err != nil {
+		t.Fatal(err)
+	}
+	target, err := NewS3Target(sbc, dialer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget(t, target)
+}
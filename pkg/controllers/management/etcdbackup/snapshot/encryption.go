@@ -0,0 +1,364 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/ref"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Snapshots are encrypted with a random data key per snapshot. The data key
+// is sealed with a key encryption key from a Secret, so rotating the key only
+// requires adding a new entry to the Secret and making it the active one;
+// snapshots sealed with older entries stay restorable as long as those
+// entries are kept.
+const (
+	AlgorithmAES256GCM = "AES-256-GCM"
+
+	// MetadataEncryption is the metadata key of snapshot files holding their
+	// encryption metadata.
+	MetadataEncryption = "rancher-snapshot-encryption"
+
+	keySize         = 32
+	chunkSize       = 64 * 1024
+	noncePrefixSize = 7
+	streamNonceSize = 12
+	sealedChunkSize = chunkSize + 16
+	lastChunkFlag   = 1
+	maxChunkCounter = 1<<32 - 1
+)
+
+// EncryptionMetadata describes how a snapshot was encrypted. It holds no
+// secret material: the data key is only stored sealed with the key named by
+// KeyID.
+type EncryptionMetadata struct {
+	Algorithm   string `json:"algorithm"`
+	KeyID       string `json:"keyId"`
+	SealedKey   string `json:"sealedKey"`
+	NoncePrefix string `json:"noncePrefix"`
+	ChunkSize   int    `json:"chunkSize"`
+}
+
+// IsEncryptionEnabled reports whether the snapshots of a cluster with spec are
+// encrypted.
+func IsEncryptionEnabled(spec *v32.ClusterSpec) bool {
+	return spec.EtcdBackupConfig != nil &&
+		spec.EtcdBackupConfig.Encryption != nil &&
+		spec.EtcdBackupConfig.Encryption.SecretName != ""
+}
+
+// WithoutS3Upload returns spec without the S3 config of its rke backup config
+// if snapshots are encrypted, so that rke only keeps them on the etcd nodes
+// instead of uploading them in the clear. Rancher uploads them once they are
+// encrypted.
+func WithoutS3Upload(spec v32.ClusterSpec) v32.ClusterSpec {
+	rke := spec.RancherKubernetesEngineConfig
+	if !IsEncryptionEnabled(&spec) || rke == nil ||
+		rke.Services.Etcd.BackupConfig == nil || rke.Services.Etcd.BackupConfig.S3BackupConfig == nil {
+		return spec
+	}
+	rkeCopy := *rke
+	backupConfig := *rke.Services.Etcd.BackupConfig
+	backupConfig.S3BackupConfig = nil
+	rkeCopy.Services.Etcd.BackupConfig = &backupConfig
+	spec.RancherKubernetesEngineConfig = &rkeCopy
+	return spec
+}
+
+// Keyring holds the key encryption keys of a cluster, by ID.
+type Keyring struct {
+	Active string
+	Keys   map[string][]byte
+}
+
+// NewKeyring reads the key encryption keys from secret. Every data entry is a
+// 32 byte key, raw or base64 encoded, named by its ID. active selects the key
+// new snapshots are sealed with and may only be empty if the secret holds a
+// single key.
+func NewKeyring(secret *corev1.Secret, active string) (*Keyring, error) {
+	keyring := &Keyring{
+		Active: active,
+		Keys:   map[string][]byte{},
+	}
+	var ids []string
+	for id, value := range secret.Data {
+		key, err := parseKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key [%s] in secret [%s:%s]: %v", id, secret.Namespace, secret.Name, err)
+		}
+		keyring.Keys[id] = key
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if keyring.Active == "" {
+		if len(ids) != 1 {
+			return nil, fmt.Errorf("secret [%s:%s] must hold exactly one key when no active key is set", secret.Namespace, secret.Name)
+		}
+		keyring.Active = ids[0]
+	}
+	if _, ok := keyring.Keys[keyring.Active]; !ok {
+		return nil, fmt.Errorf("active key [%s] not found in secret [%s:%s]", keyring.Active, secret.Namespace, secret.Name)
+	}
+	return keyring, nil
+}
+
+func parseKey(value []byte) ([]byte, error) {
+	if len(value) == keySize {
+		return value, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(value)))
+	if err != nil || len(decoded) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, raw or base64 encoded", keySize)
+	}
+	return decoded, nil
+}
+
+// Encrypt writes src encrypted with a new data key to dst. The data key is
+// sealed with the active key of the keyring.
+func (k *Keyring) Encrypt(dst io.Writer, src io.Reader) (*EncryptionMetadata, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	noncePrefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, noncePrefix); err != nil {
+		return nil, err
+	}
+	sealedKey, err := sealKey(k.Keys[k.Active], k.Active, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := encryptStream(aead, noncePrefix, dst, src); err != nil {
+		return nil, err
+	}
+	return &EncryptionMetadata{
+		Algorithm:   AlgorithmAES256GCM,
+		KeyID:       k.Active,
+		SealedKey:   base64.StdEncoding.EncodeToString(sealedKey),
+		NoncePrefix: base64.StdEncoding.EncodeToString(noncePrefix),
+		ChunkSize:   chunkSize,
+	}, nil
+}
+
+// Decrypt writes src, encrypted as described by meta, decrypted to dst.
+func (k *Keyring) Decrypt(dst io.Writer, src io.Reader, meta *EncryptionMetadata) error {
+	if meta.Algorithm != AlgorithmAES256GCM {
+		return fmt.Errorf("unsupported snapshot encryption algorithm [%s]", meta.Algorithm)
+	}
+	if meta.ChunkSize != chunkSize {
+		return fmt.Errorf("unsupported snapshot encryption chunk size %d", meta.ChunkSize)
+	}
+	kek, ok := k.Keys[meta.KeyID]
+	if !ok {
+		return fmt.Errorf("snapshot is encrypted with key [%s] which is no longer available", meta.KeyID)
+	}
+	sealedKey, err := base64.StdEncoding.DecodeString(meta.SealedKey)
+	if err != nil {
+		return fmt.Errorf("invalid sealed data key: %v", err)
+	}
+	noncePrefix, err := base64.StdEncoding.DecodeString(meta.NoncePrefix)
+	if err != nil || len(noncePrefix) != noncePrefixSize {
+		return fmt.Errorf("invalid nonce prefix")
+	}
+	dataKey, err := openKey(kek, meta.KeyID, sealedKey)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	return decryptStream(aead, noncePrefix, dst, src)
+}
+
+// Metadata returns meta as metadata of a snapshot file.
+func (m *EncryptionMetadata) Metadata() (map[string]string, error) {
+	value, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		MetadataEncryption: base64.StdEncoding.EncodeToString([]byte(value)),
+	}, nil
+}
+
+// Marshal returns meta as JSON.
+func (m *EncryptionMetadata) Marshal() (string, error) {
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// Status returns meta as the encryption status of an EtcdBackup.
+func (m *EncryptionMetadata) Status() *v32.EtcdBackupEncryptionStatus {
+	return &v32.EtcdBackupEncryptionStatus{
+		Algorithm:   m.Algorithm,
+		KeyID:       m.KeyID,
+		SealedKey:   m.SealedKey,
+		NoncePrefix: m.NoncePrefix,
+		ChunkSize:   m.ChunkSize,
+	}
+}
+
+// UnmarshalEncryptionMetadata parses metadata returned by Marshal.
+func UnmarshalEncryptionMetadata(value string) (*EncryptionMetadata, error) {
+	meta := &EncryptionMetadata{}
+	if err := json.Unmarshal([]byte(value), meta); err != nil {
+		return nil, fmt.Errorf("invalid snapshot encryption metadata: %v", err)
+	}
+	return meta, nil
+}
+
+// EncryptionMetadataFrom returns the encryption metadata held by the metadata
+// of a snapshot file, or nil if the file is not encrypted.
+func EncryptionMetadataFrom(metadata map[string]string) (*EncryptionMetadata, error) {
+	value := metadata[MetadataEncryption]
+	if value == "" {
+		return nil, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot encryption metadata: %v", err)
+	}
+	return UnmarshalEncryptionMetadata(string(decoded))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealKey(kek []byte, keyID string, dataKey []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+func openKey(kek []byte, keyID string, sealedKey []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealedKey) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid sealed data key")
+	}
+	nonce, ciphertext := sealedKey[:aead.NonceSize()], sealedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal data key with key [%s]: %v", keyID, err)
+	}
+	return dataKey, nil
+}
+
+// streamNonce returns the nonce of a chunk: the random prefix of the
+// snapshot, the chunk counter and a flag marking the last chunk, so chunks
+// can be neither reordered nor dropped from the end.
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, streamNonceSize)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[streamNonceSize-1] = lastChunkFlag
+	}
+	return nonce
+}
+
+func encryptStream(aead cipher.AEAD, prefix []byte, dst io.Writer, src io.Reader) error {
+	buf := make([]byte, chunkSize)
+	next := make([]byte, chunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	for counter := uint32(0); ; counter++ {
+		m, err := io.ReadFull(src, next)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := m == 0
+		if _, err := dst.Write(aead.Seal(nil, streamNonce(prefix, counter, last), buf[:n], nil)); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if counter == maxChunkCounter {
+			return fmt.Errorf("snapshot is too large to encrypt")
+		}
+		buf, next, n = next, buf, m
+	}
+}
+
+func decryptStream(aead cipher.AEAD, prefix []byte, dst io.Writer, src io.Reader) error {
+	buf := make([]byte, sealedChunkSize)
+	next := make([]byte, sealedChunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("encrypted snapshot is empty")
+		}
+		return err
+	}
+	for counter := uint32(0); ; counter++ {
+		m, err := io.ReadFull(src, next)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := m == 0
+		plaintext, err := aead.Open(nil, streamNonce(prefix, counter, last), buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt snapshot: %v", err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if counter == maxChunkCounter {
+			return fmt.Errorf("encrypted snapshot is too large")
+		}
+		buf, next, n = next, buf, m
+	}
+}
+
+// LoadKeyring returns the keyring configured by encryption, or nil if
+// snapshots are not encrypted.
+func LoadKeyring(secrets v1.SecretLister, encryption *v32.EtcdBackupEncryption) (*Keyring, error) {
+	if encryption == nil || encryption.SecretName == "" {
+		return nil, nil
+	}
+	ns, name := ref.Parse(encryption.SecretName)
+	if ns == "" {
+		ns = namespace.GlobalNamespace
+	}
+	secret, err := secrets.Get(ns, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot encryption secret [%s]: %v", encryption.SecretName, err)
+	}
+	return NewKeyring(secret, encryption.ActiveKey)
+}
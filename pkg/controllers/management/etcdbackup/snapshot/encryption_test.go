@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestKey(t *testing.T) []byte {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTestSecret(keys map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "etcd-keys"},
+		Data:       keys,
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	assert := assert.New(t)
+	keyring, err := NewKeyring(newTestSecret(map[string][]byte{"k1": newTestKey(t)}), "")
+	assert.Nil(err)
+
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+
+		var encrypted bytes.Buffer
+		meta, err := keyring.Encrypt(&encrypted, bytes.NewReader(plaintext))
+		assert.Nil(err)
+		assert.Equal("k1", meta.KeyID)
+		assert.False(size > 0 && bytes.Contains(encrypted.Bytes(), plaintext), "size %d", size)
+
+		var decrypted bytes.Buffer
+		assert.Nil(keyring.Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes()), meta), "size %d", size)
+		assert.Equal(size, decrypted.Len(), "size %d", size)
+		assert.True(bytes.Equal(plaintext, decrypted.Bytes()), "size %d", size)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	assert := assert.New(t)
+	keyring, err := NewKeyring(newTestSecret(map[string][]byte{"k1": newTestKey(t)}), "")
+	assert.Nil(err)
+
+	plaintext := make([]byte, 2*chunkSize+10)
+	var encrypted bytes.Buffer
+	meta, err := keyring.Encrypt(&encrypted, bytes.NewReader(plaintext))
+	assert.Nil(err)
+
+	flipped := append([]byte(nil), encrypted.Bytes()...)
+	flipped[chunkSize] ^= 1
+	assert.Error(keyring.Decrypt(&bytes.Buffer{}, bytes.NewReader(flipped), meta))
+
+	truncated := encrypted.Bytes()[:2*sealedChunkSize]
+	assert.Error(keyring.Decrypt(&bytes.Buffer{}, bytes.NewReader(truncated), meta), "dropping the last chunk must be detected")
+
+	assert.Error(keyring.Decrypt(&bytes.Buffer{}, bytes.NewReader(nil), meta))
+}
+
+func TestKeyRotation(t *testing.T) {
+	assert := assert.New(t)
+	k1, k2 := newTestKey(t), newTestKey(t)
+
+	old, err := NewKeyring(newTestSecret(map[string][]byte{"k1": k1}), "")
+	assert.Nil(err)
+	var encrypted bytes.Buffer
+	meta, err := old.Encrypt(&encrypted, bytes.NewReader([]byte("snapshot")))
+	assert.Nil(err)
+
+	rotated, err := NewKeyring(newTestSecret(map[string][]byte{"k1": k1, "k2": k2}), "k2")
+	assert.Nil(err)
+	var decrypted bytes.Buffer
+	assert.Nil(rotated.Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes()), meta))
+	assert.Equal("snapshot", decrypted.String())
+
+	newMeta, err := rotated.Encrypt(&bytes.Buffer{}, bytes.NewReader([]byte("snapshot")))
+	assert.Nil(err)
+	assert.Equal("k2", newMeta.KeyID)
+
+	removed, err := NewKeyring(newTestSecret(map[string][]byte{"k2": k2}), "k2")
+	assert.Nil(err)
+	assert.EqualError(removed.Decrypt(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), meta),
+		"snapshot is encrypted with key [k1] which is no longer available")
+
+	meta.KeyID = "k2"
+	assert.Error(rotated.Decrypt(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), meta), "data key is bound to its key ID")
+}
+
+func TestNewKeyring(t *testing.T) {
+	assert := assert.New(t)
+	key := newTestKey(t)
+
+	keyring, err := NewKeyring(newTestSecret(map[string][]byte{"k1": []byte(base64.StdEncoding.EncodeToString(key) + "\n")}), "")
+	assert.Nil(err)
+	assert.Equal(key, keyring.Keys["k1"])
+
+	_, err = NewKeyring(newTestSecret(map[string][]byte{"k1": key, "k2": key}), "")
+	assert.EqualError(err, "secret [cattle-global-data:etcd-keys] must hold exactly one key when no active key is set")
+
+	_, err = NewKeyring(newTestSecret(map[string][]byte{"k1": key}), "k2")
+	assert.EqualError(err, "active key [k2] not found in secret [cattle-global-data:etcd-keys]")
+
+	_, err = NewKeyring(newTestSecret(map[string][]byte{"k1": []byte("short")}), "")
+	assert.EqualError(err, "invalid key [k1] in secret [cattle-global-data:etcd-keys]: key must be 32 bytes, raw or base64 encoded")
+}
+
+func TestEncryptionMetadataFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := &EncryptionMetadata{
+		Algorithm:   AlgorithmAES256GCM,
+		KeyID:       "k1",
+		SealedKey:   "c2VhbGVk",
+		NoncePrefix: "bm9uY2Vz",
+		ChunkSize:   chunkSize,
+	}
+	metadata, err := meta.Metadata()
+	assert.Nil(err)
+	parsed, err := EncryptionMetadataFrom(metadata)
+	assert.Nil(err)
+	assert.Equal(meta, parsed)
+
+	parsed, err = EncryptionMetadataFrom(nil)
+	assert.Nil(err)
+	assert.Nil(parsed)
+}
@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+// metadata of a file is stored next to it, in a file with this suffix
+const filesystemMetadataSuffix = ".metadata.json"
+
+type filesystemTarget struct {
+	path string
+}
+
+// NewFilesystemTarget returns a backup target storing snapshots in a
+// directory, such as an NFS volume mounted into the rancher server.
+func NewFilesystemTarget(config *v32.FilesystemBackupConfig) (Target, error) {
+	if !filepath.IsAbs(config.Path) {
+		return nil, fmt.Errorf("backup target path [%s] must be absolute", config.Path)
+	}
+	return &filesystemTarget{
+		path: config.Path,
+	}, nil
+}
+
+func (t *filesystemTarget) Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error) {
+	info, err := t.Stat(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(t.file(name))
+	if err != nil {
+		return nil, nil, t.wrapError(name, err)
+	}
+	return f, info, nil
+}
+
+func (t *filesystemTarget) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	fi, err := os.Stat(t.file(name))
+	if err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	metadata := map[string]string{}
+	data, err := ioutil.ReadFile(t.file(name) + filesystemMetadataSuffix)
+	if err == nil {
+		err = json.Unmarshal(data, &metadata)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata of snapshot file [%s]: %v", name, err)
+	}
+	return &FileInfo{
+		Name:     name,
+		Size:     fi.Size(),
+		Metadata: metadata,
+	}, nil
+}
+
+func (t *filesystemTarget) Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error {
+	if err := os.MkdirAll(t.path, 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first, so readers never see a partial file
+	tmp, err := ioutil.TempFile(t.path, "."+name+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.CopyN(tmp, src, size); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if len(metadata) > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(t.file(name)+filesystemMetadataSuffix, data, 0600); err != nil {
+			return err
+		}
+	} else if err := os.Remove(t.file(name) + filesystemMetadataSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.file(name))
+}
+
+func (t *filesystemTarget) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	entries, err := ioutil.ReadDir(t.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var result []FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, ".") || strings.HasSuffix(name, filesystemMetadataSuffix) {
+			continue
+		}
+		info, err := t.Stat(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *info)
+	}
+	return result, nil
+}
+
+func (t *filesystemTarget) Delete(ctx context.Context, name string) error {
+	for _, file := range []string{t.file(name), t.file(name) + filesystemMetadataSuffix} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *filesystemTarget) file(name string) string {
+	return filepath.Join(t.path, filepath.Base(name))
+}
+
+func (t *filesystemTarget) wrapError(name string, err error) error {
+	if os.IsNotExist(err) {
+		return &NotFoundError{Name: name}
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	gcs "google.golang.org/api/storage/v1"
+)
+
+type gcsTarget struct {
+	service *gcs.Service
+	bucket  string
+	folder  string
+}
+
+// NewGCSTarget returns a Google Cloud Storage backup target. Targets with an
+// endpoint and without a service account key, such as fake-gcs-server, are
+// accessed without authentication.
+func NewGCSTarget(ctx context.Context, config *v32.GCSBackupConfig) (Target, error) {
+	var opts []option.ClientOption
+	if config.ServiceAccountKey != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.ServiceAccountKey)))
+	}
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+		if config.ServiceAccountKey == "" {
+			opts = append(opts, option.WithoutAuthentication())
+		}
+	}
+	service, err := gcs.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsTarget{
+		service: service,
+		bucket:  config.Bucket,
+		folder:  config.Folder,
+	}, nil
+}
+
+func (t *gcsTarget) Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error) {
+	info, err := t.Stat(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := t.service.Objects.Get(t.bucket, joinFolder(t.folder, name)).Context(ctx).Download()
+	if err != nil {
+		return nil, nil, t.wrapError(name, err)
+	}
+	return resp.Body, info, nil
+}
+
+func (t *gcsTarget) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	obj, err := t.service.Objects.Get(t.bucket, joinFolder(t.folder, name)).Context(ctx).Do()
+	if err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	return gcsFileInfo(name, obj), nil
+}
+
+func (t *gcsTarget) Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error {
+	obj := &gcs.Object{
+		Name:     joinFolder(t.folder, name),
+		Metadata: metadata,
+	}
+	_, err := t.service.Objects.Insert(t.bucket, obj).Media(src).Context(ctx).Do()
+	return err
+}
+
+func (t *gcsTarget) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var result []FileInfo
+	err := t.service.Objects.List(t.bucket).Prefix(joinFolder(t.folder, prefix)).Delimiter("/").Pages(ctx, func(objects *gcs.Objects) error {
+		for _, obj := range objects.Items {
+			result = append(result, *gcsFileInfo(strings.TrimPrefix(obj.Name, joinFolder(t.folder, "")), obj))
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (t *gcsTarget) Delete(ctx context.Context, name string) error {
+	err := t.service.Objects.Delete(t.bucket, joinFolder(t.folder, name)).Context(ctx).Do()
+	if IsNotFound(t.wrapError(name, err)) {
+		return nil
+	}
+	return err
+}
+
+func (t *gcsTarget) wrapError(name string, err error) error {
+	if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+		return &NotFoundError{Name: name}
+	}
+	return err
+}
+
+func gcsFileInfo(name string, obj *gcs.Object) *FileInfo {
+	metadata := map[string]string{}
+	for k, v := range obj.Metadata {
+		metadata[strings.ToLower(k)] = v
+	}
+	return &FileInfo{
+		Name:     name,
+		Size:     int64(obj.Size),
+		Metadata: metadata,
+	}
+}
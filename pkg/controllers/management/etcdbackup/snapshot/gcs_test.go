@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	gcs "google.golang.org/api/storage/v1"
+)
+
+// fakeGCS serves the parts of the Cloud Storage JSON API the GCS target uses,
+// for a single bucket.
+type fakeGCS struct {
+	sync.Mutex
+	bucket  string
+	objects map[string]*gcs.Object
+	data    map[string][]byte
+}
+
+func newFakeGCS(bucket string) *fakeGCS {
+	return &fakeGCS{
+		bucket:  bucket,
+		objects: map[string]*gcs.Object{},
+		data:    map[string][]byte{},
+	}
+}
+
+func (f *fakeGCS) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	objects := "/storage/v1/b/" + f.bucket + "/o"
+	path := req.URL.EscapedPath()
+	switch {
+	case req.Method == http.MethodPost && path == "/upload"+objects:
+		f.insert(rw, req)
+	case req.Method == http.MethodGet && path == objects:
+		f.list(rw, req)
+	case strings.HasPrefix(path, objects+"/"):
+		name, err := url.PathUnescape(strings.TrimPrefix(path, objects+"/"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj, ok := f.objects[name]
+		switch {
+		case !ok:
+			rw.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(rw).Encode(map[string]interface{}{"error": map[string]interface{}{"code": http.StatusNotFound, "message": "Not Found"}})
+		case req.Method == http.MethodDelete:
+			delete(f.objects, name)
+			delete(f.data, name)
+			rw.WriteHeader(http.StatusNoContent)
+		case req.URL.Query().Get("alt") == "media":
+			rw.Write(f.data[name])
+		default:
+			json.NewEncoder(rw).Encode(obj)
+		}
+	default:
+		http.Error(rw, "unsupported request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeGCS) insert(rw http.ResponseWriter, req *http.Request) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reader := multipart.NewReader(req.Body, params["boundary"])
+	obj := &gcs.Object{}
+	part, err := reader.NextPart()
+	if err == nil {
+		err = json.NewDecoder(part).Decode(obj)
+	}
+	var data []byte
+	if err == nil {
+		if part, err = reader.NextPart(); err == nil {
+			data, err = ioutil.ReadAll(part)
+		}
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	obj.Bucket = f.bucket
+	obj.Size = uint64(len(data))
+	f.objects[obj.Name] = obj
+	f.data[obj.Name] = data
+	json.NewEncoder(rw).Encode(obj)
+}
+
+func (f *fakeGCS) list(rw http.ResponseWriter, req *http.Request) {
+	prefix := req.URL.Query().Get("prefix")
+	delimiter := req.URL.Query().Get("delimiter")
+	var names []string
+	for name := range f.objects {
+		rest := strings.TrimPrefix(name, prefix)
+		if strings.HasPrefix(name, prefix) && (delimiter == "" || !strings.Contains(rest, delimiter)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := &gcs.Objects{}
+	for _, name := range names {
+		result.Items = append(result.Items, f.objects[name])
+	}
+	json.NewEncoder(rw).Encode(result)
+}
+
+func TestGCSTarget(t *testing.T) {
+	server := httptest.NewServer(newFakeGCS("backups"))
+	defer server.Close()
+
+	for _, folder := range []string{"", "etcd/snapshots"} {
+		target, err := NewGCSTarget(context.Background(), &v32.GCSBackupConfig{
+			Bucket:   "backups",
+			Folder:   folder,
+			Endpoint: server.URL + "/storage/v1/",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testTarget(t, target)
+	}
+}
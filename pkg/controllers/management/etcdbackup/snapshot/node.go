@@ -0,0 +1,212 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/rancher/norman/types/slice"
+	"github.com/rancher/rke/docker"
+	"github.com/rancher/rke/hosts"
+	"github.com/rancher/rke/services"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/rancher/rke/util"
+)
+
+// nodeSnapshotDir is where the local snapshot directory of rke is mounted in
+// the helper containers run on etcd nodes.
+const nodeSnapshotDir = "/backup"
+
+type nodeTarget struct {
+	host          *hosts.Host
+	dialerFactory hosts.DialerFactory
+	prefixPath    string
+	version       string
+	image         string
+}
+
+// NewEtcdNodeTargets returns targets for the local snapshot directory of the
+// etcd nodes of rkeConfig, which rke takes snapshots to and restores local
+// snapshots from. Nodes are reached over their docker socket, with a helper
+// container of the rke-tools image mounting the directory. Files on nodes
+// have no metadata, so only plain snapshots can be stored there.
+func NewEtcdNodeTargets(rkeConfig *rketypes.RancherKubernetesEngineConfig, dialerFactory hosts.DialerFactory) ([]Target, error) {
+	image, err := util.GetDefaultRKETools(rkeConfig.SystemImages.Alpine)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	for _, node := range rkeConfig.Nodes {
+		if !slice.ContainsString(node.Role, services.ETCDRole) {
+			continue
+		}
+		targets = append(targets, &nodeTarget{
+			host:          &hosts.Host{RKEConfigNode: node},
+			dialerFactory: dialerFactory,
+			prefixPath:    rkeConfig.PrefixPath,
+			version:       rkeConfig.Version,
+			image:         image,
+		})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("cluster has no etcd nodes")
+	}
+	return targets, nil
+}
+
+func (t *nodeTarget) String() string {
+	return t.host.Address
+}
+
+func (t *nodeTarget) Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error) {
+	id, err := t.createContainer(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, _, err := t.host.DClient.CopyFromContainer(ctx, id, t.file(name))
+	if err != nil {
+		t.removeContainer(id)
+		return nil, nil, t.wrapError(name, err)
+	}
+	tr := tar.NewReader(rc)
+	header, err := tr.Next()
+	if err != nil {
+		rc.Close()
+		t.removeContainer(id)
+		return nil, nil, fmt.Errorf("failed to read snapshot [%s] from etcd node [%s]: %v", name, t.host.Address, err)
+	}
+	return &nodeFile{
+		Reader: tr,
+		close: func() error {
+			defer t.removeContainer(id)
+			return rc.Close()
+		},
+	}, &FileInfo{Name: name, Size: header.Size}, nil
+}
+
+func (t *nodeTarget) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	id, err := t.createContainer(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer t.removeContainer(id)
+	stat, err := t.host.DClient.ContainerStatPath(ctx, id, t.file(name))
+	if err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	return &FileInfo{Name: name, Size: stat.Size}, nil
+}
+
+func (t *nodeTarget) Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error {
+	if len(metadata) > 0 {
+		return fmt.Errorf("snapshot [%s] has metadata, which etcd nodes cannot store", name)
+	}
+	id, err := t.createContainer(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer t.removeContainer(id)
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:    path.Base(name),
+			Mode:    0600,
+			Size:    size,
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, src, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	err = t.host.DClient.CopyToContainer(ctx, id, nodeSnapshotDir, pr, types.CopyToContainerOptions{})
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to copy snapshot [%s] to etcd node [%s]: %v", name, t.host.Address, err)
+	}
+	return nil
+}
+
+func (t *nodeTarget) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	return nil, fmt.Errorf("listing the snapshots of etcd nodes is not supported")
+}
+
+func (t *nodeTarget) Delete(ctx context.Context, name string) error {
+	id, err := t.createContainer(ctx, []string{"rm", "-f", t.file(name)})
+	if err != nil {
+		return err
+	}
+	defer t.removeContainer(id)
+	if err := t.host.DClient.ContainerStart(ctx, id, types.ContainerStartOptions{}); err != nil {
+		return err
+	}
+	statusCh, errCh := t.host.DClient.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return err
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("failed to remove snapshot [%s] from etcd node [%s], exit code %d", name, t.host.Address, status.StatusCode)
+		}
+	}
+	return nil
+}
+
+// createContainer creates a container mounting the snapshot directory of the
+// node, running cmd once started. Files are copied from and to the container
+// without starting it.
+func (t *nodeTarget) createContainer(ctx context.Context, cmd []string) (string, error) {
+	if err := t.host.TunnelUp(ctx, t.dialerFactory, t.prefixPath, t.version); err != nil {
+		return "", fmt.Errorf("failed to connect to etcd node [%s]: %v", t.host.Address, err)
+	}
+	if err := docker.UseLocalOrPull(ctx, t.host.DClient, t.host.Address, t.image, services.ETCDRole, nil); err != nil {
+		return "", err
+	}
+	resp, err := t.host.DClient.ContainerCreate(ctx,
+		&container.Config{
+			Image: t.image,
+			Cmd:   cmd,
+		},
+		&container.HostConfig{
+			Binds: []string{fmt.Sprintf("%s:%s:z", services.EtcdSnapshotPath, nodeSnapshotDir)},
+		}, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot container on etcd node [%s]: %v", t.host.Address, err)
+	}
+	return resp.ID, nil
+}
+
+func (t *nodeTarget) removeContainer(id string) {
+	t.host.DClient.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true})
+}
+
+func (t *nodeTarget) file(name string) string {
+	return path.Join(nodeSnapshotDir, path.Base(name))
+}
+
+func (t *nodeTarget) wrapError(name string, err error) error {
+	if client.IsErrNotFound(err) {
+		return &NotFoundError{Name: name}
+	}
+	return err
+}
+
+type nodeFile struct {
+	io.Reader
+	close func() error
+}
+
+func (f *nodeFile) Close() error {
+	return f.close()
+}
@@ -0,0 +1,175 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	minio "github.com/minio/minio-go"
+	"github.com/minio/minio-go/pkg/credentials"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	rketypes "github.com/rancher/rke/types"
+)
+
+const (
+	s3Endpoint       = "s3.amazonaws.com"
+	s3MetadataPrefix = "X-Amz-Meta-"
+)
+
+// NewS3Client returns a client for the S3 backup target, dialing through
+// dialer.
+func NewS3Client(sbc *rketypes.S3BackupConfig, dialer dialer.Dialer) (*minio.Client, error) {
+	if sbc == nil {
+		return nil, fmt.Errorf("Can't find S3 backup target configuration")
+	}
+	var s3Client = &minio.Client{}
+	var creds *credentials.Credentials
+	var tr http.RoundTripper = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	endpoint := sbc.Endpoint
+	// no access credentials, we assume IAM roles
+	if sbc.AccessKey == "" ||
+		sbc.SecretKey == "" {
+		creds = credentials.NewIAM("")
+		if sbc.Endpoint == "" {
+			endpoint = s3Endpoint
+		}
+	} else {
+		accessKey := sbc.AccessKey
+		secretKey := sbc.SecretKey
+		creds = credentials.NewStatic(accessKey, secretKey, "", credentials.SignatureDefault)
+	}
+
+	bucketLookup := getBucketLookupType(endpoint)
+	s3Client, err := minio.NewWithOptions(endpoint, &minio.Options{
+		Creds:        creds,
+		Region:       sbc.Region,
+		Secure:       true,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sbc.CustomCA != "" {
+		tr = getCustomCATransport(tr, sbc.CustomCA)
+	}
+	s3Client.SetCustomTransport(tr)
+	return s3Client, nil
+}
+
+type s3Target struct {
+	client *minio.Client
+	bucket string
+	folder string
+}
+
+// NewS3Target returns the S3 backup target, dialing through dialer.
+func NewS3Target(sbc *rketypes.S3BackupConfig, dialer dialer.Dialer) (Target, error) {
+	client, err := NewS3Client(sbc, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Target{
+		client: client,
+		bucket: sbc.BucketName,
+		folder: sbc.Folder,
+	}, nil
+}
+
+func (t *s3Target) Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error) {
+	obj, err := t.client.GetObjectWithContext(ctx, t.bucket, joinFolder(t.folder, name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, t.wrapError(name, err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, nil, t.wrapError(name, err)
+	}
+	return obj, t.fileInfo(name, info), nil
+}
+
+func (t *s3Target) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	info, err := t.client.StatObject(t.bucket, joinFolder(t.folder, name), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, t.wrapError(name, err)
+	}
+	return t.fileInfo(name, info), nil
+}
+
+func (t *s3Target) Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error {
+	_, err := t.client.PutObjectWithContext(ctx, t.bucket, joinFolder(t.folder, name), src, size, minio.PutObjectOptions{
+		UserMetadata: metadata,
+	})
+	return err
+}
+
+func (t *s3Target) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	var result []FileInfo
+	for info := range t.client.ListObjectsV2(t.bucket, joinFolder(t.folder, prefix), false, doneCh) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		name := strings.TrimPrefix(info.Key, joinFolder(t.folder, ""))
+		result = append(result, *t.fileInfo(name, info))
+	}
+	return result, nil
+}
+
+func (t *s3Target) Delete(ctx context.Context, name string) error {
+	return t.client.RemoveObject(t.bucket, joinFolder(t.folder, name))
+}
+
+func (t *s3Target) fileInfo(name string, info minio.ObjectInfo) *FileInfo {
+	metadata := map[string]string{}
+	for k, v := range info.Metadata {
+		if strings.HasPrefix(k, s3MetadataPrefix) && len(v) > 0 {
+			metadata[strings.ToLower(strings.TrimPrefix(k, s3MetadataPrefix))] = v[0]
+		}
+	}
+	return &FileInfo{
+		Name:     name,
+		Size:     info.Size,
+		Metadata: metadata,
+	}
+}
+
+func (t *s3Target) wrapError(name string, err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return &NotFoundError{Name: name}
+	}
+	return err
+}
+
+func getBucketLookupType(endpoint string) minio.BucketLookupType {
+	if endpoint == "" {
+		return minio.BucketLookupAuto
+	}
+	if strings.Contains(endpoint, "aliyun") {
+		return minio.BucketLookupDNS
+	}
+	return minio.BucketLookupAuto
+}
+
+func getCustomCATransport(tr http.RoundTripper, ca string) http.RoundTripper {
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM([]byte(ca))
+	tr.(*http.Transport).TLSClientConfig = &tls.Config{
+		RootCAs: certPool,
+	}
+	return tr
+}
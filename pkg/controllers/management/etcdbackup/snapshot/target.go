@@ -0,0 +1,361 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// ReplicasAnnotation lists the names of the targets the snapshot of an
+	// EtcdBackup has been copied to, comma separated.
+	ReplicasAnnotation = "etcdbackup.cattle.io/replicas"
+	// MetadataCluster is the metadata key naming the cluster of the snapshot
+	// files rancher stores on backup targets. Only files carrying it are ever
+	// removed from a target without a backup referring to them.
+	MetadataCluster = "rancher-snapshot-cluster"
+)
+
+// Target is a location snapshot files are stored in. Names are relative to
+// the folder of the target.
+type Target interface {
+	// Open returns the content of the file name.
+	Open(ctx context.Context, name string) (io.ReadCloser, *FileInfo, error)
+	// Stat returns the size and metadata of the file name.
+	Stat(ctx context.Context, name string) (*FileInfo, error)
+	// Upload stores size bytes of src as the file name.
+	Upload(ctx context.Context, name string, src io.Reader, size int64, metadata map[string]string) error
+	// List returns the files whose name starts with prefix.
+	List(ctx context.Context, prefix string) ([]FileInfo, error)
+	// Delete removes the file name. Removing a missing file is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// FileInfo describes a snapshot file. Metadata keys are lower case.
+type FileInfo struct {
+	Name     string
+	Size     int64
+	Metadata map[string]string
+}
+
+// NotFoundError is returned by targets for missing files.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("snapshot file [%s] not found", e.Name)
+}
+
+// IsNotFound reports whether err is a NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// NewTarget returns the target configured by config.
+func NewTarget(ctx context.Context, config *v32.EtcdBackupTarget) (Target, error) {
+	switch {
+	case config.AzureBlobConfig != nil:
+		return NewAzureBlobTarget(config.AzureBlobConfig)
+	case config.GCSConfig != nil:
+		return NewGCSTarget(ctx, config.GCSConfig)
+	case config.FilesystemConfig != nil:
+		return NewFilesystemTarget(config.FilesystemConfig)
+	}
+	return nil, fmt.Errorf("backup target [%s] is not configured", config.Name)
+}
+
+// GetReplicas returns the names of the targets listed by value, an
+// annotation set to ReplicasAnnotation.
+func GetReplicas(value string) []string {
+	var replicas []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			replicas = append(replicas, name)
+		}
+	}
+	return replicas
+}
+
+// FindTarget returns the target of targets called name, or nil.
+func FindTarget(targets []v32.EtcdBackupTarget, name string) *v32.EtcdBackupTarget {
+	for i := range targets {
+		if targets[i].Name == name {
+			return &targets[i]
+		}
+	}
+	return nil
+}
+
+// ValidateTargets checks that every target has a unique name and exactly one
+// configuration, and that primary, if set, is one of them.
+func ValidateTargets(targets []v32.EtcdBackupTarget, primary string) error {
+	names := map[string]bool{}
+	for _, target := range targets {
+		if target.Name == "" {
+			return fmt.Errorf("backup target name is required")
+		}
+		if names[target.Name] {
+			return fmt.Errorf("duplicate backup target [%s]", target.Name)
+		}
+		names[target.Name] = true
+
+		configured := 0
+		for _, set := range []bool{target.AzureBlobConfig != nil, target.GCSConfig != nil, target.FilesystemConfig != nil} {
+			if set {
+				configured++
+			}
+		}
+		if configured != 1 {
+			return fmt.Errorf("backup target [%s] must have exactly one of azureBlobConfig, gcsConfig and filesystemConfig", target.Name)
+		}
+	}
+	if primary != "" && !names[primary] {
+		return fmt.Errorf("primary backup target [%s] is not one of the backup targets", primary)
+	}
+	return nil
+}
+
+// Download writes the snapshot stored as name to dst, decrypting it with
+// keyring if it is encrypted. It returns the encryption metadata of the file,
+// or nil if the file is not encrypted.
+func Download(ctx context.Context, t Target, name string, keyring *Keyring, dst io.Writer) (*EncryptionMetadata, error) {
+	rc, info, err := t.Open(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download snapshot [%s]: %v", name, err)
+	}
+	defer rc.Close()
+
+	meta, err := EncryptionMetadataFrom(info.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &countingReader{r: rc}
+	if meta == nil {
+		_, err = io.Copy(dst, src)
+	} else if keyring == nil {
+		return nil, fmt.Errorf("snapshot [%s] is encrypted but no encryption keys are configured", name)
+	} else {
+		err = keyring.Decrypt(dst, src, meta)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download snapshot [%s]: %v", name, err)
+	}
+	if src.n != info.Size {
+		return nil, fmt.Errorf("snapshot [%s] is %d bytes, expected %d", name, src.n, info.Size)
+	}
+	return meta, nil
+}
+
+// Encrypt replaces the snapshot stored as name by a copy encrypted with the
+// active key of keyring. Snapshots that are already encrypted are left as
+// they are.
+func Encrypt(ctx context.Context, t Target, name string, keyring *Keyring) (*EncryptionMetadata, error) {
+	info, err := t.Stat(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat snapshot [%s]: %v", name, err)
+	}
+	if meta, err := EncryptionMetadataFrom(info.Metadata); err != nil || meta != nil {
+		return meta, err
+	}
+
+	plain, err := newTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempFile(plain)
+	if _, err := Download(ctx, t, name, nil, plain); err != nil {
+		return nil, err
+	}
+	if _, err := plain.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := newTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempFile(encrypted)
+	meta, err := keyring.Encrypt(encrypted, plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt snapshot [%s]: %v", name, err)
+	}
+	metadata, err := meta.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if err := uploadFile(ctx, t, name, encrypted, metadata); err != nil {
+		return nil, fmt.Errorf("failed to upload encrypted snapshot [%s]: %v", name, err)
+	}
+	return meta, nil
+}
+
+// Copy stores the snapshot file name of src on dst as it is, keeping its
+// metadata, so encrypted snapshots stay encrypted. metadata is added to the
+// metadata of the copy.
+func Copy(ctx context.Context, src, dst Target, name string, metadata map[string]string) error {
+	rc, info, err := src.Open(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to download snapshot [%s]: %v", name, err)
+	}
+	defer rc.Close()
+
+	tmp, err := newTempFile()
+	if err != nil {
+		return err
+	}
+	defer removeTempFile(tmp)
+	n, err := io.Copy(tmp, rc)
+	if err != nil {
+		return fmt.Errorf("failed to download snapshot [%s]: %v", name, err)
+	}
+	if n != info.Size {
+		return fmt.Errorf("snapshot [%s] is %d bytes, expected %d", name, n, info.Size)
+	}
+	if err := uploadFile(ctx, dst, name, tmp, mergeMetadata(info.Metadata, metadata)); err != nil {
+		return fmt.Errorf("failed to upload snapshot [%s]: %v", name, err)
+	}
+	return nil
+}
+
+// CopyFrom stores the snapshot file name of the first of srcs holding it on
+// dst, see Copy.
+func CopyFrom(ctx context.Context, srcs []Target, dst Target, name string, metadata map[string]string) error {
+	var errs []error
+	for _, src := range srcs {
+		err := Copy(ctx, src, dst, name, metadata)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", src, err))
+	}
+	return fmt.Errorf("failed to copy snapshot [%s]: %v", name, utilerrors.NewAggregate(errs))
+}
+
+// EncryptFrom stores a copy of the snapshot file name, encrypted with the
+// active key of keyring, as name on dst. The snapshot is read from the first
+// of srcs holding it and encrypted as it is read, so the plain snapshot is
+// never written to dst nor to a local file. metadata is added to the metadata
+// of the encrypted copy.
+func EncryptFrom(ctx context.Context, srcs []Target, name string, keyring *Keyring, dst Target, metadata map[string]string) (*EncryptionMetadata, error) {
+	var errs []error
+	for _, src := range srcs {
+		meta, err := encryptFrom(ctx, src, name, keyring, dst, metadata)
+		if err == nil {
+			return meta, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", src, err))
+	}
+	return nil, fmt.Errorf("failed to encrypt snapshot [%s]: %v", name, utilerrors.NewAggregate(errs))
+}
+
+func encryptFrom(ctx context.Context, src Target, name string, keyring *Keyring, dst Target, extra map[string]string) (*EncryptionMetadata, error) {
+	rc, info, err := src.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	encrypted, err := newTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempFile(encrypted)
+	plain := &countingReader{r: rc}
+	meta, err := keyring.Encrypt(encrypted, plain)
+	if err != nil {
+		return nil, err
+	}
+	if plain.n != info.Size {
+		return nil, fmt.Errorf("snapshot [%s] is %d bytes, expected %d", name, plain.n, info.Size)
+	}
+	metadata, err := meta.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if err := uploadFile(ctx, dst, name, encrypted, mergeMetadata(metadata, extra)); err != nil {
+		return nil, fmt.Errorf("failed to upload encrypted snapshot [%s]: %v", name, err)
+	}
+	return meta, nil
+}
+
+func mergeMetadata(metadata, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return metadata
+	}
+	result := map[string]string{}
+	for k, v := range metadata {
+		result[k] = v
+	}
+	for k, v := range extra {
+		result[k] = v
+	}
+	return result
+}
+
+// StageDecrypted stores a decrypted copy of the snapshot file name of src as
+// dstName on each of dsts, for tools that can neither decrypt snapshots nor
+// read from src. dsts must be private to the cluster, such as its etcd nodes,
+// and the copies should be removed as soon as they are no longer needed.
+func StageDecrypted(ctx context.Context, src Target, name string, keyring *Keyring, dsts []Target, dstName string) error {
+	plain, err := newTempFile()
+	if err != nil {
+		return err
+	}
+	defer removeTempFile(plain)
+	if _, err := Download(ctx, src, name, keyring, plain); err != nil {
+		return err
+	}
+	for _, dst := range dsts {
+		if err := uploadFile(ctx, dst, dstName, plain, nil); err != nil {
+			return fmt.Errorf("failed to upload snapshot [%s] to %s: %v", dstName, dst, err)
+		}
+	}
+	return nil
+}
+
+func uploadFile(ctx context.Context, t Target, name string, f *os.File, metadata map[string]string) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return t.Upload(ctx, name, f, size, metadata)
+}
+
+func newTempFile() (*os.File, error) {
+	return ioutil.TempFile("", "etcd-snapshot-")
+}
+
+func removeTempFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+func joinFolder(folder, name string) string {
+	if folder == "" {
+		return name
+	}
+	return fmt.Sprintf("%s/%s", folder, name)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,191 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFilesystemTarget(t *testing.T) (Target, func()) {
+	dir, err := ioutil.TempDir("", "etcd-snapshot-target-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := NewFilesystemTarget(&v32.FilesystemBackupConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target, func() { os.RemoveAll(dir) }
+}
+
+// testTarget checks the behavior every target shares.
+func testTarget(t *testing.T, target Target) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	_, err := target.Stat(ctx, "c-abcde-rs-1.zip")
+	assert.True(IsNotFound(err))
+
+	assert.Nil(target.Upload(ctx, "c-abcde-rs-1.zip", strings.NewReader("one"), 3, map[string]string{"key": "value"}))
+	assert.Nil(target.Upload(ctx, "c-abcde-rs-2.zip", strings.NewReader("two"), 3, nil))
+	assert.Nil(target.Upload(ctx, "c-fghij-rs-1.zip", strings.NewReader("three"), 5, nil))
+
+	rc, info, err := target.Open(ctx, "c-abcde-rs-1.zip")
+	assert.Nil(err)
+	content, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	assert.Equal("one", string(content))
+	assert.Equal(&FileInfo{Name: "c-abcde-rs-1.zip", Size: 3, Metadata: map[string]string{"key": "value"}}, info)
+
+	files, err := target.List(ctx, "c-abcde-")
+	assert.Nil(err)
+	assert.Len(files, 2, "metadata files are not listed")
+
+	assert.Nil(target.Delete(ctx, "c-abcde-rs-1.zip"))
+	assert.Nil(target.Delete(ctx, "c-abcde-rs-1.zip"), "deleting a missing file is not an error")
+	files, err = target.List(ctx, "c-abcde-")
+	assert.Nil(err)
+	assert.Equal([]FileInfo{{Name: "c-abcde-rs-2.zip", Size: 3, Metadata: map[string]string{}}}, files)
+}
+
+func TestFilesystemTarget(t *testing.T) {
+	assert := assert.New(t)
+	target, cleanup := newTestFilesystemTarget(t)
+	defer cleanup()
+	testTarget(t, target)
+
+	_, err := NewFilesystemTarget(&v32.FilesystemBackupConfig{Path: "relative"})
+	assert.Error(err)
+}
+
+func TestEncryptCopyDownload(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	src, cleanupSrc := newTestFilesystemTarget(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestFilesystemTarget(t)
+	defer cleanupDst()
+	keyring, err := NewKeyring(newTestSecret(map[string][]byte{"k1": newTestKey(t)}), "")
+	assert.Nil(err)
+
+	plaintext := bytes.Repeat([]byte("snapshot"), chunkSize)
+	assert.Nil(src.Upload(ctx, "s.zip", bytes.NewReader(plaintext), int64(len(plaintext)), nil))
+
+	meta, err := Encrypt(ctx, src, "s.zip", keyring)
+	assert.Nil(err)
+	again, err := Encrypt(ctx, src, "s.zip", keyring)
+	assert.Nil(err)
+	assert.Equal(meta, again, "encrypted snapshots are not encrypted twice")
+
+	_, err = Download(ctx, src, "s.zip", nil, &bytes.Buffer{})
+	assert.EqualError(err, "snapshot [s.zip] is encrypted but no encryption keys are configured")
+
+	assert.Nil(Copy(ctx, src, dst, "s.zip", map[string]string{MetadataCluster: "c-abcde"}))
+	var decrypted bytes.Buffer
+	copied, err := Download(ctx, dst, "s.zip", keyring, &decrypted)
+	assert.Nil(err)
+	assert.Equal(meta, copied, "copies stay encrypted")
+	info, err := dst.Stat(ctx, "s.zip")
+	assert.Nil(err)
+	assert.Equal("c-abcde", info.Metadata[MetadataCluster])
+	assert.True(bytes.Equal(plaintext, decrypted.Bytes()))
+
+	node1, cleanupNode1 := newTestFilesystemTarget(t)
+	defer cleanupNode1()
+	node2, cleanupNode2 := newTestFilesystemTarget(t)
+	defer cleanupNode2()
+	assert.Nil(StageDecrypted(ctx, dst, "s.zip", keyring, []Target{node1, node2}, "s-restore.zip"))
+	for _, node := range []Target{node1, node2} {
+		var staged bytes.Buffer
+		stagedMeta, err := Download(ctx, node, "s-restore.zip", nil, &staged)
+		assert.Nil(err)
+		assert.Nil(stagedMeta)
+		assert.True(bytes.Equal(plaintext, staged.Bytes()))
+	}
+	_, err = dst.Stat(ctx, "s-restore.zip")
+	assert.True(IsNotFound(err), "plain snapshots are not staged on the source")
+}
+
+func TestEncryptFrom(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	empty, cleanupEmpty := newTestFilesystemTarget(t)
+	defer cleanupEmpty()
+	node, cleanupNode := newTestFilesystemTarget(t)
+	defer cleanupNode()
+	dst, cleanupDst := newTestFilesystemTarget(t)
+	defer cleanupDst()
+	keyring, err := NewKeyring(newTestSecret(map[string][]byte{"k1": newTestKey(t)}), "")
+	assert.Nil(err)
+
+	plaintext := bytes.Repeat([]byte("snapshot"), chunkSize)
+	assert.Nil(node.Upload(ctx, "s.zip", bytes.NewReader(plaintext), int64(len(plaintext)), nil))
+
+	meta, err := EncryptFrom(ctx, []Target{empty, node}, "s.zip", keyring, dst, map[string]string{MetadataCluster: "c-abcde"})
+	assert.Nil(err)
+	rc, info, err := dst.Open(ctx, "s.zip")
+	assert.Nil(err)
+	stored, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	assert.False(bytes.Contains(stored, []byte("snapshot")), "only the encrypted snapshot is uploaded")
+	storedMeta, err := EncryptionMetadataFrom(info.Metadata)
+	assert.Nil(err)
+	assert.Equal(meta, storedMeta)
+	assert.Equal("c-abcde", info.Metadata[MetadataCluster])
+	var decrypted bytes.Buffer
+	downloaded, err := Download(ctx, dst, "s.zip", keyring, &decrypted)
+	assert.Nil(err)
+	assert.Equal(meta, downloaded)
+	assert.True(bytes.Equal(plaintext, decrypted.Bytes()))
+
+	_, err = EncryptFrom(ctx, []Target{empty}, "missing.zip", keyring, dst, nil)
+	assert.Error(err)
+}
+
+func TestWithoutS3Upload(t *testing.T) {
+	assert := assert.New(t)
+	sbc := &rketypes.S3BackupConfig{BucketName: "backups"}
+	spec := v32.ClusterSpec{
+		ClusterSpecBase: v32.ClusterSpecBase{
+			RancherKubernetesEngineConfig: &rketypes.RancherKubernetesEngineConfig{
+				Services: rketypes.RKEConfigServices{
+					Etcd: rketypes.ETCDService{BackupConfig: &rketypes.BackupConfig{S3BackupConfig: sbc}},
+				},
+			},
+		},
+	}
+	assert.Equal(sbc, WithoutS3Upload(spec).RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.S3BackupConfig)
+
+	spec.EtcdBackupConfig = &v32.EtcdBackupConfig{Encryption: &v32.EtcdBackupEncryption{SecretName: "cattle-global-data:keys"}}
+	assert.True(IsEncryptionEnabled(&spec))
+	assert.Nil(WithoutS3Upload(spec).RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.S3BackupConfig)
+	assert.Equal(sbc, spec.RancherKubernetesEngineConfig.Services.Etcd.BackupConfig.S3BackupConfig, "spec is not modified")
+}
+
+func TestValidateTargets(t *testing.T) {
+	assert := assert.New(t)
+	fs := &v32.FilesystemBackupConfig{Path: "/backups"}
+
+	targets := []v32.EtcdBackupTarget{{Name: "nfs", FilesystemConfig: fs}, {Name: "gcs", GCSConfig: &v32.GCSBackupConfig{Bucket: "b"}}}
+	assert.Nil(ValidateTargets(targets, ""))
+	assert.Nil(ValidateTargets(targets, "gcs"))
+	assert.Equal(&targets[1], FindTarget(targets, "gcs"))
+	assert.EqualError(ValidateTargets(targets, "azure"), "primary backup target [azure] is not one of the backup targets")
+	assert.EqualError(ValidateTargets([]v32.EtcdBackupTarget{{Name: "nfs", FilesystemConfig: fs}, {Name: "nfs", FilesystemConfig: fs}}, ""),
+		"duplicate backup target [nfs]")
+	assert.EqualError(ValidateTargets([]v32.EtcdBackupTarget{{Name: "none"}}, ""),
+		"backup target [none] must have exactly one of azureBlobConfig, gcsConfig and filesystemConfig")
+	assert.EqualError(ValidateTargets([]v32.EtcdBackupTarget{{FilesystemConfig: fs}}, ""), "backup target name is required")
+}
+
+func TestGetReplicas(t *testing.T) {
+	assert.Equal(t, []string{"azure", "nfs"}, GetReplicas("azure, nfs,"))
+	assert.Nil(t, GetReplicas(""))
+}
@@ -0,0 +1,421 @@
+package etcdbackup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/rancher/norman/condition"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// BackupConditionVerified is true once a recurring backup has been downloaded
+// and its etcd snapshot checked for integrity.
+const BackupConditionVerified condition.Cond = "Verified"
+
+const (
+	defaultVerificationTimeout = 300
+	verificationRetryInterval  = time.Minute
+	maxVerificationAttempts    = 5
+	verificationRetryingReason = "Retrying"
+	// etcd appends the sha256 of the database to the snapshots it streams
+	snapshotHashSize = sha256.Size
+	// enough of the database head to hold both bolt meta pages
+	snapshotHeadSize = 64 * 1024
+
+	boltMagic        = 0xED0CDAED
+	boltVersion      = 2
+	boltPageHeader   = 16
+	boltMetaSize     = 64
+	boltMinPageSize  = 512
+	boltChecksumSize = 8
+)
+
+type snapshotStatus struct {
+	// Size and SHA256 describe the downloaded archive
+	Size   int64
+	SHA256 string
+	// DBSize, PageSize and TxID describe the etcd database in the archive
+	DBSize   int64
+	PageSize uint32
+	TxID     uint64
+}
+
+func (s *snapshotStatus) String() string {
+	return fmt.Sprintf("size %d bytes, sha256 %s, database size %d bytes, page size %d, txid %d",
+		s.Size, s.SHA256, s.DBSize, s.PageSize, s.TxID)
+}
+
+func isVerificationEnabled(cluster *v3.Cluster) bool {
+	return cluster.Spec.EtcdBackupConfig != nil &&
+		cluster.Spec.EtcdBackupConfig.Verification != nil &&
+		cluster.Spec.EtcdBackupConfig.Verification.Enabled
+}
+
+func getVerificationTimeout(cluster *v3.Cluster) time.Duration {
+	timeout := cluster.Spec.EtcdBackupConfig.Verification.Timeout
+	if timeout <= 0 {
+		timeout = defaultVerificationTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// shouldVerify reports whether b is a completed recurring backup of a cluster
+// with verification enabled that has not been verified yet, or whose last
+// verification attempt failed to download the snapshot. Snapshots to be
+// encrypted or uploaded are verified once they are, so the check covers
+// decryption too.
+func shouldVerify(cluster *v3.Cluster, b *v3.EtcdBackup) bool {
+	status := BackupConditionVerified.GetStatus(b)
+	retrying := status == "Unknown" && BackupConditionVerified.GetReason(b) == verificationRetryingReason
+	return !b.Spec.Manual &&
+		rketypes.BackupConditionCompleted.IsTrue(b) &&
+		(status == "" || retrying) &&
+		isVerificationEnabled(cluster) &&
+		!shouldEncrypt(cluster, b) &&
+		!shouldUpload(cluster, b)
+}
+
+// verifyBackup starts the verification of the snapshot of b in the
+// background, as downloading it can take up to the verification timeout.
+// The result is recorded on the Verified condition of b and on the cluster.
+func (c *Controller) verifyBackup(cluster *v3.Cluster, b *v3.EtcdBackup) (*v3.EtcdBackup, error) {
+	if !isStoredRemotely(b) {
+		BackupConditionVerified.Unknown(b)
+		BackupConditionVerified.Reason(b, "Unsupported")
+		BackupConditionVerified.Message(b, "only snapshots stored on S3 or on a backup target can be verified")
+		return b, nil
+	}
+
+	// the backup is updated when a failed attempt is recorded, the next one
+	// is enqueued once the retry interval has passed
+	if wait := verificationRetryWait(b, time.Now()); wait > 0 {
+		c.backupClient.Controller().EnqueueAfter(b.Namespace, b.Name, wait)
+		return b, nil
+	}
+
+	keyring, err := c.getKeyring(cluster)
+	if err != nil {
+		return b, err
+	}
+	target, err := c.getPrimaryTarget(cluster, b)
+	if err != nil {
+		return b, err
+	}
+
+	key := b.Namespace + "/" + b.Name
+	if _, running := c.verifying.LoadOrStore(key, true); running {
+		return b, nil
+	}
+	go func() {
+		defer c.verifying.Delete(key)
+		c.runVerification(cluster.Name, getVerificationTimeout(cluster), b.DeepCopy(), target, keyring)
+	}()
+	return b, nil
+}
+
+// verificationRetryWait returns how long to wait before retrying the
+// verification of b after a failed download.
+func verificationRetryWait(b *v3.EtcdBackup, now time.Time) time.Duration {
+	if BackupConditionVerified.GetReason(b) != verificationRetryingReason {
+		return 0
+	}
+	last, err := time.Parse(time.RFC3339, BackupConditionVerified.GetLastUpdated(b))
+	if err != nil {
+		return 0
+	}
+	return last.Add(verificationRetryInterval).Sub(now)
+}
+
+// runVerification downloads and checks the snapshot of b. Snapshots that fail
+// the integrity checks are marked as not verified. Failures to download them
+// are retried, up to maxVerificationAttempts, before being reported.
+func (c *Controller) runVerification(clusterName string, timeout time.Duration, b *v3.EtcdBackup, target snapshot.Target, keyring *snapshot.Keyring) {
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
+	defer cancel()
+
+	logrus.Infof("[etcd-backup] Verifying backup %s of cluster [%s]", b.Name, clusterName)
+	status, verifyErr := downloadAndVerifySnapshot(ctx, target, getSnapshotFilename(b), keyring)
+
+	key := b.Namespace + "/" + b.Name
+	retry := false
+	if verifyErr != nil && !isCorruptSnapshot(verifyErr) && c.ctx.Err() == nil {
+		attempts := 1
+		if n, ok := c.verifyAttempts.Load(key); ok {
+			attempts = n.(int) + 1
+		}
+		if retry = attempts < maxVerificationAttempts; retry {
+			c.verifyAttempts.Store(key, attempts)
+		}
+	}
+	if !retry {
+		c.verifyAttempts.Delete(key)
+	}
+
+	err := c.setVerifiedCondition(b, func(b *v3.EtcdBackup) {
+		switch {
+		case retry:
+			logrus.Warnf("[etcd-backup] Failed to verify backup %s, retrying in %v: %v", b.Name, verificationRetryInterval, verifyErr)
+			BackupConditionVerified.Unknown(b)
+			BackupConditionVerified.Reason(b, verificationRetryingReason)
+			BackupConditionVerified.Message(b, verifyErr.Error())
+			BackupConditionVerified.LastUpdated(b, time.Now().UTC().Format(time.RFC3339))
+		case verifyErr != nil:
+			logrus.Warnf("[etcd-backup] Verification of backup %s failed: %v", b.Name, verifyErr)
+			BackupConditionVerified.False(b)
+			BackupConditionVerified.ReasonAndMessageFromError(b, verifyErr)
+		default:
+			BackupConditionVerified.True(b)
+			BackupConditionVerified.Reason(b, "")
+			BackupConditionVerified.Message(b, status.String())
+		}
+	})
+	if err != nil {
+		logrus.Errorf("[etcd-backup] Failed to record the verification of backup %s: %v", b.Name, err)
+		return
+	}
+	if retry {
+		c.backupClient.Controller().EnqueueAfter(b.Namespace, b.Name, verificationRetryInterval)
+		return
+	}
+	if err := c.setClusterVerifiedCondition(clusterName, b.Name, verifyErr); err != nil {
+		logrus.Errorf("[etcd-backup] Failed to record the verification of backup %s on cluster [%s]: %v", b.Name, clusterName, err)
+	}
+}
+
+func (c *Controller) setVerifiedCondition(b *v3.EtcdBackup, set func(*v3.EtcdBackup)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.backupClient.GetNamespaced(b.Namespace, b.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		updated := latest.DeepCopy()
+		set(updated)
+		_, err = c.backupClient.Update(updated)
+		return err
+	})
+}
+
+func (c *Controller) setClusterVerifiedCondition(clusterName, backupName string, verifyErr error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := c.clusterClient.Get(clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		updated := cluster.DeepCopy()
+		if verifyErr != nil {
+			v32.ClusterConditionEtcdBackupVerified.False(updated)
+			v32.ClusterConditionEtcdBackupVerified.Reason(updated, "VerificationFailed")
+			v32.ClusterConditionEtcdBackupVerified.Message(updated, fmt.Sprintf("backup %s: %v", backupName, verifyErr))
+		} else {
+			v32.ClusterConditionEtcdBackupVerified.True(updated)
+			v32.ClusterConditionEtcdBackupVerified.Reason(updated, "")
+			v32.ClusterConditionEtcdBackupVerified.Message(updated, fmt.Sprintf("backup %s verified", backupName))
+		}
+		_, err = c.clusterClient.Update(updated)
+		return err
+	})
+}
+
+func downloadAndVerifySnapshot(ctx context.Context, target snapshot.Target, name string, keyring *snapshot.Keyring) (*snapshotStatus, error) {
+	tmp, err := ioutil.TempFile("", "etcd-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	w := &countingWriter{w: io.MultiWriter(tmp, h)}
+	if _, err := snapshot.Download(ctx, target, name, keyring, w); err != nil {
+		return nil, err
+	}
+
+	status, err := verifySnapshotArchive(tmp, w.n)
+	if err != nil {
+		return nil, &corruptSnapshotError{fmt.Errorf("snapshot [%s] is corrupt: %v", name, err)}
+	}
+	status.SHA256 = fmt.Sprintf("%x", h.Sum(nil))
+	return status, nil
+}
+
+// corruptSnapshotError is returned for snapshots that were downloaded but
+// failed the integrity checks, as opposed to snapshots that could not be read.
+type corruptSnapshotError struct {
+	error
+}
+
+func isCorruptSnapshot(err error) bool {
+	_, ok := err.(*corruptSnapshotError)
+	return ok
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// verifySnapshotArchive checks the etcd snapshot held by the zip archive r.
+func verifySnapshotArchive(r io.ReaderAt, size int64) (*snapshotStatus, error) {
+	if size == 0 {
+		return nil, fmt.Errorf("archive is empty")
+	}
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot *zip.File
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if snapshot != nil {
+			return nil, fmt.Errorf("archive holds more than one file")
+		}
+		snapshot = f
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("archive holds no snapshot")
+	}
+
+	rc, err := snapshot.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	status, err := verifySnapshot(rc)
+	if err != nil {
+		return nil, err
+	}
+	status.Size = size
+	return status, nil
+}
+
+// verifySnapshot checks the integrity hash etcd appends to its snapshots and
+// the bolt meta pages of the database, like etcdctl snapshot status does.
+func verifySnapshot(r io.Reader) (*snapshotStatus, error) {
+	w := &snapshotWriter{hash: sha256.New()}
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+	dbSize := w.size - snapshotHashSize
+	if dbSize < 2*boltMinPageSize {
+		return nil, fmt.Errorf("snapshot is too small: %d bytes", w.size)
+	}
+	if !bytes.Equal(w.hash.Sum(nil), w.tail) {
+		return nil, fmt.Errorf("snapshot hash mismatch")
+	}
+
+	meta, err := readBoltMeta(w.head)
+	if err != nil {
+		return nil, err
+	}
+	if dbSize%int64(meta.pageSize) != 0 {
+		return nil, fmt.Errorf("database size %d is not a multiple of the page size %d", dbSize, meta.pageSize)
+	}
+	if dbSize < int64(meta.pgid)*int64(meta.pageSize) {
+		return nil, fmt.Errorf("database is truncated: %d bytes, expected at least %d", dbSize, int64(meta.pgid)*int64(meta.pageSize))
+	}
+	return &snapshotStatus{
+		DBSize:   dbSize,
+		PageSize: meta.pageSize,
+		TxID:     meta.txid,
+	}, nil
+}
+
+// snapshotWriter hashes all but the trailing hash of a snapshot, keeping the
+// head of the database for inspection.
+type snapshotWriter struct {
+	hash hash.Hash
+	head []byte
+	tail []byte
+	size int64
+}
+
+func (w *snapshotWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	if n := snapshotHeadSize - len(w.head); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.head = append(w.head, p[:n]...)
+	}
+	buf := append(w.tail, p...)
+	if len(buf) > snapshotHashSize {
+		w.hash.Write(buf[:len(buf)-snapshotHashSize])
+		buf = buf[len(buf)-snapshotHashSize:]
+	}
+	w.tail = append([]byte(nil), buf...)
+	return len(p), nil
+}
+
+type boltMeta struct {
+	pageSize uint32
+	pgid     uint64
+	txid     uint64
+}
+
+// readBoltMeta returns the newest valid of the two meta pages at the start of
+// a bolt database.
+func readBoltMeta(head []byte) (*boltMeta, error) {
+	first, err := parseBoltMeta(head)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database: %v", err)
+	}
+	if int(first.pageSize) >= len(head) {
+		return first, nil
+	}
+	if second, err := parseBoltMeta(head[first.pageSize:]); err == nil && second.txid > first.txid {
+		return second, nil
+	}
+	return first, nil
+}
+
+func parseBoltMeta(page []byte) (*boltMeta, error) {
+	if len(page) < boltPageHeader+boltMetaSize {
+		return nil, fmt.Errorf("meta page is truncated")
+	}
+	meta := page[boltPageHeader : boltPageHeader+boltMetaSize]
+	if magic := binary.LittleEndian.Uint32(meta[0:4]); magic != boltMagic {
+		return nil, fmt.Errorf("bad magic %#x", magic)
+	}
+	if version := binary.LittleEndian.Uint32(meta[4:8]); version != boltVersion {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+	h := fnv.New64a()
+	h.Write(meta[:boltMetaSize-boltChecksumSize])
+	if h.Sum64() != binary.LittleEndian.Uint64(meta[boltMetaSize-boltChecksumSize:]) {
+		return nil, fmt.Errorf("meta page checksum mismatch")
+	}
+	pageSize := binary.LittleEndian.Uint32(meta[8:12])
+	if pageSize < boltMinPageSize {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	return &boltMeta{
+		pageSize: pageSize,
+		pgid:     binary.LittleEndian.Uint64(meta[40:48]),
+		txid:     binary.LittleEndian.Uint64(meta[48:56]),
+	}, nil
+}
@@ -0,0 +1,230 @@
+package etcdbackup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/etcdbackup/snapshot"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testPageSize = 4096
+
+func newTestDB(pages int, txid uint64) []byte {
+	db := make([]byte, pages*testPageSize)
+	for i := 0; i < 2; i++ {
+		meta := db[i*testPageSize+boltPageHeader:]
+		binary.LittleEndian.PutUint32(meta[0:4], boltMagic)
+		binary.LittleEndian.PutUint32(meta[4:8], boltVersion)
+		binary.LittleEndian.PutUint32(meta[8:12], testPageSize)
+		binary.LittleEndian.PutUint64(meta[40:48], uint64(pages))
+		binary.LittleEndian.PutUint64(meta[48:56], txid+uint64(i))
+		h := fnv.New64a()
+		h.Write(meta[:boltMetaSize-boltChecksumSize])
+		binary.LittleEndian.PutUint64(meta[boltMetaSize-boltChecksumSize:boltMetaSize], h.Sum64())
+	}
+	return db
+}
+
+func newTestSnapshot(db []byte) []byte {
+	sum := sha256.Sum256(db)
+	return append(append([]byte(nil), db...), sum[:]...)
+}
+
+func newTestArchive(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	status, err := verifySnapshot(bytes.NewReader(newTestSnapshot(newTestDB(4, 7))))
+	assert.Nil(err)
+	assert.Equal(int64(4*testPageSize), status.DBSize)
+	assert.Equal(uint32(testPageSize), status.PageSize)
+	assert.Equal(uint64(8), status.TxID, "newest meta page wins")
+
+	corrupt := newTestSnapshot(newTestDB(4, 7))
+	corrupt[3*testPageSize] ^= 0xff
+	_, err = verifySnapshot(bytes.NewReader(corrupt))
+	assert.EqualError(err, "snapshot hash mismatch")
+
+	badMagic := newTestDB(4, 7)
+	badMagic[boltPageHeader] = 0
+	_, err = verifySnapshot(bytes.NewReader(newTestSnapshot(badMagic)))
+	assert.Error(err)
+
+	truncated := newTestDB(4, 7)[:3*testPageSize]
+	_, err = verifySnapshot(bytes.NewReader(newTestSnapshot(truncated)))
+	assert.EqualError(err, "database is truncated: 12288 bytes, expected at least 16384")
+
+	_, err = verifySnapshot(bytes.NewReader([]byte("not a snapshot")))
+	assert.Error(err)
+}
+
+func TestVerifySnapshotArchive(t *testing.T) {
+	assert := assert.New(t)
+
+	archive := newTestArchive(t, map[string][]byte{"backup/c-abcde-rs-xyz": newTestSnapshot(newTestDB(4, 1))})
+	status, err := verifySnapshotArchive(bytes.NewReader(archive), int64(len(archive)))
+	assert.Nil(err)
+	assert.Equal(int64(len(archive)), status.Size)
+
+	archive = newTestArchive(t, map[string][]byte{"a": {1}, "b": {2}})
+	_, err = verifySnapshotArchive(bytes.NewReader(archive), int64(len(archive)))
+	assert.EqualError(err, "archive holds more than one file")
+
+	_, err = verifySnapshotArchive(bytes.NewReader(nil), 0)
+	assert.EqualError(err, "archive is empty")
+}
+
+func TestShouldVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &v3.Cluster{}
+	cluster.Spec.EtcdBackupConfig = &v32.EtcdBackupConfig{
+		Verification: &v32.EtcdBackupVerification{Enabled: true},
+	}
+	backup := &v3.EtcdBackup{}
+	assert.False(shouldVerify(cluster, backup), "backup is not completed")
+
+	rketypes.BackupConditionCompleted.True(backup)
+	assert.True(shouldVerify(cluster, backup))
+
+	backup.Spec.Manual = true
+	assert.False(shouldVerify(cluster, backup), "manual backups are not verified")
+	backup.Spec.Manual = false
+
+	BackupConditionVerified.Unknown(backup)
+	BackupConditionVerified.Reason(backup, verificationRetryingReason)
+	assert.True(shouldVerify(cluster, backup), "failed downloads are retried")
+
+	BackupConditionVerified.True(backup)
+	BackupConditionVerified.Reason(backup, "")
+	assert.False(shouldVerify(cluster, backup), "backup is already verified")
+
+	assert.False(shouldVerify(&v3.Cluster{}, &v3.EtcdBackup{}))
+}
+
+func TestGetSnapshotFilename(t *testing.T) {
+	backup := &v3.EtcdBackup{}
+	backup.Name = "c-abcde-rs-xyz"
+	backup.Spec.Filename = "https://s3.example.com/bucket/folder/c-abcde-rs-xyz_2021-01-01T00:00:00Z.zip"
+	backup.Spec.BackupConfig.S3BackupConfig = &rketypes.S3BackupConfig{BucketName: "bucket", Folder: "folder"}
+	assert.Equal(t, "c-abcde-rs-xyz_2021-01-01T00:00:00Z.zip", getSnapshotFilename(backup))
+}
+
+func TestVerificationRetryWait(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC)
+
+	backup := &v3.EtcdBackup{}
+	assert.Equal(time.Duration(0), verificationRetryWait(backup, now))
+
+	BackupConditionVerified.Unknown(backup)
+	BackupConditionVerified.Reason(backup, verificationRetryingReason)
+	BackupConditionVerified.LastUpdated(backup, now.Add(-20*time.Second).Format(time.RFC3339))
+	assert.Equal(verificationRetryInterval-20*time.Second, verificationRetryWait(backup, now))
+	assert.True(verificationRetryWait(backup, now.Add(verificationRetryInterval)) <= 0)
+}
+
+func TestRunVerification(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "etcd-snapshot-verify-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target, err := snapshot.NewFilesystemTarget(&v32.FilesystemBackupConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := &v3.EtcdBackup{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde-rs-1", Namespace: "c-abcde"}}
+	stored.Spec.Filename = "c-abcde-rs-1"
+	var enqueued []time.Duration
+	backups := &fakes.EtcdBackupInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v3.EtcdBackup, error) {
+			return stored, nil
+		},
+		UpdateFunc: func(in *v3.EtcdBackup) (*v3.EtcdBackup, error) {
+			stored = in
+			return in, nil
+		},
+		ControllerFunc: func() v3.EtcdBackupController {
+			return &fakes.EtcdBackupControllerMock{
+				EnqueueAfterFunc: func(namespace string, name string, after time.Duration) {
+					enqueued = append(enqueued, after)
+				},
+			}
+		},
+	}
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	clusters := &fakes.ClusterInterfaceMock{
+		GetFunc: func(name string, opts metav1.GetOptions) (*v3.Cluster, error) {
+			return cluster, nil
+		},
+		UpdateFunc: func(in *v3.Cluster) (*v3.Cluster, error) {
+			cluster = in
+			return in, nil
+		},
+	}
+	c := &Controller{ctx: context.Background(), backupClient: backups, clusterClient: clusters}
+
+	// a snapshot that cannot be downloaded is retried
+	for i := 1; i < maxVerificationAttempts; i++ {
+		c.runVerification(cluster.Name, time.Minute, stored, target, nil)
+		assert.True(BackupConditionVerified.IsUnknown(stored))
+		assert.Equal(verificationRetryingReason, BackupConditionVerified.GetReason(stored))
+		assert.Len(enqueued, i)
+	}
+	assert.Empty(v32.ClusterConditionEtcdBackupVerified.GetStatus(cluster))
+
+	// until the attempts are exhausted
+	c.runVerification(cluster.Name, time.Minute, stored, target, nil)
+	assert.True(BackupConditionVerified.IsFalse(stored))
+	assert.True(v32.ClusterConditionEtcdBackupVerified.IsFalse(cluster))
+	assert.Len(enqueued, maxVerificationAttempts-1)
+
+	// a corrupt snapshot fails at once
+	archive := newTestArchive(t, map[string][]byte{"snapshot": []byte("not a snapshot")})
+	assert.Nil(target.Upload(context.Background(), getSnapshotFilename(stored), bytes.NewReader(archive), int64(len(archive)), nil))
+	stored.Status.Conditions = nil
+	c.runVerification(cluster.Name, time.Minute, stored, target, nil)
+	assert.True(BackupConditionVerified.IsFalse(stored))
+	assert.Len(enqueued, maxVerificationAttempts-1)
+
+	archive = newTestArchive(t, map[string][]byte{"snapshot": newTestSnapshot(newTestDB(4, 7))})
+	assert.Nil(target.Upload(context.Background(), getSnapshotFilename(stored), bytes.NewReader(archive), int64(len(archive)), nil))
+	c.runVerification(cluster.Name, time.Minute, stored, target, nil)
+	assert.True(BackupConditionVerified.IsTrue(stored))
+	assert.True(v32.ClusterConditionEtcdBackupVerified.IsTrue(cluster))
+}
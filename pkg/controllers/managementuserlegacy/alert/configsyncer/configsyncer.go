@@ -3,10 +3,11 @@
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
-	"net/url"
 	"sort"
-	"strconv"
+	"sync"
 	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -26,6 +27,7 @@
 	"github.com/rancher/rancher/pkg/project"
 	"github.com/rancher/rancher/pkg/ref"
 	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -40,8 +42,6 @@
 	eventGroupWait      = 1
 	eventRepeatInterval = 525600
 	webhookReceiverURL  = "http://webhook-receiver.cattle-prometheus.svc:9094/"
-	DingTalk            = "DINGTALK"
-	MicrosoftTeams      = "MICROSOFT_TEAMS"
 )
 
 type WebhookReceiverConfig struct {
@@ -62,6 +62,7 @@ type Receiver struct {
 
 func NewConfigSyncer(ctx context.Context, cluster *config.UserContext, alertManager *manager.AlertManager, operatorCRDManager *manager.PromOperatorCRDManager) *ConfigSyncer {
 	return &ConfigSyncer{
+		ctx:                     ctx,
 		apps:                    cluster.Management.Project.Apps(metav1.NamespaceAll),
 		appLister:               cluster.Management.Project.Apps(metav1.NamespaceAll).Controller().Lister(),
 		secretsGetter:           cluster.Core,
@@ -70,6 +71,9 @@ func NewConfigSyncer(ctx context.Context, cluster *config.UserContext, alertMana
 		clusterAlertRuleLister:  cluster.Management.Management.ClusterAlertRules(cluster.ClusterName).Controller().Lister(),
 		projectAlertRuleLister:  cluster.Management.Management.ProjectAlertRules("").Controller().Lister(),
 		notifierLister:          cluster.Management.Management.Notifiers(cluster.ClusterName).Controller().Lister(),
+		notifierController:      cluster.Management.Management.Notifiers(cluster.ClusterName).Controller(),
+		notifiers:               cluster.Management.Management.Notifiers(cluster.ClusterName),
+		dialerFactory:           cluster.Management.Dialer,
 		clusterLister:           cluster.Management.Management.Clusters(metav1.NamespaceAll).Controller().Lister(),
 		projectLister:           cluster.Management.Management.Projects(cluster.ClusterName).Controller().Lister(),
 		clusterName:             cluster.ClusterName,
@@ -79,6 +83,7 @@ func NewConfigSyncer(ctx context.Context, cluster *config.UserContext, alertMana
 }
 
 type ConfigSyncer struct {
+	ctx                     context.Context
 	apps                    projectv3.AppInterface
 	appLister               projectv3.AppLister
 	secretsGetter           v1.SecretsGetter
@@ -87,11 +92,17 @@ type ConfigSyncer struct {
 	projectAlertRuleLister  v3.ProjectAlertRuleLister
 	clusterAlertRuleLister  v3.ClusterAlertRuleLister
 	notifierLister          v3.NotifierLister
+	notifierController      v3.NotifierController
+	notifiers               v3.NotifierInterface
+	dialerFactory           dialer.Factory
 	clusterLister           v3.ClusterLister
 	projectLister           v3.ProjectLister
 	clusterName             string
 	alertManager            *manager.AlertManager
 	operatorCRDManager      *manager.PromOperatorCRDManager
+	// syncedNotifiers holds the hash of the config of each notifier when the
+	// alertmanager config was last synced for it
+	syncedNotifiers sync.Map
 }
 
 func (d *ConfigSyncer) ProjectGroupSync(key string, alert *v3.ProjectAlertGroup) (runtime.Object, error) {
@@ -110,8 +121,52 @@ func (d *ConfigSyncer) ClusterRuleSync(key string, alert *v3.ClusterAlertRule) (
 	return nil, d.sync()
 }
 
-func (d *ConfigSyncer) NotifierSync(key string, alert *v3.Notifier) (runtime.Object, error) {
-	return nil, d.sync()
+func (d *ConfigSyncer) NotifierSync(key string, notifier *v3.Notifier) (runtime.Object, error) {
+	if notifier == nil || notifier.DeletionTimestamp != nil {
+		d.syncedNotifiers.Delete(key)
+		return nil, d.sync()
+	}
+
+	// status updates, such as the policy state, leave the alertmanager config unchanged
+	now := time.Now()
+	hash, err := notifierConfigHash(notifier, now)
+	if err != nil {
+		return nil, err
+	}
+	if synced, ok := d.syncedNotifiers.Load(key); !ok || synced != hash {
+		if err := d.sync(); err != nil {
+			return nil, err
+		}
+		d.syncedNotifiers.Store(key, hash)
+	}
+
+	clusterDialer, err := d.dialerFactory.ClusterDialer(d.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := notifierutil.ReleaseHeldMessages(d.ctx, d.notifiers, notifier, clusterDialer); err != nil {
+		return nil, errors.Wrapf(err, "release messages held by notifier %s", notifier.Name)
+	}
+
+	// the notifier is muted or unmuted in the config when its quiet hours start or end
+	if next, ok := notifierutil.NextQuietHoursTransition(notifier.Spec.Policy, now); ok {
+		d.notifierController.EnqueueAfter(notifier.Namespace, notifier.Name, next.Sub(now))
+	}
+
+	return nil, nil
+}
+
+// notifierConfigHash identifies what the alertmanager config depends on for
+// the notifier: its spec and whether it is in quiet hours.
+func notifierConfigHash(notifier *v3.Notifier, now time.Time) (string, error) {
+	b, err := json.Marshal(notifier.Spec)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(b)
+	fmt.Fprintf(h, "%t", notifierutil.IsMuted(notifier.Spec.Policy, now))
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
 //sync: update the secret which store the configuration of alertmanager given the latest configured notifiers and alerts rules.
@@ -241,10 +296,11 @@ func (d *ConfigSyncer) sync() error {
 		return errors.Wrapf(err, "Get secrets")
 	}
 
-	if string(configSecret.Data["alertmanager.yaml"]) != string(data) || string(configSecret.Data["notification.tmpl"]) != deployer.NotificationTmpl {
+	notificationTmpl := getNotificationTemplate(notifiers)
+	if string(configSecret.Data["alertmanager.yaml"]) != string(data) || string(configSecret.Data["notification.tmpl"]) != notificationTmpl {
 		newConfigSecret := configSecret.DeepCopy()
 		newConfigSecret.Data["alertmanager.yaml"] = data
-		newConfigSecret.Data["notification.tmpl"] = []byte(deployer.NotificationTmpl)
+		newConfigSecret.Data["notification.tmpl"] = []byte(notificationTmpl)
 
 		_, err = secretClient.Update(newConfigSecret)
 		if err != nil {
@@ -275,6 +331,21 @@ func (d *ConfigSyncer) getNotifier(id string, notifiers []*v3.Notifier) *v3.Noti
 	return nil
 }
 
+// getNotificationTemplate returns the default notification templates followed by the templates of the notifiers.
+func getNotificationTemplate(notifiers []*v3.Notifier) string {
+	sorted := make([]*v3.Notifier, len(notifiers))
+	copy(sorted, notifiers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	tmpl := deployer.NotificationTmpl
+	for _, n := range sorted {
+		tmpl += notifierutil.TemplateDefinitions(n.Name, &n.Spec)
+	}
+	return tmpl
+}
+
 func (d *ConfigSyncer) addProjectAlert2Operator(clusterDisplayName string, projectGroups map[string]map[string][]*v3.ProjectAlertRule, keys []string) error {
 	for _, projectName := range keys {
 		groupRules := projectGroups[projectName]
@@ -379,6 +450,7 @@ func (d *ConfigSyncer) addProjectAlert2Config(config *alertconfig.Config, projec
 					}
 
 				}
+				d.applyNotifierPolicies(config.Route, r1, notifiers, group.Spec.Recipients)
 				d.appendRoute(config.Route, r1)
 			}
 		}
@@ -426,6 +498,7 @@ func (d *ConfigSyncer) addClusterAlert2Config(config *alertconfig.Config, alerts
 
 			}
 
+			d.applyNotifierPolicies(config.Route, r1, notifiers, group.Spec.Recipients)
 			d.appendRoute(config.Route, r1)
 		}
 	}
@@ -470,6 +543,47 @@ func (d *ConfigSyncer) newRoute(match map[string]string, inherited bool, timeFie
 	return route
 }
 
+// applyNotifierPolicies raises the intervals of a group route and its sub routes to the minimum required by the
+// policies of the group's notifiers, taking the intervals inherited from the parent route into account.
+func (d *ConfigSyncer) applyNotifierPolicies(parent, route *alertconfig.Route, notifiers []*v3.Notifier, recipients []v32.Recipient) {
+	var minGroupInterval, minRepeatInterval time.Duration
+	for _, r := range recipients {
+		notifier := d.getNotifier(r.NotifierName, notifiers)
+		if notifier == nil {
+			continue
+		}
+		gi, ri := notifierutil.MinIntervals(notifier.Spec.Policy)
+		if gi > minGroupInterval {
+			minGroupInterval = gi
+		}
+		if ri > minRepeatInterval {
+			minRepeatInterval = ri
+		}
+	}
+
+	raiseInterval(&route.GroupInterval, parent.GroupInterval, minGroupInterval)
+	raiseInterval(&route.RepeatInterval, parent.RepeatInterval, minRepeatInterval)
+	for _, subRoute := range route.Routes {
+		raiseInterval(&subRoute.GroupInterval, route.GroupInterval, minGroupInterval)
+		raiseInterval(&subRoute.RepeatInterval, route.RepeatInterval, minRepeatInterval)
+	}
+}
+
+func raiseInterval(interval **model.Duration, inherited *model.Duration, min time.Duration) {
+	if min == 0 {
+		return
+	}
+	effective := *interval
+	if effective == nil {
+		effective = inherited
+	}
+	if effective != nil && time.Duration(*effective) >= min {
+		return
+	}
+	d := model.Duration(min)
+	*interval = &d
+}
+
 func (d *ConfigSyncer) appendRoute(route *alertconfig.Route, subRoute *alertconfig.Route) {
 	if route.Routes == nil {
 		route.Routes = []*alertconfig.Route{}
@@ -486,159 +600,40 @@ func (d *ConfigSyncer) addRecipients(notifiers []*v3.Notifier, receiver *alertco
 				logrus.Debugf("Can not find the notifier %s", r.NotifierName)
 				continue
 			}
+			if notifierutil.IsMuted(notifier.Spec.Policy, time.Now()) {
+				// the receiver and its route are kept so that alertmanager delivers
+				// the alerts still firing once the quiet hours end
+				logrus.Debugf("Notifier %s is in quiet hours", r.NotifierName)
+				receiverExist = true
+				continue
+			}
+			driver, err := notifierutil.DriverFor(&notifier.Spec)
+			if err != nil {
+				logrus.Debugf("Notifier %s is not configured", r.NotifierName)
+				continue
+			}
 			commonNotifierConfig := alertconfig.NotifierConfig{
 				VSendResolved: notifier.Spec.SendResolved,
 			}
-			if notifier.Spec.PagerdutyConfig != nil {
-				pagerduty := &alertconfig.PagerdutyConfig{
-					NotifierConfig: commonNotifierConfig,
-					ServiceKey:     alertconfig.Secret(notifier.Spec.PagerdutyConfig.ServiceKey),
-					Description:    `{{ template "rancher.title" . }}`,
-				}
-
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.PagerdutyConfig.HTTPClientConfig) {
-					url, err := toAlertManagerURL(notifier.Spec.PagerdutyConfig.HTTPClientConfig.ProxyURL)
-					if err != nil {
-						logrus.Errorf("Failed to parse pagerduty proxy url %s, %v", notifier.Spec.PagerdutyConfig.HTTPClientConfig.ProxyURL, err)
-						continue
-					}
-					pagerduty.HTTPConfig = &alertconfig.HTTPClientConfig{
-						ProxyURL: *url,
-					}
-				}
-				if r.Recipient != "" {
-					pagerduty.ServiceKey = alertconfig.Secret(r.Recipient)
-				}
-				receiver.PagerdutyConfigs = append(receiver.PagerdutyConfigs, pagerduty)
-				receiverExist = true
-
-			} else if notifier.Spec.WechatConfig != nil {
-				wechat := &alertconfig.WechatConfig{
-					NotifierConfig: commonNotifierConfig,
-					APISecret:      alertconfig.Secret(notifier.Spec.WechatConfig.Secret),
-					AgentID:        notifier.Spec.WechatConfig.Agent,
-					CorpID:         notifier.Spec.WechatConfig.Corp,
-					Message:        `{{ template "wechat.text" . }}`,
-				}
 
-				recipient := notifier.Spec.WechatConfig.DefaultRecipient
-				if r.Recipient != "" {
-					recipient = r.Recipient
-				}
-
-				switch notifier.Spec.WechatConfig.RecipientType {
-				case "tag":
-					wechat.ToTag = recipient
-				case "user":
-					wechat.ToUser = recipient
-				default:
-					wechat.ToParty = recipient
-				}
-
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.WechatConfig.HTTPClientConfig) {
-					url, err := toAlertManagerURL(notifier.Spec.WechatConfig.HTTPClientConfig.ProxyURL)
-					if err != nil {
-						logrus.Errorf("Failed to parse wechat proxy url %s, %v", notifier.Spec.WechatConfig.HTTPClientConfig.ProxyURL, err)
-						continue
-					}
-					wechat.HTTPConfig = &alertconfig.HTTPClientConfig{
-						ProxyURL: *url,
-					}
-				}
-
-				receiver.WechatConfigs = append(receiver.WechatConfigs, wechat)
-				receiverExist = true
-
-			} else if notifier.Spec.DingtalkConfig != nil {
-				webhookURL := webhookReceiverURL + r.NotifierName
-				dingtalk := &alertconfig.WebhookConfig{
-					NotifierConfig: commonNotifierConfig,
-					URL:            webhookURL,
-				}
-
-				receiver.WebhookConfigs = append(receiver.WebhookConfigs, dingtalk)
-				receiverExist = true
-
-			} else if notifier.Spec.MSTeamsConfig != nil {
-				webhookURL := webhookReceiverURL + r.NotifierName
-				msTeams := &alertconfig.WebhookConfig{
-					NotifierConfig: commonNotifierConfig,
-					URL:            webhookURL,
+			switch dr := driver.(type) {
+			case notifierutil.ReceiverDriver:
+				templates := notifierutil.ReceiverTemplatesFor(notifier.Name, &notifier.Spec)
+				if err := dr.AddReceiverConfig(receiver, &notifier.Spec, r.Recipient, templates, commonNotifierConfig); err != nil {
+					logrus.Errorf("Failed to add %s notifier %s to alertmanager config, %v", driver.Name(), r.NotifierName, err)
+					continue
 				}
-
-				receiver.WebhookConfigs = append(receiver.WebhookConfigs, msTeams)
 				receiverExist = true
-
-			} else if notifier.Spec.WebhookConfig != nil {
+			case notifierutil.WebhookReceiverDriver:
 				webhook := &alertconfig.WebhookConfig{
 					NotifierConfig: commonNotifierConfig,
-					URL:            notifier.Spec.WebhookConfig.URL,
-				}
-				if r.Recipient != "" {
-					webhook.URL = r.Recipient
+					URL:            webhookReceiverURL + r.NotifierName,
 				}
-
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.WebhookConfig.HTTPClientConfig) {
-					url, err := toAlertManagerURL(notifier.Spec.WebhookConfig.HTTPClientConfig.ProxyURL)
-					if err != nil {
-						logrus.Errorf("Failed to parse webhook proxy url %s, %v", notifier.Spec.WebhookConfig.HTTPClientConfig.ProxyURL, err)
-						continue
-					}
-					webhook.HTTPConfig = &alertconfig.HTTPClientConfig{
-						ProxyURL: *url,
-					}
-				}
-
 				receiver.WebhookConfigs = append(receiver.WebhookConfigs, webhook)
 				receiverExist = true
-			} else if notifier.Spec.SlackConfig != nil {
-				slack := &alertconfig.SlackConfig{
-					NotifierConfig: commonNotifierConfig,
-					APIURL:         alertconfig.Secret(notifier.Spec.SlackConfig.URL),
-					Channel:        notifier.Spec.SlackConfig.DefaultRecipient,
-					Text:           `{{ template "slack.text" . }}`,
-					Title:          `{{ template "rancher.title" . }}`,
-					TitleLink:      "",
-					Color:          `{{ if eq (index .Alerts 0).Labels.severity "critical" }}danger{{ else if eq (index .Alerts 0).Labels.severity "warning" }}warning{{ else }}good{{ end }}`,
-				}
-				if r.Recipient != "" {
-					slack.Channel = r.Recipient
-				}
-
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.SlackConfig.HTTPClientConfig) {
-					url, err := toAlertManagerURL(notifier.Spec.SlackConfig.HTTPClientConfig.ProxyURL)
-					if err != nil {
-						logrus.Errorf("Failed to parse slack proxy url %s, %v", notifier.Spec.SlackConfig.HTTPClientConfig.ProxyURL, err)
-						continue
-					}
-					slack.HTTPConfig = &alertconfig.HTTPClientConfig{
-						ProxyURL: *url,
-					}
-				}
-				receiver.SlackConfigs = append(receiver.SlackConfigs, slack)
-				receiverExist = true
-
-			} else if notifier.Spec.SMTPConfig != nil {
-				header := map[string]string{}
-				header["Subject"] = `{{ template "rancher.title" . }}`
-				email := &alertconfig.EmailConfig{
-					NotifierConfig: commonNotifierConfig,
-					Smarthost:      notifier.Spec.SMTPConfig.Host + ":" + strconv.Itoa(notifier.Spec.SMTPConfig.Port),
-					AuthPassword:   alertconfig.Secret(notifier.Spec.SMTPConfig.Password),
-					AuthUsername:   notifier.Spec.SMTPConfig.Username,
-					RequireTLS:     notifier.Spec.SMTPConfig.TLS,
-					To:             notifier.Spec.SMTPConfig.DefaultRecipient,
-					Headers:        header,
-					From:           notifier.Spec.SMTPConfig.Sender,
-					HTML:           `{{ template "email.text" . }}`,
-				}
-				if r.Recipient != "" {
-					email.To = r.Recipient
-				}
-				receiver.EmailConfigs = append(receiver.EmailConfigs, email)
-				receiverExist = true
+			default:
+				logrus.Debugf("Notifier driver %s does not support alertmanager delivery", driver.Name())
 			}
-
 		}
 	}
 
@@ -703,14 +698,6 @@ func getProjectAlertGroupBy(spec v32.ProjectAlertRuleSpec) []model.LabelName {
 	return nil
 }
 
-func toAlertManagerURL(urlStr string) (*alertconfig.URL, error) {
-	url, err := url.Parse(urlStr)
-	if err != nil {
-		return nil, err
-	}
-	return &alertconfig.URL{URL: url}, nil
-}
-
 func (d *ConfigSyncer) syncWebhookConfig(notifiers []*v3.Notifier, cAlertGroupsMap map[string]*v3.ClusterAlertGroup, pAlertGroupsMap map[string]*v3.ProjectAlertGroup) error {
 	var recipients []v32.Recipient
 	for _, group := range cAlertGroupsMap {
@@ -739,35 +726,22 @@ func (d *ConfigSyncer) syncWebhookConfig(notifiers []*v3.Notifier, cAlertGroupsM
 				logrus.Debugf("Can not find the notifier %s", r.NotifierName)
 				continue
 			}
-			if notifier.Spec.DingtalkConfig != nil {
-				provider := &Provider{
-					Type:       DingTalk,
-					WebHookURL: notifier.Spec.DingtalkConfig.URL,
-				}
-				if notifier.Spec.DingtalkConfig.Secret != "" {
-					provider.Secret = notifier.Spec.DingtalkConfig.Secret
-				}
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.DingtalkConfig.HTTPClientConfig) {
-					provider.ProxyURL = notifier.Spec.DingtalkConfig.HTTPClientConfig.ProxyURL
-				}
-				receiver := &Receiver{
-					Provider: r.NotifierName,
-				}
-				providers[r.NotifierName] = provider
-				receivers[r.NotifierName] = receiver
-			} else if notifier.Spec.MSTeamsConfig != nil {
-				provider := &Provider{
-					Type:       MicrosoftTeams,
-					WebHookURL: notifier.Spec.MSTeamsConfig.URL,
-				}
-				if notifierutil.IsHTTPClientConfigSet(notifier.Spec.MSTeamsConfig.HTTPClientConfig) {
-					provider.ProxyURL = notifier.Spec.MSTeamsConfig.HTTPClientConfig.ProxyURL
+			driver, err := notifierutil.DriverFor(&notifier.Spec)
+			if err != nil {
+				logrus.Debugf("Notifier %s is not configured", r.NotifierName)
+				continue
+			}
+			if dr, ok := driver.(notifierutil.WebhookReceiverDriver); ok {
+				p := dr.WebhookReceiverProvider(&notifier.Spec)
+				providers[r.NotifierName] = &Provider{
+					Type:       p.Type,
+					WebHookURL: p.WebHookURL,
+					Secret:     p.Secret,
+					ProxyURL:   p.ProxyURL,
 				}
-				receiver := &Receiver{
+				receivers[r.NotifierName] = &Receiver{
 					Provider: r.NotifierName,
 				}
-				providers[r.NotifierName] = provider
-				receivers[r.NotifierName] = receiver
 			}
 		}
 	}
@@ -448,3 +448,60 @@ func TestAddProjectAlert2Config(t *testing.T) {
 
 	projectMetricGroupBy = getProjectAlertGroupBy(projectMetricAlert.Spec)
 )
+
+func TestApplyNotifierPolicies(t *testing.T) {
+	policyNotifiers := []*v3.Notifier{notifiers[0].DeepCopy()}
+	policyNotifiers[0].Spec.Policy = &v32.NotifierPolicy{
+		RateLimit:     &v32.NotifierRateLimit{MaxMessages: 2, WindowSeconds: 600},
+		Deduplication: &v32.NotifierDeduplication{WindowSeconds: 7200},
+	}
+
+	config := manager.GetAlertManagerDefaultConfig()
+	configSyncer := ConfigSyncer{
+		clusterName: clusterName,
+	}
+
+	if err := configSyncer.addClusterAlert2Config(config, metricRulesMap, []string{groupID}, clusterGroupMap, policyNotifiers); err != nil {
+		t.Fatal(err)
+	}
+
+	groupRoute := config.Route.Routes[0]
+	if *groupRoute.GroupInterval != model.Duration(5*time.Minute) {
+		t.Errorf("expect group interval 5m, actual %v", groupRoute.GroupInterval)
+	}
+	if *groupRoute.RepeatInterval != model.Duration(2*time.Hour) {
+		t.Errorf("expect repeat interval 2h, actual %v", groupRoute.RepeatInterval)
+	}
+
+	subRoute := groupRoute.Routes[0]
+	if *subRoute.RepeatInterval != model.Duration(2*time.Hour) {
+		t.Errorf("expect sub route repeat interval 2h, actual %v", subRoute.RepeatInterval)
+	}
+}
+
+func TestMutedNotifierKeepsRoute(t *testing.T) {
+	mutedNotifiers := []*v3.Notifier{notifiers[0].DeepCopy()}
+	mutedNotifiers[0].Spec.Policy = &v32.NotifierPolicy{
+		QuietHours: []v32.QuietHours{{Start: "00:00", End: "12:00"}, {Start: "12:00", End: "00:00"}},
+	}
+
+	config := manager.GetAlertManagerDefaultConfig()
+	configSyncer := ConfigSyncer{
+		clusterName: clusterName,
+	}
+
+	if err := configSyncer.addClusterAlert2Config(config, metricRulesMap, []string{groupID}, clusterGroupMap, mutedNotifiers); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config.Route.Routes) == 0 {
+		t.Fatal("expect the group route to be kept during quiet hours")
+	}
+	if len(config.Receivers) == 0 {
+		t.Fatal("expect the group receiver to be kept during quiet hours")
+	}
+	receiver := config.Receivers[len(config.Receivers)-1]
+	if receiver.Name != groupID || len(receiver.SlackConfigs)+len(receiver.WebhookConfigs)+len(receiver.EmailConfigs) != 0 {
+		t.Errorf("expect receiver %s without integrations, actual %+v", groupID, receiver)
+	}
+}
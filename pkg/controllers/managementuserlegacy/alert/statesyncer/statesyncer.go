@@ -5,9 +5,11 @@
 	"time"
 
 	"github.com/rancher/norman/controller"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/common"
 	"github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/manager"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	notifierutil "github.com/rancher/rancher/pkg/notifiers"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/wrangler/pkg/ticker"
 	"github.com/sirupsen/logrus"
@@ -17,10 +19,13 @@
 
 func StartStateSyncer(ctx context.Context, cluster *config.UserContext, manager *manager.AlertManager) {
 	s := &StateSyncer{
-		clusterAlertRules: cluster.Management.Management.ClusterAlertRules(cluster.ClusterName),
-		projectAlertRules: cluster.Management.Management.ProjectAlertRules(""),
-		alertManager:      manager,
-		clusterName:       cluster.ClusterName,
+		clusterAlertRules:  cluster.Management.Management.ClusterAlertRules(cluster.ClusterName),
+		projectAlertRules:  cluster.Management.Management.ProjectAlertRules(""),
+		clusterAlertGroups: cluster.Management.Management.ClusterAlertGroups(cluster.ClusterName).Controller().Lister(),
+		projectAlertGroups: cluster.Management.Management.ProjectAlertGroups("").Controller().Lister(),
+		notifiers:          cluster.Management.Management.Notifiers(cluster.ClusterName),
+		alertManager:       manager,
+		clusterName:        cluster.ClusterName,
 	}
 	go s.watch(ctx, 10*time.Second)
 }
@@ -32,13 +37,16 @@ func (s *StateSyncer) watch(ctx context.Context, interval time.Duration) {
 }
 
 type StateSyncer struct {
-	clusterAlertRules v3.ClusterAlertRuleInterface
-	projectAlertRules v3.ProjectAlertRuleInterface
-	alertManager      *manager.AlertManager
-	clusterName       string
+	clusterAlertRules  v3.ClusterAlertRuleInterface
+	projectAlertRules  v3.ProjectAlertRuleInterface
+	clusterAlertGroups v3.ClusterAlertGroupLister
+	projectAlertGroups v3.ProjectAlertGroupLister
+	notifiers          v3.NotifierInterface
+	alertManager       *manager.AlertManager
+	clusterName        string
 }
 
-//synchronize the state between alert CRD and alertmanager.
+// synchronize the state between alert CRD and alertmanager.
 func (s *StateSyncer) syncState() error {
 
 	if s.alertManager.IsDeploy == false {
@@ -101,14 +109,88 @@ func (s *StateSyncer) syncState() error {
 				}
 			}
 		}
+
+		s.syncMutedAlerts(apiAlerts)
 	}
 
 	return err
 
 }
 
-//The curState is the state in the CRD status,
-//The newState is the state in alert manager side
+// syncMutedAlerts records the alerts firing for the groups of the notifiers in
+// quiet hours, so they are counted as suppressed by the notifier policy.
+func (s *StateSyncer) syncMutedAlerts(apiAlerts []*manager.APIAlert) {
+	notifiers, err := s.notifiers.Controller().Lister().List(s.clusterName, labels.NewSelector())
+	if err != nil {
+		logrus.Errorf("Error occurred while listing notifiers, %v", err)
+		return
+	}
+
+	var groupRecipients map[string][]v32.Recipient
+	now := time.Now()
+	for _, notifier := range notifiers {
+		if notifier.Spec.Policy == nil || len(notifier.Spec.Policy.QuietHours) == 0 {
+			continue
+		}
+
+		var fingerprints []string
+		if notifierutil.IsMuted(notifier.Spec.Policy, now) {
+			if groupRecipients == nil {
+				if groupRecipients, err = s.listGroupRecipients(); err != nil {
+					logrus.Errorf("Error occurred while listing alert groups, %v", err)
+					return
+				}
+			}
+			fingerprints = mutedFingerprints(s.clusterName+":"+notifier.Name, groupRecipients, apiAlerts)
+		}
+
+		if err := notifierutil.RecordMutedAlerts(s.notifiers, notifier, fingerprints, now); err != nil {
+			logrus.Errorf("Error occurred while recording muted alerts of notifier %s:%s, %v", notifier.Namespace, notifier.Name, err)
+		}
+	}
+}
+
+func (s *StateSyncer) listGroupRecipients() (map[string][]v32.Recipient, error) {
+	clusterGroups, err := s.clusterAlertGroups.List(s.clusterName, labels.NewSelector())
+	if err != nil {
+		return nil, err
+	}
+	projectGroups, err := s.projectAlertGroups.List("", labels.NewSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	groupRecipients := map[string][]v32.Recipient{}
+	for _, group := range clusterGroups {
+		groupRecipients[common.GetGroupID(group.Namespace, group.Name)] = group.Spec.Recipients
+	}
+	for _, group := range projectGroups {
+		if controller.ObjectInCluster(s.clusterName, group) {
+			groupRecipients[common.GetGroupID(group.Namespace, group.Name)] = group.Spec.Recipients
+		}
+	}
+	return groupRecipients, nil
+}
+
+// mutedFingerprints returns the fingerprints of the active alerts routed to the notifier.
+func mutedFingerprints(notifierName string, groupRecipients map[string][]v32.Recipient, apiAlerts []*manager.APIAlert) []string {
+	var fingerprints []string
+	for _, a := range apiAlerts {
+		if a.Alert == nil || a.Status.State != manager.AlertStateActive {
+			continue
+		}
+		for _, r := range groupRecipients[string(a.Labels["group_id"])] {
+			if r.NotifierName == notifierName {
+				fingerprints = append(fingerprints, a.Fingerprint)
+				break
+			}
+		}
+	}
+	return fingerprints
+}
+
+// The curState is the state in the CRD status,
+// The newState is the state in alert manager side
 func (s *StateSyncer) doSync(matcherName, matcherValue, curState, newState string) (needUpdate bool) {
 	if curState == "inactive" {
 		return false
@@ -0,0 +1,224 @@
+package pipelineexecution
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/providers"
+	"github.com/rancher/rancher/pkg/pipeline/remote"
+	"github.com/rancher/rancher/pkg/pipeline/remote/model"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const commitStatusRetryInterval = 30 * time.Second
+
+// reportCommitStatus pushes the state of an execution, and of its stages when
+// enabled in the pipeline settings, to the commit it runs for. The reported
+// states are kept in an annotation so the git host is only called on changes.
+// Failures to report never fail the execution, they are recorded on the
+// Reported condition instead and the execution is requeued unless the
+// credential lacks permission on the repository.
+func (l *Lifecycle) reportCommitStatus(obj *v3.PipelineExecution) {
+	if obj.Spec.Commit == "" {
+		return
+	}
+	setting, err := l.getCommitStatusSetting(obj)
+	if err != nil {
+		logrus.Warnf("failed to get commit status setting for execution %s: %v", obj.Name, err)
+		return
+	}
+	if setting == utils.CommitStatusDisabled {
+		return
+	}
+
+	reported := getReportedCommitStatuses(obj)
+	var toReport []model.CommitStatus
+	for _, status := range getCommitStatuses(obj, setting == utils.CommitStatusStage) {
+		if reported[status.Context] != status.State {
+			toReport = append(toReport, status)
+		}
+	}
+	if len(toReport) == 0 {
+		return
+	}
+
+	remote, accessToken, err := l.getRemote(obj)
+	if err != nil {
+		setReportError(obj, err)
+		l.retryReportIfTransient(obj, err)
+		return
+	} else if remote == nil {
+		return
+	}
+	var reportErr error
+	for i := range toReport {
+		status := toReport[i]
+		if err := remote.SetCommitStatus(obj.Spec.RepositoryURL, &status, accessToken); err != nil {
+			reportErr = err
+			if !isPermissionError(err) {
+				continue
+			}
+		}
+		reported[status.Context] = status.State
+	}
+	if reportErr != nil {
+		setReportError(obj, reportErr)
+		l.retryReportIfTransient(obj, reportErr)
+	} else {
+		v32.PipelineExecutionConditionReported.True(obj)
+		v32.PipelineExecutionConditionReported.Reason(obj, "")
+		v32.PipelineExecutionConditionReported.Message(obj, "")
+	}
+
+	b, err := json.Marshal(reported)
+	if err != nil {
+		logrus.Warnf("failed to record commit statuses of execution %s: %v", obj.Name, err)
+		return
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = map[string]string{}
+	}
+	obj.Annotations[utils.CommitStatusAnnotation] = string(b)
+}
+
+// retryReportIfTransient requeues the execution so statuses that failed to be
+// reported are sent again, even when the execution itself does not change
+// anymore.
+func (l *Lifecycle) retryReportIfTransient(obj *v3.PipelineExecution, err error) {
+	if isPermissionError(err) {
+		return
+	}
+	l.pipelineExecutions.Controller().EnqueueAfter(obj.Namespace, obj.Name, commitStatusRetryInterval)
+}
+
+func (l *Lifecycle) getCommitStatusSetting(obj *v3.PipelineExecution) (string, error) {
+	_, projectID := ref.Parse(obj.Spec.ProjectName)
+	setting, err := l.pipelineSettingLister.Get(projectID, utils.SettingCommitStatus)
+	if apierrors.IsNotFound(err) {
+		return utils.SettingCommitStatusDefault, nil
+	} else if err != nil {
+		return "", err
+	}
+	return common.GetPipelineSettingValue(setting), nil
+}
+
+// getRemote returns the remote of the git host of an execution and the access
+// token to call it with, or a nil remote when the pipeline has no credential.
+func (l *Lifecycle) getRemote(obj *v3.PipelineExecution) (model.Remote, string, error) {
+	ns, name := ref.Parse(obj.Spec.PipelineName)
+	pipeline, err := l.pipelineLister.Get(ns, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if pipeline.Spec.SourceCodeCredentialName == "" {
+		return nil, "", nil
+	}
+	ns, name = ref.Parse(pipeline.Spec.SourceCodeCredentialName)
+	credential, err := l.sourceCodeCredentialLister.Get(ns, name)
+	if err != nil {
+		return nil, "", err
+	}
+	_, projID := ref.Parse(obj.Spec.ProjectName)
+	scpConfig, err := providers.GetSourceCodeProviderConfig(credential.Spec.SourceCodeType, projID)
+	if err != nil {
+		return nil, "", err
+	}
+	remote, err := remote.New(scpConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	accessToken, err := utils.EnsureAccessToken(l.sourceCodeCredentials, remote, credential)
+	if err != nil {
+		return nil, "", err
+	}
+	return remote, accessToken, nil
+}
+
+func getReportedCommitStatuses(obj *v3.PipelineExecution) map[string]string {
+	reported := map[string]string{}
+	if value := obj.Annotations[utils.CommitStatusAnnotation]; value != "" {
+		if err := json.Unmarshal([]byte(value), &reported); err != nil {
+			logrus.Warnf("invalid commit statuses recorded on execution %s: %v", obj.Name, err)
+		}
+	}
+	return reported
+}
+
+// getCommitStatuses returns the statuses to report for an execution, followed
+// by one for each stage when perStage is set.
+func getCommitStatuses(obj *v3.PipelineExecution, perStage bool) []model.CommitStatus {
+	targetURL := getExecutionURL(obj)
+	state, description := getCommitState(obj.Status.ExecutionState)
+	statuses := []model.CommitStatus{{
+		Commit:      obj.Spec.Commit,
+		State:       state,
+		Context:     utils.CommitStatusContext,
+		Description: fmt.Sprintf("Pipeline #%d %s", obj.Spec.Run, description),
+		TargetURL:   targetURL,
+	}}
+	if !perStage {
+		return statuses
+	}
+	for i, stage := range obj.Status.Stages {
+		name := fmt.Sprint(i + 1)
+		if i < len(obj.Spec.PipelineConfig.Stages) && obj.Spec.PipelineConfig.Stages[i].Name != "" {
+			name = obj.Spec.PipelineConfig.Stages[i].Name
+		}
+		state, description := getCommitState(stage.State)
+		statuses = append(statuses, model.CommitStatus{
+			Commit:      obj.Spec.Commit,
+			State:       state,
+			Context:     fmt.Sprintf("%s/%s", utils.CommitStatusContext, name),
+			Description: fmt.Sprintf("Stage %s %s", name, description),
+			TargetURL:   targetURL,
+		})
+	}
+	return statuses
+}
+
+func getCommitState(state string) (string, string) {
+	switch state {
+	case utils.StateBuilding:
+		return model.CommitStateRunning, "is running"
+	case utils.StateSuccess:
+		return model.CommitStateSuccess, "succeeded"
+	case utils.StateSkipped:
+		return model.CommitStateSuccess, "was skipped"
+	case utils.StateFailed:
+		return model.CommitStateFailure, "failed"
+	case utils.StateAborted:
+		return model.CommitStateError, "was aborted"
+	case utils.StateDenied:
+		return model.CommitStateError, "was denied"
+	}
+	return model.CommitStatePending, "is waiting"
+}
+
+func isPermissionError(err error) bool {
+	apiErr, ok := err.(*httperror.APIError)
+	if !ok {
+		return false
+	}
+	status := apiErr.Code.Status
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound
+}
+
+func setReportError(obj *v3.PipelineExecution, err error) {
+	logrus.Warnf("failed to report commit status of execution %s: %v", obj.Name, err)
+	v32.PipelineExecutionConditionReported.False(obj)
+	if isPermissionError(err) {
+		v32.PipelineExecutionConditionReported.Reason(obj, "PermissionDenied")
+		v32.PipelineExecutionConditionReported.Message(obj, fmt.Sprintf("missing permission to report commit status: %v", err))
+		return
+	}
+	v32.PipelineExecutionConditionReported.ReasonAndMessageFromError(obj, err)
+}
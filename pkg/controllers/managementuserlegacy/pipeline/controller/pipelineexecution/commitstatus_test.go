@@ -0,0 +1,78 @@
+package pipelineexecution
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/remote/model"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetCommitStatuses(t *testing.T) {
+	assert := assert.New(t)
+	execution := &v3.PipelineExecution{
+		ObjectMeta: metav1.ObjectMeta{Name: "pipeline-3"},
+		Spec: v32.PipelineExecutionSpec{
+			ProjectName:  "c-test:p-test",
+			PipelineName: "p-test:pipeline",
+			Run:          3,
+			Commit:       "0a1b2c",
+			PipelineConfig: v32.PipelineConfig{
+				Stages: []v32.Stage{{Name: "Clone"}, {Name: "Build"}, {}},
+			},
+		},
+		Status: v32.PipelineExecutionStatus{
+			ExecutionState: utils.StateBuilding,
+			Stages: []v32.StageStatus{
+				{State: utils.StateSuccess},
+				{State: utils.StateFailed},
+				{State: utils.StateWaiting},
+			},
+		},
+	}
+
+	statuses := getCommitStatuses(execution, false)
+	assert.Len(statuses, 1)
+	assert.Equal(model.CommitStatus{
+		Commit:      "0a1b2c",
+		State:       model.CommitStateRunning,
+		Context:     utils.CommitStatusContext,
+		Description: "Pipeline #3 is running",
+		TargetURL:   getExecutionURL(execution),
+	}, statuses[0])
+
+	statuses = getCommitStatuses(execution, true)
+	assert.Len(statuses, 4)
+	assert.Equal("rancher/pipeline/Clone", statuses[1].Context)
+	assert.Equal(model.CommitStateSuccess, statuses[1].State)
+	assert.Equal("Stage Build failed", statuses[2].Description)
+	assert.Equal(model.CommitStateFailure, statuses[2].State)
+	assert.Equal("rancher/pipeline/3", statuses[3].Context, "unnamed stages are reported by position")
+	assert.Equal(model.CommitStatePending, statuses[3].State)
+
+	execution.Status.ExecutionState = utils.StateAborted
+	assert.Equal(model.CommitStateError, getCommitStatuses(execution, false)[0].State)
+}
+
+func TestReportCommitStatusError(t *testing.T) {
+	assert := assert.New(t)
+	execution := &v3.PipelineExecution{}
+	execution.Annotations = map[string]string{utils.CommitStatusAnnotation: `{"rancher/pipeline":"pending"}`}
+	assert.Equal(map[string]string{utils.CommitStatusContext: model.CommitStatePending}, getReportedCommitStatuses(execution))
+
+	forbidden := httperror.NewAPIErrorLong(http.StatusForbidden, "", "Resource not accessible by integration")
+	assert.True(isPermissionError(forbidden))
+	assert.False(isPermissionError(errors.New("connection refused")))
+
+	setReportError(execution, forbidden)
+	assert.True(v32.PipelineExecutionConditionReported.IsFalse(execution))
+	assert.Equal("PermissionDenied", v32.PipelineExecutionConditionReported.GetReason(execution))
+	assert.Contains(v32.PipelineExecutionConditionReported.GetMessage(execution), "missing permission")
+	assert.False(v32.PipelineExecutionConditionBuilt.IsFalse(execution), "the build is not failed")
+}
@@ -116,9 +116,11 @@ func (l *Lifecycle) deploy(projectName string) error {
 		return err
 	}
 
-	sa := getServiceAccount(nsName)
-	if _, err := l.serviceAccounts.Create(sa); err != nil && !apierrors.IsAlreadyExists(err) {
-		return errors.Wrapf(err, "Error creating a pipeline service account")
+	for _, saName := range []string{utils.JenkinsName, utils.PipelineStepName} {
+		sa := getServiceAccount(nsName, saName)
+		if _, err := l.serviceAccounts.Create(sa); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "Error creating a pipeline service account")
+		}
 	}
 	np := getNetworkPolicy(nsName)
 	if _, err := l.networkPolicies.Create(np); err != nil && !apierrors.IsAlreadyExists(err) {
@@ -379,19 +381,34 @@ func getRegistryCredential(projectID string, token string, hostname string) (*co
 	}, nil
 }
 
-func getServiceAccount(ns string) *corev1.ServiceAccount {
+func getServiceAccount(ns string, name string) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: ns,
-			Name:      utils.JenkinsName,
+			Name:      name,
 		},
 	}
 }
 
 func getRoleBindings(rbNs string, commonName string) *rbacv1.RoleBinding {
+	return getServiceAccountRoleBinding(rbNs, commonName, commonName, utils.JenkinsName)
+}
+
+// getStepRoleBindings grants the service account of kubernetes engine step
+// pods access to a project namespace. Unlike the jenkins service account, it is
+// not bound to roles for creating or editing namespaces.
+func getStepRoleBindings(rbNs string, commonName string) *rbacv1.RoleBinding {
+	return getServiceAccountRoleBinding(rbNs, getStepRoleBindingName(commonName), commonName, utils.PipelineStepName)
+}
+
+func getStepRoleBindingName(commonName string) string {
+	return commonName + "-" + utils.PipelineStepName
+}
+
+func getServiceAccountRoleBinding(rbNs string, name string, saNs string, saName string) *rbacv1.RoleBinding {
 	return &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      commonName,
+			Name:      name,
 			Namespace: rbNs,
 		},
 		RoleRef: rbacv1.RoleRef{
@@ -401,8 +418,8 @@ func getRoleBindings(rbNs string, commonName string) *rbacv1.RoleBinding {
 		},
 		Subjects: []rbacv1.Subject{{
 			Kind:      rbacv1.ServiceAccountKind,
-			Namespace: commonName,
-			Name:      utils.JenkinsName,
+			Namespace: saNs,
+			Name:      saName,
 		}},
 	}
 }
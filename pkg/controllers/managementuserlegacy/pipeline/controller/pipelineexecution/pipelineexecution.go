@@ -90,6 +90,7 @@ type Lifecycle struct {
 	daemonsets          appsv1.DaemonSetInterface
 
 	notifierLister             mv3.NotifierLister
+	notifiers                  mv3.NotifierInterface
 	tokenLister                mv3.TokenLister
 	pipelineLister             v3.PipelineLister
 	pipelines                  v3.PipelineInterface
@@ -98,6 +99,7 @@ type Lifecycle struct {
 	pipelineSettingLister      v3.PipelineSettingLister
 	pipelineEngine             engine.PipelineEngine
 	sourceCodeCredentialLister v3.SourceCodeCredentialLister
+	sourceCodeCredentials      v3.SourceCodeCredentialInterface
 
 	DialerFactory dialer.Factory
 }
@@ -129,8 +131,10 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 	pipelineExecutions := cluster.Management.Project.PipelineExecutions("")
 	pipelineExecutionLister := pipelineExecutions.Controller().Lister()
 	pipelineSettingLister := cluster.Management.Project.PipelineSettings("").Controller().Lister()
-	sourceCodeCredentialLister := cluster.Management.Project.SourceCodeCredentials("").Controller().Lister()
-	notifierLister := cluster.Management.Management.Notifiers("").Controller().Lister()
+	sourceCodeCredentials := cluster.Management.Project.SourceCodeCredentials("")
+	sourceCodeCredentialLister := sourceCodeCredentials.Controller().Lister()
+	notifiers := cluster.Management.Management.Notifiers("")
+	notifierLister := notifiers.Controller().Lister()
 	tokenLister := cluster.Management.Management.Tokens("").Controller().Lister()
 
 	pipelineEngine := engine.New(cluster, true)
@@ -160,7 +164,9 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 		pipelineSettingLister:      pipelineSettingLister,
 		pipelineEngine:             pipelineEngine,
 		sourceCodeCredentialLister: sourceCodeCredentialLister,
+		sourceCodeCredentials:      sourceCodeCredentials,
 		notifierLister:             notifierLister,
+		notifiers:                  notifiers,
 		tokenLister:                tokenLister,
 
 		DialerFactory: cluster.Management.Dialer,
@@ -187,8 +193,14 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 
 	pipelineExecutions.AddClusterScopedLifecycle(ctx, pipelineExecutionLifecycle.GetName(), cluster.ClusterName, pipelineExecutionLifecycle)
 
+	storageSyncer := &StorageSyncer{
+		namespaceLister: namespaceLister,
+		pipelineEngine:  pipelineEngine,
+	}
+
 	go stateSyncer.sync(ctx, syncStateInterval)
 	go registryCertSyncer.sync(ctx, checkCertRotateInterval)
+	go storageSyncer.sync(ctx, pruneStorageInterval)
 
 }
 
@@ -212,6 +224,8 @@ func (l *Lifecycle) Sync(obj *v3.PipelineExecution) (runtime.Object, error) {
 		}
 	}
 
+	l.reportCommitStatus(obj)
+
 	//doIfFinish
 	if obj.Labels != nil && obj.Labels[utils.PipelineFinishLabel] == "true" {
 		return l.doFinish(obj)
@@ -247,6 +261,14 @@ func (l *Lifecycle) Sync(obj *v3.PipelineExecution) (runtime.Object, error) {
 	v32.PipelineExecutionConditionInitialized.CreateUnknownIfNotExists(obj)
 	obj.Labels[utils.PipelineFinishLabel] = "false"
 
+	if err := utils.ExpandMatrix(obj); err != nil {
+		obj.Labels[utils.PipelineFinishLabel] = "true"
+		obj.Status.ExecutionState = utils.StateFailed
+		v32.PipelineExecutionConditionInitialized.False(obj)
+		v32.PipelineExecutionConditionInitialized.ReasonAndMessageFromError(obj, err)
+		return obj, nil
+	}
+
 	if err := l.deploy(obj.Spec.ProjectName); err != nil {
 		obj.Labels[utils.PipelineFinishLabel] = "true"
 		obj.Status.ExecutionState = utils.StateFailed
@@ -445,10 +467,18 @@ func (l *Lifecycle) doCleanup(obj *v3.PipelineExecution) error {
 }
 
 func (l *Lifecycle) Remove(obj *v3.PipelineExecution) (runtime.Object, error) {
-	if utils.IsFinishState(obj.Status.ExecutionState) {
-		return obj, nil
+	if !utils.IsFinishState(obj.Status.ExecutionState) {
+		finished, err := l.doFinish(obj)
+		if err != nil {
+			return finished, err
+		}
+		obj = finished
+	}
+	//artifacts that fail to be deleted are removed when pruning the pipeline storage
+	if err := l.pipelineEngine.DeleteExecutionStorage(obj); err != nil {
+		logrus.Warnf("failed to delete the storage of pipeline execution %s: %v", obj.Name, err)
 	}
-	return l.doFinish(obj)
+	return obj, nil
 }
 
 func (l *Lifecycle) shouldNotify(obj *v3.PipelineExecution) (bool, error) {
@@ -494,19 +524,22 @@ func (l *Lifecycle) doNotify(obj *v3.PipelineExecution) (runtime.Object, error)
 	if obj.Spec.PipelineConfig.Notification.Message != "" {
 		message = obj.Spec.PipelineConfig.Notification.Message
 	}
+	repoName := getRepoNameFromURL(obj.Spec.RepositoryURL)
+	title := fmt.Sprintf("Notification From Rancher: Pipeline #%d build for %s repo %s", obj.Spec.Run, repoName, obj.Status.ExecutionState)
 	var g errgroup.Group
 	for i := range toSendRecipients {
 		toSendRecipient := toSendRecipients[i]
 		notifierMessage := &notifiers.Message{
+			Title:   title,
 			Content: message,
 		}
-		if toSendRecipient.Notifier.Spec.SMTPConfig != nil {
-			repoName := getRepoNameFromURL(obj.Spec.RepositoryURL)
-			notifierMessage.Title = fmt.Sprintf("Notification From Rancher: Pipeline #%d build for %s repo %s", obj.Spec.Run, repoName, obj.Status.ExecutionState)
-			notifierMessage.Content = strings.Replace(message, "\n", "<br>\n", -1)
-		}
 		g.Go(func() error {
-			return notifiers.SendMessage(l.ctx, toSendRecipient.Notifier, toSendRecipient.Recipient, notifierMessage, clusterDialer)
+			err := notifiers.SendMessage(l.ctx, l.notifiers, toSendRecipient.Notifier, toSendRecipient.Recipient, notifierMessage, clusterDialer)
+			if reason, ok := notifiers.IsSuppressed(err); ok {
+				logrus.Debugf("Pipeline notification to notifier %s suppressed: %s", toSendRecipient.Notifier.Name, reason)
+				return nil
+			}
+			return err
 		})
 	}
 	return obj, g.Wait()
@@ -538,7 +571,7 @@ func (l *Lifecycle) GetName() string {
 	return "pipeline-execution-controller"
 }
 
-//reconcileRb grant access to pipeline service account inside project namespaces
+//reconcileRb grant access to pipeline service accounts inside project namespaces
 func (l *Lifecycle) reconcileRb(projectName string) error {
 	commonName := utils.GetPipelineCommonName(projectName)
 	_, projectID := ref.Parse(projectName)
@@ -553,8 +586,10 @@ func (l *Lifecycle) reconcileRb(projectName string) error {
 		if len(parts) == 2 && parts[1] == projectID {
 			namespacesInProject = append(namespacesInProject, namespace)
 		} else {
-			if err := l.roleBindings.DeleteNamespaced(namespace.Name, commonName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
-				return err
+			for _, rbName := range []string{commonName, getStepRoleBindingName(commonName)} {
+				if err := l.roleBindings.DeleteNamespaced(namespace.Name, rbName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
 			}
 		}
 	}
@@ -563,6 +598,10 @@ func (l *Lifecycle) reconcileRb(projectName string) error {
 		if _, err := l.roleBindings.Create(rb); err != nil && !apierrors.IsAlreadyExists(err) {
 			return errors.Wrapf(err, "Error create role binding")
 		}
+		stepRb := getStepRoleBindings(namespace.Name, commonName)
+		if _, err := l.roleBindings.Create(stepRb); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "Error create role binding")
+		}
 	}
 
 	clusterRbs := []string{roleCreateNs, projectID + roleEditNsSuffix}
@@ -597,12 +636,7 @@ func defaultNotificationMessage(execution *v3.PipelineExecution) (string, error)
 	} else {
 		logrus.Warnf("cannot parse duration of pipeline execution %s: %v,%v", execution.Name, err1, err2)
 	}
-	buildLink := fmt.Sprintf("%s/p/%s/pipeline/pipelines/%s/run/%d",
-		settings.ServerURL.Get(),
-		execution.Spec.ProjectName,
-		execution.Spec.PipelineName,
-		execution.Spec.Run,
-	)
+	buildLink := getExecutionURL(execution)
 	builtMessage := "Success"
 	if v32.PipelineExecutionConditionBuilt.IsFalse(execution) {
 		builtMessage = v32.PipelineExecutionConditionBuilt.GetMessage(execution)
@@ -630,6 +664,15 @@ func defaultNotificationMessage(execution *v3.PipelineExecution) (string, error)
 	return buf.String(), nil
 }
 
+func getExecutionURL(execution *v3.PipelineExecution) string {
+	return fmt.Sprintf("%s/p/%s/pipeline/pipelines/%s/run/%d",
+		settings.ServerURL.Get(),
+		execution.Spec.ProjectName,
+		execution.Spec.PipelineName,
+		execution.Spec.Run,
+	)
+}
+
 func getRepoNameFromURL(repoURL string) string {
 	reg := regexp.MustCompile(".*/([^/]*?)/([^/]*?).git")
 	match := reg.FindStringSubmatch(repoURL)
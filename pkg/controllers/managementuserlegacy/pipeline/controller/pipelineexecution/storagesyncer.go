@@ -0,0 +1,52 @@
+package pipelineexecution
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/pipeline/engine"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/wrangler/pkg/ticker"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// This Syncer is responsible for evicting expired caches and orphaned
+// artifacts from the pipeline storage of projects
+
+const (
+	pruneStorageInterval = 1 * time.Hour
+)
+
+type StorageSyncer struct {
+	namespaceLister v1.NamespaceLister
+	pipelineEngine  engine.PipelineEngine
+}
+
+func (s *StorageSyncer) sync(ctx context.Context, syncInterval time.Duration) {
+	for range ticker.Context(ctx, syncInterval) {
+		s.pruneStorage()
+	}
+}
+
+func (s *StorageSyncer) pruneStorage() {
+	labelsSearchSet := labels.Set{utils.PipelineNamespaceLabel: "true"}
+	namespaces, err := s.namespaceLister.List("", labels.SelectorFromSet(labelsSearchSet))
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	for _, ns := range namespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		projectName := getProjectID(ns)
+		if projectName == "" {
+			continue
+		}
+		if err := s.pipelineEngine.PruneStorage(projectName); err != nil {
+			logrus.Warnf("failed to prune the pipeline storage of project %s: %v", projectName, err)
+		}
+	}
+}
@@ -83,6 +83,7 @@ type Interface interface {
 	PodSecurityPolicyTemplateProjectBinding() PodSecurityPolicyTemplateProjectBindingController
 	Preference() PreferenceController
 	Principal() PrincipalController
+	PrincipalMappingRule() PrincipalMappingRuleController
 	Project() ProjectController
 	ProjectAlert() ProjectAlertController
 	ProjectAlertGroup() ProjectAlertGroupController
@@ -276,6 +277,9 @@ func (c *version) Preference() PreferenceController {
 func (c *version) Principal() PrincipalController {
 	return NewPrincipalController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "Principal"}, "principals", false, c.controllerFactory)
 }
+func (c *version) PrincipalMappingRule() PrincipalMappingRuleController {
+	return NewPrincipalMappingRuleController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "PrincipalMappingRule"}, "principalmappingrules", false, c.controllerFactory)
+}
 func (c *version) Project() ProjectController {
 	return NewProjectController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "Project"}, "projects", true, c.controllerFactory)
 }
@@ -22,6 +22,7 @@ type Interface interface {
 	PodSecurityPolicyTemplateProjectBindingsGetter
 	ClusterRoleTemplateBindingsGetter
 	ProjectRoleTemplateBindingsGetter
+	PrincipalMappingRulesGetter
 	ClustersGetter
 	ClusterRegistrationTokensGetter
 	CatalogsGetter
@@ -269,6 +270,20 @@ func (c *Client) ProjectRoleTemplateBindings(namespace string) ProjectRoleTempla
 	}
 }
 
+type PrincipalMappingRulesGetter interface {
+	PrincipalMappingRules(namespace string) PrincipalMappingRuleInterface
+}
+
+func (c *Client) PrincipalMappingRules(namespace string) PrincipalMappingRuleInterface {
+	sharedClient := c.clientFactory.ForResourceKind(PrincipalMappingRuleGroupVersionResource, PrincipalMappingRuleGroupVersionKind.Kind, false)
+	objectClient := objectclient.NewObjectClient(namespace, sharedClient, &PrincipalMappingRuleResource, PrincipalMappingRuleGroupVersionKind, principalMappingRuleFactory{})
+	return &principalMappingRuleClient{
+		ns:           namespace,
+		client:       c,
+		objectClient: objectClient,
+	}
+}
+
 type ClustersGetter interface {
 	Clusters(namespace string) ClusterInterface
 }
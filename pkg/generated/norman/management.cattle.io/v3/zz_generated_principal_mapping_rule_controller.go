@@ -0,0 +1,325 @@
+package v3
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/norman/controller"
+	"github.com/rancher/norman/objectclient"
+	"github.com/rancher/norman/resource"
+	"github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	PrincipalMappingRuleGroupVersionKind = schema.GroupVersionKind{
+		Version: Version,
+		Group:   GroupName,
+		Kind:    "PrincipalMappingRule",
+	}
+	PrincipalMappingRuleResource = metav1.APIResource{
+		Name:         "principalmappingrules",
+		SingularName: "principalmappingrule",
+		Namespaced:   false,
+		Kind:         PrincipalMappingRuleGroupVersionKind.Kind,
+	}
+
+	PrincipalMappingRuleGroupVersionResource = schema.GroupVersionResource{
+		Group:    GroupName,
+		Version:  Version,
+		Resource: "principalmappingrules",
+	}
+)
+
+func init() {
+	resource.Put(PrincipalMappingRuleGroupVersionResource)
+}
+
+// Deprecated use v3.PrincipalMappingRule instead
+type PrincipalMappingRule = v3.PrincipalMappingRule
+
+func NewPrincipalMappingRule(namespace, name string, obj v3.PrincipalMappingRule) *v3.PrincipalMappingRule {
+	obj.APIVersion, obj.Kind = PrincipalMappingRuleGroupVersionKind.ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+type PrincipalMappingRuleHandlerFunc func(key string, obj *v3.PrincipalMappingRule) (runtime.Object, error)
+
+type PrincipalMappingRuleChangeHandlerFunc func(obj *v3.PrincipalMappingRule) (runtime.Object, error)
+
+type PrincipalMappingRuleLister interface {
+	List(namespace string, selector labels.Selector) (ret []*v3.PrincipalMappingRule, err error)
+	Get(namespace, name string) (*v3.PrincipalMappingRule, error)
+}
+
+type PrincipalMappingRuleController interface {
+	Generic() controller.GenericController
+	Informer() cache.SharedIndexInformer
+	Lister() PrincipalMappingRuleLister
+	AddHandler(ctx context.Context, name string, handler PrincipalMappingRuleHandlerFunc)
+	AddFeatureHandler(ctx context.Context, enabled func() bool, name string, sync PrincipalMappingRuleHandlerFunc)
+	AddClusterScopedHandler(ctx context.Context, name, clusterName string, handler PrincipalMappingRuleHandlerFunc)
+	AddClusterScopedFeatureHandler(ctx context.Context, enabled func() bool, name, clusterName string, handler PrincipalMappingRuleHandlerFunc)
+	Enqueue(namespace, name string)
+	EnqueueAfter(namespace, name string, after time.Duration)
+}
+
+type PrincipalMappingRuleInterface interface {
+	ObjectClient() *objectclient.ObjectClient
+	Create(*v3.PrincipalMappingRule) (*v3.PrincipalMappingRule, error)
+	GetNamespaced(namespace, name string, opts metav1.GetOptions) (*v3.PrincipalMappingRule, error)
+	Get(name string, opts metav1.GetOptions) (*v3.PrincipalMappingRule, error)
+	Update(*v3.PrincipalMappingRule) (*v3.PrincipalMappingRule, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+	DeleteNamespaced(namespace, name string, options *metav1.DeleteOptions) error
+	List(opts metav1.ListOptions) (*v3.PrincipalMappingRuleList, error)
+	ListNamespaced(namespace string, opts metav1.ListOptions) (*v3.PrincipalMappingRuleList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	DeleteCollection(deleteOpts *metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Controller() PrincipalMappingRuleController
+	AddHandler(ctx context.Context, name string, sync PrincipalMappingRuleHandlerFunc)
+	AddFeatureHandler(ctx context.Context, enabled func() bool, name string, sync PrincipalMappingRuleHandlerFunc)
+	AddLifecycle(ctx context.Context, name string, lifecycle PrincipalMappingRuleLifecycle)
+	AddFeatureLifecycle(ctx context.Context, enabled func() bool, name string, lifecycle PrincipalMappingRuleLifecycle)
+	AddClusterScopedHandler(ctx context.Context, name, clusterName string, sync PrincipalMappingRuleHandlerFunc)
+	AddClusterScopedFeatureHandler(ctx context.Context, enabled func() bool, name, clusterName string, sync PrincipalMappingRuleHandlerFunc)
+	AddClusterScopedLifecycle(ctx context.Context, name, clusterName string, lifecycle PrincipalMappingRuleLifecycle)
+	AddClusterScopedFeatureLifecycle(ctx context.Context, enabled func() bool, name, clusterName string, lifecycle PrincipalMappingRuleLifecycle)
+}
+
+type principalMappingRuleLister struct {
+	ns         string
+	controller *principalMappingRuleController
+}
+
+func (l *principalMappingRuleLister) List(namespace string, selector labels.Selector) (ret []*v3.PrincipalMappingRule, err error) {
+	if namespace == "" {
+		namespace = l.ns
+	}
+	err = cache.ListAllByNamespace(l.controller.Informer().GetIndexer(), namespace, selector, func(obj interface{}) {
+		ret = append(ret, obj.(*v3.PrincipalMappingRule))
+	})
+	return
+}
+
+func (l *principalMappingRuleLister) Get(namespace, name string) (*v3.PrincipalMappingRule, error) {
+	var key string
+	if namespace != "" {
+		key = namespace + "/" + name
+	} else {
+		key = name
+	}
+	obj, exists, err := l.controller.Informer().GetIndexer().GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(schema.GroupResource{
+			Group:    PrincipalMappingRuleGroupVersionKind.Group,
+			Resource: PrincipalMappingRuleGroupVersionResource.Resource,
+		}, key)
+	}
+	return obj.(*v3.PrincipalMappingRule), nil
+}
+
+type principalMappingRuleController struct {
+	ns string
+	controller.GenericController
+}
+
+func (c *principalMappingRuleController) Generic() controller.GenericController {
+	return c.GenericController
+}
+
+func (c *principalMappingRuleController) Lister() PrincipalMappingRuleLister {
+	return &principalMappingRuleLister{
+		ns:         c.ns,
+		controller: c,
+	}
+}
+
+func (c *principalMappingRuleController) AddHandler(ctx context.Context, name string, handler PrincipalMappingRuleHandlerFunc) {
+	c.GenericController.AddHandler(ctx, name, func(key string, obj interface{}) (interface{}, error) {
+		if obj == nil {
+			return handler(key, nil)
+		} else if v, ok := obj.(*v3.PrincipalMappingRule); ok {
+			return handler(key, v)
+		} else {
+			return nil, nil
+		}
+	})
+}
+
+func (c *principalMappingRuleController) AddFeatureHandler(ctx context.Context, enabled func() bool, name string, handler PrincipalMappingRuleHandlerFunc) {
+	c.GenericController.AddHandler(ctx, name, func(key string, obj interface{}) (interface{}, error) {
+		if !enabled() {
+			return nil, nil
+		} else if obj == nil {
+			return handler(key, nil)
+		} else if v, ok := obj.(*v3.PrincipalMappingRule); ok {
+			return handler(key, v)
+		} else {
+			return nil, nil
+		}
+	})
+}
+
+func (c *principalMappingRuleController) AddClusterScopedHandler(ctx context.Context, name, cluster string, handler PrincipalMappingRuleHandlerFunc) {
+	c.GenericController.AddHandler(ctx, name, func(key string, obj interface{}) (interface{}, error) {
+		if obj == nil {
+			return handler(key, nil)
+		} else if v, ok := obj.(*v3.PrincipalMappingRule); ok && controller.ObjectInCluster(cluster, obj) {
+			return handler(key, v)
+		} else {
+			return nil, nil
+		}
+	})
+}
+
+func (c *principalMappingRuleController) AddClusterScopedFeatureHandler(ctx context.Context, enabled func() bool, name, cluster string, handler PrincipalMappingRuleHandlerFunc) {
+	c.GenericController.AddHandler(ctx, name, func(key string, obj interface{}) (interface{}, error) {
+		if !enabled() {
+			return nil, nil
+		} else if obj == nil {
+			return handler(key, nil)
+		} else if v, ok := obj.(*v3.PrincipalMappingRule); ok && controller.ObjectInCluster(cluster, obj) {
+			return handler(key, v)
+		} else {
+			return nil, nil
+		}
+	})
+}
+
+type principalMappingRuleFactory struct {
+}
+
+func (c principalMappingRuleFactory) Object() runtime.Object {
+	return &v3.PrincipalMappingRule{}
+}
+
+func (c principalMappingRuleFactory) List() runtime.Object {
+	return &v3.PrincipalMappingRuleList{}
+}
+
+func (s *principalMappingRuleClient) Controller() PrincipalMappingRuleController {
+	genericController := controller.NewGenericController(s.ns, PrincipalMappingRuleGroupVersionKind.Kind+"Controller",
+		s.client.controllerFactory.ForResourceKind(PrincipalMappingRuleGroupVersionResource, PrincipalMappingRuleGroupVersionKind.Kind, false))
+
+	return &principalMappingRuleController{
+		ns:                s.ns,
+		GenericController: genericController,
+	}
+}
+
+type principalMappingRuleClient struct {
+	client       *Client
+	ns           string
+	objectClient *objectclient.ObjectClient
+	controller   PrincipalMappingRuleController
+}
+
+func (s *principalMappingRuleClient) ObjectClient() *objectclient.ObjectClient {
+	return s.objectClient
+}
+
+func (s *principalMappingRuleClient) Create(o *v3.PrincipalMappingRule) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.Create(o)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) Get(name string, opts metav1.GetOptions) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.Get(name, opts)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) GetNamespaced(namespace, name string, opts metav1.GetOptions) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.GetNamespaced(namespace, name, opts)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) Update(o *v3.PrincipalMappingRule) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.Update(o.Name, o)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) UpdateStatus(o *v3.PrincipalMappingRule) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.UpdateStatus(o.Name, o)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return s.objectClient.Delete(name, options)
+}
+
+func (s *principalMappingRuleClient) DeleteNamespaced(namespace, name string, options *metav1.DeleteOptions) error {
+	return s.objectClient.DeleteNamespaced(namespace, name, options)
+}
+
+func (s *principalMappingRuleClient) List(opts metav1.ListOptions) (*v3.PrincipalMappingRuleList, error) {
+	obj, err := s.objectClient.List(opts)
+	return obj.(*v3.PrincipalMappingRuleList), err
+}
+
+func (s *principalMappingRuleClient) ListNamespaced(namespace string, opts metav1.ListOptions) (*v3.PrincipalMappingRuleList, error) {
+	obj, err := s.objectClient.ListNamespaced(namespace, opts)
+	return obj.(*v3.PrincipalMappingRuleList), err
+}
+
+func (s *principalMappingRuleClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return s.objectClient.Watch(opts)
+}
+
+// Patch applies the patch and returns the patched deployment.
+func (s *principalMappingRuleClient) Patch(o *v3.PrincipalMappingRule, patchType types.PatchType, data []byte, subresources ...string) (*v3.PrincipalMappingRule, error) {
+	obj, err := s.objectClient.Patch(o.Name, o, patchType, data, subresources...)
+	return obj.(*v3.PrincipalMappingRule), err
+}
+
+func (s *principalMappingRuleClient) DeleteCollection(deleteOpts *metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	return s.objectClient.DeleteCollection(deleteOpts, listOpts)
+}
+
+func (s *principalMappingRuleClient) AddHandler(ctx context.Context, name string, sync PrincipalMappingRuleHandlerFunc) {
+	s.Controller().AddHandler(ctx, name, sync)
+}
+
+func (s *principalMappingRuleClient) AddFeatureHandler(ctx context.Context, enabled func() bool, name string, sync PrincipalMappingRuleHandlerFunc) {
+	s.Controller().AddFeatureHandler(ctx, enabled, name, sync)
+}
+
+func (s *principalMappingRuleClient) AddLifecycle(ctx context.Context, name string, lifecycle PrincipalMappingRuleLifecycle) {
+	sync := NewPrincipalMappingRuleLifecycleAdapter(name, false, s, lifecycle)
+	s.Controller().AddHandler(ctx, name, sync)
+}
+
+func (s *principalMappingRuleClient) AddFeatureLifecycle(ctx context.Context, enabled func() bool, name string, lifecycle PrincipalMappingRuleLifecycle) {
+	sync := NewPrincipalMappingRuleLifecycleAdapter(name, false, s, lifecycle)
+	s.Controller().AddFeatureHandler(ctx, enabled, name, sync)
+}
+
+func (s *principalMappingRuleClient) AddClusterScopedHandler(ctx context.Context, name, clusterName string, sync PrincipalMappingRuleHandlerFunc) {
+	s.Controller().AddClusterScopedHandler(ctx, name, clusterName, sync)
+}
+
+func (s *principalMappingRuleClient) AddClusterScopedFeatureHandler(ctx context.Context, enabled func() bool, name, clusterName string, sync PrincipalMappingRuleHandlerFunc) {
+	s.Controller().AddClusterScopedFeatureHandler(ctx, enabled, name, clusterName, sync)
+}
+
+func (s *principalMappingRuleClient) AddClusterScopedLifecycle(ctx context.Context, name, clusterName string, lifecycle PrincipalMappingRuleLifecycle) {
+	sync := NewPrincipalMappingRuleLifecycleAdapter(name+"_"+clusterName, true, s, lifecycle)
+	s.Controller().AddClusterScopedHandler(ctx, name, clusterName, sync)
+}
+
+func (s *principalMappingRuleClient) AddClusterScopedFeatureLifecycle(ctx context.Context, enabled func() bool, name, clusterName string, lifecycle PrincipalMappingRuleLifecycle) {
+	sync := NewPrincipalMappingRuleLifecycleAdapter(name+"_"+clusterName, true, s, lifecycle)
+	s.Controller().AddClusterScopedFeatureHandler(ctx, enabled, name, clusterName, sync)
+}
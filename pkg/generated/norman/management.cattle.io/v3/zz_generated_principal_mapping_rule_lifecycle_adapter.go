@@ -0,0 +1,67 @@
+package v3
+
+import (
+	"github.com/rancher/norman/lifecycle"
+	"github.com/rancher/norman/resource"
+	"github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type PrincipalMappingRuleLifecycle interface {
+	Create(obj *v3.PrincipalMappingRule) (runtime.Object, error)
+	Remove(obj *v3.PrincipalMappingRule) (runtime.Object, error)
+	Updated(obj *v3.PrincipalMappingRule) (runtime.Object, error)
+}
+
+type principalMappingRuleLifecycleAdapter struct {
+	lifecycle PrincipalMappingRuleLifecycle
+}
+
+func (w *principalMappingRuleLifecycleAdapter) HasCreate() bool {
+	o, ok := w.lifecycle.(lifecycle.ObjectLifecycleCondition)
+	return !ok || o.HasCreate()
+}
+
+func (w *principalMappingRuleLifecycleAdapter) HasFinalize() bool {
+	o, ok := w.lifecycle.(lifecycle.ObjectLifecycleCondition)
+	return !ok || o.HasFinalize()
+}
+
+func (w *principalMappingRuleLifecycleAdapter) Create(obj runtime.Object) (runtime.Object, error) {
+	o, err := w.lifecycle.Create(obj.(*v3.PrincipalMappingRule))
+	if o == nil {
+		return nil, err
+	}
+	return o, err
+}
+
+func (w *principalMappingRuleLifecycleAdapter) Finalize(obj runtime.Object) (runtime.Object, error) {
+	o, err := w.lifecycle.Remove(obj.(*v3.PrincipalMappingRule))
+	if o == nil {
+		return nil, err
+	}
+	return o, err
+}
+
+func (w *principalMappingRuleLifecycleAdapter) Updated(obj runtime.Object) (runtime.Object, error) {
+	o, err := w.lifecycle.Updated(obj.(*v3.PrincipalMappingRule))
+	if o == nil {
+		return nil, err
+	}
+	return o, err
+}
+
+func NewPrincipalMappingRuleLifecycleAdapter(name string, clusterScoped bool, client PrincipalMappingRuleInterface, l PrincipalMappingRuleLifecycle) PrincipalMappingRuleHandlerFunc {
+	if clusterScoped {
+		resource.PutClusterScoped(PrincipalMappingRuleGroupVersionResource)
+	}
+	adapter := &principalMappingRuleLifecycleAdapter{lifecycle: l}
+	syncFn := lifecycle.NewObjectLifecycleAdapter(name, clusterScoped, adapter, client.ObjectClient())
+	return func(key string, obj *v3.PrincipalMappingRule) (runtime.Object, error) {
+		newObj, err := syncFn(key, obj)
+		if o, ok := newObj.(runtime.Object); ok {
+			return o, err
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ldapCacheLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "auth",
+			Name:      "ldap_cache_lookups_total",
+			Help:      "Lookups in the caches of ldap auth providers by result, hit or miss",
+		},
+		[]string{"provider", "cache", "result"},
+	)
+
+	ldapOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "auth",
+			Name:      "ldap_operation_duration_seconds",
+			Help:      "Duration of the operations of ldap auth providers against the directory",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"provider", "operation"},
+	)
+)
+
+func RecordLDAPCacheLookup(provider, cache string, hit bool) {
+	if prometheusMetrics {
+		result := "miss"
+		if hit {
+			result = "hit"
+		}
+		ldapCacheLookups.With(
+			prometheus.Labels{
+				"provider": provider,
+				"cache":    cache,
+				"result":   result,
+			}).Inc()
+	}
+}
+
+func ObserveLDAPOperation(provider, operation string, start time.Time) {
+	if prometheusMetrics {
+		ldapOperationDuration.With(
+			prometheus.Labels{
+				"provider":  provider,
+				"operation": operation,
+			}).Observe(time.Since(start).Seconds())
+	}
+}
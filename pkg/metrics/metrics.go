@@ -80,6 +80,10 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	// Cluster Owner
 	prometheus.MustRegister(clusterOwner)
 
+	// LDAP auth providers
+	prometheus.MustRegister(ldapCacheLookups)
+	prometheus.MustRegister(ldapOperationDuration)
+
 	gc := metricGarbageCollector{
 		clusterLister:  scaledContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:     scaledContext.Management.Nodes("").Controller().Lister(),
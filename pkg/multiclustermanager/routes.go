@@ -16,10 +16,12 @@
 	"github.com/rancher/rancher/pkg/api/norman/customization/oci"
 	"github.com/rancher/rancher/pkg/api/norman/customization/vsphere"
 	managementapi "github.com/rancher/rancher/pkg/api/norman/server"
+	"github.com/rancher/rancher/pkg/auth/device"
 	"github.com/rancher/rancher/pkg/auth/providers/publicapi"
 	"github.com/rancher/rancher/pkg/auth/providers/saml"
 	"github.com/rancher/rancher/pkg/auth/requests"
 	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/scim"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	"github.com/rancher/rancher/pkg/auth/webhook"
 	"github.com/rancher/rancher/pkg/channelserver"
@@ -61,6 +63,8 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 		return nil, err
 	}
 
+	deviceAuth := device.NewHandler(ctx, scaledContext)
+
 	metaProxy, err := httpproxy.NewProxy("/proxy/", whitelist.Proxy.Get, scaledContext)
 	if err != nil {
 		return nil, err
@@ -85,6 +89,9 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 		unauthed.PathPrefix("/hooks").Handler(hooks.New(scaledContext))
 	}
 	unauthed.PathPrefix("/v1-{prefix}-release/release").Handler(channelserver.NewProxy(ctx))
+	unauthed.Path("/v1-device").MatcherFunc(onlyGet).HandlerFunc(deviceAuth.Page)
+	unauthed.Path("/v1-device/code").Methods(http.MethodPost).HandlerFunc(deviceAuth.DeviceCode)
+	unauthed.Path("/v1-device/token").Methods(http.MethodPost).HandlerFunc(deviceAuth.Token)
 	unauthed.PathPrefix("/v1-saml").Handler(saml.AuthHandler())
 	unauthed.PathPrefix("/v3-public").Handler(publicAPI)
 
@@ -105,10 +112,12 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 	authed.Path("/meta/gkeZones").Handler(capabilities.NewGKEZonesHandler())
 	authed.Path("/meta/oci/{resource}").Handler(oci.NewOCIHandler(scaledContext))
 	authed.Path("/meta/vsphere/{field}").Handler(vsphere.NewVsphereHandler(scaledContext))
+	authed.Path("/v1-device/verify").Methods(http.MethodGet, http.MethodPost).HandlerFunc(deviceAuth.Verify)
 	authed.Path("/v3/tokenreview").Methods(http.MethodPost).Handler(&webhook.TokenReviewer{})
 	authed.PathPrefix("/k8s/clusters/").Handler(k8sProxy)
 	authed.PathPrefix("/meta/proxy").Handler(metaProxy)
 	authed.PathPrefix("/metrics").Handler(metrics.NewMetricsHandler(scaledContext, promhttp.Handler()))
+	authed.PathPrefix("/v1-scim/").Handler(scim.NewHandler(scaledContext))
 	authed.PathPrefix("/v1-telemetry").Handler(telemetry.NewProxy())
 	authed.PathPrefix("/v3/identit").Handler(tokenAPI)
 	authed.PathPrefix("/v3/token").Handler(tokenAPI)
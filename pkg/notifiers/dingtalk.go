@@ -0,0 +1,54 @@
+package notifiers
+
+import (
+	"context"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+const dingtalkProviderType = "DINGTALK"
+
+type dingtalkDriver struct{}
+
+func (dingtalkDriver) Name() string {
+	return "dingtalk"
+}
+
+func (dingtalkDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.DingtalkConfig != nil
+}
+
+func (dingtalkDriver) Validate(spec *v32.NotifierSpec) error {
+	if err := validateNoTemplates("dingtalk", spec); err != nil {
+		return err
+	}
+	if err := validateURL("dingtalkConfig.url", spec.DingtalkConfig.URL); err != nil {
+		return err
+	}
+	return validateHTTPClientConfig(spec.DingtalkConfig.HTTPClientConfig)
+}
+
+func (dingtalkDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "Dingtalk setting validated"
+	}
+	return result
+}
+
+func (dingtalkDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	return TestDingtalk(spec.DingtalkConfig.URL, spec.DingtalkConfig.Secret, msg.Content, spec.DingtalkConfig.HTTPClientConfig, dialer)
+}
+
+func (dingtalkDriver) WebhookReceiverProvider(spec *v32.NotifierSpec) *WebhookReceiverProvider {
+	provider := &WebhookReceiverProvider{
+		Type:       dingtalkProviderType,
+		WebHookURL: spec.DingtalkConfig.URL,
+		Secret:     spec.DingtalkConfig.Secret,
+	}
+	if IsHTTPClientConfigSet(spec.DingtalkConfig.HTTPClientConfig) {
+		provider.ProxyURL = spec.DingtalkConfig.HTTPClientConfig.ProxyURL
+	}
+	return provider
+}
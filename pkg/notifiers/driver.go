@@ -0,0 +1,183 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+// NotifierDriver sends messages through one kind of notification channel. The
+// driver owns its section of NotifierSpec: it decides whether a spec is
+// configured for it, validates it, renders messages and delivers them.
+type NotifierDriver interface {
+	// Name returns the notifier type, as used by Recipient.NotifierType.
+	Name() string
+	// IsConfigured reports whether spec holds the configuration of this driver.
+	IsConfigured(spec *v32.NotifierSpec) bool
+	// Validate checks the driver configuration held by spec.
+	Validate(spec *v32.NotifierSpec) error
+	// Render returns a copy of msg adjusted to the channel, filling in
+	// defaults for empty fields.
+	Render(msg *Message) *Message
+	// Send delivers a rendered message to recipient, or to the default
+	// recipient of spec if recipient is empty.
+	Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error
+}
+
+// ReceiverDriver is implemented by drivers that alertmanager can deliver to
+// natively. AddReceiverConfig appends the driver configuration to receiver,
+// rendering messages with the given templates.
+type ReceiverDriver interface {
+	AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error
+}
+
+// WebhookReceiverDriver is implemented by drivers whose alerts are relayed
+// through the rancher webhook-receiver instead of alertmanager itself.
+type WebhookReceiverDriver interface {
+	WebhookReceiverProvider(spec *v32.NotifierSpec) *WebhookReceiverProvider
+}
+
+// WebhookReceiverProvider is the provider entry of the webhook-receiver config.
+type WebhookReceiverProvider struct {
+	Type       string
+	WebHookURL string
+	Secret     string
+	ProxyURL   string
+}
+
+var (
+	driversLock sync.RWMutex
+	drivers     = map[string]NotifierDriver{}
+	driverNames []string
+)
+
+func init() {
+	RegisterDriver(slackDriver{})
+	RegisterDriver(smtpDriver{})
+	RegisterDriver(pagerdutyDriver{})
+	RegisterDriver(wechatDriver{})
+	RegisterDriver(webhookDriver{})
+	RegisterDriver(dingtalkDriver{})
+	RegisterDriver(msTeamsDriver{})
+}
+
+// RegisterDriver makes a notifier driver available under its name. Drivers
+// are matched against a NotifierSpec in the order they were registered.
+func RegisterDriver(driver NotifierDriver) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	name := driver.Name()
+	if _, ok := drivers[name]; ok {
+		panic(fmt.Sprintf("notifier driver %s is already registered", name))
+	}
+	drivers[name] = driver
+	driverNames = append(driverNames, name)
+}
+
+// GetDriver returns the driver registered under name.
+func GetDriver(name string) (NotifierDriver, bool) {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	driver, ok := drivers[name]
+	return driver, ok
+}
+
+// Drivers returns all registered drivers in registration order.
+func Drivers() []NotifierDriver {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	result := make([]NotifierDriver, 0, len(driverNames))
+	for _, name := range driverNames {
+		result = append(result, drivers[name])
+	}
+	return result
+}
+
+// DriverFor returns the driver configured by spec.
+func DriverFor(spec *v32.NotifierSpec) (NotifierDriver, error) {
+	for _, driver := range Drivers() {
+		if driver.IsConfigured(spec) {
+			return driver, nil
+		}
+	}
+	return nil, errors.New("Notifier not configured")
+}
+
+// Validate checks that spec configures exactly one driver and that the
+// configuration of that driver is valid.
+func Validate(spec *v32.NotifierSpec) error {
+	var configured NotifierDriver
+	for _, driver := range Drivers() {
+		if !driver.IsConfigured(spec) {
+			continue
+		}
+		if configured != nil {
+			return fmt.Errorf("notifier can only have one of %s and %s configured", configured.Name(), driver.Name())
+		}
+		configured = driver
+	}
+	if configured == nil {
+		return errors.New("Notifier not configured")
+	}
+	if err := configured.Validate(spec); err != nil {
+		return err
+	}
+	if err := ValidatePolicy(spec.Policy); err != nil {
+		return err
+	}
+	return ValidateTemplates(spec.Templates)
+}
+
+func validateURL(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s", field)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid %s %s, scheme must be http or https", field, value)
+	}
+	return nil
+}
+
+func validateHTTPClientConfig(cfg *v32.HTTPClientConfig) error {
+	if !IsHTTPClientConfigSet(cfg) {
+		return nil
+	}
+	if _, err := url.Parse(cfg.ProxyURL); err != nil {
+		return errors.Wrap(err, "invalid proxyUrl")
+	}
+	return nil
+}
+
+func proxyConfig(cfg *v32.HTTPClientConfig) (*alertconfig.HTTPClientConfig, error) {
+	if !IsHTTPClientConfigSet(cfg) {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.ProxyURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse proxy url %s", cfg.ProxyURL)
+	}
+	return &alertconfig.HTTPClientConfig{
+		ProxyURL: alertconfig.URL{URL: u},
+	}, nil
+}
+
+func copyMessage(msg *Message) *Message {
+	if msg == nil {
+		return &Message{}
+	}
+	result := *msg
+	return &result
+}
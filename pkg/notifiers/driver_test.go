@@ -0,0 +1,89 @@
+package notifiers
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriverFor(t *testing.T) {
+	assert := assert.New(t)
+
+	driver, err := DriverFor(&v32.NotifierSpec{SlackConfig: &v32.SlackConfig{URL: "https://hooks.slack.com/x"}})
+	assert.Nil(err)
+	assert.Equal("slack", driver.Name())
+
+	driver, err = DriverFor(&v32.NotifierSpec{SMTPConfig: &v32.SMTPConfig{}})
+	assert.Nil(err)
+	assert.Equal("email", driver.Name())
+
+	_, err = DriverFor(&v32.NotifierSpec{})
+	assert.NotNil(err)
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		spec    v32.NotifierSpec
+		wantErr bool
+	}
+
+	testCases := []testCase{
+		{
+			spec:    v32.NotifierSpec{WebhookConfig: &v32.WebhookConfig{URL: "http://example.com/hook"}},
+			wantErr: false,
+		},
+		{
+			spec:    v32.NotifierSpec{WebhookConfig: &v32.WebhookConfig{URL: "example.com/hook"}},
+			wantErr: true,
+		},
+		{
+			spec: v32.NotifierSpec{
+				SlackConfig:   &v32.SlackConfig{URL: "https://hooks.slack.com/x"},
+				MSTeamsConfig: &v32.MSTeamsConfig{URL: "https://outlook.office.com/x"},
+			},
+			wantErr: true,
+		},
+		{
+			spec:    v32.NotifierSpec{},
+			wantErr: true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tcase := range testCases {
+		err := Validate(&tcase.spec)
+		assert.Equal(tcase.wantErr, err != nil, "spec %+v, err %v", tcase.spec, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := smtpDriver{}.Render(&Message{Content: "a\nb"})
+	assert.Equal(defaultEmailTitle, msg.Title)
+	assert.Equal("a<br>\nb", msg.Content)
+
+	msg = slackDriver{}.Render(&Message{})
+	assert.Equal("Slack setting validated", msg.Content)
+}
+
+func TestAddReceiverConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	spec := &v32.NotifierSpec{
+		SlackConfig: &v32.SlackConfig{
+			URL:              "https://hooks.slack.com/x",
+			DefaultRecipient: "#alerts",
+			HTTPClientConfig: &v32.HTTPClientConfig{ProxyURL: "http://proxy:3128"},
+		},
+	}
+	receiver := &alertconfig.Receiver{}
+	err := slackDriver{}.AddReceiverConfig(receiver, spec, "#oncall", ReceiverTemplates{}, alertconfig.NotifierConfig{})
+	assert.Nil(err)
+	assert.Len(receiver.SlackConfigs, 1)
+	assert.Equal("#oncall", receiver.SlackConfigs[0].Channel)
+	assert.Equal("proxy:3128", receiver.SlackConfigs[0].HTTPConfig.ProxyURL.Host)
+}
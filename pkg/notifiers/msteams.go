@@ -0,0 +1,53 @@
+package notifiers
+
+import (
+	"context"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+const msTeamsProviderType = "MICROSOFT_TEAMS"
+
+type msTeamsDriver struct{}
+
+func (msTeamsDriver) Name() string {
+	return "msteams"
+}
+
+func (msTeamsDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.MSTeamsConfig != nil
+}
+
+func (msTeamsDriver) Validate(spec *v32.NotifierSpec) error {
+	if err := validateNoTemplates("msteams", spec); err != nil {
+		return err
+	}
+	if err := validateURL("msteamsConfig.url", spec.MSTeamsConfig.URL); err != nil {
+		return err
+	}
+	return validateHTTPClientConfig(spec.MSTeamsConfig.HTTPClientConfig)
+}
+
+func (msTeamsDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "MicrosoftTeams setting validated"
+	}
+	return result
+}
+
+func (msTeamsDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	return TestMicrosoftTeams(spec.MSTeamsConfig.URL, msg.Content, spec.MSTeamsConfig.HTTPClientConfig, dialer)
+}
+
+func (msTeamsDriver) WebhookReceiverProvider(spec *v32.NotifierSpec) *WebhookReceiverProvider {
+	provider := &WebhookReceiverProvider{
+		Type:       msTeamsProviderType,
+		WebHookURL: spec.MSTeamsConfig.URL,
+	}
+	if IsHTTPClientConfigSet(spec.MSTeamsConfig.HTTPClientConfig) {
+		provider.ProxyURL = spec.MSTeamsConfig.HTTPClientConfig.ProxyURL
+	}
+	return provider
+}
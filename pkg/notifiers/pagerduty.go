@@ -0,0 +1,59 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+type pagerdutyDriver struct{}
+
+func (pagerdutyDriver) Name() string {
+	return "pagerduty"
+}
+
+func (pagerdutyDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.PagerdutyConfig != nil
+}
+
+func (pagerdutyDriver) Validate(spec *v32.NotifierSpec) error {
+	if spec.PagerdutyConfig.ServiceKey == "" {
+		return errors.New("pagerdutyConfig.serviceKey is required")
+	}
+	return validateHTTPClientConfig(spec.PagerdutyConfig.HTTPClientConfig)
+}
+
+func (pagerdutyDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "Pagerduty setting validated"
+	}
+	return result
+}
+
+func (pagerdutyDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	return TestPagerduty(spec.PagerdutyConfig.ServiceKey, msg.Content, spec.PagerdutyConfig.HTTPClientConfig, dialer)
+}
+
+func (pagerdutyDriver) AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error {
+	pagerduty := &alertconfig.PagerdutyConfig{
+		NotifierConfig: common,
+		ServiceKey:     alertconfig.Secret(spec.PagerdutyConfig.ServiceKey),
+		Description:    templates.title(),
+	}
+	if recipient != "" {
+		pagerduty.ServiceKey = alertconfig.Secret(recipient)
+	}
+
+	httpConfig, err := proxyConfig(spec.PagerdutyConfig.HTTPClientConfig)
+	if err != nil {
+		return err
+	}
+	pagerduty.HTTPConfig = httpConfig
+
+	receiver.PagerdutyConfigs = append(receiver.PagerdutyConfigs, pagerduty)
+	return nil
+}
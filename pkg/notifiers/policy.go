@@ -0,0 +1,533 @@
+package notifiers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+)
+
+// Reasons a message is suppressed by a notifier policy.
+const (
+	SuppressedRateLimited = "RateLimited"
+	SuppressedDuplicate   = "Duplicate"
+	SuppressedQuietHours  = "QuietHours"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// maxHeldMessages bounds the messages held back during quiet hours, further
+// messages are suppressed.
+const maxHeldMessages = 100
+
+// SuppressedError is returned when a message is not sent because of the
+// notifier policy. Held messages are sent when the quiet hours end.
+type SuppressedError struct {
+	Reason string
+	Held   bool
+}
+
+func (e *SuppressedError) Error() string {
+	if e.Held {
+		return "message held by notifier policy until the end of quiet hours"
+	}
+	return fmt.Sprintf("message suppressed by notifier policy: %s", e.Reason)
+}
+
+// IsSuppressed returns the suppression reason if err is a SuppressedError.
+func IsSuppressed(err error) (string, bool) {
+	if e, ok := err.(*SuppressedError); ok {
+		return e.Reason, true
+	}
+	return "", false
+}
+
+// ValidatePolicy checks the limits and quiet hours of a notifier policy.
+func ValidatePolicy(policy *v32.NotifierPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.RateLimit != nil && (policy.RateLimit.MaxMessages < 1 || policy.RateLimit.WindowSeconds < 1) {
+		return fmt.Errorf("policy.rateLimit.maxMessages and policy.rateLimit.windowSeconds must be positive")
+	}
+	if policy.Deduplication != nil && policy.Deduplication.WindowSeconds < 1 {
+		return fmt.Errorf("policy.deduplication.windowSeconds must be positive")
+	}
+	for i, q := range policy.QuietHours {
+		if _, err := parseQuietHours(q); err != nil {
+			return fmt.Errorf("invalid policy.quietHours[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// IsMuted reports whether now falls into the quiet hours of the policy.
+func IsMuted(policy *v32.NotifierPolicy, now time.Time) bool {
+	if policy == nil {
+		return false
+	}
+	for _, q := range policy.QuietHours {
+		w, err := parseQuietHours(q)
+		if err != nil {
+			continue
+		}
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextQuietHoursTransition returns the next time a quiet hours window of the
+// policy starts or ends after now.
+func NextQuietHoursTransition(policy *v32.NotifierPolicy, now time.Time) (time.Time, bool) {
+	var next time.Time
+	if policy == nil {
+		return next, false
+	}
+	for _, q := range policy.QuietHours {
+		w, err := parseQuietHours(q)
+		if err != nil {
+			continue
+		}
+		if t := w.nextTransition(now); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// MinIntervals returns the smallest group and repeat intervals alertmanager
+// routes may use so that the notifier policy holds for an alert group.
+func MinIntervals(policy *v32.NotifierPolicy) (groupInterval, repeatInterval time.Duration) {
+	if policy == nil {
+		return 0, 0
+	}
+	if policy.RateLimit != nil && policy.RateLimit.MaxMessages > 0 {
+		groupInterval = time.Duration(policy.RateLimit.WindowSeconds) * time.Second / time.Duration(policy.RateLimit.MaxMessages)
+		repeatInterval = groupInterval
+	}
+	if policy.Deduplication != nil {
+		if d := time.Duration(policy.Deduplication.WindowSeconds) * time.Second; d > repeatInterval {
+			repeatInterval = d
+		}
+	}
+	return groupInterval, repeatInterval
+}
+
+// Fingerprint identifies the alerts of a message for deduplication. Messages
+// with alert data are identified by the labels of their alerts, others by
+// their title and content. Released held messages keep the fingerprint they
+// were held under.
+func (m *Message) Fingerprint() string {
+	if m.fingerprint != "" {
+		return m.fingerprint
+	}
+	h := sha256.New()
+	if m.Data == nil {
+		fmt.Fprintf(h, "%s\xff%s", m.Title, m.Content)
+		return fmt.Sprintf("%x", h.Sum(nil))
+	}
+	var alerts []string
+	for _, a := range m.Data.Alerts {
+		var pairs []string
+		for _, p := range a.Labels.SortedPairs() {
+			pairs = append(pairs, p.Name+"\xff"+p.Value)
+		}
+		alerts = append(alerts, strings.Join(pairs, "\xff"))
+	}
+	sort.Strings(alerts)
+	fmt.Fprintf(h, "%s\xfe%s", m.Data.Status, strings.Join(alerts, "\xfe"))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// enforcePolicy decides whether msg may be sent to recipient by the notifier
+// and records the decision on the notifier status. The state is read from the
+// API server and written back with the resource version it was read at, so
+// that concurrent senders on any replica see each other; a message is only
+// sent once its delivery is recorded.
+func enforcePolicy(client v3.NotifierInterface, notifier *v3.Notifier, recipient string, msg *Message, now time.Time) error {
+	// unsaved notifiers that are only tested have no history to check
+	if notifier.Spec.Policy == nil || notifier.Name == "" {
+		return nil
+	}
+	var result error
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		n, err := client.GetNamespaced(notifier.Namespace, notifier.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		n = n.DeepCopy()
+		result = applyPolicy(n, recipient, msg, now)
+		_, err = client.Update(n)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to record the policy state of notifier %s", notifier.Name)
+	}
+	return result
+}
+
+// applyPolicy applies the policy of the notifier to msg, updating the state
+// on the notifier status. It returns a SuppressedError unless msg may be sent.
+func applyPolicy(notifier *v3.Notifier, recipient string, msg *Message, now time.Time) error {
+	policy := notifier.Spec.Policy
+	if policy == nil {
+		return nil
+	}
+	if notifier.Status.PolicyState == nil {
+		notifier.Status.PolicyState = &v32.NotifierPolicyState{}
+	}
+	state := notifier.Status.PolicyState
+	fingerprint := msg.Fingerprint()
+	pruneState(policy, state, now)
+
+	if IsMuted(policy, now) {
+		if policy.Deduplication != nil {
+			for _, h := range state.HeldMessages {
+				if h.Fingerprint == fingerprint {
+					return suppress(notifier, SuppressedDuplicate, now)
+				}
+			}
+		}
+		if len(state.HeldMessages) >= maxHeldMessages {
+			return suppress(notifier, SuppressedQuietHours, now)
+		}
+		state.HeldMessages = append(state.HeldMessages, v32.HeldNotification{
+			Recipient:   recipient,
+			Title:       msg.Title,
+			Content:     msg.Content,
+			Fingerprint: fingerprint,
+			HeldTime:    now.UTC().Format(time.RFC3339),
+		})
+		err := suppress(notifier, SuppressedQuietHours, now)
+		err.Held = true
+		return err
+	}
+
+	if policy.Deduplication != nil {
+		if _, ok := state.Fingerprints[fingerprint]; ok {
+			return suppress(notifier, SuppressedDuplicate, now)
+		}
+	}
+	if policy.RateLimit != nil && len(state.SentTimes) >= policy.RateLimit.MaxMessages {
+		return suppress(notifier, SuppressedRateLimited, now)
+	}
+
+	sent := now.UTC().Format(time.RFC3339Nano)
+	if policy.RateLimit != nil {
+		state.SentTimes = append(state.SentTimes, sent)
+	}
+	if policy.Deduplication != nil {
+		if state.Fingerprints == nil {
+			state.Fingerprints = map[string]string{}
+		}
+		state.Fingerprints[fingerprint] = sent
+	}
+	return nil
+}
+
+// forgetSent takes the message enforcePolicy recorded as sent at now off the
+// notifier state, once its delivery failed, so that it neither counts toward
+// the rate limit nor suppresses its retry as a duplicate.
+func forgetSent(client v3.NotifierInterface, notifier *v3.Notifier, msg *Message, now time.Time) error {
+	if notifier.Spec.Policy == nil || notifier.Name == "" {
+		return nil
+	}
+	sent := now.UTC().Format(time.RFC3339Nano)
+	fingerprint := msg.Fingerprint()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		n, err := client.GetNamespaced(notifier.Namespace, notifier.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		n = n.DeepCopy()
+		if !unrecordSent(n.Status.PolicyState, fingerprint, sent) {
+			return nil
+		}
+		_, err = client.Update(n)
+		return err
+	})
+	return errors.Wrapf(err, "failed to forget the undelivered message of notifier %s", notifier.Name)
+}
+
+// unrecordSent drops the message with fingerprint sent at sent from state,
+// returning whether it was recorded.
+func unrecordSent(state *v32.NotifierPolicyState, fingerprint, sent string) bool {
+	if state == nil {
+		return false
+	}
+	var found bool
+	for i, s := range state.SentTimes {
+		if s == sent {
+			state.SentTimes = append(state.SentTimes[:i:i], state.SentTimes[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if s, ok := state.Fingerprints[fingerprint]; ok && s == sent {
+		delete(state.Fingerprints, fingerprint)
+		found = true
+	}
+	return found
+}
+
+// pruneState drops the sent messages that are out of the policy windows.
+func pruneState(policy *v32.NotifierPolicy, state *v32.NotifierPolicyState, now time.Time) {
+	var sentTimes []string
+	if policy.RateLimit != nil {
+		window := time.Duration(policy.RateLimit.WindowSeconds) * time.Second
+		for _, s := range state.SentTimes {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil && now.Sub(t) < window {
+				sentTimes = append(sentTimes, s)
+			}
+		}
+	}
+	state.SentTimes = sentTimes
+
+	if policy.Deduplication == nil {
+		state.Fingerprints = nil
+		return
+	}
+	window := time.Duration(policy.Deduplication.WindowSeconds) * time.Second
+	for f, s := range state.Fingerprints {
+		if t, err := time.Parse(time.RFC3339Nano, s); err != nil || now.Sub(t) >= window {
+			delete(state.Fingerprints, f)
+		}
+	}
+}
+
+func suppress(notifier *v3.Notifier, reason string, now time.Time) *SuppressedError {
+	countSuppressed(notifier, reason, 1, now)
+	return &SuppressedError{Reason: reason}
+}
+
+func countSuppressed(notifier *v3.Notifier, reason string, count int, now time.Time) {
+	notifier.Status.SuppressedCount += int64(count)
+	notifier.Status.LastSuppressedTime = now.UTC().Format(time.RFC3339)
+	notifier.Status.LastSuppressedReason = reason
+}
+
+// ReleaseHeldMessages sends the messages the notifier held back once its quiet
+// hours are over. The messages are taken off the notifier before they are
+// sent, so that they are released only once, and go through the policy again.
+func ReleaseHeldMessages(ctx context.Context, client v3.NotifierInterface, notifier *v3.Notifier, dialer dialer.Dialer) error {
+	now := time.Now()
+	if notifier.Status.PolicyState == nil || len(notifier.Status.PolicyState.HeldMessages) == 0 || IsMuted(notifier.Spec.Policy, now) {
+		return nil
+	}
+
+	var held []v32.HeldNotification
+	var released *v3.Notifier
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		n, err := client.GetNamespaced(notifier.Namespace, notifier.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if n.Status.PolicyState == nil || len(n.Status.PolicyState.HeldMessages) == 0 {
+			held = nil
+			return nil
+		}
+		n = n.DeepCopy()
+		held = n.Status.PolicyState.HeldMessages
+		n.Status.PolicyState.HeldMessages = nil
+		released, err = client.Update(n)
+		return err
+	})
+	if err != nil || len(held) == 0 {
+		return err
+	}
+
+	var errs []error
+	for _, h := range held {
+		msg := &Message{Title: h.Title, Content: h.Content, fingerprint: h.Fingerprint}
+		if err := SendMessage(ctx, client, released, h.Recipient, msg, dialer); err != nil {
+			if _, ok := IsSuppressed(err); !ok {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// RecordMutedAlerts counts the alerts alertmanager held back for the notifier
+// during its quiet hours. Fingerprints are the alertmanager fingerprints of
+// the alerts currently routed to the notifier; each alert is counted once per
+// quiet hours window.
+func RecordMutedAlerts(client v3.NotifierInterface, notifier *v3.Notifier, fingerprints []string, now time.Time) error {
+	if !needsMutedAlertsUpdate(notifier, fingerprints, now) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		n, err := client.GetNamespaced(notifier.Namespace, notifier.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !needsMutedAlertsUpdate(n, fingerprints, now) {
+			return nil
+		}
+		n = n.DeepCopy()
+		if n.Status.PolicyState == nil {
+			n.Status.PolicyState = &v32.NotifierPolicyState{}
+		}
+		state := n.Status.PolicyState
+		if !IsMuted(n.Spec.Policy, now) {
+			state.MutedAlerts = nil
+		} else {
+			added := newFingerprints(state.MutedAlerts, fingerprints)
+			state.MutedAlerts = append(state.MutedAlerts, added...)
+			countSuppressed(n, SuppressedQuietHours, len(added), now)
+		}
+		_, err = client.Update(n)
+		return err
+	})
+}
+
+func needsMutedAlertsUpdate(notifier *v3.Notifier, fingerprints []string, now time.Time) bool {
+	var muted []string
+	if notifier.Status.PolicyState != nil {
+		muted = notifier.Status.PolicyState.MutedAlerts
+	}
+	if !IsMuted(notifier.Spec.Policy, now) {
+		return len(muted) > 0
+	}
+	return len(newFingerprints(muted, fingerprints)) > 0
+}
+
+func newFingerprints(known, fingerprints []string) []string {
+	seen := map[string]bool{}
+	for _, f := range known {
+		seen[f] = true
+	}
+	var result []string
+	for _, f := range fingerprints {
+		if !seen[f] {
+			seen[f] = true
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+type quietHoursWindow struct {
+	start, end time.Duration
+	location   *time.Location
+	days       map[time.Weekday]bool
+}
+
+func parseQuietHours(q v32.QuietHours) (*quietHoursWindow, error) {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %v", err)
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return nil, fmt.Errorf("end: %v", err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	timezone := q.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone: %v", err)
+	}
+	w := &quietHoursWindow{
+		start:    start,
+		end:      end,
+		location: location,
+	}
+	if len(q.Days) > 0 {
+		w.days = map[time.Weekday]bool{}
+		for _, d := range q.Days {
+			day, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return nil, fmt.Errorf("unknown day %q, must be one of Mon, Tue, Wed, Thu, Fri, Sat, Sun", d)
+			}
+			w.days[day] = true
+		}
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// occurrences returns the windows starting on the day before, the day of and
+// the day after t, in the window location.
+func (w *quietHoursWindow) occurrences(t time.Time) [][2]time.Time {
+	t = t.In(w.location)
+	var result [][2]time.Time
+	for offset := -1; offset <= 1; offset++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, w.location)
+		if w.days != nil && !w.days[day.Weekday()] {
+			continue
+		}
+		start := day.Add(w.start)
+		end := day.Add(w.end)
+		if w.end < w.start {
+			end = end.AddDate(0, 0, 1)
+		}
+		result = append(result, [2]time.Time{start, end})
+	}
+	return result
+}
+
+func (w *quietHoursWindow) contains(t time.Time) bool {
+	for _, o := range w.occurrences(t) {
+		if !t.Before(o[0]) && t.Before(o[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *quietHoursWindow) nextTransition(t time.Time) time.Time {
+	var next time.Time
+	// a transition is at most a week away when the window is restricted to days
+	for offset := 0; offset <= 8 && next.IsZero(); offset++ {
+		for _, o := range w.occurrences(t.AddDate(0, 0, offset)) {
+			for _, b := range o {
+				if b.After(t) && (next.IsZero() || b.Before(next)) {
+					next = b
+				}
+			}
+		}
+	}
+	return next
+}
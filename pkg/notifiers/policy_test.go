@@ -0,0 +1,326 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	type testCase struct {
+		policy  *v32.NotifierPolicy
+		wantErr bool
+	}
+
+	testCases := []testCase{
+		{
+			policy: nil,
+		},
+		{
+			policy: &v32.NotifierPolicy{
+				RateLimit:  &v32.NotifierRateLimit{MaxMessages: 5, WindowSeconds: 60},
+				QuietHours: []v32.QuietHours{{Start: "22:00", End: "07:00", Timezone: "Europe/Berlin", Days: []string{"Mon", "fri"}}},
+			},
+		},
+		{
+			policy:  &v32.NotifierPolicy{RateLimit: &v32.NotifierRateLimit{MaxMessages: 0, WindowSeconds: 60}},
+			wantErr: true,
+		},
+		{
+			policy:  &v32.NotifierPolicy{QuietHours: []v32.QuietHours{{Start: "25:00", End: "07:00"}}},
+			wantErr: true,
+		},
+		{
+			policy:  &v32.NotifierPolicy{QuietHours: []v32.QuietHours{{Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"}}},
+			wantErr: true,
+		},
+		{
+			policy:  &v32.NotifierPolicy{QuietHours: []v32.QuietHours{{Start: "22:00", End: "07:00", Days: []string{"Funday"}}}},
+			wantErr: true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tcase := range testCases {
+		err := ValidatePolicy(tcase.policy)
+		assert.Equal(tcase.wantErr, err != nil, "policy %+v, err %v", tcase.policy, err)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := &v32.NotifierPolicy{
+		QuietHours: []v32.QuietHours{{Start: "22:00", End: "07:00", Timezone: "UTC", Days: []string{"Fri"}}},
+	}
+
+	// Friday 2021-03-19
+	assert.False(IsMuted(policy, time.Date(2021, 3, 19, 21, 59, 0, 0, time.UTC)))
+	assert.True(IsMuted(policy, time.Date(2021, 3, 19, 22, 0, 0, 0, time.UTC)))
+	assert.True(IsMuted(policy, time.Date(2021, 3, 20, 6, 59, 0, 0, time.UTC)))
+	assert.False(IsMuted(policy, time.Date(2021, 3, 20, 7, 0, 0, 0, time.UTC)))
+	// Saturday night is not muted
+	assert.False(IsMuted(policy, time.Date(2021, 3, 20, 23, 0, 0, 0, time.UTC)))
+
+	next, ok := NextQuietHoursTransition(policy, time.Date(2021, 3, 20, 8, 0, 0, 0, time.UTC))
+	assert.True(ok)
+	assert.Equal(time.Date(2021, 3, 26, 22, 0, 0, 0, time.UTC), next.UTC())
+
+	next, ok = NextQuietHoursTransition(policy, time.Date(2021, 3, 19, 23, 0, 0, 0, time.UTC))
+	assert.True(ok)
+	assert.Equal(time.Date(2021, 3, 20, 7, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestApplyPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	notifier := &v3.Notifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "n-test", Namespace: "local"},
+		Spec: v32.NotifierSpec{
+			Policy: &v32.NotifierPolicy{
+				RateLimit:     &v32.NotifierRateLimit{MaxMessages: 2, WindowSeconds: 60},
+				Deduplication: &v32.NotifierDeduplication{WindowSeconds: 30},
+			},
+		},
+	}
+	now := time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC)
+	allow := func(msg *Message, t time.Time) string {
+		reason, _ := IsSuppressed(applyPolicy(notifier, "", msg, t))
+		return reason
+	}
+
+	assert.Equal("", allow(&Message{Content: "a"}, now))
+	assert.Equal(SuppressedDuplicate, allow(&Message{Content: "a"}, now.Add(10*time.Second)))
+	assert.Equal("", allow(&Message{Content: "b"}, now.Add(20*time.Second)))
+	assert.Equal(SuppressedRateLimited, allow(&Message{Content: "c"}, now.Add(30*time.Second)))
+	assert.Equal("", allow(&Message{Content: "a"}, now.Add(61*time.Second)))
+	assert.Equal(int64(2), notifier.Status.SuppressedCount)
+	assert.Equal(SuppressedRateLimited, notifier.Status.LastSuppressedReason)
+
+	// alerts with the same labels are duplicates regardless of the rendered content
+	notifier.Spec.Policy.RateLimit = nil
+	data := NewTestTemplateData("local", "")
+	assert.Equal("", allow(&Message{Content: "x", Data: data}, now.Add(2*time.Minute)))
+	assert.Equal(SuppressedDuplicate, allow(&Message{Content: "y", Data: data}, now.Add(2*time.Minute)))
+}
+
+func TestApplyPolicyHoldsInQuietHours(t *testing.T) {
+	assert := assert.New(t)
+
+	notifier := &v3.Notifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "n-test", Namespace: "local"},
+		Spec: v32.NotifierSpec{
+			Policy: &v32.NotifierPolicy{
+				Deduplication: &v32.NotifierDeduplication{WindowSeconds: 30},
+				QuietHours:    []v32.QuietHours{{Start: "22:00", End: "07:00", Timezone: "UTC"}},
+			},
+		},
+	}
+	night := time.Date(2021, 3, 19, 23, 0, 0, 0, time.UTC)
+
+	err := applyPolicy(notifier, "#alerts", &Message{Title: "t", Content: "a"}, night)
+	assert.True(err.(*SuppressedError).Held)
+	err = applyPolicy(notifier, "#alerts", &Message{Title: "t", Content: "a"}, night.Add(time.Minute))
+	assert.False(err.(*SuppressedError).Held)
+	assert.Equal(SuppressedDuplicate, err.(*SuppressedError).Reason)
+
+	held := notifier.Status.PolicyState.HeldMessages
+	assert.Len(held, 1)
+	assert.Equal("#alerts", held[0].Recipient)
+	assert.Equal("a", held[0].Content)
+
+	notifier.Status.PolicyState.HeldMessages = nil
+	for i := 0; i < maxHeldMessages; i++ {
+		notifier.Status.PolicyState.HeldMessages = append(notifier.Status.PolicyState.HeldMessages, v32.HeldNotification{})
+	}
+	err = applyPolicy(notifier, "", &Message{Content: "b"}, night)
+	assert.False(err.(*SuppressedError).Held)
+	assert.Equal(SuppressedQuietHours, err.(*SuppressedError).Reason)
+
+	assert.NoError(applyPolicy(notifier, "", &Message{Content: "b"}, night.Add(9*time.Hour)))
+}
+
+func TestEnforcePolicyPersistsState(t *testing.T) {
+	assert := assert.New(t)
+
+	stored := &v3.Notifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "n-test", Namespace: "local", ResourceVersion: "1"},
+		Spec: v32.NotifierSpec{
+			Policy: &v32.NotifierPolicy{RateLimit: &v32.NotifierRateLimit{MaxMessages: 1, WindowSeconds: 60}},
+		},
+	}
+	conflicts := 1
+	client := &fakes.NotifierInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v3.Notifier, error) {
+			return stored, nil
+		},
+		UpdateFunc: func(in *v3.Notifier) (*v3.Notifier, error) {
+			if conflicts > 0 {
+				conflicts--
+				return nil, apierrors.NewConflict(schema.GroupResource{Resource: "notifiers"}, in.Name, fmt.Errorf("conflict"))
+			}
+			stored = in
+			return in, nil
+		},
+	}
+	now := time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC)
+
+	// the cached copy has no state, the live one is used
+	cached := stored.DeepCopy()
+	assert.NoError(enforcePolicy(client, cached, "", &Message{Content: "a"}, now))
+	assert.Len(stored.Status.PolicyState.SentTimes, 1)
+	assert.Len(client.UpdateCalls(), 2)
+
+	reason, ok := IsSuppressed(enforcePolicy(client, cached, "", &Message{Content: "b"}, now))
+	assert.True(ok)
+	assert.Equal(SuppressedRateLimited, reason)
+	assert.Equal(int64(1), stored.Status.SuppressedCount)
+
+	// the message is not sent when its delivery cannot be recorded
+	client.UpdateFunc = func(in *v3.Notifier) (*v3.Notifier, error) {
+		return nil, fmt.Errorf("unavailable")
+	}
+	err := enforcePolicy(client, cached, "", &Message{Content: "c"}, now.Add(time.Hour))
+	assert.Error(err)
+	_, ok = IsSuppressed(err)
+	assert.False(ok)
+}
+
+// newStoredNotifier returns a webhook notifier to the server with policy,
+// stored by the returned client.
+func newStoredNotifier(server *httptest.Server, policy *v32.NotifierPolicy) (*fakes.NotifierInterfaceMock, func() *v3.Notifier) {
+	stored := &v3.Notifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "n-test", Namespace: "local"},
+		Spec: v32.NotifierSpec{
+			WebhookConfig: &v32.WebhookConfig{URL: server.URL},
+			Policy:        policy,
+		},
+	}
+	client := &fakes.NotifierInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v3.Notifier, error) {
+			return stored, nil
+		},
+		UpdateFunc: func(in *v3.Notifier) (*v3.Notifier, error) {
+			stored = in
+			return in, nil
+		},
+	}
+	return client, func() *v3.Notifier { return stored }
+}
+
+func TestSendMessageForgetsFailedDeliveries(t *testing.T) {
+	assert := assert.New(t)
+
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if failing {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client, stored := newStoredNotifier(server, &v32.NotifierPolicy{
+		RateLimit:     &v32.NotifierRateLimit{MaxMessages: 1, WindowSeconds: 60},
+		Deduplication: &v32.NotifierDeduplication{WindowSeconds: 60},
+	})
+	ctx := context.Background()
+
+	err := SendMessage(ctx, client, stored(), "", &Message{Content: "a"}, nil)
+	assert.Error(err)
+	_, ok := IsSuppressed(err)
+	assert.False(ok)
+	assert.Empty(stored().Status.PolicyState.SentTimes, "failed deliveries do not count toward the rate limit")
+	assert.Empty(stored().Status.PolicyState.Fingerprints)
+
+	failing = false
+	assert.NoError(SendMessage(ctx, client, stored(), "", &Message{Content: "a"}, nil), "retries are not duplicates")
+	assert.Len(stored().Status.PolicyState.SentTimes, 1)
+	assert.Len(stored().Status.PolicyState.Fingerprints, 1)
+
+	reason, _ := IsSuppressed(SendMessage(ctx, client, stored(), "", &Message{Content: "a"}, nil))
+	assert.Equal(SuppressedDuplicate, reason)
+}
+
+func TestReleaseHeldMessagesKeepsFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	client, stored := newStoredNotifier(server, &v32.NotifierPolicy{
+		Deduplication: &v32.NotifierDeduplication{WindowSeconds: 3600},
+	})
+	data := NewTestTemplateData("local", "")
+	held := &Message{Title: "t", Content: "held", Data: data}
+	stored().Status.PolicyState = &v32.NotifierPolicyState{
+		HeldMessages: []v32.HeldNotification{{Title: held.Title, Content: held.Content, Fingerprint: held.Fingerprint()}},
+	}
+	ctx := context.Background()
+
+	assert.NoError(ReleaseHeldMessages(ctx, client, stored(), nil))
+	assert.Empty(stored().Status.PolicyState.HeldMessages)
+	assert.Contains(stored().Status.PolicyState.Fingerprints, held.Fingerprint(), "released under the fingerprint of their alerts")
+
+	reason, _ := IsSuppressed(SendMessage(ctx, client, stored(), "", &Message{Title: "t", Content: "again", Data: data}, nil))
+	assert.Equal(SuppressedDuplicate, reason, "the alert firing again is a duplicate of the released message")
+}
+
+func TestRecordMutedAlerts(t *testing.T) {
+	assert := assert.New(t)
+
+	stored := &v3.Notifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "n-test", Namespace: "local"},
+		Spec: v32.NotifierSpec{
+			Policy: &v32.NotifierPolicy{QuietHours: []v32.QuietHours{{Start: "22:00", End: "07:00", Timezone: "UTC"}}},
+		},
+	}
+	client := &fakes.NotifierInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v3.Notifier, error) {
+			return stored, nil
+		},
+		UpdateFunc: func(in *v3.Notifier) (*v3.Notifier, error) {
+			stored = in
+			return in, nil
+		},
+	}
+	night := time.Date(2021, 3, 19, 23, 0, 0, 0, time.UTC)
+
+	assert.NoError(RecordMutedAlerts(client, stored, []string{"f1", "f2"}, night))
+	assert.NoError(RecordMutedAlerts(client, stored, []string{"f1", "f2", "f3"}, night.Add(time.Minute)))
+	assert.Equal(int64(3), stored.Status.SuppressedCount)
+	assert.Equal(SuppressedQuietHours, stored.Status.LastSuppressedReason)
+
+	// alerts still firing are not counted again
+	assert.NoError(RecordMutedAlerts(client, stored, []string{"f1"}, night.Add(2*time.Minute)))
+	assert.Equal(int64(3), stored.Status.SuppressedCount)
+	assert.Len(client.UpdateCalls(), 2)
+
+	assert.NoError(RecordMutedAlerts(client, stored, nil, night.Add(9*time.Hour)))
+	assert.Empty(stored.Status.PolicyState.MutedAlerts)
+	assert.Equal(int64(3), stored.Status.SuppressedCount)
+}
+
+func TestMinIntervals(t *testing.T) {
+	assert := assert.New(t)
+
+	gi, ri := MinIntervals(&v32.NotifierPolicy{
+		RateLimit:     &v32.NotifierRateLimit{MaxMessages: 4, WindowSeconds: 3600},
+		Deduplication: &v32.NotifierDeduplication{WindowSeconds: 7200},
+	})
+	assert.Equal(15*time.Minute, gi)
+	assert.Equal(2*time.Hour, ri)
+
+	gi, ri = MinIntervals(nil)
+	assert.Equal(time.Duration(0), gi)
+	assert.Equal(time.Duration(0), ri)
+}
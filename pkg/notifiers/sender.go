@@ -27,6 +27,7 @@
 	"github.com/prometheus/common/model"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 const contentTypeJSON = "application/json"
@@ -34,6 +35,12 @@
 type Message struct {
 	Title   string
 	Content string
+	// Data is the alert data the notifier templates are rendered with.
+	// Messages without data are sent as they are.
+	Data *TemplateData
+	// fingerprint is the fingerprint a held message was held under, which
+	// it keeps once its data is gone.
+	fingerprint string
 }
 
 type wechatToken struct {
@@ -50,48 +57,31 @@ type dingtalkResponse struct {
 	Errmsg  string `json:"errmsg"`
 }
 
-func SendMessage(ctx context.Context, notifier *v3.Notifier, recipient string, msg *Message, dialer dialer.Dialer) error {
-	if notifier.Spec.SlackConfig != nil {
-		if recipient == "" {
-			recipient = notifier.Spec.SlackConfig.DefaultRecipient
-		}
-		return TestSlack(notifier.Spec.SlackConfig.URL, recipient, msg.Content, notifier.Spec.SlackConfig.HTTPClientConfig, dialer)
+// SendMessage renders msg with the notifier templates and delivers it
+// through the driver configured by the notifier. Messages breaking the
+// notifier policy are not sent and a SuppressedError is returned; the policy
+// state is kept on the notifier through client. Messages that fail to be
+// delivered are taken off the policy state again.
+func SendMessage(ctx context.Context, client v3.NotifierInterface, notifier *v3.Notifier, recipient string, msg *Message, dialer dialer.Dialer) error {
+	driver, err := DriverFor(&notifier.Spec)
+	if err != nil {
+		return err
 	}
-
-	if notifier.Spec.SMTPConfig != nil {
-		s := notifier.Spec.SMTPConfig
-		if recipient == "" {
-			recipient = s.DefaultRecipient
-		}
-		return TestEmail(ctx, s.Host, s.Password, s.Username, int(s.Port), s.TLS, msg.Title, msg.Content, recipient, s.Sender, dialer)
+	msg, err = RenderTemplates(&notifier.Spec, msg)
+	if err != nil {
+		return err
 	}
-
-	if notifier.Spec.PagerdutyConfig != nil {
-		return TestPagerduty(notifier.Spec.PagerdutyConfig.ServiceKey, msg.Content, notifier.Spec.PagerdutyConfig.HTTPClientConfig, dialer)
+	now := time.Now()
+	if err := enforcePolicy(client, notifier, recipient, msg, now); err != nil {
+		return err
 	}
-
-	if notifier.Spec.WechatConfig != nil {
-		s := notifier.Spec.WechatConfig
-		if recipient == "" {
-			recipient = s.DefaultRecipient
+	if err := driver.Send(ctx, &notifier.Spec, recipient, driver.Render(msg), dialer); err != nil {
+		if forgetErr := forgetSent(client, notifier, msg, now); forgetErr != nil {
+			return utilerrors.NewAggregate([]error{err, forgetErr})
 		}
-		return TestWechat(notifier.Spec.WechatConfig.Secret, notifier.Spec.WechatConfig.Agent, notifier.Spec.WechatConfig.Corp, notifier.Spec.WechatConfig.RecipientType,
-			recipient, msg.Content, notifier.Spec.WechatConfig.HTTPClientConfig, dialer)
-	}
-
-	if notifier.Spec.WebhookConfig != nil {
-		return TestWebhook(notifier.Spec.WebhookConfig.URL, msg.Content, notifier.Spec.WebhookConfig.HTTPClientConfig, dialer)
-	}
-
-	if notifier.Spec.DingtalkConfig != nil {
-		return TestDingtalk(notifier.Spec.DingtalkConfig.URL, notifier.Spec.DingtalkConfig.Secret, msg.Content, notifier.Spec.DingtalkConfig.HTTPClientConfig, dialer)
-	}
-
-	if notifier.Spec.MSTeamsConfig != nil {
-		return TestMicrosoftTeams(notifier.Spec.MSTeamsConfig.URL, msg.Content, notifier.Spec.MSTeamsConfig.HTTPClientConfig, dialer)
+		return err
 	}
-
-	return errors.New("Notifier not configured")
+	return nil
 }
 
 func TestPagerduty(key, msg string, cfg *v32.HTTPClientConfig, dialer dialer.Dialer) error {
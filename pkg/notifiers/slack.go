@@ -0,0 +1,65 @@
+package notifiers
+
+import (
+	"context"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+type slackDriver struct{}
+
+func (slackDriver) Name() string {
+	return "slack"
+}
+
+func (slackDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.SlackConfig != nil
+}
+
+func (slackDriver) Validate(spec *v32.NotifierSpec) error {
+	if err := validateURL("slackConfig.url", spec.SlackConfig.URL); err != nil {
+		return err
+	}
+	return validateHTTPClientConfig(spec.SlackConfig.HTTPClientConfig)
+}
+
+func (slackDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "Slack setting validated"
+	}
+	return result
+}
+
+func (slackDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	if recipient == "" {
+		recipient = spec.SlackConfig.DefaultRecipient
+	}
+	return TestSlack(spec.SlackConfig.URL, recipient, msg.Content, spec.SlackConfig.HTTPClientConfig, dialer)
+}
+
+func (slackDriver) AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error {
+	slack := &alertconfig.SlackConfig{
+		NotifierConfig: common,
+		APIURL:         alertconfig.Secret(spec.SlackConfig.URL),
+		Channel:        spec.SlackConfig.DefaultRecipient,
+		Text:           templates.text("slack.text"),
+		Title:          templates.title(),
+		TitleLink:      "",
+		Color:          `{{ if eq (index .Alerts 0).Labels.severity "critical" }}danger{{ else if eq (index .Alerts 0).Labels.severity "warning" }}warning{{ else }}good{{ end }}`,
+	}
+	if recipient != "" {
+		slack.Channel = recipient
+	}
+
+	httpConfig, err := proxyConfig(spec.SlackConfig.HTTPClientConfig)
+	if err != nil {
+		return err
+	}
+	slack.HTTPConfig = httpConfig
+
+	receiver.SlackConfigs = append(receiver.SlackConfigs, slack)
+	return nil
+}
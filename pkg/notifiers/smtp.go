@@ -0,0 +1,85 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+const defaultEmailTitle = "Alert From Rancher"
+
+type smtpDriver struct{}
+
+func (smtpDriver) Name() string {
+	return "email"
+}
+
+func (smtpDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.SMTPConfig != nil
+}
+
+func (smtpDriver) Validate(spec *v32.NotifierSpec) error {
+	s := spec.SMTPConfig
+	if s.Host == "" {
+		return errors.New("smtpConfig.host is required")
+	}
+	if s.Port < 1 || s.Port > 65535 {
+		return errors.New("smtpConfig.port must be between 1 and 65535")
+	}
+	if s.Sender == "" || s.DefaultRecipient == "" {
+		return errors.New("smtpConfig.sender and smtpConfig.defaultRecipient are required")
+	}
+	return nil
+}
+
+// Render turns the message into the HTML body sent by email.
+func (smtpDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Title == "" {
+		result.Title = defaultEmailTitle
+	}
+	if result.Content == "" {
+		result.Content = "Alert Name: Test SMTP setting"
+	}
+	result.Content = strings.Replace(result.Content, "\n", "<br>\n", -1)
+	return result
+}
+
+func (smtpDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	s := spec.SMTPConfig
+	if recipient == "" {
+		recipient = s.DefaultRecipient
+	}
+	requireTLS := s.TLS
+	if requireTLS == nil {
+		requireTLS = new(bool)
+	}
+	return TestEmail(ctx, s.Host, s.Password, s.Username, s.Port, requireTLS, msg.Title, msg.Content, recipient, s.Sender, dialer)
+}
+
+func (smtpDriver) AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error {
+	s := spec.SMTPConfig
+	header := map[string]string{}
+	header["Subject"] = templates.title()
+	email := &alertconfig.EmailConfig{
+		NotifierConfig: common,
+		Smarthost:      s.Host + ":" + strconv.Itoa(s.Port),
+		AuthPassword:   alertconfig.Secret(s.Password),
+		AuthUsername:   s.Username,
+		RequireTLS:     s.TLS,
+		To:             s.DefaultRecipient,
+		Headers:        header,
+		From:           s.Sender,
+		HTML:           templates.text("email.text"),
+	}
+	if recipient != "" {
+		email.To = recipient
+	}
+	receiver.EmailConfigs = append(receiver.EmailConfigs, email)
+	return nil
+}
@@ -0,0 +1,312 @@
+package notifiers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+const (
+	templateTitle   = "title"
+	templateContent = "content"
+)
+
+// templateFuncs is the function set available to notifier templates. It is
+// the same set alertmanager provides, so a template that renders here also
+// renders when alertmanager delivers the alert.
+var templateFuncs = texttemplate.FuncMap{
+	"toUpper": strings.ToUpper,
+	"toLower": strings.ToLower,
+	"title":   strings.Title,
+	"join": func(sep string, s []string) string {
+		return strings.Join(s, sep)
+	},
+	"match": regexp.MatchString,
+	"safeHtml": func(text string) template.HTML {
+		return template.HTML(text)
+	},
+	"reReplaceAll": func(pattern, repl, text string) string {
+		re := regexp.MustCompile(pattern)
+		return re.ReplaceAllString(text, repl)
+	},
+}
+
+// TemplateData is the data notifier templates are executed against. It
+// mirrors the notification data of alertmanager.
+type TemplateData struct {
+	Receiver string
+	Status   string
+	Alerts   TemplateAlerts
+
+	GroupLabels       KV
+	CommonLabels      KV
+	CommonAnnotations KV
+
+	ExternalURL string
+}
+
+// TemplateAlert is a single alert of TemplateData.
+type TemplateAlert struct {
+	Status       string
+	Labels       KV
+	Annotations  KV
+	StartsAt     time.Time
+	EndsAt       time.Time
+	GeneratorURL string
+}
+
+// TemplateAlerts is a list of alerts with helpers to filter them by status.
+type TemplateAlerts []TemplateAlert
+
+func (as TemplateAlerts) Firing() []TemplateAlert {
+	return as.withStatus("firing")
+}
+
+func (as TemplateAlerts) Resolved() []TemplateAlert {
+	return as.withStatus("resolved")
+}
+
+func (as TemplateAlerts) withStatus(status string) []TemplateAlert {
+	var result []TemplateAlert
+	for _, a := range as {
+		if a.Status == status {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// KV is a set of labels or annotations.
+type KV map[string]string
+
+// Pair is a key/value string pair.
+type Pair struct {
+	Name, Value string
+}
+
+// Pairs is a list of key/value string pairs.
+type Pairs []Pair
+
+func (ps Pairs) Names() []string {
+	result := make([]string, 0, len(ps))
+	for _, p := range ps {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+func (ps Pairs) Values() []string {
+	result := make([]string, 0, len(ps))
+	for _, p := range ps {
+		result = append(result, p.Value)
+	}
+	return result
+}
+
+func (kv KV) SortedPairs() Pairs {
+	var result Pairs
+	for _, name := range kv.Names() {
+		result = append(result, Pair{Name: name, Value: kv[name]})
+	}
+	return result
+}
+
+func (kv KV) Remove(keys []string) KV {
+	result := KV{}
+	for k, v := range kv {
+		result[k] = v
+	}
+	for _, k := range keys {
+		delete(result, k)
+	}
+	return result
+}
+
+func (kv KV) Names() []string {
+	result := make([]string, 0, len(kv))
+	for k := range kv {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (kv KV) Values() []string {
+	return kv.SortedPairs().Values()
+}
+
+// NewTestTemplateData returns the data used to render the templates of a
+// notifier when it is tested: a single firing alert carrying msg as its
+// message annotation.
+func NewTestTemplateData(clusterName, msg string) *TemplateData {
+	labels := KV{
+		"alert_name":   "Test notification",
+		"alert_type":   "test",
+		"severity":     "info",
+		"cluster_name": clusterName,
+		"group_id":     "test",
+		"rule_id":      "test",
+	}
+	annotations := KV{
+		"message": msg,
+	}
+	return &TemplateData{
+		Receiver: "test",
+		Status:   "firing",
+		Alerts: TemplateAlerts{
+			{
+				Status:      "firing",
+				Labels:      labels,
+				Annotations: annotations,
+				StartsAt:    time.Now(),
+			},
+		},
+		GroupLabels:       KV{"group_id": "test"},
+		CommonLabels:      labels,
+		CommonAnnotations: annotations,
+	}
+}
+
+// ValidateTemplates parses the notifier templates and executes them against
+// test data, so that unknown fields and functions are reported up front.
+func ValidateTemplates(templates *v32.NotifierTemplates) error {
+	if templates == nil {
+		return nil
+	}
+	data := NewTestTemplateData("", "")
+	if _, err := executeTemplate(templateTitle, templates.Title, data); err != nil {
+		return err
+	}
+	_, err := executeTemplate(templateContent, templates.Content, data)
+	return err
+}
+
+// validateNoTemplates rejects templates for drivers that alertmanager delivers
+// alerts to without a rendered message, as the templates would only apply to
+// the messages sent by Rancher itself.
+func validateNoTemplates(driverName string, spec *v32.NotifierSpec) error {
+	if spec.Templates == nil || (spec.Templates.Title == "" && spec.Templates.Content == "") {
+		return nil
+	}
+	return fmt.Errorf("templates are not supported by %s notifiers, alerts are delivered to them without a rendered message", driverName)
+}
+
+// RenderTemplates returns a copy of msg with its title and content replaced
+// by the notifier templates executed against msg.Data. Messages without data
+// and notifiers without templates are returned unchanged.
+func RenderTemplates(spec *v32.NotifierSpec, msg *Message) (*Message, error) {
+	result := copyMessage(msg)
+	if spec.Templates == nil || result.Data == nil {
+		return result, nil
+	}
+	if spec.Templates.Title != "" {
+		title, err := executeTemplate(templateTitle, spec.Templates.Title, result.Data)
+		if err != nil {
+			return nil, err
+		}
+		result.Title = title
+	}
+	if spec.Templates.Content != "" {
+		content, err := executeTemplate(templateContent, spec.Templates.Content, result.Data)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = content
+	}
+	return result, nil
+}
+
+func parseTemplate(name, text string) (*texttemplate.Template, error) {
+	t, err := texttemplate.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid templates.%s: %v", name, err)
+	}
+	if len(t.Templates()) > 1 {
+		return nil, fmt.Errorf("invalid templates.%s: nested template definitions are not allowed", name)
+	}
+	return t, nil
+}
+
+func executeTemplate(name, text string, data *TemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	t, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid templates.%s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// ReceiverTemplates names the alertmanager templates a receiver renders its
+// title and text with. Empty names fall back to the driver defaults.
+type ReceiverTemplates struct {
+	Title string
+	Text  string
+}
+
+// ReceiverTemplatesFor returns the alertmanager template names defined by
+// TemplateDefinitions for the notifier.
+func ReceiverTemplatesFor(notifierName string, spec *v32.NotifierSpec) ReceiverTemplates {
+	var result ReceiverTemplates
+	if spec.Templates == nil || ValidateTemplates(spec.Templates) != nil {
+		return result
+	}
+	if spec.Templates.Title != "" {
+		result.Title = alertmanagerTemplateName(notifierName, templateTitle)
+	}
+	if spec.Templates.Content != "" {
+		result.Text = alertmanagerTemplateName(notifierName, templateContent)
+	}
+	return result
+}
+
+// TemplateDefinitions returns the alertmanager template definitions of the
+// notifier templates, to be appended to the alertmanager template file.
+// Invalid templates are left out, so they cannot break the whole file.
+func TemplateDefinitions(notifierName string, spec *v32.NotifierSpec) string {
+	if spec.Templates == nil || ValidateTemplates(spec.Templates) != nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	for _, t := range []struct{ name, text string }{
+		{templateTitle, spec.Templates.Title},
+		{templateContent, spec.Templates.Content},
+	} {
+		if t.text == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n{{ define %q }}%s{{ end }}\n", alertmanagerTemplateName(notifierName, t.name), t.text)
+	}
+	return buf.String()
+}
+
+func (t ReceiverTemplates) title() string {
+	return templateRef(t.Title, "rancher.title")
+}
+
+func (t ReceiverTemplates) text(defaultName string) string {
+	return templateRef(t.Text, defaultName)
+}
+
+func templateRef(name, defaultName string) string {
+	if name == "" {
+		name = defaultName
+	}
+	return fmt.Sprintf("{{ template %q . }}", name)
+}
+
+func alertmanagerTemplateName(notifierName, field string) string {
+	return fmt.Sprintf("notifier.%s.%s", notifierName, field)
+}
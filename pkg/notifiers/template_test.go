@@ -0,0 +1,115 @@
+package notifiers
+
+import (
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	type testCase struct {
+		templates *v32.NotifierTemplates
+		wantErr   string
+	}
+
+	testCases := []testCase{
+		{
+			templates: nil,
+		},
+		{
+			templates: &v32.NotifierTemplates{
+				Title:   `[{{ .CommonLabels.severity | toUpper }}] {{ .CommonLabels.alert_name }}`,
+				Content: `{{ range .Alerts.Firing }}{{ .Labels.cluster_name }} {{ .StartsAt.Format "15:04" }}{{ end }}`,
+			},
+		},
+		{
+			templates: &v32.NotifierTemplates{Title: `{{ .CommonLabels.severity `},
+			wantErr:   "invalid templates.title",
+		},
+		{
+			templates: &v32.NotifierTemplates{Content: `{{ .Severity }}`},
+			wantErr:   "can't evaluate field Severity",
+		},
+		{
+			templates: &v32.NotifierTemplates{Content: `{{ exec "ls" }}`},
+			wantErr:   `function "exec" not defined`,
+		},
+		{
+			templates: &v32.NotifierTemplates{Content: `{{ define "x" }}x{{ end }}`},
+			wantErr:   "nested template definitions are not allowed",
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tcase := range testCases {
+		err := ValidateTemplates(tcase.templates)
+		if tcase.wantErr == "" {
+			assert.Nil(err)
+			continue
+		}
+		if assert.NotNil(err) {
+			assert.Contains(err.Error(), tcase.wantErr)
+		}
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	assert := assert.New(t)
+
+	spec := &v32.NotifierSpec{
+		Templates: &v32.NotifierTemplates{
+			Title:   `{{ .CommonLabels.alert_name }} in {{ .CommonLabels.cluster_name }}`,
+			Content: `{{ .CommonAnnotations.message }} ({{ .CommonLabels.severity }})`,
+		},
+	}
+
+	msg, err := RenderTemplates(spec, &Message{Content: "hello", Data: NewTestTemplateData("local", "hello")})
+	assert.Nil(err)
+	assert.Equal("Test notification in local", msg.Title)
+	assert.Equal("hello (info)", msg.Content)
+
+	msg, err = RenderTemplates(spec, &Message{Content: "hello"})
+	assert.Nil(err)
+	assert.Equal("hello", msg.Content)
+}
+
+func TestTemplateDefinitions(t *testing.T) {
+	assert := assert.New(t)
+
+	spec := &v32.NotifierSpec{
+		Templates: &v32.NotifierTemplates{Content: `{{ .Status }}`},
+	}
+
+	definitions := TemplateDefinitions("n-abc", spec)
+	assert.True(strings.Contains(definitions, `{{ define "notifier.n-abc.content" }}{{ .Status }}{{ end }}`))
+
+	templates := ReceiverTemplatesFor("n-abc", spec)
+	assert.Equal(`{{ template "rancher.title" . }}`, templates.title())
+	assert.Equal(`{{ template "notifier.n-abc.content" . }}`, templates.text("slack.text"))
+
+	spec.Templates.Content = `{{ .Unknown }}`
+	assert.Equal("", TemplateDefinitions("n-abc", spec))
+	assert.Equal(ReceiverTemplates{}, ReceiverTemplatesFor("n-abc", spec))
+}
+
+func TestValidateTemplatesPerDriver(t *testing.T) {
+	assert := assert.New(t)
+	templates := &v32.NotifierTemplates{Content: `{{ .Status }}`}
+
+	assert.Nil(Validate(&v32.NotifierSpec{
+		SlackConfig: &v32.SlackConfig{URL: "https://hooks.slack.com/x", DefaultRecipient: "#alerts"},
+		Templates:   templates,
+	}))
+	for _, spec := range []*v32.NotifierSpec{
+		{WebhookConfig: &v32.WebhookConfig{URL: "https://example.com"}},
+		{MSTeamsConfig: &v32.MSTeamsConfig{URL: "https://example.com"}},
+		{DingtalkConfig: &v32.DingtalkConfig{URL: "https://example.com"}},
+	} {
+		assert.Nil(Validate(spec))
+		spec.Templates = templates
+		assert.NotNil(Validate(spec), "alerts to the driver cannot be rendered")
+	}
+}
@@ -0,0 +1,60 @@
+package notifiers
+
+import (
+	"context"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+type webhookDriver struct{}
+
+func (webhookDriver) Name() string {
+	return "webhook"
+}
+
+func (webhookDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.WebhookConfig != nil
+}
+
+func (webhookDriver) Validate(spec *v32.NotifierSpec) error {
+	if err := validateNoTemplates("webhook", spec); err != nil {
+		return err
+	}
+	if err := validateURL("webhookConfig.url", spec.WebhookConfig.URL); err != nil {
+		return err
+	}
+	return validateHTTPClientConfig(spec.WebhookConfig.HTTPClientConfig)
+}
+
+func (webhookDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "Webhook setting validated"
+	}
+	return result
+}
+
+func (webhookDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	return TestWebhook(spec.WebhookConfig.URL, msg.Content, spec.WebhookConfig.HTTPClientConfig, dialer)
+}
+
+func (webhookDriver) AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error {
+	webhook := &alertconfig.WebhookConfig{
+		NotifierConfig: common,
+		URL:            spec.WebhookConfig.URL,
+	}
+	if recipient != "" {
+		webhook.URL = recipient
+	}
+
+	httpConfig, err := proxyConfig(spec.WebhookConfig.HTTPClientConfig)
+	if err != nil {
+		return err
+	}
+	webhook.HTTPConfig = httpConfig
+
+	receiver.WebhookConfigs = append(receiver.WebhookConfigs, webhook)
+	return nil
+}
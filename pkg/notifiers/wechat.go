@@ -0,0 +1,82 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/managementuserlegacy/alert/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+)
+
+type wechatDriver struct{}
+
+func (wechatDriver) Name() string {
+	return "wechat"
+}
+
+func (wechatDriver) IsConfigured(spec *v32.NotifierSpec) bool {
+	return spec.WechatConfig != nil
+}
+
+func (wechatDriver) Validate(spec *v32.NotifierSpec) error {
+	s := spec.WechatConfig
+	if s.Secret == "" || s.Agent == "" || s.Corp == "" {
+		return errors.New("wechatConfig.secret, wechatConfig.agent and wechatConfig.corp are required")
+	}
+	switch s.RecipientType {
+	case "", "tag", "party", "user":
+	default:
+		return errors.New("wechatConfig.recipientType must be one of tag, party or user")
+	}
+	return validateHTTPClientConfig(s.HTTPClientConfig)
+}
+
+func (wechatDriver) Render(msg *Message) *Message {
+	result := copyMessage(msg)
+	if result.Content == "" {
+		result.Content = "Wechat setting validated"
+	}
+	return result
+}
+
+func (wechatDriver) Send(ctx context.Context, spec *v32.NotifierSpec, recipient string, msg *Message, dialer dialer.Dialer) error {
+	s := spec.WechatConfig
+	if recipient == "" {
+		recipient = s.DefaultRecipient
+	}
+	return TestWechat(s.Secret, s.Agent, s.Corp, s.RecipientType, recipient, msg.Content, s.HTTPClientConfig, dialer)
+}
+
+func (wechatDriver) AddReceiverConfig(receiver *alertconfig.Receiver, spec *v32.NotifierSpec, recipient string, templates ReceiverTemplates, common alertconfig.NotifierConfig) error {
+	s := spec.WechatConfig
+	wechat := &alertconfig.WechatConfig{
+		NotifierConfig: common,
+		APISecret:      alertconfig.Secret(s.Secret),
+		AgentID:        s.Agent,
+		CorpID:         s.Corp,
+		Message:        templates.text("wechat.text"),
+	}
+
+	if recipient == "" {
+		recipient = s.DefaultRecipient
+	}
+
+	switch s.RecipientType {
+	case "tag":
+		wechat.ToTag = recipient
+	case "user":
+		wechat.ToUser = recipient
+	default:
+		wechat.ToParty = recipient
+	}
+
+	httpConfig, err := proxyConfig(s.HTTPClientConfig)
+	if err != nil {
+		return err
+	}
+	wechat.HTTPConfig = httpConfig
+
+	receiver.WechatConfigs = append(receiver.WechatConfigs, wechat)
+	return nil
+}
@@ -0,0 +1,250 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v33 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	images "github.com/rancher/rancher/pkg/image"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/rancher/pkg/settings"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+// GetStepContainer returns the container running a step of an execution. The
+// container idles, it is up to the engine to run the step command in it.
+func GetStepContainer(execution *v3.PipelineExecution, opts *ExecuteOptions, stageOrdinal int, stepOrdinal int) (v1.Container, error) {
+	stage := execution.Spec.PipelineConfig.Stages[stageOrdinal]
+	step := &stage.Steps[stepOrdinal]
+
+	container := v1.Container{
+		Name:    fmt.Sprintf("step-%d-%d", stageOrdinal, stepOrdinal),
+		TTY:     true,
+		Command: []string{"cat"},
+		Env:     []v1.EnvVar{},
+	}
+	if step.SourceCodeConfig != nil {
+		if err := configCloneStepContainer(&container, step); err != nil {
+			return container, err
+		}
+	} else if step.RunScriptConfig != nil {
+		configRunScriptStepContainer(&container, step)
+	} else if step.PublishImageConfig != nil {
+		configPublishStepContainer(execution, &container, step)
+	} else if step.ApplyYamlConfig != nil {
+		if err := configApplyYamlStepContainer(execution, &container, step, stageOrdinal); err != nil {
+			return container, err
+		}
+	} else if step.PublishCatalogConfig != nil {
+		if err := configPublishCatalogContainer(opts, &container, step); err != nil {
+			return container, err
+		}
+	} else if step.ApplyAppConfig != nil {
+		if err := configApplyAppContainer(&container, step); err != nil {
+			return container, err
+		}
+	}
+
+	//common step configurations
+	for k, v := range utils.GetEnvVarMap(execution) {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	for k, v := range step.Env {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	if execution.Spec.Event != utils.WebhookEventPullRequest {
+		//expose no secrets on pull_request events
+		for _, e := range step.EnvFrom {
+			envName := e.SourceKey
+			if e.TargetKey != "" {
+				envName = e.TargetKey
+			}
+			container.Env = append(container.Env, v1.EnvVar{
+				Name: envName,
+				ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: e.SourceName,
+					},
+					Key: e.SourceKey,
+				}}})
+		}
+	}
+	if step.Privileged {
+		container.SecurityContext = &v1.SecurityContext{Privileged: &step.Privileged}
+	}
+	err := InjectResources(&container, step.CPULimit, step.CPURequest, step.MemoryLimit, step.MemoryRequest)
+	return container, err
+}
+
+func configCloneStepContainer(container *v1.Container, step *v32.Step) error {
+	container.Image = images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.AlpineGit)
+	return InjectResources(container, utils.PipelineToolsCPULimitDefault, utils.PipelineToolsCPURequestDefault, utils.PipelineToolsMemoryLimitDefault, utils.PipelineToolsMemoryRequestDefault)
+}
+
+func configRunScriptStepContainer(container *v1.Container, step *v32.Step) {
+	container.Image = step.RunScriptConfig.Image
+}
+
+func configPublishStepContainer(execution *v3.PipelineExecution, container *v1.Container, step *v32.Step) {
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	config := step.PublishImageConfig
+	m := utils.GetEnvVarMap(execution)
+	config.Tag = SubstituteEnvVar(m, config.Tag)
+
+	registry, repo, tag := utils.SplitImageTag(config.Tag)
+
+	if config.PushRemote {
+		registry = config.Registry
+	} else {
+		_, projectID := ref.Parse(execution.Spec.ProjectName)
+		registry = fmt.Sprintf("%s.%s-pipeline", utils.LocalRegistry, projectID)
+	}
+
+	reg, _ := regexp.Compile("[^a-zA-Z0-9]+")
+	processedRegistry := strings.ToLower(reg.ReplaceAllString(registry, ""))
+	secretName := fmt.Sprintf("%s-%s", execution.Namespace, processedRegistry)
+	secretUserKey := utils.PublishSecretUserKey
+	secretPwKey := utils.PublishSecretPwKey
+	if !config.PushRemote {
+		//use local registry credential
+		secretName = utils.PipelineSecretName
+		secretUserKey = utils.PipelineSecretUserKey
+		secretPwKey = utils.PipelineSecretTokenKey
+	}
+	pluginRepo := fmt.Sprintf("%s/%s", registry, repo)
+	if registry == utils.DefaultRegistry {
+		//the `plugins/docker` image fails when setting DOCKER_REGISTRY to index.docker.io
+		registry = ""
+	}
+
+	container.Image = images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.PluginsDocker)
+	publishEnv := map[string]string{
+		"DOCKER_REGISTRY":   registry,
+		"PLUGIN_REPO":       pluginRepo,
+		"PLUGIN_TAG":        tag,
+		"PLUGIN_DOCKERFILE": config.DockerfilePath,
+		"PLUGIN_CONTEXT":    config.BuildContext,
+	}
+	for k, v := range publishEnv {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	container.Env = append(container.Env, v1.EnvVar{
+		Name: "DOCKER_USERNAME",
+		ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{
+				Name: secretName,
+			},
+			Key: secretUserKey,
+		}}})
+	container.Env = append(container.Env, v1.EnvVar{
+		Name: "DOCKER_PASSWORD",
+		ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{
+				Name: secretName,
+			},
+			Key: secretPwKey,
+		}}})
+	privileged := true
+	container.SecurityContext = &v1.SecurityContext{Privileged: &privileged}
+	container.VolumeMounts = []v1.VolumeMount{
+		{
+			Name:      utils.RegistryCrtVolumeName,
+			MountPath: fmt.Sprintf("/etc/docker/certs.d/docker-registry.%s", ns),
+			ReadOnly:  true,
+		},
+	}
+}
+
+func configApplyYamlStepContainer(execution *v3.PipelineExecution, container *v1.Container, step *v32.Step, stageOrdinal int) error {
+	config := step.ApplyYamlConfig
+	container.Image = images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.KubeApply)
+
+	applyEnv := map[string]string{
+		"YAML_PATH":    config.Path,
+		"YAML_CONTENT": config.Content,
+		"NAMESPACE":    config.Namespace,
+	}
+
+	//for deploy step, get registry & image variable from a previous publish step
+	var registry, imageRepo string
+StageLoop:
+	for i := stageOrdinal; i >= 0; i-- {
+		stage := execution.Spec.PipelineConfig.Stages[i]
+		for j := len(stage.Steps) - 1; j >= 0; j-- {
+			step := stage.Steps[j]
+			if step.PublishImageConfig != nil {
+				config := step.PublishImageConfig
+				if config.PushRemote {
+					registry = step.PublishImageConfig.Registry
+				}
+				_, imageRepo, _ = utils.SplitImageTag(step.PublishImageConfig.Tag)
+				break StageLoop
+			}
+		}
+	}
+
+	applyEnv[utils.EnvRegistry] = registry
+	applyEnv[utils.EnvImageRepo] = imageRepo
+
+	for k, v := range applyEnv {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	return InjectResources(container, utils.PipelineToolsCPULimitDefault, utils.PipelineToolsCPURequestDefault, utils.PipelineToolsMemoryLimitDefault, utils.PipelineToolsMemoryRequestDefault)
+}
+
+func configPublishCatalogContainer(opts *ExecuteOptions, container *v1.Container, step *v32.Step) error {
+	if opts.GitCaCerts != "" {
+		InjectGitCaCertToContainer(container)
+	}
+	config := step.PublishCatalogConfig
+	container.Image = images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.KubeApply)
+	envs := map[string]string{
+		"CATALOG_PATH":          config.Path,
+		"CATALOG_TEMPLATE_NAME": config.CatalogTemplate,
+		"VERSION":               config.Version,
+		"GIT_AUTHOR":            config.GitAuthor,
+		"GIT_EMAIL":             config.GitEmail,
+		"GIT_URL":               config.GitURL,
+		"GIT_BRANCH":            config.GitBranch,
+	}
+	for k, v := range envs {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	var customEnvs []string
+	for k := range step.Env {
+		customEnvs = append(customEnvs, k)
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: "CICD_SUBSTITUTE_VARS", Value: strings.Join(customEnvs, ",")})
+	return InjectResources(container, utils.PipelineToolsCPULimitDefault, utils.PipelineToolsCPURequestDefault, utils.PipelineToolsMemoryLimitDefault, utils.PipelineToolsMemoryRequestDefault)
+}
+
+func configApplyAppContainer(container *v1.Container, step *v32.Step) error {
+	config := step.ApplyAppConfig
+	container.Image = images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.KubeApply)
+	answerBytes, _ := yaml.Marshal(config.Answers)
+	envs := map[string]string{
+		"APP_NAME":              config.Name,
+		"ANSWERS":               string(answerBytes),
+		"CATALOG_TEMPLATE_NAME": config.CatalogTemplate,
+		"VERSION":               config.Version,
+		"TARGET_NAMESPACE":      config.TargetNamespace,
+		"RANCHER_URL":           settings.ServerURL.Get(),
+	}
+	for k, v := range envs {
+		container.Env = append(container.Env, v1.EnvVar{Name: k, Value: v})
+	}
+	container.Env = append(container.Env, v1.EnvVar{
+		Name: utils.PipelineSecretAPITokenKey,
+		ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{
+				Name: utils.PipelineAPIKeySecretName,
+			},
+			Key: utils.PipelineSecretAPITokenKey,
+		}}})
+	return InjectResources(container, utils.PipelineToolsCPULimitDefault, utils.PipelineToolsCPURequestDefault, utils.PipelineToolsMemoryLimitDefault, utils.PipelineToolsMemoryRequestDefault)
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/providers"
+	"github.com/rancher/rancher/pkg/pipeline/remote"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+)
+
+// GetGitCredential returns the username and password used to clone the
+// repository of an execution, refreshing the access token when it expired.
+func GetGitCredential(execution *v3.PipelineExecution, credential *v3.SourceCodeCredential, sourceCodeCredentials v3.SourceCodeCredentialInterface) (string, string, error) {
+	_, projID := ref.Parse(execution.Spec.ProjectName)
+	scpConfig, err := providers.GetSourceCodeProviderConfig(credential.Spec.SourceCodeType, projID)
+	if err != nil {
+		return "", "", err
+	}
+	remote, err := remote.New(scpConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	username := credential.Spec.GitLoginName
+	password := credential.Spec.AccessToken
+	if credential.Spec.GitCloneToken != "" {
+		password = credential.Spec.GitCloneToken
+	}
+	if accessToken, err := utils.EnsureAccessToken(sourceCodeCredentials, remote, credential); err != nil {
+		return "", "", err
+	} else if accessToken != credential.Spec.AccessToken {
+		password = accessToken
+	}
+	return username, password, nil
+}
@@ -0,0 +1,265 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	v33 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	apiv1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	images "github.com/rancher/rancher/pkg/image"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ExecuteOptions are the project pipeline settings applied to the pods of an execution.
+type ExecuteOptions struct {
+	GitCaCerts            string
+	ImagePullSecretNames  []string
+	ExecutorMemoryRequest string
+	ExecutorMemoryLimit   string
+	ExecutorCPURequest    string
+	ExecutorCPULimit      string
+}
+
+func GetExecuteOptions(execution *v3.PipelineExecution, pipelineSettingLister v3.PipelineSettingLister, secretLister apiv1.SecretLister) (*ExecuteOptions, error) {
+	_, projectID := ref.Parse(execution.Spec.ProjectName)
+	cacertSetting, err := pipelineSettingLister.Get(projectID, utils.SettingGitCaCerts)
+	if err != nil {
+		return nil, err
+	}
+	secretNames, err := getImagePullSecretNames(secretLister, execution)
+	if err != nil {
+		return nil, err
+	}
+	memoryRequestSetting, err := pipelineSettingLister.Get(projectID, utils.SettingExecutorMemoryRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuantity(memoryRequestSetting.Value); err != nil {
+		return nil, errors.Wrap(err, "invalid executor memory request config")
+	}
+	memoryLimitSetting, err := pipelineSettingLister.Get(projectID, utils.SettingExecutorMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuantity(memoryLimitSetting.Value); err != nil {
+		return nil, errors.Wrap(err, "invalid executor memory limit config")
+	}
+	cpuRequestSetting, err := pipelineSettingLister.Get(projectID, utils.SettingExecutorCPURequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuantity(cpuRequestSetting.Value); err != nil {
+		return nil, errors.Wrap(err, "invalid executor cpu request config")
+	}
+	cpuLimitSetting, err := pipelineSettingLister.Get(projectID, utils.SettingExecutorCPULimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuantity(cpuLimitSetting.Value); err != nil {
+		return nil, errors.Wrap(err, "invalid executor cpu limit config")
+	}
+	return &ExecuteOptions{
+		GitCaCerts:            cacertSetting.Value,
+		ImagePullSecretNames:  secretNames,
+		ExecutorMemoryRequest: GetPipelineSettingValue(memoryRequestSetting),
+		ExecutorMemoryLimit:   GetPipelineSettingValue(memoryLimitSetting),
+		ExecutorCPURequest:    GetPipelineSettingValue(cpuRequestSetting),
+		ExecutorCPULimit:      GetPipelineSettingValue(cpuLimitSetting),
+	}, nil
+}
+
+func validateQuantity(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := resource.ParseQuantity(value)
+	return err
+}
+
+func getImagePullSecretNames(secretLister apiv1.SecretLister, execution *v3.PipelineExecution) ([]string, error) {
+	result := []string{}
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	secrets, err := secretLister.List(ns, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets {
+		if s.Type == v1.SecretTypeDockerConfigJson {
+			result = append(result, s.Name)
+		}
+	}
+	logrus.Debugf("using imagepullsecrets %v for the build", result)
+	return result, nil
+}
+
+// GetEngineName returns the pipeline engine set for a project.
+func GetEngineName(pipelineSettingLister v3.PipelineSettingLister, projectID string) (string, error) {
+	setting, err := pipelineSettingLister.Get(projectID, utils.SettingEngine)
+	if apierrors.IsNotFound(err) {
+		return utils.SettingEngineDefault, nil
+	} else if err != nil {
+		return "", err
+	}
+	return GetPipelineSettingValue(setting), nil
+}
+
+func GetPipelineSettingValue(setting *v3.PipelineSetting) string {
+	if setting.Value != "" {
+		return setting.Value
+	}
+	return setting.Default
+}
+
+func ConfigImagePullSecrets(pod *v1.Pod, secretNames []string) {
+	var refs []v1.LocalObjectReference
+	for _, secretName := range secretNames {
+		refs = append(refs, v1.LocalObjectReference{
+			Name: secretName,
+		})
+	}
+	pod.Spec.ImagePullSecrets = refs
+}
+
+// InjectGitCaCert writes the git CA certificates to a volume of the pod with an
+// init container, and configures the named container to trust them.
+func InjectGitCaCert(pod *v1.Pod, gitCaCerts string, containerName string) {
+	pod.Spec.InitContainers = []v1.Container{
+		{
+			Name:    "config-crt",
+			Image:   images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.AlpineGit),
+			Command: []string{"sh", "-c", "CERT_PATH=/home/jenkins/certs/ca.crt;printf \"%s\" \"$CA_CERT\" > $CERT_PATH;chown 10000:10000 $CERT_PATH;"},
+			Env: []v1.EnvVar{
+				{
+					Name:  "CA_CERT",
+					Value: gitCaCerts,
+				},
+			},
+			VolumeMounts: []v1.VolumeMount{
+				{
+					Name:      utils.GitCaCertVolumeName,
+					MountPath: utils.GitCaCertPath,
+				},
+			},
+		},
+	}
+	for i, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			InjectGitCaCertToContainer(&pod.Spec.Containers[i])
+			break
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: utils.GitCaCertVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+}
+
+func InjectGitCaCertToContainer(container *v1.Container) {
+	container.Env = append(container.Env, v1.EnvVar{
+		Name:  "GIT_SSL_CAINFO",
+		Value: utils.GitCaCertPath + "/ca.crt",
+	})
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      utils.GitCaCertVolumeName,
+		MountPath: utils.GitCaCertPath,
+	})
+}
+
+func InjectResources(container *v1.Container, cpuLimit string, cpuRequest string, memoryLimit string, memoryRequest string) error {
+	if cpuLimit != "" {
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = v1.ResourceList{}
+		}
+		quantity, err := resource.ParseQuantity(cpuLimit)
+		if err != nil {
+			return errors.Wrapf(err, "invalid CPU limit %q", cpuLimit)
+		}
+
+		container.Resources.Limits[v1.ResourceCPU] = quantity
+	}
+	if cpuRequest != "" {
+		if container.Resources.Requests == nil {
+			container.Resources.Requests = v1.ResourceList{}
+		}
+		quantity, err := resource.ParseQuantity(cpuRequest)
+		if err != nil {
+			return errors.Wrapf(err, "invalid CPU request %q", cpuRequest)
+		}
+
+		container.Resources.Requests[v1.ResourceCPU] = quantity
+	}
+	if memoryLimit != "" {
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = v1.ResourceList{}
+		}
+		quantity, err := resource.ParseQuantity(memoryLimit)
+		if err != nil {
+			return errors.Wrapf(err, "invalid memory limit %q", memoryLimit)
+		}
+
+		container.Resources.Limits[v1.ResourceMemory] = quantity
+	}
+	if memoryRequest != "" {
+		if container.Resources.Requests == nil {
+			container.Resources.Requests = v1.ResourceList{}
+		}
+		quantity, err := resource.ParseQuantity(memoryRequest)
+		if err != nil {
+			return errors.Wrapf(err, "invalid memory request %q", memoryRequest)
+		}
+
+		container.Resources.Requests[v1.ResourceMemory] = quantity
+	}
+	return nil
+}
+
+func ParsePreservedEnvVar(execution *v3.PipelineExecution) {
+	m := utils.GetEnvVarMap(execution)
+	pipelineConfig := execution.Spec.PipelineConfig
+
+	//environment variables substitution in configs
+	for _, stage := range pipelineConfig.Stages {
+		for _, step := range stage.Steps {
+			if step.RunScriptConfig != nil {
+				step.RunScriptConfig.Image = SubstituteEnvVar(m, step.RunScriptConfig.Image)
+			} else if step.PublishImageConfig != nil {
+				step.PublishImageConfig.Tag = SubstituteEnvVar(m, step.PublishImageConfig.Tag)
+			} else if step.ApplyYamlConfig != nil {
+				step.ApplyYamlConfig.Path = SubstituteEnvVar(m, step.ApplyYamlConfig.Path)
+				step.ApplyYamlConfig.Content = SubstituteEnvVar(m, step.ApplyYamlConfig.Content)
+			} else if step.PublishCatalogConfig != nil {
+				step.PublishCatalogConfig.Path = SubstituteEnvVar(m, step.PublishCatalogConfig.Path)
+				step.PublishCatalogConfig.CatalogTemplate = SubstituteEnvVar(m, step.PublishCatalogConfig.CatalogTemplate)
+				step.PublishCatalogConfig.Version = SubstituteEnvVar(m, step.PublishCatalogConfig.Version)
+			} else if step.ApplyAppConfig != nil {
+				step.ApplyAppConfig.CatalogTemplate = SubstituteEnvVar(m, step.ApplyAppConfig.CatalogTemplate)
+				step.ApplyAppConfig.Version = SubstituteEnvVar(m, step.ApplyAppConfig.Version)
+				step.ApplyAppConfig.Name = SubstituteEnvVar(m, step.ApplyAppConfig.Name)
+				step.ApplyAppConfig.TargetNamespace = SubstituteEnvVar(m, step.ApplyAppConfig.TargetNamespace)
+				for k, v := range step.ApplyAppConfig.Answers {
+					step.ApplyAppConfig.Answers[k] = SubstituteEnvVar(m, v)
+				}
+			}
+			for k, v := range step.Env {
+				step.Env[k] = SubstituteEnvVar(m, v)
+			}
+		}
+	}
+}
+
+func SubstituteEnvVar(envvar map[string]string, raw string) string {
+	result := raw
+	for k, v := range envvar {
+		result = strings.Replace(result, "${"+k+"}", v, -1)
+	}
+	return result
+}
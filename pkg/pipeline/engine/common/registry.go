@@ -0,0 +1,98 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/norman/types/convert"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PrepareRegistryCredentials copies the credentials of the registries that
+// publish image steps push to into the pipeline namespace.
+func PrepareRegistryCredentials(execution *v3.PipelineExecution, secrets v1.SecretInterface, managementSecretLister v1.SecretLister) error {
+	for _, stage := range execution.Spec.PipelineConfig.Stages {
+		for _, step := range stage.Steps {
+			if step.PublishImageConfig != nil {
+				//prepare docker credential for publishimage step
+				registry := utils.DefaultRegistry
+				if step.PublishImageConfig.PushRemote && step.PublishImageConfig.Registry != "" {
+					registry = step.PublishImageConfig.Registry
+				} else {
+					_, projectID := ref.Parse(execution.Spec.ProjectName)
+					registry = fmt.Sprintf("%s.%s-pipeline", utils.LocalRegistry, projectID)
+				}
+				if err := prepareRegistryCredential(execution, registry, secrets, managementSecretLister); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func prepareRegistryCredential(execution *v3.PipelineExecution, registry string, secrets v1.SecretInterface, managementSecretLister v1.SecretLister) error {
+	managementSecrets, err := managementSecretLister.List(execution.Namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	username := ""
+	password := ""
+	for _, s := range managementSecrets {
+		if s.Type == "kubernetes.io/dockerconfigjson" {
+			m := map[string]interface{}{}
+			if err := json.Unmarshal(s.Data[".dockerconfigjson"], &m); err != nil {
+				return err
+			}
+			auths := convert.ToMapInterface(m["auths"])
+			for k, v := range auths {
+				if registry != k {
+					//find matching registry credential
+					continue
+				}
+				cred := convert.ToMapInterface(v)
+				username, _ = cred["username"].(string)
+				password, _ = cred["password"].(string)
+			}
+
+		}
+
+	}
+
+	//store dockercredential in pipeline namespace
+	//TODO key-key mapping instead of registry-key mapping
+	reg, _ := regexp.Compile("[^a-zA-Z0-9]+")
+	proceccedRegistry := strings.ToLower(reg.ReplaceAllString(registry, ""))
+
+	secretName := fmt.Sprintf("%s-%s", execution.Namespace, proceccedRegistry)
+	logrus.Debugf("preparing registry credential %s for %s", secretName, registry)
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      secretName,
+		},
+		Data: map[string][]byte{
+			utils.PublishSecretUserKey: []byte(username),
+			utils.PublishSecretPwKey:   []byte(password),
+		},
+	}
+	_, err = secrets.Create(secret)
+	if apierrors.IsAlreadyExists(err) {
+		if _, err := secrets.Update(secret); err != nil {
+			return err
+		}
+		return nil
+	}
+	return err
+}
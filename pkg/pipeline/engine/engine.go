@@ -1,23 +1,35 @@
 package engine
 
 import (
+	"fmt"
+	"io"
+
 	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
 	"github.com/rancher/rancher/pkg/pipeline/engine/jenkins"
+	"github.com/rancher/rancher/pkg/pipeline/engine/kubernetes"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
 	"github.com/rancher/rancher/pkg/types/config"
 )
 
 type PipelineEngine interface {
 	PreCheck(execution *v3.PipelineExecution) (bool, error)
+	SetupMessage(execution *v3.PipelineExecution) string
 	RunPipelineExecution(execution *v3.PipelineExecution) error
 	RerunExecution(execution *v3.PipelineExecution) error
 	StopExecution(execution *v3.PipelineExecution) error
 	GetStepLog(execution *v3.PipelineExecution, stage int, step int) (string, error)
+	GetArtifact(execution *v3.PipelineExecution, stage int, step int, name string) (io.ReadCloser, error)
 	SyncExecution(execution *v3.PipelineExecution) (bool, error)
+	DeleteExecutionStorage(execution *v3.PipelineExecution) error
+	PruneStorage(projectName string) error
 }
 
 func New(cluster *config.UserContext, useCache bool) PipelineEngine {
 	serviceLister := cluster.Core.Services("").Controller().Lister()
-	podLister := cluster.Core.Pods("").Controller().Lister()
+	pods := cluster.Core.Pods("")
+	podLister := pods.Controller().Lister()
 	secrets := cluster.Core.Secrets("")
 	secretLister := secrets.Controller().Lister()
 	managementSecretLister := cluster.Management.Core.Secrets("").Controller().Lister()
@@ -27,7 +39,7 @@ func New(cluster *config.UserContext, useCache bool) PipelineEngine {
 	pipelineSettingLister := cluster.Management.Project.PipelineSettings("").Controller().Lister()
 	dialer := cluster.Management.Dialer
 
-	engine := &jenkins.Engine{
+	jenkinsEngine := &jenkins.Engine{
 		UseCache:                   useCache,
 		ServiceLister:              serviceLister,
 		PodLister:                  podLister,
@@ -42,5 +54,149 @@ func New(cluster *config.UserContext, useCache bool) PipelineEngine {
 		Dialer:      dialer,
 		ClusterName: cluster.ClusterName,
 	}
-	return engine
+	kubernetesEngine := &kubernetes.Engine{
+		UseCache:                   useCache,
+		Pods:                       pods,
+		PodLister:                  podLister,
+		PodLogs:                    cluster.K8sClient.CoreV1(),
+		PersistentVolumeClaims:     cluster.Core.PersistentVolumeClaims(""),
+		ServiceLister:              serviceLister,
+		Secrets:                    secrets,
+		SecretLister:               secretLister,
+		ManagementSecretLister:     managementSecretLister,
+		SourceCodeCredentials:      sourceCodeCredentials,
+		SourceCodeCredentialLister: sourceCodeCredentialLister,
+		PipelineLister:             pipelineLister,
+		PipelineExecutionLister:    cluster.Management.Project.PipelineExecutions("").Controller().Lister(),
+		PipelineSettingLister:      pipelineSettingLister,
+
+		Dialer:      dialer,
+		ClusterName: cluster.ClusterName,
+	}
+	return &engineSelector{
+		pipelineSettingLister: pipelineSettingLister,
+		engines: map[string]PipelineEngine{
+			utils.EngineJenkins:    jenkinsEngine,
+			utils.EngineKubernetes: kubernetesEngine,
+		},
+	}
+}
+
+// engineSelector runs executions with the engine chosen in the pipeline
+// settings of their project. The engine is recorded on the execution when it
+// starts, so changing the setting does not affect running executions.
+type engineSelector struct {
+	pipelineSettingLister v3.PipelineSettingLister
+	engines               map[string]PipelineEngine
+}
+
+func (s *engineSelector) engineName(execution *v3.PipelineExecution) (string, error) {
+	if name := execution.Annotations[utils.PipelineEngineLabel]; name != "" {
+		return name, nil
+	}
+	_, projectID := ref.Parse(execution.Spec.ProjectName)
+	return common.GetEngineName(s.pipelineSettingLister, projectID)
+}
+
+func (s *engineSelector) engine(execution *v3.PipelineExecution) (PipelineEngine, error) {
+	name, err := s.engineName(execution)
+	if err != nil {
+		return nil, err
+	}
+	engine, ok := s.engines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pipeline engine %q", name)
+	}
+	return engine, nil
+}
+
+func (s *engineSelector) PreCheck(execution *v3.PipelineExecution) (bool, error) {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return false, err
+	}
+	return engine.PreCheck(execution)
+}
+
+func (s *engineSelector) SetupMessage(execution *v3.PipelineExecution) string {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return "Setting up the pipeline engine. If it is not deployed, this can take a few minutes."
+	}
+	return engine.SetupMessage(execution)
+}
+
+func (s *engineSelector) RunPipelineExecution(execution *v3.PipelineExecution) error {
+	name, err := s.engineName(execution)
+	if err != nil {
+		return err
+	}
+	engine, err := s.engine(execution)
+	if err != nil {
+		return err
+	}
+	if execution.Annotations == nil {
+		execution.Annotations = map[string]string{}
+	}
+	execution.Annotations[utils.PipelineEngineLabel] = name
+	return engine.RunPipelineExecution(execution)
+}
+
+func (s *engineSelector) RerunExecution(execution *v3.PipelineExecution) error {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return err
+	}
+	return engine.RerunExecution(execution)
+}
+
+func (s *engineSelector) StopExecution(execution *v3.PipelineExecution) error {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return err
+	}
+	return engine.StopExecution(execution)
+}
+
+func (s *engineSelector) GetStepLog(execution *v3.PipelineExecution, stage int, step int) (string, error) {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return "", err
+	}
+	return engine.GetStepLog(execution, stage, step)
+}
+
+func (s *engineSelector) GetArtifact(execution *v3.PipelineExecution, stage int, step int, name string) (io.ReadCloser, error) {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return nil, err
+	}
+	return engine.GetArtifact(execution, stage, step, name)
+}
+
+func (s *engineSelector) SyncExecution(execution *v3.PipelineExecution) (bool, error) {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return false, err
+	}
+	return engine.SyncExecution(execution)
+}
+
+func (s *engineSelector) DeleteExecutionStorage(execution *v3.PipelineExecution) error {
+	engine, err := s.engine(execution)
+	if err != nil {
+		return err
+	}
+	return engine.DeleteExecutionStorage(execution)
+}
+
+// PruneStorage prunes the storage of every engine, since executions of a
+// project may have run with an engine that is no longer set.
+func (s *engineSelector) PruneStorage(projectName string) error {
+	for _, engine := range s.engines {
+		if err := engine.PruneStorage(projectName); err != nil {
+			return err
+		}
+	}
+	return nil
 }
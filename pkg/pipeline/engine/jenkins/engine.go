@@ -5,6 +5,7 @@
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
 	"strconv"
@@ -15,18 +16,15 @@
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
-	"github.com/rancher/norman/types/convert"
 	appsv1 "github.com/rancher/rancher/pkg/generated/norman/apps/v1"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
-	"github.com/rancher/rancher/pkg/pipeline/providers"
-	"github.com/rancher/rancher/pkg/pipeline/remote"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
 	"github.com/rancher/rancher/pkg/pipeline/utils"
 	"github.com/rancher/rancher/pkg/ref"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
@@ -90,6 +88,10 @@ func (j *Engine) PreCheck(execution *v3.PipelineExecution) (bool, error) {
 	return false, nil
 }
 
+func (j *Engine) SetupMessage(execution *v3.PipelineExecution) string {
+	return "Setting up jenkins. If it is not deployed, this can take a few minutes."
+}
+
 func (j *Engine) getJenkinsClient(execution *v3.PipelineExecution) (*Client, error) {
 	url, err := j.getJenkinsURL(execution)
 	if err != nil {
@@ -125,6 +127,9 @@ func (j *Engine) getJenkinsClient(execution *v3.PipelineExecution) (*Client, err
 
 func (j *Engine) RunPipelineExecution(execution *v3.PipelineExecution) error {
 	logrus.Debug("start RunPipelineExecution")
+	if err := utils.ValidEngineConfig(execution.Spec.PipelineConfig, utils.EngineJenkins); err != nil {
+		return err
+	}
 	jobName := getJobName(execution)
 	client, err := j.getJenkinsClient(execution)
 	if err != nil {
@@ -151,7 +156,7 @@ func (j *Engine) RunPipelineExecution(execution *v3.PipelineExecution) error {
 		return err
 	}
 
-	if err := j.preparePipeline(execution); err != nil {
+	if err := common.PrepareRegistryCredentials(execution, j.Secrets, j.ManagementSecretLister); err != nil {
 		return err
 	}
 	if _, err := client.buildJob(jobName, map[string]string{}); err != nil {
@@ -160,83 +165,6 @@ func (j *Engine) RunPipelineExecution(execution *v3.PipelineExecution) error {
 	return nil
 }
 
-func (j *Engine) preparePipeline(execution *v3.PipelineExecution) error {
-	for _, stage := range execution.Spec.PipelineConfig.Stages {
-		for _, step := range stage.Steps {
-			if step.PublishImageConfig != nil {
-				//prepare docker credential for publishimage step
-				registry := utils.DefaultRegistry
-				if step.PublishImageConfig.PushRemote && step.PublishImageConfig.Registry != "" {
-					registry = step.PublishImageConfig.Registry
-				} else {
-					_, projectID := ref.Parse(execution.Spec.ProjectName)
-					registry = fmt.Sprintf("%s.%s-pipeline", utils.LocalRegistry, projectID)
-				}
-				if err := j.prepareRegistryCredential(execution, registry); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	return nil
-}
-
-func (j *Engine) prepareRegistryCredential(execution *v3.PipelineExecution, registry string) error {
-	secrets, err := j.ManagementSecretLister.List(execution.Namespace, labels.Everything())
-	if err != nil {
-		return err
-	}
-	username := ""
-	password := ""
-	for _, s := range secrets {
-		if s.Type == "kubernetes.io/dockerconfigjson" {
-			m := map[string]interface{}{}
-			if err := json.Unmarshal(s.Data[".dockerconfigjson"], &m); err != nil {
-				return err
-			}
-			auths := convert.ToMapInterface(m["auths"])
-			for k, v := range auths {
-				if registry != k {
-					//find matching registry credential
-					continue
-				}
-				cred := convert.ToMapInterface(v)
-				username, _ = cred["username"].(string)
-				password, _ = cred["password"].(string)
-			}
-
-		}
-
-	}
-
-	//store dockercredential in pipeline namespace
-	//TODO key-key mapping instead of registry-key mapping
-	reg, _ := regexp.Compile("[^a-zA-Z0-9]+")
-	proceccedRegistry := strings.ToLower(reg.ReplaceAllString(registry, ""))
-
-	secretName := fmt.Sprintf("%s-%s", execution.Namespace, proceccedRegistry)
-	logrus.Debugf("preparing registry credential %s for %s", secretName, registry)
-	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: ns,
-			Name:      secretName,
-		},
-		Data: map[string][]byte{
-			utils.PublishSecretUserKey: []byte(username),
-			utils.PublishSecretPwKey:   []byte(password),
-		},
-	}
-	_, err = j.Secrets.Create(secret)
-	if apierrors.IsAlreadyExists(err) {
-		if _, err := j.Secrets.Update(secret); err != nil {
-			return err
-		}
-		return nil
-	}
-	return err
-}
-
 func (j *Engine) createPipelineJob(client *Client, execution *v3.PipelineExecution) error {
 	logrus.Debug("create jenkins job for pipeline")
 	converter, err := initJenkinsPipelineConverter(execution, j.PipelineSettingLister, j.SecretLister)
@@ -369,6 +297,15 @@ func (j *Engine) SyncExecution(execution *v3.PipelineExecution) (bool, error) {
 				}
 			} else if (status == "FAILED" || status == "ABORTED") && execution.Status.Stages[stage].Steps[step].State != utils.StateFailed {
 				updated = true
+				if !utils.IsFailFast(execution.Spec.PipelineConfig.Stages[stage].Steps[step]) {
+					//Jenkins runs the other parallel steps to the end, the stage fails once they finish
+					if err := j.failMatrixStep(execution, stage, step, jenkinsStage); err != nil {
+						return false, err
+					}
+					if utils.IsStageRunning(execution.Status.Stages[stage]) {
+						continue
+					}
+				}
 				if err := j.failStep(execution, stage, step, jenkinsStage); err != nil {
 					return false, err
 				}
@@ -399,6 +336,13 @@ func (j *Engine) SyncExecution(execution *v3.PipelineExecution) (bool, error) {
 		}
 		v32.PipelineExecutionConditionBuilt.False(execution)
 		v32.PipelineExecutionConditionBuilt.Message(execution, "Buildinfo got FAILED status")
+		for i := range execution.Status.Stages {
+			//stages with failed matrix steps that do not fail fast
+			if execution.Status.Stages[i].State == utils.StateBuilding {
+				execution.Status.Stages[i].State = utils.StateFailed
+				execution.Status.Stages[i].Ended = time.Now().Format(time.RFC3339)
+			}
+		}
 	} else if info.Status == "IN_PROGRESS" && execution.Status.ExecutionState == utils.StateWaiting {
 		updated = true
 		execution.Status.ExecutionState = utils.StateBuilding
@@ -517,6 +461,19 @@ func (j *Engine) failStep(execution *v3.PipelineExecution, stage int, step int,
 	return nil
 }
 
+// failMatrixStep marks a failed combination of a matrix that does not fail
+// fast, leaving its stage running.
+func (j *Engine) failMatrixStep(execution *v3.PipelineExecution, stage int, step int, jenkinsStage Stage) error {
+	startTime := time.Unix(jenkinsStage.StartTimeMillis/1000, 0).Format(time.RFC3339)
+	endTime := time.Unix((jenkinsStage.StartTimeMillis+jenkinsStage.DurationMillis)/1000, 0).Format(time.RFC3339)
+	execution.Status.Stages[stage].Steps[step].State = utils.StateFailed
+	if execution.Status.Stages[stage].Steps[step].Started == "" {
+		execution.Status.Stages[stage].Steps[step].Started = startTime
+	}
+	execution.Status.Stages[stage].Steps[step].Ended = endTime
+	return j.saveStepLogToMinio(execution, stage, step)
+}
+
 func buildingStep(execution *v3.PipelineExecution, stage int, step int, jenkinsStage Stage) {
 	startTime := time.Unix(jenkinsStage.StartTimeMillis/1000, 0).Format(time.RFC3339)
 	execution.Status.Stages[stage].Steps[step].State = utils.StateBuilding
@@ -584,6 +541,10 @@ func (j Engine) GetStepLog(execution *v3.PipelineExecution, stage int, step int)
 	return j.getStepLogFromJenkins(execution, stage, step)
 }
 
+func (j Engine) GetArtifact(execution *v3.PipelineExecution, stage int, step int, name string) (io.ReadCloser, error) {
+	return nil, errors.New("artifacts are not supported by the jenkins pipeline engine")
+}
+
 func (j Engine) getStepLogFromJenkins(execution *v3.PipelineExecution, stage int, step int) (string, error) {
 	if len(execution.Status.Stages) <= stage || len(execution.Status.Stages[stage].Steps) <= step {
 		return "", errors.New("invalid step index")
@@ -650,27 +611,11 @@ func (j Engine) setCredential(client *Client, execution *v3.PipelineExecution, c
 	jenkinsCred.Scope = "GLOBAL"
 	jenkinsCred.ID = execution.Name
 
-	_, projID := ref.Parse(execution.Spec.ProjectName)
-	scpConfig, err := providers.GetSourceCodeProviderConfig(credential.Spec.SourceCodeType, projID)
-	if err != nil {
-		return err
-	}
-	remote, err := remote.New(scpConfig)
+	jenkinsCred.Username, jenkinsCred.Password, err = common.GetGitCredential(execution, credential, j.SourceCodeCredentials)
 	if err != nil {
 		return err
 	}
 
-	jenkinsCred.Username = credential.Spec.GitLoginName
-	jenkinsCred.Password = credential.Spec.AccessToken
-	if credential.Spec.GitCloneToken != "" {
-		jenkinsCred.Password = credential.Spec.GitCloneToken
-	}
-	if accessToken, err := utils.EnsureAccessToken(j.SourceCodeCredentials, remote, credential); err != nil {
-		return err
-	} else if accessToken != credential.Spec.AccessToken {
-		jenkinsCred.Password = accessToken
-	}
-
 	bodyContent := map[string]interface{}{}
 	bodyContent["credentials"] = jenkinsCred
 	b, err := json.Marshal(bodyContent)
@@ -705,3 +650,13 @@ func stripTags(content string) string {
 	stripped := re.ReplaceAllString(content, "")
 	return stripped
 }
+
+// DeleteExecutionStorage and PruneStorage have nothing to remove, the jenkins
+// engine does not store caches or artifacts.
+func (j Engine) DeleteExecutionStorage(execution *v3.PipelineExecution) error {
+	return nil
+}
+
+func (j Engine) PruneStorage(projectName string) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package jenkins
+
+import (
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertMatrixStage(t *testing.T) {
+	assert := assert.New(t)
+	matrixStep := v32.Step{
+		RunScriptConfig: &v32.RunScriptConfig{Image: "golang", ShellScript: "go test ./..."},
+		Matrix:          &v32.StepMatrix{Values: map[string][]string{"GOARCH": {"amd64"}}},
+	}
+	execution := &v3.PipelineExecution{
+		Spec: v32.PipelineExecutionSpec{
+			PipelineConfig: v32.PipelineConfig{
+				Stages: []v32.Stage{
+					{Name: "Build", Steps: []v32.Step{{RunScriptConfig: &v32.RunScriptConfig{Image: "golang", ShellScript: "go build ./..."}}}},
+					{Name: "Test", Steps: []v32.Step{matrixStep, matrixStep}},
+				},
+			},
+		},
+	}
+	c := &jenkinsPipelineConverter{execution: execution}
+	assert.NotContains(c.convertStage(0), "failFast", "stages without a matrix keep the jenkins default")
+	assert.NotContains(c.convertStage(1), "failFast", "failed combinations let the others finish")
+
+	for i := range execution.Spec.PipelineConfig.Stages[1].Steps {
+		execution.Spec.PipelineConfig.Stages[1].Steps[i].Matrix.FailFast = true
+	}
+	script := c.convertStage(1)
+	assert.True(strings.HasSuffix(strings.TrimSpace(script), ", failFast: true\n}"), script)
+}
@@ -0,0 +1,57 @@
+package kubernetes
+
+const (
+	WorkspaceVolumeName = "workspace"
+	WorkspacePath       = "/workspace"
+	WorkspaceSuffix     = "-workspace"
+	GitSecretSuffix     = "-git"
+	GitSecretUserKey    = "username"
+	GitSecretPwKey      = "password"
+	HelperVolumeName    = "pipeline-helper"
+	HelperPath          = "/var/run/pipeline"
+	ExitCodePath        = HelperPath + "/exit-code"
+	StorageSecretSuffix = "-storage"
+	StorageVolumeName   = "pipeline-storage"
+	StoragePath         = "/var/run/pipeline-storage"
+
+	restoreCacheURLKey = "restore-cache-url"
+	saveCacheURLKey    = "save-cache-url"
+	artifactsURLKey    = "artifacts-url"
+
+	restoreCacheContainerName = "restore-cache"
+	postStepContainerName     = "post-step"
+
+	cloneScript = `git init -q . && ` +
+		`git config credential.helper '!f() { echo "username=${GIT_USERNAME}"; echo "password=${GIT_PASSWORD}"; }; f' && ` +
+		`git remote add origin "${CICD_GIT_URL}" && ` +
+		`git fetch -q origin "+${CICD_GIT_REF}:refs/remotes/local/temp" && ` +
+		`git checkout -q local/temp && ` +
+		`git config --unset credential.helper`
+
+	// checksumScript reports the checksums of the files cache keys are derived
+	// from in the termination message of the clone step.
+	checksumScript = `for f in ${CICD_CACHE_KEY_FILES}; do echo "$f $(sha256sum "$f" | cut -d' ' -f1)"; done > /dev/termination-log`
+
+	restoreCacheScript = `if curl -sfL -o /tmp/cache.tar.gz "$(cat ` + StoragePath + `/` + restoreCacheURLKey + `)" && tar -xzf /tmp/cache.tar.gz; then
+  echo "cache restored"
+else
+  echo "failed to restore cache"
+fi`
+
+	// postStepScript waits for the step container to exit, and saves the cache
+	// and uploads the artifacts of the step if it succeeded. The urls are read
+	// from the storage secret of the step, which only holds the urls it needs.
+	postStepScript = `while [ ! -f ` + ExitCodePath + ` ]; do sleep 1; done
+[ "$(cat ` + ExitCodePath + `)" = "0" ] || exit 0
+if [ -f ` + StoragePath + `/` + saveCacheURLKey + ` ]; then
+  if tar -czf /tmp/cache.tar.gz ${CICD_CACHE_PATHS} && curl -sf -T /tmp/cache.tar.gz "$(cat ` + StoragePath + `/` + saveCacheURLKey + `)"; then
+    echo "cache saved"
+  else
+    echo "failed to save cache"
+  fi
+fi
+if [ -f ` + StoragePath + `/` + artifactsURLKey + ` ]; then
+  tar -czf /tmp/artifacts.tar.gz ${CICD_ARTIFACTS}
+  curl -sf -T /tmp/artifacts.tar.gz "$(cat ` + StoragePath + `/` + artifactsURLKey + `)"
+fi`
+)
@@ -0,0 +1,619 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Engine runs every step of a pipeline execution as a pod in the pipeline
+// namespace of the project. Stages run one after another, the steps of a
+// stage run in parallel, and all of them share a workspace volume holding the
+// source checkout.
+type Engine struct {
+	// UseCache affects resources that is not cached in follower instances of HA mode
+	UseCache   bool
+	HTTPClient *http.Client
+
+	Pods                   v1.PodInterface
+	PodLister              v1.PodLister
+	PodLogs                typedcorev1.PodsGetter
+	PersistentVolumeClaims v1.PersistentVolumeClaimInterface
+	ServiceLister          v1.ServiceLister
+
+	Secrets                    v1.SecretInterface
+	SecretLister               v1.SecretLister
+	ManagementSecretLister     v1.SecretLister
+	SourceCodeCredentials      v3.SourceCodeCredentialInterface
+	SourceCodeCredentialLister v3.SourceCodeCredentialLister
+	PipelineLister             v3.PipelineLister
+	PipelineExecutionLister    v3.PipelineExecutionLister
+	PipelineSettingLister      v3.PipelineSettingLister
+
+	ClusterName string
+	Dialer      dialer.Factory
+}
+
+// PreCheck waits for the log store of the project, the only pipeline workload
+// the engine depends on.
+func (e *Engine) PreCheck(execution *v3.PipelineExecution) (bool, error) {
+	set := labels.Set(map[string]string{utils.LabelKeyApp: utils.MinioName})
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	pods, err := e.PodLister.List(ns, set.AsSelector())
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range pods {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (e *Engine) SetupMessage(execution *v3.PipelineExecution) string {
+	return "Setting up the pipeline log store. If it is not deployed, this can take a few minutes."
+}
+
+func (e *Engine) RunPipelineExecution(execution *v3.PipelineExecution) error {
+	logrus.Debug("start RunPipelineExecution")
+	if err := utils.ValidPipelineConfig(execution.Spec.PipelineConfig); err != nil {
+		return err
+	}
+	if err := common.PrepareRegistryCredentials(execution, e.Secrets, e.ManagementSecretLister); err != nil {
+		return err
+	}
+	if err := e.prepareGitCredential(execution); err != nil {
+		return err
+	}
+	if err := e.prepareWorkspace(execution); err != nil {
+		return err
+	}
+	_, err := e.SyncExecution(execution)
+	return err
+}
+
+func (e *Engine) RerunExecution(execution *v3.PipelineExecution) error {
+	if err := e.StopExecution(execution); err != nil {
+		return err
+	}
+	return e.RunPipelineExecution(execution)
+}
+
+// StopExecution deletes the pods, the workspace and the secrets of an
+// execution, saving the logs of the steps that are still running first.
+func (e *Engine) StopExecution(execution *v3.PipelineExecution) error {
+	for i, stage := range execution.Status.Stages {
+		for j, step := range stage.Steps {
+			if step.State != utils.StateBuilding {
+				continue
+			}
+			if err := e.saveStepLogToMinio(execution, i, j); err != nil {
+				logrus.Warnf("failed to save log of step %d-%d of pipeline execution %s: %v", i, j, execution.Name, err)
+			}
+		}
+	}
+
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	set := labels.Set(map[string]string{
+		utils.LabelKeyApp:       utils.PipelineName,
+		utils.LabelKeyExecution: execution.Name,
+	})
+	pods, err := e.Pods.ListNamespaced(ns, metav1.ListOptions{LabelSelector: set.String()})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if err := e.Pods.DeleteNamespaced(ns, pod.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	if err := e.PersistentVolumeClaims.DeleteNamespaced(ns, getWorkspaceName(execution), &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := e.Secrets.DeleteNamespaced(ns, getGitSecretName(execution), &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	for i, stage := range execution.Spec.PipelineConfig.Stages {
+		for j, step := range stage.Steps {
+			if step.Cache == nil && len(step.Artifacts) == 0 {
+				continue
+			}
+			if err := e.Secrets.DeleteNamespaced(ns, getStorageSecretName(execution, i, j), &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SyncExecution updates the states of an execution from its step pods, and
+// starts the pods of the next stage once all steps of a stage succeeded.
+func (e *Engine) SyncExecution(execution *v3.PipelineExecution) (bool, error) {
+	if utils.IsFinishState(execution.Status.ExecutionState) {
+		return false, nil
+	}
+	if e.timedOut(execution) {
+		return true, e.abortExecution(execution, "Timed out")
+	}
+
+	updated := false
+	for i := range execution.Status.Stages {
+		stage := &execution.Status.Stages[i]
+		if stage.State == utils.StateSuccess || stage.State == utils.StateSkipped {
+			continue
+		}
+		if stage.State == utils.StateWaiting {
+			if err := e.startStage(execution, i); err != nil {
+				return false, err
+			}
+			updated = true
+		}
+		stageUpdated, err := e.syncStage(execution, i)
+		if err != nil {
+			return false, err
+		}
+		updated = updated || stageUpdated
+		if stage.State != utils.StateSuccess && stage.State != utils.StateSkipped {
+			return updated, nil
+		}
+	}
+
+	execution.Status.ExecutionState = utils.StateSuccess
+	execution.Status.Ended = time.Now().Format(time.RFC3339)
+	execution.Labels[utils.PipelineFinishLabel] = "true"
+	v32.PipelineExecutionConditionProvisioned.True(execution)
+	v32.PipelineExecutionConditionBuilt.True(execution)
+	return true, nil
+}
+
+func (e *Engine) GetStepLog(execution *v3.PipelineExecution, stage int, step int) (string, error) {
+	if len(execution.Status.Stages) <= stage || len(execution.Status.Stages[stage].Steps) <= step {
+		return "", errors.New("invalid step index")
+	}
+	curStep := execution.Status.Stages[stage].Steps[step]
+	if curStep.State == utils.StateWaiting || curStep.State == utils.StateSkipped {
+		return "", nil
+	} else if curStep.State != utils.StateBuilding {
+		return e.getStepLogFromMinioStore(execution, stage, step)
+	}
+	return e.getStepLogFromPod(execution, stage, step)
+}
+
+func (e *Engine) getStepLogFromPod(execution *v3.PipelineExecution, stage int, step int) (string, error) {
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	log, err := e.PodLogs.Pods(ns).GetLogs(getPodName(execution, stage, step), &corev1.PodLogOptions{
+		Container:  getStepContainerName(stage, step),
+		Timestamps: true,
+	}).DoRaw(context.TODO())
+	if err != nil {
+		return "", err
+	}
+	return string(log), nil
+}
+
+func (e *Engine) prepareGitCredential(execution *v3.PipelineExecution) error {
+	ns, name := ref.Parse(execution.Spec.PipelineName)
+	pipeline, err := e.PipelineLister.Get(ns, name)
+	if err != nil {
+		return err
+	}
+	if pipeline.Spec.SourceCodeCredentialName == "" {
+		return nil
+	}
+	ns, name = ref.Parse(pipeline.Spec.SourceCodeCredentialName)
+	credential, err := e.SourceCodeCredentialLister.Get(ns, name)
+	if err != nil {
+		return err
+	}
+	username, password, err := common.GetGitCredential(execution, credential, e.SourceCodeCredentials)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: utils.GetPipelineCommonName(execution.Spec.ProjectName),
+			Name:      getGitSecretName(execution),
+		},
+		Data: map[string][]byte{
+			GitSecretUserKey: []byte(username),
+			GitSecretPwKey:   []byte(password),
+		},
+	}
+	if _, err := e.Secrets.Create(secret); apierrors.IsAlreadyExists(err) {
+		_, err = e.Secrets.Update(secret)
+		return err
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) prepareStorageSecret(secret *corev1.Secret) error {
+	if _, err := e.Secrets.Create(secret); apierrors.IsAlreadyExists(err) {
+		_, err = e.Secrets.Update(secret)
+		return err
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) prepareWorkspace(execution *v3.PipelineExecution) error {
+	_, projectID := ref.Parse(execution.Spec.ProjectName)
+	size := utils.SettingWorkspaceSizeDefault
+	setting, err := e.PipelineSettingLister.Get(projectID, utils.SettingWorkspaceSize)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	} else if err == nil {
+		size = common.GetPipelineSettingValue(setting)
+	}
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return errors.Wrapf(err, "invalid workspace size %q", size)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: utils.GetPipelineCommonName(execution.Spec.ProjectName),
+			Name:      getWorkspaceName(execution),
+			Labels: map[string]string{
+				utils.LabelKeyApp:       utils.PipelineName,
+				utils.LabelKeyExecution: execution.Name,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: quantity,
+				},
+			},
+		},
+	}
+	if _, err := e.PersistentVolumeClaims.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// startStage creates the pods of the steps of a stage, skipping the steps
+// whose conditions do not match the execution.
+func (e *Engine) startStage(execution *v3.PipelineExecution, stage int) error {
+	config := execution.Spec.PipelineConfig.Stages[stage]
+	status := &execution.Status.Stages[stage]
+	now := time.Now().Format(time.RFC3339)
+
+	var opts *common.ExecuteOptions
+	var toRun *v3.PipelineExecution
+	var checksums map[string]string
+	nodeName := ""
+	skipped := 0
+	for i, step := range config.Steps {
+		if !utils.MatchAll(config.When, execution) || !utils.MatchAll(step.When, execution) {
+			status.Steps[i].State = utils.StateSkipped
+			skipped++
+			continue
+		}
+		if opts == nil {
+			var err error
+			if opts, err = common.GetExecuteOptions(execution, e.PipelineSettingLister, e.SecretLister); err != nil {
+				return err
+			}
+			toRun = execution.DeepCopy()
+			common.ParsePreservedEnvVar(toRun)
+			if stage > 0 {
+				if nodeName, checksums, err = e.getCloneResult(execution); err != nil {
+					return err
+				}
+			}
+		}
+		storage, err := e.getStepStorage(execution, stage, i, checksums)
+		if err != nil {
+			return err
+		}
+		if storage != nil {
+			if err := e.prepareStorageSecret(getStorageSecret(execution, stage, i, storage)); err != nil {
+				return err
+			}
+		}
+		pod, err := getStepPod(toRun, opts, stage, i, nodeName, storage)
+		if err != nil {
+			return err
+		}
+		if _, err := e.Pods.Create(pod); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	if skipped == len(config.Steps) {
+		status.State = utils.StateSkipped
+		return nil
+	}
+	status.State = utils.StateBuilding
+	status.Started = now
+	if execution.Status.ExecutionState == utils.StateWaiting {
+		execution.Status.ExecutionState = utils.StateBuilding
+	}
+	if execution.Status.Started == "" {
+		execution.Status.Started = now
+	}
+	v32.PipelineExecutionConditionBuilt.CreateUnknownIfNotExists(execution)
+	v32.PipelineExecutionConditionBuilt.Message(execution, fmt.Sprintf("Running '%s' stage", config.Name))
+	return nil
+}
+
+// getCloneResult returns the node the clone step ran on, where the workspace
+// volume of the execution is available, and the checksums of the cache key
+// files it reported.
+func (e *Engine) getCloneResult(execution *v3.PipelineExecution) (string, map[string]string, error) {
+	pod, err := e.getStepPod(execution, 0, 0)
+	if apierrors.IsNotFound(err) {
+		return "", nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	return pod.Spec.NodeName, getCacheChecksums(pod), nil
+}
+
+func (e *Engine) getStepPod(execution *v3.PipelineExecution, stage int, step int) (*corev1.Pod, error) {
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	if e.UseCache {
+		return e.PodLister.Get(ns, getPodName(execution, stage, step))
+	}
+	return e.Pods.GetNamespaced(ns, getPodName(execution, stage, step), metav1.GetOptions{})
+}
+
+// syncStage updates the steps of a running stage from their pods. A failed
+// step fails the stage right away unless it is a combination of a matrix that
+// does not fail fast, then the stage fails once its other steps finished.
+func (e *Engine) syncStage(execution *v3.PipelineExecution, stage int) (bool, error) {
+	updated := false
+	config := execution.Spec.PipelineConfig.Stages[stage]
+	status := &execution.Status.Stages[stage]
+	for i := range status.Steps {
+		step := &status.Steps[i]
+		if step.State != utils.StateWaiting && step.State != utils.StateBuilding {
+			continue
+		}
+		failure := ""
+		pod, err := e.getStepPod(execution, stage, i)
+		if apierrors.IsNotFound(err) {
+			if step.State != utils.StateBuilding {
+				continue
+			}
+			failure = "step pod was deleted"
+		} else if err != nil {
+			return false, err
+		} else {
+			var stepUpdated bool
+			if stepUpdated, failure, err = e.syncStep(execution, stage, i, pod); err != nil {
+				return false, err
+			}
+			updated = updated || stepUpdated
+			if failure == "" {
+				continue
+			}
+		}
+
+		if utils.IsFailFast(config.Steps[i]) {
+			return true, e.failStep(execution, stage, i, failure)
+		}
+		step.State = utils.StateFailed
+		if step.Ended == "" {
+			step.Ended = time.Now().Format(time.RFC3339)
+		}
+		updated = true
+	}
+
+	if utils.IsStageSuccess(*status) {
+		status.State = utils.StateSuccess
+		status.Ended = time.Now().Format(time.RFC3339)
+		updated = true
+	} else if !utils.IsStageRunning(*status) {
+		for i, step := range status.Steps {
+			if step.State == utils.StateFailed {
+				return true, e.failStep(execution, stage, i, fmt.Sprintf("Got FAILED status in '%s' stage", config.Name))
+			}
+		}
+	}
+	return updated, nil
+}
+
+// syncStep updates a step from its pod, and returns why the step failed if it
+// did.
+func (e *Engine) syncStep(execution *v3.PipelineExecution, stage int, i int, pod *corev1.Pod) (bool, string, error) {
+	step := &execution.Status.Stages[stage].Steps[i]
+	stageName := execution.Spec.PipelineConfig.Stages[stage].Name
+	containerName := getStepContainerName(stage, i)
+	started, ended := getContainerTimes(pod, containerName)
+	switch pod.Status.Phase {
+	case corev1.PodRunning:
+		if exitCode, ok := getExitCode(pod, containerName); ok && exitCode != 0 {
+			if step.Started == "" {
+				step.Started = started
+			}
+			step.Ended = ended
+			if err := e.saveStepLogToMinio(execution, stage, i); err != nil {
+				logrus.Warnf("failed to save log of step %d-%d of pipeline execution %s: %v", stage, i, execution.Name, err)
+			}
+			return true, fmt.Sprintf("Got FAILED status in '%s' stage", stageName), nil
+		}
+		if step.State != utils.StateBuilding {
+			step.State = utils.StateBuilding
+			step.Started = started
+			v32.PipelineExecutionConditionProvisioned.True(execution)
+			return true, "", nil
+		}
+	case corev1.PodSucceeded:
+		if len(execution.Spec.PipelineConfig.Stages[stage].Steps[i].Artifacts) > 0 {
+			if err := e.syncArtifacts(execution, stage, i); err != nil {
+				return false, "", err
+			}
+		}
+		if step.Started == "" {
+			step.Started = started
+		}
+		step.State = utils.StateSuccess
+		step.Ended = ended
+		if err := e.saveStepLogToMinio(execution, stage, i); err != nil {
+			return false, "", err
+		}
+		return true, "", nil
+	case corev1.PodFailed:
+		if step.Started == "" {
+			step.Started = started
+		}
+		step.Ended = ended
+		if err := e.saveStepLogToMinio(execution, stage, i); err != nil {
+			logrus.Warnf("failed to save log of step %d-%d of pipeline execution %s: %v", stage, i, execution.Name, err)
+		}
+		if exitCode, ok := getExitCode(pod, containerName); ok && exitCode == 0 {
+			return true, fmt.Sprintf("Failed to upload artifacts in '%s' stage", stageName), nil
+		}
+		return true, fmt.Sprintf("Got FAILED status in '%s' stage", stageName), nil
+	case corev1.PodPending:
+		if message := getWaitingError(pod, containerName); message != "" {
+			return true, message, nil
+		}
+	}
+	return false, "", nil
+}
+
+// failStep marks a step, its stage and the execution as failed, and aborts the
+// other steps of the stage that are still running.
+func (e *Engine) failStep(execution *v3.PipelineExecution, stage int, step int, message string) error {
+	now := time.Now().Format(time.RFC3339)
+	status := &execution.Status.Stages[stage]
+	status.Steps[step].State = utils.StateFailed
+	if status.Steps[step].Ended == "" {
+		status.Steps[step].Ended = now
+	}
+	status.State = utils.StateFailed
+	status.Ended = now
+	execution.Status.ExecutionState = utils.StateFailed
+	execution.Status.Ended = now
+	if v32.PipelineExecutionConditionProvisioned.IsUnknown(execution) {
+		v32.PipelineExecutionConditionProvisioned.True(execution)
+	}
+	v32.PipelineExecutionConditionBuilt.False(execution)
+	v32.PipelineExecutionConditionBuilt.Message(execution, message)
+	return e.abortSteps(execution, now)
+}
+
+// abortExecution stops an execution that is still running.
+func (e *Engine) abortExecution(execution *v3.PipelineExecution, message string) error {
+	now := time.Now().Format(time.RFC3339)
+	for i := range execution.Status.Stages {
+		if execution.Status.Stages[i].State == utils.StateBuilding {
+			execution.Status.Stages[i].State = utils.StateFailed
+			execution.Status.Stages[i].Ended = now
+		}
+	}
+	execution.Status.ExecutionState = utils.StateFailed
+	execution.Status.Ended = now
+	v32.PipelineExecutionConditionBuilt.False(execution)
+	v32.PipelineExecutionConditionBuilt.Message(execution, message)
+	return e.abortSteps(execution, now)
+}
+
+func (e *Engine) abortSteps(execution *v3.PipelineExecution, now string) error {
+	for i := range execution.Status.Stages {
+		stage := &execution.Status.Stages[i]
+		if stage.State == utils.StateWaiting {
+			stage.State = ""
+		}
+		for j := range stage.Steps {
+			step := &stage.Steps[j]
+			if step.State == utils.StateWaiting {
+				step.State = ""
+			} else if step.State == utils.StateBuilding {
+				step.State = utils.StateAborted
+				step.Ended = now
+				if err := e.saveStepLogToMinio(execution, i, j); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// timedOut checks the timeout of the pipeline config from the start of the
+// first stage, executions may have been queueing for a while before.
+func (e *Engine) timedOut(execution *v3.PipelineExecution) bool {
+	if len(execution.Status.Stages) == 0 {
+		return false
+	}
+	started, err := time.Parse(time.RFC3339, execution.Status.Stages[0].Started)
+	if err != nil {
+		return false
+	}
+	timeout := utils.DefaultTimeout
+	if execution.Spec.PipelineConfig.Timeout > 0 {
+		timeout = execution.Spec.PipelineConfig.Timeout
+	}
+	return time.Since(started) > time.Duration(timeout)*time.Minute
+}
+
+func getContainerTimes(pod *corev1.Pod, containerName string) (string, string) {
+	now := time.Now().Format(time.RFC3339)
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		if status.State.Running != nil {
+			return status.State.Running.StartedAt.Format(time.RFC3339), ""
+		}
+		if status.State.Terminated != nil {
+			return status.State.Terminated.StartedAt.Format(time.RFC3339), status.State.Terminated.FinishedAt.Format(time.RFC3339)
+		}
+	}
+	return now, now
+}
+
+// getExitCode returns the exit code of a container that terminated. The pod of
+// a step keeps running after the step container exits while the artifacts are
+// uploaded.
+func getExitCode(pod *corev1.Pod, containerName string) (int32, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode, true
+		}
+	}
+	return 0, false
+}
+
+// getWaitingError returns why a step container cannot start, if it will not
+// start without the user fixing the pipeline config.
+func getWaitingError(pod *corev1.Pod, containerName string) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName || status.State.Waiting == nil {
+			continue
+		}
+		switch status.State.Waiting.Reason {
+		case "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError":
+			return fmt.Sprintf("%s: %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+	}
+	return ""
+}
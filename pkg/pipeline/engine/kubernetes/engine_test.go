@@ -0,0 +1,258 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	projectfakes "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestExecution() *v3.PipelineExecution {
+	return &v3.PipelineExecution{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pipeline-1",
+			Namespace: "p-test",
+			Labels:    map[string]string{utils.PipelineFinishLabel: "false"},
+		},
+		Spec: v32.PipelineExecutionSpec{
+			ProjectName:   "c-test:p-test",
+			PipelineName:  "p-test:pipeline",
+			RepositoryURL: "https://github.com/rancher/pipeline-example-go.git",
+			Ref:           "refs/heads/master",
+			Branch:        "master",
+			PipelineConfig: v32.PipelineConfig{
+				Stages: []v32.Stage{
+					{
+						Name:  "Clone",
+						Steps: []v32.Step{{SourceCodeConfig: &v32.SourceCodeConfig{}}},
+					},
+					{
+						Name: "Build",
+						Steps: []v32.Step{
+							{RunScriptConfig: &v32.RunScriptConfig{Image: "golang", ShellScript: "go build ./..."}},
+							{
+								RunScriptConfig: &v32.RunScriptConfig{Image: "golang", ShellScript: "go test ./..."},
+								When:            &v32.Constraints{Branch: &v32.Constraint{Include: []string{"release"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Status: v32.PipelineExecutionStatus{
+			ExecutionState: utils.StateBuilding,
+			Stages: []v32.StageStatus{
+				{
+					State:   utils.StateSuccess,
+					Started: time.Now().Format(time.RFC3339),
+					Steps:   []v32.StepStatus{{State: utils.StateSuccess}},
+				},
+				{
+					State: utils.StateWaiting,
+					Steps: []v32.StepStatus{{State: utils.StateWaiting}, {State: utils.StateWaiting}},
+				},
+			},
+		},
+	}
+}
+
+func TestGetStepPod(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	opts := &common.ExecuteOptions{ImagePullSecretNames: []string{"registry"}}
+
+	pod, err := getStepPod(execution, opts, 0, 0, "", nil)
+	assert.Nil(err)
+	assert.Equal("pipeline-1-0-0", pod.Name)
+	assert.Equal("p-test-pipeline", pod.Namespace)
+	assert.Equal(corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.Equal(utils.PipelineStepName, pod.Spec.ServiceAccountName)
+	assert.False(*pod.Spec.AutomountServiceAccountToken)
+	podTerms := pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(corev1.LabelHostname, podTerms[0].TopologyKey)
+	assert.Equal(map[string]string{utils.LabelKeyExecution: "pipeline-1", utils.LabelKeyStage: "0"}, podTerms[0].LabelSelector.MatchLabels,
+		"first stage pods sharing the workspace run on one node")
+	assert.Equal([]corev1.LocalObjectReference{{Name: "registry"}}, pod.Spec.ImagePullSecrets)
+	container := pod.Spec.Containers[0]
+	assert.Equal([]string{"sh", "-ec", cloneScript}, container.Command)
+	assert.Equal(WorkspacePath, container.WorkingDir)
+	assert.Contains(container.VolumeMounts, corev1.VolumeMount{Name: WorkspaceVolumeName, MountPath: WorkspacePath})
+	var gitEnv []string
+	for _, env := range container.Env {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == "pipeline-1-git" {
+			gitEnv = append(gitEnv, env.Name)
+		}
+	}
+	assert.Equal([]string{"GIT_USERNAME", "GIT_PASSWORD"}, gitEnv, "the clone step reads the git credential")
+	assert.Equal("pipeline-1-workspace", pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+	pod, err = getStepPod(execution, opts, 1, 0, "node1", nil)
+	assert.Nil(err)
+	assert.Equal([]string{"sh", "-xec", "go build ./..."}, pod.Spec.Containers[0].Command)
+	assert.Equal("golang", pod.Spec.Containers[0].Image)
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Equal([]string{"node1"}, terms[0].MatchFields[0].Values, "steps run on the node of the workspace")
+	assert.Nil(pod.Spec.Affinity.PodAffinity)
+
+	execution.Spec.PipelineConfig.Stages[1].Steps[0] = v32.Step{ApplyYamlConfig: &v32.ApplyYamlConfig{Path: "deployment.yaml"}}
+	pod, err = getStepPod(execution, opts, 1, 0, "node1", nil)
+	assert.Nil(err)
+	assert.True(*pod.Spec.AutomountServiceAccountToken, "steps applying yaml use the service account token")
+}
+
+func TestSyncExecution(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	pods := map[string]*corev1.Pod{
+		"pipeline-1-0-0": {
+			ObjectMeta: metav1.ObjectMeta{Name: "pipeline-1-0-0"},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+	}
+	var created []*corev1.Pod
+	engine := &Engine{
+		UseCache: true,
+		PodLister: &corefakes.PodListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Pod, error) {
+				if pod, ok := pods[name]; ok {
+					return pod, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+			},
+		},
+		Pods: &corefakes.PodInterfaceMock{
+			CreateFunc: func(pod *corev1.Pod) (*corev1.Pod, error) {
+				created = append(created, pod)
+				return pod, nil
+			},
+		},
+		SecretLister: &corefakes.SecretListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+				return nil, nil
+			},
+		},
+		PipelineSettingLister: &projectfakes.PipelineSettingListerMock{
+			GetFunc: func(namespace string, name string) (*v3.PipelineSetting, error) {
+				return &v3.PipelineSetting{}, nil
+			},
+		},
+	}
+
+	updated, err := engine.SyncExecution(execution)
+	assert.Nil(err)
+	assert.True(updated)
+	assert.Len(created, 1, "steps whose conditions do not match are skipped")
+	assert.Equal("pipeline-1-1-0", created[0].Name)
+	assert.Equal(utils.StateSkipped, execution.Status.Stages[1].Steps[1].State)
+	assert.Equal(utils.StateBuilding, execution.Status.Stages[1].State)
+
+	pods["pipeline-1-1-0"] = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pipeline-1-1-0"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "step-1-0",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "ImagePullBackOff",
+					Message: "Back-off pulling image",
+				}},
+			}},
+		},
+	}
+	updated, err = engine.SyncExecution(execution)
+	assert.Nil(err)
+	assert.True(updated)
+	assert.Equal(utils.StateFailed, execution.Status.Stages[1].Steps[0].State)
+	assert.Equal(utils.StateFailed, execution.Status.ExecutionState)
+	assert.Equal("ImagePullBackOff: Back-off pulling image", v32.PipelineExecutionConditionBuilt.GetMessage(execution))
+	assert.Len(created, 1)
+}
+
+func TestSyncMatrixStage(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	matrix := &v32.StepMatrix{Values: map[string][]string{"GO_VERSION": {"1.15"}}}
+	execution.Spec.PipelineConfig.Stages[1].Steps = []v32.Step{
+		{RunScriptConfig: &v32.RunScriptConfig{Image: "golang"}, Matrix: matrix},
+		{RunScriptConfig: &v32.RunScriptConfig{Image: "golang"}, Matrix: matrix},
+	}
+	execution.Status.Stages[1].State = utils.StateBuilding
+	execution.Status.Stages[1].Steps = []v32.StepStatus{{State: utils.StateBuilding}, {State: utils.StateBuilding}}
+	pods := map[string]*corev1.Pod{
+		"pipeline-1-1-0": {Status: corev1.PodStatus{Phase: corev1.PodFailed}},
+		"pipeline-1-1-1": {Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	}
+	engine := &Engine{
+		UseCache: true,
+		PodLister: &corefakes.PodListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Pod, error) {
+				return pods[name], nil
+			},
+		},
+		PodLogs: fakeclientset.NewSimpleClientset().CoreV1(),
+		HTTPClient: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+		})},
+		ServiceLister: &corefakes.ServiceListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Service, error) {
+				return &corev1.Service{Spec: corev1.ServiceSpec{ClusterIP: "10.43.0.1"}}, nil
+			},
+		},
+		SecretLister: &corefakes.SecretListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Secret, error) {
+				return &corev1.Secret{Data: map[string][]byte{utils.PipelineSecretTokenKey: []byte("token")}}, nil
+			},
+		},
+	}
+
+	updated, err := engine.SyncExecution(execution)
+	assert.Nil(err)
+	assert.True(updated)
+	assert.Equal(utils.StateFailed, execution.Status.Stages[1].Steps[0].State)
+	assert.Equal(utils.StateBuilding, execution.Status.Stages[1].Steps[1].State, "other combinations keep running")
+	assert.Equal(utils.StateBuilding, execution.Status.ExecutionState)
+
+	pods["pipeline-1-1-1"] = &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}
+	updated, err = engine.SyncExecution(execution)
+	assert.Nil(err)
+	assert.True(updated)
+	assert.Equal(utils.StateSuccess, execution.Status.Stages[1].Steps[1].State)
+	assert.Equal(utils.StateFailed, execution.Status.Stages[1].State, "the stage fails once all combinations finished")
+	assert.Equal(utils.StateFailed, execution.Status.ExecutionState)
+
+	execution = newTestExecution()
+	matrix.FailFast = true
+	execution.Spec.PipelineConfig.Stages[1].Steps = []v32.Step{
+		{RunScriptConfig: &v32.RunScriptConfig{Image: "golang"}, Matrix: matrix},
+		{RunScriptConfig: &v32.RunScriptConfig{Image: "golang"}, Matrix: matrix},
+	}
+	execution.Status.Stages[1].State = utils.StateBuilding
+	execution.Status.Stages[1].Steps = []v32.StepStatus{{State: utils.StateBuilding}, {State: utils.StateBuilding}}
+	pods["pipeline-1-1-1"] = &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	_, err = engine.SyncExecution(execution)
+	assert.Nil(err)
+	assert.Equal(utils.StateAborted, execution.Status.Stages[1].Steps[1].State)
+	assert.Equal(utils.StateFailed, execution.Status.ExecutionState)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,198 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getPodName(execution *v3.PipelineExecution, stage int, step int) string {
+	return fmt.Sprintf("%s-%d-%d", execution.Name, stage, step)
+}
+
+func getStepContainerName(stage int, step int) string {
+	return fmt.Sprintf("step-%d-%d", stage, step)
+}
+
+func getWorkspaceName(execution *v3.PipelineExecution) string {
+	return execution.Name + WorkspaceSuffix
+}
+
+func getGitSecretName(execution *v3.PipelineExecution) string {
+	return execution.Name + GitSecretSuffix
+}
+
+// getStepPod returns the pod running a step of an execution. Steps share the
+// source checkout through the workspace volume of the execution, so all pods
+// after the clone step are scheduled to the node the workspace was used on,
+// and pods of the first stage are scheduled together with each other.
+// Only steps applying yaml get the token of the step service account mounted.
+// Steps with a cache or artifacts get helper containers transferring them
+// with the urls of storage.
+func getStepPod(execution *v3.PipelineExecution, opts *common.ExecuteOptions, stage int, step int, nodeName string, storage *stepStorage) (*v1.Pod, error) {
+	container, err := common.GetStepContainer(execution, opts, stage, step)
+	if err != nil {
+		return nil, err
+	}
+	config := execution.Spec.PipelineConfig.Stages[stage].Steps[step]
+	container.TTY = false
+	container.Command = getStepCommand(execution, stage, step)
+	container.WorkingDir = WorkspacePath
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      WorkspaceVolumeName,
+		MountPath: WorkspacePath,
+	})
+	if config.SourceCodeConfig != nil {
+		container.Env = append(container.Env, getGitCredentialEnv(execution)...)
+		if keyFiles := getCacheKeyFiles(execution); len(keyFiles) > 0 {
+			container.Env = append(container.Env, v1.EnvVar{Name: "CICD_CACHE_KEY_FILES", Value: strings.Join(keyFiles, " ")})
+		}
+	}
+
+	timeout := utils.DefaultTimeout
+	if execution.Spec.PipelineConfig.Timeout > 0 {
+		timeout = execution.Spec.PipelineConfig.Timeout
+	}
+	deadline := int64(timeout * 60)
+	automountToken := config.ApplyYamlConfig != nil
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getPodName(execution, stage, step),
+			Namespace: utils.GetPipelineCommonName(execution.Spec.ProjectName),
+			Labels: map[string]string{
+				utils.LabelKeyApp:       utils.PipelineName,
+				utils.LabelKeyExecution: execution.Name,
+				utils.LabelKeyStage:     strconv.Itoa(stage),
+				utils.LabelKeyStep:      strconv.Itoa(step),
+			},
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy:                v1.RestartPolicyNever,
+			ServiceAccountName:           utils.PipelineStepName,
+			AutomountServiceAccountToken: &automountToken,
+			ActiveDeadlineSeconds:        &deadline,
+			Containers:                   []v1.Container{container},
+			Volumes: []v1.Volume{
+				{
+					Name: WorkspaceVolumeName,
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+							ClaimName: getWorkspaceName(execution),
+						},
+					},
+				},
+				{
+					Name: utils.RegistryCrtVolumeName,
+					VolumeSource: v1.VolumeSource{
+						Secret: &v1.SecretVolumeSource{
+							SecretName: utils.RegistryCrtSecretName,
+						},
+					},
+				},
+			},
+		},
+	}
+	if nodeName != "" {
+		pod.Spec.Affinity = &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchFields: []v1.NodeSelectorRequirement{
+								{
+									Key:      "metadata.name",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{nodeName},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	} else if stage == 0 {
+		pod.Spec.Affinity = &v1.Affinity{
+			PodAffinity: &v1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								utils.LabelKeyExecution: execution.Name,
+								utils.LabelKeyStage:     strconv.Itoa(stage),
+							},
+						},
+						TopologyKey: v1.LabelHostname,
+					},
+				},
+			},
+		}
+	}
+	if opts.GitCaCerts != "" {
+		caContainerName := ""
+		if config.SourceCodeConfig != nil {
+			caContainerName = container.Name
+		}
+		common.InjectGitCaCert(pod, opts.GitCaCerts, caContainerName)
+	}
+	if len(opts.ImagePullSecretNames) > 0 {
+		common.ConfigImagePullSecrets(pod, opts.ImagePullSecretNames)
+	}
+	if storage != nil {
+		injectStepStorage(pod, config, storage, getStorageSecretName(execution, stage, step))
+	}
+	return pod, nil
+}
+
+func getStepCommand(execution *v3.PipelineExecution, stage int, step int) []string {
+	config := execution.Spec.PipelineConfig.Stages[stage].Steps[step]
+	script := ""
+	if config.SourceCodeConfig != nil {
+		script = cloneScript
+		if len(getCacheKeyFiles(execution)) > 0 {
+			script += " && " + checksumScript
+		}
+	} else if config.RunScriptConfig != nil {
+		return []string{"sh", "-xec", config.RunScriptConfig.ShellScript}
+	} else if config.PublishImageConfig != nil {
+		script = "/usr/local/bin/dockerd-entrypoint.sh /bin/drone-docker"
+	} else if config.ApplyYamlConfig != nil {
+		script = "kube-apply"
+	} else if config.PublishCatalogConfig != nil {
+		script = "publish-catalog"
+	} else if config.ApplyAppConfig != nil {
+		script = "apply-app"
+	}
+	return []string{"sh", "-ec", script}
+}
+
+func getGitCredentialEnv(execution *v3.PipelineExecution) []v1.EnvVar {
+	optional := true
+	return []v1.EnvVar{
+		{
+			Name: "GIT_USERNAME",
+			ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: getGitSecretName(execution),
+				},
+				Key:      GitSecretUserKey,
+				Optional: &optional,
+			}},
+		},
+		{
+			Name: "GIT_PASSWORD",
+			ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: getGitSecretName(execution),
+				},
+				Key:      GitSecretPwKey,
+				Optional: &optional,
+			}},
+		},
+	}
+}
@@ -0,0 +1,446 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go"
+	"github.com/pkg/errors"
+	v33 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	images "github.com/rancher/rancher/pkg/image"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// stepStorage holds the presigned urls of the project log store a step pod
+// restores its cache from, and uploads its cache and artifacts to. A cache is
+// only saved when there was none to restore. The urls are handed to the pod in
+// a secret, so they are not exposed in the pod spec.
+type stepStorage struct {
+	RestoreCacheURL string
+	SaveCacheURL    string
+	ArtifactsURL    string
+}
+
+// getCacheKeyFiles returns the files the cache keys of an execution are
+// derived from.
+func getCacheKeyFiles(execution *v3.PipelineExecution) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, stage := range execution.Spec.PipelineConfig.Stages {
+		for _, step := range stage.Steps {
+			if step.Cache == nil {
+				continue
+			}
+			for _, file := range step.Cache.KeyFiles {
+				if !seen[file] {
+					seen[file] = true
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// getCacheChecksums reads the checksums of the cache key files reported by the
+// clone step.
+func getCacheChecksums(pod *v1.Pod) map[string]string {
+	checksums := map[string]string{}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != getStepContainerName(0, 0) || status.State.Terminated == nil {
+			continue
+		}
+		for _, line := range strings.Split(status.State.Terminated.Message, "\n") {
+			if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+				checksums[parts[0]] = parts[1]
+			}
+		}
+	}
+	return checksums
+}
+
+// getCacheObjectName returns where a cache is stored. Caches are shared by
+// the executions of a pipeline, and change with the checksums of the key files.
+func getCacheObjectName(execution *v3.PipelineExecution, cache *v32.StepCache, checksums map[string]string) string {
+	_, pipelineID := ref.Parse(execution.Spec.PipelineName)
+	name := cache.Key
+	if len(cache.KeyFiles) > 0 {
+		h := sha256.New()
+		for _, file := range cache.KeyFiles {
+			fmt.Fprintf(h, "%s %s\n", file, checksums[file])
+		}
+		name = fmt.Sprintf("%s-%x", name, h.Sum(nil)[:8])
+	}
+	return fmt.Sprintf("%s/%s.tar.gz", pipelineID, name)
+}
+
+func getArtifactObjectName(execution *v3.PipelineExecution, stage int, step int) string {
+	return fmt.Sprintf("%s-%d-%d.tar.gz", execution.Name, stage, step)
+}
+
+func getStorageSecretName(execution *v3.PipelineExecution, stage int, step int) string {
+	return getPodName(execution, stage, step) + StorageSecretSuffix
+}
+
+// getStorageSecret returns the secret holding the urls of a step storage.
+func getStorageSecret(execution *v3.PipelineExecution, stage int, step int, storage *stepStorage) *v1.Secret {
+	data := map[string][]byte{}
+	if storage.RestoreCacheURL != "" {
+		data[restoreCacheURLKey] = []byte(storage.RestoreCacheURL)
+	}
+	if storage.SaveCacheURL != "" {
+		data[saveCacheURLKey] = []byte(storage.SaveCacheURL)
+	}
+	if storage.ArtifactsURL != "" {
+		data[artifactsURLKey] = []byte(storage.ArtifactsURL)
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: utils.GetPipelineCommonName(execution.Spec.ProjectName),
+			Name:      getStorageSecretName(execution, stage, step),
+			Labels: map[string]string{
+				utils.LabelKeyApp:       utils.PipelineName,
+				utils.LabelKeyExecution: execution.Name,
+			},
+		},
+		Data: data,
+	}
+}
+
+// injectStepStorage adds the helper containers restoring the cache of a step
+// before it runs, and saving the cache and uploading the artifacts after the
+// step container exits. The step container records its exit code for them in
+// a volume shared with the helpers, and the helpers read the urls from the
+// storage secret of the step.
+func injectStepStorage(pod *v1.Pod, config v32.Step, storage *stepStorage, secretName string) {
+	workspaceMount := v1.VolumeMount{Name: WorkspaceVolumeName, MountPath: WorkspacePath}
+	helperMount := v1.VolumeMount{Name: HelperVolumeName, MountPath: HelperPath}
+	storageMount := v1.VolumeMount{Name: StorageVolumeName, MountPath: StoragePath, ReadOnly: true}
+	image := images.Resolve(v33.ToolsSystemImages.PipelineSystemImages.AlpineGit)
+
+	container := &pod.Spec.Containers[0]
+	script := container.Command[len(container.Command)-1]
+	container.Command[len(container.Command)-1] = fmt.Sprintf("trap 'echo $? > %s' EXIT\n%s", ExitCodePath, script)
+	container.VolumeMounts = append(container.VolumeMounts, helperMount)
+	pod.Spec.Volumes = append(pod.Spec.Volumes,
+		v1.Volume{
+			Name:         HelperVolumeName,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		},
+		v1.Volume{
+			Name:         StorageVolumeName,
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: secretName}},
+		},
+	)
+
+	if storage.RestoreCacheURL != "" {
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, v1.Container{
+			Name:         restoreCacheContainerName,
+			Image:        image,
+			Command:      []string{"sh", "-ec", restoreCacheScript},
+			WorkingDir:   WorkspacePath,
+			VolumeMounts: []v1.VolumeMount{workspaceMount, storageMount},
+		})
+	}
+
+	if storage.SaveCacheURL == "" && storage.ArtifactsURL == "" {
+		return
+	}
+	var env []v1.EnvVar
+	if storage.SaveCacheURL != "" {
+		env = append(env, v1.EnvVar{Name: "CICD_CACHE_PATHS", Value: strings.Join(config.Cache.Paths, " ")})
+	}
+	if storage.ArtifactsURL != "" {
+		env = append(env, v1.EnvVar{Name: "CICD_ARTIFACTS", Value: strings.Join(config.Artifacts, " ")})
+	}
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name:         postStepContainerName,
+		Image:        image,
+		Command:      []string{"sh", "-ec", postStepScript},
+		WorkingDir:   WorkspacePath,
+		Env:          env,
+		VolumeMounts: []v1.VolumeMount{workspaceMount, helperMount, storageMount},
+	})
+}
+
+// getStepStorage presigns the urls a step uses to transfer its cache and
+// artifacts, they are valid until the execution times out.
+func (e *Engine) getStepStorage(execution *v3.PipelineExecution, stage int, step int, checksums map[string]string) (*stepStorage, error) {
+	config := execution.Spec.PipelineConfig.Stages[stage].Steps[step]
+	if config.Cache == nil && len(config.Artifacts) == 0 {
+		return nil, nil
+	}
+	client, err := e.getMinioClient(utils.GetPipelineCommonName(execution.Spec.ProjectName))
+	if err != nil {
+		return nil, err
+	}
+	timeout := utils.DefaultTimeout
+	if execution.Spec.PipelineConfig.Timeout > 0 {
+		timeout = execution.Spec.PipelineConfig.Timeout
+	}
+	expires := time.Duration(timeout) * time.Minute
+
+	storage := &stepStorage{}
+	if config.Cache != nil {
+		if err := ensureBucket(client, utils.MinioCacheBucket); err != nil {
+			return nil, err
+		}
+		objectName := getCacheObjectName(execution, config.Cache, checksums)
+		_, err := client.StatObject(utils.MinioCacheBucket, objectName, minio.StatObjectOptions{})
+		if err == nil {
+			u, err := client.PresignedGetObject(utils.MinioCacheBucket, objectName, expires, nil)
+			if err != nil {
+				return nil, err
+			}
+			storage.RestoreCacheURL = u.String()
+		} else if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			u, err := client.PresignedPutObject(utils.MinioCacheBucket, objectName, expires)
+			if err != nil {
+				return nil, err
+			}
+			storage.SaveCacheURL = u.String()
+		} else {
+			return nil, err
+		}
+	}
+	if len(config.Artifacts) > 0 {
+		if err := ensureBucket(client, utils.MinioArtifactBucket); err != nil {
+			return nil, err
+		}
+		u, err := client.PresignedPutObject(utils.MinioArtifactBucket, getArtifactObjectName(execution, stage, step), expires)
+		if err != nil {
+			return nil, err
+		}
+		storage.ArtifactsURL = u.String()
+	}
+	return storage, nil
+}
+
+// DeleteExecutionStorage removes the artifacts uploaded by the steps of an
+// execution.
+func (e *Engine) DeleteExecutionStorage(execution *v3.PipelineExecution) error {
+	ns := utils.GetPipelineCommonName(execution.Spec.ProjectName)
+	if _, err := e.ServiceLister.Get(ns, utils.MinioName); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var client *minio.Client
+	for i, stage := range execution.Spec.PipelineConfig.Stages {
+		for j, step := range stage.Steps {
+			if len(step.Artifacts) == 0 {
+				continue
+			}
+			if client == nil {
+				var err error
+				if client, err = e.getMinioClient(ns); err != nil {
+					return err
+				}
+			}
+			err := client.RemoveObject(utils.MinioArtifactBucket, getArtifactObjectName(execution, i, j))
+			if err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PruneStorage evicts the caches of a project that were saved longer than the
+// cache retention ago or belong to deleted pipelines, and removes artifacts
+// left behind by deleted executions.
+func (e *Engine) PruneStorage(projectName string) error {
+	ns := utils.GetPipelineCommonName(projectName)
+	if _, err := e.ServiceLister.Get(ns, utils.MinioName); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	client, err := e.getMinioClient(ns)
+	if err != nil {
+		return err
+	}
+	_, projectID := ref.Parse(projectName)
+	retention := e.getCacheRetention(projectID)
+	if err := pruneBucket(client, utils.MinioCacheBucket, func(object minio.ObjectInfo) bool {
+		return e.cacheExpired(projectID, retention, object)
+	}); err != nil {
+		return err
+	}
+	return pruneBucket(client, utils.MinioArtifactBucket, func(object minio.ObjectInfo) bool {
+		return e.artifactOrphaned(projectID, object)
+	})
+}
+
+func (e *Engine) getCacheRetention(projectID string) time.Duration {
+	retention, _ := time.ParseDuration(utils.SettingCacheRetentionDefault)
+	setting, err := e.PipelineSettingLister.Get(projectID, utils.SettingCacheRetention)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logrus.Warn(err)
+		}
+		return retention
+	}
+	value, err := time.ParseDuration(common.GetPipelineSettingValue(setting))
+	if err != nil {
+		logrus.Warnf("invalid pipeline cache retention of project %s: %v", projectID, err)
+		return retention
+	}
+	return value
+}
+
+func (e *Engine) cacheExpired(projectID string, retention time.Duration, object minio.ObjectInfo) bool {
+	if time.Since(object.LastModified) > retention {
+		return true
+	}
+	pipelineID := strings.SplitN(object.Key, "/", 2)[0]
+	_, err := e.PipelineLister.Get(projectID, pipelineID)
+	return apierrors.IsNotFound(err)
+}
+
+func (e *Engine) artifactOrphaned(projectID string, object minio.ObjectInfo) bool {
+	executionName, ok := parseArtifactObjectName(object.Key)
+	if !ok {
+		return false
+	}
+	_, err := e.PipelineExecutionLister.Get(projectID, executionName)
+	return apierrors.IsNotFound(err)
+}
+
+// parseArtifactObjectName returns the execution an artifact object was
+// uploaded by.
+func parseArtifactObjectName(objectName string) (string, bool) {
+	if !strings.HasSuffix(objectName, ".tar.gz") {
+		return "", false
+	}
+	name := strings.TrimSuffix(objectName, ".tar.gz")
+	for i := 0; i < 2; i++ {
+		index := strings.LastIndex(name, "-")
+		if index <= 0 {
+			return "", false
+		}
+		if _, err := strconv.Atoi(name[index+1:]); err != nil {
+			return "", false
+		}
+		name = name[:index]
+	}
+	return name, true
+}
+
+// pruneBucket removes the objects of a bucket matching expired.
+func pruneBucket(client *minio.Client, bucket string, expired func(minio.ObjectInfo) bool) error {
+	exists, err := client.BucketExists(bucket)
+	if err != nil || !exists {
+		return err
+	}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	for object := range client.ListObjectsV2(bucket, "", true, doneCh) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if !expired(object) {
+			continue
+		}
+		if err := client.RemoveObject(bucket, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getArtifactArchive opens the archive of the artifacts uploaded by a step.
+func (e *Engine) getArtifactArchive(execution *v3.PipelineExecution, stage int, step int) (*tar.Reader, io.Closer, error) {
+	client, err := e.getMinioClient(utils.GetPipelineCommonName(execution.Spec.ProjectName))
+	if err != nil {
+		return nil, nil, err
+	}
+	object, err := client.GetObject(utils.MinioArtifactBucket, getArtifactObjectName(execution, stage, step), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	gz, err := gzip.NewReader(object)
+	if err != nil {
+		object.Close()
+		return nil, nil, err
+	}
+	return tar.NewReader(gz), object, nil
+}
+
+// syncArtifacts lists the artifacts uploaded by a step on the execution status.
+func (e *Engine) syncArtifacts(execution *v3.PipelineExecution, stage int, step int) error {
+	archive, closer, err := e.getArtifactArchive(execution, stage, step)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	var artifacts []v32.Artifact
+	for _, artifact := range execution.Status.Artifacts {
+		if artifact.Stage != stage || artifact.Step != step {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		artifacts = append(artifacts, v32.Artifact{
+			Name:  getArtifactName(header.Name),
+			Stage: stage,
+			Step:  step,
+			Size:  header.Size,
+		})
+	}
+	execution.Status.Artifacts = artifacts
+	return nil
+}
+
+func (e *Engine) GetArtifact(execution *v3.PipelineExecution, stage int, step int, name string) (io.ReadCloser, error) {
+	archive, closer, err := e.getArtifactArchive(execution, stage, step)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			closer.Close()
+			return nil, errors.Errorf("artifact %s of step %d-%d not found", name, stage, step)
+		} else if err != nil {
+			closer.Close()
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && getArtifactName(header.Name) == name {
+			return &artifactReader{Reader: archive, Closer: closer}, nil
+		}
+	}
+}
+
+func getArtifactName(path string) string {
+	return strings.TrimPrefix(path, "./")
+}
+
+type artifactReader struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go"
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	projectfakes "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/pipeline/engine/common"
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCacheObjectName(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	cache := &v32.StepCache{Key: "go-mod", KeyFiles: []string{"go.sum"}, Paths: []string{".cache"}}
+	execution.Spec.PipelineConfig.Stages[1].Steps[0].Cache = cache
+	assert.Equal([]string{"go.sum"}, getCacheKeyFiles(execution))
+
+	clonePod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "step-0-0",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: "go.sum 3b2f5a\n",
+				}},
+			}},
+		},
+	}
+	checksums := getCacheChecksums(clonePod)
+	assert.Equal(map[string]string{"go.sum": "3b2f5a"}, checksums)
+
+	name := getCacheObjectName(execution, cache, checksums)
+	assert.Regexp(`^pipeline/go-mod-[0-9a-f]{16}\.tar\.gz$`, name)
+	assert.Equal(name, getCacheObjectName(execution, cache, checksums))
+	assert.NotEqual(name, getCacheObjectName(execution, cache, map[string]string{"go.sum": "c0ffee"}), "the key changes with the checksums")
+	assert.Equal("pipeline/go-mod.tar.gz", getCacheObjectName(execution, &v32.StepCache{Key: "go-mod"}, nil))
+
+	pod, err := getStepPod(execution, &common.ExecuteOptions{}, 0, 0, "", nil)
+	assert.Nil(err)
+	assert.Equal([]string{"sh", "-ec", cloneScript + " && " + checksumScript}, pod.Spec.Containers[0].Command)
+	assert.Contains(pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "CICD_CACHE_KEY_FILES", Value: "go.sum"})
+}
+
+func TestInjectStepStorage(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	step := &execution.Spec.PipelineConfig.Stages[1].Steps[0]
+	step.Cache = &v32.StepCache{Key: "go-mod", Paths: []string{".cache/go-mod"}}
+	step.Artifacts = []string{"bin/*"}
+
+	pod, err := getStepPod(execution, &common.ExecuteOptions{}, 1, 0, "", &stepStorage{
+		RestoreCacheURL: "http://minio/restore",
+		ArtifactsURL:    "http://minio/artifacts",
+	})
+	assert.Nil(err)
+	assert.Equal("trap 'echo $? > /var/run/pipeline/exit-code' EXIT\ngo build ./...", pod.Spec.Containers[0].Command[2])
+	assert.Len(pod.Spec.InitContainers, 1)
+	assert.Equal(restoreCacheContainerName, pod.Spec.InitContainers[0].Name)
+	storageMount := corev1.VolumeMount{Name: StorageVolumeName, MountPath: StoragePath, ReadOnly: true}
+	assert.Contains(pod.Spec.InitContainers[0].VolumeMounts, storageMount)
+	assert.Len(pod.Spec.Containers, 2)
+	assert.Equal(postStepContainerName, pod.Spec.Containers[1].Name)
+	assert.Contains(pod.Spec.Containers[1].VolumeMounts, storageMount)
+	assert.Equal([]corev1.EnvVar{{Name: "CICD_ARTIFACTS", Value: "bin/*"}}, pod.Spec.Containers[1].Env, "a restored cache is not saved again")
+	assert.Contains(pod.Spec.Volumes, corev1.Volume{
+		Name:         StorageVolumeName,
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "pipeline-1-1-0-storage"}},
+	})
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		for _, env := range container.Env {
+			assert.NotContains(env.Value, "http://minio", "the urls are not exposed in the pod spec")
+		}
+	}
+
+	pod, err = getStepPod(execution, &common.ExecuteOptions{}, 1, 0, "", &stepStorage{SaveCacheURL: "http://minio/save"})
+	assert.Nil(err)
+	assert.Empty(pod.Spec.InitContainers)
+	assert.Equal([]corev1.EnvVar{{Name: "CICD_CACHE_PATHS", Value: ".cache/go-mod"}}, pod.Spec.Containers[1].Env)
+}
+
+func TestGetStorageSecret(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+
+	secret := getStorageSecret(execution, 1, 0, &stepStorage{
+		RestoreCacheURL: "http://minio/restore",
+		ArtifactsURL:    "http://minio/artifacts",
+	})
+	assert.Equal("pipeline-1-1-0-storage", secret.Name)
+	assert.Equal("p-test-pipeline", secret.Namespace)
+	assert.Equal("pipeline-1", secret.Labels[utils.LabelKeyExecution])
+	assert.Equal(map[string][]byte{
+		restoreCacheURLKey: []byte("http://minio/restore"),
+		artifactsURLKey:    []byte("http://minio/artifacts"),
+	}, secret.Data)
+}
+
+func TestPruneStorage(t *testing.T) {
+	assert := assert.New(t)
+	notFound := func(name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{}, name)
+	}
+	e := &Engine{
+		PipelineLister: &projectfakes.PipelineListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Pipeline, error) {
+				if name == "pipeline" {
+					return &v3.Pipeline{}, nil
+				}
+				return nil, notFound(name)
+			},
+		},
+		PipelineExecutionLister: &projectfakes.PipelineExecutionListerMock{
+			GetFunc: func(namespace string, name string) (*v3.PipelineExecution, error) {
+				if name == "pipeline-1" {
+					return &v3.PipelineExecution{}, nil
+				}
+				return nil, notFound(name)
+			},
+		},
+		PipelineSettingLister: &projectfakes.PipelineSettingListerMock{
+			GetFunc: func(namespace string, name string) (*v3.PipelineSetting, error) {
+				return &v3.PipelineSetting{Value: "1h", Default: utils.SettingCacheRetentionDefault}, nil
+			},
+		},
+	}
+
+	retention := e.getCacheRetention("p-test")
+	assert.Equal(time.Hour, retention)
+	now := time.Now()
+	assert.False(e.cacheExpired("p-test", retention, minio.ObjectInfo{Key: "pipeline/go-mod.tar.gz", LastModified: now}))
+	assert.True(e.cacheExpired("p-test", retention, minio.ObjectInfo{Key: "pipeline/go-mod.tar.gz", LastModified: now.Add(-2 * time.Hour)}),
+		"caches are evicted after the retention")
+	assert.True(e.cacheExpired("p-test", retention, minio.ObjectInfo{Key: "deleted/go-mod.tar.gz", LastModified: now}),
+		"caches of deleted pipelines are evicted")
+
+	assert.False(e.artifactOrphaned("p-test", minio.ObjectInfo{Key: "pipeline-1-1-0.tar.gz"}))
+	assert.True(e.artifactOrphaned("p-test", minio.ObjectInfo{Key: "pipeline-2-1-0.tar.gz"}), "artifacts of deleted executions are removed")
+	assert.False(e.artifactOrphaned("p-test", minio.ObjectInfo{Key: "unknown.tar.gz"}))
+}
+
+func TestParseArtifactObjectName(t *testing.T) {
+	assert := assert.New(t)
+	execution := newTestExecution()
+	name, ok := parseArtifactObjectName(getArtifactObjectName(execution, 1, 0))
+	assert.True(ok)
+	assert.Equal("pipeline-1", name)
+
+	for _, objectName := range []string{"pipeline-1", "pipeline.tar.gz", "pipeline-1-a.tar.gz", "-1-0.tar.gz"} {
+		_, ok := parseArtifactObjectName(objectName)
+		assert.False(ok, objectName)
+	}
+}
@@ -44,4 +44,25 @@ func RegisterDrivers(Management *config.ScaledContext) {
 		SourceCodeCredentials:      sourceCodeCredentials,
 		SourceCodeCredentialLister: sourceCodeCredentialLister,
 	}
+	Drivers[drivers.GiteaWebhookHeader] = drivers.GiteaDriver{
+		PipelineLister:             pipelineLister,
+		PipelineExecutions:         pipelineExecutions,
+		SourceCodeCredentials:      sourceCodeCredentials,
+		SourceCodeCredentialLister: sourceCodeCredentialLister,
+	}
+}
+
+// getDriver returns the driver for the git host that sent the webhook. Gitea
+// also sets the GitHub event header for compatibility, so its header is
+// checked first.
+func getDriver(req *http.Request) (string, Driver) {
+	if req.Header.Get(drivers.GiteaWebhookHeader) != "" {
+		return drivers.GiteaWebhookHeader, Drivers[drivers.GiteaWebhookHeader]
+	}
+	for key, driver := range Drivers {
+		if exist := req.Header.Get(key); exist != "" {
+			return key, driver
+		}
+	}
+	return "", nil
 }
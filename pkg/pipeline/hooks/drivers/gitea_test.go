@@ -0,0 +1,59 @@
+package drivers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/pipeline/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyGiteaWebhookSignature(t *testing.T) {
+	assert := assert.New(t)
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(verifyGiteaWebhookSignature([]byte("secret"), signature, body))
+	assert.False(verifyGiteaWebhookSignature([]byte("other"), signature, body))
+	assert.False(verifyGiteaWebhookSignature([]byte("secret"), signature, []byte(`{"ref":"refs/heads/dev"}`)))
+	assert.False(verifyGiteaWebhookSignature([]byte("secret"), "not-hex", body))
+}
+
+func TestGiteaParsePayload(t *testing.T) {
+	assert := assert.New(t)
+	info, err := giteaParsePushPayload([]byte(`{
+		"ref": "refs/heads/main",
+		"after": "0a1b2c",
+		"head_commit": {"message": "fix build", "url": "http://gitea/owner/repo/commit/0a1b2c", "author": {"email": "user@example.com"}},
+		"sender": {"login": "gitea-user"}
+	}`))
+	assert.Nil(err)
+	assert.Equal(utils.WebhookEventPush, info.Event)
+	assert.Equal("main", info.Branch)
+	assert.Equal("0a1b2c", info.Commit)
+	assert.Equal("fix build", info.Message)
+	assert.Equal("gitea-user", info.Author)
+
+	info, err = giteaParsePushPayload([]byte(`{"ref": "refs/tags/v1.0.0", "after": "0a1b2c"}`))
+	assert.Nil(err)
+	assert.Equal(utils.WebhookEventTag, info.Event)
+	assert.Equal("v1.0.0", info.Branch)
+
+	info, err = giteaParsePullRequestPayload([]byte(`{
+		"action": "synchronized",
+		"pull_request": {"number": 7, "title": "Add feature", "state": "open", "base": {"ref": "main"}, "head": {"ref": "feature", "sha": "3d4e5f"}},
+		"sender": {"login": "gitea-user"}
+	}`))
+	assert.Nil(err)
+	assert.Equal(utils.WebhookEventPullRequest, info.Event)
+	assert.Equal("main", info.Branch)
+	assert.Equal("refs/pull/7/head", info.Ref)
+	assert.Equal("3d4e5f", info.Commit)
+
+	_, err = giteaParsePullRequestPayload([]byte(`{"action": "closed", "pull_request": {"state": "closed", "base": {}, "head": {}}}`))
+	assert.NotNil(err)
+}
@@ -22,22 +22,20 @@ func (h *WebhookHandler) initDrivers(management *config.ScaledContext) {
 }
 
 func (h *WebhookHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	for key, driver := range Drivers {
-		if exist := req.Header.Get(key); exist != "" {
-			code, err := driver.Execute(req)
-			if err != nil {
-				e := map[string]interface{}{
-					"type":    "error",
-					"code":    code,
-					"message": err.Error(),
-				}
-				logrus.Debugf("executing %s driver got error: %v", key, err)
-				rw.WriteHeader(code)
-				responseBody, _ := json.Marshal(e)
-				rw.Write(responseBody)
+	if key, driver := getDriver(req); driver != nil {
+		code, err := driver.Execute(req)
+		if err != nil {
+			e := map[string]interface{}{
+				"type":    "error",
+				"code":    code,
+				"message": err.Error(),
 			}
-			rw.WriteHeader(http.StatusOK)
+			logrus.Debugf("executing %s driver got error: %v", key, err)
+			rw.WriteHeader(code)
+			responseBody, _ := json.Marshal(e)
+			rw.Write(responseBody)
+			return
 		}
+		rw.WriteHeader(http.StatusOK)
 	}
-
 }
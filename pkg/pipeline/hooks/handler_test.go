@@ -0,0 +1,42 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/pipeline/hooks/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDriver struct {
+	code int
+	err  error
+}
+
+func (d fakeDriver) Execute(req *http.Request) (int, error) {
+	return d.code, d.err
+}
+
+func TestServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+	Drivers = map[string]Driver{
+		drivers.GithubWebhookHeader: fakeDriver{code: http.StatusUnprocessableEntity, err: errors.New("invalid payload")},
+		drivers.GiteaWebhookHeader:  fakeDriver{code: http.StatusOK},
+	}
+	defer func() { Drivers = nil }()
+	h := &WebhookHandler{}
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks", nil)
+	req.Header.Set(drivers.GithubWebhookHeader, "push")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(http.StatusUnprocessableEntity, rw.Code)
+	assert.Contains(rw.Body.String(), "invalid payload")
+
+	req.Header.Set(drivers.GiteaWebhookHeader, "push")
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(http.StatusOK, rw.Code, "gitea takes precedence over the github compatible header")
+}
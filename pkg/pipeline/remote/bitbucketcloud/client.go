@@ -307,6 +307,39 @@ func (c *client) GetHeadInfo(repoURL string, branch string, accessToken string)
 	return info, nil
 }
 
+func (c *client) SetCommitStatus(repoURL string, status *model.CommitStatus, accessToken string) error {
+	user, repo, err := getUserRepoFromURL(repoURL)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(BuildStatus{
+		Key:         model.ShortenContext(status.Context, buildStatusKeyMaxLength),
+		Name:        status.Context,
+		State:       getBuildState(status.State),
+		URL:         status.TargetURL,
+		Description: status.Description,
+	})
+	if err != nil {
+		return err
+	}
+	header := map[string]string{"Content-Type": "application/json"}
+	url := fmt.Sprintf("%s/repositories/%s/%s/commit/%s/statuses/build", apiEndpoint, user, repo, status.Commit)
+	_, err = doRequestToBitbucket(http.MethodPost, url, accessToken, header, bytes.NewReader(b))
+	return err
+}
+
+func getBuildState(state string) string {
+	switch state {
+	case model.CommitStateSuccess:
+		return "SUCCESSFUL"
+	case model.CommitStateFailure:
+		return "FAILED"
+	case model.CommitStateError:
+		return "STOPPED"
+	}
+	return "INPROGRESS"
+}
+
 func convertUser(bitbucketUser *User) *v3.SourceCodeCredential {
 
 	if bitbucketUser == nil {
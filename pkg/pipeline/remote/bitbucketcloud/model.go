@@ -134,3 +134,14 @@ type PullRequestEndpoint struct {
 	} `json:"commit"`
 	Repository Repository `json:"repository"`
 }
+
+// buildStatusKeyMaxLength is the longest key bitbucket accepts for a build status
+const buildStatusKeyMaxLength = 40
+
+type BuildStatus struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
@@ -446,6 +446,33 @@ func (c *client) GetHeadInfo(repoURL string, branch string, accessToken string)
 	return info, nil
 }
 
+func (c *client) SetCommitStatus(repoURL string, status *model.CommitStatus, accessToken string) error {
+	//build statuses of bitbucket server are stored per commit, not per repository
+	b, err := json.Marshal(BuildStatus{
+		Key:         model.ShortenContext(status.Context, buildStatusKeyMaxLength),
+		Name:        status.Context,
+		State:       getBuildState(status.State),
+		URL:         status.TargetURL,
+		Description: status.Description,
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/rest/build-status/1.0/commits/%s", c.BaseURL, status.Commit)
+	_, err = c.doRequestToBitbucket(http.MethodPost, url, accessToken, nil, bytes.NewReader(b))
+	return err
+}
+
+func getBuildState(state string) string {
+	switch state {
+	case model.CommitStateSuccess:
+		return "SUCCESSFUL"
+	case model.CommitStateFailure, model.CommitStateError:
+		return "FAILED"
+	}
+	return "INPROGRESS"
+}
+
 func convertUser(bitbucketUser *User) *v3.SourceCodeCredential {
 
 	if bitbucketUser == nil {
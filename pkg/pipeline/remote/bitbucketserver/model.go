@@ -169,3 +169,14 @@ type LastModified struct {
 	Files        map[string]Commit `json:"files"`
 	LatestCommit Commit            `json:"latestCommit"`
 }
+
+// buildStatusKeyMaxLength is the longest key bitbucket accepts for a build status
+const buildStatusKeyMaxLength = 40
+
+type BuildStatus struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
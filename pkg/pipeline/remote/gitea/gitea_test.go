@@ -0,0 +1,256 @@
+package gitea
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/pipeline/remote/model"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testToken = "access-token"
+
+// fakeGitea serves the parts of the gitea API used by the remote and records
+// the requests that modify the repository.
+type fakeGitea struct {
+	server   *httptest.Server
+	files    map[string]string
+	hooks    []Hook
+	statuses map[string][]Status
+	updates  []FileOptions
+}
+
+func newFakeGitea() *fakeGitea {
+	f := &fakeGitea{
+		files:    map[string]string{},
+		statuses: map[string][]Status{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", f.token)
+	mux.HandleFunc("/api/v1/", f.api)
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeGitea) newClient() *client {
+	remote, _ := New(&v32.GiteaPipelineConfig{
+		Hostname:     strings.TrimPrefix(f.server.URL, "http://"),
+		ClientID:     "id",
+		ClientSecret: "secret",
+	})
+	return remote.(*client)
+}
+
+func (f *fakeGitea) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.Form.Get("code") != "code" && r.Form.Get("refresh_token") != "refresh" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"access_token":%q,"refresh_token":"refresh","token_type":"bearer","expires_in":3600}`, testToken)
+}
+
+func (f *fakeGitea) api(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	switch {
+	case path == "/user":
+		writeJSON(w, User{Login: "gitea-user", FullName: "Gitea User", AvatarURL: "http://avatar"})
+	case path == "/user/repos" && r.URL.Query().Get("page") == "":
+		w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/user/repos?page=2>; rel="next"`, f.server.URL))
+		writeJSON(w, []Repository{{CloneURL: "http://gitea/owner/one.git", DefaultBranch: "main", Permissions: &Permission{Admin: true, Push: true, Pull: true}}})
+	case path == "/user/repos":
+		writeJSON(w, []Repository{{CloneURL: "http://gitea/owner/two.git", DefaultBranch: "master", Permissions: &Permission{Pull: true}}})
+	case path == "/repos/owner/repo":
+		writeJSON(w, Repository{DefaultBranch: "main"})
+	case path == "/repos/owner/repo/branches":
+		writeJSON(w, []Branch{{Name: "main"}, {Name: "dev"}})
+	case path == "/repos/owner/repo/branches/main":
+		writeJSON(w, Branch{Name: "main", Commit: &Commit{
+			ID:      "0a1b2c",
+			Message: "initial commit",
+			URL:     "http://gitea/owner/repo/commit/0a1b2c",
+			Author:  &CommitUser{UserName: "gitea-user", Email: "user@example.com"},
+		}})
+	case strings.HasPrefix(path, "/repos/owner/repo/contents/"):
+		name := strings.TrimPrefix(path, "/repos/owner/repo/contents/")
+		if r.Method != http.MethodGet {
+			var opt FileOptions
+			json.NewDecoder(r.Body).Decode(&opt)
+			f.updates = append(f.updates, opt)
+			f.files[name] = opt.Content
+			writeJSON(w, map[string]interface{}{})
+			return
+		}
+		content, ok := f.files[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, ContentsResponse{Name: name, SHA: "sha-" + name, Content: content})
+	case path == "/repos/owner/repo/hooks" && r.Method == http.MethodPost:
+		var hook Hook
+		json.NewDecoder(r.Body).Decode(&hook)
+		hook.ID = int64(len(f.hooks) + 1)
+		f.hooks = append(f.hooks, hook)
+		writeJSON(w, hook)
+	case path == "/repos/owner/repo/hooks":
+		writeJSON(w, f.hooks)
+	case strings.HasPrefix(path, "/repos/owner/repo/hooks/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, "/repos/owner/repo/hooks/")
+		for i, hook := range f.hooks {
+			if fmt.Sprint(hook.ID) == id {
+				f.hooks = append(f.hooks[:i], f.hooks[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(path, "/repos/owner/repo/statuses/") && r.Method == http.MethodPost:
+		b, _ := ioutil.ReadAll(r.Body)
+		var status Status
+		json.Unmarshal(b, &status)
+		commit := strings.TrimPrefix(path, "/repos/owner/repo/statuses/")
+		f.statuses[commit] = append(f.statuses[commit], status)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, status)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obj)
+}
+
+func TestGiteaAccount(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeGitea()
+	defer fake.server.Close()
+	c := fake.newClient()
+
+	cred, err := c.Login("code")
+	assert.Nil(err)
+	assert.Equal(model.GiteaType, cred.Spec.SourceCodeType)
+	assert.Equal("gitea-user", cred.Spec.LoginName)
+	assert.Equal("Gitea User", cred.Spec.DisplayName)
+	assert.Equal(fake.server.URL+"/gitea-user", cred.Spec.HTMLURL)
+	assert.Equal(testToken, cred.Spec.AccessToken)
+	assert.Equal("refresh", cred.Spec.RefreshToken)
+	assert.NotEmpty(cred.Spec.Expiry)
+
+	cred.Spec.AccessToken = "expired"
+	refreshed, err := c.Refresh(cred)
+	assert.Nil(err)
+	assert.True(refreshed)
+	assert.Equal(testToken, cred.Spec.AccessToken)
+
+	_, err = c.Login("invalid")
+	assert.NotNil(err)
+
+	repos, err := c.Repos(cred)
+	assert.Nil(err)
+	assert.Len(repos, 2, "repositories are listed across pages")
+	assert.Equal("http://gitea/owner/one.git", repos[0].Spec.URL)
+	assert.True(repos[0].Spec.Permissions.Admin)
+	assert.Equal("master", repos[1].Spec.DefaultBranch)
+	assert.False(repos[1].Spec.Permissions.Push)
+}
+
+func TestGiteaRepository(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeGitea()
+	defer fake.server.Close()
+	c := fake.newClient()
+	repoURL := "http://gitea/owner/repo.git"
+
+	branches, err := c.GetBranches(repoURL, testToken)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "dev"}, branches)
+
+	info, err := c.GetHeadInfo(repoURL, "main", testToken)
+	assert.Nil(err)
+	assert.Equal("0a1b2c", info.Commit)
+	assert.Equal("refs/heads/main", info.Ref)
+	assert.Equal("gitea-user", info.Author)
+	assert.Equal("http://avatar", info.AvatarURL)
+
+	content, err := c.GetPipelineFileInRepo(repoURL, "", testToken)
+	assert.Nil(err)
+	assert.Nil(content, "a missing pipeline file is not an error")
+
+	fake.files[".rancher-pipeline.yaml"] = base64.StdEncoding.EncodeToString([]byte("stages: []"))
+	content, err = c.GetPipelineFileInRepo(repoURL, "main", testToken)
+	assert.Nil(err)
+	assert.Equal("stages: []", string(content))
+
+	assert.Nil(c.SetPipelineFileInRepo(repoURL, "main", testToken, []byte("stages: [{}]")))
+	assert.Len(fake.updates, 1)
+	assert.Equal("sha-.rancher-pipeline.yaml", fake.updates[0].SHA, "the existing file is updated")
+	assert.Equal("main", fake.updates[0].Branch)
+	content, err = c.GetPipelineFileInRepo(repoURL, "main", testToken)
+	assert.Nil(err)
+	assert.Equal("stages: [{}]", string(content))
+}
+
+func TestGiteaHookAndStatus(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeGitea()
+	defer fake.server.Close()
+	c := fake.newClient()
+
+	pipeline := &v3.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "p-test", Name: "pipeline"},
+		Spec:       v32.PipelineSpec{RepositoryURL: "http://gitea/owner/repo.git"},
+		Status:     v32.PipelineStatus{Token: "webhook-secret"},
+	}
+	id, err := c.CreateHook(pipeline, testToken)
+	assert.Nil(err)
+	assert.Equal("1", id)
+	assert.Len(fake.hooks, 1)
+	assert.Equal("webhook-secret", fake.hooks[0].Config["secret"])
+	assert.True(strings.HasSuffix(fake.hooks[0].Config["url"], "hooks?pipelineId=p-test:pipeline"))
+
+	assert.Nil(c.DeleteHook(pipeline, testToken))
+	assert.Empty(fake.hooks)
+
+	err = c.SetCommitStatus(pipeline.Spec.RepositoryURL, &model.CommitStatus{
+		Commit:    "0a1b2c",
+		State:     model.CommitStateSuccess,
+		Context:   "rancher/pipeline",
+		TargetURL: "https://rancher/execution",
+	}, testToken)
+	assert.Nil(err)
+	assert.Equal([]Status{{State: "success", Context: "rancher/pipeline", TargetURL: "https://rancher/execution"}}, fake.statuses["0a1b2c"])
+
+	err = c.SetCommitStatus(pipeline.Spec.RepositoryURL, &model.CommitStatus{Commit: "3d4e5f", State: model.CommitStateRunning}, testToken)
+	assert.Nil(err)
+	assert.Equal("pending", fake.statuses["3d4e5f"][0].State, "gitea has no running state")
+
+	_, err = c.GetBranches("http://gitea/owner/missing.git", testToken)
+	assert.NotNil(err)
+}
+
+func TestGetOwnerRepoFromURL(t *testing.T) {
+	assert := assert.New(t)
+	owner, repo, err := getOwnerRepoFromURL("https://git.example.com/gitea/org/project.git")
+	assert.Nil(err)
+	assert.Equal("org", owner)
+	assert.Equal("project", repo)
+
+	_, _, err = getOwnerRepoFromURL("https://git.example.com/project")
+	assert.NotNil(err)
+}
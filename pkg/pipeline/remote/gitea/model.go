@@ -0,0 +1,109 @@
+package gitea
+
+type User struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	FullName  string `json:"full_name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type Permission struct {
+	Admin bool `json:"admin"`
+	Push  bool `json:"push"`
+	Pull  bool `json:"pull"`
+}
+
+type Repository struct {
+	ID            int64       `json:"id"`
+	Owner         *User       `json:"owner"`
+	Name          string      `json:"name"`
+	FullName      string      `json:"full_name"`
+	HTMLURL       string      `json:"html_url"`
+	CloneURL      string      `json:"clone_url"`
+	DefaultBranch string      `json:"default_branch"`
+	Permissions   *Permission `json:"permissions"`
+}
+
+type CommitUser struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	UserName string `json:"username"`
+}
+
+type Commit struct {
+	ID      string      `json:"id"`
+	Message string      `json:"message"`
+	URL     string      `json:"url"`
+	Author  *CommitUser `json:"author"`
+}
+
+type Branch struct {
+	Name   string  `json:"name"`
+	Commit *Commit `json:"commit"`
+}
+
+type Hook struct {
+	ID     int64             `json:"id,omitempty"`
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config"`
+	Events []string          `json:"events"`
+	Active bool              `json:"active"`
+}
+
+type ContentsResponse struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+type FileOptions struct {
+	Content string `json:"content"`
+	Message string `json:"message"`
+	Branch  string `json:"branch"`
+	SHA     string `json:"sha,omitempty"`
+}
+
+type Status struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+type PushEventPayload struct {
+	Ref        string      `json:"ref"`
+	Before     string      `json:"before"`
+	After      string      `json:"after"`
+	CompareURL string      `json:"compare_url"`
+	Commits    []Commit    `json:"commits"`
+	HeadCommit *Commit     `json:"head_commit"`
+	Repository *Repository `json:"repository"`
+	Pusher     *User       `json:"pusher"`
+	Sender     *User       `json:"sender"`
+}
+
+type PRBranch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type PullRequest struct {
+	Number  int64     `json:"number"`
+	User    *User     `json:"user"`
+	Title   string    `json:"title"`
+	HTMLURL string    `json:"html_url"`
+	State   string    `json:"state"`
+	Base    *PRBranch `json:"base"`
+	Head    *PRBranch `json:"head"`
+}
+
+type PullRequestEventPayload struct {
+	Action      string       `json:"action"`
+	Number      int64        `json:"number"`
+	PullRequest *PullRequest `json:"pull_request"`
+	Repository  *Repository  `json:"repository"`
+	Sender      *User        `json:"sender"`
+}
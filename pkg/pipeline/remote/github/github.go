@@ -479,6 +479,32 @@ func (c *client) GetHeadInfo(repoURL string, branch string, accessToken string)
 	return info, nil
 }
 
+func (c *client) SetCommitStatus(repoURL string, status *model.CommitStatus, accessToken string) error {
+	user, repo, err := getUserRepoFromURL(repoURL)
+	if err != nil {
+		return err
+	}
+	state := status.State
+	if state == model.CommitStateRunning {
+		state = model.CommitStatePending
+	}
+	repoStatus := &github.RepoStatus{
+		State:       &state,
+		TargetURL:   &status.TargetURL,
+		Description: &status.Description,
+		Context:     &status.Context,
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", c.API, user, repo, status.Commit)
+	b := new(bytes.Buffer)
+	json.NewEncoder(b).Encode(repoStatus)
+
+	resp, err := doRequestToGithub(http.MethodPost, url, accessToken, b)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
 func convertRepos(repos []github.Repository) []v3.SourceCodeRepository {
 	result := []v3.SourceCodeRepository{}
 	for _, repo := range repos {
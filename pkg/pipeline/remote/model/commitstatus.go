@@ -0,0 +1,36 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	CommitStatePending = "pending"
+	CommitStateRunning = "running"
+	CommitStateSuccess = "success"
+	CommitStateFailure = "failure"
+	CommitStateError   = "error"
+)
+
+// CommitStatus is the state of a build reported for a commit on the git host.
+// Hosts without a running state report it as pending.
+type CommitStatus struct {
+	Commit      string
+	State       string
+	Context     string
+	Description string
+	TargetURL   string
+}
+
+// ShortenContext returns the context cut to maxLength for hosts limiting the
+// length of status keys. A hash of the full context replaces the cut part so
+// that contexts sharing a prefix stay distinct.
+func ShortenContext(context string, maxLength int) string {
+	if len(context) <= maxLength {
+		return context
+	}
+	sum := sha256.Sum256([]byte(context))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return context[:maxLength-len(hash)-1] + "-" + hash
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortenContext(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("rancher/pipeline/Build", ShortenContext("rancher/pipeline/Build", 40))
+
+	long := ShortenContext("rancher/pipeline/Publish images to the registry", 40)
+	other := ShortenContext("rancher/pipeline/Publish images to the mirror", 40)
+	assert.Len(long, 40)
+	assert.Len(other, 40)
+	assert.NotEqual(long, other, "contexts with a common prefix stay distinct")
+	assert.Equal("rancher/pipeline/Publish images", long[:31])
+}
@@ -23,6 +23,9 @@ type Remote interface {
 	GetBranches(repoURL string, accessToken string) ([]string, error)
 
 	GetHeadInfo(repoURL string, branch string, accessToken string) (*BuildInfo, error)
+
+	//SetCommitStatus reports the state of a build for a commit
+	SetCommitStatus(repoURL string, status *CommitStatus, accessToken string) error
 }
 
 type Refresher interface {
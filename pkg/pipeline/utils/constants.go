@@ -6,6 +6,7 @@
 	JenkinsName                    = "jenkins"
 	JenkinsAgentContainerName      = "jnlp"
 	PipelineName                   = "pipeline"
+	PipelineStepName               = "pipeline-step"
 	PipelineSecretName             = "pipeline-secret"
 	PipelineAPIKeySecretName       = "pipeline-api-key"
 	PipelineSecretUserKey          = "admin-user"
@@ -38,11 +39,15 @@
 	MinioName                      = "minio"
 	MinioBucketLocation            = "local"
 	MinioLogBucket                 = "pipeline-logs"
+	MinioCacheBucket               = "pipeline-cache"
+	MinioArtifactBucket            = "pipeline-artifacts"
 	NetWorkPolicyName              = "pipeline-np"
 	LabelKeyApp                    = "app"
 	LabelKeyJenkins                = "jenkins"
 	JenkinsMaster                  = "master"
 	LabelKeyExecution              = "execution"
+	LabelKeyStage                  = "stage"
+	LabelKeyStep                   = "step"
 	DefaultRegistry                = "index.docker.io"
 	LocalRegistry                  = "docker-registry"
 	DefaultTag                     = "latest"
@@ -73,6 +78,9 @@
 	PipelineFinishLabel    = "pipeline.project.cattle.io/finish"
 	LocalRegistryPortLabel = "pipeline.project.cattle.io/local-registry-port"
 	PipelineNamespaceLabel = "pipeline.project.cattle.io/pipeline-namespace"
+	PipelineEngineLabel    = "pipeline.project.cattle.io/engine"
+
+	CommitStatusAnnotation = "pipeline.project.cattle.io/commit-status"
 
 	PipelineFileYml  = ".rancher-pipeline.yml"
 	PipelineFileYaml = ".rancher-pipeline.yaml"
@@ -107,6 +115,22 @@
 	SettingExecutorCPURequestDefault    = "10m"
 	SettingExecutorCPULimit             = "executor-cpu-limit"
 	SettingExecutorCPULimitDefault      = "1"
+	SettingEngine                       = "engine"
+	SettingEngineDefault                = EngineJenkins
+	SettingWorkspaceSize                = "workspace-size"
+	SettingWorkspaceSizeDefault         = "1Gi"
+	SettingCacheRetention               = "cache-retention"
+	SettingCacheRetentionDefault        = "168h"
+	SettingCommitStatus                 = "commit-status"
+	SettingCommitStatusDefault          = CommitStatusExecution
+
+	EngineJenkins    = "jenkins"
+	EngineKubernetes = "kubernetes"
+
+	CommitStatusDisabled  = "disabled"
+	CommitStatusExecution = "execution"
+	CommitStatusStage     = "stage"
+	CommitStatusContext   = "rancher/pipeline"
 
 	PipelineToolsMemoryRequestDefault = "10Mi"
 	PipelineToolsMemoryLimitDefault   = "100Mi"
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+)
+
+const maxMatrixCombinations = 64
+
+// ExpandMatrix replaces every step with a matrix in the config of an execution
+// by a step for each combination of the matrix values, each with its own step
+// status. The expanded steps keep a matrix of the single values they run with,
+// so expanding an execution again does not change it.
+func ExpandMatrix(execution *v3.PipelineExecution) error {
+	config := &execution.Spec.PipelineConfig
+	for i := range config.Stages {
+		stage := &config.Stages[i]
+		var steps []v32.Step
+		var statuses []v32.StepStatus
+		for j, step := range stage.Steps {
+			status := v32.StepStatus{State: StateWaiting}
+			if i < len(execution.Status.Stages) && j < len(execution.Status.Stages[i].Steps) {
+				status = execution.Status.Stages[i].Steps[j]
+			}
+			if step.Matrix == nil {
+				steps = append(steps, step)
+				statuses = append(statuses, status)
+				continue
+			}
+			combinations, err := getMatrixCombinations(step.Matrix)
+			if err != nil {
+				return fmt.Errorf("invalid matrix in stage '%s': %v", stage.Name, err)
+			}
+			for _, combination := range combinations {
+				expanded := step.DeepCopy()
+				expanded.Matrix.Values = map[string][]string{}
+				if expanded.Env == nil {
+					expanded.Env = map[string]string{}
+				}
+				for k, v := range combination {
+					expanded.Matrix.Values[k] = []string{v}
+					expanded.Env[k] = v
+				}
+				steps = append(steps, *expanded)
+				statuses = append(statuses, status)
+			}
+		}
+		stage.Steps = steps
+		if i < len(execution.Status.Stages) {
+			execution.Status.Stages[i].Steps = statuses
+		}
+	}
+	return nil
+}
+
+func getMatrixCombinations(matrix *v32.StepMatrix) ([]map[string]string, error) {
+	keys := make([]string, 0, len(matrix.Values))
+	for k := range matrix.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	combinations := []map[string]string{{}}
+	for _, k := range keys {
+		values := matrix.Values[k]
+		if len(values) == 0 {
+			return nil, fmt.Errorf("expect values of %s", k)
+		}
+		if len(combinations)*len(values) > maxMatrixCombinations {
+			return nil, fmt.Errorf("expect at most %d combinations", maxMatrixCombinations)
+		}
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, v := range values {
+				c := make(map[string]string, len(combination)+1)
+				for ck, cv := range combination {
+					c[ck] = cv
+				}
+				c[k] = v
+				next = append(next, c)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// IsFailFast checks whether a failed step aborts the other steps of its stage.
+func IsFailFast(step v32.Step) bool {
+	return step.Matrix == nil || step.Matrix.FailFast
+}
+
+// IsStageRunning checks whether any step of a stage has not finished yet.
+func IsStageRunning(stage v32.StageStatus) bool {
+	for _, step := range stage.Steps {
+		if step.State == StateWaiting || step.State == StateBuilding {
+			return true
+		}
+	}
+	return false
+}
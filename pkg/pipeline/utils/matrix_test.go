@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	assert := assert.New(t)
+	execution := &v3.PipelineExecution{
+		Spec: v32.PipelineExecutionSpec{
+			PipelineConfig: v32.PipelineConfig{
+				Stages: []v32.Stage{
+					{Steps: []v32.Step{{SourceCodeConfig: &v32.SourceCodeConfig{}}}},
+					{Steps: []v32.Step{
+						{
+							RunScriptConfig: &v32.RunScriptConfig{Image: "golang:${GO_VERSION}", ShellScript: "go build"},
+							Env:             map[string]string{"GOARCH": "386", "CGO_ENABLED": "0"},
+							Matrix: &v32.StepMatrix{Values: map[string][]string{
+								"GO_VERSION": {"1.15", "1.16"},
+								"GOARCH":     {"amd64", "arm64"},
+							}},
+						},
+						{RunScriptConfig: &v32.RunScriptConfig{Image: "busybox"}},
+					}},
+				},
+			},
+		},
+		Status: v32.PipelineExecutionStatus{
+			Stages: []v32.StageStatus{
+				{Steps: []v32.StepStatus{{State: StateWaiting}}},
+				{Steps: []v32.StepStatus{{State: StateWaiting}, {State: StateWaiting}}},
+			},
+		},
+	}
+
+	assert.Nil(ExpandMatrix(execution))
+	steps := execution.Spec.PipelineConfig.Stages[1].Steps
+	assert.Len(steps, 5)
+	assert.Len(execution.Status.Stages[1].Steps, 5)
+	assert.Equal(map[string]string{"GOARCH": "amd64", "GO_VERSION": "1.15", "CGO_ENABLED": "0"}, steps[0].Env)
+	assert.Equal(map[string]string{"GOARCH": "arm64", "GO_VERSION": "1.16", "CGO_ENABLED": "0"}, steps[3].Env)
+	assert.Equal(map[string][]string{"GOARCH": {"arm64"}, "GO_VERSION": {"1.16"}}, steps[3].Matrix.Values)
+	assert.Equal("busybox", steps[4].RunScriptConfig.Image)
+	assert.Len(execution.Spec.PipelineConfig.Stages[0].Steps, 1)
+
+	expanded := execution.DeepCopy()
+	assert.Nil(ExpandMatrix(execution))
+	assert.Equal(expanded, execution, "expanding twice does not change the execution")
+
+	execution.Spec.PipelineConfig.Stages[1].Steps[0].Matrix.Values["GOARCH"] = nil
+	assert.NotNil(ExpandMatrix(execution))
+}
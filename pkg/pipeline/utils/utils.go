@@ -159,6 +159,37 @@ func ValidPipelineConfig(config v32.PipelineConfig) error {
 		config.Stages[0].Steps[0].SourceCodeConfig == nil {
 		return fmt.Errorf("invalid definition for pipeline: expect souce code step at the start")
 	}
+	for _, stage := range config.Stages {
+		for _, step := range stage.Steps {
+			if step.Cache == nil && len(step.Artifacts) == 0 {
+				continue
+			}
+			if step.RunScriptConfig == nil {
+				return fmt.Errorf("invalid definition for pipeline: cache and artifacts are only supported in run script steps of stage '%s'", stage.Name)
+			}
+			if step.Cache != nil && (step.Cache.Key == "" || len(step.Cache.Paths) == 0) {
+				return fmt.Errorf("invalid definition for pipeline: expect key and paths of the cache in stage '%s'", stage.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidEngineConfig checks that a pipeline config only uses features of the
+// pipeline engine set for its project. Cache and artifacts of steps are only
+// supported by the kubernetes engine.
+func ValidEngineConfig(config v32.PipelineConfig, engine string) error {
+	if engine == EngineKubernetes {
+		return nil
+	}
+	for _, stage := range config.Stages {
+		for _, step := range stage.Steps {
+			if step.Cache != nil || len(step.Artifacts) > 0 {
+				return fmt.Errorf("invalid definition for pipeline: cache and artifacts in stage '%s' require the '%s' pipeline engine, set the '%s' pipeline setting of the project to '%s' to use them",
+					stage.Name, EngineKubernetes, SettingEngine, EngineKubernetes)
+			}
+		}
+	}
 	return nil
 }
 
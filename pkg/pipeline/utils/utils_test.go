@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/project.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidEngineConfig(t *testing.T) {
+	assert := assert.New(t)
+	config := v32.PipelineConfig{
+		Stages: []v32.Stage{
+			{Name: "Clone", Steps: []v32.Step{{SourceCodeConfig: &v32.SourceCodeConfig{}}}},
+			{Name: "Build", Steps: []v32.Step{{RunScriptConfig: &v32.RunScriptConfig{Image: "golang", ShellScript: "go build"}}}},
+		},
+	}
+	assert.Nil(ValidEngineConfig(config, EngineJenkins))
+	assert.Nil(ValidEngineConfig(config, EngineKubernetes))
+
+	config.Stages[1].Steps[0].Artifacts = []string{"bin/*"}
+	err := ValidEngineConfig(config, EngineJenkins)
+	assert.EqualError(err, "invalid definition for pipeline: cache and artifacts in stage 'Build' require the 'kubernetes' pipeline engine, "+
+		"set the 'engine' pipeline setting of the project to 'kubernetes' to use them")
+	assert.Nil(ValidEngineConfig(config, EngineKubernetes))
+}
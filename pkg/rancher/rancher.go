@@ -3,6 +3,7 @@
 import (
 	"context"
 	"net/http"
+	"time"
 
 	responsewriter "github.com/rancher/apiserver/pkg/middleware"
 	"github.com/rancher/rancher/pkg/api/norman/customization/kontainerdriver"
@@ -51,6 +52,16 @@ type Options struct {
 	AuditLevel        int
 	Agent             bool
 	Features          string
+
+	AuditPolicyFile          string
+	AuditWebhookURL          string
+	AuditWebhookBatchSize    int
+	AuditWebhookBatchMaxWait int
+	AuditWebhookMaxRetries   int
+	AuditSyslogAddress       string
+	AuditSyslogCAFile        string
+	AuditSyslogCertFile      string
+	AuditSyslogKeyFile       string
 }
 
 type Rancher struct {
@@ -152,7 +163,27 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 		return nil, err
 	}
 
-	auditLogWriter := audit.NewLogWriter(opts.AuditLogPath, opts.AuditLevel, opts.AuditLogMaxage, opts.AuditLogMaxbackup, opts.AuditLogMaxsize)
+	auditLogWriter, err := audit.NewLogWriter(audit.LogWriterOptions{
+		Level:               opts.AuditLevel,
+		PolicyFile:          opts.AuditPolicyFile,
+		Path:                opts.AuditLogPath,
+		MaxAge:              opts.AuditLogMaxage,
+		MaxBackup:           opts.AuditLogMaxbackup,
+		MaxSize:             opts.AuditLogMaxsize,
+		WebhookURL:          opts.AuditWebhookURL,
+		WebhookBatchSize:    opts.AuditWebhookBatchSize,
+		WebhookBatchMaxWait: time.Duration(opts.AuditWebhookBatchMaxWait) * time.Second,
+		WebhookMaxRetries:   opts.AuditWebhookMaxRetries,
+		SyslogAddress:       opts.AuditSyslogAddress,
+		SyslogTLS: audit.SyslogTLSOptions{
+			CAFile:   opts.AuditSyslogCAFile,
+			CertFile: opts.AuditSyslogCertFile,
+			KeyFile:  opts.AuditSyslogKeyFile,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
 	auditFilter := audit.NewAuditLogMiddleware(auditLogWriter)
 	aggregation := aggregation.NewMiddleware(ctx, wranglerContext.Mgmt.APIService(), wranglerContext.TunnelServer)
 
@@ -317,6 +317,7 @@ func authzTypes(schemas *types.Schemas) *types.Schemas {
 		).
 		AddMapperForType(&Version, v3.GlobalRole{}, m.DisplayName{}).
 		AddMapperForType(&Version, v3.RoleTemplate{}, m.DisplayName{}).
+		AddMapperForType(&Version, v3.PrincipalMappingRule{}, m.DisplayName{}).
 		AddMapperForType(&Version,
 			v3.PodSecurityPolicyTemplateProjectBinding{},
 			&mapper.NamespaceIDMapper{}).
@@ -356,7 +357,8 @@ func authzTypes(schemas *types.Schemas) *types.Schemas {
 		}).
 		MustImport(&Version, v3.ClusterRoleTemplateBinding{}).
 		MustImport(&Version, v3.ProjectRoleTemplateBinding{}).
-		MustImport(&Version, v3.GlobalRoleBinding{})
+		MustImport(&Version, v3.GlobalRoleBinding{}).
+		MustImport(&Version, v3.PrincipalMappingRule{})
 }
 
 func nodeTypes(schemas *types.Schemas) *types.Schemas {
@@ -466,6 +468,8 @@ func authnTypes(schemas *types.Schemas) *types.Schemas {
 		MustImport(&Version, v3.SearchPrincipalsInput{}).
 		MustImport(&Version, v3.ChangePasswordInput{}).
 		MustImport(&Version, v3.SetPasswordInput{}).
+		MustImport(&Version, v3.MFACodeInput{}).
+		MustImport(&Version, v3.MFAEnrollment{}).
 		MustImportAndCustomize(&Version, v3.User{}, func(schema *types.Schema) {
 			schema.ResourceActions = map[string]types.Action{
 				"setpassword": {
@@ -473,12 +477,23 @@ func authnTypes(schemas *types.Schemas) *types.Schemas {
 					Output: "user",
 				},
 				"refreshauthprovideraccess": {},
+				"resetmfa":                  {},
+				"unlock":                    {},
 			}
 			schema.CollectionActions = map[string]types.Action{
 				"changepassword": {
 					Input: "changePasswordInput",
 				},
 				"refreshauthprovideraccess": {},
+				"enrollmfa": {
+					Output: "mfaEnrollment",
+				},
+				"confirmmfa": {
+					Input: "mfaCodeInput",
+				},
+				"disablemfa": {
+					Input: "mfaCodeInput",
+				},
 			}
 		}).
 		MustImportAndCustomize(&Version, v3.AuthConfig{}, func(schema *types.Schema) {
@@ -613,7 +628,25 @@ func authnTypes(schemas *types.Schemas) *types.Schemas {
 			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut}
 		}).
 		MustImport(&Version, v3.GoogleOauthConfigApplyInput{}).
-		MustImport(&Version, v3.GoogleOauthConfigTestOutput{})
+		MustImport(&Version, v3.GoogleOauthConfigTestOutput{}).
+		//OIDC Config
+		MustImportAndCustomize(&Version, v3.OIDCConfig{}, func(schema *types.Schema) {
+			schema.BaseType = "authConfig"
+			schema.ResourceActions = map[string]types.Action{
+				"disable": {},
+				"configureTest": {
+					Input:  "oidcConfig",
+					Output: "oidcConfigTestOutput",
+				},
+				"testAndApply": {
+					Input: "oidcConfigApplyInput",
+				},
+			}
+			schema.CollectionMethods = []string{}
+			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut}
+		}).
+		MustImport(&Version, v3.OIDCConfigApplyInput{}).
+		MustImport(&Version, v3.OIDCConfigTestOutput{})
 }
 
 func configSchema(schema *types.Schema) {
@@ -41,11 +41,17 @@ func authProvidersTypes(schemas *types.Schemas) *types.Schemas {
 					Input:  "basicLogin",
 					Output: "token",
 				},
+				"mfaLogin": {
+					Input:  "mfaLogin",
+					Output: "token",
+				},
 			}
 			schema.CollectionMethods = []string{}
 			schema.ResourceMethods = []string{http.MethodGet}
 		}).
 		MustImport(&PublicVersion, v3.BasicLogin{}).
+		MustImport(&PublicVersion, v3.MFALogin{}).
+		MustImport(&PublicVersion, v3.MFAChallenge{}).
 		// Github provider
 		MustImportAndCustomize(&PublicVersion, v3.GithubProvider{}, func(schema *types.Schema) {
 			schema.BaseType = "authProvider"
@@ -72,6 +78,19 @@ func authProvidersTypes(schemas *types.Schemas) *types.Schemas {
 			schema.ResourceMethods = []string{http.MethodGet}
 		}).
 		MustImport(&PublicVersion, v3.GoogleOauthLogin{}).
+		// OIDC provider
+		MustImportAndCustomize(&PublicVersion, v3.OIDCProvider{}, func(schema *types.Schema) {
+			schema.BaseType = "authProvider"
+			schema.ResourceActions = map[string]types.Action{
+				"login": {
+					Input:  "oidcLogin",
+					Output: "token",
+				},
+			}
+			schema.CollectionMethods = []string{}
+			schema.ResourceMethods = []string{http.MethodGet}
+		}).
+		MustImport(&PublicVersion, v3.OIDCLogin{}).
 		// Active Directory provider
 		MustImportAndCustomize(&PublicVersion, v3.ActiveDirectoryProvider{}, func(schema *types.Schema) {
 			schema.BaseType = "authProvider"
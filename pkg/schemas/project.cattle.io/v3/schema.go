@@ -858,6 +858,7 @@ func pipelineTypes(schema *types.Schemas) *types.Schemas {
 		MustImport(&Version, v3.GithubApplyInput{}).
 		MustImport(&Version, v3.GitlabApplyInput{}).
 		MustImport(&Version, v3.BitbucketCloudApplyInput{}).
+		MustImport(&Version, v3.GiteaApplyInput{}).
 		MustImport(&Version, v3.BitbucketServerApplyInput{}).
 		MustImport(&Version, v3.BitbucketServerRequestLoginInput{}).
 		MustImport(&Version, v3.BitbucketServerRequestLoginOutput{}).
@@ -867,6 +868,7 @@ func pipelineTypes(schema *types.Schemas) *types.Schemas {
 		MustImportAndCustomize(&Version, v3.GithubProvider{}, baseProviderCustomizeFunc).
 		MustImportAndCustomize(&Version, v3.GitlabProvider{}, baseProviderCustomizeFunc).
 		MustImportAndCustomize(&Version, v3.BitbucketCloudProvider{}, baseProviderCustomizeFunc).
+		MustImportAndCustomize(&Version, v3.GiteaProvider{}, baseProviderCustomizeFunc).
 		MustImportAndCustomize(&Version, v3.BitbucketServerProvider{}, func(schema *types.Schema) {
 			schema.BaseType = "sourceCodeProvider"
 			schema.ResourceActions = map[string]types.Action{
@@ -917,6 +919,17 @@ func pipelineTypes(schema *types.Schemas) *types.Schemas {
 			}
 			schema.CollectionMethods = []string{}
 			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut}
+		}).
+		MustImportAndCustomize(&Version, v3.GiteaPipelineConfig{}, func(schema *types.Schema) {
+			schema.BaseType = "sourceCodeProviderConfig"
+			schema.ResourceActions = map[string]types.Action{
+				"disable": {},
+				"testAndApply": {
+					Input: "giteaApplyInput",
+				},
+			}
+			schema.CollectionMethods = []string{}
+			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut}
 		}).MustImportAndCustomize(&Version, v3.BitbucketServerPipelineConfig{}, func(schema *types.Schema) {
 		schema.BaseType = "sourceCodeProviderConfig"
 		schema.ResourceActions = map[string]types.Action{
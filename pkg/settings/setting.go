@@ -86,8 +86,28 @@
 	AuthUserInfoResyncCron            = NewSetting("auth-user-info-resync-cron", "0 0 * * *")
 	AuthUserSessionTTLMinutes         = NewSetting("auth-user-session-ttl-minutes", "960")   // 16 hours
 	AuthUserInfoMaxAgeSeconds         = NewSetting("auth-user-info-max-age-seconds", "3600") // 1 hour
-	APIUIVersion                      = NewSetting("api-ui-version", "1.1.6")                // Please update the CATTLE_API_UI_VERSION in package/Dockerfile when updating the version here.
-	RotateCertsIfExpiringInDays       = NewSetting("rotate-certs-if-expiring-in-days", "7")  // 7 days
+	LocalMFARequired                  = NewSetting("local-mfa-required", "false")            // require every local user to log in with a second factor
+	LocalMFARequiredGlobalRoles       = NewSetting("local-mfa-required-global-roles", "")    // comma separated global roles whose local users must log in with a second factor
+	AuthLockoutUserMaxFailures        = NewSetting("auth-lockout-user-max-failures", "0")    // failed logins locking a username, 0 to never lock. Off by default, anyone knowing a username such as admin could lock it out, the backoff slows guessing instead
+	AuthLockoutIPMaxFailures          = NewSetting("auth-lockout-ip-max-failures", "100")    // failed logins locking a source IP, 0 to never lock. Behind a proxy set auth-trusted-proxies, or the proxy itself is locked out
+	AuthLockoutDurationMinutes        = NewSetting("auth-lockout-duration-minutes", "15")
+	AuthLockoutFailureWindowMinutes   = NewSetting("auth-lockout-failure-window-minutes", "15") // failed logins older than this are forgotten
+	AuthLoginBackoffBaseSeconds       = NewSetting("auth-login-backoff-base-seconds", "1")      // delay after a failed login of a username, doubled on each failure
+	AuthLoginBackoffMaxSeconds        = NewSetting("auth-login-backoff-max-seconds", "30")
+	AuthTrustedProxies                = NewSetting("auth-trusted-proxies", "")              // comma separated IPs and CIDRs of proxies, such as the ingress controller, whose X-Forwarded-For tells the client IP
+	AuthDeviceCodeTTLMinutes          = NewSetting("auth-device-code-ttl-minutes", "10")    // how long users have to approve the login of a device
+	AuthDeviceCodeRatePerClient       = NewSetting("auth-device-code-rate-per-client", "5") // device logins a client IP may start per minute, 0 to not limit
+	AuthDeviceMaxPending              = NewSetting("auth-device-max-pending", "1000")       // pending device logins, beyond which new ones are refused, 0 to not limit
+	AuthDeviceTokenTTLMinutes         = NewSetting("auth-device-token-ttl-minutes", "960")  // 16 hours
+	LdapConnectionPoolSize            = NewSetting("ldap-connection-pool-size", "10")       // open connections of each ldap auth provider
+	LdapCacheTTLSeconds               = NewSetting("ldap-cache-ttl-seconds", "300")         // how long ldap principals and group memberships are cached, 0 to not cache
+	PasswordMinLength                 = NewSetting("password-min-length", "12")
+	PasswordRequiredCharacterClasses  = NewSetting("password-required-character-classes", "") // comma separated classes among lower, upper, digit and symbol which passwords must contain
+	PasswordDenylistEnabled           = NewSetting("password-denylist-enabled", "true")       // reject common passwords
+	PasswordHistorySize               = NewSetting("password-history-size", "0")              // last passwords of a local user they can not reuse
+	PasswordMaxAgeDays                = NewSetting("password-max-age-days", "0")              // local users must change older passwords at their next login, 0 to never
+	APIUIVersion                      = NewSetting("api-ui-version", "1.1.6")                 // Please update the CATTLE_API_UI_VERSION in package/Dockerfile when updating the version here.
+	RotateCertsIfExpiringInDays       = NewSetting("rotate-certs-if-expiring-in-days", "7")   // 7 days
 	ClusterTemplateEnforcement        = NewSetting("cluster-template-enforcement", "false")
 	InitialDockerRootDir              = NewSetting("initial-docker-root-dir", "/var/lib/docker")
 	SystemCatalog                     = NewSetting("system-catalog", "external") // Options are 'external' or 'bundled'